@@ -0,0 +1,52 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// followActiveNode asks sys/leader for the active node and points the client
+// at it when it moved, so reads stop going through a standby that redirects
+// or forwards every request. Clusters without HA leave the client as is. If
+// the node followed earlier is gone, the configured Host is asked instead.
+func (w *Watcher) followActiveNode() error {
+	leader, err := w.readLeader()
+	if err != nil && strings.TrimSuffix(w.client.Address(), "/") != strings.TrimSuffix(w.vaultConfig.Host, "/") {
+		if setErr := w.client.SetAddress(w.vaultConfig.Host); setErr != nil {
+			return fmt.Errorf("invalid vault address %q: %w", w.vaultConfig.Host, setErr)
+		}
+		leader, err = w.readLeader()
+	}
+	if err != nil {
+		return err
+	}
+	if !leader.HAEnabled || leader.IsSelf || leader.LeaderAddress == "" {
+		return nil
+	}
+
+	current := strings.TrimSuffix(w.client.Address(), "/")
+	active := strings.TrimSuffix(leader.LeaderAddress, "/")
+	if active == current {
+		return nil
+	}
+	if err := w.client.SetAddress(active); err != nil {
+		return fmt.Errorf("invalid leader address %q: %w", active, err)
+	}
+
+	w.logger.Info("Vault leadership moved, following the active node", "from", current, "to", active)
+	return nil
+}
+
+// readLeader reads sys/leader from the node the client points at
+func (w *Watcher) readLeader() (*api.LeaderResponse, error) {
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	leader, err := w.client.Sys().LeaderWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault leader: %w", classifyVaultError(err))
+	}
+	return leader, nil
+}
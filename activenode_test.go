@@ -0,0 +1,107 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCluster serves sys/leader and a secret from nodes sharing one leader
+type fakeCluster struct {
+	mu     sync.Mutex
+	leader string
+	reads  map[string]int
+}
+
+func (c *fakeCluster) setLeader(leader string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.leader = leader
+}
+
+func (c *fakeCluster) readCount(node string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reads[node]
+}
+
+// node returns a server for one node; its address is set once it's listening
+func (c *fakeCluster) node() *httptest.Server {
+	var self string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/sys/leader" {
+			fmt.Fprintf(w, `{"ha_enabled": true, "is_self": %t, "leader_address": %q}`, c.leader == self, c.leader)
+			return
+		}
+		c.reads[self]++
+		fmt.Fprintf(w, `{"data": {"data": {"node": %q}, "metadata": {"version": 1}}}`, self)
+	}))
+	self = server.URL
+	return server
+}
+
+func TestWatcher_ActiveNodeDiscovery(t *testing.T) {
+	cluster := &fakeCluster{reads: map[string]int{}}
+	standby := cluster.node()
+	defer standby.Close()
+	active := cluster.node()
+	defer active.Close()
+	cluster.setLeader(active.URL)
+
+	watcher, err := NewWatcher(&VaultConfig{Host: standby.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithActiveNodeDiscovery(), WithRetryPolicy(RetryPolicy{}))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertStringEquals(t, watcher.client.Address(), active.URL, "address after start")
+	if reads := cluster.readCount(standby.URL); reads != 0 {
+		t.Errorf("standby reads = %d, want 0", reads)
+	}
+
+	// Leadership moves back to the configured node
+	cluster.setLeader(standby.URL)
+	AssertNoError(t, watcher.check(), "check after leadership moved")
+	AssertStringEquals(t, watcher.client.Address(), standby.URL, "address after leadership moved")
+	if reads := cluster.readCount(standby.URL); reads != 1 {
+		t.Errorf("standby reads = %d, want 1", reads)
+	}
+
+	// The followed node goes away and the configured Host is asked again
+	cluster.setLeader(active.URL)
+	AssertNoError(t, watcher.check(), "check after leadership moved again")
+	active.Close()
+	cluster.setLeader(standby.URL)
+	AssertNoError(t, watcher.check(), "check after the active node went away")
+	AssertStringEquals(t, watcher.client.Address(), standby.URL, "address after the active node went away")
+}
+
+func TestWatcher_ActiveNodeDiscoveryErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *VaultConfig
+	}{
+		{
+			name:   "failover hosts",
+			config: &VaultConfig{Host: "https://vault-a:8200", FailoverHosts: []string{"https://vault-b:8200"}, Path: "secret/data/app", Token: "t"},
+		},
+		{
+			name:   "unix socket",
+			config: &VaultConfig{Host: "unix:///var/run/vault.sock", Path: "secret/data/app", Token: "t"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWatcher(tt.config, time.Hour, func() error { return nil }, WithActiveNodeDiscovery())
+			AssertError(t, err, "active node discovery needs a single tcp vault address", "NewWatcher()")
+		})
+	}
+}
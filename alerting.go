@@ -0,0 +1,234 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	defaultOpsgenieAPIURL     = "https://api.opsgenie.com"
+)
+
+// alertKey builds the deduplication key shared by the trigger and resolve of one incident
+func alertKey(path, kind string) string {
+	return fmt.Sprintf("vault-watcher/%s/%s", path, kind)
+}
+
+// PagerDutyConfig holds the configuration for a PagerDutyNotifier
+type PagerDutyConfig struct {
+	RoutingKey string       // Integration key of the PagerDuty service
+	Source     string       // Source reported on incidents (default "vault-watcher")
+	Severity   string       // critical, error, warning or info (default "error")
+	EventsURL  string       // Events API v2 endpoint (default PagerDuty's public endpoint)
+	HTTPClient *http.Client // Optional custom HTTP client
+}
+
+// PagerDutyNotifier opens PagerDuty incidents when the watcher becomes unhealthy
+// or detects churn, and resolves them on recovery. Ordinary changes are ignored.
+type PagerDutyNotifier struct {
+	config  PagerDutyConfig
+	webhook *WebhookNotifier
+}
+
+// NewPagerDutyNotifier creates an alerting sink using the PagerDuty Events API v2
+func NewPagerDutyNotifier(config PagerDutyConfig) (*PagerDutyNotifier, error) {
+	if config.RoutingKey == "" {
+		return nil, fmt.Errorf("pagerduty routing key is required")
+	}
+	if config.Source == "" {
+		config.Source = "vault-watcher"
+	}
+	if config.Severity == "" {
+		config.Severity = "error"
+	}
+	if config.EventsURL == "" {
+		config.EventsURL = defaultPagerDutyEventsURL
+	}
+
+	webhook, err := NewWebhookNotifier(WebhookConfig{
+		URLs:       []string{config.EventsURL},
+		HTTPClient: config.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PagerDutyNotifier{
+		config:  config,
+		webhook: webhook,
+	}, nil
+}
+
+// Notify ignores ordinary changes
+func (n *PagerDutyNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	return nil
+}
+
+// NotifyHealth triggers an incident when the watcher becomes unhealthy and resolves it on recovery
+func (n *PagerDutyNotifier) NotifyHealth(ctx context.Context, event HealthEvent) error {
+	dedupKey := alertKey(event.Path, "unhealthy")
+	if event.Healthy {
+		return n.send(ctx, "resolve", dedupKey, nil)
+	}
+
+	return n.send(ctx, "trigger", dedupKey, map[string]interface{}{
+		"summary":   fmt.Sprintf("Vault watcher for %s is unhealthy after %d consecutive failures", event.Path, event.ConsecutiveFailures),
+		"source":    n.config.Source,
+		"severity":  n.config.Severity,
+		"component": event.Path,
+		"custom_details": map[string]interface{}{
+			"consecutive_failures": event.ConsecutiveFailures,
+			"error":                event.Error,
+		},
+	})
+}
+
+// NotifyChurn triggers an incident when churn starts and resolves it once it has settled
+func (n *PagerDutyNotifier) NotifyChurn(ctx context.Context, event ChurnEvent) error {
+	dedupKey := alertKey(event.Path, "churn")
+	if !event.Active {
+		return n.send(ctx, "resolve", dedupKey, nil)
+	}
+
+	return n.send(ctx, "trigger", dedupKey, map[string]interface{}{
+		"summary":   fmt.Sprintf("Vault secret %s changed %d times within %s", event.Path, event.Changes, event.Window),
+		"source":    n.config.Source,
+		"severity":  n.config.Severity,
+		"component": event.Path,
+		"custom_details": map[string]interface{}{
+			"changes": event.Changes,
+			"limit":   event.Limit,
+			"window":  event.Window.String(),
+		},
+	})
+}
+
+func (n *PagerDutyNotifier) send(ctx context.Context, action, dedupKey string, payload map[string]interface{}) error {
+	message := map[string]interface{}{
+		"routing_key":  n.config.RoutingKey,
+		"event_action": action,
+		"dedup_key":    dedupKey,
+	}
+	if payload != nil {
+		message["payload"] = payload
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pagerduty event: %w", err)
+	}
+	if err := n.webhook.deliver(ctx, n.config.EventsURL, body); err != nil {
+		return fmt.Errorf("pagerduty %s: %w", action, err)
+	}
+	return nil
+}
+
+// OpsgenieConfig holds the configuration for an OpsgenieNotifier
+type OpsgenieConfig struct {
+	APIKey     string       // Opsgenie API integration key
+	APIURL     string       // API base URL (default "https://api.opsgenie.com", use api.eu.opsgenie.com for EU)
+	Priority   string       // P1 to P5 (default "P3")
+	Source     string       // Source reported on alerts (default "vault-watcher")
+	HTTPClient *http.Client // Optional custom HTTP client
+}
+
+// OpsgenieNotifier opens Opsgenie alerts when the watcher becomes unhealthy
+// or detects churn, and closes them on recovery. Ordinary changes are ignored.
+type OpsgenieNotifier struct {
+	config  OpsgenieConfig
+	webhook *WebhookNotifier
+}
+
+// NewOpsgenieNotifier creates an alerting sink using the Opsgenie Alert API
+func NewOpsgenieNotifier(config OpsgenieConfig) (*OpsgenieNotifier, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("opsgenie API key is required")
+	}
+	if config.APIURL == "" {
+		config.APIURL = defaultOpsgenieAPIURL
+	}
+	config.APIURL = strings.TrimSuffix(config.APIURL, "/")
+	if config.Priority == "" {
+		config.Priority = "P3"
+	}
+	if config.Source == "" {
+		config.Source = "vault-watcher"
+	}
+
+	webhook, err := NewWebhookNotifier(WebhookConfig{
+		URLs:       []string{config.APIURL},
+		Headers:    map[string]string{"Authorization": "GenieKey " + config.APIKey},
+		HTTPClient: config.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpsgenieNotifier{
+		config:  config,
+		webhook: webhook,
+	}, nil
+}
+
+// Notify ignores ordinary changes
+func (n *OpsgenieNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	return nil
+}
+
+// NotifyHealth creates an alert when the watcher becomes unhealthy and closes it on recovery
+func (n *OpsgenieNotifier) NotifyHealth(ctx context.Context, event HealthEvent) error {
+	alias := alertKey(event.Path, "unhealthy")
+	if event.Healthy {
+		return n.close(ctx, alias)
+	}
+
+	return n.create(ctx, alias,
+		fmt.Sprintf("Vault watcher for %s is unhealthy", event.Path),
+		fmt.Sprintf("%d consecutive failures. Last error: %s", event.ConsecutiveFailures, event.Error))
+}
+
+// NotifyChurn creates an alert when churn starts and closes it once it has settled
+func (n *OpsgenieNotifier) NotifyChurn(ctx context.Context, event ChurnEvent) error {
+	alias := alertKey(event.Path, "churn")
+	if !event.Active {
+		return n.close(ctx, alias)
+	}
+
+	return n.create(ctx, alias,
+		fmt.Sprintf("Unusual churn on Vault secret %s", event.Path),
+		fmt.Sprintf("%d changes within %s (limit %d)", event.Changes, event.Window, event.Limit))
+}
+
+func (n *OpsgenieNotifier) create(ctx context.Context, alias, message, description string) error {
+	body, err := json.Marshal(map[string]string{
+		"message":     message,
+		"alias":       alias,
+		"description": description,
+		"priority":    n.config.Priority,
+		"source":      n.config.Source,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal opsgenie alert: %w", err)
+	}
+	if err := n.webhook.deliver(ctx, n.config.APIURL+"/v2/alerts", body); err != nil {
+		return fmt.Errorf("opsgenie create alert: %w", err)
+	}
+	return nil
+}
+
+func (n *OpsgenieNotifier) close(ctx context.Context, alias string) error {
+	body, err := json.Marshal(map[string]string{"source": n.config.Source})
+	if err != nil {
+		return fmt.Errorf("failed to marshal opsgenie close request: %w", err)
+	}
+	closeURL := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", n.config.APIURL, url.PathEscape(alias))
+	if err := n.webhook.deliver(ctx, closeURL, body); err != nil {
+		return fmt.Errorf("opsgenie close alert: %w", err)
+	}
+	return nil
+}
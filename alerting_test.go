@@ -0,0 +1,101 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// alertRequest is a request received by the fake alerting API
+type alertRequest struct {
+	path   string
+	query  string
+	auth   string
+	fields map[string]interface{}
+}
+
+func alertServer(t *testing.T) (*httptest.Server, *[]alertRequest) {
+	var requests []alertRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := alertRequest{path: r.URL.EscapedPath(), query: r.URL.RawQuery, auth: r.Header.Get("Authorization")}
+		if err := json.NewDecoder(r.Body).Decode(&req.fields); err != nil {
+			t.Errorf("invalid JSON body: %v", err)
+		}
+		requests = append(requests, req)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return server, &requests
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	if _, err := NewPagerDutyNotifier(PagerDutyConfig{}); err == nil {
+		t.Error("NewPagerDutyNotifier() expected error for missing routing key")
+	}
+
+	server, requests := alertServer(t)
+	defer server.Close()
+
+	notifier, err := NewPagerDutyNotifier(PagerDutyConfig{RoutingKey: "routing-key", EventsURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewPagerDutyNotifier() error = %v", err)
+	}
+
+	ctx := context.Background()
+	AssertNoError(t, notifier.Notify(ctx, ChangeEvent{Path: "kv/data/app"}), "Notify()")
+	AssertNoError(t, notifier.NotifyHealth(ctx, HealthEvent{Path: "kv/data/app", ConsecutiveFailures: 3, Error: "timeout"}), "NotifyHealth(unhealthy)")
+	AssertNoError(t, notifier.NotifyHealth(ctx, HealthEvent{Path: "kv/data/app", Healthy: true}), "NotifyHealth(healthy)")
+	AssertNoError(t, notifier.NotifyChurn(ctx, ChurnEvent{Path: "kv/data/app", Active: true, Changes: 6, Limit: 5, Window: time.Minute}), "NotifyChurn(active)")
+
+	if len(*requests) != 3 {
+		t.Fatalf("received %d requests, want 3 (changes must not page)", len(*requests))
+	}
+
+	trigger := (*requests)[0].fields
+	AssertStringEquals(t, trigger["routing_key"].(string), "routing-key", "routing_key")
+	AssertStringEquals(t, trigger["event_action"].(string), "trigger", "event_action")
+	AssertStringEquals(t, trigger["dedup_key"].(string), "vault-watcher/kv/data/app/unhealthy", "dedup_key")
+	payload := trigger["payload"].(map[string]interface{})
+	AssertStringEquals(t, payload["severity"].(string), "error", "severity")
+
+	resolve := (*requests)[1].fields
+	AssertStringEquals(t, resolve["event_action"].(string), "resolve", "event_action")
+	AssertStringEquals(t, resolve["dedup_key"].(string), "vault-watcher/kv/data/app/unhealthy", "dedup_key")
+
+	churn := (*requests)[2].fields
+	AssertStringEquals(t, churn["dedup_key"].(string), "vault-watcher/kv/data/app/churn", "dedup_key")
+}
+
+func TestOpsgenieNotifier(t *testing.T) {
+	if _, err := NewOpsgenieNotifier(OpsgenieConfig{}); err == nil {
+		t.Error("NewOpsgenieNotifier() expected error for missing API key")
+	}
+
+	server, requests := alertServer(t)
+	defer server.Close()
+
+	notifier, err := NewOpsgenieNotifier(OpsgenieConfig{APIKey: "genie", APIURL: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("NewOpsgenieNotifier() error = %v", err)
+	}
+
+	ctx := context.Background()
+	AssertNoError(t, notifier.NotifyChurn(ctx, ChurnEvent{Path: "kv/data/app", Active: true, Changes: 6, Limit: 5, Window: time.Minute}), "NotifyChurn(active)")
+	AssertNoError(t, notifier.NotifyChurn(ctx, ChurnEvent{Path: "kv/data/app", Active: false}), "NotifyChurn(settled)")
+
+	if len(*requests) != 2 {
+		t.Fatalf("received %d requests, want 2", len(*requests))
+	}
+
+	create := (*requests)[0]
+	AssertStringEquals(t, create.path, "/v2/alerts", "create path")
+	AssertStringEquals(t, create.auth, "GenieKey genie", "Authorization")
+	AssertStringEquals(t, create.fields["alias"].(string), "vault-watcher/kv/data/app/churn", "alias")
+	AssertStringEquals(t, create.fields["priority"].(string), "P3", "priority")
+
+	closeReq := (*requests)[1]
+	AssertStringEquals(t, closeReq.path, "/v2/alerts/vault-watcher%2Fkv%2Fdata%2Fapp%2Fchurn/close", "close path")
+	AssertStringEquals(t, closeReq.query, "identifierType=alias", "close query")
+}
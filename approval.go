@@ -0,0 +1,44 @@
+package vaultwatcher
+
+import (
+	"fmt"
+)
+
+// AwaitingApproval returns the change the approval hook last deferred, if it
+// is still waiting
+func (w *Watcher) AwaitingApproval() (ChangeEvent, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.awaitingApproval == nil {
+		return ChangeEvent{}, false
+	}
+	return *w.awaitingApproval, true
+}
+
+// approveChange asks the hook set with WithApprovalHook whether event may be
+// applied. A deferred change is logged once and asked about again by the
+// next check.
+func (w *Watcher) approveChange(event ChangeEvent) (bool, error) {
+	if w.approvalHook == nil {
+		return true, nil
+	}
+
+	approved, err := w.approvalHook(event)
+	if err != nil {
+		return false, fmt.Errorf("approval hook failed: %w", err)
+	}
+
+	w.mu.Lock()
+	alreadyWaiting := w.awaitingApproval != nil && w.awaitingApproval.NewHash == event.NewHash
+	if approved {
+		w.awaitingApproval = nil
+	} else {
+		w.awaitingApproval = &event
+	}
+	w.mu.Unlock()
+
+	if !approved && !alreadyWaiting {
+		w.logger.Info("Change is waiting for approval", "path", event.Path)
+	}
+	return approved, nil
+}
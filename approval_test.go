@@ -0,0 +1,86 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWatcher_ApprovalHook(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one"})
+
+	var answer struct {
+		approve bool
+		err     error
+	}
+	var asked []string
+	called := 0
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		called++
+		return nil
+	}, WithApprovalHook(func(event ChangeEvent) (bool, error) {
+		asked = append(asked, fmt.Sprint(event.ChangedKeys))
+		return answer.approve, answer.err
+	}))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+	hash := watcher.GetCurrentHash()
+
+	tests := []struct {
+		name         string
+		approve      bool
+		err          error
+		wantErr      string
+		wantCalled   int
+		wantAwaiting bool
+	}{
+		{name: "deferred", wantAwaiting: true},
+		{name: "deferred again", wantAwaiting: true},
+		{name: "hook failing", err: errors.New("approval service unavailable"),
+			wantErr: "approval hook failed: approval service unavailable", wantAwaiting: true},
+		{name: "approved", approve: true, wantCalled: 1},
+	}
+
+	source.set(map[string]interface{}{"password": "two"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer.approve, answer.err = tt.approve, tt.err
+			err := watcher.check()
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "check()")
+			} else {
+				AssertNoError(t, err, "check()")
+			}
+			AssertBoolEquals(t, called == tt.wantCalled, true, fmt.Sprintf("onChange called %d times", called))
+			_, awaiting := watcher.AwaitingApproval()
+			AssertBoolEquals(t, awaiting, tt.wantAwaiting, "AwaitingApproval()")
+			AssertBoolEquals(t, watcher.GetCurrentHash() == hash, tt.wantCalled == 0, "hash unchanged")
+		})
+	}
+	AssertStringEquals(t, fmt.Sprint(asked), "[[password] [password] [password] [password]]", "changes asked about")
+}
+
+func TestWatcher_ApprovalHook_Reverted(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil },
+		WithApprovalHook(func(ChangeEvent) (bool, error) { return false, nil }))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	source.set(map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.check(), "check()")
+	event, awaiting := watcher.AwaitingApproval()
+	AssertBoolEquals(t, awaiting, true, "AwaitingApproval() after the change")
+	AssertStringEquals(t, fmt.Sprint(event.ChangedKeys), "[password]", "deferred ChangedKeys")
+
+	// Reverting the secret leaves nothing to approve
+	source.set(map[string]interface{}{"password": "one"})
+	AssertNoError(t, watcher.check(), "check() after the revert")
+	_, awaiting = watcher.AwaitingApproval()
+	AssertBoolEquals(t, awaiting, false, "AwaitingApproval() after the revert")
+}
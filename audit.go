@@ -0,0 +1,154 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultAuditMaxBackups = 5
+
+// AuditRecord is one line of an audit log. It describes a change by its
+// hashes and key names only, never by values.
+type AuditRecord struct {
+	Timestamp       time.Time `json:"timestamp"` // When the change was detected
+	Path            string    `json:"path"`
+	Outcome         string    `json:"outcome"` // "applied" or "rejected"
+	Version         int       `json:"version,omitempty"`
+	PreviousVersion int       `json:"previous_version,omitempty"`
+	ChangedKeys     []string  `json:"changed_keys"`
+	OldHash         string    `json:"old_hash"`
+	NewHash         string    `json:"new_hash"`
+	RequestID       string    `json:"request_id,omitempty"`
+	Errors          []string  `json:"errors,omitempty"` // Why a change was rejected
+}
+
+// AuditLogConfig configures an AuditLog
+type AuditLogConfig struct {
+	Path string // File the records are appended to, created if missing
+
+	// MaxSize rotates the file before it grows past this many bytes, renaming
+	// it to Path.1 and older files to Path.2 and so on. Zero never rotates.
+	MaxSize int64
+	// MaxBackups is the number of rotated files kept (default 5)
+	MaxBackups int
+}
+
+// AuditLog is a notifier appending every applied change, and every change
+// rejected by validation, to a file as JSON lines. Register it with
+// WithNotifier; one log can be shared by several watchers.
+type AuditLog struct {
+	config AuditLogConfig
+	mu     sync.Mutex
+}
+
+// NewAuditLog creates an audit log appending to config.Path
+func NewAuditLog(config AuditLogConfig) (*AuditLog, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("audit log path is required")
+	}
+	if config.MaxSize < 0 {
+		return nil, fmt.Errorf("audit log max size cannot be negative")
+	}
+	if config.MaxBackups < 0 {
+		return nil, fmt.Errorf("audit log max backups cannot be negative")
+	}
+	if config.MaxBackups == 0 {
+		config.MaxBackups = defaultAuditMaxBackups
+	}
+	return &AuditLog{config: config}, nil
+}
+
+// Notify records an applied change
+func (l *AuditLog) Notify(ctx context.Context, event ChangeEvent) error {
+	return l.Write(AuditRecord{
+		Timestamp:       event.Timestamp,
+		Path:            event.Path,
+		Outcome:         "applied",
+		Version:         event.Version,
+		PreviousVersion: event.PreviousVersion,
+		ChangedKeys:     event.ChangedKeys,
+		OldHash:         event.OldHash,
+		NewHash:         event.NewHash,
+		RequestID:       event.RequestID,
+	})
+}
+
+// NotifyValidationFailure records a change rejected by validation
+func (l *AuditLog) NotifyValidationFailure(ctx context.Context, event ValidationFailedEvent) error {
+	return l.Write(AuditRecord{
+		Timestamp:   event.Timestamp,
+		Path:        event.Path,
+		Outcome:     "rejected",
+		Version:     event.Version,
+		ChangedKeys: event.ChangedKeys,
+		OldHash:     event.OldHash,
+		NewHash:     event.NewHash,
+		RequestID:   event.RequestID,
+		Errors:      event.Errors,
+	})
+}
+
+// Write appends record to the file, rotating it first if it would grow past
+// MaxSize
+func (l *AuditLog) Write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.MaxSize > 0 {
+		info, err := os.Stat(l.config.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to stat audit log: %w", err)
+		}
+		if err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > l.config.MaxSize {
+			if err := l.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	file, err := os.OpenFile(l.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts Path.n to Path.n+1, dropping the oldest, and Path to Path.1;
+// l.mu must be held
+func (l *AuditLog) rotate() error {
+	backup := func(n int) string {
+		return l.config.Path + "." + strconv.Itoa(n)
+	}
+
+	if err := os.Remove(backup(l.config.MaxBackups)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	for n := l.config.MaxBackups - 1; n >= 1; n-- {
+		if err := os.Rename(backup(n), backup(n+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(l.config.Path, backup(1)); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return nil
+}
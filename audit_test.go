@@ -0,0 +1,108 @@
+package vaultwatcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAuditRecords reads the records of an audit log file
+func readAuditRecords(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	file, err := os.Open(path)
+	AssertNoError(t, err, "Open()")
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		AssertNoError(t, json.Unmarshal(scanner.Bytes(), &record), "Unmarshal()")
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNewAuditLog_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AuditLogConfig
+		wantErr string
+	}{
+		{name: "no path", wantErr: "audit log path is required"},
+		{name: "negative size", config: AuditLogConfig{Path: "audit.jsonl", MaxSize: -1}, wantErr: "audit log max size cannot be negative"},
+		{name: "negative backups", config: AuditLogConfig{Path: "audit.jsonl", MaxBackups: -1}, wantErr: "audit log max backups cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAuditLog(tt.config)
+			AssertError(t, err, tt.wantErr, "NewAuditLog()")
+		})
+	}
+}
+
+func TestWatcher_AuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := NewAuditLog(AuditLogConfig{Path: path})
+	AssertNoError(t, err, "NewAuditLog()")
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one", "port": "5432"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil }, WithNotifier(audit),
+		WithSecretValidator(func(data map[string]interface{}) error {
+			if data["port"] == "" {
+				return errors.New("port is required")
+			}
+			return nil
+		}))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	source.set(map[string]interface{}{"password": "s3cret-two", "port": "5432"})
+	AssertNoError(t, watcher.check(), "check()")
+	source.set(map[string]interface{}{"password": "s3cret-two", "port": ""})
+	AssertError(t, watcher.check(), "secret failed validation: port is required", "check() with an invalid change")
+
+	records := readAuditRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	AssertStringEquals(t, records[0].Outcome+" "+strings.Join(records[0].ChangedKeys, ","), "applied password", "first record")
+	AssertBoolEquals(t, records[0].Version == 2 && records[0].PreviousVersion == 1, true, "first record versions")
+	AssertStringEquals(t, records[1].Outcome+" "+strings.Join(records[1].ChangedKeys, ","), "rejected port", "second record")
+
+	contents, err := os.ReadFile(path)
+	AssertNoError(t, err, "ReadFile()")
+	AssertBoolEquals(t, strings.Contains(string(contents), "s3cret"), false, "audit log contains a value")
+}
+
+func TestAuditLog_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := NewAuditLog(AuditLogConfig{Path: path, MaxSize: 200, MaxBackups: 2})
+	AssertNoError(t, err, "NewAuditLog()")
+
+	// Each record is over 100 bytes, so every record after the first rotates
+	for i := 0; i < 4; i++ {
+		AssertNoError(t, audit.Write(AuditRecord{Path: "secret/data/app", Outcome: "applied", Version: i + 1,
+			ChangedKeys: []string{"password"}, OldHash: strings.Repeat("a", 32), NewHash: strings.Repeat("b", 32)}), "Write()")
+	}
+
+	versions := map[string]int{}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		records := readAuditRecords(t, name)
+		if len(records) != 1 {
+			t.Fatalf("%s has %d records, want 1", name, len(records))
+		}
+		versions[filepath.Base(name)] = records[0].Version
+	}
+	AssertBoolEquals(t, versions["audit.jsonl"] == 4 && versions["audit.jsonl.1"] == 3 && versions["audit.jsonl.2"] == 2, true, "rotated versions")
+	_, err = os.Stat(path + ".3")
+	AssertBoolEquals(t, os.IsNotExist(err), true, "oldest file dropped")
+}
@@ -0,0 +1,61 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are access keys issued by the AWS secrets engine
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Only set for STS credentials
+	LeaseID         string
+	Expires         time.Time // When the lease expires unless renewed
+}
+
+// ParseAWSCredentials extracts AWS access keys from a secret issued by
+// aws/creds/<role> or aws/sts/<role>
+func ParseAWSCredentials(secret *DynamicSecret) (AWSCredentials, error) {
+	if secret == nil {
+		return AWSCredentials{}, fmt.Errorf("secret cannot be nil")
+	}
+
+	creds := AWSCredentials{
+		AccessKeyID:     secret.Get("access_key"),
+		SecretAccessKey: secret.Get("secret_key"),
+		SessionToken:    secret.Get("session_token"),
+		LeaseID:         secret.LeaseID,
+		Expires:         secret.ExpiresAt(),
+	}
+	if creds.SessionToken == "" {
+		// Older Vault versions only return security_token for STS credentials
+		creds.SessionToken = secret.Get("security_token")
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("secret at %s does not contain AWS access keys", secret.Path)
+	}
+
+	return creds, nil
+}
+
+// NewAWSCredentialsWatcher creates a watcher delivering AWS access keys from the
+// AWS secrets engine, e.g. "aws/creds/deploy" or "aws/sts/deploy". Fresh keys
+// are passed to onRotate ahead of lease expiry so SDK clients can be rebuilt.
+func NewAWSCredentialsWatcher(vaultConfig *VaultConfig, onRotate func(AWSCredentials) error, opts ...DynamicSecretOption) (*DynamicSecretWatcher, error) {
+	if onRotate == nil {
+		return nil, fmt.Errorf("onRotate callback cannot be nil")
+	}
+	if vaultConfig != nil && !strings.Contains(vaultConfig.Path, "/creds/") && !strings.Contains(vaultConfig.Path, "/sts/") {
+		return nil, fmt.Errorf("AWS credentials path must look like <mount>/creds/<role> or <mount>/sts/<role>, got %q", vaultConfig.Path)
+	}
+
+	return NewDynamicSecretWatcher(vaultConfig, func(secret *DynamicSecret) error {
+		creds, err := ParseAWSCredentials(secret)
+		if err != nil {
+			return err
+		}
+		return onRotate(creds)
+	}, opts...)
+}
@@ -0,0 +1,128 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseAWSCredentials(t *testing.T) {
+	renewedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		secret      *DynamicSecret
+		expectError bool
+		wantToken   string
+	}{
+		{
+			name: "IAM user credentials",
+			secret: &DynamicSecret{
+				Path: "aws/creds/deploy",
+				Data: map[string]interface{}{"access_key": "AKIA1", "secret_key": "s1", "security_token": nil},
+			},
+		},
+		{
+			name: "STS credentials with session_token",
+			secret: &DynamicSecret{
+				Path: "aws/sts/deploy",
+				Data: map[string]interface{}{"access_key": "ASIA1", "secret_key": "s1", "session_token": "tok"},
+			},
+			wantToken: "tok",
+		},
+		{
+			name: "STS credentials with legacy security_token",
+			secret: &DynamicSecret{
+				Path: "aws/sts/deploy",
+				Data: map[string]interface{}{"access_key": "ASIA1", "secret_key": "s1", "security_token": "legacy"},
+			},
+			wantToken: "legacy",
+		},
+		{
+			name:        "missing keys",
+			secret:      &DynamicSecret{Path: "database/creds/ro", Data: map[string]interface{}{"username": "u"}},
+			expectError: true,
+		},
+		{
+			name:        "nil secret",
+			secret:      nil,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secret != nil {
+				tt.secret.LeaseID = "lease-1"
+				tt.secret.LeaseDuration = time.Hour
+				tt.secret.RenewedAt = renewedAt
+			}
+
+			creds, err := ParseAWSCredentials(tt.secret)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseAWSCredentials() expected error but got none")
+				}
+				return
+			}
+
+			AssertNoError(t, err, "ParseAWSCredentials()")
+			AssertStringEquals(t, creds.SecretAccessKey, "s1", "SecretAccessKey")
+			AssertStringEquals(t, creds.SessionToken, tt.wantToken, "SessionToken")
+			AssertStringEquals(t, creds.LeaseID, "lease-1", "LeaseID")
+			if !creds.Expires.Equal(renewedAt.Add(time.Hour)) {
+				t.Errorf("Expires = %v, want %v", creds.Expires, renewedAt.Add(time.Hour))
+			}
+		})
+	}
+}
+
+func TestNewAWSCredentialsWatcher(t *testing.T) {
+	config := &VaultConfig{Host: "https://vault.example.com", Path: "secret/data/app", Token: "t"}
+	_, err := NewAWSCredentialsWatcher(config, func(AWSCredentials) error { return nil })
+	if err == nil {
+		t.Error("NewAWSCredentialsWatcher() expected error for a non-AWS path")
+	}
+
+	config.Path = "aws/sts/deploy"
+	_, err = NewAWSCredentialsWatcher(config, nil)
+	AssertError(t, err, "onRotate callback cannot be nil", "NewAWSCredentialsWatcher(nil callback)")
+}
+
+func TestAWSCredentialsWatcher_DeliversKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       "aws/sts/deploy/abc",
+			"lease_duration": 3600,
+			"renewable":      false,
+			"data": map[string]interface{}{
+				"access_key":     "ASIAEXAMPLE",
+				"secret_key":     "secret",
+				"security_token": "session",
+			},
+		})
+	}))
+	defer server.Close()
+
+	var delivered AWSCredentials
+	watcher, err := NewAWSCredentialsWatcher(
+		&VaultConfig{Host: server.URL, Path: "aws/sts/deploy", Token: "test-token"},
+		func(creds AWSCredentials) error {
+			delivered = creds
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewAWSCredentialsWatcher() error = %v", err)
+	}
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	AssertStringEquals(t, delivered.AccessKeyID, "ASIAEXAMPLE", "AccessKeyID")
+	AssertStringEquals(t, delivered.SessionToken, "session", "SessionToken")
+	AssertStringEquals(t, delivered.LeaseID, "aws/sts/deploy/abc", "LeaseID")
+}
@@ -0,0 +1,367 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const awsJSONContentType = "application/x-amz-json-1.1"
+
+// AWSConfig holds what AWS sources need to call Secrets Manager and Parameter
+// Store. Requests are signed with Signature Version 4.
+type AWSConfig struct {
+	Region      string         // AWS_REGION, or AWS_DEFAULT_REGION
+	Credentials AWSCredentials // AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+
+	// Endpoint replaces https://<service>.<region>.amazonaws.com, e.g. for a
+	// VPC endpoint or LocalStack (AWS_ENDPOINT_URL)
+	Endpoint string
+
+	HTTPClient *http.Client // Default http.DefaultClient
+}
+
+// LoadAWSConfigFromEnv loads the region, credentials and endpoint of AWS
+// sources from the standard AWS environment variables
+func LoadAWSConfigFromEnv() (*AWSConfig, error) {
+	config := &AWSConfig{
+		Region: getEnv("AWS_REGION", getEnv("AWS_DEFAULT_REGION", "")),
+		Credentials: AWSCredentials{
+			AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+			SessionToken:    getEnv("AWS_SESSION_TOKEN", ""),
+		},
+		Endpoint: getEnv("AWS_ENDPOINT_URL", ""),
+	}
+	if err := validateAWSConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// validateAWSConfig checks that all required connection details are present
+func validateAWSConfig(config *AWSConfig) error {
+	if config == nil {
+		return fmt.Errorf("aws config cannot be nil")
+	}
+	if config.Region == "" {
+		return fmt.Errorf("AWS_REGION is required")
+	}
+	if config.Credentials.AccessKeyID == "" || config.Credentials.SecretAccessKey == "" {
+		return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+	}
+	if config.Endpoint != "" {
+		if _, err := url.Parse(config.Endpoint); err != nil {
+			return fmt.Errorf("invalid AWS endpoint %q: %w", config.Endpoint, err)
+		}
+	}
+	return nil
+}
+
+// awsClient calls an AWS JSON 1.1 API such as Secrets Manager or SSM
+type awsClient struct {
+	config   *AWSConfig
+	service  string // Signing name, e.g. "secretsmanager"
+	target   string // X-Amz-Target prefix, e.g. "secretsmanager"
+	endpoint string
+}
+
+func newAWSClient(config *AWSConfig, service, target string) (*awsClient, error) {
+	if err := validateAWSConfig(config); err != nil {
+		return nil, err
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, config.Region)
+	}
+	return &awsClient{config: config, service: service, target: target, endpoint: strings.TrimSuffix(endpoint, "/") + "/"}, nil
+}
+
+// call sends input to the operation and decodes its response into output
+func (c *awsClient) call(ctx context.Context, operation string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", operation, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", operation, err)
+	}
+	req.Header.Set("Content-Type", awsJSONContentType)
+	req.Header.Set("X-Amz-Target", c.target+"."+operation)
+	signAWSRequest(req, body, c.config.Credentials, c.config.Region, c.service, time.Now())
+
+	client := c.config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", operation, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed: %w", operation, parseAWSError(resp))
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(output); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", operation, err)
+	}
+	return nil
+}
+
+// parseAWSError turns an AWS error response into an error, adding the
+// sentinel matching its type
+func parseAWSError(resp *http.Response) error {
+	var body struct {
+		Type         string `json:"__type"`
+		Message      string `json:"message"`
+		MessageUpper string `json:"Message"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	json.Unmarshal(raw, &body)
+
+	// Types may be prefixed with a namespace, e.g. "com.amazonaws...#Type"
+	errorType := body.Type[strings.LastIndex(body.Type, "#")+1:]
+	message := body.Message
+	if message == "" {
+		message = body.MessageUpper
+	}
+	err := fmt.Errorf("aws returned %s: %s: %s", resp.Status, errorType, message)
+
+	var sentinel error
+	switch errorType {
+	case "ResourceNotFoundException", "ParameterNotFound", "ParameterVersionNotFound":
+		sentinel = ErrSecretNotFound
+	case "AccessDeniedException", "UnrecognizedClientException", "InvalidSignatureException", "ExpiredTokenException":
+		sentinel = ErrPermissionDenied
+	case "ThrottlingException", "TooManyRequestsException":
+		sentinel = ErrRateLimited
+	default:
+		return err
+	}
+	return &sentinelError{sentinel: sentinel, err: err}
+}
+
+// signAWSRequest adds a Signature Version 4 Authorization header to req. The
+// host, X-Amz-* and Content-Type headers are signed.
+func signAWSRequest(req *http.Request, body []byte, creds AWSCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsTime converts an AWS timestamp, in seconds since the epoch, to a time
+func awsTime(seconds json.Number) time.Time {
+	value, err := seconds.Float64()
+	if err != nil || value == 0 {
+		return time.Time{}
+	}
+	whole, fraction := math.Modf(value)
+	return time.Unix(int64(whole), int64(fraction*1e9)).UTC()
+}
+
+// SecretsManagerSource reads a secret from AWS Secrets Manager. A secret
+// string holding a JSON object is watched key by key; any other string, or a
+// binary secret, is watched under the key "value".
+type SecretsManagerSource struct {
+	client       *awsClient
+	secretID     string
+	versionStage string
+}
+
+// NewSecretsManagerSource creates a source reading the AWSCURRENT version of
+// secretID, a secret name or ARN
+func NewSecretsManagerSource(config *AWSConfig, secretID string) (*SecretsManagerSource, error) {
+	if secretID == "" {
+		return nil, fmt.Errorf("secret id is required")
+	}
+	client, err := newAWSClient(config, "secretsmanager", "secretsmanager")
+	if err != nil {
+		return nil, err
+	}
+	return &SecretsManagerSource{client: client, secretID: secretID, versionStage: "AWSCURRENT"}, nil
+}
+
+// Fetch reads the secret's current value. Meta.CreatedTime is when that
+// version was created; Secrets Manager versions are IDs, not numbers.
+func (s *SecretsManagerSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	var output struct {
+		SecretString *string     `json:"SecretString"`
+		SecretBinary string      `json:"SecretBinary"` // Base64
+		CreatedDate  json.Number `json:"CreatedDate"`
+	}
+	err := s.client.call(ctx, "GetSecretValue", map[string]string{
+		"SecretId":     s.secretID,
+		"VersionStage": s.versionStage,
+	}, &output)
+	if err != nil {
+		return nil, Meta{}, fmt.Errorf("failed to read secret %s from secrets manager: %w", s.secretID, err)
+	}
+
+	meta := Meta{CreatedTime: awsTime(output.CreatedDate)}
+	if output.SecretString == nil {
+		return map[string]interface{}{"value": output.SecretBinary}, meta, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(*output.SecretString))
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil || data == nil {
+		return map[string]interface{}{"value": *output.SecretString}, meta, nil
+	}
+	return data, meta, nil
+}
+
+// ParameterStoreSource reads parameters from AWS Systems Manager Parameter
+// Store, decrypting SecureString parameters
+type ParameterStoreSource struct {
+	client *awsClient
+	path   string
+}
+
+// NewParameterStoreSource creates a source reading path. A path ending in a
+// slash, e.g. "/myapp/prod/", reads every parameter below it, keyed by its
+// name relative to path. Any other path reads the single parameter, keyed by
+// the last segment of its name.
+func NewParameterStoreSource(config *AWSConfig, path string) (*ParameterStoreSource, error) {
+	if path == "" {
+		return nil, fmt.Errorf("parameter path is required")
+	}
+	client, err := newAWSClient(config, "ssm", "AmazonSSM")
+	if err != nil {
+		return nil, err
+	}
+	return &ParameterStoreSource{client: client, path: path}, nil
+}
+
+// ssmParameter is a parameter in SSM responses
+type ssmParameter struct {
+	Name             string      `json:"Name"`
+	Value            string      `json:"Value"`
+	Version          json.Number `json:"Version"`
+	LastModifiedDate json.Number `json:"LastModifiedDate"`
+}
+
+// Fetch reads the parameters. A single parameter reports its version in
+// Meta; a hierarchy reports when its latest parameter was modified.
+func (s *ParameterStoreSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	if !strings.HasSuffix(s.path, "/") {
+		var output struct {
+			Parameter ssmParameter `json:"Parameter"`
+		}
+		err := s.client.call(ctx, "GetParameter", map[string]interface{}{
+			"Name":           s.path,
+			"WithDecryption": true,
+		}, &output)
+		if err != nil {
+			return nil, Meta{}, fmt.Errorf("failed to read parameter %s: %w", s.path, err)
+		}
+
+		parameter := output.Parameter
+		version, _ := parameter.Version.Int64()
+		key := parameter.Name[strings.LastIndex(parameter.Name, "/")+1:]
+		return map[string]interface{}{key: parameter.Value},
+			Meta{Version: int(version), CreatedTime: awsTime(parameter.LastModifiedDate)}, nil
+	}
+
+	data := map[string]interface{}{}
+	var meta Meta
+	root := s.path
+	if len(root) > 1 {
+		root = strings.TrimSuffix(root, "/")
+	}
+	input := map[string]interface{}{
+		"Path":           root,
+		"Recursive":      true,
+		"WithDecryption": true,
+	}
+	for {
+		var output struct {
+			Parameters []ssmParameter `json:"Parameters"`
+			NextToken  string         `json:"NextToken"`
+		}
+		if err := s.client.call(ctx, "GetParametersByPath", input, &output); err != nil {
+			return nil, Meta{}, fmt.Errorf("failed to read parameters below %s: %w", s.path, err)
+		}
+		for _, parameter := range output.Parameters {
+			data[strings.TrimPrefix(parameter.Name, s.path)] = parameter.Value
+			if modified := awsTime(parameter.LastModifiedDate); modified.After(meta.CreatedTime) {
+				meta.CreatedTime = modified
+			}
+		}
+		if output.NextToken == "" {
+			return data, meta, nil
+		}
+		input["NextToken"] = output.NextToken
+	}
+}
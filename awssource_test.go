@@ -0,0 +1,201 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAWS answers Secrets Manager and SSM JSON requests by X-Amz-Target
+type fakeAWS struct {
+	t         *testing.T
+	responses map[string][]string // Bodies by target, one per call
+	status    int
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		f.t.Errorf("request is not signed: %q", r.Header.Get("Authorization"))
+	}
+	AssertStringEquals(f.t, r.Header.Get("Content-Type"), awsJSONContentType, "Content-Type")
+	io.Copy(io.Discard, r.Body)
+
+	target := r.Header.Get("X-Amz-Target")
+	bodies := f.responses[target]
+	if len(bodies) == 0 {
+		f.t.Errorf("unexpected call to %s", target)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.responses[target] = bodies[1:]
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+	}
+	io.WriteString(w, bodies[0])
+}
+
+func testAWSConfig(endpoint string) *AWSConfig {
+	return &AWSConfig{
+		Region:      "eu-west-1",
+		Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		Endpoint:    endpoint,
+	}
+}
+
+func TestSignAWSRequest(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	AssertNoError(t, err, "NewRequest()")
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signAWSRequest(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	AssertStringEquals(t, req.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, "+
+			"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31", "Authorization")
+	AssertStringEquals(t, req.Header.Get("X-Amz-Date"), "20150830T123600Z", "X-Amz-Date")
+}
+
+func TestSecretsManagerSource_Fetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		status   int
+		want     map[string]interface{}
+		wantErr  error
+	}{
+		{
+			name:     "json object",
+			response: `{"SecretString": "{\"username\":\"app\",\"port\":5432}", "CreatedDate": 1700000000.5}`,
+			want:     map[string]interface{}{"username": "app", "port": json.Number("5432")},
+		},
+		{
+			name:     "plain string",
+			response: `{"SecretString": "hunter2", "CreatedDate": 1700000000}`,
+			want:     map[string]interface{}{"value": "hunter2"},
+		},
+		{
+			name:     "binary",
+			response: `{"SecretBinary": "aHVudGVyMg==", "CreatedDate": 1700000000}`,
+			want:     map[string]interface{}{"value": "aHVudGVyMg=="},
+		},
+		{
+			name:     "not found",
+			response: `{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`,
+			status:   http.StatusBadRequest,
+			wantErr:  ErrSecretNotFound,
+		},
+		{
+			name:     "throttled",
+			response: `{"__type": "ThrottlingException", "message": "Rate exceeded"}`,
+			status:   http.StatusBadRequest,
+			wantErr:  ErrRateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(&fakeAWS{t: t, status: tt.status, responses: map[string][]string{
+				"secretsmanager.GetSecretValue": {tt.response},
+			}})
+			defer server.Close()
+
+			source, err := NewSecretsManagerSource(testAWSConfig(server.URL), "prod/app")
+			AssertNoError(t, err, "NewSecretsManagerSource()")
+
+			data, meta, err := source.Fetch(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Fetch() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			AssertNoError(t, err, "Fetch()")
+			AssertStringEquals(t, mustHash(t, data), mustHash(t, tt.want), "data")
+			if meta.CreatedTime.Unix() != 1700000000 {
+				t.Errorf("CreatedTime = %s, want 2023-11-14T22:13:20Z", meta.CreatedTime)
+			}
+		})
+	}
+}
+
+func TestParameterStoreSource_Fetch(t *testing.T) {
+	t.Run("single parameter", func(t *testing.T) {
+		server := httptest.NewServer(&fakeAWS{t: t, responses: map[string][]string{
+			"AmazonSSM.GetParameter": {`{"Parameter": {"Name": "/myapp/prod/db_password", "Value": "hunter2", "Version": 4, "LastModifiedDate": 1700000000}}`},
+		}})
+		defer server.Close()
+
+		source, err := NewParameterStoreSource(testAWSConfig(server.URL), "/myapp/prod/db_password")
+		AssertNoError(t, err, "NewParameterStoreSource()")
+
+		data, meta, err := source.Fetch(context.Background())
+		AssertNoError(t, err, "Fetch()")
+		AssertStringEquals(t, data["db_password"].(string), "hunter2", "db_password")
+		if meta.Version != 4 {
+			t.Errorf("Version = %d, want 4", meta.Version)
+		}
+	})
+
+	t.Run("hierarchy", func(t *testing.T) {
+		server := httptest.NewServer(&fakeAWS{t: t, responses: map[string][]string{
+			"AmazonSSM.GetParametersByPath": {
+				`{"Parameters": [{"Name": "/myapp/prod/db/password", "Value": "hunter2", "LastModifiedDate": 1700000000}], "NextToken": "page2"}`,
+				`{"Parameters": [{"Name": "/myapp/prod/api_key", "Value": "abc123", "LastModifiedDate": 1700000100}]}`,
+			},
+		}})
+		defer server.Close()
+
+		source, err := NewParameterStoreSource(testAWSConfig(server.URL), "/myapp/prod/")
+		AssertNoError(t, err, "NewParameterStoreSource()")
+
+		data, meta, err := source.Fetch(context.Background())
+		AssertNoError(t, err, "Fetch()")
+		if len(data) != 2 {
+			t.Fatalf("got %d parameters, want 2: %v", len(data), data)
+		}
+		AssertStringEquals(t, data["db/password"].(string), "hunter2", "db/password")
+		AssertStringEquals(t, data["api_key"].(string), "abc123", "api_key")
+		if meta.CreatedTime.Unix() != 1700000100 {
+			t.Errorf("CreatedTime = %s, want the latest modification", meta.CreatedTime)
+		}
+	})
+}
+
+func TestNewAWSSources_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		create  func() error
+		wantErr string
+	}{
+		{name: "nil config", create: func() error { _, err := NewSecretsManagerSource(nil, "app"); return err }, wantErr: "aws config cannot be nil"},
+		{name: "no region", create: func() error {
+			_, err := NewSecretsManagerSource(&AWSConfig{Credentials: AWSCredentials{AccessKeyID: "a", SecretAccessKey: "b"}}, "app")
+			return err
+		}, wantErr: "AWS_REGION is required"},
+		{name: "no credentials", create: func() error { _, err := NewParameterStoreSource(&AWSConfig{Region: "eu-west-1"}, "/app"); return err },
+			wantErr: "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required"},
+		{name: "no secret id", create: func() error { _, err := NewSecretsManagerSource(testAWSConfig(""), ""); return err }, wantErr: "secret id is required"},
+		{name: "no parameter path", create: func() error { _, err := NewParameterStoreSource(testAWSConfig(""), ""); return err }, wantErr: "parameter path is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertError(t, tt.create(), tt.wantErr, "create")
+		})
+	}
+}
+
+// mustHash hashes data to compare maps
+func mustHash(t *testing.T, data map[string]interface{}) string {
+	t.Helper()
+	hash, err := CalculateHash(data)
+	AssertNoError(t, err, "CalculateHash()")
+	return hash
+}
@@ -0,0 +1,75 @@
+package vaultwatcher
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// isBinaryKey reports whether key was marked with WithBinaryKeys
+func (w *Watcher) isBinaryKey(key string) bool {
+	for _, filter := range w.binaryKeys {
+		if filter.Match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// calculateBinaryHashes hashes data like calculateHash and calculateKeyHashes,
+// except that the base64 values of binary keys are decoded as they are
+// streamed into their key hash. The whole hash covers those key hashes in
+// place of the values, so no blob is held in memory twice.
+func (w *Watcher) calculateBinaryHashes(data map[string]interface{}) (string, map[string]string, error) {
+	if data == nil {
+		return "", nil, fmt.Errorf("vault data cannot be nil")
+	}
+
+	hashed := make(map[string]interface{}, len(data))
+	keyHashes := make(map[string]string, len(data))
+	for key, value := range data {
+		h, err := w.hashAlgorithm.newHash()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if blob, ok := value.(string); ok && w.isBinaryKey(key) {
+			if _, err := io.Copy(h, base64.NewDecoder(base64.StdEncoding, strings.NewReader(blob))); err == nil {
+				keyHashes[key] = hex.EncodeToString(h.Sum(nil))
+				hashed[key] = keyHashes[key]
+				continue
+			}
+			// Not base64 after all: hashed like any other value
+			h.Reset()
+		}
+
+		if err := writeCanonicalJSON(h, value); err != nil {
+			return "", nil, fmt.Errorf("failed to marshal key %q: %w", key, err)
+		}
+		keyHashes[key] = hex.EncodeToString(h.Sum(nil))
+		hashed[key] = value
+	}
+
+	hash, err := calculateHash(hashed, w.hashAlgorithm)
+	if err != nil {
+		return "", nil, err
+	}
+	return hash, keyHashes, nil
+}
+
+// diffData returns data without the binary keys when WithoutBinaryDiffs is
+// set, or data itself
+func (w *Watcher) diffData(data map[string]interface{}) map[string]interface{} {
+	if !w.skipBinaryDiffs || len(w.binaryKeys) == 0 || data == nil {
+		return data
+	}
+	diffed := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if !w.isBinaryKey(key) {
+			diffed[key] = value
+		}
+	}
+	return diffed
+}
@@ -0,0 +1,123 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcher_BinaryKeys(t *testing.T) {
+	keystore := bytes.Repeat([]byte{0x00, 0xfe, 0x42, 0x7f}, 1<<18)
+	sum := sha256.Sum256(keystore)
+	keystoreHash := hex.EncodeToString(sum[:])
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{
+		"keystore": base64.StdEncoding.EncodeToString(keystore),
+		"notes":    "not base64!",
+		"password": "one",
+	})
+	changes := make(chan ChangeEvent, 1)
+	var watcher *Watcher
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		event, _ := watcher.LastChange()
+		changes <- event
+		return nil
+	}, WithBinaryKeys(ExactKey("keystore"), ExactKey("notes")), WithJSONPatch(), WithPatchValues(), WithoutBinaryDiffs())
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.initialize(), "initialize()")
+
+	// The blob is hashed decoded, and the secret's hash covers its digest
+	watcher.mu.RLock()
+	keyHashes := watcher.keyHashes
+	watcher.mu.RUnlock()
+	AssertStringEquals(t, keyHashes["keystore"], keystoreHash, "keystore hash")
+	wantNotes, _ := CalculateKeyHashes(map[string]interface{}{"notes": "not base64!"})
+	AssertStringEquals(t, keyHashes["notes"], wantNotes["notes"], "hash of a value that isn't base64")
+	wantHash, _ := CalculateHash(map[string]interface{}{"keystore": keystoreHash, "notes": "not base64!", "password": "one"})
+	AssertStringEquals(t, watcher.GetCurrentHash(), wantHash, "GetCurrentHash()")
+
+	tests := []struct {
+		name        string
+		data        map[string]interface{}
+		wantChanged []string
+		wantPatch   []PatchOperation
+	}{
+		{
+			name: "blob changed",
+			data: map[string]interface{}{
+				"keystore": base64.StdEncoding.EncodeToString(append(keystore, 1)),
+				"notes":    "not base64!",
+				"password": "one",
+			},
+			wantChanged: []string{"keystore"},
+		},
+		{
+			name: "text changed",
+			data: map[string]interface{}{
+				"keystore": base64.StdEncoding.EncodeToString(append(keystore, 1)),
+				"notes":    "not base64!",
+				"password": "two",
+			},
+			wantChanged: []string{"password"},
+			wantPatch:   []PatchOperation{{Op: "replace", Path: "/password", Value: "two"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source.set(tt.data)
+			AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+			select {
+			case event := <-changes:
+				if !reflect.DeepEqual(event.ChangedKeys, tt.wantChanged) {
+					t.Errorf("ChangedKeys = %v, want %v", event.ChangedKeys, tt.wantChanged)
+				}
+				if len(event.Patch) != len(tt.wantPatch) || (len(tt.wantPatch) > 0 && !reflect.DeepEqual(event.Patch, tt.wantPatch)) {
+					t.Errorf("Patch = %+v, want %+v", event.Patch, tt.wantPatch)
+				}
+			default:
+				t.Fatal("onChange was not called")
+			}
+		})
+	}
+
+	_, err = NewWatcher(&VaultConfig{Host: "https://vault.example.com", Path: "secret/data/app", Token: "test-token"}, time.Hour,
+		func() error { return nil }, WithBinaryKeys(ExactKey("keystore")), WithTransitHMAC("transit/keys/watcher"))
+	AssertError(t, err, "WithBinaryKeys can't be combined with WithTransitHMAC", "NewWatcher() with transit")
+}
+
+func BenchmarkWatcher_BinaryKeys(b *testing.B) {
+	data := map[string]interface{}{"keystore": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1, 2, 3}, 2<<20))}
+
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{"json", nil},
+		{"binary", []Option{WithBinaryKeys(ExactKey("keystore"))}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			watcher, err := NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil }, bench.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				// As a check does: key hashes come with the hash when they are free
+				_, keyHashes, err := watcher.calculateHashes(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if keyHashes == nil {
+					if _, err := watcher.calculateKeyHashes(data); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
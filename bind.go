@@ -0,0 +1,176 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// BindOption configures a Binding
+type BindOption func(*Binding)
+
+// WithValidator checks every newly decoded value before it is swapped in.
+// validate receives a pointer to the new struct; an error keeps the previous
+// value and fails the change like an onChange error, so it is retried.
+func WithValidator(validate func(config interface{}) error) BindOption {
+	return func(b *Binding) {
+		b.validate = validate
+	}
+}
+
+// WithUpdateHook calls hook after every swap with pointers to the previous
+// and new structs. previous is nil for the first value.
+func WithUpdateHook(hook func(previous, current interface{})) BindOption {
+	return func(b *Binding) {
+		b.hook = hook
+	}
+}
+
+// Binding keeps a struct decoded from the watched data, see Watcher.Bind
+type Binding struct {
+	target   reflect.Value // Pointer passed to Bind
+	defaults reflect.Value // Copy of the struct when bound; every decode starts from it
+	current  atomic.Value  // Pointer to the latest decoded struct
+	mu       sync.Mutex    // Serializes writes to target
+	validate func(interface{}) error
+	hook     func(previous, current interface{})
+}
+
+// Bind registers a pointer to a struct that is decoded again, with
+// DecodeInto, whenever the data changes. Values set before binding are
+// defaults for missing keys. A bound watcher that is already running
+// decodes right away; otherwise the struct is filled when it starts.
+//
+// The new value is decoded and validated before onChange runs, so the
+// callback sees it; if the callback fails, the previous value is swapped
+// back. Goroutines other than the callback and hook should read it with
+// Load: target is written in place while Load returns immutable snapshots
+// swapped atomically. Bind waits for a check in progress, so it must not be
+// called from onChange.
+func (w *Watcher) Bind(target interface{}, opts ...BindOption) (*Binding, error) {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("bind target must be a non-nil pointer to a struct, got %T", target)
+	}
+	if w.fetchData != nil {
+		return nil, fmt.Errorf("this watcher doesn't read a secret that can be decoded")
+	}
+
+	b := &Binding{target: value, defaults: reflect.New(value.Type().Elem()).Elem()}
+	b.defaults.Set(value.Elem())
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	// A check must not swap in its value between this read and registering
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+	if w.IsStarted() {
+		data, err := w.readData()
+		if err != nil {
+			return nil, err
+		}
+		next, err := b.decode(data)
+		if err != nil {
+			return nil, err
+		}
+		b.swap(next)
+	}
+
+	w.mu.Lock()
+	w.bindings = append(w.bindings, b)
+	w.mu.Unlock()
+
+	return b, nil
+}
+
+// Load returns a pointer to the latest decoded struct, e.g.
+// binding.Load().(*Config), or nil before the first decode. Callers must not
+// modify it.
+func (b *Binding) Load() interface{} {
+	return b.current.Load()
+}
+
+// decode returns a new struct with data decoded over the defaults, validated
+func (b *Binding) decode(data map[string]interface{}) (reflect.Value, error) {
+	next := reflect.New(b.defaults.Type())
+	next.Elem().Set(b.defaults)
+	if err := DecodeInto(data, next.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to bind %s: %w", b.target.Type(), err)
+	}
+	if b.validate != nil {
+		if err := b.validate(next.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("failed to bind %s: invalid configuration: %w", b.target.Type(), err)
+		}
+	}
+	return next, nil
+}
+
+// swap stores next as the current value and copies it into the target
+func (b *Binding) swap(next reflect.Value) {
+	b.mu.Lock()
+	previous := b.current.Load()
+	b.current.Store(next.Interface())
+	b.target.Elem().Set(next.Elem())
+	b.mu.Unlock()
+
+	if b.hook != nil {
+		b.hook(previous, next.Interface())
+	}
+}
+
+// applyBindings decodes data into every binding. All of them are decoded and
+// validated before any is swapped, so they change together or not at all.
+func (w *Watcher) applyBindings(data map[string]interface{}) error {
+	w.mu.RLock()
+	bindings := w.bindings
+	w.mu.RUnlock()
+	if len(bindings) == 0 {
+		return nil
+	}
+
+	updates := make([]reflect.Value, len(bindings))
+	for i, b := range bindings {
+		next, err := b.decode(data)
+		if err != nil {
+			return err
+		}
+		updates[i] = next
+	}
+	for i, b := range bindings {
+		b.swap(updates[i])
+	}
+	return nil
+}
+
+// hasBindings reports whether any struct is bound
+func (w *Watcher) hasBindings() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.bindings) > 0
+}
+
+// bindingValues returns the current value of every binding, so a change that
+// fails after applyBindings can be reverted with restoreBindings
+func (w *Watcher) bindingValues() []interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	values := make([]interface{}, len(w.bindings))
+	for i, b := range w.bindings {
+		values[i] = b.Load()
+	}
+	return values
+}
+
+// restoreBindings swaps the values returned by bindingValues back in
+func (w *Watcher) restoreBindings(values []interface{}) {
+	w.mu.RLock()
+	bindings := w.bindings
+	w.mu.RUnlock()
+	for i, value := range values {
+		if value != nil {
+			bindings[i].swap(reflect.ValueOf(value))
+		}
+	}
+}
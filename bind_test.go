@@ -0,0 +1,124 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Bind_Errors(t *testing.T) {
+	watcher, err := NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	var config decodeDatabase
+	_, err = watcher.Bind(config)
+	AssertError(t, err, "bind target must be a non-nil pointer to a struct, got vaultwatcher.decodeDatabase", "Bind(struct)")
+
+	mounts, err := NewMountWatcher(&VaultConfig{Host: "https://vault.example.com", Path: "sys/mounts", Token: "test-token"},
+		time.Hour, func(MountChange) error { return nil })
+	AssertNoError(t, err, "NewMountWatcher()")
+	_, err = mounts.Bind(&config)
+	AssertError(t, err, "this watcher doesn't read a secret that can be decoded", "Bind(mount watcher)")
+}
+
+func TestWatcher_Bind(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1", "port": "6432"})
+
+	config := decodeDatabase{Port: 5432}
+	var binding *Binding
+	seen := make(chan decodeDatabase, 1)
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error {
+		// The callback sees the new value
+		seen <- *binding.Load().(*decodeDatabase)
+		return nil
+	})
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	hooks := make(chan [2]interface{}, 2)
+	binding, err = watcher.Bind(&config, WithUpdateHook(func(previous, current interface{}) {
+		hooks <- [2]interface{}{previous, current}
+	}))
+	AssertNoError(t, err, "Bind()")
+	if binding.Load() != nil {
+		t.Errorf("Load() before Start = %v, want nil", binding.Load())
+	}
+
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertStringEquals(t, fmt.Sprint(binding.Load()), "&{db-1 6432}", "Load() after Start")
+	first := <-hooks
+	if first[0] != nil {
+		t.Errorf("previous of the first update = %v, want nil", first[0])
+	}
+
+	// The port falls back to its default when the key is removed
+	source.set(map[string]interface{}{"host": "db-2"})
+	select {
+	case got := <-seen:
+		AssertStringEquals(t, fmt.Sprint(got), "{db-2 5432}", "value seen by onChange")
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called")
+	}
+	second := <-hooks
+	AssertStringEquals(t, fmt.Sprint(second[0], second[1]), "&{db-1 6432} &{db-2 5432}", "hook arguments")
+	AssertStringEquals(t, fmt.Sprint(binding.Load()), "&{db-2 5432}", "Load() after the change")
+}
+
+func TestWatcher_Bind_Validator(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1", "port": "6432"})
+
+	called := make(chan struct{}, 1)
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error {
+		called <- struct{}{}
+		return nil
+	})
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	var config decodeDatabase
+	binding, err := watcher.Bind(&config, WithValidator(func(value interface{}) error {
+		if value.(*decodeDatabase).Port == 0 {
+			return fmt.Errorf("port is required")
+		}
+		return nil
+	}))
+	AssertNoError(t, err, "Bind()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	source.set(map[string]interface{}{"host": "db-2"})
+	waitFor(t, time.Second, func() bool { return watcher.Status().Callbacks["onChange"].Failures > 0 }, "rejected change")
+	select {
+	case <-called:
+		t.Fatal("onChange was called for an invalid configuration")
+	default:
+	}
+	AssertStringEquals(t, fmt.Sprint(binding.Load()), "&{db-1 6432}", "Load() after the rejected change")
+	AssertStringEquals(t, watcher.Status().Callbacks["onChange"].LastError,
+		"onChange callback failed: failed to bind *vaultwatcher.decodeDatabase: invalid configuration: port is required", "LastError")
+
+	source.set(map[string]interface{}{"host": "db-2", "port": "7432"})
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called for a valid configuration")
+	}
+	AssertStringEquals(t, fmt.Sprint(binding.Load()), "&{db-2 7432}", "Load() after the valid change")
+}
+
+func TestWatcher_Bind_Running(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	var config decodeDatabase
+	binding, err := watcher.Bind(&config)
+	AssertNoError(t, err, "Bind()")
+	AssertStringEquals(t, config.Host, "db-1", "Host after Bind on a running watcher")
+	AssertStringEquals(t, fmt.Sprint(binding.Load()), "&{db-1 0}", "Load()")
+}
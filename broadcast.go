@@ -0,0 +1,124 @@
+package vaultwatcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultBroadcastBuffer = 16
+
+// Broadcaster is a Notifier that fans change events out to any number of
+// subscribers. It is the building block for streaming events to remote
+// consumers, e.g. from a gRPC ChangeStream server or an SSE handler.
+//
+// Delivery never blocks the watcher: when a subscriber's buffer is full the
+// event is dropped for that subscriber and counted in Dropped.
+type Broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	bufferSize  int
+	closed      bool
+}
+
+// Subscription receives the events published by a Broadcaster
+type Subscription struct {
+	broadcaster *Broadcaster
+	events      chan ChangeEvent
+	paths       map[string]bool
+	dropped     uint64
+	closeOnce   sync.Once
+}
+
+// NewBroadcaster creates a broadcaster whose subscriptions buffer up to bufferSize events.
+// A bufferSize below 1 uses the default of 16.
+func NewBroadcaster(bufferSize int) *Broadcaster {
+	if bufferSize < 1 {
+		bufferSize = defaultBroadcastBuffer
+	}
+	return &Broadcaster{
+		subscribers: make(map[*Subscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe registers a new subscription. When paths are given, only events
+// for those paths are delivered. The subscription must be closed when done.
+func (b *Broadcaster) Subscribe(paths ...string) *Subscription {
+	sub := &Subscription{
+		broadcaster: b,
+		events:      make(chan ChangeEvent, b.bufferSize),
+	}
+	if len(paths) > 0 {
+		sub.paths = make(map[string]bool, len(paths))
+		for _, path := range paths {
+			sub.paths[path] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.closeOnce.Do(func() { close(sub.events) })
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Notify publishes the event to every matching subscription
+func (b *Broadcaster) Notify(ctx context.Context, event ChangeEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if sub.paths != nil && !sub.paths[event.Path] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+	return nil
+}
+
+// Subscribers returns the number of open subscriptions
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// Close closes every subscription. Later subscriptions are closed immediately.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		sub.closeOnce.Do(func() { close(sub.events) })
+	}
+}
+
+// Events returns the channel of events. It is closed when the subscription
+// or its broadcaster is closed.
+func (s *Subscription) Events() <-chan ChangeEvent {
+	return s.events
+}
+
+// Dropped returns how many events were dropped because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close unsubscribes and closes the events channel
+func (s *Subscription) Close() {
+	s.broadcaster.mu.Lock()
+	defer s.broadcaster.mu.Unlock()
+
+	delete(s.broadcaster.subscribers, s)
+	s.closeOnce.Do(func() { close(s.events) })
+}
@@ -0,0 +1,72 @@
+package vaultwatcher
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBroadcaster_FanOut(t *testing.T) {
+	broadcaster := NewBroadcaster(4)
+	defer broadcaster.Close()
+
+	all := broadcaster.Subscribe()
+	defer all.Close()
+	filtered := broadcaster.Subscribe("kv/data/other")
+	defer filtered.Close()
+
+	if broadcaster.Subscribers() != 2 {
+		t.Fatalf("Subscribers() = %d, want 2", broadcaster.Subscribers())
+	}
+
+	AssertNoError(t, broadcaster.Notify(context.Background(), ChangeEvent{Path: "kv/data/app", NewHash: "h1"}), "Notify()")
+	AssertNoError(t, broadcaster.Notify(context.Background(), ChangeEvent{Path: "kv/data/other", NewHash: "h2"}), "Notify()")
+
+	first := <-all.Events()
+	second := <-all.Events()
+	AssertStringEquals(t, first.NewHash, "h1", "first event")
+	AssertStringEquals(t, second.NewHash, "h2", "second event")
+
+	only := <-filtered.Events()
+	AssertStringEquals(t, only.Path, "kv/data/other", "filtered event")
+	select {
+	case event := <-filtered.Events():
+		t.Errorf("filtered subscription received unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestBroadcaster_DropsWhenFull(t *testing.T) {
+	broadcaster := NewBroadcaster(1)
+	defer broadcaster.Close()
+
+	sub := broadcaster.Subscribe()
+	defer sub.Close()
+
+	for i := 0; i < 3; i++ {
+		AssertNoError(t, broadcaster.Notify(context.Background(), ChangeEvent{Path: "kv/data/app"}), "Notify()")
+	}
+	if sub.Dropped() != 2 {
+		t.Errorf("Dropped() = %d, want 2", sub.Dropped())
+	}
+}
+
+func TestBroadcaster_Close(t *testing.T) {
+	broadcaster := NewBroadcaster(0)
+	sub := broadcaster.Subscribe()
+
+	broadcaster.Close()
+	if _, ok := <-sub.Events(); ok {
+		t.Error("Events() should be closed after the broadcaster is closed")
+	}
+	if broadcaster.Subscribers() != 0 {
+		t.Errorf("Subscribers() = %d, want 0", broadcaster.Subscribers())
+	}
+
+	// Closing twice and subscribing after close must not panic
+	sub.Close()
+	late := broadcaster.Subscribe()
+	late.Close()
+	if _, ok := <-late.Events(); ok {
+		t.Error("Events() should be closed for subscriptions made after Close")
+	}
+}
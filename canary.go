@@ -0,0 +1,56 @@
+package vaultwatcher
+
+import "time"
+
+// delayChange reports whether the change to newHash is still within the delay
+// set with WithChangeDelay. The first check seeing a new hash starts the
+// delay and schedules a check for when it ends; a newer change restarts it.
+func (w *Watcher) delayChange(newHash string) bool {
+	if w.changeDelay <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	if w.delayedHash == newHash {
+		waiting := now.Before(w.delayedSince.Add(w.changeDelay))
+		w.mu.Unlock()
+		return waiting
+	}
+	w.delayedHash, w.delayedSince = newHash, now
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go w.checkAfterDelay()
+	return true
+}
+
+// clearDelayedChange forgets the delayed change once the watched data is back
+// at the applied hash, logging it as cancelled if it was never applied
+func (w *Watcher) clearDelayedChange() {
+	w.mu.Lock()
+	cancelled := w.delayedHash != "" && w.delayedHash != w.currentHash
+	w.delayedHash = ""
+	w.mu.Unlock()
+
+	if cancelled {
+		w.logger.Info("Change was reverted within the change delay and won't be applied", "path", w.vaultConfig.Path)
+	}
+}
+
+// checkAfterDelay runs in a goroutine and checks again once the change delay
+// has passed. The check waits for one already running, so the change is only
+// applied once.
+func (w *Watcher) checkAfterDelay() {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(w.changeDelay)
+	defer timer.Stop()
+
+	select {
+	case <-w.ctx.Done():
+		return
+	case <-timer.C:
+	}
+	w.check()
+}
@@ -0,0 +1,67 @@
+package vaultwatcher
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatcher_ChangeDelay(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one"})
+
+	var called int32
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		atomic.AddInt32(&called, 1)
+		return nil
+	}, WithChangeDelay(100*time.Millisecond))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+	hash := watcher.GetCurrentHash()
+
+	// Reverted within the delay, so never applied
+	source.set(map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.check(), "check() after the change")
+	AssertBoolEquals(t, watcher.HasPendingChange(), true, "HasPendingChange() during the delay")
+	source.set(map[string]interface{}{"password": "one"})
+	AssertNoError(t, watcher.check(), "check() after the revert")
+	AssertBoolEquals(t, watcher.HasPendingChange(), false, "HasPendingChange() after the revert")
+
+	time.Sleep(150 * time.Millisecond)
+	AssertBoolEquals(t, atomic.LoadInt32(&called) == 0, true, "onChange not called for the reverted change")
+	AssertStringEquals(t, watcher.GetCurrentHash(), hash, "hash after the reverted change")
+
+	// Applied by the check scheduled for the end of the delay
+	source.set(map[string]interface{}{"password": "three"})
+	AssertNoError(t, watcher.check(), "check() after the second change")
+	AssertBoolEquals(t, atomic.LoadInt32(&called) == 0, true, "onChange not called during the delay")
+	waitFor(t, time.Second, func() bool { return atomic.LoadInt32(&called) == 1 }, "delayed change applied")
+	AssertBoolEquals(t, watcher.HasPendingChange(), false, "HasPendingChange() after the change was applied")
+}
+
+func TestWatcher_ChangeDelayOverlappingCheck(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one"})
+
+	var called int32
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&called, 1)
+		return nil
+	}, WithChangeDelay(20*time.Millisecond))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	// The check scheduled for the end of the delay is still in the callback
+	source.set(map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.check(), "check() after the change")
+	time.Sleep(30 * time.Millisecond)
+	AssertNoError(t, watcher.check(), "check() during the delayed callback")
+
+	time.Sleep(100 * time.Millisecond)
+	if called := atomic.LoadInt32(&called); called != 1 {
+		t.Errorf("onChange called %d times, want 1", called)
+	}
+}
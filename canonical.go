@@ -0,0 +1,284 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// canonicalBufferSize is how much canonicalEncoder buffers before writing
+const canonicalBufferSize = 32 << 10
+
+// canonicalEncoder writes a value as the exact bytes json.Marshal would
+// produce, straight into a writer such as a hash.Hash, so large secrets are
+// hashed without holding their whole encoding in memory. Values it doesn't
+// know are encoded with json.Marshal.
+type canonicalEncoder struct {
+	w    io.Writer
+	buf  []byte
+	keys []string // Reused for sorting map keys
+}
+
+var canonicalEncoders = sync.Pool{
+	New: func() interface{} {
+		return &canonicalEncoder{buf: make([]byte, 0, canonicalBufferSize)}
+	},
+}
+
+// writeCanonicalJSON writes the JSON encoding of v to w
+func writeCanonicalJSON(w io.Writer, v interface{}) error {
+	e := canonicalEncoders.Get().(*canonicalEncoder)
+	defer canonicalEncoders.Put(e)
+
+	e.w, e.buf = w, e.buf[:0]
+	defer func() { e.w = nil }()
+	if err := e.encode(v); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// flush writes out the buffer
+func (e *canonicalEncoder) flush() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	return err
+}
+
+// grow flushes the buffer if it is full
+func (e *canonicalEncoder) grow() error {
+	if len(e.buf) < canonicalBufferSize {
+		return nil
+	}
+	return e.flush()
+}
+
+func (e *canonicalEncoder) encode(v interface{}) error {
+	if err := e.grow(); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		e.buf = append(e.buf, "null"...)
+	case bool:
+		e.buf = strconv.AppendBool(e.buf, v)
+	case string:
+		return e.encodeString(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return e.marshal(v)
+		}
+		e.buf = appendJSONFloat(e.buf, v)
+	case int:
+		e.buf = strconv.AppendInt(e.buf, int64(v), 10)
+	case int64:
+		e.buf = strconv.AppendInt(e.buf, v, 10)
+	case json.Number:
+		if v == "" {
+			e.buf = append(e.buf, '0')
+		} else if validJSONNumber(string(v)) {
+			e.buf = append(e.buf, v...)
+		} else {
+			return e.marshal(v)
+		}
+	case map[string]interface{}:
+		return e.encodeMap(v)
+	case []interface{}:
+		if v == nil {
+			e.buf = append(e.buf, "null"...)
+			return nil
+		}
+		e.buf = append(e.buf, '[')
+		for i, item := range v {
+			if i > 0 {
+				e.buf = append(e.buf, ',')
+			}
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+		e.buf = append(e.buf, ']')
+	default:
+		return e.marshal(v)
+	}
+	return nil
+}
+
+// encodeMap writes m with its keys sorted, as json.Marshal does
+func (e *canonicalEncoder) encodeMap(m map[string]interface{}) error {
+	if m == nil {
+		e.buf = append(e.buf, "null"...)
+		return nil
+	}
+
+	// Nested maps append their keys after this map's
+	start := len(e.keys)
+	for key := range m {
+		e.keys = append(e.keys, key)
+	}
+	keys := e.keys[start:]
+	sort.Strings(keys)
+	defer func() { e.keys = e.keys[:start] }()
+
+	e.buf = append(e.buf, '{')
+	for i, key := range keys {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		if err := e.encodeString(key); err != nil {
+			return err
+		}
+		e.buf = append(e.buf, ':')
+		if err := e.encode(m[key]); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+// encodeString writes s quoted and escaped as json.Marshal does. Long strings
+// are copied into the buffer in pieces, so multi-line certificates and
+// embedded JSON are streamed like any other string.
+func (e *canonicalEncoder) encodeString(s string) error {
+	e.buf = append(e.buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if len(e.buf)+i-start >= canonicalBufferSize {
+			e.buf = append(e.buf, s[start:i]...)
+			start = i
+			if err := e.flush(); err != nil {
+				return err
+			}
+		}
+
+		if b := s[i]; b < utf8.RuneSelf {
+			if jsonEscapes[b] != "" {
+				e.buf = append(e.buf, s[start:i]...)
+				e.buf = append(e.buf, jsonEscapes[b]...)
+				start = i + 1
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		var escape string
+		switch {
+		case r == utf8.RuneError && size == 1:
+			escape = jsonInvalidUTF8
+		case r == '\u2028' || r == '\u2029':
+			escape = jsonSeparators[r-'\u2028']
+		}
+		if escape != "" {
+			e.buf = append(e.buf, s[start:i]...)
+			e.buf = append(e.buf, escape...)
+			start = i + size
+		}
+		i += size
+	}
+	e.buf = append(e.buf, s[start:]...)
+	e.buf = append(e.buf, '"')
+	return nil
+}
+
+// marshal writes v encoded by json.Marshal
+func (e *canonicalEncoder) marshal(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.buf = append(e.buf, data...)
+	return nil
+}
+
+// jsonEscapes holds how json.Marshal writes the ASCII characters it escapes:
+// control characters, quotes, backslashes and HTML characters
+var jsonEscapes = func() (escapes [utf8.RuneSelf]string) {
+	for b := 0; b < utf8.RuneSelf; b++ {
+		if quoted := jsonQuoted(string(rune(b))); quoted != string(rune(b)) {
+			escapes[b] = quoted
+		}
+	}
+	return escapes
+}()
+
+// jsonInvalidUTF8 is how json.Marshal writes an invalid UTF-8 byte, which
+// depends on the Go version
+var jsonInvalidUTF8 = jsonQuoted("\xff")
+
+// jsonSeparators holds how json.Marshal writes the line and paragraph separators
+var jsonSeparators = [2]string{jsonQuoted("\u2028"), jsonQuoted("\u2029")}
+
+// jsonQuoted returns s encoded by json.Marshal, without the quotes
+func jsonQuoted(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+// appendJSONFloat appends f formatted as json.Marshal does
+func appendJSONFloat(buf []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	buf = strconv.AppendFloat(buf, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf
+}
+
+// validJSONNumber reports whether s is a JSON number:
+// -?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?
+func validJSONNumber(s string) bool {
+	digits := func() bool {
+		n := 0
+		for len(s) > 0 && s[0] >= '0' && s[0] <= '9' {
+			s = s[1:]
+			n++
+		}
+		return n > 0
+	}
+
+	if len(s) > 0 && s[0] == '-' {
+		s = s[1:]
+	}
+	switch {
+	case len(s) == 0:
+		return false
+	case s[0] == '0':
+		s = s[1:]
+	case !digits():
+		return false
+	}
+	if len(s) > 0 && s[0] == '.' {
+		s = s[1:]
+		if !digits() {
+			return false
+		}
+	}
+	if len(s) > 0 && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if !digits() {
+			return false
+		}
+	}
+	return len(s) == 0
+}
@@ -0,0 +1,156 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"nil", nil},
+		{"bools", []interface{}{true, false}},
+		{"plain string", "s3cr3t-value_with.dots"},
+		{"escaped string", "quote\" backslash\\ newline\n tab\t nul\x00 bell\x07 <html> & \b\f\r"},
+		{"unicode", "pässwörd ключ 密码 🔑"},
+		{"separators", "line paragraph "},
+		{"invalid utf-8", "bad\xff\xfe bytes"},
+		{"empty string", ""},
+		{"long string", strings.Repeat("abcdefgh", canonicalBufferSize/4)},
+		{"long escaped string", strings.Repeat("line \"one\"\n<two> & \u2028 bad\xff\n", canonicalBufferSize/8)},
+		{"pem bundle", pemBundle(100)},
+		{"floats", []interface{}{0.0, math.Copysign(0, -1), 1.5, -2.25, 1e20, 1e21, 1e-6, 1e-7, 123456789.123, 5e-324, math.MaxFloat64}},
+		{"ints", []interface{}{0, -1, math.MaxInt64, int64(math.MinInt64)}},
+		{"numbers", []interface{}{json.Number("42"), json.Number("-0.5e+10"), json.Number("1E3"), json.Number("")}},
+		{"other types", []interface{}{map[string]string{"b": "<2>", "a": "1"}, []string{"x"}, uint8(7), float32(0.1)}},
+		{"nested", map[string]interface{}{
+			"z": map[string]interface{}{"b": []interface{}{1.0, "two", nil}, "a": map[string]interface{}{}},
+			"a": []interface{}{map[string]interface{}{"y": true, "x": false}},
+			"<": "html key",
+			"m": map[string]interface{}(nil),
+			"l": []interface{}(nil),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json.Marshal(tt.value)
+			AssertNoError(t, err, "json.Marshal()")
+
+			var got bytes.Buffer
+			AssertNoError(t, writeCanonicalJSON(&got, tt.value), "writeCanonicalJSON()")
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("writeCanonicalJSON() = %.200s, want %.200s", got.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestWriteCanonicalJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"NaN", map[string]interface{}{"a": math.NaN()}},
+		{"infinity", []interface{}{math.Inf(1)}},
+		{"invalid number", json.Number("1.2.3")},
+		{"unsupported type", map[string]interface{}{"c": make(chan int)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Failing values are left to json.Marshal, so its error is returned
+			_, want := json.Marshal(tt.value)
+			var got bytes.Buffer
+			AssertError(t, writeCanonicalJSON(&got, tt.value), want.Error(), "writeCanonicalJSON()")
+		})
+	}
+}
+
+func TestValidJSONNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"0", true},
+		{"-12.5e-3", true},
+		{"1E+9", true},
+		{"", false},
+		{"-", false},
+		{"01", false},
+		{"1.", false},
+		{".5", false},
+		{"1e", false},
+		{"0x1F", false},
+	}
+
+	for _, tt := range tests {
+		AssertBoolEquals(t, validJSONNumber(tt.number), tt.want, fmt.Sprintf("validJSONNumber(%q)", tt.number))
+	}
+}
+
+// pemBundle returns n PEM certificates, with newlines like real ones
+func pemBundle(n int) string {
+	var bundle strings.Builder
+	for i := 0; i < n; i++ {
+		bundle.WriteString("-----BEGIN CERTIFICATE-----\n")
+		for line := 0; line < 20; line++ {
+			bundle.WriteString(strings.Repeat(fmt.Sprintf("MIIDdzCCAl+gAwIBAgIE%04d", i), 3)[:64])
+			bundle.WriteByte('\n')
+		}
+		bundle.WriteString("-----END CERTIFICATE-----\n")
+	}
+	return bundle.String()
+}
+
+// largeSecret returns a secret of about size bytes: bundles of multi-line
+// PEM certificates and embedded JSON documents, which both need escaping
+func largeSecret(size int) map[string]interface{} {
+	data := map[string]interface{}{}
+	for i := 0; size > 0; i++ {
+		bundle := pemBundle(16)
+		document := strings.Repeat(fmt.Sprintf(`{"id":%d,"name":"service-%d","tags":["a","b"]},`, i, i), 512)
+		data[fmt.Sprintf("cert_%d", i)] = bundle
+		data[fmt.Sprintf("config_%d", i)] = document
+		data[fmt.Sprintf("meta_%d", i)] = map[string]interface{}{"serial": json.Number(fmt.Sprint(i)), "ca": i%2 == 0}
+		size -= len(bundle) + len(document)
+	}
+	return data
+}
+
+// BenchmarkCalculateHash compares hashing a 4 MiB secret by marshaling it
+// first with streaming it into the hash. Run with -benchmem:
+//
+//	go test -run XXX -bench CalculateHash -benchmem
+func BenchmarkCalculateHash(b *testing.B) {
+	data := largeSecret(4 << 20)
+
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			jsonBytes, err := json.Marshal(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sum := sha256.Sum256(jsonBytes)
+			_ = hex.EncodeToString(sum[:])
+		}
+	})
+
+	b.Run("streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := CalculateHash(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
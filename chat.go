@@ -0,0 +1,164 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SlackConfig holds the configuration for a SlackNotifier
+type SlackConfig struct {
+	WebhookURL string       // Slack incoming webhook URL
+	Channel    string       // Optional channel override, e.g. "#ops"
+	Username   string       // Optional bot username override
+	HTTPClient *http.Client // Optional custom HTTP client
+}
+
+// SlackNotifier posts change and health messages to a Slack incoming webhook
+type SlackNotifier struct {
+	config  SlackConfig
+	webhook *WebhookNotifier
+}
+
+// NewSlackNotifier creates a notifier for a Slack incoming webhook
+func NewSlackNotifier(config SlackConfig) (*SlackNotifier, error) {
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("slack webhook URL is required")
+	}
+
+	webhook, err := NewWebhookNotifier(WebhookConfig{
+		URLs:       []string{config.WebhookURL},
+		HTTPClient: config.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SlackNotifier{
+		config:  config,
+		webhook: webhook,
+	}, nil
+}
+
+// Notify posts a message describing the change
+func (n *SlackNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	text := fmt.Sprintf(":key: Vault secret changed at `%s`", event.Path)
+	if len(event.ChangedKeys) > 0 {
+		text += fmt.Sprintf("\nChanged keys: `%s`", strings.Join(event.ChangedKeys, "`, `"))
+	}
+	text += fmt.Sprintf("\nHash: `%s` → `%s`", shortHash(event.OldHash), shortHash(event.NewHash))
+
+	return n.post(ctx, text)
+}
+
+// NotifyHealth posts a message when the watcher becomes unhealthy or recovers
+func (n *SlackNotifier) NotifyHealth(ctx context.Context, event HealthEvent) error {
+	if event.Healthy {
+		return n.post(ctx, fmt.Sprintf(":white_check_mark: Vault watcher for `%s` has recovered", event.Path))
+	}
+	return n.post(ctx, fmt.Sprintf(":rotating_light: Vault watcher for `%s` is unhealthy after %d consecutive failures: %s",
+		event.Path, event.ConsecutiveFailures, event.Error))
+}
+
+func (n *SlackNotifier) post(ctx context.Context, text string) error {
+	message := map[string]string{"text": text}
+	if n.config.Channel != "" {
+		message["channel"] = n.config.Channel
+	}
+	if n.config.Username != "" {
+		message["username"] = n.config.Username
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+	if err := n.webhook.deliver(ctx, n.config.WebhookURL, body); err != nil {
+		return fmt.Errorf("slack webhook: %w", err)
+	}
+	return nil
+}
+
+// TeamsConfig holds the configuration for a TeamsNotifier
+type TeamsConfig struct {
+	WebhookURL string       // Microsoft Teams incoming webhook URL
+	HTTPClient *http.Client // Optional custom HTTP client
+}
+
+// TeamsNotifier posts change and health messages to a Microsoft Teams incoming webhook
+type TeamsNotifier struct {
+	config  TeamsConfig
+	webhook *WebhookNotifier
+}
+
+// NewTeamsNotifier creates a notifier for a Microsoft Teams incoming webhook
+func NewTeamsNotifier(config TeamsConfig) (*TeamsNotifier, error) {
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("teams webhook URL is required")
+	}
+
+	webhook, err := NewWebhookNotifier(WebhookConfig{
+		URLs:       []string{config.WebhookURL},
+		HTTPClient: config.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TeamsNotifier{
+		config:  config,
+		webhook: webhook,
+	}, nil
+}
+
+// Notify posts a message card describing the change
+func (n *TeamsNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	text := fmt.Sprintf("Hash changed from `%s` to `%s`.", shortHash(event.OldHash), shortHash(event.NewHash))
+	if len(event.ChangedKeys) > 0 {
+		text += fmt.Sprintf("<br>Changed keys: `%s`", strings.Join(event.ChangedKeys, "`, `"))
+	}
+
+	return n.post(ctx, "0078D7", fmt.Sprintf("Vault secret changed at %s", event.Path), text)
+}
+
+// NotifyHealth posts a message card when the watcher becomes unhealthy or recovers
+func (n *TeamsNotifier) NotifyHealth(ctx context.Context, event HealthEvent) error {
+	if event.Healthy {
+		return n.post(ctx, "2EB886", fmt.Sprintf("Vault watcher for %s has recovered", event.Path), "Checks are succeeding again.")
+	}
+	return n.post(ctx, "D00000", fmt.Sprintf("Vault watcher for %s is unhealthy", event.Path),
+		fmt.Sprintf("%d consecutive failures. Last error: %s", event.ConsecutiveFailures, event.Error))
+}
+
+func (n *TeamsNotifier) post(ctx context.Context, color, title, text string) error {
+	card := map[string]string{
+		"@type":      "MessageCard",
+		"@context":   "http://schema.org/extensions",
+		"summary":    title,
+		"themeColor": color,
+		"title":      title,
+		"text":       text,
+	}
+
+	body, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("failed to marshal teams message: %w", err)
+	}
+	if err := n.webhook.deliver(ctx, n.config.WebhookURL, body); err != nil {
+		return fmt.Errorf("teams webhook: %w", err)
+	}
+	return nil
+}
+
+// shortHash abbreviates a hash for display in chat messages
+func shortHash(hash string) string {
+	if hash == "" {
+		return "none"
+	}
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
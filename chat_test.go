@@ -0,0 +1,85 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureServer records the JSON body of every request it receives
+func captureServer(t *testing.T) (*httptest.Server, *[]map[string]string) {
+	var messages []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("invalid JSON body: %v", err)
+		}
+		messages = append(messages, message)
+	}))
+	return server, &messages
+}
+
+func TestSlackNotifier(t *testing.T) {
+	if _, err := NewSlackNotifier(SlackConfig{}); err == nil {
+		t.Error("NewSlackNotifier() expected error for missing webhook URL")
+	}
+
+	server, messages := captureServer(t)
+	defer server.Close()
+
+	notifier, err := NewSlackNotifier(SlackConfig{WebhookURL: server.URL, Channel: "#ops"})
+	if err != nil {
+		t.Fatalf("NewSlackNotifier() error = %v", err)
+	}
+
+	event := ChangeEvent{Path: "kv/data/app", OldHash: "0123456789abcdef", NewHash: "fedcba9876543210", ChangedKeys: []string{"api_key", "db_url"}}
+	AssertNoError(t, notifier.Notify(context.Background(), event), "Notify()")
+	AssertNoError(t, notifier.NotifyHealth(context.Background(), HealthEvent{Path: "kv/data/app", ConsecutiveFailures: 3, Error: "permission denied"}), "NotifyHealth()")
+	AssertNoError(t, notifier.NotifyHealth(context.Background(), HealthEvent{Path: "kv/data/app", Healthy: true}), "NotifyHealth()")
+
+	if len(*messages) != 3 {
+		t.Fatalf("received %d messages, want 3", len(*messages))
+	}
+	change := (*messages)[0]
+	AssertStringEquals(t, change["channel"], "#ops", "channel")
+	for _, want := range []string{"kv/data/app", "`api_key`, `db_url`", "0123456789ab"} {
+		if !strings.Contains(change["text"], want) {
+			t.Errorf("change message %q does not contain %q", change["text"], want)
+		}
+	}
+	if !strings.Contains((*messages)[1]["text"], "unhealthy after 3 consecutive failures") {
+		t.Errorf("unhealthy message = %q", (*messages)[1]["text"])
+	}
+	if !strings.Contains((*messages)[2]["text"], "recovered") {
+		t.Errorf("recovered message = %q", (*messages)[2]["text"])
+	}
+}
+
+func TestTeamsNotifier(t *testing.T) {
+	if _, err := NewTeamsNotifier(TeamsConfig{}); err == nil {
+		t.Error("NewTeamsNotifier() expected error for missing webhook URL")
+	}
+
+	server, messages := captureServer(t)
+	defer server.Close()
+
+	notifier, err := NewTeamsNotifier(TeamsConfig{WebhookURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewTeamsNotifier() error = %v", err)
+	}
+
+	AssertNoError(t, notifier.Notify(context.Background(), ChangeEvent{Path: "kv/data/app", ChangedKeys: []string{"api_key"}}), "Notify()")
+	AssertNoError(t, notifier.NotifyHealth(context.Background(), HealthEvent{Path: "kv/data/app", ConsecutiveFailures: 5, Error: "timeout"}), "NotifyHealth()")
+
+	if len(*messages) != 2 {
+		t.Fatalf("received %d messages, want 2", len(*messages))
+	}
+	AssertStringEquals(t, (*messages)[0]["@type"], "MessageCard", "@type")
+	AssertStringEquals(t, (*messages)[0]["title"], "Vault secret changed at kv/data/app", "title")
+	if !strings.Contains((*messages)[1]["text"], "5 consecutive failures") {
+		t.Errorf("unhealthy card text = %q", (*messages)[1]["text"])
+	}
+}
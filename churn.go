@@ -0,0 +1,82 @@
+package vaultwatcher
+
+import (
+	"context"
+	"time"
+)
+
+// ChurnEvent reports that a path is changing more often than the configured
+// churn limit, which can indicate unauthorized or runaway writes to Vault.
+// Active is false once the rate has dropped back under the limit.
+type ChurnEvent struct {
+	Path      string        `json:"path"`
+	Active    bool          `json:"active"`
+	Changes   int           `json:"changes"`
+	Limit     int           `json:"limit"`
+	Window    time.Duration `json:"window"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// ChurnNotifier is implemented by notifiers that also want churn alerts.
+// Notifiers registered with WithNotifier are checked for it automatically.
+type ChurnNotifier interface {
+	NotifyChurn(ctx context.Context, event ChurnEvent) error
+}
+
+// updateChurn records an applied change and emits a churn event when the
+// number of changes within the churn window crosses the limit in either direction
+func (w *Watcher) updateChurn(changed bool) {
+	if w.churnLimit == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	w.mu.Lock()
+	if changed {
+		w.changeTimes = append(w.changeTimes, now)
+	}
+	cutoff := now.Add(-w.churnWindow)
+	recent := w.changeTimes[:0]
+	for _, t := range w.changeTimes {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	w.changeTimes = recent
+
+	var event *ChurnEvent
+	switch {
+	case !w.churning && len(recent) > w.churnLimit:
+		w.churning = true
+		event = &ChurnEvent{Active: true}
+	case w.churning && len(recent) <= w.churnLimit:
+		w.churning = false
+		event = &ChurnEvent{Active: false}
+	}
+	if event != nil {
+		event.Changes = len(recent)
+	}
+	w.mu.Unlock()
+
+	if event != nil {
+		event.Path = w.vaultConfig.Path
+		event.Limit = w.churnLimit
+		event.Window = w.churnWindow
+		event.Timestamp = now.UTC()
+		w.notifyChurn(*event)
+	}
+}
+
+// notifyChurn delivers the churn event to every notifier implementing ChurnNotifier
+func (w *Watcher) notifyChurn(event ChurnEvent) {
+	for _, notifier := range w.notifiers {
+		churnNotifier, ok := notifier.(ChurnNotifier)
+		if !ok {
+			continue
+		}
+		if err := churnNotifier.NotifyChurn(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying vault churn", "error", w.redactError(err))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package vaultwatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// churnRecorder collects the churn events it is notified about
+type churnRecorder struct {
+	events []ChurnEvent
+}
+
+func (r *churnRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *churnRecorder) NotifyChurn(ctx context.Context, event ChurnEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestWatcher_ChurnDetection(t *testing.T) {
+	recorder := &churnRecorder{}
+	window := 50 * time.Millisecond
+	watcher, err := NewWatcher(TestVaultConfig(), time.Second, func() error { return nil },
+		WithNotifier(recorder),
+		WithChurnLimit(2, window),
+	)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	watcher.updateChurn(true)
+	watcher.updateChurn(true)
+	if len(recorder.events) != 0 {
+		t.Fatalf("got %d churn events at the limit, want 0", len(recorder.events))
+	}
+
+	watcher.updateChurn(true)
+	if len(recorder.events) != 1 {
+		t.Fatalf("got %d churn events above the limit, want 1", len(recorder.events))
+	}
+	active := recorder.events[0]
+	AssertBoolEquals(t, active.Active, true, "churn event active")
+	if active.Changes != 3 || active.Limit != 2 || active.Window != window {
+		t.Errorf("churn event = %+v, want 3 changes, limit 2, window %v", active, window)
+	}
+
+	// Further changes while churning don't produce duplicate events
+	watcher.updateChurn(true)
+	if len(recorder.events) != 1 {
+		t.Fatalf("got %d churn events while churning, want 1", len(recorder.events))
+	}
+
+	time.Sleep(2 * window)
+	watcher.updateChurn(false)
+	if len(recorder.events) != 2 {
+		t.Fatalf("got %d churn events after settling, want 2", len(recorder.events))
+	}
+	AssertBoolEquals(t, recorder.events[1].Active, false, "churn settled event active")
+}
+
+func TestWatcher_ChurnDisabledByDefault(t *testing.T) {
+	recorder := &churnRecorder{}
+	watcher, err := NewWatcher(TestVaultConfig(), time.Second, func() error { return nil }, WithNotifier(recorder))
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	for i := 0; i < 100; i++ {
+		watcher.updateChurn(true)
+	}
+	if len(recorder.events) != 0 || len(watcher.changeTimes) != 0 {
+		t.Errorf("churn tracking should be disabled without WithChurnLimit")
+	}
+}
@@ -0,0 +1,264 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCloudWatchNamespace     = "VaultWatcher"
+	defaultCloudWatchFlushInterval = time.Minute
+	cloudWatchBatchSize            = 20 // Metrics per PutMetricData request
+)
+
+// CloudWatchConfig configures a CloudWatchSink
+type CloudWatchConfig struct {
+	Namespace     string            // Default "VaultWatcher"
+	Dimensions    map[string]string // Added to every metric, e.g. {"Service": "billing-api"}
+	FlushInterval time.Duration     // How often metrics are published (default 1m)
+}
+
+// CloudWatchSink is a MetricsSink publishing to Amazon CloudWatch, so fleets
+// on AWS can alert with CloudWatch alarms. Metrics are aggregated in memory
+// and published with PutMetricData every FlushInterval: counters as sums with
+// unit Count, timings as statistic sets in Milliseconds. Tags become
+// dimensions.
+type CloudWatchSink struct {
+	client        *awsClient
+	namespace     string
+	dimensions    map[string]string
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	metrics map[string]*cloudWatchMetric // By name and dimensions
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// cloudWatchMetric aggregates the values of one metric since the last flush
+type cloudWatchMetric struct {
+	name       string
+	dimensions map[string]string
+	unit       string
+	count      float64 // Samples for timings
+	sum        float64
+	min, max   float64
+}
+
+// NewCloudWatchSink creates a sink publishing with awsConfig and starts
+// flushing in the background. Register it with WithMetricsSink and Close it
+// on shutdown to publish the last metrics. The credentials need
+// cloudwatch:PutMetricData.
+func NewCloudWatchSink(awsConfig *AWSConfig, config CloudWatchConfig) (*CloudWatchSink, error) {
+	client, err := newAWSClient(awsConfig, "monitoring", "")
+	if err != nil {
+		return nil, err
+	}
+	if config.Namespace == "" {
+		config.Namespace = defaultCloudWatchNamespace
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultCloudWatchFlushInterval
+	}
+
+	s := &CloudWatchSink{
+		client:        client,
+		namespace:     config.Namespace,
+		dimensions:    config.Dimensions,
+		flushInterval: config.FlushInterval,
+		metrics:       map[string]*cloudWatchMetric{},
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Count adds value to a counter
+func (s *CloudWatchSink) Count(name string, value int64, tags map[string]string) {
+	s.record(name, "Count", float64(value), tags)
+}
+
+// Timing records a duration in milliseconds
+func (s *CloudWatchSink) Timing(name string, duration time.Duration, tags map[string]string) {
+	s.record(name, "Milliseconds", float64(duration)/float64(time.Millisecond), tags)
+}
+
+func (s *CloudWatchSink) record(name, unit string, value float64, tags map[string]string) {
+	dimensions := make(map[string]string, len(s.dimensions)+len(tags))
+	for key, value := range s.dimensions {
+		dimensions[key] = value
+	}
+	for key, value := range tags {
+		dimensions[key] = value
+	}
+	key := name + "\x00" + encodeDimensions(dimensions)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metric := s.metrics[key]
+	if metric == nil {
+		metric = &cloudWatchMetric{name: name, dimensions: dimensions, unit: unit, min: value, max: value}
+		s.metrics[key] = metric
+	}
+	metric.count++
+	metric.sum += value
+	if value < metric.min {
+		metric.min = value
+	}
+	if value > metric.max {
+		metric.max = value
+	}
+}
+
+// encodeDimensions returns dimensions in a stable form, to key metrics by them
+func encodeDimensions(dimensions map[string]string) string {
+	keys := make([]string, 0, len(dimensions))
+	for key := range dimensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key + "=" + dimensions[key] + "\x00")
+	}
+	return b.String()
+}
+
+// run flushes every flush interval until Close
+func (s *CloudWatchSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Flush(context.Background()); err != nil {
+				slog.Error("Error publishing cloudwatch metrics", "error", err)
+			}
+		}
+	}
+}
+
+// Close stops the background flushing and publishes what is left
+func (s *CloudWatchSink) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.Flush(context.Background())
+}
+
+// Flush publishes the metrics aggregated since the last flush. Metrics that
+// fail to publish are dropped, so a long outage doesn't grow the buffer.
+func (s *CloudWatchSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	metrics := make([]*cloudWatchMetric, 0, len(s.metrics))
+	for _, metric := range s.metrics {
+		metrics = append(metrics, metric)
+	}
+	s.metrics = map[string]*cloudWatchMetric{}
+	s.mu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].name != metrics[j].name {
+			return metrics[i].name < metrics[j].name
+		}
+		return encodeDimensions(metrics[i].dimensions) < encodeDimensions(metrics[j].dimensions)
+	})
+
+	now := time.Now().UTC()
+	for start := 0; start < len(metrics); start += cloudWatchBatchSize {
+		end := start + cloudWatchBatchSize
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+		if err := s.putMetricData(ctx, metrics[start:end], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putMetricData publishes one batch with the CloudWatch query API
+func (s *CloudWatchSink) putMetricData(ctx context.Context, metrics []*cloudWatchMetric, now time.Time) error {
+	form := url.Values{
+		"Action":    {"PutMetricData"},
+		"Version":   {"2010-08-01"},
+		"Namespace": {s.namespace},
+	}
+	for i, metric := range metrics {
+		prefix := "MetricData.member." + strconv.Itoa(i+1) + "."
+		form.Set(prefix+"MetricName", metric.name)
+		form.Set(prefix+"Unit", metric.unit)
+		form.Set(prefix+"Timestamp", now.Format(time.RFC3339))
+		if metric.unit == "Count" {
+			form.Set(prefix+"Value", formatCloudWatchValue(metric.sum))
+		} else {
+			form.Set(prefix+"StatisticValues.SampleCount", formatCloudWatchValue(metric.count))
+			form.Set(prefix+"StatisticValues.Sum", formatCloudWatchValue(metric.sum))
+			form.Set(prefix+"StatisticValues.Minimum", formatCloudWatchValue(metric.min))
+			form.Set(prefix+"StatisticValues.Maximum", formatCloudWatchValue(metric.max))
+		}
+
+		names := make([]string, 0, len(metric.dimensions))
+		for name := range metric.dimensions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for j, name := range names {
+			dimension := prefix + "Dimensions.member." + strconv.Itoa(j+1) + "."
+			form.Set(dimension+"Name", name)
+			form.Set(dimension+"Value", metric.dimensions[name])
+		}
+	}
+
+	body := []byte(form.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.client.endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create PutMetricData request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signAWSRequest(req, body, s.client.config.Credentials, s.client.config.Region, s.client.service, time.Now())
+
+	httpClient := s.client.config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call PutMetricData: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error struct {
+				Code    string `xml:"Code"`
+				Message string `xml:"Message"`
+			} `xml:"Error"`
+		}
+		xml.Unmarshal(raw, &body)
+		return fmt.Errorf("PutMetricData failed: aws returned %s: %s: %s", resp.Status, body.Error.Code, body.Error.Message)
+	}
+	return nil
+}
+
+func formatCloudWatchValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,83 @@
+package vaultwatcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudWatchSink_Flush(t *testing.T) {
+	var forms []url.Values
+	var authorization string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		forms = append(forms, form)
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			io.WriteString(w, `<ErrorResponse><Error><Code>AccessDenied</Code><Message>not allowed</Message></Error></ErrorResponse>`)
+		}
+	}))
+	defer server.Close()
+
+	awsConfig := &AWSConfig{Region: "eu-west-1", Endpoint: server.URL,
+		Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}}
+	sink, err := NewCloudWatchSink(awsConfig, CloudWatchConfig{Dimensions: map[string]string{"Service": "billing"}, FlushInterval: time.Hour})
+	AssertNoError(t, err, "NewCloudWatchSink()")
+
+	tags := map[string]string{"path": "secret/data/app"}
+	sink.Count("checks", 1, tags)
+	sink.Count("checks", 1, tags)
+	sink.Timing("callback.duration", 20*time.Millisecond, map[string]string{"path": "secret/data/app", "callback": "onChange"})
+	sink.Timing("callback.duration", 40*time.Millisecond, map[string]string{"path": "secret/data/app", "callback": "onChange"})
+	AssertNoError(t, sink.Flush(context.Background()), "Flush()")
+
+	if len(forms) != 1 {
+		t.Fatalf("requests = %d, want 1", len(forms))
+	}
+	form := forms[0]
+	AssertBoolEquals(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/"), true, "signed request")
+	AssertBoolEquals(t, strings.Contains(authorization, "/eu-west-1/monitoring/aws4_request"), true, "signing scope")
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "Action", want: "PutMetricData"},
+		{field: "Namespace", want: "VaultWatcher"},
+		{field: "MetricData.member.1.MetricName", want: "callback.duration"},
+		{field: "MetricData.member.1.Unit", want: "Milliseconds"},
+		{field: "MetricData.member.1.StatisticValues.SampleCount", want: "2"},
+		{field: "MetricData.member.1.StatisticValues.Sum", want: "60"},
+		{field: "MetricData.member.1.StatisticValues.Minimum", want: "20"},
+		{field: "MetricData.member.1.StatisticValues.Maximum", want: "40"},
+		{field: "MetricData.member.1.Dimensions.member.1.Name", want: "Service"},
+		{field: "MetricData.member.1.Dimensions.member.2.Name", want: "callback"},
+		{field: "MetricData.member.2.MetricName", want: "checks"},
+		{field: "MetricData.member.2.Unit", want: "Count"},
+		{field: "MetricData.member.2.Value", want: "2"},
+		{field: "MetricData.member.2.Dimensions.member.2.Value", want: "secret/data/app"},
+	}
+	for _, tt := range tests {
+		AssertStringEquals(t, form.Get(tt.field), tt.want, tt.field)
+	}
+
+	// Nothing recorded, nothing sent
+	AssertNoError(t, sink.Flush(context.Background()), "Flush() without metrics")
+	AssertBoolEquals(t, len(forms) == 1, true, "no request without metrics")
+
+	status = http.StatusForbidden
+	sink.Count("changes", 1, tags)
+	AssertError(t, sink.Close(), "PutMetricData failed: aws returned 403 Forbidden: AccessDenied: not allowed", "Close()")
+}
+
+func TestNewCloudWatchSink_Errors(t *testing.T) {
+	_, err := NewCloudWatchSink(&AWSConfig{Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}}, CloudWatchConfig{})
+	AssertError(t, err, "AWS_REGION is required", "NewCloudWatchSink() without a region")
+}
@@ -0,0 +1,224 @@
+// Command vault-watcher runs the watcher as a sidecar container. It renders
+// the secret at VAULT_PATH to files on a volume shared with the main
+// container and, on every change, can signal the main container's process
+// to reload them.
+//
+//	vault-watcher sidecar -render /vault/secrets/db.env=/etc/templates/db.env.tmpl -signal HUP -process nginx
+//	vault-watcher healthcheck -url http://127.0.0.1:8090/readyz
+//
+// VAULT_HOST, VAULT_PATH and VAULT_TOKEN configure the connection.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "sidecar":
+		err = sidecar(os.Args[2:])
+	case "healthcheck":
+		err = healthcheck(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "vault-watcher: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: vault-watcher sidecar [flags]\n       vault-watcher healthcheck [-url URL]")
+	os.Exit(2)
+}
+
+// renderFlags collects -render destination[=template] flags
+type renderFlags []vaultwatcher.FileTemplate
+
+func (r *renderFlags) String() string {
+	destinations := make([]string, len(*r))
+	for i, file := range *r {
+		destinations[i] = file.Destination
+	}
+	return strings.Join(destinations, ",")
+}
+
+func (r *renderFlags) Set(value string) error {
+	destination, templateFile, _ := strings.Cut(value, "=")
+	file := vaultwatcher.FileTemplate{Destination: destination}
+	if templateFile != "" {
+		content, err := os.ReadFile(templateFile)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		file.Template = string(content)
+	}
+	*r = append(*r, file)
+	return nil
+}
+
+func sidecar(args []string) error {
+	flags := flag.NewFlagSet("sidecar", flag.ExitOnError)
+	var files renderFlags
+	flags.Var(&files, "render", "render the secret to `destination[=template]`, as JSON without a template; repeatable")
+	interval := flags.Duration("interval", 30*time.Second, "how often to check for changes")
+	mode := flags.String("mode", "0600", "permissions of rendered files, in octal")
+	signalName := flags.String("signal", "", "signal sent on every change, e.g. HUP")
+	process := flags.String("process", "", "name of the process to signal; needs shareProcessNamespace")
+	pidFile := flags.String("pid-file", "", "file holding the PID of the process to signal")
+	listen := flags.String("listen", "", "address serving /healthz and /readyz, e.g. :8090")
+	flags.Parse(args)
+
+	if len(files) == 0 {
+		return fmt.Errorf("at least one -render flag is required")
+	}
+	perm, err := strconv.ParseUint(*mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid -mode %q: %w", *mode, err)
+	}
+	for i := range files {
+		files[i].Mode = os.FileMode(perm)
+	}
+
+	var sig os.Signal
+	if *signalName != "" {
+		if sig = parseSignal(*signalName); sig == nil {
+			return fmt.Errorf("unknown signal %q", *signalName)
+		}
+		if *process == "" && *pidFile == "" {
+			return fmt.Errorf("-signal needs -process or -pid-file")
+		}
+	}
+
+	config, err := vaultwatcher.LoadVaultConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	onChange := func() error {
+		if sig == nil {
+			return nil
+		}
+		return signalProcess("/proc", *process, *pidFile, sig)
+	}
+	watcher, err := vaultwatcher.NewWatcher(config, *interval, onChange, vaultwatcher.WithFileTemplates(files...))
+	if err != nil {
+		return err
+	}
+
+	if *listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/healthz", vaultwatcher.HealthzHandler(watcher))
+		mux.Handle("/readyz", vaultwatcher.ReadyzHandler(watcher))
+		server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintf(os.Stderr, "vault-watcher: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+		defer server.Close()
+	}
+
+	if err := watcher.Start(); err != nil {
+		return err
+	}
+	defer watcher.Stop()
+	fmt.Printf("Rendering %s to %s every %v\n", config.Path, files.String(), *interval)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	return nil
+}
+
+func healthcheck(args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	url := flags.String("url", "http://127.0.0.1:8090/readyz", "probe endpoint of the sidecar")
+	timeout := flags.Duration("timeout", 5*time.Second, "how long to wait for the probe")
+	flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	return vaultwatcher.ProbeHealth(ctx, *url)
+}
+
+// signalProcess sends sig to the process in pidFile, or to every process
+// named name, found in procDir
+func signalProcess(procDir, name, pidFile string, sig os.Signal) error {
+	var pids []int
+	if pidFile != "" {
+		content, err := os.ReadFile(pidFile)
+		if err != nil {
+			return fmt.Errorf("failed to read pid file: %w", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return fmt.Errorf("invalid pid file %s: %w", pidFile, err)
+		}
+		pids = append(pids, pid)
+	} else {
+		var err error
+		if pids, err = findProcesses(procDir, name); err != nil {
+			return err
+		}
+	}
+
+	for _, pid := range pids {
+		process, err := os.FindProcess(pid)
+		if err == nil {
+			err = process.Signal(sig)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to signal process %d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// findProcesses returns the PIDs of the processes named name, other than
+// this one
+func findProcesses(procDir, name string) ([]int, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "comm"))
+		if err != nil {
+			// Exited meanwhile
+			continue
+		}
+		if strings.TrimSpace(string(comm)) == name {
+			pids = append(pids, pid)
+		}
+	}
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("no process named %q; is shareProcessNamespace enabled?", name)
+	}
+	return pids, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+)
+
+func TestRenderFlags(t *testing.T) {
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "db.env.tmpl")
+	vaultwatcher.AssertNoError(t, os.WriteFile(templateFile, []byte("DB_PASSWORD={{ .password }}\n"), 0600), "WriteFile()")
+
+	var files renderFlags
+	vaultwatcher.AssertNoError(t, files.Set("/vault/secrets/db.env="+templateFile), "Set(with template)")
+	vaultwatcher.AssertNoError(t, files.Set("/vault/secrets/app.json"), "Set(json)")
+	vaultwatcher.AssertError(t, files.Set("/vault/secrets/x="+filepath.Join(dir, "missing")), "failed to read template: open "+filepath.Join(dir, "missing")+": no such file or directory", "Set(missing template)")
+
+	vaultwatcher.AssertStringEquals(t, files.String(), "/vault/secrets/db.env,/vault/secrets/app.json", "String()")
+	vaultwatcher.AssertStringEquals(t, files[0].Template, "DB_PASSWORD={{ .password }}\n", "template")
+	vaultwatcher.AssertStringEquals(t, files[1].Template, "", "json template")
+}
+
+func TestFindProcesses(t *testing.T) {
+	proc := t.TempDir()
+	for pid, comm := range map[string]string{"12": "nginx\n", "13": "nginx\n", "40": "sh\n", "self": "vault-watcher\n"} {
+		vaultwatcher.AssertNoError(t, os.MkdirAll(filepath.Join(proc, pid), 0700), "MkdirAll()")
+		vaultwatcher.AssertNoError(t, os.WriteFile(filepath.Join(proc, pid, "comm"), []byte(comm), 0600), "WriteFile()")
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "nginx", want: "[12 13]"},
+		{name: "sh", want: "[40]"},
+		{name: "envoy", wantErr: `no process named "envoy"; is shareProcessNamespace enabled?`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pids, err := findProcesses(proc, tt.name)
+			if tt.wantErr != "" {
+				vaultwatcher.AssertError(t, err, tt.wantErr, "findProcesses()")
+				return
+			}
+			vaultwatcher.AssertNoError(t, err, "findProcesses()")
+			vaultwatcher.AssertStringEquals(t, fmt.Sprint(pids), tt.want, "findProcesses()")
+		})
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"TERM", "sigterm", "INT"} {
+		vaultwatcher.AssertBoolEquals(t, parseSignal(name) != nil, true, "parseSignal("+name+")")
+	}
+	vaultwatcher.AssertBoolEquals(t, parseSignal("BOGUS") == nil, true, "parseSignal(BOGUS)")
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// parseSignal returns the signal named name; only the portable ones exist here
+func parseSignal(name string) os.Signal {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "INT":
+		return os.Interrupt
+	case "TERM":
+		return syscall.SIGTERM
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// parseSignal returns the signal named name, e.g. "HUP" or "SIGUSR1"
+func parseSignal(name string) os.Signal {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "HUP":
+		return syscall.SIGHUP
+	case "INT":
+		return syscall.SIGINT
+	case "QUIT":
+		return syscall.SIGQUIT
+	case "TERM":
+		return syscall.SIGTERM
+	case "USR1":
+		return syscall.SIGUSR1
+	case "USR2":
+		return syscall.SIGUSR2
+	case "WINCH":
+		return syscall.SIGWINCH
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CompositeLayer is one source of a CompositeWatcher
+type CompositeLayer struct {
+	Name     string // Identifies the layer in errors (default "layer <index>")
+	Source   SecretSource
+	Optional bool // Skip the layer while it doesn't exist instead of failing the check
+}
+
+// CompositeWatcher merges several sources into one config map, e.g. defaults
+// from a file, settings from Consul and secrets from Vault, and calls
+// onChange once whenever the merged view changes.
+//
+// Layers are listed from lowest to highest precedence: a key in a later layer
+// overrides the same key in earlier ones. Nested maps are merged key by key;
+// any other value, including lists, is replaced as a whole.
+type CompositeWatcher struct {
+	*Watcher
+	layers   []CompositeLayer
+	onChange func(map[string]interface{}) error
+
+	mu             sync.Mutex
+	data           map[string]interface{} // Merged view as of the last processed change
+	fetched        map[string]interface{} // Merged view of the last fetch
+	initialFetched bool
+}
+
+// NewCompositeWatcher creates a watcher for the merged view of layers
+// name: Identifies the merged view in events, logs and state keys
+// checkInterval: How often to check the layers for changes
+// onChange: Callback invoked with the new merged view
+// opts: Optional settings; those talking to Vault can't be used, give them to NewVaultSource instead
+func NewCompositeWatcher(name string, layers []CompositeLayer, checkInterval time.Duration, onChange func(map[string]interface{}) error, opts ...Option) (*CompositeWatcher, error) {
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("at least one layer is required")
+	}
+	if onChange == nil {
+		return nil, fmt.Errorf("onChange callback cannot be nil")
+	}
+
+	cw := &CompositeWatcher{layers: make([]CompositeLayer, len(layers)), onChange: onChange}
+	for i, layer := range layers {
+		if layer.Source == nil {
+			return nil, fmt.Errorf("layer %d has no source", i)
+		}
+		if layer.Name == "" {
+			layer.Name = fmt.Sprintf("layer %d", i)
+		}
+		cw.layers[i] = layer
+	}
+
+	watcher, err := NewSourceWatcher(name, compositeSource{cw: cw}, checkInterval, cw.handleChange, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cw.Watcher = watcher
+
+	return cw, nil
+}
+
+// Data returns a copy of the merged view as of the last processed change
+func (cw *CompositeWatcher) Data() map[string]interface{} {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return mergeLayer(nil, cw.data)
+}
+
+// DecodeInto decodes the merged view as of the last processed change into
+// out, see DecodeInto
+func (cw *CompositeWatcher) DecodeInto(out interface{}) error {
+	return DecodeInto(cw.Data(), out)
+}
+
+// handleChange is the Watcher callback, called when the merged view changed
+func (cw *CompositeWatcher) handleChange() error {
+	cw.mu.Lock()
+	fetched := cw.fetched
+	cw.mu.Unlock()
+
+	if err := cw.onChange(mergeLayer(nil, fetched)); err != nil {
+		return err
+	}
+
+	cw.mu.Lock()
+	cw.data = fetched
+	cw.mu.Unlock()
+
+	return nil
+}
+
+// compositeSource fetches and merges the layers of a CompositeWatcher
+type compositeSource struct {
+	cw *CompositeWatcher
+}
+
+// Fetch reads every layer in order and merges them
+func (s compositeSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	cw := s.cw
+	merged := map[string]interface{}{}
+	for _, layer := range cw.layers {
+		data, _, err := layer.Source.Fetch(ctx)
+		if err != nil {
+			if layer.Optional && errors.Is(err, ErrSecretNotFound) {
+				continue
+			}
+			return nil, Meta{}, fmt.Errorf("failed to read %s: %w", layer.Name, err)
+		}
+		merged = mergeLayer(merged, data)
+	}
+
+	cw.mu.Lock()
+	cw.fetched = merged
+	if !cw.initialFetched {
+		// First read when the watcher starts
+		cw.data = merged
+		cw.initialFetched = true
+	}
+	cw.mu.Unlock()
+
+	return merged, Meta{}, nil
+}
+
+// Changes forwards the change signals of every layer that watches natively.
+// It is closed once all of them are, right away if no layer watches.
+func (s compositeSource) Changes(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	for _, layer := range s.cw.layers {
+		source, ok := layer.Source.(ChangeNotifyingSource)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(layerChanges <-chan struct{}) {
+			defer wg.Done()
+			for range layerChanges {
+				notifyChange(changes)
+			}
+		}(source.Changes(ctx))
+	}
+	go func() {
+		wg.Wait()
+		close(changes)
+	}()
+	return changes
+}
+
+// mergeLayer returns a copy of base with layer merged over it. Nested maps
+// are merged recursively and copied, so neither argument is modified.
+func mergeLayer(base, layer map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(layer))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range layer {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			merged[key] = value
+			continue
+		}
+		baseNested, _ := merged[key].(map[string]interface{})
+		merged[key] = mergeLayer(baseNested, nested)
+	}
+	return merged
+}
@@ -0,0 +1,151 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// notifyingSource is a fakeSource that signals when set
+type notifyingSource struct {
+	fakeSource
+	changes chan struct{}
+}
+
+func (s *notifyingSource) Changes(ctx context.Context) <-chan struct{} {
+	return s.changes
+}
+
+func TestNewCompositeWatcher_Errors(t *testing.T) {
+	onChange := func(map[string]interface{}) error { return nil }
+
+	tests := []struct {
+		name     string
+		layers   []CompositeLayer
+		onChange func(map[string]interface{}) error
+		opts     []Option
+		wantErr  string
+	}{
+		{name: "no layers", onChange: onChange, wantErr: "at least one layer is required"},
+		{name: "no source", layers: []CompositeLayer{{Source: &fakeSource{}}, {Name: "vault"}}, onChange: onChange, wantErr: "layer 1 has no source"},
+		{name: "no callback", layers: []CompositeLayer{{Source: &fakeSource{}}}, wantErr: "onChange callback cannot be nil"},
+		{name: "vault option", layers: []CompositeLayer{{Source: &fakeSource{}}}, onChange: onChange, opts: []Option{WithPinnedVersion(1)},
+			wantErr: "WithPinnedVersion needs vault and can't be used with a custom source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCompositeWatcher("app", tt.layers, time.Hour, tt.onChange, tt.opts...)
+			AssertError(t, err, tt.wantErr, "NewCompositeWatcher()")
+		})
+	}
+}
+
+func TestMergeLayer(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		layer string
+		want  string
+	}{
+		{name: "override", base: `{"a":1,"b":2}`, layer: `{"b":3,"c":4}`, want: `{"a":1,"b":3,"c":4}`},
+		{name: "nested maps merge", base: `{"db":{"host":"a","port":1}}`, layer: `{"db":{"host":"b"}}`, want: `{"db":{"host":"b","port":1}}`},
+		{name: "lists replace", base: `{"hosts":["a","b"]}`, layer: `{"hosts":["c"]}`, want: `{"hosts":["c"]}`},
+		{name: "map replaces scalar", base: `{"db":"url"}`, layer: `{"db":{"host":"b"}}`, want: `{"db":{"host":"b"}}`},
+		{name: "scalar replaces map", base: `{"db":{"host":"a"}}`, layer: `{"db":null}`, want: `{"db":null}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := parseJSONObject([]byte(tt.base))
+			AssertNoError(t, err, "parse base")
+			layer, err := parseJSONObject([]byte(tt.layer))
+			AssertNoError(t, err, "parse layer")
+			before := fmt.Sprint(base)
+
+			assertJSONEquals(t, mergeLayer(base, layer), tt.want)
+			AssertStringEquals(t, fmt.Sprint(base), before, "base after merge")
+		})
+	}
+}
+
+func TestCompositeWatcher_MergesLayers(t *testing.T) {
+	defaults := &fakeSource{}
+	defaults.set(map[string]interface{}{"log_level": "info", "db": map[string]interface{}{"host": "localhost", "port": 5432}})
+	overrides := &fakeSource{}
+	overrides.set(map[string]interface{}{"db": map[string]interface{}{"host": "db.internal"}})
+
+	changes := make(chan map[string]interface{}, 1)
+	watcher, err := NewCompositeWatcher("app", []CompositeLayer{
+		{Name: "defaults", Source: defaults},
+		{Name: "overrides", Source: overrides},
+	}, 10*time.Millisecond, func(data map[string]interface{}) error {
+		changes <- data
+		return nil
+	})
+	AssertNoError(t, err, "NewCompositeWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	assertJSONEquals(t, watcher.Data(), `{"log_level":"info","db":{"host":"db.internal","port":5432}}`)
+
+	// A key shadowed by a higher layer doesn't change the merged view
+	defaults.set(map[string]interface{}{"log_level": "info", "db": map[string]interface{}{"host": "127.0.0.1", "port": 5432}})
+	select {
+	case data := <-changes:
+		t.Fatalf("onChange called for a shadowed key: %v", data)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	overrides.set(map[string]interface{}{"db": map[string]interface{}{"host": "db.internal", "port": 6432}})
+	select {
+	case data := <-changes:
+		assertJSONEquals(t, data, `{"log_level":"info","db":{"host":"db.internal","port":6432}}`)
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called")
+	}
+	waitFor(t, time.Second, func() bool { return fmt.Sprint(watcher.Data()["db"]) == "map[host:db.internal port:6432]" }, "Data() after the change")
+}
+
+func TestCompositeWatcher_OptionalLayer(t *testing.T) {
+	base := &fakeSource{}
+	base.set(map[string]interface{}{"key": "base"})
+	local := &fakeSource{err: fmt.Errorf("failed to read local.env: %w", ErrSecretNotFound)}
+
+	layers := []CompositeLayer{{Source: base}, {Name: "local", Source: local}}
+	watcher, err := NewCompositeWatcher("app", layers, time.Hour, func(map[string]interface{}) error { return nil })
+	AssertNoError(t, err, "NewCompositeWatcher()")
+	AssertError(t, watcher.Start(), "failed to fetch initial vault data: failed to read local: failed to read local.env: secret not found", "Start()")
+
+	layers[1].Optional = true
+	watcher, err = NewCompositeWatcher("app", layers, time.Hour, func(map[string]interface{}) error { return nil })
+	AssertNoError(t, err, "NewCompositeWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+	assertJSONEquals(t, watcher.Data(), `{"key":"base"}`)
+}
+
+func TestCompositeWatcher_LayerChangeSignals(t *testing.T) {
+	polled := &fakeSource{}
+	polled.set(map[string]interface{}{"a": "1"})
+	watched := &notifyingSource{changes: make(chan struct{}, 1)}
+	watched.set(map[string]interface{}{"b": "1"})
+
+	changed := make(chan struct{}, 1)
+	watcher, err := NewCompositeWatcher("app", []CompositeLayer{{Source: polled}, {Source: watched}}, time.Hour, func(map[string]interface{}) error {
+		changed <- struct{}{}
+		return nil
+	})
+	AssertNoError(t, err, "NewCompositeWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	watched.set(map[string]interface{}{"b": "2"})
+	watched.changes <- struct{}{}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called after a layer signalled a change")
+	}
+}
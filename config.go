@@ -0,0 +1,122 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const defaultConfigInterval = 30 * time.Second
+
+// FileConfig is a watcher configuration file in JSON, e.g.
+//
+//	{
+//	  "host": "https://vault.example.com:8200",
+//	  "paths": ["kv/data/myapp/db", "kv/data/myapp/cache"],
+//	  "interval": "30s",
+//	  "log_level": "info"
+//	}
+//
+// host and token fall back to VAULT_HOST and VAULT_TOKEN, so the token
+// doesn't have to be stored in the file. interval defaults to 30s. Without
+// log_level the logger's handler decides which levels are logged.
+type FileConfig struct {
+	Host     string
+	Token    string
+	Paths    []string
+	Interval time.Duration
+	LogLevel string // debug, info, warn or error; applied by ConfigReloader
+}
+
+// fileConfigJSON is the on-disk form of FileConfig
+type fileConfigJSON struct {
+	Host     string   `json:"host"`
+	Token    string   `json:"token"`
+	Paths    []string `json:"paths"`
+	Interval string   `json:"interval"`
+	LogLevel string   `json:"log_level"`
+}
+
+// LoadConfigFile reads a watcher configuration file
+func LoadConfigFile(path string) (*FileConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseFileConfig(content)
+}
+
+// VaultConfig returns the connection details for NewWatcherGroup
+func (c *FileConfig) VaultConfig() *VaultConfig {
+	return &VaultConfig{Host: c.Host, Token: c.Token}
+}
+
+// logLevel returns the level of log_level, or slog.LevelDebug when it is empty
+func (c *FileConfig) logLevel() (slog.Level, error) {
+	if c.LogLevel == "" {
+		return slog.LevelDebug, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return 0, fmt.Errorf("invalid log_level %q: use debug, info, warn or error", c.LogLevel)
+	}
+	return level, nil
+}
+
+func parseFileConfig(content []byte) (*FileConfig, error) {
+	var raw fileConfigJSON
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	config := &FileConfig{
+		Host:     raw.Host,
+		Token:    raw.Token,
+		Paths:    raw.Paths,
+		Interval: defaultConfigInterval,
+		LogLevel: raw.LogLevel,
+	}
+	if config.Host == "" {
+		config.Host = getEnv("VAULT_HOST", "")
+	}
+	if config.Token == "" {
+		config.Token = getEnv("VAULT_TOKEN", "")
+	}
+	if raw.Interval != "" {
+		interval, err := time.ParseDuration(raw.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", raw.Interval, err)
+		}
+		config.Interval = interval
+	}
+	if _, err := config.logLevel(); err != nil {
+		return nil, err
+	}
+
+	if config.Host == "" {
+		return nil, fmt.Errorf("host is required in the config file or VAULT_HOST")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("token is required in the config file or VAULT_TOKEN")
+	}
+	if len(config.Paths) == 0 {
+		return nil, fmt.Errorf("at least one path is required")
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	seen := make(map[string]bool, len(config.Paths))
+	for _, path := range config.Paths {
+		if path == "" {
+			return nil, fmt.Errorf("paths cannot be empty")
+		}
+		if seen[path] {
+			return nil, fmt.Errorf("path %q is listed twice", path)
+		}
+		seen[path] = true
+	}
+
+	return config, nil
+}
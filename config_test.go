@@ -0,0 +1,87 @@
+package vaultwatcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, dir, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "watcher.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Setenv("VAULT_HOST", "")
+	t.Setenv("VAULT_TOKEN", "env-token")
+
+	path := writeConfigFile(t, t.TempDir(), `{
+		"host": "https://vault.example.com",
+		"paths": ["kv/data/a", "kv/data/b"],
+		"interval": "10s",
+		"log_level": "debug"
+	}`)
+
+	config, err := LoadConfigFile(path)
+	AssertNoError(t, err, "LoadConfigFile()")
+
+	want := &FileConfig{
+		Host:     "https://vault.example.com",
+		Token:    "env-token",
+		Paths:    []string{"kv/data/a", "kv/data/b"},
+		Interval: 10 * time.Second,
+		LogLevel: "debug",
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("LoadConfigFile() = %+v, want %+v", config, want)
+	}
+	AssertStringEquals(t, config.VaultConfig().Token, "env-token", "VaultConfig().Token")
+}
+
+func TestLoadConfigFile_Validation(t *testing.T) {
+	t.Setenv("VAULT_HOST", "")
+	t.Setenv("VAULT_TOKEN", "")
+
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"invalid json", `{"host":`, "failed to parse config file: unexpected end of JSON input"},
+		{"missing host", `{"token": "t", "paths": ["a"]}`, "host is required in the config file or VAULT_HOST"},
+		{"missing token", `{"host": "h", "paths": ["a"]}`, "token is required in the config file or VAULT_TOKEN"},
+		{"no paths", `{"host": "h", "token": "t"}`, "at least one path is required"},
+		{"invalid log level", `{"host": "h", "token": "t", "paths": ["a"], "log_level": "verbose"}`, `invalid log_level "verbose": use debug, info, warn or error`},
+		{"empty path", `{"host": "h", "token": "t", "paths": [""]}`, "paths cannot be empty"},
+		{"duplicate path", `{"host": "h", "token": "t", "paths": ["a", "a"]}`, `path "a" is listed twice`},
+		{"bad interval", `{"host": "h", "token": "t", "paths": ["a"], "interval": "soon"}`, `invalid interval "soon": time: invalid duration "soon"`},
+		{"negative interval", `{"host": "h", "token": "t", "paths": ["a"], "interval": "-1s"}`, "interval must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFile(writeConfigFile(t, t.TempDir(), tt.content))
+			AssertError(t, err, tt.errMsg, "LoadConfigFile()")
+		})
+	}
+}
+
+func TestLoadConfigFile_Defaults(t *testing.T) {
+	t.Setenv("VAULT_HOST", "https://env.example.com")
+	t.Setenv("VAULT_TOKEN", "env-token")
+
+	config, err := LoadConfigFile(writeConfigFile(t, t.TempDir(), `{"paths": ["kv/data/a"]}`))
+	AssertNoError(t, err, "LoadConfigFile()")
+
+	AssertStringEquals(t, config.Host, "https://env.example.com", "Host")
+	if config.Interval != defaultConfigInterval {
+		t.Errorf("Interval = %v, want %v", config.Interval, defaultConfigInterval)
+	}
+}
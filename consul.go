@@ -0,0 +1,58 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConsulToken is an ACL token issued by the Consul secrets engine
+type ConsulToken struct {
+	Token     string
+	Accessor  string
+	Local     bool
+	Namespace string // Consul Enterprise namespace, empty otherwise
+	LeaseID   string
+	Expires   time.Time // When the lease expires unless renewed
+}
+
+// ParseConsulToken extracts the ACL token from a secret issued by consul/creds/<role>
+func ParseConsulToken(secret *DynamicSecret) (ConsulToken, error) {
+	if secret == nil {
+		return ConsulToken{}, fmt.Errorf("secret cannot be nil")
+	}
+
+	token := ConsulToken{
+		Token:     secret.Get("token"),
+		Accessor:  secret.Get("accessor"),
+		Local:     secret.Get("local") == "true",
+		Namespace: secret.Get("consul_namespace"),
+		LeaseID:   secret.LeaseID,
+		Expires:   secret.ExpiresAt(),
+	}
+	if token.Token == "" {
+		return ConsulToken{}, fmt.Errorf("secret at %s does not contain a consul token", secret.Path)
+	}
+
+	return token, nil
+}
+
+// NewConsulTokenWatcher creates a watcher delivering Consul ACL tokens from the
+// Consul secrets engine, e.g. "consul/creds/web". The lease is renewed while
+// possible and a new token is passed to onRotate before the old one expires.
+func NewConsulTokenWatcher(vaultConfig *VaultConfig, onRotate func(ConsulToken) error, opts ...DynamicSecretOption) (*DynamicSecretWatcher, error) {
+	if onRotate == nil {
+		return nil, fmt.Errorf("onRotate callback cannot be nil")
+	}
+	if vaultConfig != nil && !strings.Contains(vaultConfig.Path, "/creds/") {
+		return nil, fmt.Errorf("consul token path must look like <mount>/creds/<role>, got %q", vaultConfig.Path)
+	}
+
+	return NewDynamicSecretWatcher(vaultConfig, func(secret *DynamicSecret) error {
+		token, err := ParseConsulToken(secret)
+		if err != nil {
+			return err
+		}
+		return onRotate(token)
+	}, opts...)
+}
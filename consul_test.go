@@ -0,0 +1,78 @@
+package vaultwatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseConsulToken(t *testing.T) {
+	secret := &DynamicSecret{
+		Path:          "consul/creds/web",
+		LeaseID:       "consul/creds/web/abc",
+		LeaseDuration: time.Hour,
+		RenewedAt:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Data: map[string]interface{}{
+			"token":    "b5d1a3c8-0000-0000-0000-000000000000",
+			"accessor": "acc-1",
+			"local":    true,
+		},
+	}
+
+	token, err := ParseConsulToken(secret)
+	AssertNoError(t, err, "ParseConsulToken()")
+	AssertStringEquals(t, token.Token, "b5d1a3c8-0000-0000-0000-000000000000", "Token")
+	AssertStringEquals(t, token.Accessor, "acc-1", "Accessor")
+	AssertBoolEquals(t, token.Local, true, "Local")
+	AssertStringEquals(t, token.LeaseID, "consul/creds/web/abc", "LeaseID")
+
+	_, err = ParseConsulToken(&DynamicSecret{Path: "consul/creds/web", Data: map[string]interface{}{}})
+	AssertError(t, err, "secret at consul/creds/web does not contain a consul token", "ParseConsulToken(empty)")
+
+	_, err = ParseConsulToken(nil)
+	AssertError(t, err, "secret cannot be nil", "ParseConsulToken(nil)")
+}
+
+func TestNewConsulTokenWatcher(t *testing.T) {
+	config := &VaultConfig{Host: "https://vault.example.com", Path: "consul/roles/web", Token: "t"}
+	if _, err := NewConsulTokenWatcher(config, func(ConsulToken) error { return nil }); err == nil {
+		t.Error("NewConsulTokenWatcher() expected error for a non-creds path")
+	}
+
+	config.Path = "consul/creds/web"
+	_, err := NewConsulTokenWatcher(config, nil)
+	AssertError(t, err, "onRotate callback cannot be nil", "NewConsulTokenWatcher(nil callback)")
+}
+
+func TestDynamicSecretWatcher_NotifiesRotation(t *testing.T) {
+	vault := newFakeDynamicVault(false, 0)
+	defer vault.server.Close()
+
+	events := make(chan ChangeEvent, 4)
+	notifier := NotifierFunc(func(ctx context.Context, event ChangeEvent) error {
+		events <- event
+		return nil
+	})
+
+	watcher, err := NewDynamicSecretWatcher(vault.config(), func(*DynamicSecret) error { return nil }, WithDynamicNotifier(notifier))
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	select {
+	case event := <-events:
+		AssertStringEquals(t, event.Path, "database/creds/readonly", "event path")
+		if event.OldHash == "" || event.NewHash == "" || event.OldHash == event.NewHash {
+			t.Errorf("event hashes = %q -> %q, want two different hashes", event.OldHash, event.NewHash)
+		}
+		if len(event.ChangedKeys) != 1 || event.ChangedKeys[0] != "username" {
+			t.Errorf("ChangedKeys = %v, want [username]", event.ChangedKeys)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change event after rotation")
+	}
+}
@@ -0,0 +1,73 @@
+// Package changestream serves the ChangeStream gRPC service defined in
+// proto/vaultwatcher/v1/changestream.proto from a vaultwatcher.Broadcaster.
+// It is a module of its own, so the watcher doesn't depend on gRPC.
+package changestream
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=module=github.com/naman-dave/vault-watcher/contrib/changestream --go-grpc_out=. --go-grpc_opt=module=github.com/naman-dave/vault-watcher/contrib/changestream vaultwatcher/v1/changestream.proto
+
+import (
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+	"github.com/naman-dave/vault-watcher/contrib/changestream/vaultwatcherv1"
+)
+
+// Server implements the ChangeStream service. Each call subscribes to the
+// broadcaster, so a subscriber whose buffer is full misses events rather
+// than slowing down the watcher.
+type Server struct {
+	vaultwatcherv1.UnimplementedChangeStreamServer
+	broadcaster *vaultwatcher.Broadcaster
+}
+
+// NewServer creates a server streaming the events of broadcaster, which must
+// be registered as a notifier of the watchers with WithNotifier
+func NewServer(broadcaster *vaultwatcher.Broadcaster) *Server {
+	return &Server{broadcaster: broadcaster}
+}
+
+// Subscribe streams events until the client cancels the call or the
+// broadcaster is closed
+func (s *Server) Subscribe(req *vaultwatcherv1.SubscribeRequest, stream vaultwatcherv1.ChangeStream_SubscribeServer) error {
+	sub := s.broadcaster.Subscribe(req.GetPaths()...)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(Event(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Event converts a change event to its protobuf message. The JSON patch,
+// which may hold secret values, is left out.
+func Event(event vaultwatcher.ChangeEvent) *vaultwatcherv1.ChangeEvent {
+	message := &vaultwatcherv1.ChangeEvent{
+		Path:            event.Path,
+		OldHash:         event.OldHash,
+		NewHash:         event.NewHash,
+		ChangedKeys:     event.ChangedKeys,
+		Timestamp:       timestamppb.New(event.Timestamp),
+		MountType:       string(event.MountType),
+		Version:         int64(event.Version),
+		PreviousVersion: int64(event.PreviousVersion),
+		RequestId:       event.RequestID,
+		Warnings:        event.Warnings,
+	}
+	if event.CreatedTime != nil {
+		message.CreatedTime = timestamppb.New(*event.CreatedTime)
+	}
+	if event.DetectionLatency != 0 {
+		message.DetectionLatency = durationpb.New(event.DetectionLatency)
+	}
+	return message
+}
@@ -0,0 +1,75 @@
+package changestream
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+	"github.com/naman-dave/vault-watcher/contrib/changestream/vaultwatcherv1"
+)
+
+func TestServer_Subscribe(t *testing.T) {
+	broadcaster := vaultwatcher.NewBroadcaster(4)
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	vaultwatcherv1.RegisterChangeStreamServer(server, NewServer(broadcaster))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := vaultwatcherv1.NewChangeStreamClient(conn).Subscribe(ctx, &vaultwatcherv1.SubscribeRequest{Paths: []string{"kv/data/app"}})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	// The subscription is registered once the server handles the call
+	for broadcaster.Subscribers() == 0 {
+		if ctx.Err() != nil {
+			t.Fatal("the call never subscribed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	broadcaster.Notify(ctx, vaultwatcher.ChangeEvent{Path: "kv/data/other", NewHash: "other"})
+	broadcaster.Notify(ctx, vaultwatcher.ChangeEvent{
+		Path:             "kv/data/app",
+		MountType:        vaultwatcher.MountTypeKVv2,
+		NewHash:          "h2",
+		ChangedKeys:      []string{"password"},
+		Version:          2,
+		PreviousVersion:  1,
+		CreatedTime:      &created,
+		RequestID:        "req-1",
+		DetectionLatency: time.Second,
+		Patch:            []vaultwatcher.PatchOperation{{Op: "replace", Path: "/password", Value: "s3cr3t"}},
+	})
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if event.GetPath() != "kv/data/app" || event.GetNewHash() != "h2" {
+		t.Errorf("event = %v, want the change to kv/data/app", event)
+	}
+	if event.GetMountType() != "kv-v2" || event.GetVersion() != 2 || event.GetPreviousVersion() != 1 || event.GetRequestId() != "req-1" {
+		t.Errorf("event = %v, want its version, mount type and request ID", event)
+	}
+	if !event.GetCreatedTime().AsTime().Equal(created) || event.GetDetectionLatency().AsDuration() != time.Second {
+		t.Errorf("event = %v, want its created time and detection latency", event)
+	}
+}
@@ -0,0 +1,292 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: vaultwatcher/v1/changestream.proto
+
+package vaultwatcherv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only stream events for these Vault paths. Empty means all paths.
+	Paths         []string `protobuf:"bytes,1,rep,name=paths,proto3" json:"paths,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_vaultwatcher_v1_changestream_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vaultwatcher_v1_changestream_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_vaultwatcher_v1_changestream_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubscribeRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+// ChangeEvent mirrors vaultwatcher.ChangeEvent. It never carries secret
+// values, so the JSON patch is left out.
+type ChangeEvent struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Path        string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	OldHash     string                 `protobuf:"bytes,2,opt,name=old_hash,json=oldHash,proto3" json:"old_hash,omitempty"`
+	NewHash     string                 `protobuf:"bytes,3,opt,name=new_hash,json=newHash,proto3" json:"new_hash,omitempty"`
+	ChangedKeys []string               `protobuf:"bytes,4,rep,name=changed_keys,json=changedKeys,proto3" json:"changed_keys,omitempty"`
+	// When the change was detected
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Secrets engine the path was read from, e.g. "kv-v2"; empty for sources
+	// outside Vault
+	MountType string `protobuf:"bytes,6,opt,name=mount_type,json=mountType,proto3" json:"mount_type,omitempty"`
+	// KV v2 version metadata, zero for KV v1 secrets or unknown versions
+	Version         int64 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	PreviousVersion int64 `protobuf:"varint,8,opt,name=previous_version,json=previousVersion,proto3" json:"previous_version,omitempty"`
+	// When the version was written
+	CreatedTime *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_time,json=createdTime,proto3" json:"created_time,omitempty"`
+	// ID of the Vault request the change was read from, as in Vault's audit log
+	RequestId string   `protobuf:"bytes,10,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Warnings  []string `protobuf:"bytes,11,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// Time from writing the version to detecting it
+	DetectionLatency *durationpb.Duration `protobuf:"bytes,12,opt,name=detection_latency,json=detectionLatency,proto3" json:"detection_latency,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	mi := &file_vaultwatcher_v1_changestream_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_vaultwatcher_v1_changestream_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_vaultwatcher_v1_changestream_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ChangeEvent) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetOldHash() string {
+	if x != nil {
+		return x.OldHash
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetNewHash() string {
+	if x != nil {
+		return x.NewHash
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetChangedKeys() []string {
+	if x != nil {
+		return x.ChangedKeys
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetMountType() string {
+	if x != nil {
+		return x.MountType
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetPreviousVersion() int64 {
+	if x != nil {
+		return x.PreviousVersion
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetCreatedTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTime
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetDetectionLatency() *durationpb.Duration {
+	if x != nil {
+		return x.DetectionLatency
+	}
+	return nil
+}
+
+var File_vaultwatcher_v1_changestream_proto protoreflect.FileDescriptor
+
+const file_vaultwatcher_v1_changestream_proto_rawDesc = "" +
+	"\n" +
+	"\"vaultwatcher/v1/changestream.proto\x12\x0fvaultwatcher.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"(\n" +
+	"\x10SubscribeRequest\x12\x14\n" +
+	"\x05paths\x18\x01 \x03(\tR\x05paths\"\xda\x03\n" +
+	"\vChangeEvent\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x19\n" +
+	"\bold_hash\x18\x02 \x01(\tR\aoldHash\x12\x19\n" +
+	"\bnew_hash\x18\x03 \x01(\tR\anewHash\x12!\n" +
+	"\fchanged_keys\x18\x04 \x03(\tR\vchangedKeys\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"mount_type\x18\x06 \x01(\tR\tmountType\x12\x18\n" +
+	"\aversion\x18\a \x01(\x03R\aversion\x12)\n" +
+	"\x10previous_version\x18\b \x01(\x03R\x0fpreviousVersion\x12=\n" +
+	"\fcreated_time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedTime\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\n" +
+	" \x01(\tR\trequestId\x12\x1a\n" +
+	"\bwarnings\x18\v \x03(\tR\bwarnings\x12F\n" +
+	"\x11detection_latency\x18\f \x01(\v2\x19.google.protobuf.DurationR\x10detectionLatency2^\n" +
+	"\fChangeStream\x12N\n" +
+	"\tSubscribe\x12!.vaultwatcher.v1.SubscribeRequest\x1a\x1c.vaultwatcher.v1.ChangeEvent0\x01BXZVgithub.com/naman-dave/vault-watcher/contrib/changestream/vaultwatcherv1;vaultwatcherv1b\x06proto3"
+
+var (
+	file_vaultwatcher_v1_changestream_proto_rawDescOnce sync.Once
+	file_vaultwatcher_v1_changestream_proto_rawDescData []byte
+)
+
+func file_vaultwatcher_v1_changestream_proto_rawDescGZIP() []byte {
+	file_vaultwatcher_v1_changestream_proto_rawDescOnce.Do(func() {
+		file_vaultwatcher_v1_changestream_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_vaultwatcher_v1_changestream_proto_rawDesc), len(file_vaultwatcher_v1_changestream_proto_rawDesc)))
+	})
+	return file_vaultwatcher_v1_changestream_proto_rawDescData
+}
+
+var file_vaultwatcher_v1_changestream_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_vaultwatcher_v1_changestream_proto_goTypes = []any{
+	(*SubscribeRequest)(nil),      // 0: vaultwatcher.v1.SubscribeRequest
+	(*ChangeEvent)(nil),           // 1: vaultwatcher.v1.ChangeEvent
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 3: google.protobuf.Duration
+}
+var file_vaultwatcher_v1_changestream_proto_depIdxs = []int32{
+	2, // 0: vaultwatcher.v1.ChangeEvent.timestamp:type_name -> google.protobuf.Timestamp
+	2, // 1: vaultwatcher.v1.ChangeEvent.created_time:type_name -> google.protobuf.Timestamp
+	3, // 2: vaultwatcher.v1.ChangeEvent.detection_latency:type_name -> google.protobuf.Duration
+	0, // 3: vaultwatcher.v1.ChangeStream.Subscribe:input_type -> vaultwatcher.v1.SubscribeRequest
+	1, // 4: vaultwatcher.v1.ChangeStream.Subscribe:output_type -> vaultwatcher.v1.ChangeEvent
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_vaultwatcher_v1_changestream_proto_init() }
+func file_vaultwatcher_v1_changestream_proto_init() {
+	if File_vaultwatcher_v1_changestream_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_vaultwatcher_v1_changestream_proto_rawDesc), len(file_vaultwatcher_v1_changestream_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_vaultwatcher_v1_changestream_proto_goTypes,
+		DependencyIndexes: file_vaultwatcher_v1_changestream_proto_depIdxs,
+		MessageInfos:      file_vaultwatcher_v1_changestream_proto_msgTypes,
+	}.Build()
+	File_vaultwatcher_v1_changestream_proto = out.File
+	file_vaultwatcher_v1_changestream_proto_goTypes = nil
+	file_vaultwatcher_v1_changestream_proto_depIdxs = nil
+}
@@ -0,0 +1,134 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: vaultwatcher/v1/changestream.proto
+
+package vaultwatcherv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ChangeStream_Subscribe_FullMethodName = "/vaultwatcher.v1.ChangeStream/Subscribe"
+)
+
+// ChangeStreamClient is the client API for ChangeStream service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ChangeStream streams change events detected by a vault-watcher process to
+// remote subscribers, so one watcher can serve many downstream services.
+type ChangeStreamClient interface {
+	// Subscribe streams change events until the client cancels the call or the
+	// server shuts down. Events detected before the call are not replayed.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error)
+}
+
+type changeStreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChangeStreamClient(cc grpc.ClientConnInterface) ChangeStreamClient {
+	return &changeStreamClient{cc}
+}
+
+func (c *changeStreamClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ChangeStream_ServiceDesc.Streams[0], ChangeStream_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, ChangeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ChangeStream_SubscribeClient = grpc.ServerStreamingClient[ChangeEvent]
+
+// ChangeStreamServer is the server API for ChangeStream service.
+// All implementations must embed UnimplementedChangeStreamServer
+// for forward compatibility.
+//
+// ChangeStream streams change events detected by a vault-watcher process to
+// remote subscribers, so one watcher can serve many downstream services.
+type ChangeStreamServer interface {
+	// Subscribe streams change events until the client cancels the call or the
+	// server shuts down. Events detected before the call are not replayed.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ChangeEvent]) error
+	mustEmbedUnimplementedChangeStreamServer()
+}
+
+// UnimplementedChangeStreamServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedChangeStreamServer struct{}
+
+func (UnimplementedChangeStreamServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ChangeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedChangeStreamServer) mustEmbedUnimplementedChangeStreamServer() {}
+func (UnimplementedChangeStreamServer) testEmbeddedByValue()                      {}
+
+// UnsafeChangeStreamServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChangeStreamServer will
+// result in compilation errors.
+type UnsafeChangeStreamServer interface {
+	mustEmbedUnimplementedChangeStreamServer()
+}
+
+func RegisterChangeStreamServer(s grpc.ServiceRegistrar, srv ChangeStreamServer) {
+	// If the following call pancis, it indicates UnimplementedChangeStreamServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ChangeStream_ServiceDesc, srv)
+}
+
+func _ChangeStream_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChangeStreamServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, ChangeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ChangeStream_SubscribeServer = grpc.ServerStreamingServer[ChangeEvent]
+
+// ChangeStream_ServiceDesc is the grpc.ServiceDesc for ChangeStream service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ChangeStream_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vaultwatcher.v1.ChangeStream",
+	HandlerType: (*ChangeStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _ChangeStream_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "vaultwatcher/v1/changestream.proto",
+}
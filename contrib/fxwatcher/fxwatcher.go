@@ -0,0 +1,40 @@
+// Package fxwatcher runs a vault-watcher Watcher in an fx application. It is
+// a module of its own, so the watcher doesn't depend on fx.
+package fxwatcher
+
+import (
+	"log/slog"
+	"time"
+
+	"go.uber.org/fx"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+)
+
+// serviceParams are the dependencies of the service taken from the container
+type serviceParams struct {
+	fx.In
+
+	Config *vaultwatcher.VaultConfig
+	Logger *slog.Logger `optional:"true"`
+}
+
+// Module provides a *vaultwatcher.Service and its *vaultwatcher.Watcher built
+// from the *vaultwatcher.VaultConfig in the container. The service starts
+// with the application, which fails to start if the secret can't be read,
+// and stops with it. The watcher logs to the *slog.Logger in the container,
+// if there is one, unless opts include vaultwatcher.WithLogger.
+func Module(checkInterval time.Duration, handler vaultwatcher.ChangeHandler, opts ...vaultwatcher.Option) fx.Option {
+	return fx.Module("vault-watcher",
+		fx.Provide(
+			func(params serviceParams) (*vaultwatcher.Service, error) {
+				options := append([]vaultwatcher.Option{vaultwatcher.WithLogger(params.Logger)}, opts...)
+				return vaultwatcher.ServiceProvider(checkInterval, handler, options...)(params.Config)
+			},
+			func(service *vaultwatcher.Service) *vaultwatcher.Watcher { return service.Watcher() },
+		),
+		fx.Invoke(func(lc fx.Lifecycle, service *vaultwatcher.Service) {
+			lc.Append(fx.StartStopHook(service.OnStart, service.OnStop))
+		}),
+	)
+}
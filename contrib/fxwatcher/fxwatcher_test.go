@@ -0,0 +1,99 @@
+package fxwatcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestModule(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	handler := vaultwatcher.ChangeHandlerFunc(func(context.Context, vaultwatcher.ChangeEvent) error { return nil })
+	var watcher *vaultwatcher.Watcher
+	app := fxtest.New(t,
+		fx.Supply(&vaultwatcher.VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}),
+		Module(time.Hour, handler),
+		fx.Populate(&watcher),
+	)
+
+	app.RequireStart()
+	if watcher.GetCurrentHash() == "" {
+		t.Error("GetCurrentHash() is empty after start, want the secret read")
+	}
+	app.RequireStop()
+}
+
+func TestModule_StartError(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+
+	handler := vaultwatcher.ChangeHandlerFunc(func(context.Context, vaultwatcher.ChangeEvent) error { return nil })
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(&vaultwatcher.VaultConfig{Host: vault.URL, Path: "secret/data/missing", Token: vault.Token}),
+		Module(time.Hour, handler),
+	)
+	if err := app.Start(context.Background()); err == nil {
+		app.Stop(context.Background())
+		t.Error("Start() succeeded, want the failed read of the missing secret")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for a logger used by the watcher
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestModule_Logger(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	var logs syncBuffer
+	handler := vaultwatcher.ChangeHandlerFunc(func(context.Context, vaultwatcher.ChangeEvent) error {
+		return errors.New("reload failed")
+	})
+	app := fxtest.New(t,
+		fx.Supply(&vaultwatcher.VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}),
+		fx.Supply(slog.New(slog.NewTextHandler(&logs, nil))),
+		Module(10*time.Millisecond, handler),
+	)
+	app.RequireStart()
+	defer app.RequireStop()
+
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(logs.String(), "reload failed") {
+		if time.Now().After(deadline) {
+			t.Fatalf("the injected logger got %q, want the failed check", logs.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,43 @@
+// Package kafka publishes vault-watcher change events to Kafka with
+// segmentio/kafka-go. It is a module of its own, so the watcher doesn't
+// depend on a Kafka client.
+package kafka
+
+import (
+	"context"
+	"strings"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+)
+
+// Writer is the subset of *kafka.Writer used for publishing. The writer must
+// not set Topic, since every message names its own.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
+}
+
+// NewPublisher adapts a Kafka writer to vaultwatcher.MessagePublisher. Topics
+// are passed through Topic, so a "{path}" placeholder gives a valid name.
+func NewPublisher(writer Writer) vaultwatcher.MessagePublisher {
+	return vaultwatcher.MessagePublisherFunc(func(ctx context.Context, topic string, key, value []byte) error {
+		return writer.WriteMessages(ctx, kafkago.Message{Topic: Topic(topic), Key: key, Value: value})
+	})
+}
+
+// Topic turns topic into a valid Kafka topic name. Kafka allows only ASCII
+// letters, digits, '.', '_' and '-', so slashes, e.g. from a Vault path,
+// become dots and other characters underscores.
+func Topic(topic string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '/':
+			return '.'
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.Trim(topic, "/"))
+}
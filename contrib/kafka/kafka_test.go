@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+)
+
+// fakeWriter records written messages
+type fakeWriter struct {
+	messages []kafkago.Message
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafkago.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestNewPublisher(t *testing.T) {
+	writer := &fakeWriter{}
+	notifier, err := vaultwatcher.NewPublisherNotifier(NewPublisher(writer), "vault-changes.{path}")
+	if err != nil {
+		t.Fatalf("NewPublisherNotifier() error = %v", err)
+	}
+
+	event := vaultwatcher.ChangeEvent{Path: "kv/data/app", NewHash: "h1"}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(writer.messages) != 1 {
+		t.Fatalf("wrote %d messages, want 1", len(writer.messages))
+	}
+	message := writer.messages[0]
+	if message.Topic != "vault-changes.kv.data.app" {
+		t.Errorf("topic = %q, want %q", message.Topic, "vault-changes.kv.data.app")
+	}
+	if string(message.Key) != "kv/data/app" {
+		t.Errorf("key = %q, want %q", message.Key, "kv/data/app")
+	}
+	var got vaultwatcher.ChangeEvent
+	if err := json.Unmarshal(message.Value, &got); err != nil || got.NewHash != "h1" {
+		t.Errorf("value = %s, want the event", message.Value)
+	}
+}
+
+func TestTopic(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  string
+	}{
+		{"vault-changes", "vault-changes"},
+		{"/vault/changes/", "vault.changes"},
+		{"changes.kv/data/my app+1", "changes.kv.data.my_app_1"},
+	}
+	for _, tt := range tests {
+		if got := Topic(tt.topic); got != tt.want {
+			t.Errorf("Topic(%q) = %q, want %q", tt.topic, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Package wirewatcher builds a running vault-watcher Watcher with Wire. It is
+// a module of its own, so the watcher doesn't depend on Wire.
+package wirewatcher
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/wire"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+)
+
+// ProviderSet provides a started *vaultwatcher.Service and its
+// *vaultwatcher.Watcher from an injected *vaultwatcher.VaultConfig and
+// Settings. Wire's cleanup function stops the service.
+var ProviderSet = wire.NewSet(NewService, Watcher)
+
+// Settings configures the watcher built by NewService
+type Settings struct {
+	CheckInterval time.Duration
+	Handler       vaultwatcher.ChangeHandler
+	Options       []vaultwatcher.Option
+	Logger        *slog.Logger // Optional, the watcher logs to slog.Default() without it
+}
+
+// NewService creates the watcher's Service and starts it, returning once the
+// secret was read. The cleanup function stops it. The watcher logs to
+// settings.Logger unless Options include vaultwatcher.WithLogger.
+func NewService(config *vaultwatcher.VaultConfig, settings Settings) (*vaultwatcher.Service, func(), error) {
+	options := append([]vaultwatcher.Option{vaultwatcher.WithLogger(settings.Logger)}, settings.Options...)
+	service, err := vaultwatcher.ServiceProvider(settings.CheckInterval, settings.Handler, options...)(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := service.OnStart(context.Background()); err != nil {
+		return nil, nil, err
+	}
+	return service, func() { service.OnStop(context.Background()) }, nil
+}
+
+// Watcher returns the watcher of service
+func Watcher(service *vaultwatcher.Service) *vaultwatcher.Watcher {
+	return service.Watcher()
+}
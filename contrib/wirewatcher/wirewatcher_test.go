@@ -0,0 +1,87 @@
+package wirewatcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	vaultwatcher "github.com/naman-dave/vault-watcher"
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestNewService(t *testing.T) {
+	// Called the way an injector generated from ProviderSet calls it
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	settings := Settings{
+		CheckInterval: time.Hour,
+		Handler:       vaultwatcher.ChangeHandlerFunc(func(context.Context, vaultwatcher.ChangeEvent) error { return nil }),
+	}
+	service, cleanup, err := NewService(&vaultwatcher.VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, settings)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if Watcher(service).GetCurrentHash() == "" {
+		t.Error("GetCurrentHash() is empty, want the secret read")
+	}
+	cleanup()
+
+	_, _, err = NewService(&vaultwatcher.VaultConfig{Host: vault.URL, Path: "secret/data/missing", Token: vault.Token}, settings)
+	if err == nil {
+		t.Error("NewService() succeeded, want the failed read of the missing secret")
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for a logger used by the watcher
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestNewService_Logger(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	var logs syncBuffer
+	settings := Settings{
+		CheckInterval: 10 * time.Millisecond,
+		Handler: vaultwatcher.ChangeHandlerFunc(func(context.Context, vaultwatcher.ChangeEvent) error {
+			return errors.New("reload failed")
+		}),
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+	_, cleanup, err := NewService(&vaultwatcher.VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, settings)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	defer cleanup()
+
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(logs.String(), "reload failed") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Settings.Logger got %q, want the failed check", logs.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,66 @@
+package vaultwatcher
+
+import "time"
+
+// HasPendingChange returns whether a detected change is waiting for its
+// callback to be allowed, i.e. for a cooldown to elapse, a quiet window to
+// close or a change delay to pass
+func (w *Watcher) HasPendingChange() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.pendingChange || w.delayedHash != "" && w.delayedHash != w.currentHash
+}
+
+// callbackAllowedAt returns when the onChange callback may run next, after
+// the cooldown and any quiet window. Callers must hold w.mu.
+func (w *Watcher) callbackAllowedAt(now time.Time) time.Time {
+	allowedAt := now
+	if w.cooldown > 0 && !w.lastCallback.IsZero() && w.lastCallback.Add(w.cooldown).After(allowedAt) {
+		allowedAt = w.lastCallback.Add(w.cooldown)
+	}
+	if until := w.quietUntil(allowedAt); !until.IsZero() {
+		allowedAt = until
+	}
+	return allowedAt
+}
+
+// deferCallback reports whether the onChange callback has to wait. The first
+// deferred change schedules a check for when the callback is allowed again;
+// the hash isn't adopted meanwhile, so that check applies the latest data.
+func (w *Watcher) deferCallback() bool {
+	now := time.Now()
+	w.mu.Lock()
+	allowedAt := w.callbackAllowedAt(now)
+	if !allowedAt.After(now) {
+		w.mu.Unlock()
+		return false
+	}
+	schedule := !w.pendingChange
+	w.pendingChange = true
+	w.mu.Unlock()
+
+	if schedule {
+		w.wg.Add(1)
+		go w.checkAt(allowedAt)
+	}
+	return true
+}
+
+// checkAt runs in a goroutine and checks for the pending change at the given time
+func (w *Watcher) checkAt(at time.Time) {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-w.ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	w.mu.Lock()
+	w.pendingChange = false
+	w.mu.Unlock()
+	w.check()
+}
@@ -0,0 +1,129 @@
+package vaultwatcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestWatcher_Cooldown(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"value": "v1"})
+
+	var mu sync.Mutex
+	var calls []time.Time
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, time.Now())
+			return nil
+		}, WithCooldown(150*time.Millisecond))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.Put("secret/app", map[string]interface{}{"value": "v2"})
+	AssertNoError(t, watcher.checkForChanges(), "first change")
+	AssertBoolEquals(t, watcher.HasPendingChange(), false, "HasPendingChange() after first change")
+
+	// Two more changes within the cooldown collapse into one deferred callback
+	vault.Put("secret/app", map[string]interface{}{"value": "v3"})
+	AssertNoError(t, watcher.checkForChanges(), "change during cooldown")
+	vault.Put("secret/app", map[string]interface{}{"value": "v4"})
+	AssertNoError(t, watcher.checkForChanges(), "second change during cooldown")
+	AssertBoolEquals(t, watcher.HasPendingChange(), true, "HasPendingChange() during cooldown")
+
+	mu.Lock()
+	if len(calls) != 1 {
+		t.Errorf("calls during cooldown = %d, want 1", len(calls))
+	}
+	mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && watcher.HasPendingChange() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 {
+		t.Fatalf("calls after cooldown = %d, want 2", len(calls))
+	}
+	if gap := calls[1].Sub(calls[0]); gap < 150*time.Millisecond {
+		t.Errorf("callbacks %v apart, want at least the 150ms cooldown", gap)
+	}
+
+	hash, err := CalculateHash(map[string]interface{}{"value": "v4"})
+	AssertNoError(t, err, "CalculateHash()")
+	AssertStringEquals(t, watcher.GetCurrentHash(), hash, "hash after cooldown")
+}
+
+func TestWatcher_CooldownNotUsedByDefault(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"value": "v1"})
+
+	calls := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			calls++
+			return nil
+		})
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	for _, value := range []string{"v2", "v3"} {
+		vault.Put("secret/app", map[string]interface{}{"value": value})
+		AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestWatcher_CooldownDeferredCheckOverlapsTick(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"value": "v1"})
+
+	var mu sync.Mutex
+	calls := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			time.Sleep(50 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return nil
+		}, WithCooldown(50*time.Millisecond))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.Put("secret/app", map[string]interface{}{"value": "v2"})
+	AssertNoError(t, watcher.check(), "first change")
+	vault.Put("secret/app", map[string]interface{}{"value": "v3"})
+	AssertNoError(t, watcher.check(), "change during cooldown")
+
+	// A tick right as the deferred check starts sees the same change
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && watcher.HasPendingChange() {
+		time.Sleep(time.Millisecond)
+	}
+	AssertNoError(t, watcher.check(), "check overlapping the deferred check")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	hash, err := CalculateHash(map[string]interface{}{"value": "v3"})
+	AssertNoError(t, err, "CalculateHash()")
+	AssertStringEquals(t, watcher.GetCurrentHash(), hash, "hash after the deferred check")
+}
@@ -0,0 +1,132 @@
+package vaultwatcher
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, time.May, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.May, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2024, time.May, 15, 10, 10, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, time.May, 16, 2, 30, 0, 0, time.UTC)},
+		{"*/5 8-18 * * MON-FRI", time.Date(2024, time.May, 15, 10, 10, 0, 0, time.UTC)},
+		{"0 9 * * sat,sun", time.Date(2024, time.May, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 JAN *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month and day of week both restricted: either matches
+		{"0 0 20 * MON", time.Date(2024, time.May, 20, 0, 0, 0, 0, time.UTC)},
+		{"15/20 * * * *", time.Date(2024, time.May, 15, 10, 15, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.May, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			AssertNoError(t, err, "ParseCron()")
+
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronSchedule_NextKeepsLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	schedule, err := ParseCron("0 8 * * *")
+	AssertNoError(t, err, "ParseCron()")
+
+	got := schedule.Next(time.Date(2024, time.May, 15, 9, 0, 0, 0, loc))
+	if want := time.Date(2024, time.May, 16, 8, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		errMsg string
+	}{
+		{"* * * *", "want 5 fields, got 4"},
+		{"60 * * * *", "minute: value 60 out of range 0-59"},
+		{"* 24 * * *", "hour: value 24 out of range 0-23"},
+		{"* * 0 * *", "day of month: value 0 out of range 1-31"},
+		{"* * * FOO *", `month: invalid value "FOO"`},
+		{"* * * * 5-2", `day of week: invalid range "5-2"`},
+		{"*/0 * * * *", `minute: invalid step "0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCron(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseCron() error = %v, want it to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestRunCronSchedule(t *testing.T) {
+	schedule, err := ParseCron("* * * * *")
+	AssertNoError(t, err, "ParseCron()")
+
+	// Waiting for the next minute would slow the tests down; cancel instead
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runCronSchedule(ctx, slog.Default(), schedule, func() { t.Error("fn ran before the next minute") })
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runCronSchedule did not return after cancel")
+	}
+
+	never, err := ParseCron("0 0 30 2 *")
+	AssertNoError(t, err, "ParseCron()")
+	finished := make(chan struct{})
+	go func() {
+		runCronSchedule(context.Background(), slog.Default(), never, func() {})
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("runCronSchedule did not return for a schedule that never matches")
+	}
+}
+
+func TestWatcher_WithCronSchedule(t *testing.T) {
+	schedule, err := ParseCron("*/5 8-18 * * MON-FRI")
+	AssertNoError(t, err, "ParseCron()")
+
+	watcher, err := NewWatcher(TestVaultConfig(), 0, func() error { return nil }, WithCronSchedule(schedule))
+	AssertNoError(t, err, "NewWatcher()")
+	if watcher.schedule != schedule {
+		t.Error("WithCronSchedule() did not set the schedule")
+	}
+
+	group, err := NewWatcherGroup(TestVaultConfig(), []string{"kv/data/a"}, time.Minute,
+		func(string) error { return nil }, WithGroupSchedule(schedule))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	if group.schedule != schedule {
+		t.Error("WithGroupSchedule() did not set the schedule")
+	}
+}
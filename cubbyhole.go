@@ -0,0 +1,75 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// isCubbyholePath reports whether path is in the cubbyhole of the token. The
+// cubbyhole is a KV v1 store private to each token: no other token can read
+// or list it, and it is destroyed with the token.
+func isCubbyholePath(path string) bool {
+	path = strings.Trim(path, "/")
+	return path == "cubbyhole" || strings.HasPrefix(path, "cubbyhole/")
+}
+
+// tokenAccessor returns the accessor of the watcher's token, which identifies
+// the token, and so its cubbyhole, without revealing it
+func (w *Watcher) tokenAccessor(ctx context.Context) (string, error) {
+	secret, err := w.client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("token lookup returned no data")
+	}
+	accessor, _ := secret.Data["accessor"].(string)
+	return accessor, nil
+}
+
+// rememberCubbyholeToken records which token the cubbyhole was first read
+// with since the watcher started. Without permission to look the token up, replaced tokens can't be told
+// apart from a deleted secret.
+func (w *Watcher) rememberCubbyholeToken(ctx context.Context) {
+	w.mu.RLock()
+	known := w.cubbyholeAccessor != ""
+	w.mu.RUnlock()
+	if known {
+		return
+	}
+
+	accessor, err := w.tokenAccessor(ctx)
+	if err != nil {
+		w.logger.Error("Error looking up the token owning the cubbyhole", "error", w.redactError(err))
+		return
+	}
+	w.mu.Lock()
+	w.cubbyholeAccessor = accessor
+	w.mu.Unlock()
+}
+
+// cubbyholeError explains a failed cubbyhole read: when the token the
+// cubbyhole was read with was replaced, expired or was revoked, the error
+// matches ErrCubbyholeInaccessible. Other errors are returned as they are.
+func (w *Watcher) cubbyholeError(ctx context.Context, err error) error {
+	w.mu.RLock()
+	known := w.cubbyholeAccessor
+	w.mu.RUnlock()
+
+	accessor, lookupErr := w.tokenAccessor(ctx)
+	var responseErr *api.ResponseError
+	switch {
+	case lookupErr != nil && errors.As(lookupErr, &responseErr) && responseErr.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %s belongs to a token that expired or was revoked: %w",
+			ErrCubbyholeInaccessible, w.vaultConfig.Path, ErrPermissionDenied)
+	case lookupErr == nil && known != "" && accessor != known:
+		return fmt.Errorf("%w: %s belongs to the token it was first read with, which was replaced",
+			ErrCubbyholeInaccessible, w.vaultConfig.Path)
+	}
+	return err
+}
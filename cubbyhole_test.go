@@ -0,0 +1,93 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestIsCubbyholePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"cubbyhole/app", true},
+		{"/cubbyhole/app/db", true},
+		{"cubbyhole", true},
+		{"secret/data/cubbyhole", false},
+		{"cubbyholes/app", false},
+	}
+	for _, tt := range tests {
+		AssertBoolEquals(t, isCubbyholePath(tt.path), tt.want, tt.path)
+	}
+}
+
+func TestWatcher_Cubbyhole(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.AddToken("new-token")
+	// A key named "data" doesn't make a cubbyhole secret KV v2
+	vault.PutCubbyhole(vault.Token, "cubbyhole/app", map[string]interface{}{"data": map[string]interface{}{"a": "b"}, "password": "one"})
+
+	changes := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "cubbyhole/app", Token: vault.Token}, time.Hour, func() error {
+		changes++
+		return nil
+	})
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	data, err := watcher.fetchVaultData()
+	AssertNoError(t, err, "fetchVaultData()")
+	AssertStringEquals(t, data["password"].(string), "one", "password")
+
+	vault.PutCubbyhole(vault.Token, "cubbyhole/app", map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if changes != 1 {
+		t.Errorf("onChange called %d times, want 1", changes)
+	}
+
+	tests := []struct {
+		name    string
+		setup   func()
+		wantErr string
+		wantIs  []error
+	}{
+		{
+			name: "secret deleted",
+			setup: func() {
+				_, err := watcher.client.Logical().Delete("cubbyhole/app")
+				AssertNoError(t, err, "Delete()")
+			},
+			wantErr: "failed to read secret from vault: secret not found",
+			wantIs:  []error{ErrSecretNotFound},
+		},
+		{
+			name:    "token replaced",
+			setup:   func() { watcher.client.SetToken("new-token") },
+			wantErr: "failed to read secret from vault: cubbyhole is inaccessible: cubbyhole/app belongs to the token it was first read with, which was replaced",
+			wantIs:  []error{ErrCubbyholeInaccessible},
+		},
+		{
+			name:    "token revoked",
+			setup:   func() { vault.RevokeToken("new-token") },
+			wantErr: "failed to read secret from vault: cubbyhole is inaccessible: cubbyhole/app belongs to a token that expired or was revoked: permission denied",
+			wantIs:  []error{ErrCubbyholeInaccessible, ErrPermissionDenied},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			_, err := watcher.fetchVaultData()
+			AssertError(t, err, tt.wantErr, "fetchVaultData()")
+			for _, target := range tt.wantIs {
+				if !errors.Is(err, target) {
+					t.Errorf("errors.Is(err, %v) = false", target)
+				}
+			}
+		})
+	}
+}
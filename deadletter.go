@@ -0,0 +1,164 @@
+package vaultwatcher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultDeadLetterAttempts = 3
+
+// DeadLetter is a change whose onChange callback kept failing. The watcher
+// moves on from it, so it can be replayed later with ReplayDeadLetter.
+type DeadLetter struct {
+	Event     ChangeEvent `json:"event"`
+	Attempts  int         `json:"attempts"`
+	Error     string      `json:"error"` // Last callback error, with secret values redacted
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// DeadLetterSink receives changes whose callback exhausted its attempts. A
+// failing Send keeps the change pending, so it is retried by the next check.
+type DeadLetterSink interface {
+	Send(ctx context.Context, letter DeadLetter) error
+}
+
+// DeadLetterFunc adapts a function to a DeadLetterSink
+type DeadLetterFunc func(ctx context.Context, letter DeadLetter) error
+
+// Send calls f
+func (f DeadLetterFunc) Send(ctx context.Context, letter DeadLetter) error {
+	return f(ctx, letter)
+}
+
+// DeadLetterChannel returns a sink sending to ch. It doesn't block: a full
+// channel fails the send, keeping the change pending.
+func DeadLetterChannel(ch chan<- DeadLetter) DeadLetterSink {
+	return DeadLetterFunc(func(ctx context.Context, letter DeadLetter) error {
+		select {
+		case ch <- letter:
+			return nil
+		default:
+			return fmt.Errorf("dead letter channel is full")
+		}
+	})
+}
+
+// FileDeadLetterSink appends dead letters to a file as JSON lines, to be read
+// back with ReadDeadLetters
+type FileDeadLetterSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileDeadLetterSink creates a sink appending to path, created if missing
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("dead letter file path is required")
+	}
+	return &FileDeadLetterSink{path: path}, nil
+}
+
+// Send appends letter to the file
+func (s *FileDeadLetterSink) Send(ctx context.Context, letter DeadLetter) error {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return nil
+}
+
+// ReadDeadLetters reads the dead letters written by a FileDeadLetterSink. A
+// missing file holds no dead letters.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer file.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			return nil, fmt.Errorf("failed to parse dead letter on line %d: %w", line, err)
+		}
+		letters = append(letters, letter)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letter file: %w", err)
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetter runs onChange again for a dead-lettered change, with
+// LastChange returning its event while the callback runs. It doesn't change
+// what the watcher considers applied.
+func (w *Watcher) ReplayDeadLetter(letter DeadLetter) error {
+	event := letter.Event
+	w.mu.Lock()
+	previous := w.lastChange
+	w.lastChange = &event
+	w.mu.Unlock()
+
+	err := w.runCallback("onChange", true, func() error {
+		return w.handler.HandleChange(w.ctx, event)
+	})
+
+	w.mu.Lock()
+	w.lastChange = previous
+	w.mu.Unlock()
+
+	return err
+}
+
+// deadLetterChange sends the event to the dead letter sink once its callback
+// failed the configured number of times in a row. It reports whether the
+// change was dead-lettered, so the watcher can move on from it.
+func (w *Watcher) deadLetterChange(event ChangeEvent, callbackErr error, attempts int) bool {
+	if w.deadLetter == nil || attempts < w.deadLetterAttempts {
+		return false
+	}
+
+	letter := DeadLetter{
+		Event:     event,
+		Attempts:  attempts,
+		Error:     w.redact(callbackErr.Error()),
+		Timestamp: time.Now().UTC(),
+	}
+	if err := w.deadLetter.Send(w.ctx, letter); err != nil {
+		w.logger.Error("Error sending change to the dead letter sink", "error", w.redactError(err))
+		return false
+	}
+
+	w.resetCallbackFailures()
+	return true
+}
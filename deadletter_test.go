@@ -0,0 +1,115 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestWatcher_DeadLetter(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	calls := 0
+	failing := true
+	var seen string
+	letters := make(chan DeadLetter, 1)
+	var watcher *Watcher
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			calls++
+			event, _ := watcher.LastChange()
+			seen = event.NewHash
+			if failing {
+				return fmt.Errorf("cannot apply s3cr3t-password")
+			}
+			return nil
+		}, WithDeadLetter(DeadLetterChannel(letters), 2))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+	initialHash := watcher.GetCurrentHash()
+
+	vault.Put("secret/app", map[string]interface{}{"password": "s3cr3t-password"})
+	AssertError(t, watcher.check(), "onChange callback failed: cannot apply [REDACTED]", "first check")
+	AssertStringEquals(t, watcher.GetCurrentHash(), initialHash, "hash after the first failure")
+	if len(letters) != 0 {
+		t.Fatalf("dead letters after the first failure = %d, want 0", len(letters))
+	}
+
+	AssertError(t, watcher.check(), "onChange callback failed: cannot apply [REDACTED]", "second check")
+	letter := <-letters
+	if letter.Attempts != 2 {
+		t.Errorf("Attempts = %d, want 2", letter.Attempts)
+	}
+	AssertStringEquals(t, letter.Error, "onChange callback failed: cannot apply [REDACTED]", "Error")
+	AssertStringEquals(t, letter.Event.OldHash, initialHash, "Event.OldHash")
+	AssertStringEquals(t, watcher.GetCurrentHash(), letter.Event.NewHash, "hash after dead-lettering")
+
+	// The watcher moved on
+	AssertNoError(t, watcher.check(), "check after dead-lettering")
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+
+	// Replaying runs the callback with the dead-lettered event
+	failing = false
+	seen = ""
+	AssertNoError(t, watcher.ReplayDeadLetter(letter), "ReplayDeadLetter()")
+	AssertStringEquals(t, seen, letter.Event.NewHash, "LastChange() during replay")
+}
+
+func TestWatcher_DeadLetterSinkFailure(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	sink := DeadLetterFunc(func(ctx context.Context, letter DeadLetter) error {
+		return errors.New("sink is down")
+	})
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return errors.New("failed") }, WithDeadLetter(sink, 1))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+	initialHash := watcher.GetCurrentHash()
+
+	// Without a sink to take it, the change stays pending
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	AssertError(t, watcher.check(), "onChange callback failed: failed", "check()")
+	AssertStringEquals(t, watcher.GetCurrentHash(), initialHash, "hash after a failed send")
+}
+
+func TestFileDeadLetterSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+
+	letters, err := ReadDeadLetters(path)
+	AssertNoError(t, err, "ReadDeadLetters() of a missing file")
+	if len(letters) != 0 {
+		t.Errorf("letters = %d, want 0", len(letters))
+	}
+
+	sink, err := NewFileDeadLetterSink(path)
+	AssertNoError(t, err, "NewFileDeadLetterSink()")
+	for _, hash := range []string{"a", "b"} {
+		letter := DeadLetter{Event: ChangeEvent{Path: "secret/data/app", NewHash: hash}, Attempts: 3, Error: "failed"}
+		AssertNoError(t, sink.Send(context.Background(), letter), "Send()")
+	}
+
+	letters, err = ReadDeadLetters(path)
+	AssertNoError(t, err, "ReadDeadLetters()")
+	if len(letters) != 2 {
+		t.Fatalf("letters = %d, want 2", len(letters))
+	}
+	AssertStringEquals(t, letters[0].Event.NewHash, "a", "first letter")
+	AssertStringEquals(t, letters[1].Event.NewHash, "b", "second letter")
+
+	_, err = NewFileDeadLetterSink("")
+	AssertError(t, err, "dead letter file path is required", "NewFileDeadLetterSink() without a path")
+}
@@ -0,0 +1,362 @@
+package vaultwatcher
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// DecodeInto copies data into the struct out points to. Fields are matched
+// by their `vault:"name"` tag, or by name ignoring case when untagged;
+// `vault:"-"` skips a field and `vault:"name,required"` fails when the key
+// is missing. Missing keys leave fields unchanged, so defaults set before
+// decoding stay.
+//
+// Values are coerced to the field type, since Vault often returns strings:
+// "5432" decodes into an int, "true" into a bool and "30s" or 30 (seconds)
+// into a time.Duration. Times are parsed as RFC 3339, []byte fields take
+// base64, slices accept a comma-separated string, nested maps decode into
+// nested structs, and types implementing encoding.TextUnmarshaler parse
+// strings themselves.
+func DecodeInto(data map[string]interface{}, out interface{}) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a non-nil pointer to a struct, got %T", out)
+	}
+	return decodeStruct("", data, target.Elem())
+}
+
+// DecodeInto reads the watched data and decodes it into out, see DecodeInto.
+// It reads the source again rather than keeping secret data in memory.
+func (w *Watcher) DecodeInto(out interface{}) error {
+	if w.fetchData != nil {
+		return fmt.Errorf("this watcher doesn't read a secret that can be decoded")
+	}
+
+	data, err := w.readData()
+	if err != nil {
+		return err
+	}
+	return DecodeInto(data, out)
+}
+
+// readData reads the watched data outside of a check, without recording its
+// version
+func (w *Watcher) readData() (map[string]interface{}, error) {
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	data, _, err := w.secretSource().Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if w.selectData != nil {
+		return w.selectData(data)
+	}
+	return data, nil
+}
+
+// decodeField describes how a struct field is decoded
+type decodeField struct {
+	index    []int
+	key      string
+	tagged   bool
+	required bool
+}
+
+// decodeFields lists the decodable fields of t, including those promoted
+// from untagged embedded structs
+func decodeFields(t reflect.Type) []decodeField {
+	var fields []decodeField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("vault")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for _, promoted := range decodeFields(field.Type) {
+				promoted.index = append([]int{i}, promoted.index...)
+				fields = append(fields, promoted)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		decoded := decodeField{index: []int{i}, key: name, tagged: name != "", required: options == "required"}
+		if name == "" {
+			decoded.key = field.Name
+		}
+		fields = append(fields, decoded)
+	}
+	return fields
+}
+
+// decodeStruct decodes data into the struct value v; path names v in errors
+func decodeStruct(path string, data map[string]interface{}, v reflect.Value) error {
+	for _, field := range decodeFields(v.Type()) {
+		value, ok := data[field.key]
+		if !ok && !field.tagged {
+			// Untagged fields match keys ignoring case
+			for key, candidate := range data {
+				if strings.EqualFold(key, field.key) {
+					value, ok = candidate, true
+					break
+				}
+			}
+		}
+
+		fieldPath := field.key
+		if path != "" {
+			fieldPath = path + "." + field.key
+		}
+		if !ok {
+			if field.required {
+				return fmt.Errorf("missing required key %q", fieldPath)
+			}
+			continue
+		}
+		if err := decodeValue(fieldPath, value, v.FieldByIndex(field.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeValue coerces value into v
+func decodeValue(path string, value interface{}, v reflect.Value) error {
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		// Decode into a copy, so a struct sharing the pointer isn't modified
+		element := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			element.Elem().Set(v.Elem())
+		}
+		if err := decodeValue(path, value, element.Elem()); err != nil {
+			return err
+		}
+		v.Set(element)
+		return nil
+	}
+
+	if text, ok := value.(string); ok && v.Type() != durationType && v.Type() != timeType && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		duration, err := toDuration(value)
+		if err != nil {
+			return decodeError(path, value, v)
+		}
+		v.SetInt(int64(duration))
+		return nil
+	case timeType:
+		text, ok := value.(string)
+		if !ok {
+			return decodeError(path, value, v)
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return decodeError(path, value, v)
+		}
+		v.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if !reflect.TypeOf(value).AssignableTo(v.Type()) {
+			return decodeError(path, value, v)
+		}
+		v.Set(reflect.ValueOf(value))
+	case reflect.String:
+		text, ok := toText(value)
+		if !ok {
+			return decodeError(path, value, v)
+		}
+		v.SetString(text)
+	case reflect.Bool:
+		var parsed bool
+		var err error
+		switch typed := value.(type) {
+		case bool:
+			parsed = typed
+		case string:
+			parsed, err = strconv.ParseBool(strings.TrimSpace(typed))
+		default:
+			return decodeError(path, value, v)
+		}
+		if err != nil {
+			return decodeError(path, value, v)
+		}
+		v.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		text, ok := toText(value)
+		if !ok {
+			return decodeError(path, value, v)
+		}
+		parsed, err := strconv.ParseInt(strings.TrimSpace(text), 10, v.Type().Bits())
+		if err != nil {
+			return decodeError(path, value, v)
+		}
+		v.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		text, ok := toText(value)
+		if !ok {
+			return decodeError(path, value, v)
+		}
+		parsed, err := strconv.ParseUint(strings.TrimSpace(text), 10, v.Type().Bits())
+		if err != nil {
+			return decodeError(path, value, v)
+		}
+		v.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		text, ok := toText(value)
+		if !ok {
+			return decodeError(path, value, v)
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(text), v.Type().Bits())
+		if err != nil {
+			return decodeError(path, value, v)
+		}
+		v.SetFloat(parsed)
+	case reflect.Slice:
+		return decodeSlice(path, value, v)
+	case reflect.Map:
+		return decodeMap(path, value, v)
+	case reflect.Struct:
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return decodeError(path, value, v)
+		}
+		return decodeStruct(path, nested, v)
+	default:
+		return fmt.Errorf("failed to decode %s: unsupported field type %s", path, v.Type())
+	}
+	return nil
+}
+
+// decodeSlice decodes a list, or a comma-separated string, into v. []byte
+// fields take base64 strings.
+func decodeSlice(path string, value interface{}, v reflect.Value) error {
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		text, ok := value.(string)
+		if !ok {
+			return decodeError(path, value, v)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return decodeError(path, value, v)
+		}
+		v.SetBytes(decoded)
+		return nil
+	}
+
+	var items []interface{}
+	switch typed := value.(type) {
+	case []interface{}:
+		items = typed
+	case string:
+		for _, item := range strings.Split(typed, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	default:
+		return decodeError(path, value, v)
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := decodeValue(fmt.Sprintf("%s[%d]", path, i), item, slice.Index(i)); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+// decodeMap decodes a map into v, which must have string keys
+func decodeMap(path string, value interface{}, v reflect.Value) error {
+	entries, ok := value.(map[string]interface{})
+	if !ok {
+		return decodeError(path, value, v)
+	}
+	if v.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("failed to decode %s: map keys must be strings, got %s", path, v.Type().Key())
+	}
+
+	decoded := reflect.MakeMapWithSize(v.Type(), len(entries))
+	for key, entry := range entries {
+		element := reflect.New(v.Type().Elem()).Elem()
+		if err := decodeValue(path+"."+key, entry, element); err != nil {
+			return err
+		}
+		decoded.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), element)
+	}
+	v.Set(decoded)
+	return nil
+}
+
+// toText returns a string or number as text, formatting whole floats without
+// an exponent so 5432.0 reads as "5432"
+func toText(value interface{}) (string, bool) {
+	switch typed := value.(type) {
+	case string:
+		return typed, true
+	case json.Number:
+		return typed.String(), true
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(typed), 'f', -1, 32), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(typed), true
+	case bool:
+		return strconv.FormatBool(typed), true
+	}
+	return "", false
+}
+
+// toDuration parses a Go duration string, or a number of seconds like the
+// TTLs Vault returns
+func toDuration(value interface{}) (time.Duration, error) {
+	text, ok := toText(value)
+	if !ok {
+		return 0, fmt.Errorf("not a duration")
+	}
+	text = strings.TrimSpace(text)
+	if seconds, err := strconv.ParseFloat(text, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(text)
+}
+
+// decodeError reports that value can't be decoded into v. The value itself
+// is left out, since it may be a secret.
+func decodeError(path string, value interface{}, v reflect.Value) error {
+	return fmt.Errorf("failed to decode %s: cannot convert %T to %s", path, value, v.Type())
+}
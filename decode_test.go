@@ -0,0 +1,142 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type decodeDatabase struct {
+	Host string `vault:"host"`
+	Port int    `vault:"port"`
+}
+
+type decodeCommon struct {
+	LogLevel string `vault:"log_level"`
+}
+
+type decodeConfig struct {
+	decodeCommon
+	DatabaseURL string            `vault:"database_url,required"`
+	Port        int               `vault:"port"`
+	Ratio       float64           `vault:"ratio"`
+	Enabled     bool              `vault:"enabled"`
+	Timeout     time.Duration     `vault:"timeout"`
+	TTL         time.Duration     `vault:"ttl"`
+	Rotated     time.Time         `vault:"rotated"`
+	Hosts       []string          `vault:"hosts"`
+	Ports       []uint16          `vault:"ports"`
+	Key         []byte            `vault:"key"`
+	Labels      map[string]string `vault:"labels"`
+	Database    decodeDatabase    `vault:"database"`
+	Replica     *decodeDatabase   `vault:"replica"`
+	Bind        net.IP            `vault:"bind"`
+	Raw         interface{}       `vault:"raw"`
+	Region      string
+	Ignored     string `vault:"-"`
+	unexported  string
+}
+
+func TestDecodeInto(t *testing.T) {
+	data := map[string]interface{}{
+		"log_level":    "debug",
+		"database_url": "postgres://db",
+		"port":         "5432",
+		"ratio":        json.Number("0.5"),
+		"enabled":      "true",
+		"timeout":      "1m30s",
+		"ttl":          float64(3600),
+		"rotated":      "2024-05-01T10:00:00Z",
+		"hosts":        "a, b,c",
+		"ports":        []interface{}{"80", float64(443)},
+		"key":          "c2VjcmV0",
+		"labels":       map[string]interface{}{"team": "payments", "tier": float64(1)},
+		"database":     map[string]interface{}{"host": "db-1", "port": "6432"},
+		"replica":      map[string]interface{}{"host": "db-2"},
+		"bind":         "10.0.0.1",
+		"raw":          map[string]interface{}{"any": true},
+		"REGION":       "eu-west-1",
+		"Ignored":      "x",
+	}
+
+	config := decodeConfig{Ignored: "kept", unexported: "kept"}
+	AssertNoError(t, DecodeInto(data, &config), "DecodeInto()")
+
+	want := decodeConfig{
+		decodeCommon: decodeCommon{LogLevel: "debug"},
+		DatabaseURL:  "postgres://db",
+		Port:         5432,
+		Ratio:        0.5,
+		Enabled:      true,
+		Timeout:      90 * time.Second,
+		TTL:          time.Hour,
+		Rotated:      time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Hosts:        []string{"a", "b", "c"},
+		Ports:        []uint16{80, 443},
+		Key:          []byte("secret"),
+		Labels:       map[string]string{"team": "payments", "tier": "1"},
+		Database:     decodeDatabase{Host: "db-1", Port: 6432},
+		Replica:      &decodeDatabase{Host: "db-2"},
+		Bind:         net.ParseIP("10.0.0.1"),
+		Raw:          map[string]interface{}{"any": true},
+		Region:       "eu-west-1",
+		Ignored:      "kept",
+		unexported:   "kept",
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("DecodeInto() =\n%+v\nwant\n%+v", config, want)
+	}
+}
+
+func TestDecodeInto_KeepsDefaults(t *testing.T) {
+	config := decodeDatabase{Host: "localhost", Port: 5432}
+	AssertNoError(t, DecodeInto(map[string]interface{}{"host": "db-1"}, &config), "DecodeInto()")
+	AssertStringEquals(t, config.Host, "db-1", "Host")
+	if config.Port != 5432 {
+		t.Errorf("Port = %d, want the default 5432", config.Port)
+	}
+}
+
+func TestDecodeInto_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		out     interface{}
+		wantErr string
+	}{
+		{name: "not a pointer", out: decodeDatabase{}, wantErr: "decode target must be a non-nil pointer to a struct, got vaultwatcher.decodeDatabase"},
+		{name: "nil pointer", out: (*decodeDatabase)(nil), wantErr: "decode target must be a non-nil pointer to a struct, got *vaultwatcher.decodeDatabase"},
+		{name: "missing required", data: map[string]interface{}{}, out: &decodeConfig{}, wantErr: `missing required key "database_url"`},
+		{name: "bad int", data: map[string]interface{}{"port": "s3cret"}, out: &decodeDatabase{},
+			wantErr: "failed to decode port: cannot convert string to int"},
+		{name: "nested", data: map[string]interface{}{"database_url": "x", "database": map[string]interface{}{"port": true}}, out: &decodeConfig{},
+			wantErr: "failed to decode database.port: cannot convert bool to int"},
+		{name: "list item", data: map[string]interface{}{"database_url": "x", "ports": []interface{}{"80", "http"}}, out: &decodeConfig{},
+			wantErr: "failed to decode ports[1]: cannot convert string to uint16"},
+		{name: "overflow", data: map[string]interface{}{"database_url": "x", "ports": "70000"}, out: &decodeConfig{},
+			wantErr: "failed to decode ports[0]: cannot convert string to uint16"},
+		{name: "struct from string", data: map[string]interface{}{"database_url": "x", "database": "db-1"}, out: &decodeConfig{},
+			wantErr: "failed to decode database: cannot convert string to vaultwatcher.decodeDatabase"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertError(t, DecodeInto(tt.data, tt.out), tt.wantErr, "DecodeInto()")
+		})
+	}
+}
+
+func TestWatcher_DecodeInto(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1", "port": "6432"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	var config decodeDatabase
+	AssertNoError(t, watcher.DecodeInto(&config), "DecodeInto()")
+	if config != (decodeDatabase{Host: "db-1", Port: 6432}) {
+		t.Errorf("DecodeInto() = %+v", config)
+	}
+}
@@ -0,0 +1,173 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DecryptFailedEvent describes values that couldn't be decrypted with
+// WithTransitDecrypt. Keys are the JSON Pointers of the values, e.g.
+// "/database/password"; the values themselves are never included.
+type DecryptFailedEvent struct {
+	Path      string    `json:"path"`
+	Keys      []string  `json:"keys"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DecryptFailureNotifier is implemented by notifiers that want to know when
+// transit ciphertext in the secret couldn't be decrypted. Notifiers
+// registered with WithNotifier are checked for it automatically.
+type DecryptFailureNotifier interface {
+	NotifyDecryptFailure(ctx context.Context, event DecryptFailedEvent) error
+}
+
+// transitCiphertext is a ciphertext value found in the secret
+type transitCiphertext struct {
+	pointer    string
+	ciphertext string
+	set        func(plaintext string)
+}
+
+// decryptValues returns a copy of data with its transit ciphertext values
+// decrypted, or data itself without WithTransitDecrypt
+func (w *Watcher) decryptValues(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+	if w.transitDecrypt == nil || data == nil {
+		return data, nil
+	}
+
+	var found []transitCiphertext
+	decrypted := collectCiphertexts(data, "", &found).(map[string]interface{})
+	if len(found) == 0 {
+		return data, nil
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].pointer < found[j].pointer })
+
+	pointers := make([]string, len(found))
+	inputs := make([]interface{}, len(found))
+	for i, value := range found {
+		pointers[i] = value.pointer
+		inputs[i] = map[string]interface{}{"ciphertext": value.ciphertext}
+	}
+
+	key := w.transitDecrypt
+	secret, err := w.client.Logical().WriteWithContext(ctx, key.mount+"/decrypt/"+key.key,
+		map[string]interface{}{"batch_input": inputs})
+	if err != nil {
+		err = classifyVaultError(err)
+		w.notifyDecryptFailure(pointers, err.Error())
+		return nil, fmt.Errorf("failed to decrypt %s with transit: %w", strings.Join(pointers, ", "), err)
+	}
+	var results []interface{}
+	if secret != nil && secret.Data != nil {
+		results, _ = secret.Data["batch_results"].([]interface{})
+	}
+	if len(results) != len(found) {
+		reason := fmt.Sprintf("got %d results for %d values", len(results), len(found))
+		w.notifyDecryptFailure(pointers, reason)
+		return nil, fmt.Errorf("failed to decrypt with transit: %s", reason)
+	}
+
+	var failed []string
+	var reason string
+	for i, result := range results {
+		fields, _ := result.(map[string]interface{})
+		message, _ := fields["error"].(string)
+		plaintext, _ := fields["plaintext"].(string)
+		decoded, err := base64.StdEncoding.DecodeString(plaintext)
+		if message == "" && err != nil {
+			message = "plaintext is not base64"
+		}
+		if message != "" {
+			failed = append(failed, found[i].pointer)
+			if reason == "" {
+				reason = message
+			}
+			continue
+		}
+		found[i].set(string(decoded))
+	}
+	if len(failed) > 0 {
+		w.notifyDecryptFailure(failed, reason)
+		return nil, fmt.Errorf("failed to decrypt %s with transit: %s", strings.Join(failed, ", "), reason)
+	}
+	return decrypted, nil
+}
+
+// collectCiphertexts copies value, adding the ciphertext strings in it to
+// found so they can be replaced in the copy
+func collectCiphertexts(value interface{}, pointer string, found *[]transitCiphertext) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			key := key
+			copied[key] = collectCiphertexts(item, pointer+"/"+escapePointer(key), found)
+			if ciphertext, ok := item.(string); ok && isTransitCiphertext(ciphertext) {
+				*found = append(*found, transitCiphertext{
+					pointer:    pointer + "/" + escapePointer(key),
+					ciphertext: ciphertext,
+					set:        func(plaintext string) { copied[key] = plaintext },
+				})
+			}
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for i, item := range typed {
+			i := i
+			copied[i] = collectCiphertexts(item, fmt.Sprintf("%s/%d", pointer, i), found)
+			if ciphertext, ok := item.(string); ok && isTransitCiphertext(ciphertext) {
+				*found = append(*found, transitCiphertext{
+					pointer:    fmt.Sprintf("%s/%d", pointer, i),
+					ciphertext: ciphertext,
+					set:        func(plaintext string) { copied[i] = plaintext },
+				})
+			}
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// isTransitCiphertext reports whether s looks like "vault:v<version>:..."
+func isTransitCiphertext(s string) bool {
+	rest, ok := strings.CutPrefix(s, "vault:v")
+	if !ok {
+		return false
+	}
+	version, _, ok := strings.Cut(rest, ":")
+	if !ok || version == "" {
+		return false
+	}
+	for _, r := range version {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// notifyDecryptFailure tells notifiers which values couldn't be decrypted
+func (w *Watcher) notifyDecryptFailure(pointers []string, reason string) {
+	event := DecryptFailedEvent{
+		Path:      w.vaultConfig.Path,
+		Keys:      pointers,
+		Reason:    reason,
+		Timestamp: time.Now().UTC(),
+	}
+	for _, notifier := range w.notifiers {
+		decryptNotifier, ok := notifier.(DecryptFailureNotifier)
+		if !ok {
+			continue
+		}
+		if err := decryptNotifier.NotifyDecryptFailure(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying decryption failure", "error", w.redactError(err))
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransitDecrypt serves a KV v2 secret and transit/decrypt for key "app".
+// Its ciphertext is "vault:v1:" and the base64 plaintext.
+type fakeTransitDecrypt struct {
+	mu       sync.Mutex
+	password string // Ciphertext of the password
+}
+
+func (f *fakeTransitDecrypt) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path != "/v1/transit/decrypt/app" {
+		fmt.Fprintf(w, `{"data": {"data": {"user": "app", "database": {"password": %q}, "hosts": ["db-1", %q]}, "metadata": {"version": 1}}}`,
+			f.password, encryptForTest("db-2"))
+		return
+	}
+
+	var body struct {
+		BatchInput []struct{ Ciphertext string } `json:"batch_input"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	results := make([]string, len(body.BatchInput))
+	for i, input := range body.BatchInput {
+		encoded := strings.TrimPrefix(input.Ciphertext, "vault:v1:")
+		if _, err := base64.StdEncoding.DecodeString(encoded); err != nil {
+			results[i] = `{"error": "invalid ciphertext: unable to decrypt"}`
+			continue
+		}
+		results[i] = fmt.Sprintf(`{"plaintext": %q}`, encoded)
+	}
+	fmt.Fprintf(w, `{"data": {"batch_results": [%s]}}`, strings.Join(results, ","))
+}
+
+func encryptForTest(plaintext string) string {
+	return "vault:v1:" + base64.StdEncoding.EncodeToString([]byte(plaintext))
+}
+
+// decryptRecorder records decryption failures
+type decryptRecorder struct {
+	NotifierFunc
+	failures chan DecryptFailedEvent
+}
+
+func (r decryptRecorder) NotifyDecryptFailure(ctx context.Context, event DecryptFailedEvent) error {
+	r.failures <- event
+	return nil
+}
+
+func TestIsTransitCiphertext(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "vault:v1:c2VjcmV0", want: true},
+		{value: "vault:v12:c2VjcmV0", want: true},
+		{value: "vault:v:c2VjcmV0"},
+		{value: "vault:vx:c2VjcmV0"},
+		{value: "vault:v1"},
+		{value: "s3cret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			AssertBoolEquals(t, isTransitCiphertext(tt.value), tt.want, "isTransitCiphertext()")
+		})
+	}
+}
+
+func TestWatcher_TransitDecrypt(t *testing.T) {
+	fake := &fakeTransitDecrypt{password: encryptForTest("one")}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	recorder := decryptRecorder{
+		NotifierFunc: func(context.Context, ChangeEvent) error { return nil },
+		failures:     make(chan DecryptFailedEvent, 1),
+	}
+	watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithTransitDecrypt("transit/keys/app"), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	var config struct {
+		User     string   `vault:"user"`
+		Hosts    []string `vault:"hosts"`
+		Database struct {
+			Password string `vault:"password"`
+		} `vault:"database"`
+	}
+	AssertNoError(t, watcher.DecodeInto(&config), "DecodeInto()")
+	AssertStringEquals(t, fmt.Sprint(config.User, config.Hosts, config.Database.Password), "app[db-1 db-2]one", "decoded values")
+
+	hash := watcher.GetCurrentHash()
+	fake.mu.Lock()
+	fake.password = "vault:v1:corrupt"
+	fake.mu.Unlock()
+
+	AssertError(t, watcher.check(), "failed to fetch vault data: failed to decrypt /database/password with transit: invalid ciphertext: unable to decrypt", "check()")
+	AssertStringEquals(t, watcher.GetCurrentHash(), hash, "hash after the failed check")
+	select {
+	case event := <-recorder.failures:
+		AssertStringEquals(t, fmt.Sprint(event.Path, event.Keys), "secret/data/app[/database/password]", "DecryptFailedEvent")
+	default:
+		t.Fatal("decryption failure was not notified")
+	}
+}
+
+func TestWatcher_TransitDecryptErrors(t *testing.T) {
+	_, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, WithTransitDecrypt("transit/app"))
+	AssertError(t, err, `transit key path must look like <mount>/keys/<name>, got "transit/app"`, "NewWatcher() with a bad key path")
+
+	_, err = NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil }, WithTransitDecrypt("transit/keys/app"))
+	AssertError(t, err, "WithTransitDecrypt needs vault and can't be used with a custom source", "NewSourceWatcher()")
+}
@@ -0,0 +1,165 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// srvScheme prefixes a DNS SRV record listing the Vault nodes:
+	// srv://_vault._tcp.example.com
+	srvScheme = "srv://"
+	// consulScheme prefixes a Consul agent address and the Vault service
+	// registered there: consul://127.0.0.1:8500/vault
+	consulScheme = "consul://"
+
+	discoveryTimeout = 5 * time.Second
+)
+
+// lookupSRV resolves SRV records; tests replace it
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// addressResolver looks up the current addresses of the Vault nodes
+type addressResolver func(ctx context.Context) ([]string, error)
+
+// isDiscoveryAddress reports whether host names a service to look up rather
+// than a Vault node
+func isDiscoveryAddress(host string) bool {
+	return strings.HasPrefix(host, srvScheme) || strings.HasPrefix(host, consulScheme)
+}
+
+// newAddressResolver creates the resolver for a srv:// or consul:// address.
+// The scheme query parameter sets how the nodes are reached (default https);
+// Consul addresses also accept tag and dc.
+func newAddressResolver(address string) (addressResolver, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address %q: %w", address, err)
+	}
+
+	query := u.Query()
+	scheme := query.Get("scheme")
+	if scheme == "" {
+		scheme = "https"
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("discovered addresses must be http or https, got %q", scheme)
+	}
+
+	switch u.Scheme {
+	case "srv":
+		if u.Host == "" {
+			return nil, fmt.Errorf("srv address %q has no record name", address)
+		}
+		return func(ctx context.Context) ([]string, error) {
+			return resolveSRV(ctx, u.Host, scheme)
+		}, nil
+	case "consul":
+		service := strings.Trim(u.Path, "/")
+		if u.Host == "" || service == "" {
+			return nil, fmt.Errorf("consul address %q must look like consul://<agent>/<service>", address)
+		}
+		catalog := url.URL{Scheme: "http", Host: u.Host, Path: "/v1/health/service/" + service}
+		params := url.Values{"passing": {"true"}}
+		if tag := query.Get("tag"); tag != "" {
+			params.Set("tag", tag)
+		}
+		if dc := query.Get("dc"); dc != "" {
+			params.Set("dc", dc)
+		}
+		catalog.RawQuery = params.Encode()
+		return func(ctx context.Context) ([]string, error) {
+			return resolveConsul(ctx, catalog.String(), scheme)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported discovery scheme %q", u.Scheme)
+	}
+}
+
+// discoverHosts resolves address once, at startup
+func discoverHosts(resolve addressResolver, address string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	hosts, err := resolve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover vault addresses from %s: %w", address, err)
+	}
+	return hosts, nil
+}
+
+// resolveSRV returns the targets of an SRV record, ordered by priority and weight
+func resolveSRV(ctx context.Context, name, scheme string) ([]string, error) {
+	_, records, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+
+	hosts := make([]string, 0, len(records))
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		hosts = append(hosts, scheme+"://"+net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no vault address found for %s", name)
+	}
+	return hosts, nil
+}
+
+// consulServiceEntry is the part of a Consul health API entry naming a node
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// resolveConsul returns the addresses of the passing instances of a Consul
+// service. CONSUL_HTTP_TOKEN is sent as the ACL token when set.
+func resolveConsul(ctx context.Context, catalog, scheme string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, catalog, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consul request: %w", err)
+	}
+	if token := getEnv("CONSUL_HTTP_TOKEN", ""); token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consul: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned %s", resp.Status)
+	}
+
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse consul response: %w", err)
+	}
+
+	hosts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Services registered without an address use the node's
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		hosts = append(hosts, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no passing instance of the vault service is registered")
+	}
+	return hosts, nil
+}
@@ -0,0 +1,172 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsulCatalog serves the health API for the vault service
+type fakeConsulCatalog struct {
+	mu        sync.Mutex
+	instances []string // host:port of each passing instance
+	query     url.Values
+	token     string
+}
+
+func (c *fakeConsulCatalog) register(servers ...*httptest.Server) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.instances = nil
+	for _, server := range servers {
+		c.instances = append(c.instances, server.Listener.Addr().String())
+	}
+}
+
+func (c *fakeConsulCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r.URL.Path != "/v1/health/service/vault" {
+		http.NotFound(w, r)
+		return
+	}
+	c.query = r.URL.Query()
+	c.token = r.Header.Get("X-Consul-Token")
+
+	fmt.Fprint(w, "[")
+	for i, instance := range c.instances {
+		host, port, _ := net.SplitHostPort(instance)
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		// The first instance is registered without a service address
+		serviceAddress := host
+		if i == 0 {
+			serviceAddress = ""
+		}
+		fmt.Fprintf(w, `{"Node": {"Address": %q}, "Service": {"Address": %q, "Port": %s}}`, host, serviceAddress, port)
+	}
+	fmt.Fprint(w, "]")
+}
+
+func TestWatcher_ConsulDiscovery(t *testing.T) {
+	t.Setenv("CONSUL_HTTP_TOKEN", "consul-token")
+
+	first := &fakeVaultNode{name: "first"}
+	second := &fakeVaultNode{name: "second"}
+	firstServer := httptest.NewServer(first)
+	defer firstServer.Close()
+	secondServer := httptest.NewServer(second)
+	defer secondServer.Close()
+
+	consul := &fakeConsulCatalog{}
+	consul.register(firstServer)
+	consulServer := httptest.NewServer(consul)
+	defer consulServer.Close()
+
+	host := "consul://" + consulServer.Listener.Addr().String() + "/vault?scheme=http&tag=active&dc=eu"
+	watcher, err := NewWatcher(&VaultConfig{Host: host, Path: "secret/data/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithRetryPolicy(RetryPolicy{}))
+	AssertNoError(t, err, "NewWatcher()")
+
+	AssertStringEquals(t, consul.query.Get("passing"), "true", "passing query")
+	AssertStringEquals(t, consul.query.Get("tag"), "active", "tag query")
+	AssertStringEquals(t, consul.query.Get("dc"), "eu", "dc query")
+	AssertStringEquals(t, consul.token, "consul-token", "consul token")
+
+	servedBy := func() string {
+		t.Helper()
+		data, err := watcher.fetchVaultData()
+		AssertNoError(t, err, "fetchVaultData()")
+		return data["node"].(string)
+	}
+	AssertStringEquals(t, servedBy(), "first", "node at startup")
+
+	// The service moves and the old node goes away
+	consul.register(secondServer)
+	firstServer.Close()
+	AssertStringEquals(t, servedBy(), "second", "node after re-resolving")
+}
+
+func TestResolveSRV(t *testing.T) {
+	defer func(lookup func(context.Context, string, string, string) (string, []*net.SRV, error)) {
+		lookupSRV = lookup
+	}(lookupSRV)
+
+	var looked string
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		looked = name
+		return name, []*net.SRV{
+			{Target: "vault-1.example.com.", Port: 8200},
+			{Target: "vault-2.example.com.", Port: 8201},
+		}, nil
+	}
+
+	resolve, err := newAddressResolver("srv://_vault._tcp.example.com")
+	AssertNoError(t, err, "newAddressResolver()")
+	hosts, err := resolve(context.Background())
+	AssertNoError(t, err, "resolve()")
+
+	AssertStringEquals(t, looked, "_vault._tcp.example.com", "looked up name")
+	want := []string{"https://vault-1.example.com:8200", "https://vault-2.example.com:8201"}
+	if len(hosts) != len(want) {
+		t.Fatalf("hosts = %v, want %v", hosts, want)
+	}
+	for i := range want {
+		AssertStringEquals(t, hosts[i], want[i], "host "+strconv.Itoa(i))
+	}
+
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		return name, nil, nil
+	}
+	_, err = NewWatcher(&VaultConfig{Host: "srv://_vault._tcp.example.com", Path: "secret/data/app", Token: "t"},
+		time.Hour, func() error { return nil })
+	AssertError(t, err, "failed to discover vault addresses from srv://_vault._tcp.example.com: no vault address found for _vault._tcp.example.com", "NewWatcher() without records")
+}
+
+func TestNewAddressResolver_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr string
+	}{
+		{
+			name:    "srv without a name",
+			address: "srv://",
+			wantErr: `srv address "srv://" has no record name`,
+		},
+		{
+			name:    "consul without a service",
+			address: "consul://127.0.0.1:8500",
+			wantErr: `consul address "consul://127.0.0.1:8500" must look like consul://<agent>/<service>`,
+		},
+		{
+			name:    "unsupported scheme",
+			address: "srv://_vault._tcp.example.com?scheme=ftp",
+			wantErr: `discovered addresses must be http or https, got "ftp"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAddressResolver(tt.address)
+			AssertError(t, err, tt.wantErr, "newAddressResolver()")
+		})
+	}
+
+	_, err := NewWatcher(&VaultConfig{
+		Host:          "srv://_vault._tcp.example.com",
+		FailoverHosts: []string{"https://vault-2.example.com"},
+		Path:          "secret/data/app",
+		Token:         "t",
+	}, time.Hour, func() error { return nil })
+	AssertError(t, err, "failover hosts cannot be combined with service discovery", "NewWatcher() with failover hosts")
+}
@@ -0,0 +1,182 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// DriftEvent describes the live secret starting or stopping to deviate from
+// the baseline hash given with WithBaselineHash, or from the values given with
+// WithExpectedValues. With expected values, the keys that deviate are listed
+// by name; values are never included.
+type DriftEvent struct {
+	Path         string    `json:"path"`
+	Drifted      bool      `json:"drifted"`
+	BaselineHash string    `json:"baseline_hash,omitempty"`
+	CurrentHash  string    `json:"current_hash"`
+	Missing      []string  `json:"missing,omitempty"`    // Expected keys absent from the secret
+	Extra        []string  `json:"extra,omitempty"`      // Keys of the secret that aren't expected
+	Mismatched   []string  `json:"mismatched,omitempty"` // Keys whose value differs from the expected one
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// keyDrift lists the keys deviating from the expected values
+type keyDrift struct {
+	missing, extra, mismatched []string
+}
+
+func (d keyDrift) drifted() bool {
+	return len(d.missing) > 0 || len(d.extra) > 0 || len(d.mismatched) > 0
+}
+
+// DriftNotifier is implemented by notifiers that want drift events. Notifiers
+// registered with WithNotifier are checked for it automatically.
+type DriftNotifier interface {
+	NotifyDrift(ctx context.Context, event DriftEvent) error
+}
+
+// IsDrifted returns whether the live secret differed from the baseline hash or
+// the expected values at the last check. It is always false without
+// WithBaselineHash or WithExpectedValues.
+func (w *Watcher) IsDrifted() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.drifted
+}
+
+// SetBaselineHash replaces the expected hash, e.g. after the baseline was
+// updated in git. The next check compares against it.
+func (w *Watcher) SetBaselineHash(hash string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.baselineHash = hash
+}
+
+// SetExpectedValues replaces the values given with WithExpectedValues or
+// WithExpectedValuesFile. The next check compares against them; nil stops
+// comparing values.
+func (w *Watcher) SetExpectedValues(values map[string]interface{}) error {
+	hashes, err := expectedKeyHashes(values)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expectedHashes = hashes
+	return nil
+}
+
+// loadExpectedValues hashes the expected values, reading them from
+// WithExpectedValuesFile if given
+func (w *Watcher) loadExpectedValues() error {
+	values := w.expectedValues
+	if w.expectedValuesFile != "" {
+		source, err := NewFileSource(w.expectedValuesFile, "")
+		if err != nil {
+			return fmt.Errorf("invalid expected values: %w", err)
+		}
+		values, _, err = source.Fetch(context.Background())
+		if err != nil {
+			return fmt.Errorf("invalid expected values: %w", err)
+		}
+	}
+	hashes, err := expectedKeyHashes(values)
+	if err != nil {
+		return err
+	}
+	w.expectedHashes = hashes
+	// Only the hashes are kept
+	w.expectedValues = nil
+	return nil
+}
+
+func expectedKeyHashes(values map[string]interface{}) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+	hashes, err := CalculateKeyHashes(values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expected values: %w", err)
+	}
+	return hashes, nil
+}
+
+// diffKeys compares the key hashes of the live secret with the expected ones
+func diffKeys(expected, live map[string]string) keyDrift {
+	var drift keyDrift
+	for key, hash := range expected {
+		liveHash, ok := live[key]
+		switch {
+		case !ok:
+			drift.missing = append(drift.missing, key)
+		case liveHash != hash:
+			drift.mismatched = append(drift.mismatched, key)
+		}
+	}
+	for key := range live {
+		if _, ok := expected[key]; !ok {
+			drift.extra = append(drift.extra, key)
+		}
+	}
+	sort.Strings(drift.missing)
+	sort.Strings(drift.extra)
+	sort.Strings(drift.mismatched)
+	return drift
+}
+
+// checkDrift compares the live secret with the baseline hash and the expected
+// values, and emits a drift event when the secret starts or stops deviating
+// from them, or when the deviating keys change
+func (w *Watcher) checkDrift(data map[string]interface{}, liveHash string) {
+	w.mu.RLock()
+	baseline := w.baselineHash
+	expected := w.expectedHashes
+	w.mu.RUnlock()
+	if baseline == "" && expected == nil {
+		return
+	}
+
+	var drift keyDrift
+	if expected != nil {
+		live, err := CalculateKeyHashes(data)
+		if err != nil {
+			w.logger.Error("Error comparing vault data with expected values", "error", w.redactError(err))
+			return
+		}
+		drift = diffKeys(expected, live)
+	}
+
+	w.mu.Lock()
+	drifted := (baseline != "" && liveHash != baseline) || drift.drifted()
+	changed := drifted != w.drifted || !reflect.DeepEqual(drift, w.keyDrift)
+	w.drifted = drifted
+	w.keyDrift = drift
+	w.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	event := DriftEvent{
+		Path:         w.vaultConfig.Path,
+		Drifted:      drifted,
+		BaselineHash: baseline,
+		CurrentHash:  liveHash,
+		Missing:      drift.missing,
+		Extra:        drift.extra,
+		Mismatched:   drift.mismatched,
+		Timestamp:    time.Now().UTC(),
+	}
+	for _, notifier := range w.notifiers {
+		driftNotifier, ok := notifier.(DriftNotifier)
+		if !ok {
+			continue
+		}
+		if err := driftNotifier.NotifyDrift(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying vault drift", "error", w.redactError(err))
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// driftRecorder collects drift events
+type driftRecorder struct {
+	mu     sync.Mutex
+	events []DriftEvent
+}
+
+func (r *driftRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *driftRecorder) NotifyDrift(ctx context.Context, event DriftEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *driftRecorder) drifted() []bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	drifted := make([]bool, len(r.events))
+	for i, event := range r.events {
+		drifted[i] = event.Drifted
+	}
+	return drifted
+}
+
+func TestWatcher_BaselineHash(t *testing.T) {
+	expected := map[string]interface{}{"password": "one"}
+	baseline, err := CalculateHash(expected)
+	AssertNoError(t, err, "CalculateHash()")
+
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", expected)
+
+	recorder := &driftRecorder{}
+	changes := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			changes++
+			return nil
+		}, WithBaselineHash(baseline), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertBoolEquals(t, watcher.IsDrifted(), false, "IsDrifted() at start")
+
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.checkForChanges(), "check after drift")
+	AssertNoError(t, watcher.checkForChanges(), "check while drifted")
+	AssertBoolEquals(t, watcher.IsDrifted(), true, "IsDrifted() after drift")
+
+	vault.Put("secret/app", expected)
+	AssertNoError(t, watcher.checkForChanges(), "check after revert")
+	AssertBoolEquals(t, watcher.IsDrifted(), false, "IsDrifted() after revert")
+
+	if got := recorder.drifted(); len(got) != 2 || !got[0] || got[1] {
+		t.Errorf("drift events = %v, want [true false]", got)
+	}
+	recorder.mu.Lock()
+	AssertStringEquals(t, recorder.events[0].BaselineHash, baseline, "BaselineHash")
+	recorder.mu.Unlock()
+	if changes != 2 {
+		t.Errorf("changes = %d, want 2: drift detection still follows changes", changes)
+	}
+
+	// A new baseline the live data does not match
+	watcher.SetBaselineHash("other")
+	AssertNoError(t, watcher.checkForChanges(), "check with new baseline")
+	AssertBoolEquals(t, watcher.IsDrifted(), true, "IsDrifted() with new baseline")
+}
+
+func TestWatcher_BaselineHashDriftedAtStart(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "live"})
+
+	recorder := &driftRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil }, WithBaselineHash("expected"), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertBoolEquals(t, watcher.IsDrifted(), true, "IsDrifted()")
+	if got := recorder.drifted(); len(got) != 1 || !got[0] {
+		t.Errorf("drift events = %v, want [true]", got)
+	}
+}
+
+func TestWatcher_ExpectedValues(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1", "port": "5432"})
+
+	recorder := &driftRecorder{}
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil },
+		WithExpectedValues(map[string]interface{}{"host": "db-1", "port": "5432"}), WithNotifier(recorder))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	tests := []struct {
+		name           string
+		data           map[string]interface{}
+		wantDrifted    bool
+		wantEvents     int
+		wantMissing    string
+		wantExtra      string
+		wantMismatched string
+	}{
+		{name: "as expected", data: map[string]interface{}{"host": "db-1", "port": "5432"}},
+		{name: "mismatched", data: map[string]interface{}{"host": "db-2", "port": "5432"},
+			wantDrifted: true, wantEvents: 1, wantMissing: "[]", wantExtra: "[]", wantMismatched: "[host]"},
+		{name: "same drift again", data: map[string]interface{}{"host": "db-3", "port": "5432"},
+			wantDrifted: true, wantEvents: 1, wantMissing: "[]", wantExtra: "[]", wantMismatched: "[host]"},
+		{name: "missing and extra", data: map[string]interface{}{"host": "db-1", "user": "app"},
+			wantDrifted: true, wantEvents: 2, wantMissing: "[port]", wantExtra: "[user]", wantMismatched: "[]"},
+		{name: "back to expected", data: map[string]interface{}{"host": "db-1", "port": "5432"},
+			wantEvents: 3, wantMissing: "[]", wantExtra: "[]", wantMismatched: "[]"},
+	}
+
+	for _, tt := range tests {
+		source.set(tt.data)
+		AssertNoError(t, watcher.checkForChanges(), tt.name)
+		AssertBoolEquals(t, watcher.IsDrifted(), tt.wantDrifted, tt.name+": IsDrifted()")
+
+		recorder.mu.Lock()
+		events := append([]DriftEvent(nil), recorder.events...)
+		recorder.mu.Unlock()
+		if len(events) != tt.wantEvents {
+			t.Fatalf("%s: %d drift events, want %d", tt.name, len(events), tt.wantEvents)
+		}
+		if tt.wantEvents == 0 {
+			continue
+		}
+		last := events[len(events)-1]
+		AssertBoolEquals(t, last.Drifted, tt.wantDrifted, tt.name+": Drifted")
+		AssertStringEquals(t, fmt.Sprint(last.Missing), tt.wantMissing, tt.name+": Missing")
+		AssertStringEquals(t, fmt.Sprint(last.Extra), tt.wantExtra, tt.name+": Extra")
+		AssertStringEquals(t, fmt.Sprint(last.Mismatched), tt.wantMismatched, tt.name+": Mismatched")
+	}
+
+	// New expectations apply from the next check
+	AssertNoError(t, watcher.SetExpectedValues(map[string]interface{}{"host": "db-1"}), "SetExpectedValues()")
+	AssertNoError(t, watcher.checkForChanges(), "check with new expected values")
+	AssertBoolEquals(t, watcher.IsDrifted(), true, "IsDrifted() with new expected values")
+}
+
+func TestWatcher_ExpectedValuesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "expected.env")
+	AssertNoError(t, os.WriteFile(path, []byte("HOST=db-1\nPORT=5432\n"), 0600), "WriteFile()")
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"HOST": "db-1", "PORT": "6432"})
+
+	recorder := &driftRecorder{}
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil },
+		WithExpectedValuesFile(path), WithNotifier(recorder))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertBoolEquals(t, watcher.IsDrifted(), true, "IsDrifted() at start")
+	recorder.mu.Lock()
+	AssertStringEquals(t, fmt.Sprint(recorder.events[0].Mismatched), "[PORT]", "Mismatched")
+	recorder.mu.Unlock()
+
+	_, err = NewSourceWatcher("app", source, time.Hour, func() error { return nil },
+		WithExpectedValuesFile(filepath.Join(dir, "missing.json")))
+	if err == nil {
+		t.Error("NewSourceWatcher() with a missing expected values file: expected an error")
+	}
+}
@@ -0,0 +1,359 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultDynamicRetryInterval = 5 * time.Second
+
+// dynamicRevokeTimeout bounds revoking credentials the callback rejected,
+// which also happens while the watcher stops
+const dynamicRevokeTimeout = 10 * time.Second
+
+// DynamicSecret holds credentials issued by a dynamic secrets engine
+// (e.g. database/creds/<role>) together with their lease
+type DynamicSecret struct {
+	Path          string
+	Data          map[string]interface{}
+	LeaseID       string
+	LeaseDuration time.Duration // Lease duration as of the last issue or renewal
+	Renewable     bool
+	IssuedAt      time.Time // When the credentials were issued
+	RenewedAt     time.Time // When the lease was last issued or renewed
+}
+
+// ExpiresAt returns when the lease expires unless it is renewed
+func (s *DynamicSecret) ExpiresAt() time.Time {
+	return s.RenewedAt.Add(s.LeaseDuration)
+}
+
+// Get returns the value of a key as a string, e.g. Get("username")
+func (s *DynamicSecret) Get(key string) string {
+	value, ok := s.Data[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return fmt.Sprint(value)
+}
+
+// lease returns the lease of the secret
+func (s *DynamicSecret) lease() Lease {
+	return Lease{
+		ID:        s.LeaseID,
+		Path:      s.Path,
+		Duration:  s.LeaseDuration,
+		Renewable: s.Renewable,
+		RenewedAt: s.RenewedAt,
+	}
+}
+
+// DynamicSecretOption configures optional DynamicSecretWatcher behaviour
+type DynamicSecretOption func(*DynamicSecretWatcher)
+
+// WithRetryInterval sets how long to wait before retrying a failed renewal or
+// rotation (default 5s). It is capped so retries still happen before expiry.
+func WithRetryInterval(interval time.Duration) DynamicSecretOption {
+	return func(w *DynamicSecretWatcher) {
+		if interval > 0 {
+			w.retryInterval = interval
+		}
+	}
+}
+
+// WithLeaseManager makes the watcher track its leases in a shared LeaseManager
+// instead of a private one. The shared manager must be started by the caller.
+func WithLeaseManager(manager *LeaseManager) DynamicSecretOption {
+	return func(w *DynamicSecretWatcher) {
+		if manager != nil {
+			w.leaseManager = manager
+			w.ownsLeaseManager = false
+		}
+	}
+}
+
+// WithDynamicNotifier registers a notifier receiving a ChangeEvent each time
+// the credentials are replaced. It can be given multiple times.
+func WithDynamicNotifier(notifier Notifier) DynamicSecretOption {
+	return func(w *DynamicSecretWatcher) {
+		if notifier != nil {
+			w.notifiers = append(w.notifiers, notifier)
+		}
+	}
+}
+
+// DynamicSecretWatcher keeps credentials from a dynamic secrets endpoint fresh.
+// Its lease is renewed by a LeaseManager; when the lease can't be renewed, was
+// revoked, or has reached its max TTL, new credentials are requested and passed
+// to the callback before the old ones expire.
+type DynamicSecretWatcher struct {
+	vaultConfig      *VaultConfig
+	client           *api.Client
+	onRotate         func(*DynamicSecret) error
+	retryInterval    time.Duration
+	leaseManager     *LeaseManager
+	ownsLeaseManager bool
+	leaseEvents      chan LeaseEvent
+	notifiers        []Notifier
+	logger           *slog.Logger
+	current          *DynamicSecret
+	ctx              context.Context
+	cancel           context.CancelFunc
+	wg               sync.WaitGroup
+	mu               sync.RWMutex
+	started          bool
+}
+
+// NewDynamicSecretWatcher creates a watcher for a dynamic secrets endpoint
+// vaultConfig: Vault connection configuration, Path is e.g. "database/creds/readonly"
+// onRotate: Callback invoked with the initial and every newly issued set of credentials
+func NewDynamicSecretWatcher(vaultConfig *VaultConfig, onRotate func(*DynamicSecret) error, opts ...DynamicSecretOption) (*DynamicSecretWatcher, error) {
+	if err := validateVaultConfig(vaultConfig); err != nil {
+		return nil, err
+	}
+	if onRotate == nil {
+		return nil, fmt.Errorf("onRotate callback cannot be nil")
+	}
+
+	client, err := newVaultClient(vaultConfig, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &DynamicSecretWatcher{
+		vaultConfig:      vaultConfig,
+		client:           client,
+		onRotate:         onRotate,
+		retryInterval:    defaultDynamicRetryInterval,
+		ownsLeaseManager: true,
+		leaseEvents:      make(chan LeaseEvent, 8),
+		logger:           slog.Default(),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.leaseManager == nil {
+		w.leaseManager = newLeaseManager(client, WithLeaseRetryInterval(w.retryInterval))
+	}
+
+	return w, nil
+}
+
+// Start requests the initial credentials, passes them to the callback and
+// begins managing their lease
+func (w *DynamicSecretWatcher) Start() error {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return fmt.Errorf("watcher is already started")
+	}
+	w.started = true
+	w.mu.Unlock()
+
+	if err := w.rotate(); err != nil {
+		w.mu.Lock()
+		w.started = false
+		w.mu.Unlock()
+		return fmt.Errorf("failed to issue initial credentials: %w", err)
+	}
+
+	if w.ownsLeaseManager {
+		if err := w.leaseManager.Start(); err != nil {
+			w.leaseManager.Untrack(w.Current().LeaseID)
+			w.mu.Lock()
+			w.started = false
+			w.mu.Unlock()
+			return fmt.Errorf("failed to start lease manager: %w", err)
+		}
+	}
+
+	w.wg.Add(1)
+	go w.manage()
+
+	return nil
+}
+
+// Stop stops managing the lease. The current credentials stay valid until their lease expires.
+func (w *DynamicSecretWatcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+
+	if current := w.Current(); current != nil {
+		w.leaseManager.Untrack(current.LeaseID)
+	}
+	if w.ownsLeaseManager {
+		w.leaseManager.Stop()
+	}
+
+	w.mu.Lock()
+	w.started = false
+	w.mu.Unlock()
+}
+
+// Current returns the credentials most recently delivered to the callback
+func (w *DynamicSecretWatcher) Current() *DynamicSecret {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// IsStarted returns whether the watcher is currently running
+func (w *DynamicSecretWatcher) IsStarted() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.started
+}
+
+// handleLeaseEvent is the lease handler. It hands events over to manage so the
+// shared lease manager is never blocked by the rotation callback.
+func (w *DynamicSecretWatcher) handleLeaseEvent(event LeaseEvent) {
+	select {
+	case w.leaseEvents <- event:
+	case <-w.ctx.Done():
+	}
+}
+
+// manage runs in a goroutine and reacts to lease events, retrying failed rotations
+func (w *DynamicSecretWatcher) manage() {
+	defer w.wg.Done()
+
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event := <-w.leaseEvents:
+			current := w.Current()
+			if current == nil || event.Lease.ID != current.LeaseID {
+				// Event for a lease that was already replaced
+				continue
+			}
+			if event.Type == LeaseRenewed {
+				w.mu.Lock()
+				updated := *current
+				updated.LeaseDuration = event.Lease.Duration
+				updated.RenewedAt = event.Lease.RenewedAt
+				w.current = &updated
+				w.mu.Unlock()
+				continue
+			}
+			if event.Type == LeaseRenewalFailed {
+				// The lease may have been revoked; replace the credentials right away
+				w.logger.Error("Error renewing dynamic secret lease", "error", event.Error)
+			}
+		case <-retry:
+		}
+
+		retry = nil
+		if err := w.rotate(); err != nil {
+			w.logger.Error("Error refreshing dynamic secret", "error", err)
+			retry = time.After(w.retryDelay())
+		}
+	}
+}
+
+// retryDelay returns the retry interval, shortened so retries happen before the lease expires
+func (w *DynamicSecretWatcher) retryDelay() time.Duration {
+	remaining := time.Until(w.Current().ExpiresAt())
+	if delay := remaining / 4; delay < w.retryInterval {
+		return max(delay, minLeaseRetryInterval)
+	}
+	return w.retryInterval
+}
+
+// rotate issues new credentials and passes them to the callback. The current
+// credentials are only replaced once the callback has accepted the new ones.
+func (w *DynamicSecretWatcher) rotate() error {
+	secret, err := w.client.Logical().ReadWithContext(w.ctx, w.vaultConfig.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read dynamic secret from vault: %w", classifyVaultError(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return fmt.Errorf("failed to read dynamic secret from vault: %w", ErrSecretNotFound)
+	}
+	if secret.LeaseDuration <= 0 {
+		return fmt.Errorf("secret at %s has no lease, use Watcher for static secrets", w.vaultConfig.Path)
+	}
+
+	now := time.Now()
+	issued := &DynamicSecret{
+		Path:          w.vaultConfig.Path,
+		Data:          secret.Data,
+		LeaseID:       secret.LeaseID,
+		LeaseDuration: time.Duration(secret.LeaseDuration) * time.Second,
+		Renewable:     secret.Renewable,
+		IssuedAt:      now,
+		RenewedAt:     now,
+	}
+
+	if err := w.onRotate(issued); err != nil {
+		// Nothing tracks the rejected lease, so each retry would leave one behind
+		w.revoke(issued.LeaseID)
+		return &CallbackError{Callback: "onRotate", Err: err}
+	}
+
+	w.mu.Lock()
+	previous := w.current
+	w.current = issued
+	w.mu.Unlock()
+
+	if previous != nil {
+		w.leaseManager.Untrack(previous.LeaseID)
+	}
+	if err := w.leaseManager.Track(issued.lease(), w.handleLeaseEvent); err != nil {
+		return fmt.Errorf("failed to track lease: %w", err)
+	}
+
+	if previous != nil {
+		w.notify(previous, issued)
+	}
+
+	return nil
+}
+
+// revoke revokes a lease that won't be used. Failures are logged; the lease
+// then expires on its own.
+func (w *DynamicSecretWatcher) revoke(leaseID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(w.ctx), dynamicRevokeTimeout)
+	defer cancel()
+	if err := w.client.Sys().RevokeWithContext(ctx, leaseID); err != nil {
+		w.logger.Error("Error revoking rejected dynamic secret lease", "error", classifyVaultError(err))
+	}
+}
+
+// notify delivers a change event for replaced credentials to every registered notifier
+func (w *DynamicSecretWatcher) notify(previous, issued *DynamicSecret) {
+	if len(w.notifiers) == 0 {
+		return
+	}
+
+	event := ChangeEvent{
+		Path:      issued.Path,
+		Timestamp: time.Now().UTC(),
+	}
+	oldKeyHashes, oldErr := CalculateKeyHashes(previous.Data)
+	newKeyHashes, newErr := CalculateKeyHashes(issued.Data)
+	if oldErr == nil && newErr == nil {
+		event.ChangedKeys = ChangedKeys(oldKeyHashes, newKeyHashes)
+	}
+	event.OldHash, _ = CalculateHash(previous.Data)
+	event.NewHash, _ = CalculateHash(issued.Data)
+
+	for _, notifier := range w.notifiers {
+		if err := notifier.Notify(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying dynamic secret rotation", "error", err)
+		}
+	}
+}
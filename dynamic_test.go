@@ -0,0 +1,219 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDynamicVault issues numbered credentials with a one second lease
+type fakeDynamicVault struct {
+	mu        sync.Mutex
+	issued    int
+	renewals  int
+	renewable bool
+	renewTTL  int
+	revoked   bool
+	noLease   bool
+	revokes   []string // Leases revoked by the client
+	server    *httptest.Server
+}
+
+func newFakeDynamicVault(renewable bool, renewTTL int) *fakeDynamicVault {
+	f := &fakeDynamicVault{renewable: renewable, renewTTL: renewTTL}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *fakeDynamicVault) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/database/creds/readonly":
+		f.issued++
+		leaseDuration := 1
+		if f.noLease {
+			leaseDuration = 0
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       fmt.Sprintf("database/creds/readonly/lease-%d", f.issued),
+			"lease_duration": leaseDuration,
+			"renewable":      f.renewable,
+			"data": map[string]interface{}{
+				"username": fmt.Sprintf("v-user-%d", f.issued),
+				"password": "secret",
+			},
+		})
+	case "/v1/sys/leases/renew":
+		f.renewals++
+		if f.revoked {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"lease not found"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_duration": f.renewTTL,
+			"renewable":      true,
+		})
+	case "/v1/sys/leases/revoke":
+		var body struct {
+			LeaseID string `json:"lease_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.revokes = append(f.revokes, body.LeaseID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeDynamicVault) counts() (issued, renewals int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.issued, f.renewals
+}
+
+func (f *fakeDynamicVault) config() *VaultConfig {
+	return &VaultConfig{Host: f.server.URL, Path: "database/creds/readonly", Token: "test-token"}
+}
+
+// waitFor polls cond until it is true or the timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool, context string) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: timed out", context)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewDynamicSecretWatcher(t *testing.T) {
+	_, err := NewDynamicSecretWatcher(nil, func(*DynamicSecret) error { return nil })
+	AssertError(t, err, "vault config cannot be nil", "NewDynamicSecretWatcher(nil config)")
+
+	_, err = NewDynamicSecretWatcher(TestVaultConfig(), nil)
+	AssertError(t, err, "onRotate callback cannot be nil", "NewDynamicSecretWatcher(nil callback)")
+
+	watcher, err := NewDynamicSecretWatcher(TestVaultConfig(), func(*DynamicSecret) error { return nil }, WithRetryInterval(time.Second))
+	AssertNoError(t, err, "NewDynamicSecretWatcher()")
+	if watcher.retryInterval != time.Second {
+		t.Errorf("retryInterval = %v, want 1s", watcher.retryInterval)
+	}
+}
+
+func TestDynamicSecretWatcher_RotatesNonRenewableLease(t *testing.T) {
+	vault := newFakeDynamicVault(false, 0)
+	defer vault.server.Close()
+
+	var mu sync.Mutex
+	var usernames []string
+	watcher, err := NewDynamicSecretWatcher(vault.config(), func(secret *DynamicSecret) error {
+		mu.Lock()
+		defer mu.Unlock()
+		usernames = append(usernames, secret.Get("username"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	current := watcher.Current()
+	AssertStringEquals(t, current.Get("username"), "v-user-1", "initial credentials")
+	AssertStringEquals(t, current.LeaseID, "database/creds/readonly/lease-1", "initial lease")
+	if current.LeaseDuration != time.Second {
+		t.Errorf("LeaseDuration = %v, want 1s", current.LeaseDuration)
+	}
+
+	// New credentials must arrive before the one second lease expires
+	waitFor(t, 900*time.Millisecond, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(usernames) >= 2
+	}, "rotation before expiry")
+
+	mu.Lock()
+	AssertStringEquals(t, usernames[1], "v-user-2", "rotated credentials")
+	mu.Unlock()
+}
+
+func TestDynamicSecretWatcher_RenewsLease(t *testing.T) {
+	vault := newFakeDynamicVault(true, 1)
+	defer vault.server.Close()
+
+	watcher, err := NewDynamicSecretWatcher(vault.config(), func(*DynamicSecret) error { return nil })
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, renewals := vault.counts()
+		return renewals >= 2
+	}, "lease renewals")
+
+	issued, _ := vault.counts()
+	if issued != 1 {
+		t.Errorf("issued %d credentials, want 1 while the lease is renewable", issued)
+	}
+	AssertStringEquals(t, watcher.Current().Get("username"), "v-user-1", "renewed credentials")
+}
+
+func TestDynamicSecretWatcher_RotatesOnRevocation(t *testing.T) {
+	vault := newFakeDynamicVault(true, 1)
+	vault.revoked = true
+	defer vault.server.Close()
+
+	watcher, err := NewDynamicSecretWatcher(vault.config(), func(*DynamicSecret) error { return nil })
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		issued, _ := vault.counts()
+		return issued >= 2
+	}, "rotation after revocation")
+}
+
+func TestDynamicSecretWatcher_StartErrors(t *testing.T) {
+	vault := newFakeDynamicVault(false, 0)
+	defer vault.server.Close()
+
+	failing, err := NewDynamicSecretWatcher(vault.config(), func(*DynamicSecret) error { return errors.New("reconnect failed") })
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+	AssertError(t, failing.Start(), "failed to issue initial credentials: onRotate callback failed: reconnect failed", "Start()")
+	AssertBoolEquals(t, failing.IsStarted(), false, "IsStarted() after failed start")
+	vault.mu.Lock()
+	AssertStringEquals(t, fmt.Sprint(vault.revokes), "[database/creds/readonly/lease-1]", "revoked leases")
+	vault.mu.Unlock()
+
+	vault.noLease = true
+	static, err := NewDynamicSecretWatcher(vault.config(), func(*DynamicSecret) error { return nil })
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+	if err := static.Start(); err == nil {
+		t.Error("Start() expected error for a secret without a lease")
+	}
+}
@@ -0,0 +1,200 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// StateCipher encrypts state and cached secrets before they are written to
+// disk or another store
+type StateCipher interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// AESStateCipher encrypts with AES-GCM under a locally provided key. Each
+// ciphertext starts with its random nonce.
+type AESStateCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAESStateCipher creates a cipher from a 16, 24 or 32 byte key
+func NewAESStateCipher(key []byte) (*AESStateCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return &AESStateCipher{aead: aead}, nil
+}
+
+// Encrypt seals plaintext under a new random nonce
+func (c *AESStateCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt
+func (c *AESStateCipher) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	size := c.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, fmt.Errorf("failed to decrypt: ciphertext is too short")
+	}
+	plaintext, err := c.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// TransitStateCipher encrypts with a key of Vault's transit secrets engine,
+// so no key material is kept locally. Data can't be decrypted while Vault is
+// unreachable; use AESStateCipher for caches that must work offline.
+type TransitStateCipher struct {
+	client *api.Client
+	mount  string
+	key    string
+}
+
+// NewTransitStateCipher creates a cipher using the transit key at
+// vaultConfig.Path, e.g. "transit/keys/watcher-state"
+func NewTransitStateCipher(vaultConfig *VaultConfig) (*TransitStateCipher, error) {
+	if err := validateVaultConfig(vaultConfig); err != nil {
+		return nil, err
+	}
+	mount, key, ok := strings.Cut(vaultConfig.Path, "/keys/")
+	if !ok || mount == "" || key == "" {
+		return nil, fmt.Errorf("transit key path must look like <mount>/keys/<name>, got %q", vaultConfig.Path)
+	}
+
+	client, err := newVaultClient(vaultConfig, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TransitStateCipher{client: client, mount: mount, key: key}, nil
+}
+
+// Encrypt encrypts plaintext with transit; the result is a vault:v<n>: ciphertext
+func (c *TransitStateCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	secret, err := c.client.Logical().WriteWithContext(ctx, c.mount+"/encrypt/"+c.key, map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt with transit: %w", classifyVaultError(err))
+	}
+	ciphertext, _ := secretField(secret, "ciphertext")
+	if ciphertext == "" {
+		return nil, fmt.Errorf("failed to encrypt with transit: response has no ciphertext")
+	}
+	return []byte(ciphertext), nil
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt
+func (c *TransitStateCipher) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	secret, err := c.client.Logical().WriteWithContext(ctx, c.mount+"/decrypt/"+c.key, map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with transit: %w", classifyVaultError(err))
+	}
+	encoded, ok := secretField(secret, "plaintext")
+	if !ok {
+		return nil, fmt.Errorf("failed to decrypt with transit: response has no plaintext")
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with transit: %w", err)
+	}
+	return plaintext, nil
+}
+
+// secretField returns a string field of a secret's data
+func secretField(secret *api.Secret, field string) (string, bool) {
+	if secret == nil || secret.Data == nil {
+		return "", false
+	}
+	value, ok := secret.Data[field].(string)
+	return value, ok
+}
+
+// EncryptedStateStore encrypts the values of another StateStore, e.g. a
+// FileStateStore on a shared volume
+type EncryptedStateStore struct {
+	store  StateStore
+	cipher StateCipher
+}
+
+// NewEncryptedStateStore wraps store so values are encrypted with cipher
+func NewEncryptedStateStore(store StateStore, cipher StateCipher) *EncryptedStateStore {
+	return &EncryptedStateStore{store: store, cipher: cipher}
+}
+
+// Get returns the decrypted value of key, or nil if it doesn't exist
+func (s *EncryptedStateStore) Get(ctx context.Context, key string) ([]byte, error) {
+	plaintext, _, err := s.get(ctx, key)
+	return plaintext, err
+}
+
+// Put encrypts value and stores it as key
+func (s *EncryptedStateStore) Put(ctx context.Context, key string, value []byte) error {
+	ciphertext, err := s.cipher.Encrypt(ctx, value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt state %s: %w", key, err)
+	}
+	return s.store.Put(ctx, key, ciphertext)
+}
+
+// CompareAndSwap sets key to new only if its decrypted value is old (nil:
+// absent). Ciphertexts differ on every encryption, so the stored ciphertext
+// is swapped after comparing its plaintext.
+func (s *EncryptedStateStore) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error) {
+	plaintext, ciphertext, err := s.get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if (old == nil) != (ciphertext == nil) || !bytes.Equal(plaintext, old) {
+		return false, nil
+	}
+
+	encrypted, err := s.cipher.Encrypt(ctx, new)
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt state %s: %w", key, err)
+	}
+	return s.store.CompareAndSwap(ctx, key, ciphertext, encrypted)
+}
+
+// Delete removes key
+func (s *EncryptedStateStore) Delete(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, key)
+}
+
+// get returns the decrypted and the stored value of key
+func (s *EncryptedStateStore) get(ctx context.Context, key string) ([]byte, []byte, error) {
+	ciphertext, err := s.store.Get(ctx, key)
+	if err != nil || ciphertext == nil {
+		return nil, nil, err
+	}
+	plaintext, err := s.cipher.Decrypt(ctx, ciphertext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt state %s: %w", key, err)
+	}
+	if plaintext == nil {
+		// An empty value still exists
+		plaintext = []byte{}
+	}
+	return plaintext, ciphertext, nil
+}
@@ -0,0 +1,125 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestAESStateCipher(t *testing.T) {
+	cipher, err := NewAESStateCipher(bytes.Repeat([]byte("k"), 32))
+	AssertNoError(t, err, "NewAESStateCipher()")
+	ctx := context.Background()
+
+	first, err := cipher.Encrypt(ctx, []byte("handled-hash"))
+	AssertNoError(t, err, "Encrypt()")
+	second, _ := cipher.Encrypt(ctx, []byte("handled-hash"))
+	AssertBoolEquals(t, bytes.Equal(first, second), false, "ciphertexts of the same plaintext are equal")
+	AssertBoolEquals(t, bytes.Contains(first, []byte("handled-hash")), false, "ciphertext contains the plaintext")
+
+	plaintext, err := cipher.Decrypt(ctx, first)
+	AssertNoError(t, err, "Decrypt()")
+	AssertStringEquals(t, string(plaintext), "handled-hash", "Decrypt()")
+
+	first[len(first)-1] ^= 1
+	_, err = cipher.Decrypt(ctx, first)
+	AssertError(t, err, "failed to decrypt: cipher: message authentication failed", "Decrypt() of a tampered ciphertext")
+	_, err = cipher.Decrypt(ctx, []byte("short"))
+	AssertError(t, err, "failed to decrypt: ciphertext is too short", "Decrypt() of a short ciphertext")
+
+	_, err = NewAESStateCipher([]byte("too short"))
+	AssertError(t, err, "invalid encryption key: crypto/aes: invalid key size 9", "NewAESStateCipher() with a short key")
+}
+
+func TestTransitStateCipher(t *testing.T) {
+	// A fake transit engine "encrypting" by prefixing the base64 plaintext
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/state":
+			fmt.Fprintf(w, `{"data": {"ciphertext": "vault:v1:%s"}}`, body["plaintext"])
+		case "/v1/transit/decrypt/state":
+			fmt.Fprintf(w, `{"data": {"plaintext": %q}}`, strings.TrimPrefix(body["ciphertext"], "vault:v1:"))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+		}
+	}))
+	defer server.Close()
+
+	cipher, err := NewTransitStateCipher(&VaultConfig{Host: server.URL, Path: "transit/keys/state", Token: "t"})
+	AssertNoError(t, err, "NewTransitStateCipher()")
+	ctx := context.Background()
+
+	ciphertext, err := cipher.Encrypt(ctx, []byte("handled-hash"))
+	AssertNoError(t, err, "Encrypt()")
+	AssertStringEquals(t, string(ciphertext), "vault:v1:"+base64.StdEncoding.EncodeToString([]byte("handled-hash")), "ciphertext")
+
+	plaintext, err := cipher.Decrypt(ctx, ciphertext)
+	AssertNoError(t, err, "Decrypt()")
+	AssertStringEquals(t, string(plaintext), "handled-hash", "Decrypt()")
+	AssertStringEquals(t, strings.Join(paths, ","), "/v1/transit/encrypt/state,/v1/transit/decrypt/state", "requests")
+
+	denied, err := NewTransitStateCipher(&VaultConfig{Host: server.URL, Path: "other/keys/state", Token: "t"})
+	AssertNoError(t, err, "NewTransitStateCipher()")
+	_, err = denied.Encrypt(ctx, []byte("x"))
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Encrypt() error = %v, want ErrPermissionDenied", err)
+	}
+
+	_, err = NewTransitStateCipher(&VaultConfig{Host: server.URL, Path: "transit/state", Token: "t"})
+	AssertError(t, err, `transit key path must look like <mount>/keys/<name>, got "transit/state"`, "NewTransitStateCipher() with a bad path")
+}
+
+func TestFileSync_Encryption(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+	cipher, err := NewAESStateCipher(make([]byte, 32))
+	AssertNoError(t, err, "NewAESStateCipher()")
+
+	path := filepath.Join(t.TempDir(), "app.json")
+	fileSync, err := NewFileSync(watcher, path, time.Hour, WithFileEncryption(cipher))
+	AssertNoError(t, err, "NewFileSync()")
+	AssertNoError(t, fileSync.Start(), "Start()")
+	defer fileSync.Stop()
+
+	content, err := os.ReadFile(path)
+	AssertNoError(t, err, "ReadFile()")
+	AssertBoolEquals(t, bytes.Contains(content, []byte("one")), false, "file contains the plaintext")
+
+	// While Vault is unreachable the decrypted file is served
+	vault.SetSealed(true)
+	if err := fileSync.Sync(); err == nil {
+		t.Error("Sync() while sealed should fail")
+	}
+	AssertStringEquals(t, fileSync.Data()["password"].(string), "one", "Data() while sealed")
+
+	// A plaintext file can't be decrypted
+	vault.SetSealed(false)
+	writeSyncFile(t, path, map[string]interface{}{"password": "two"})
+	err = fileSync.Sync()
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to decrypt sync file:") {
+		t.Errorf("Sync() of a plaintext file error = %v, want a decryption error", err)
+	}
+}
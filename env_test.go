@@ -188,3 +188,27 @@ func TestLoadVaultConfigFromEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadVaultConfigFromEnv_FailoverHosts(t *testing.T) {
+	t.Setenv("VAULT_HOST", "https://vault-1.example.com, https://vault-2.example.com,https://vault-3.example.com")
+	t.Setenv("VAULT_PATH", "kv/data/myapp/config")
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	config, err := LoadVaultConfigFromEnv()
+	AssertNoError(t, err, "LoadVaultConfigFromEnv()")
+	AssertStringEquals(t, config.Host, "https://vault-1.example.com", "Host")
+	if len(config.FailoverHosts) != 2 || config.FailoverHosts[0] != "https://vault-2.example.com" || config.FailoverHosts[1] != "https://vault-3.example.com" {
+		t.Errorf("FailoverHosts = %v, want vault-2 and vault-3", config.FailoverHosts)
+	}
+}
+
+func TestLoadVaultConfigFromEnv_Namespace(t *testing.T) {
+	t.Setenv("VAULT_HOST", "https://vault.example.com")
+	t.Setenv("VAULT_PATH", "kv/data/myapp/config")
+	t.Setenv("VAULT_TOKEN", "test-token")
+	t.Setenv("VAULT_NAMESPACE", "team-a")
+
+	config, err := LoadVaultConfigFromEnv()
+	AssertNoError(t, err, "LoadVaultConfigFromEnv()")
+	AssertStringEquals(t, config.Namespace, "team-a", "Namespace")
+}
@@ -0,0 +1,115 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Errors returned by watchers, for use with errors.Is. Errors from the Vault
+// API keep their *api.ResponseError, which errors.As can still extract.
+var (
+	// ErrSecretNotFound means there is no secret at the path, or the requested
+	// KV v2 version is deleted
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrPermissionDenied means the token may not perform the request
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrVaultSealed means Vault is sealed or not initialized and cannot serve requests
+	ErrVaultSealed = errors.New("vault is sealed")
+	// ErrVaultStandby means the Vault node is a standby that cannot serve the request
+	ErrVaultStandby = errors.New("vault is in standby")
+	// ErrRateLimited means Vault rejected the request because of a rate limit quota
+	ErrRateLimited = errors.New("rate limited by vault")
+	// ErrCallbackFailed means a callback such as onChange returned an error.
+	// The error is a *CallbackError holding the cause.
+	ErrCallbackFailed = errors.New("callback failed")
+	// ErrVersionConflict means a check-and-set write found a newer version
+	ErrVersionConflict = errors.New("secret changed since the observed version")
+	// ErrValidationFailed means a changed secret was rejected by WithSchema or
+	// WithSecretValidator
+	ErrValidationFailed = errors.New("secret failed validation")
+	// ErrCubbyholeInaccessible means a cubbyhole path can no longer be read
+	// because the token owning it was replaced, expired or was revoked
+	ErrCubbyholeInaccessible = errors.New("cubbyhole is inaccessible")
+)
+
+// CallbackError is returned when a callback fails. It matches
+// ErrCallbackFailed and unwraps to the callback's error.
+type CallbackError struct {
+	Callback string // Name of the callback, e.g. "onChange"
+	Err      error
+}
+
+func (e *CallbackError) Error() string {
+	return e.Callback + " callback failed: " + e.Err.Error()
+}
+
+func (e *CallbackError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrCallbackFailed
+func (e *CallbackError) Is(target error) bool {
+	return target == ErrCallbackFailed
+}
+
+// RequestError adds the ID of the Vault request whose data a check was
+// handling when it failed, so the failure can be found in Vault's audit log.
+// It unwraps to the error.
+type RequestError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error() + " (vault request " + e.RequestID + ")"
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// sentinelError adds a sentinel to an error without changing its message
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func (e *sentinelError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sentinelError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
+
+// classifyVaultError adds the sentinel matching the status code and messages
+// of an error from the Vault API. Other errors are returned unchanged.
+func classifyVaultError(err error) error {
+	var responseErr *api.ResponseError
+	if !errors.As(err, &responseErr) {
+		return err
+	}
+
+	message := strings.ToLower(strings.Join(responseErr.Errors, "; "))
+	var sentinel error
+	switch {
+	case strings.Contains(message, "vault is sealed") || strings.Contains(message, "not initialized"):
+		sentinel = ErrVaultSealed
+	case strings.Contains(message, "standby") || strings.Contains(message, "node not active"):
+		sentinel = ErrVaultStandby
+	case strings.Contains(message, "check-and-set"):
+		sentinel = ErrVersionConflict
+	case responseErr.StatusCode == http.StatusUnauthorized || responseErr.StatusCode == http.StatusForbidden:
+		sentinel = ErrPermissionDenied
+	case responseErr.StatusCode == http.StatusNotFound:
+		sentinel = ErrSecretNotFound
+	case responseErr.StatusCode == http.StatusTooManyRequests:
+		sentinel = ErrRateLimited
+	default:
+		return err
+	}
+	return &sentinelError{sentinel: sentinel, err: err}
+}
@@ -0,0 +1,199 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestWatcher_SentinelErrors(t *testing.T) {
+	errInvalid := errors.New("invalid config")
+
+	tests := []struct {
+		name  string
+		setup func(vault *vaultwatchertest.Server) (*Watcher, error)
+		run   func(w *Watcher) error
+		want  error
+	}{
+		{
+			name: "secret not found",
+			setup: func(vault *vaultwatchertest.Server) (*Watcher, error) {
+				return NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/missing", Token: vault.Token}, time.Hour, func() error { return nil })
+			},
+			run:  func(w *Watcher) error { return w.Start() },
+			want: ErrSecretNotFound,
+		},
+		{
+			name: "permission denied",
+			setup: func(vault *vaultwatchertest.Server) (*Watcher, error) {
+				return NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: "wrong"}, time.Hour, func() error { return nil })
+			},
+			run:  func(w *Watcher) error { return w.Start() },
+			want: ErrPermissionDenied,
+		},
+		{
+			name: "vault sealed",
+			setup: func(vault *vaultwatchertest.Server) (*Watcher, error) {
+				vault.SetSealed(true)
+				return NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour, func() error { return nil }, WithSealAwareness())
+			},
+			run:  func(w *Watcher) error { return w.Start() },
+			want: ErrVaultSealed,
+		},
+		{
+			name: "vault sealed without seal awareness",
+			setup: func(vault *vaultwatchertest.Server) (*Watcher, error) {
+				vault.SetSealed(true)
+				return NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour, func() error { return nil })
+			},
+			run:  func(w *Watcher) error { return w.Start() },
+			want: ErrVaultSealed,
+		},
+		{
+			name: "callback failed",
+			setup: func(vault *vaultwatchertest.Server) (*Watcher, error) {
+				return NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour, func() error { return errInvalid })
+			},
+			run: func(w *Watcher) error {
+				if err := w.Start(); err != nil {
+					return err
+				}
+				return w.ApplyRemoteChange(ChangeEvent{Path: "secret/data/app", NewHash: "new"})
+			},
+			want: ErrCallbackFailed,
+		},
+		{
+			name: "version conflict",
+			setup: func(vault *vaultwatchertest.Server) (*Watcher, error) {
+				return NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour, func() error { return nil })
+			},
+			run: func(w *Watcher) error {
+				_, err := w.UpdateSecret(context.Background(), map[string]interface{}{"password": "two"}, UpdateOptions{Version: 5})
+				return err
+			},
+			want: ErrVersionConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := vaultwatchertest.NewServer()
+			defer vault.Close()
+			vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+			watcher, err := tt.setup(vault)
+			AssertNoError(t, err, "NewWatcher()")
+			defer watcher.Stop()
+
+			err = tt.run(watcher)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want errors.Is(err, %v)", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("callback cause", func(t *testing.T) {
+		err := error(&CallbackError{Callback: "onChange", Err: errInvalid})
+		AssertBoolEquals(t, errors.Is(err, errInvalid), true, "errors.Is(err, cause)")
+		AssertStringEquals(t, err.Error(), "onChange callback failed: invalid config", "Error()")
+		var callbackErr *CallbackError
+		AssertBoolEquals(t, errors.As(err, &callbackErr), true, "errors.As(err, *CallbackError)")
+	})
+
+}
+
+func TestClassifyVaultError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		errors []string
+		want   error
+	}{
+		{name: "unauthorized", status: 401, want: ErrPermissionDenied},
+		{name: "forbidden", status: 403, errors: []string{"permission denied"}, want: ErrPermissionDenied},
+		{name: "not found", status: 404, want: ErrSecretNotFound},
+		{name: "rate limited", status: 429, errors: []string{"request path \"secret/data/app\": rate limit quota exceeded"}, want: ErrRateLimited},
+		{name: "sealed", status: 503, errors: []string{"Vault is sealed"}, want: ErrVaultSealed},
+		{name: "not initialized", status: 503, errors: []string{"Vault is not initialized"}, want: ErrVaultSealed},
+		{name: "standby", status: 503, errors: []string{"Vault is in standby mode"}, want: ErrVaultStandby},
+		{name: "inactive node", status: 500, errors: []string{"local node not active but active cluster node not found"}, want: ErrVaultStandby},
+		{name: "check-and-set", status: 400, errors: []string{"check-and-set parameter did not match the current version"}, want: ErrVersionConflict},
+		{name: "other", status: 500, errors: []string{"internal error"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseErr := &api.ResponseError{StatusCode: tt.status, Errors: tt.errors}
+			err := classifyVaultError(fmt.Errorf("request failed: %w", responseErr))
+
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want errors.Is(err, %v)", err, tt.want)
+			}
+			if tt.want == nil {
+				var sentinel *sentinelError
+				AssertBoolEquals(t, errors.As(err, &sentinel), false, "classified")
+			}
+
+			var unwrapped *api.ResponseError
+			AssertBoolEquals(t, errors.As(err, &unwrapped), true, "errors.As(err, *api.ResponseError)")
+			if unwrapped != responseErr {
+				t.Error("errors.As() returned a different *api.ResponseError")
+			}
+			AssertStringEquals(t, err.Error(), fmt.Errorf("request failed: %w", responseErr).Error(), "Error()")
+		})
+	}
+
+	other := errors.New("connection refused")
+	if classifyVaultError(other) != other {
+		t.Error("classifyVaultError() changed a non-API error")
+	}
+}
+
+func TestWatcher_RequestID(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isMountLookup(r) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"request_id": "req-%d", "warnings": ["ignored parameter"], "data": {"data": {"password": "v%d"}, "metadata": {"version": %d}}}`, n, n, n)
+	}))
+	defer server.Close()
+
+	var fail atomic.Bool
+	recorder := &changeRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour, func() error {
+		if fail.Load() {
+			return errors.New("reload failed")
+		}
+		return nil
+	}, WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertNoError(t, watcher.check(), "check()")
+	recorder.mu.Lock()
+	event := recorder.events[0]
+	recorder.mu.Unlock()
+	AssertStringEquals(t, event.RequestID+" "+fmt.Sprint(event.Warnings), "req-2 [ignored parameter]", "event request ID and warnings")
+
+	fail.Store(true)
+	err = watcher.check()
+	AssertError(t, err, "onChange callback failed: reload failed (vault request req-3)", "check() with a failing callback")
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || requestErr.RequestID != "req-3" {
+		t.Errorf("errors.As(*RequestError) = %v, want request req-3", requestErr)
+	}
+	AssertBoolEquals(t, errors.Is(err, ErrCallbackFailed), true, "errors.Is(ErrCallbackFailed)")
+}
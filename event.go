@@ -0,0 +1,31 @@
+package vaultwatcher
+
+import "time"
+
+// ChangeEvent describes a change detected at a watched Vault path.
+// It carries secret values only in Patch, which is set by WithJSONPatch and
+// keeps values only with WithPatchValues.
+type ChangeEvent struct {
+	Path        string    `json:"path"`
+	MountType   MountType `json:"mount_type,omitempty"` // Empty for sources outside Vault
+	OldHash     string    `json:"old_hash"`
+	NewHash     string    `json:"new_hash"`
+	ChangedKeys []string  `json:"changed_keys"`
+	Timestamp   time.Time `json:"timestamp"` // When the change was detected
+
+	// KV v2 version metadata, zero for KV v1 secrets or unknown versions
+	Version         int        `json:"version,omitempty"`
+	PreviousVersion int        `json:"previous_version,omitempty"`
+	CreatedTime     *time.Time `json:"created_time,omitempty"` // When the version was written, nil if unknown
+
+	// RequestID and Warnings come from the Vault response the change was
+	// read from; the request ID matches Vault's audit log
+	RequestID string   `json:"request_id,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+
+	// DetectionLatency is the time from writing the version to detecting it
+	DetectionLatency time.Duration `json:"detection_latency,omitempty"`
+
+	// Patch is an RFC 6902 JSON Patch from the old to the new data
+	Patch []PatchOperation `json:"patch,omitempty"`
+}
@@ -0,0 +1,235 @@
+package vaultwatcher
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultExpiryLeadTimes are the lead times of WithExpiryMonitoring unless
+// others are given
+var defaultExpiryLeadTimes = []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}
+
+// ExpiringSoonEvent describes a value of the secret, such as a certificate or
+// a token, getting within a lead time of its expiry, or expiring. It names the
+// key but never includes the value.
+type ExpiringSoonEvent struct {
+	Path      string        `json:"path"`
+	Key       string        `json:"key"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	LeadTime  time.Duration `json:"lead_time"` // Lead time crossed, zero once expired
+	Expired   bool          `json:"expired"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// ExpiryNotifier is implemented by notifiers that want expiry events.
+// Notifiers registered with WithNotifier are checked for it automatically.
+type ExpiryNotifier interface {
+	NotifyExpiringSoon(ctx context.Context, event ExpiringSoonEvent) error
+}
+
+// ExpiryParser interprets a value of the secret and returns when it expires,
+// or false when the value doesn't expire or isn't one it understands
+type ExpiryParser func(key string, value interface{}) (time.Time, bool)
+
+// expiryState is what the watcher remembers about an expiring value
+type expiryState struct {
+	expiresAt time.Time
+	notified  bool
+	leadTime  time.Duration // Smallest lead time reported
+}
+
+// CertificateExpiry parses PEM certificates. A chain expires with its
+// earliest certificate.
+func CertificateExpiry(key string, value interface{}) (time.Time, bool) {
+	text, ok := value.(string)
+	if !ok || !strings.Contains(text, "-----BEGIN CERTIFICATE-----") {
+		return time.Time{}, false
+	}
+	var expiresAt time.Time
+	rest := []byte(text)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if expiresAt.IsZero() || cert.NotAfter.Before(expiresAt) {
+			expiresAt = cert.NotAfter
+		}
+	}
+	return expiresAt, !expiresAt.IsZero()
+}
+
+// JWTExpiry parses the exp claim of JSON Web Tokens. Signatures aren't
+// verified.
+func JWTExpiry(key string, value interface{}) (time.Time, bool) {
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	parts := strings.Split(strings.TrimSpace(text), ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if !decodeJWTPart(parts[0], &header) || header.Alg == "" {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if !decodeJWTPart(parts[1], &claims) || claims.Exp == "" {
+		return time.Time{}, false
+	}
+	return unixExpiry(claims.Exp)
+}
+
+func decodeJWTPart(part string, out interface{}) bool {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, out) == nil
+}
+
+// TimestampExpiry parses RFC 3339 timestamps, dates and Unix times in keys
+// named like expires_at, expiry, expiration or not_after, alone or as a
+// suffix, e.g. "cert_expires_at"
+func TimestampExpiry(key string, value interface{}) (time.Time, bool) {
+	if !isExpiryKey(key) {
+		return time.Time{}, false
+	}
+	switch v := value.(type) {
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, true
+			}
+		}
+		return unixExpiry(json.Number(strings.TrimSpace(v)))
+	case json.Number:
+		return unixExpiry(v)
+	case float64:
+		return time.Unix(int64(v), 0), v > 0
+	case int:
+		return time.Unix(int64(v), 0), v > 0
+	case int64:
+		return time.Unix(v, 0), v > 0
+	}
+	return time.Time{}, false
+}
+
+func isExpiryKey(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, name := range []string{"expires_at", "expires", "expiry", "expiration", "not_after"} {
+		if key == name || strings.HasSuffix(key, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+func unixExpiry(n json.Number) (time.Time, bool) {
+	seconds, err := n.Int64()
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// parseExpiry returns when value expires, according to the first parser that
+// understands it
+func (w *Watcher) parseExpiry(key string, value interface{}) (time.Time, bool) {
+	for _, parse := range w.expiryParsers {
+		if expiresAt, ok := parse(key, value); ok {
+			return expiresAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// checkExpiry looks for expiring values in the secret and emits an event each
+// time one crosses a lead time, and once it expired. A value replaced by one
+// expiring later, e.g. a renewed certificate, starts over.
+func (w *Watcher) checkExpiry(data map[string]interface{}) {
+	if len(w.expiryLeadTimes) == 0 {
+		return
+	}
+	now := time.Now()
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var events []ExpiringSoonEvent
+	w.mu.Lock()
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		expiresAt, ok := w.parseExpiry(key, data[key])
+		if !ok {
+			continue
+		}
+		seen[key] = true
+		state := w.expiries[key]
+		if state == nil || !state.expiresAt.Equal(expiresAt) {
+			state = &expiryState{expiresAt: expiresAt}
+			w.expiries[key] = state
+		}
+
+		remaining := expiresAt.Sub(now)
+		var leadTime time.Duration
+		crossed := remaining <= 0
+		for _, lead := range w.expiryLeadTimes {
+			if remaining > 0 && remaining <= lead && (!crossed || lead < leadTime) {
+				leadTime, crossed = lead, true
+			}
+		}
+		if !crossed || (state.notified && leadTime >= state.leadTime) {
+			continue
+		}
+		state.notified, state.leadTime = true, leadTime
+		events = append(events, ExpiringSoonEvent{
+			Path:      w.vaultConfig.Path,
+			Key:       key,
+			ExpiresAt: expiresAt.UTC(),
+			LeadTime:  leadTime,
+			Expired:   remaining <= 0,
+			Timestamp: now.UTC(),
+		})
+	}
+	for key := range w.expiries {
+		if !seen[key] {
+			delete(w.expiries, key)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, event := range events {
+		for _, notifier := range w.notifiers {
+			expiryNotifier, ok := notifier.(ExpiryNotifier)
+			if !ok {
+				continue
+			}
+			if err := expiryNotifier.NotifyExpiringSoon(w.ctx, event); err != nil {
+				w.logger.Error("Error notifying vault expiry", "error", w.redactError(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package vaultwatcher
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCertificate returns a self-signed PEM certificate expiring at notAfter
+func testCertificate(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	AssertNoError(t, err, "GenerateKey()")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "app"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	AssertNoError(t, err, "CreateCertificate()")
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// testJWT returns a JWT with the given claims and a dummy signature
+func testJWT(claims map[string]interface{}) string {
+	part := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	return part(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + part(claims) + ".c2lnbmF0dXJl"
+}
+
+func TestExpiryParsers(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	earlier := expiresAt.Add(-time.Hour)
+	chain := testCertificate(t, expiresAt) + testCertificate(t, earlier)
+
+	tests := []struct {
+		name   string
+		parse  ExpiryParser
+		key    string
+		value  interface{}
+		want   time.Time
+		wantOK bool
+	}{
+		{name: "certificate", parse: CertificateExpiry, key: "tls_crt", value: testCertificate(t, expiresAt), want: expiresAt, wantOK: true},
+		{name: "chain expires first", parse: CertificateExpiry, key: "tls_crt", value: chain, want: earlier, wantOK: true},
+		{name: "not a certificate", parse: CertificateExpiry, key: "tls_crt", value: "plain"},
+		{name: "jwt", parse: JWTExpiry, key: "token", value: testJWT(map[string]interface{}{"exp": expiresAt.Unix()}), want: expiresAt, wantOK: true},
+		{name: "jwt without exp", parse: JWTExpiry, key: "token", value: testJWT(map[string]interface{}{"sub": "app"})},
+		{name: "dotted value", parse: JWTExpiry, key: "host", value: "db.example.com"},
+		{name: "rfc 3339", parse: TimestampExpiry, key: "expires_at", value: "2030-01-02T03:04:05Z", want: expiresAt, wantOK: true},
+		{name: "suffixed key", parse: TimestampExpiry, key: "Cert-Expiry", value: "2030-01-02", want: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "unix time", parse: TimestampExpiry, key: "not_after", value: json.Number(fmt.Sprint(expiresAt.Unix())), want: expiresAt, wantOK: true},
+		{name: "unix time string", parse: TimestampExpiry, key: "expiration", value: fmt.Sprint(expiresAt.Unix()), want: expiresAt, wantOK: true},
+		{name: "other key", parse: TimestampExpiry, key: "created_at", value: "2030-01-02T03:04:05Z"},
+		{name: "not a time", parse: TimestampExpiry, key: "expires_at", value: "never"},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.parse(tt.key, tt.value)
+		AssertBoolEquals(t, ok, tt.wantOK, tt.name)
+		if ok && !got.Equal(tt.want) {
+			t.Errorf("%s: expiry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// expiryRecorder collects expiry events
+type expiryRecorder struct {
+	mu     sync.Mutex
+	events []ExpiringSoonEvent
+}
+
+func (r *expiryRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *expiryRecorder) NotifyExpiringSoon(ctx context.Context, event ExpiringSoonEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *expiryRecorder) take() []ExpiringSoonEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func TestWatcher_ExpiryMonitoring(t *testing.T) {
+	now := time.Now()
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1", "tls_crt": testCertificate(t, now.Add(60*24*time.Hour))})
+
+	recorder := &expiryRecorder{}
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil },
+		WithExpiryMonitoring([]time.Duration{24 * time.Hour, 7 * 24 * time.Hour}), WithNotifier(recorder))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	certIn5Days := testCertificate(t, now.Add(5*24*time.Hour))
+	tests := []struct {
+		name       string
+		data       map[string]interface{}
+		wantEvents string // key/lead time/expired of each event
+	}{
+		{name: "far from expiry", data: map[string]interface{}{"host": "db-1", "tls_crt": testCertificate(t, now.Add(60*24*time.Hour))}},
+		{name: "within 7 days", data: map[string]interface{}{"host": "db-1", "tls_crt": certIn5Days},
+			wantEvents: "[tls_crt/168h0m0s/false]"},
+		{name: "reported once", data: map[string]interface{}{"host": "db-1", "tls_crt": certIn5Days}},
+		{name: "within a day", data: map[string]interface{}{"host": "db-1", "tls_crt": certIn5Days, "expires_at": now.Add(time.Hour).Format(time.RFC3339)},
+			wantEvents: "[expires_at/24h0m0s/false]"},
+		{name: "expired", data: map[string]interface{}{"host": "db-1", "tls_crt": certIn5Days, "expires_at": now.Add(-time.Minute).Format(time.RFC3339),
+			"token": testJWT(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})},
+			wantEvents: "[expires_at/0s/true token/0s/true]"},
+		{name: "renewed", data: map[string]interface{}{"host": "db-1", "tls_crt": testCertificate(t, now.Add(90*24*time.Hour))}},
+	}
+
+	for _, tt := range tests {
+		source.set(tt.data)
+		AssertNoError(t, watcher.checkForChanges(), tt.name)
+		var got []string
+		for _, event := range recorder.take() {
+			AssertStringEquals(t, event.Path, "app", tt.name+": Path")
+			got = append(got, fmt.Sprintf("%s/%v/%v", event.Key, event.LeadTime, event.Expired))
+		}
+		want := tt.wantEvents
+		if want == "" {
+			want = "[]"
+		}
+		AssertStringEquals(t, fmt.Sprint(got), want, tt.name+": events")
+	}
+}
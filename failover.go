@@ -0,0 +1,200 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const failoverHealthTimeout = 2 * time.Second
+
+// failoverTransport sends requests to one of several Vault addresses. It
+// sticks to the address that last worked and only moves on when it is
+// unreachable or sealed, to the next address whose health check passes. With
+// a resolver, the addresses are looked up again once none of them works; if
+// still none does, the request fails with an error.
+type failoverTransport struct {
+	next    http.RoundTripper
+	resolve addressResolver
+
+	mu        sync.Mutex
+	addresses []*url.URL
+	current   int
+}
+
+// newFailoverTransport creates a transport failing over between hosts, the
+// first of which is preferred at startup
+func newFailoverTransport(next http.RoundTripper, hosts []string) (*failoverTransport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	addresses, err := parseFailoverAddresses(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &failoverTransport{next: next, addresses: addresses}, nil
+}
+
+// parseFailoverAddresses parses hosts, which must all be http or https
+func parseFailoverAddresses(hosts []string) ([]*url.URL, error) {
+	addresses := make([]*url.URL, 0, len(hosts))
+	for _, host := range hosts {
+		address, err := url.Parse(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vault address %q: %w", host, err)
+		}
+		if address.Scheme != "http" && address.Scheme != "https" {
+			return nil, fmt.Errorf("failover addresses must be http or https, got %q", host)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// active returns the addresses and the index requests are currently sent to
+func (t *failoverTransport) active() ([]*url.URL, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.addresses, t.current
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body may have to be sent more than once
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	addresses, start := t.active()
+	resp, err := t.roundTrip(req, body, addresses, start)
+	if err == nil || t.resolve == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+
+	// Every known address failed, so the service may have moved
+	hosts, resolveErr := t.resolve(req.Context())
+	if resolveErr != nil {
+		return nil, fmt.Errorf("%w (re-resolving failed: %v)", err, resolveErr)
+	}
+	if addresses, resolveErr = parseFailoverAddresses(hosts); resolveErr != nil {
+		return nil, fmt.Errorf("%w (re-resolving failed: %v)", err, resolveErr)
+	}
+	t.mu.Lock()
+	t.addresses, t.current = addresses, 0
+	t.mu.Unlock()
+
+	return t.roundTrip(req, body, addresses, 0)
+}
+
+// roundTrip tries addresses from start on and sticks to the first that works
+func (t *failoverTransport) roundTrip(req *http.Request, body []byte, addresses []*url.URL, start int) (*http.Response, error) {
+	var lastErr error
+	for i := range addresses {
+		index := (start + i) % len(addresses)
+		if i > 0 && !t.healthy(req.Context(), addresses[index]) {
+			continue
+		}
+
+		resp, err := t.next.RoundTrip(redirectTo(req, addresses[index], body))
+		if err == nil && resp.StatusCode != http.StatusServiceUnavailable {
+			if index != start {
+				t.mu.Lock()
+				t.current = index
+				t.mu.Unlock()
+				slog.Warn("Vault is unavailable, failed over", "from", addresses[start].Host, "to", addresses[index].Host)
+			}
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+		if err == nil {
+			err = unavailableError(addresses[index], resp)
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("no vault address is available: %w", lastErr)
+}
+
+// unavailableError closes the 503 response of address and describes it. It
+// wraps ErrVaultSealed if Vault said it is sealed.
+func unavailableError(address *url.URL, resp *http.Response) error {
+	sealed := isSealedResponse(resp)
+	resp.Body.Close()
+	if sealed {
+		return fmt.Errorf("vault at %s is sealed: %w", address.Host, ErrVaultSealed)
+	}
+	return fmt.Errorf("vault at %s returned %s", address.Host, resp.Status)
+}
+
+// redirectTo copies req with its URL pointing to address. The path prefix of
+// the address, for a Vault behind a reverse proxy, replaces the one the
+// request was built with.
+func redirectTo(req *http.Request, address *url.URL, body []byte) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.URL.Scheme = address.Scheme
+	clone.URL.Host = address.Host
+	clone.URL.Path = withAddressPrefix(address.Path, clone.URL.Path)
+	if clone.URL.RawPath != "" {
+		clone.URL.RawPath = withAddressPrefix(address.EscapedPath(), clone.URL.RawPath)
+	}
+	clone.Host = address.Host
+	return withBody(clone, body)
+}
+
+// withBody sets the body of req, which is a copy only the caller uses, to a
+// fresh reader of body
+func withBody(req *http.Request, body []byte) *http.Request {
+	if body != nil {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	return req
+}
+
+// withAddressPrefix replaces what precedes the /v1/ API path in path with prefix
+func withAddressPrefix(prefix, path string) string {
+	api := strings.Index(path, "/v1/")
+	if api < 0 {
+		return path
+	}
+	return strings.TrimSuffix(prefix, "/") + path[api:]
+}
+
+// healthy checks sys/health of address. Standbys count as healthy since they
+// forward requests to the active node.
+func (t *failoverTransport) healthy(ctx context.Context, address *url.URL) bool {
+	ctx, cancel := context.WithTimeout(ctx, failoverHealthTimeout)
+	defer cancel()
+
+	health := *address
+	health.Path = strings.TrimSuffix(address.Path, "/") + "/v1/sys/health"
+	health.RawPath = ""
+	health.RawQuery = "standbyok=true&perfstandbyok=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, health.String(), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
@@ -0,0 +1,159 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVaultNode serves a secret and sys/health, and can be sealed
+type fakeVaultNode struct {
+	name string
+
+	mu     sync.Mutex
+	sealed bool
+	reads  int
+}
+
+func (n *fakeVaultNode) setSealed(sealed bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sealed = sealed
+}
+
+func (n *fakeVaultNode) readCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.reads
+}
+
+func (n *fakeVaultNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if n.sealed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"errors": ["Vault is sealed"]}`)
+		return
+	}
+	if r.URL.Path == "/v1/sys/health" {
+		fmt.Fprint(w, `{"initialized": true, "sealed": false, "standby": false}`)
+		return
+	}
+	if isMountLookup(r) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	n.reads++
+	fmt.Fprintf(w, `{"data": {"data": {"node": %q}, "metadata": {"version": 1}}}`, n.name)
+}
+
+func TestWatcher_FailoverHosts(t *testing.T) {
+	primary := &fakeVaultNode{name: "primary"}
+	secondary := &fakeVaultNode{name: "secondary"}
+	primaryServer := httptest.NewServer(primary)
+	defer primaryServer.Close()
+	secondaryServer := httptest.NewServer(secondary)
+	defer secondaryServer.Close()
+
+	watcher, err := NewWatcher(&VaultConfig{
+		Host:          primaryServer.URL,
+		FailoverHosts: []string{secondaryServer.URL},
+		Path:          "secret/data/app",
+		Token:         "t",
+	}, time.Hour, func() error { return nil }, WithRetryPolicy(RetryPolicy{}))
+	AssertNoError(t, err, "NewWatcher()")
+
+	servedBy := func() string {
+		t.Helper()
+		data, err := watcher.fetchVaultData()
+		AssertNoError(t, err, "fetchVaultData()")
+		return data["node"].(string)
+	}
+
+	AssertStringEquals(t, servedBy(), "primary", "node while primary is up")
+
+	primary.setSealed(true)
+	AssertStringEquals(t, servedBy(), "secondary", "node while primary is sealed")
+
+	// Sticks to the secondary once the primary is back
+	primary.setSealed(false)
+	AssertStringEquals(t, servedBy(), "secondary", "node after primary recovered")
+
+	secondary.setSealed(true)
+	AssertStringEquals(t, servedBy(), "primary", "node while secondary is sealed")
+
+	// An unreachable address is skipped like a sealed one
+	secondaryServer.Close()
+	primary.setSealed(true)
+	_, err = watcher.fetchVaultData()
+	if err == nil || !strings.Contains(err.Error(), "vault is sealed") && !strings.Contains(err.Error(), "no vault address is available") {
+		t.Errorf("fetchVaultData() error = %v, want every address unavailable", err)
+	}
+	if primary.readCount() != 2 || secondary.readCount() != 2 {
+		t.Errorf("reads = %d/%d, want 2/2", primary.readCount(), secondary.readCount())
+	}
+}
+
+func TestNewFailoverTransport_Errors(t *testing.T) {
+	_, err := NewWatcher(&VaultConfig{
+		Host:          "https://vault-1.example.com",
+		FailoverHosts: []string{"unix:///var/run/vault.sock"},
+		Path:          "secret/data/app",
+		Token:         "t",
+	}, time.Hour, func() error { return nil })
+	AssertError(t, err, `failover addresses must be http or https, got "unix:///var/run/vault.sock"`, "NewWatcher()")
+}
+
+func TestWatcher_FailoverAllSealed(t *testing.T) {
+	primary := &fakeVaultNode{name: "primary"}
+	secondary := &fakeVaultNode{name: "secondary"}
+	primaryServer := httptest.NewServer(primary)
+	defer primaryServer.Close()
+	secondaryServer := httptest.NewServer(secondary)
+	defer secondaryServer.Close()
+
+	watcher, err := NewWatcher(&VaultConfig{
+		Host:          primaryServer.URL,
+		FailoverHosts: []string{secondaryServer.URL},
+		Path:          "secret/data/app",
+		Token:         "t",
+	}, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+
+	primary.setSealed(true)
+	secondary.setSealed(true)
+	_, err = watcher.fetchVaultData()
+	if !errors.Is(err, ErrVaultSealed) || !strings.Contains(err.Error(), "no vault address is available") {
+		t.Errorf("fetchVaultData() error = %v, want every address sealed", err)
+	}
+}
+
+func TestWatcher_FailoverAddressPrefix(t *testing.T) {
+	primary := &fakeVaultNode{name: "primary"}
+	secondary := &fakeVaultNode{name: "secondary"}
+	primaryServer := httptest.NewServer(http.StripPrefix("/vault-a", primary))
+	defer primaryServer.Close()
+	secondaryServer := httptest.NewServer(http.StripPrefix("/vault-b", secondary))
+	defer secondaryServer.Close()
+
+	watcher, err := NewWatcher(&VaultConfig{
+		Host:          primaryServer.URL + "/vault-a",
+		FailoverHosts: []string{secondaryServer.URL + "/vault-b/"},
+		Path:          "secret/data/app",
+		Token:         "t",
+	}, time.Hour, func() error { return nil }, WithRetryPolicy(RetryPolicy{}))
+	AssertNoError(t, err, "NewWatcher()")
+
+	// Both the health check and the read go below the secondary's prefix
+	primary.setSealed(true)
+	data, err := watcher.fetchVaultData()
+	AssertNoError(t, err, "fetchVaultData()")
+	AssertStringEquals(t, data["node"].(string), "secondary", "node while primary is sealed")
+}
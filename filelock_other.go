@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package vaultwatcher
+
+import (
+	"errors"
+	"os"
+)
+
+// errFileLocked means another process holds the lock on a file. It is never
+// returned here.
+var errFileLocked = errors.New("locked by another process")
+
+// lockFile is a no-op where files can't be locked with flock
+func lockFile(file *os.File) error { return nil }
+
+// syncDir is a no-op where directories can't be synced
+func syncDir(path string) error { return nil }
@@ -0,0 +1,33 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package vaultwatcher
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errFileLocked means another process holds the lock on a file
+var errFileLocked = errors.New("locked by another process")
+
+// lockFile takes an exclusive lock on file without waiting, released when the
+// file is closed
+func lockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errFileLocked
+	}
+	return err
+}
+
+// syncDir syncs the directory at path, so a file renamed into it survives a
+// crash
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,341 @@
+package vaultwatcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileFormat is the format of a file read by a FileSource
+type FileFormat string
+
+const (
+	// FileFormatJSON is a JSON object
+	FileFormatJSON FileFormat = "json"
+	// FileFormatYAML is a YAML document whose root is a mapping
+	FileFormatYAML FileFormat = "yaml"
+	// FileFormatDotenv holds KEY=value lines; every value is a string
+	FileFormatDotenv FileFormat = "dotenv"
+)
+
+// FileSource reads secrets from a local file, so development environments
+// without Vault run the same watcher code as production
+type FileSource struct {
+	path   string
+	format FileFormat
+}
+
+// NewFileSource creates a source reading path. An empty format is detected
+// from the extension: .json, .yaml or .yml, and .env or a file named .env.
+func NewFileSource(path string, format FileFormat) (*FileSource, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file path is required")
+	}
+	if format == "" {
+		format = detectFileFormat(path)
+		if format == "" {
+			return nil, fmt.Errorf("cannot tell the format of %s from its extension", path)
+		}
+	}
+	switch format {
+	case FileFormatJSON, FileFormatYAML, FileFormatDotenv:
+	default:
+		return nil, fmt.Errorf("unknown file format %q", format)
+	}
+	return &FileSource{path: path, format: format}, nil
+}
+
+// detectFileFormat returns the format matching the extension of path
+func detectFileFormat(path string) FileFormat {
+	base := filepath.Base(path)
+	switch strings.ToLower(filepath.Ext(base)) {
+	case ".json":
+		return FileFormatJSON
+	case ".yaml", ".yml":
+		return FileFormatYAML
+	case ".env":
+		return FileFormatDotenv
+	}
+	if base == ".env" || strings.HasPrefix(base, ".env.") {
+		return FileFormatDotenv
+	}
+	return ""
+}
+
+// Fetch reads and parses the file. Meta.CreatedTime is its modification time.
+func (s *FileSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, Meta{}, fmt.Errorf("failed to read %s: %w", s.path, ErrSecretNotFound)
+	}
+	if err != nil {
+		return nil, Meta{}, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, Meta{}, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+
+	var data map[string]interface{}
+	switch s.format {
+	case FileFormatJSON:
+		data, err = parseJSONObject(content)
+	case FileFormatYAML:
+		data, err = parseYAML(content)
+	case FileFormatDotenv:
+		data, err = parseDotenv(content)
+	}
+	if err != nil {
+		return nil, Meta{}, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return data, Meta{CreatedTime: info.ModTime().UTC()}, nil
+}
+
+// parseJSONObject parses a JSON object. Numbers stay json.Number, as in data
+// read from Vault, so hashes match.
+func parseJSONObject(content []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data, nil
+}
+
+// parseDotenv parses KEY=value lines. Lines may start with "export"; values
+// may be single quoted (literal) or double quoted (with escapes), and
+// unquoted values end at a " #" comment.
+func parseDotenv(content []byte) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", number)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated double quote", number)
+			}
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", number)
+			}
+			value = value[1 : end+1]
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		data[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// closingQuote returns the index of the double quote closing the one at the
+// start of s, or -1
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseYAML parses a YAML document whose root is a mapping. Numbers become
+// json.Number and booleans bool, as JSON from Vault would; timestamps and
+// other scalars stay the strings they were written as.
+func parseYAML(content []byte) (map[string]interface{}, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	var document yaml.Node
+	if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+		return map[string]interface{}{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("line %d: only one document is supported", next.Line)
+	}
+
+	value, err := yamlValue(&document)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return map[string]interface{}{}, nil
+	}
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the document must be a mapping")
+	}
+	return data, nil
+}
+
+// yamlValue converts a node to the types JSON decoded from Vault has
+func yamlValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		return yamlMapping(node)
+	case yaml.SequenceNode:
+		items := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	}
+	return yamlScalar(node)
+}
+
+// yamlMapping converts a mapping, including keys merged in with "<<"
+func yamlMapping(node *yaml.Node) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	merged := map[string]interface{}{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: keys must be scalars", key.Line)
+		}
+		if key.ShortTag() == "!!merge" {
+			if err := yamlMerge(merged, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, exists := data[key.Value]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", key.Line, key.Value)
+		}
+
+		converted, err := yamlValue(value)
+		if err != nil {
+			return nil, err
+		}
+		data[key.Value] = converted
+	}
+
+	// Keys of the mapping itself win over merged ones
+	for key, value := range merged {
+		if _, exists := data[key]; !exists {
+			data[key] = value
+		}
+	}
+	return data, nil
+}
+
+// yamlMerge adds the keys of the mapping, or sequence of mappings, merged
+// with "<<" that aren't in data yet
+func yamlMerge(data map[string]interface{}, node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	sources := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		sources = node.Content
+	}
+
+	for _, source := range sources {
+		if source.Kind == yaml.AliasNode {
+			source = source.Alias
+		}
+		if source.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d: only mappings can be merged", source.Line)
+		}
+		mapping, err := yamlMapping(source)
+		if err != nil {
+			return err
+		}
+		for key, value := range mapping {
+			if _, exists := data[key]; !exists {
+				data[key] = value
+			}
+		}
+	}
+	return nil
+}
+
+// yamlScalar converts a scalar. Numbers JSON can't hold, such as .inf, stay
+// strings.
+func yamlScalar(node *yaml.Node) (interface{}, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var value bool
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case "!!int":
+		if validJSONNumber(node.Value) {
+			return json.Number(node.Value), nil
+		}
+		var value int64
+		if err := node.Decode(&value); err != nil {
+			return node.Value, nil
+		}
+		return json.Number(strconv.FormatInt(value, 10)), nil
+	case "!!float":
+		if validJSONNumber(node.Value) {
+			return json.Number(node.Value), nil
+		}
+		var value float64
+		if err := node.Decode(&value); err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return node.Value, nil
+		}
+		return json.Number(strconv.FormatFloat(value, 'g', -1, 64)), nil
+	}
+	return node.Value, nil
+}
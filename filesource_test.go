@@ -0,0 +1,232 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewFileSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		format  FileFormat
+		want    FileFormat
+		wantErr string
+	}{
+		{name: "json", path: "config/app.json", want: FileFormatJSON},
+		{name: "yaml", path: "app.yaml", want: FileFormatYAML},
+		{name: "yml", path: "APP.YML", want: FileFormatYAML},
+		{name: "env extension", path: "app.env", want: FileFormatDotenv},
+		{name: "dotenv", path: "/srv/app/.env", want: FileFormatDotenv},
+		{name: "dotenv variant", path: ".env.local", want: FileFormatDotenv},
+		{name: "explicit format", path: "secrets", format: FileFormatYAML, want: FileFormatYAML},
+		{name: "unknown extension", path: "app.toml", wantErr: "cannot tell the format of app.toml from its extension"},
+		{name: "unknown format", path: "app.json", format: "toml", wantErr: `unknown file format "toml"`},
+		{name: "no path", wantErr: "file path is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewFileSource(tt.path, tt.format)
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "NewFileSource()")
+				return
+			}
+			AssertNoError(t, err, "NewFileSource()")
+			AssertStringEquals(t, string(source.format), string(tt.want), "format")
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string // JSON
+		wantErr string
+	}{
+		{
+			name: "scalars",
+			content: `---
+# database settings
+username: app
+password: "s3cr#t\n"   # quoted hash
+port: 5432
+ratio: 0.5
+enabled: true
+zip: "007"
+version: 1.2.3
+empty:
+quoted: 'it''s'
+url: http://db:5432/app
+`,
+			want: `{"username":"app","password":"s3cr#t\n","port":5432,"ratio":0.5,"enabled":true,"zip":"007","version":"1.2.3","empty":null,"quoted":"it's","url":"http://db:5432/app"}`,
+		},
+		{
+			name: "nested",
+			content: `database:
+  primary:
+    host: db-1
+  replicas:
+    - db-2
+    - db-3
+hosts:
+- name: a
+  port: 1
+- name: b
+  port: 2
+none: []
+`,
+			want: `{"database":{"primary":{"host":"db-1"},"replicas":["db-2","db-3"]},"hosts":[{"name":"a","port":1},{"name":"b","port":2}],"none":[]}`,
+		},
+		{
+			name: "block scalars",
+			content: `certificate: |
+  -----BEGIN CERTIFICATE-----
+  MIIB # not a comment
+
+  -----END CERTIFICATE-----
+folded: >-
+  one
+  two
+after: x
+`,
+			want: `{"certificate":"-----BEGIN CERTIFICATE-----\nMIIB # not a comment\n\n-----END CERTIFICATE-----\n","folded":"one two","after":"x"}`,
+		},
+		{
+			name:    "folded paragraphs",
+			content: "folded: >\n one\n two\n\n three\n",
+			want:    `{"folded":"one two\nthree\n"}`,
+		},
+		{
+			name:    "double-quoted escapes",
+			content: `escapes: "esc\e nel\N nbsp\_ tab\t \x41\u00e9"` + "\n",
+			want:    `{"escapes":"esc\u001b nel\u0085 nbsp\u00a0 tab\t A\u00e9"}`,
+		},
+		{
+			name: "flow collections and anchors",
+			content: `base: &base {host: db, port: 5432}
+replica:
+  <<: *base
+  host: db-2
+hosts: [a, b]
+limits: {max: 0x10, ratio: .5, inf: .inf, started: 2024-01-02}
+`,
+			want: `{"base":{"host":"db","port":5432},"replica":{"host":"db-2","port":5432},"hosts":["a","b"],"limits":{"max":16,"ratio":0.5,"inf":".inf","started":"2024-01-02"}}`,
+		},
+		{name: "empty", content: "# nothing\n", want: `{}`},
+		{name: "duplicate key", content: "a: 1\na: 2\n", wantErr: `line 2: duplicate key "a"`},
+		{name: "bad indentation", content: "a: 1\n  b: 2\n", wantErr: "yaml: line 2: mapping values are not allowed in this context"},
+		{name: "unknown alias", content: "a: *base\n", wantErr: "yaml: unknown anchor 'base' referenced"},
+		{name: "root sequence", content: "- a\n- b\n", wantErr: "the document must be a mapping"},
+		{name: "two documents", content: "a: 1\n---\nb: 2\n", wantErr: "line 2: only one document is supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := parseYAML([]byte(tt.content))
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "parseYAML()")
+				return
+			}
+			AssertNoError(t, err, "parseYAML()")
+			assertJSONEquals(t, data, tt.want)
+		})
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string // JSON
+		wantErr string
+	}{
+		{
+			name: "values",
+			content: `# app settings
+DB_USER=app
+export DB_PASSWORD="p@ss \"word\"\n"
+API_KEY='literal $KEY # kept'
+PORT=5432 # comment
+EMPTY=
+URL=http://host/#anchor
+`,
+			want: `{"DB_USER":"app","DB_PASSWORD":"p@ss \"word\"\n","API_KEY":"literal $KEY # kept","PORT":"5432","EMPTY":"","URL":"http://host/#anchor"}`,
+		},
+		{name: "missing equals", content: "DB_USER\n", wantErr: "line 1: expected KEY=value"},
+		{name: "unterminated", content: "A=ok\nB=\"open\n", wantErr: "line 2: unterminated double quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := parseDotenv([]byte(tt.content))
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "parseDotenv()")
+				return
+			}
+			AssertNoError(t, err, "parseDotenv()")
+			assertJSONEquals(t, data, tt.want)
+		})
+	}
+}
+
+func TestFileSource_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.json")
+	AssertNoError(t, os.WriteFile(path, []byte(`{"port": 5432}`), 0o600), "WriteFile()")
+
+	source, err := NewFileSource(path, "")
+	AssertNoError(t, err, "NewFileSource()")
+	data, meta, err := source.Fetch(context.Background())
+	AssertNoError(t, err, "Fetch()")
+	assertJSONEquals(t, data, `{"port":5432}`)
+	if meta.CreatedTime.IsZero() {
+		t.Error("CreatedTime is zero, want the modification time")
+	}
+
+	missing, err := NewFileSource(filepath.Join(dir, "missing.yaml"), "")
+	AssertNoError(t, err, "NewFileSource()")
+	_, _, err = missing.Fetch(context.Background())
+	AssertBoolEquals(t, errors.Is(err, ErrSecretNotFound), true, "missing file error is ErrSecretNotFound")
+}
+
+func TestFileSource_Watcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	AssertNoError(t, os.WriteFile(path, []byte("DB_PASSWORD=one\n"), 0o600), "WriteFile()")
+
+	source, err := NewFileSource(path, "")
+	AssertNoError(t, err, "NewFileSource()")
+
+	changed := make(chan struct{}, 1)
+	watcher, err := NewSourceWatcher(path, source, 10*time.Millisecond, func() error {
+		changed <- struct{}{}
+		return nil
+	})
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertNoError(t, os.WriteFile(path, []byte("DB_PASSWORD=two\n"), 0o600), "WriteFile()")
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called after the file changed")
+	}
+}
+
+// assertJSONEquals compares data with the JSON encoding of the expected value
+func assertJSONEquals(t *testing.T, data map[string]interface{}, want string) {
+	t.Helper()
+	var expected interface{}
+	AssertNoError(t, json.Unmarshal([]byte(want), &expected), "json.Unmarshal(want)")
+	got, err := json.Marshal(data)
+	AssertNoError(t, err, "json.Marshal(data)")
+	wantJSON, _ := json.Marshal(expected)
+	AssertStringEquals(t, string(got), string(wantJSON), "data")
+}
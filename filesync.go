@@ -0,0 +1,340 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultFileSyncInterval = 30 * time.Second
+
+// ConflictPolicy decides which side wins when the file and Vault both changed
+type ConflictPolicy int
+
+const (
+	// VaultWins overwrites the file with the data in Vault
+	VaultWins ConflictPolicy = iota
+	// FileWins writes the file back to Vault
+	FileWins
+)
+
+// FileSyncOption configures a FileSync
+type FileSyncOption func(*FileSync)
+
+// WithConflictPolicy sets which side wins when both changed (default VaultWins)
+func WithConflictPolicy(policy ConflictPolicy) FileSyncOption {
+	return func(s *FileSync) {
+		s.policy = policy
+	}
+}
+
+// WithFileEncryption encrypts the sync file with cipher, so the secret isn't
+// stored in plaintext on disk. The file can then only be edited by decrypting
+// it; use a local key to keep serving the file while Vault is unreachable.
+func WithFileEncryption(cipher StateCipher) FileSyncOption {
+	return func(s *FileSync) {
+		s.cipher = cipher
+	}
+}
+
+// FileSync keeps a local JSON file and the watched secret in sync in both
+// directions. Vault changes are written to the file and file edits are
+// written to Vault. While Vault is unreachable, Data serves the file, so edge
+// deployments keep operating from disk. A side changed since the last sync
+// wins; if both changed, the ConflictPolicy decides. At startup there is no
+// previous sync, so any difference is a conflict.
+type FileSync struct {
+	watcher      *Watcher
+	path         string
+	pollInterval time.Duration
+	policy       ConflictPolicy
+	cipher       StateCipher
+	synced       string // Hash of the data both sides last agreed on
+	data         map[string]interface{}
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	mu           sync.RWMutex
+	started      bool
+}
+
+// NewFileSync creates a two-way sync between the watcher's secret and a file
+// watcher: Watcher of the secret; it doesn't need to be started
+// path: JSON file holding the secret's data, created if missing
+// pollInterval: How often to sync (default 30s)
+func NewFileSync(watcher *Watcher, path string, pollInterval time.Duration, opts ...FileSyncOption) (*FileSync, error) {
+	if watcher == nil {
+		return nil, fmt.Errorf("watcher cannot be nil")
+	}
+	if path == "" {
+		return nil, fmt.Errorf("sync file path is required")
+	}
+	if watcher.source != nil {
+		return nil, fmt.Errorf("file sync writes to vault, so it can't be used with a custom source")
+	}
+	if watcher.fetchData != nil || watcher.selectData != nil || watcher.metadataOnly ||
+		watcher.includeCustomMetadata || watcher.pinnedVersion > 0 {
+		return nil, fmt.Errorf("file sync needs a watcher that reads the whole secret")
+	}
+	if watcher.hashOnly {
+		return nil, fmt.Errorf("file sync keeps secret data in memory, which hash-only watchers don't allow")
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultFileSyncInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &FileSync{
+		watcher:      watcher,
+		path:         path,
+		pollInterval: pollInterval,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Start syncs once and then keeps syncing in the background. It only fails
+// if neither Vault nor the file can provide the data.
+func (s *FileSync) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return fmt.Errorf("file sync is already started")
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	if err := s.Sync(); err != nil {
+		if s.Data() == nil {
+			s.mu.Lock()
+			s.started = false
+			s.mu.Unlock()
+			return err
+		}
+		slog.Error("Error syncing file", "path", s.path, "error", err)
+	}
+
+	s.wg.Add(1)
+	go s.run()
+
+	return nil
+}
+
+// Stop stops syncing
+func (s *FileSync) Stop() {
+	s.cancel()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.started = false
+	s.mu.Unlock()
+}
+
+// Data returns the data most recently synced, or read from the file while
+// Vault is unreachable. It returns nil before the first successful sync.
+func (s *FileSync) Data() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.data == nil {
+		return nil
+	}
+	return copyMap(s.data)
+}
+
+// run runs in a goroutine and syncs on every poll
+func (s *FileSync) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sync(); err != nil {
+				slog.Error("Error syncing file", "path", s.path, "error", err)
+			}
+		}
+	}
+}
+
+// Sync compares the file and Vault with the last synced data and copies the
+// changed side to the other one
+func (s *FileSync) Sync() error {
+	fileData, err := s.readFile()
+	if err != nil {
+		return err
+	}
+
+	vaultData, err := s.watcher.fetchVaultData()
+	if err != nil {
+		// Keep operating from disk; the file is pushed once Vault is back
+		if fileData != nil {
+			s.mu.Lock()
+			s.data = fileData
+			s.mu.Unlock()
+		}
+		return fmt.Errorf("vault is unreachable, using %s: %w", s.path, err)
+	}
+
+	vaultHash, err := CalculateHash(vaultData)
+	if err != nil {
+		return fmt.Errorf("failed to calculate hash: %w", err)
+	}
+
+	s.mu.RLock()
+	synced := s.synced
+	s.mu.RUnlock()
+
+	if fileData == nil {
+		return s.pull(vaultData, vaultHash)
+	}
+
+	fileHash, err := CalculateHash(fileData)
+	if err != nil {
+		return fmt.Errorf("failed to calculate hash: %w", err)
+	}
+
+	vaultChanged := vaultHash != synced
+	fileChanged := fileHash != synced
+	switch {
+	case vaultHash == fileHash:
+		s.remember(vaultData, vaultHash)
+		return nil
+	case vaultChanged && fileChanged && s.policy == FileWins, fileChanged && !vaultChanged:
+		return s.push(fileData, fileHash)
+	default:
+		return s.pull(vaultData, vaultHash)
+	}
+}
+
+// pull writes the data from Vault to the file
+func (s *FileSync) pull(data map[string]interface{}, hash string) error {
+	if err := s.writeFile(data); err != nil {
+		return err
+	}
+	s.remember(data, hash)
+	return nil
+}
+
+// push writes the file's data to Vault, with check-and-set for KV v2
+func (s *FileSync) push(data map[string]interface{}, hash string) error {
+	if _, err := kvMetadataPath(s.watcher.vaultConfig.Path); err == nil {
+		s.watcher.mu.RLock()
+		version := s.watcher.readVersion
+		s.watcher.mu.RUnlock()
+
+		// A concurrent Vault change fails the write, and the next sync resolves it
+		if _, err := s.watcher.UpdateSecret(s.ctx, data, UpdateOptions{Version: version}); err != nil {
+			return err
+		}
+	} else if err := s.writeKVv1(data); err != nil {
+		return err
+	}
+
+	s.remember(data, hash)
+	return nil
+}
+
+// writeKVv1 writes the file's data to a KV v1 secret
+func (s *FileSync) writeKVv1(data map[string]interface{}) error {
+	ctx, cancel := s.watcher.withRequestTimeout(s.ctx)
+	defer cancel()
+
+	if _, err := s.watcher.client.Logical().WriteWithContext(ctx, s.watcher.vaultConfig.Path, data); err != nil {
+		return fmt.Errorf("failed to write secret to vault: %w", classifyVaultError(err))
+	}
+	return nil
+}
+
+// remember records the data both sides agree on
+func (s *FileSync) remember(data map[string]interface{}, hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	s.synced = hash
+}
+
+// readFile returns the file's data, or nil if the file doesn't exist
+func (s *FileSync) readFile() (map[string]interface{}, error) {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync file: %w", err)
+	}
+	if s.cipher != nil {
+		if content, err = s.cipher.Decrypt(s.ctx, content); err != nil {
+			return nil, fmt.Errorf("failed to decrypt sync file: %w", err)
+		}
+	}
+
+	// Numbers stay json.Number, as in data read from Vault, so hashes match
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse sync file: %w", err)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data, nil
+}
+
+// writeFile replaces the file atomically with data as JSON
+func (s *FileSync) writeFile(data map[string]interface{}) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync file: %w", err)
+	}
+	content = append(content, '\n')
+	if s.cipher != nil {
+		if content, err = s.cipher.Encrypt(s.ctx, content); err != nil {
+			return fmt.Errorf("failed to encrypt sync file: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write sync file: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write sync file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write sync file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write sync file: %w", err)
+	}
+	return nil
+}
+
+func copyMap(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		copied[k] = v
+	}
+	return copied
+}
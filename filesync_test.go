@@ -0,0 +1,151 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func writeSyncFile(t *testing.T, path string, data map[string]interface{}) {
+	t.Helper()
+	content, err := json.Marshal(data)
+	AssertNoError(t, err, "json.Marshal()")
+	AssertNoError(t, os.WriteFile(path, content, 0o600), "WriteFile()")
+}
+
+func readSyncFile(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	AssertNoError(t, err, "ReadFile()")
+	var data map[string]interface{}
+	AssertNoError(t, json.Unmarshal(content, &data), "json.Unmarshal()")
+	return data
+}
+
+func TestFileSync(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+
+	path := filepath.Join(t.TempDir(), "app.json")
+	fileSync, err := NewFileSync(watcher, path, time.Hour)
+	AssertNoError(t, err, "NewFileSync()")
+	AssertNoError(t, fileSync.Start(), "Start()")
+	defer fileSync.Stop()
+
+	// A missing file is created from Vault
+	AssertStringEquals(t, readSyncFile(t, path)["password"].(string), "one", "file after start")
+
+	// A file edit is written to Vault
+	writeSyncFile(t, path, map[string]interface{}{"password": "two"})
+	AssertNoError(t, fileSync.Sync(), "Sync() after file edit")
+	data, err := watcher.fetchVaultData()
+	AssertNoError(t, err, "fetchVaultData()")
+	AssertStringEquals(t, data["password"].(string), "two", "vault after file edit")
+
+	// A Vault change is written to the file
+	vault.Put("secret/app", map[string]interface{}{"password": "three"})
+	AssertNoError(t, fileSync.Sync(), "Sync() after vault change")
+	AssertStringEquals(t, readSyncFile(t, path)["password"].(string), "three", "file after vault change")
+
+	// While Vault is unreachable the file is served, and pushed once it is back
+	vault.SetSealed(true)
+	writeSyncFile(t, path, map[string]interface{}{"password": "offline"})
+	if err := fileSync.Sync(); err == nil {
+		t.Error("Sync() while sealed should fail")
+	}
+	AssertStringEquals(t, fileSync.Data()["password"].(string), "offline", "Data() while sealed")
+
+	vault.SetSealed(false)
+	AssertNoError(t, fileSync.Sync(), "Sync() after unseal")
+	data, err = watcher.fetchVaultData()
+	AssertNoError(t, err, "fetchVaultData()")
+	AssertStringEquals(t, data["password"].(string), "offline", "vault after unseal")
+}
+
+func TestFileSync_Conflict(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ConflictPolicy
+		want   string
+	}{
+		{name: "vault wins", policy: VaultWins, want: "vault"},
+		{name: "file wins", policy: FileWins, want: "file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := vaultwatchertest.NewServer()
+			defer vault.Close()
+			vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+			watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+				func() error { return nil })
+			AssertNoError(t, err, "NewWatcher()")
+
+			path := filepath.Join(t.TempDir(), "app.json")
+			fileSync, err := NewFileSync(watcher, path, time.Hour, WithConflictPolicy(tt.policy))
+			AssertNoError(t, err, "NewFileSync()")
+			AssertNoError(t, fileSync.Sync(), "first Sync()")
+
+			vault.Put("secret/app", map[string]interface{}{"password": "vault"})
+			writeSyncFile(t, path, map[string]interface{}{"password": "file"})
+			AssertNoError(t, fileSync.Sync(), "Sync() with conflict")
+
+			data, err := watcher.fetchVaultData()
+			AssertNoError(t, err, "fetchVaultData()")
+			AssertStringEquals(t, data["password"].(string), tt.want, "vault")
+			AssertStringEquals(t, readSyncFile(t, path)["password"].(string), tt.want, "file")
+			AssertStringEquals(t, fileSync.Data()["password"].(string), tt.want, "Data()")
+		})
+	}
+}
+
+func TestNewFileSync_Errors(t *testing.T) {
+	watcher := TestWatcher(t, func() error { return nil })
+	pinned, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, WithPinnedVersion(1))
+	AssertNoError(t, err, "NewWatcher()")
+
+	tests := []struct {
+		name    string
+		watcher *Watcher
+		path    string
+		wantErr string
+	}{
+		{name: "nil watcher", path: "app.json", wantErr: "watcher cannot be nil"},
+		{name: "missing path", watcher: watcher, wantErr: "sync file path is required"},
+		{name: "pinned watcher", watcher: pinned, path: "app.json", wantErr: "file sync needs a watcher that reads the whole secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFileSync(tt.watcher, tt.path, 0)
+			AssertError(t, err, tt.wantErr, "NewFileSync()")
+		})
+	}
+}
+
+func TestFileSync_StartWithoutData(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.SetSealed(true)
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+
+	fileSync, err := NewFileSync(watcher, filepath.Join(t.TempDir(), "app.json"), time.Hour)
+	AssertNoError(t, err, "NewFileSync()")
+	if err := fileSync.Start(); err == nil {
+		fileSync.Stop()
+		t.Error("Start() without Vault or file should fail")
+	}
+}
@@ -2,7 +2,10 @@ module github.com/naman-dave/vault-watcher
 
 go 1.23.0
 
-require github.com/hashicorp/vault/api v1.22.0
+require (
+	github.com/hashicorp/vault/api v1.22.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
@@ -0,0 +1,845 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// GroupOption configures optional WatcherGroup behaviour
+type GroupOption func(*WatcherGroup)
+
+// WithWatcherOptions applies watcher options, such as WithNotifier, to every path of the group
+func WithWatcherOptions(opts ...Option) GroupOption {
+	return func(g *WatcherGroup) {
+		g.watcherOpts = append(g.watcherOpts, opts...)
+	}
+}
+
+// WithGroupPaths adds paths read in their own namespace or with their own
+// token to the group, in addition to the paths given to NewWatcherGroup
+func WithGroupPaths(paths ...GroupPath) GroupOption {
+	return func(g *WatcherGroup) {
+		g.extraPaths = append(g.extraPaths, paths...)
+	}
+}
+
+// WithGroupRateLimiter makes every request of the group wait for limiter, so
+// the whole group stays within one requests-per-second budget
+func WithGroupRateLimiter(limiter *RateLimiter) GroupOption {
+	return func(g *WatcherGroup) {
+		if limiter != nil {
+			g.rateLimiter = limiter
+		}
+	}
+}
+
+// WithGroupRetryPolicy sets how the group's shared client retries failed requests
+func WithGroupRetryPolicy(policy RetryPolicy) GroupOption {
+	return func(g *WatcherGroup) {
+		g.retryPolicy = &policy
+	}
+}
+
+// WithGroupConsistency sets how the group reads from performance standbys
+func WithGroupConsistency(consistency Consistency) GroupOption {
+	return func(g *WatcherGroup) {
+		g.consistency = consistency
+	}
+}
+
+// WithGroupConcurrency sets how many paths the group reads at the same time (default 4)
+func WithGroupConcurrency(n int) GroupOption {
+	return func(g *WatcherGroup) {
+		if n > 0 {
+			g.concurrency = n
+		}
+	}
+}
+
+// WithPathTimeout bounds every request made for a path, so one slow path
+// can't hold a worker for the whole interval
+func WithPathTimeout(timeout time.Duration) GroupOption {
+	return func(g *WatcherGroup) {
+		if timeout > 0 {
+			g.pathTimeout = timeout
+		}
+	}
+}
+
+// WithStaggeredChecks spreads the checks of a cycle evenly across the check
+// interval instead of starting them all at the same tick, smoothing load on Vault
+func WithStaggeredChecks() GroupOption {
+	return func(g *WatcherGroup) {
+		g.stagger = true
+	}
+}
+
+// WithGroupSystemdNotify is WithSystemdNotify for a group: READY=1 is sent
+// once every path was read, and the watchdog is pet after each check cycle
+// while every path is healthy
+func WithGroupSystemdNotify() GroupOption {
+	return func(g *WatcherGroup) {
+		g.systemd = newSystemdNotifier()
+	}
+}
+
+// WithGroupSchedule checks the group's paths at the times matching schedule
+// instead of every check interval. With WithStaggeredChecks the checks are
+// still spread across the check interval.
+func WithGroupSchedule(schedule *CronSchedule) GroupOption {
+	return func(g *WatcherGroup) {
+		if schedule != nil {
+			g.schedule = schedule
+		}
+	}
+}
+
+const defaultGroupConcurrency = 4
+
+// GroupPath is a path of a WatcherGroup with settings of its own: a Vault
+// Enterprise namespace and token, so one group can watch several namespaces,
+// or its own interval, callback and watcher options
+type GroupPath struct {
+	Path      string        // e.g. "secret/data/app"
+	Namespace string        // Relative to the group's namespace; empty for the group's own
+	Token     string        // Token valid in the namespace; empty for the group's token
+	Interval  time.Duration // How often to check the path; zero for the group's interval
+	OnChange  func() error  // Called instead of the group's callback when the path changed
+	Options   []Option      // Applied after WithWatcherOptions, e.g. WithKeyFilter or WithHashAlgorithm
+	Priority  Priority      // Overrides WithPriority from the options when not PriorityNormal
+}
+
+// name is how the group refers to the path: prefixed with its namespace, as
+// Vault accepts it in request paths, e.g. "team-a/secret/data/app"
+func (p GroupPath) name() string {
+	namespace := strings.Trim(p.Namespace, "/")
+	if namespace == "" {
+		return p.Path
+	}
+	return namespace + "/" + strings.TrimLeft(p.Path, "/")
+}
+
+// GroupMetrics describes the check cycles of a WatcherGroup
+type GroupMetrics struct {
+	Paths               int           // Number of watched paths
+	Concurrency         int           // Number of paths read at the same time
+	Cycles              int64         // Completed check cycles
+	LastCycleStart      time.Time     // When the last completed cycle started
+	LastCycleDuration   time.Duration // How long the last cycle took for every path
+	MaxCycleDuration    time.Duration // Longest cycle so far
+	LastCycleFailures   int           // Paths whose check failed in the last cycle
+	SlowestPath         string        // Slowest path of the last cycle
+	SlowestPathDuration time.Duration // How long the slowest path took
+	DeferredPaths       int           // Low-priority paths left out of the last cycle
+	Shards              int           // Instances splitting the paths; 0 without sharding
+}
+
+// groupJob is one path check handed to a worker
+type groupJob struct {
+	watcher *Watcher
+	results chan<- groupResult
+}
+
+// groupResult is the outcome of one path check
+type groupResult struct {
+	path     string
+	duration time.Duration
+	err      error
+}
+
+// WatcherGroup watches many paths with one Vault client. Every path behaves
+// like a Watcher, but checks are scheduled by the group instead of one
+// goroutine per path.
+type WatcherGroup struct {
+	vaultConfig   *VaultConfig
+	client        *api.Client
+	checkInterval time.Duration
+	watchers      []*Watcher
+	byPath        map[string]*Watcher
+	onChange      func(path string) error
+	pathHandlers  map[string]func(ChangeEvent) error
+	watcherOpts   []Option
+	extraPaths    []GroupPath
+	pathIntervals map[string]time.Duration // Of the paths with their own interval
+	nextChecks    map[string]time.Time     // When those paths are due
+	deferredLow   bool                     // Whether the last cycle deferred low-priority paths
+	lastCycleBusy time.Duration            // LastCycleDuration without stagger waits
+	shards        ShardMembership
+	shardRefresh  time.Duration
+	shardMembers  []string
+	shardPaths    []GroupPath // Every instance's paths when sharded
+	rateLimiter   *RateLimiter
+	retryPolicy   *RetryPolicy
+	consistency   Consistency
+	concurrency   int
+	pathTimeout   time.Duration
+	stagger       bool
+	logger        *slog.Logger
+	logLevel      *slog.LevelVar
+	schedule      *CronSchedule
+	systemd       *systemdNotifier
+	jobs          chan groupJob
+	reschedule    chan struct{}
+	metrics       GroupMetrics
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	mu            sync.RWMutex
+	started       bool
+}
+
+// NewWatcherGroup creates a watcher for several paths
+// vaultConfig: Vault connection configuration; Path is not used
+// paths: Paths to watch, e.g. "kv/data/app/db" and "kv/data/app/cache"; see
+// WithGroupPaths for paths in other namespaces
+// checkInterval: How often to check every path
+// onChange: Callback invoked with the path whose data changed
+func NewWatcherGroup(vaultConfig *VaultConfig, paths []string, checkInterval time.Duration, onChange func(path string) error, opts ...GroupOption) (*WatcherGroup, error) {
+	if vaultConfig == nil {
+		return nil, fmt.Errorf("vault config cannot be nil")
+	}
+	if vaultConfig.Host == "" {
+		return nil, fmt.Errorf("VAULT_HOST is required")
+	}
+	if vaultConfig.Token == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN is required")
+	}
+	if onChange == nil {
+		return nil, fmt.Errorf("onChange callback cannot be nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	g := &WatcherGroup{
+		vaultConfig:   vaultConfig,
+		checkInterval: checkInterval,
+		byPath:        make(map[string]*Watcher, len(paths)),
+		pathIntervals: make(map[string]time.Duration),
+		pathHandlers:  make(map[string]func(ChangeEvent) error),
+		nextChecks:    make(map[string]time.Time),
+		concurrency:   defaultGroupConcurrency,
+		onChange:      onChange,
+		jobs:          make(chan groupJob),
+		reschedule:    make(chan struct{}, 1),
+		logger:        slog.Default(),
+		logLevel:      new(slog.LevelVar),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	// Members log through g.logger too, so SetLogLevel applies to them
+	g.logLevel.Set(slog.LevelDebug)
+	g.logger = slog.New(&leveledHandler{handler: g.logger.Handler(), level: g.logLevel})
+	if g.systemd != nil {
+		g.systemd.logger = g.logger
+	}
+	if len(paths)+len(g.extraPaths) == 0 {
+		return nil, fmt.Errorf("at least one path is required")
+	}
+
+	client, err := newVaultClient(vaultConfig, g.rateLimiter, g.retryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	g.consistency.apply(client)
+	if g.pathTimeout > 0 {
+		client.SetClientTimeout(g.pathTimeout)
+	}
+	g.client = client
+
+	entries := make([]GroupPath, 0, len(paths)+len(g.extraPaths))
+	for _, path := range paths {
+		entries = append(entries, GroupPath{Path: path})
+	}
+	entries = append(entries, g.extraPaths...)
+	listed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.Path == "" {
+			return nil, fmt.Errorf("paths cannot be empty")
+		}
+		if listed[entry.name()] {
+			return nil, fmt.Errorf("path %q is listed twice", entry.name())
+		}
+		listed[entry.name()] = true
+	}
+
+	if g.shards != nil {
+		members, err := g.shards.Members(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list shard members: %w", err)
+		}
+		g.shardMembers = members
+		g.shardPaths = entries
+		g.metrics.Shards = len(members)
+		var owned []GroupPath
+		for _, entry := range entries {
+			if g.ownsLocked(entry.name()) {
+				owned = append(owned, entry)
+			}
+		}
+		entries = owned
+	}
+
+	for _, entry := range entries {
+		name := entry.name()
+		w, err := g.newPathWatcher(entry)
+		if err != nil {
+			return nil, err
+		}
+		g.watchers = append(g.watchers, w)
+		g.byPath[name] = w
+		if entry.Interval > 0 {
+			g.pathIntervals[name] = entry.Interval
+		}
+	}
+	g.metrics.Paths = len(g.watchers)
+	g.metrics.Concurrency = g.concurrency
+
+	return g, nil
+}
+
+// Start reads every path and begins checking them for changes
+func (g *WatcherGroup) Start() error {
+	g.mu.Lock()
+	if g.started {
+		g.mu.Unlock()
+		return fmt.Errorf("watcher group is already started")
+	}
+	g.started = true
+	g.mu.Unlock()
+
+	watchers := g.members()
+	if err := g.initializeAll(watchers); err != nil {
+		return err
+	}
+	for _, w := range watchers {
+		w.mu.Lock()
+		w.started = true
+		w.mu.Unlock()
+		w.startElection()
+	}
+
+	for i := 0; i < g.concurrency; i++ {
+		g.wg.Add(1)
+		go g.work()
+	}
+	g.wg.Add(1)
+	go g.run()
+	if g.shards != nil && g.shardRefresh > 0 {
+		g.wg.Add(1)
+		go g.refreshShards()
+	}
+
+	g.systemd.ready(g.Interval())
+	return nil
+}
+
+// Stop stops checking every path
+func (g *WatcherGroup) Stop() {
+	g.systemd.notify("STOPPING=1")
+	g.cancel()
+	g.wg.Wait()
+
+	for _, w := range g.members() {
+		w.Stop()
+	}
+
+	g.mu.Lock()
+	g.started = false
+	g.mu.Unlock()
+}
+
+// IsStarted returns whether the group is currently running
+func (g *WatcherGroup) IsStarted() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.started
+}
+
+// Paths returns the watched paths in the order they were added. Paths in
+// another namespace are prefixed with it. With sharding, only the paths this
+// instance owns are returned.
+func (g *WatcherGroup) Paths() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	paths := make([]string, len(g.watchers))
+	for i, w := range g.watchers {
+		paths[i] = w.vaultConfig.Path
+	}
+	return paths
+}
+
+// Watcher returns the watcher of a path, e.g. for GetCurrentHash or IsHealthy,
+// or nil if the path isn't part of the group
+func (g *WatcherGroup) Watcher(path string) *Watcher {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.byPath[path]
+}
+
+// RegisterPathHandler routes the changes of path to handler instead of the
+// group's callback or the path's GroupPath.OnChange. The handler gets the
+// change event, with the path and its mount type. path is named as in Paths,
+// and may be registered before it is added; a nil handler removes it.
+func (g *WatcherGroup) RegisterPathHandler(path string, handler func(event ChangeEvent) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if handler == nil {
+		delete(g.pathHandlers, path)
+		return
+	}
+	g.pathHandlers[path] = handler
+}
+
+// pathHandler returns the handler registered for path, if any
+func (g *WatcherGroup) pathHandler(path string) func(ChangeEvent) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.pathHandlers[path]
+}
+
+// AddPath starts watching another path. With sharding, the path is only
+// watched by the instance owning it. On a running group the path is read
+// once before AddPath returns, so its first check only reports real changes.
+func (g *WatcherGroup) AddPath(path string) error {
+	return g.AddGroupPath(GroupPath{Path: path})
+}
+
+// AddGroupPath is AddPath for a path in another namespace or read with its own
+// token. The group refers to it by its name with the namespace prefixed.
+func (g *WatcherGroup) AddGroupPath(entry GroupPath) error {
+	if entry.Path == "" {
+		return fmt.Errorf("paths cannot be empty")
+	}
+	path := entry.name()
+
+	if g.shards != nil {
+		g.mu.Lock()
+		for _, shardPath := range g.shardPaths {
+			if shardPath.name() == path {
+				g.mu.Unlock()
+				return fmt.Errorf("path %q is already watched", path)
+			}
+		}
+		g.shardPaths = append(g.shardPaths, entry)
+		owned := g.ownsLocked(path)
+		g.mu.Unlock()
+		if !owned {
+			return nil
+		}
+	}
+
+	err := g.addMember(entry)
+	if err != nil && g.shards != nil {
+		g.mu.Lock()
+		g.forgetShardPathLocked(path)
+		g.mu.Unlock()
+	}
+	return err
+}
+
+// addMember creates, and on a running group reads, the watcher of a path
+func (g *WatcherGroup) addMember(entry GroupPath) error {
+	path := entry.name()
+
+	g.mu.RLock()
+	_, exists := g.byPath[path]
+	started := g.started
+	g.mu.RUnlock()
+	if exists {
+		return fmt.Errorf("path %q is already watched", path)
+	}
+
+	w, err := g.newPathWatcher(entry)
+	if err != nil {
+		return err
+	}
+	if started {
+		if err := w.initialize(); err != nil {
+			return fmt.Errorf("failed to start watcher for %s: %w", path, err)
+		}
+		w.mu.Lock()
+		w.started = true
+		w.mu.Unlock()
+		w.startElection()
+	}
+
+	g.mu.Lock()
+	if _, exists := g.byPath[path]; exists {
+		g.mu.Unlock()
+		w.Stop()
+		return fmt.Errorf("path %q is already watched", path)
+	}
+	g.watchers = append(g.watchers, w)
+	g.byPath[path] = w
+	g.metrics.Paths = len(g.watchers)
+	if entry.Interval > 0 {
+		g.pathIntervals[path] = entry.Interval
+	}
+	g.mu.Unlock()
+
+	if entry.Interval > 0 {
+		g.rescheduleChecks()
+	}
+	return nil
+}
+
+// RemovePath stops watching a path
+func (g *WatcherGroup) RemovePath(path string) error {
+	g.mu.Lock()
+	sharded := g.shards != nil && g.forgetShardPathLocked(path)
+	g.mu.Unlock()
+
+	if !g.removeMember(path) && !sharded {
+		return fmt.Errorf("path %q is not watched", path)
+	}
+	return nil
+}
+
+// removeMember stops and drops the watcher of a path, reporting whether there
+// was one
+func (g *WatcherGroup) removeMember(path string) bool {
+	g.mu.Lock()
+	w, exists := g.byPath[path]
+	if !exists {
+		g.mu.Unlock()
+		return false
+	}
+	delete(g.byPath, path)
+	delete(g.pathIntervals, path)
+	delete(g.nextChecks, path)
+	for i, member := range g.watchers {
+		if member == w {
+			g.watchers = append(g.watchers[:i:i], g.watchers[i+1:]...)
+			break
+		}
+	}
+	g.metrics.Paths = len(g.watchers)
+	g.mu.Unlock()
+
+	w.Stop()
+	return true
+}
+
+// SetInterval changes how often the paths are checked. A running group uses
+// the new interval from its next cycle on. It has no effect on the schedule
+// of WithGroupSchedule other than for staggering.
+func (g *WatcherGroup) SetInterval(checkInterval time.Duration) error {
+	if checkInterval <= 0 {
+		return fmt.Errorf("check interval must be positive")
+	}
+
+	g.mu.Lock()
+	g.checkInterval = checkInterval
+	g.mu.Unlock()
+
+	g.rescheduleChecks()
+	return nil
+}
+
+// rescheduleChecks makes a running group pick up changed intervals
+func (g *WatcherGroup) rescheduleChecks() {
+	select {
+	case g.reschedule <- struct{}{}:
+	default:
+	}
+}
+
+// Interval returns how often the paths are checked
+func (g *WatcherGroup) Interval() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.checkInterval
+}
+
+// newPathWatcher builds a member watcher sharing the group's client, or a
+// copy of it with the entry's token
+func (g *WatcherGroup) newPathWatcher(entry GroupPath) (*Watcher, error) {
+	path := entry.name()
+	pathConfig := *g.vaultConfig
+	pathConfig.Path = path
+	interval := g.Interval()
+	if entry.Interval > 0 {
+		interval = entry.Interval
+	}
+	handler := ChangeHandlerFunc(func(ctx context.Context, event ChangeEvent) error {
+		if handler := g.pathHandler(path); handler != nil {
+			return handler(event)
+		}
+		if entry.OnChange != nil {
+			return entry.OnChange()
+		}
+		return g.onChange(path)
+	})
+	opts := append(append([]Option{WithLogger(g.logger)}, g.watcherOpts...), entry.Options...)
+	if entry.Priority != PriorityNormal {
+		opts = append(opts, WithPriority(entry.Priority))
+	}
+	client := g.client
+	if entry.Token != "" {
+		var err error
+		if client, err = g.client.Clone(); err != nil {
+			return nil, fmt.Errorf("failed to create vault client for %s: %w", path, err)
+		}
+		client.SetToken(entry.Token)
+		if g.vaultConfig.Namespace != "" {
+			client.SetNamespace(g.vaultConfig.Namespace)
+		}
+		g.consistency.apply(client)
+		pathConfig.Token = entry.Token
+	}
+	w := newWatcher(&pathConfig, interval, handler, opts...)
+	if err := w.prepare(); err != nil {
+		return nil, fmt.Errorf("invalid options for %s: %w", path, err)
+	}
+	w.client = client
+	w.consistency = g.consistency
+	// Stopping the group interrupts the member's in-flight reads
+	w.cancel()
+	w.ctx, w.cancel = context.WithCancel(g.ctx)
+	return w, nil
+}
+
+// members returns a snapshot of the member watchers
+func (g *WatcherGroup) members() []*Watcher {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]*Watcher(nil), g.watchers...)
+}
+
+// Watchers returns the member watchers, one per path, e.g. for
+// PrometheusHandler
+func (g *WatcherGroup) Watchers() []*Watcher {
+	return g.members()
+}
+
+// Metrics returns the timing of the group's check cycles
+func (g *WatcherGroup) Metrics() GroupMetrics {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.metrics
+}
+
+// initializeAll reads every path once, at most concurrency paths at a time
+func (g *WatcherGroup) initializeAll(watchers []*Watcher) error {
+	sem := make(chan struct{}, g.concurrency)
+	errs := make([]error, len(watchers))
+
+	var wg sync.WaitGroup
+	for i, w := range watchers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, w *Watcher) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := w.initialize(); err != nil {
+				errs[i] = fmt.Errorf("failed to start watcher for %s: %w", w.vaultConfig.Path, err)
+			}
+		}(i, w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs in a goroutine and checks every path each interval or on the schedule
+func (g *WatcherGroup) run() {
+	defer g.wg.Done()
+
+	if g.schedule != nil {
+		runCronSchedule(g.ctx, g.logger, g.schedule, g.checkAll)
+		return
+	}
+
+	ticker := time.NewTicker(g.tick())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-g.reschedule:
+			ticker.Reset(g.tick())
+		case <-ticker.C:
+			g.checkDue()
+		}
+	}
+}
+
+// tick returns how often the group wakes up: every check interval, or more
+// often for paths with a shorter interval of their own
+func (g *WatcherGroup) tick() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	tick := g.checkInterval
+	for _, interval := range g.pathIntervals {
+		tick = min(tick, interval)
+	}
+	return tick
+}
+
+// checkDue checks the paths whose interval has passed. Without paths having
+// their own interval, that is every path on every tick.
+func (g *WatcherGroup) checkDue() {
+	tick := g.tick()
+	now := time.Now()
+
+	g.mu.Lock()
+	// While the group is behind, its last cycle having outlasted a tick,
+	// low-priority paths sit out every other cycle. Stagger waits spread a
+	// cycle over the interval on purpose, so they don't count.
+	behind := g.metrics.Cycles > 0 && g.lastCycleBusy > tick
+	deferLow := behind && !g.deferredLow
+	g.deferredLow = deferLow
+	deferred := 0
+	var due []*Watcher
+	for _, w := range g.watchers {
+		if deferLow && w.priority == PriorityLow {
+			deferred++
+			continue
+		}
+		if len(g.pathIntervals) == 0 {
+			due = append(due, w)
+			continue
+		}
+		path := w.vaultConfig.Path
+		interval, ok := g.pathIntervals[path]
+		if !ok {
+			interval = g.checkInterval
+		}
+		next, ok := g.nextChecks[path]
+		if !ok {
+			// The first tick comes one tick after the path was read
+			next = now.Add(interval - tick)
+		}
+		// Ticks drift a little, so a path due within half a tick is due now
+		if next.Sub(now) >= tick/2 {
+			g.nextChecks[path] = next
+			continue
+		}
+		g.nextChecks[path] = now.Add(interval)
+		due = append(due, w)
+	}
+	g.metrics.DeferredPaths = deferred
+	g.mu.Unlock()
+
+	g.checkMembers(due)
+}
+
+// work runs in a goroutine and checks the paths handed to it
+func (g *WatcherGroup) work() {
+	defer g.wg.Done()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case job := <-g.jobs:
+			start := time.Now()
+			err := job.watcher.check()
+			job.results <- groupResult{
+				path:     job.watcher.vaultConfig.Path,
+				duration: time.Since(start),
+				err:      err,
+			}
+		}
+	}
+}
+
+// checkAll checks every path once
+func (g *WatcherGroup) checkAll() {
+	g.checkMembers(g.members())
+}
+
+// checkMembers checks watchers once using the worker pool and records the
+// cycle. Higher priorities are handed out first, and with WithStaggeredChecks
+// they are handed out across the interval.
+func (g *WatcherGroup) checkMembers(watchers []*Watcher) {
+	watchers = byPriority(watchers)
+	start := time.Now()
+	interval := g.Interval()
+	// Buffered so workers never block on a cycle that was abandoned by Stop
+	results := make(chan groupResult, len(watchers))
+
+	sent := 0
+	var waited time.Duration
+	for i, w := range watchers {
+		if g.stagger && i > 0 {
+			// Path i starts i/n of the way through the interval
+			waitStart := time.Now()
+			timer := time.NewTimer(time.Until(start.Add(interval * time.Duration(i) / time.Duration(len(watchers)))))
+			select {
+			case <-g.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			waited += time.Since(waitStart)
+		}
+		select {
+		case <-g.ctx.Done():
+			return
+		case g.jobs <- groupJob{watcher: w, results: results}:
+			sent++
+		}
+	}
+
+	cycle := GroupMetrics{LastCycleStart: start}
+	for i := 0; i < sent; i++ {
+		select {
+		case <-g.ctx.Done():
+			return
+		case result := <-results:
+			if result.err != nil {
+				cycle.LastCycleFailures++
+			}
+			if result.duration > cycle.SlowestPathDuration {
+				cycle.SlowestPath = result.path
+				cycle.SlowestPathDuration = result.duration
+			}
+		}
+	}
+	duration := time.Since(start)
+
+	g.mu.Lock()
+	g.metrics.Cycles++
+	g.metrics.LastCycleStart = start
+	g.metrics.LastCycleDuration = duration
+	g.lastCycleBusy = duration - waited
+	g.metrics.MaxCycleDuration = max(g.metrics.MaxCycleDuration, duration)
+	g.metrics.LastCycleFailures = cycle.LastCycleFailures
+	g.metrics.SlowestPath = cycle.SlowestPath
+	g.metrics.SlowestPathDuration = cycle.SlowestPathDuration
+	g.mu.Unlock()
+
+	g.petWatchdog(watchers)
+}
+
+// petWatchdog resets the systemd watchdog after a check cycle while every
+// path is healthy
+func (g *WatcherGroup) petWatchdog(watchers []*Watcher) {
+	if g.systemd == nil {
+		return
+	}
+	for _, w := range watchers {
+		if !w.IsHealthy() {
+			return
+		}
+	}
+	g.systemd.petWatchdog()
+}
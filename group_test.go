@@ -0,0 +1,666 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// fakeKVPaths serves KV v2 secrets for several paths and counts requests
+type fakeKVPaths struct {
+	mu       sync.Mutex
+	values   map[string]string
+	requests int
+}
+
+func (f *fakeKVPaths) set(path, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[path] = value
+}
+
+func (f *fakeKVPaths) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *fakeKVPaths) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if isMountLookup(r) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f.requests++
+
+	value, ok := f.values[strings.TrimPrefix(r.URL.Path, "/v1/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{"data": map[string]interface{}{"value": value}},
+	})
+}
+
+func TestNewWatcherGroup_Validation(t *testing.T) {
+	onChange := func(string) error { return nil }
+	config := &VaultConfig{Host: "https://vault.example.com", Token: "test-token"}
+
+	tests := []struct {
+		name     string
+		config   *VaultConfig
+		paths    []string
+		onChange func(string) error
+		errMsg   string
+	}{
+		{"nil config", nil, []string{"kv/data/a"}, onChange, "vault config cannot be nil"},
+		{"missing token", &VaultConfig{Host: "https://vault.example.com"}, []string{"kv/data/a"}, onChange, "VAULT_TOKEN is required"},
+		{"no paths", config, nil, onChange, "at least one path is required"},
+		{"empty path", config, []string{"kv/data/a", ""}, onChange, "paths cannot be empty"},
+		{"duplicate path", config, []string{"kv/data/a", "kv/data/a"}, onChange, `path "kv/data/a" is listed twice`},
+		{"nil callback", config, []string{"kv/data/a"}, nil, "onChange callback cannot be nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWatcherGroup(tt.config, tt.paths, time.Second, tt.onChange)
+			AssertError(t, err, tt.errMsg, "NewWatcherGroup()")
+		})
+	}
+}
+
+func TestWatcherGroup_DetectsChangesPerPath(t *testing.T) {
+	vault := &fakeKVPaths{values: map[string]string{
+		"kv/data/app/db":    "db-v1",
+		"kv/data/app/cache": "cache-v1",
+	}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	var mu sync.Mutex
+	var changed []string
+	group, err := NewWatcherGroup(&VaultConfig{Host: server.URL, Token: "test-token"},
+		[]string{"kv/data/app/db", "kv/data/app/cache"}, time.Hour,
+		func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			changed = append(changed, path)
+			return nil
+		})
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	if got := group.Paths(); !reflect.DeepEqual(got, []string{"kv/data/app/db", "kv/data/app/cache"}) {
+		t.Errorf("Paths() = %v", got)
+	}
+	if group.Watcher("kv/data/other") != nil {
+		t.Error("Watcher() returned a watcher for an unknown path")
+	}
+	AssertBoolEquals(t, group.Watcher("kv/data/app/db").IsStarted(), true, "member IsStarted()")
+
+	vault.set("kv/data/app/cache", "cache-v2")
+	group.checkAll()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(changed, []string{"kv/data/app/cache"}) {
+		t.Errorf("changed = %v, want [kv/data/app/cache]", changed)
+	}
+}
+
+func TestWatcherGroup_RateLimit(t *testing.T) {
+	values := map[string]string{}
+	var paths []string
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		path := "kv/data/" + name
+		values[path] = name
+		paths = append(paths, path)
+	}
+	vault := &fakeKVPaths{values: values}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	limiter, err := NewRateLimiter(50, 1)
+	AssertNoError(t, err, "NewRateLimiter()")
+
+	group, err := NewWatcherGroup(&VaultConfig{Host: server.URL, Token: "test-token"}, paths, time.Hour,
+		func(string) error { return nil }, WithGroupRateLimiter(limiter))
+	AssertNoError(t, err, "NewWatcherGroup()")
+
+	// Six initial reads at 50 requests per second with no burst take at least 100ms
+	start := time.Now()
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("6 rate limited reads took %v, want at least 100ms", elapsed)
+	}
+	if vault.requestCount() != 6 {
+		t.Errorf("requests = %d, want 6", vault.requestCount())
+	}
+}
+
+// slowHandler delays every request and records the most requests in flight at once
+type slowHandler struct {
+	next     http.Handler
+	delay    func(path string) time.Duration
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (h *slowHandler) maxInFlight() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.peak
+}
+
+func (h *slowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.inFlight++
+	h.peak = max(h.peak, h.inFlight)
+	h.mu.Unlock()
+
+	select {
+	case <-time.After(h.delay(r.URL.Path)):
+	case <-r.Context().Done():
+	}
+
+	h.mu.Lock()
+	h.inFlight--
+	h.mu.Unlock()
+	h.next.ServeHTTP(w, r)
+}
+
+func TestWatcherGroup_Concurrency(t *testing.T) {
+	values := map[string]string{}
+	var paths []string
+	for i := 0; i < 8; i++ {
+		path := "kv/data/" + string(rune('a'+i))
+		values[path] = path
+		paths = append(paths, path)
+	}
+	handler := &slowHandler{
+		next:  &fakeKVPaths{values: values},
+		delay: func(string) time.Duration { return 20 * time.Millisecond },
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	group, err := NewWatcherGroup(&VaultConfig{Host: server.URL, Token: "test-token"}, paths, time.Hour,
+		func(string) error { return nil }, WithGroupConcurrency(3))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	group.checkAll()
+
+	if peak := handler.maxInFlight(); peak != 3 {
+		t.Errorf("max requests in flight = %d, want 3", peak)
+	}
+
+	metrics := group.Metrics()
+	if metrics.Paths != 8 {
+		t.Errorf("Paths = %d, want 8", metrics.Paths)
+	}
+	if metrics.Concurrency != 3 {
+		t.Errorf("Concurrency = %d, want 3", metrics.Concurrency)
+	}
+	if metrics.Cycles != 1 {
+		t.Errorf("Cycles = %d, want 1", metrics.Cycles)
+	}
+	// 8 paths, 3 at a time, take three rounds of 20ms
+	if metrics.LastCycleDuration < 60*time.Millisecond || metrics.LastCycleDuration > 150*time.Millisecond {
+		t.Errorf("LastCycleDuration = %v, want about 60ms", metrics.LastCycleDuration)
+	}
+	if metrics.LastCycleFailures != 0 {
+		t.Errorf("LastCycleFailures = %d, want 0", metrics.LastCycleFailures)
+	}
+}
+
+func TestWatcherGroup_PathTimeout(t *testing.T) {
+	slow := time.Duration(0)
+	var mu sync.Mutex
+	handler := &slowHandler{
+		next: &fakeKVPaths{values: map[string]string{"kv/data/fast": "fast", "kv/data/slow": "slow"}},
+		delay: func(path string) time.Duration {
+			mu.Lock()
+			defer mu.Unlock()
+			if path == "/v1/kv/data/slow" {
+				return slow
+			}
+			return 0
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	group, err := NewWatcherGroup(&VaultConfig{Host: server.URL, Token: "test-token"},
+		[]string{"kv/data/fast", "kv/data/slow"}, time.Hour,
+		func(string) error { return nil }, WithPathTimeout(50*time.Millisecond))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	mu.Lock()
+	slow = time.Second
+	mu.Unlock()
+
+	start := time.Now()
+	group.checkAll()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("cycle took %v, want the slow path to time out after 50ms", elapsed)
+	}
+
+	metrics := group.Metrics()
+	if metrics.LastCycleFailures != 1 {
+		t.Errorf("LastCycleFailures = %d, want 1", metrics.LastCycleFailures)
+	}
+	AssertStringEquals(t, metrics.SlowestPath, "kv/data/slow", "SlowestPath")
+}
+
+func TestWatcherGroup_StaggeredChecks(t *testing.T) {
+	paths := []string{"kv/data/a", "kv/data/b", "kv/data/c", "kv/data/d"}
+	values := map[string]string{}
+	for _, path := range paths {
+		values[path] = path
+	}
+
+	var mu sync.Mutex
+	initial := true
+	started := map[string]time.Time{}
+	handler := &slowHandler{
+		next: &fakeKVPaths{values: values},
+		delay: func(path string) time.Duration {
+			mu.Lock()
+			defer mu.Unlock()
+			// Keep the first read of the cycle; the group's own ticker may start another
+			if _, ok := started[strings.TrimPrefix(path, "/v1/")]; !ok && !initial {
+				started[strings.TrimPrefix(path, "/v1/")] = time.Now()
+			}
+			return 0
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	interval := 200 * time.Millisecond
+	group, err := NewWatcherGroup(&VaultConfig{Host: server.URL, Token: "test-token"}, paths, interval,
+		func(string) error { return nil }, WithStaggeredChecks())
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	mu.Lock()
+	initial = false
+	mu.Unlock()
+
+	start := time.Now()
+	group.checkAll()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, path := range paths {
+		offset := started[path].Sub(start)
+		want := interval * time.Duration(i) / time.Duration(len(paths))
+		if offset < want-10*time.Millisecond || offset > want+40*time.Millisecond {
+			t.Errorf("%s started after %v, want about %v", path, offset, want)
+		}
+	}
+
+	// The cycle is spread over most of the interval, but only its checks
+	// count towards being behind schedule
+	group.mu.RLock()
+	defer group.mu.RUnlock()
+	if group.metrics.LastCycleDuration < interval/2 || group.lastCycleBusy > interval/4 {
+		t.Errorf("LastCycleDuration = %v, lastCycleBusy = %v, want the stagger waits left out", group.metrics.LastCycleDuration, group.lastCycleBusy)
+	}
+}
+
+func TestWatcherGroup_AddRemovePath(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/a", map[string]interface{}{"value": "a1"})
+	vault.Put("secret/b", map[string]interface{}{"value": "b1"})
+
+	var mu sync.Mutex
+	var changed []string
+	group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, []string{"secret/data/a"}, time.Hour,
+		func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			changed = append(changed, path)
+			return nil
+		})
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	AssertNoError(t, group.AddPath("secret/data/b"), "AddPath()")
+	AssertError(t, group.AddPath("secret/data/b"), `path "secret/data/b" is already watched`, "AddPath() twice")
+	AssertError(t, group.AddPath(""), "paths cannot be empty", "AddPath() empty")
+	AssertError(t, group.AddPath("secret/data/missing"), "failed to start watcher for secret/data/missing: failed to fetch initial vault data: failed to read secret from vault: secret not found", "AddPath() missing")
+
+	vault.Put("secret/a", map[string]interface{}{"value": "a2"})
+	vault.Put("secret/b", map[string]interface{}{"value": "b2"})
+	group.checkAll()
+
+	AssertNoError(t, group.RemovePath("secret/data/a"), "RemovePath()")
+	AssertError(t, group.RemovePath("secret/data/a"), `path "secret/data/a" is not watched`, "RemovePath() twice")
+
+	vault.Put("secret/a", map[string]interface{}{"value": "a3"})
+	group.checkAll()
+
+	if got := group.Paths(); !reflect.DeepEqual(got, []string{"secret/data/b"}) {
+		t.Errorf("Paths() = %v, want [secret/data/b]", got)
+	}
+	if group.Metrics().Paths != 1 {
+		t.Errorf("Metrics().Paths = %d, want 1", group.Metrics().Paths)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(changed)
+	if !reflect.DeepEqual(changed, []string{"secret/data/a", "secret/data/b"}) {
+		t.Errorf("changed = %v, want one change per path", changed)
+	}
+}
+
+func TestWatcherGroup_SetInterval(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/a", map[string]interface{}{"value": "a"})
+
+	group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, []string{"secret/data/a"}, time.Hour,
+		func(string) error { return nil })
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	AssertError(t, group.SetInterval(0), "check interval must be positive", "SetInterval(0)")
+	AssertNoError(t, group.SetInterval(20*time.Millisecond), "SetInterval()")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && group.Metrics().Cycles < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cycles := group.Metrics().Cycles; cycles < 2 {
+		t.Errorf("Cycles = %d after shortening the interval, want at least 2", cycles)
+	}
+}
+
+func TestWatcherGroup_Watchers(t *testing.T) {
+	group, err := NewWatcherGroup(TestVaultConfig(), []string{"kv/data/a", "kv/data/b"}, time.Hour,
+		func(string) error { return nil })
+	AssertNoError(t, err, "NewWatcherGroup()")
+
+	watchers := group.Watchers()
+	if len(watchers) != 2 {
+		t.Fatalf("len(Watchers()) = %d, want 2", len(watchers))
+	}
+	AssertStringEquals(t, watchers[0].Status().Path, "kv/data/a", "first path")
+	AssertStringEquals(t, watchers[1].Status().Path, "kv/data/b", "second path")
+}
+
+func TestWatcherGroup_Namespaces(t *testing.T) {
+	vault := vaultwatchertest.NewServer(
+		vaultwatchertest.WithNamespace("team-a", "team-a-token"),
+		vaultwatchertest.WithNamespace("team-b", "team-b-token"),
+	)
+	defer vault.Close()
+	vault.Put("secret/shared", map[string]interface{}{"value": "shared-v1"})
+	vault.Put("team-a/secret/app", map[string]interface{}{"value": "a-v1"})
+	vault.Put("team-b/secret/app", map[string]interface{}{"value": "b-v1"})
+
+	var mu sync.Mutex
+	var changed []string
+	group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, []string{"secret/data/shared"}, time.Hour,
+		func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			changed = append(changed, path)
+			return nil
+		}, WithGroupPaths(
+			GroupPath{Path: "secret/data/app", Namespace: "team-a", Token: "team-a-token"},
+			GroupPath{Path: "secret/data/app", Namespace: "team-b/"},
+		))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	want := []string{"secret/data/shared", "team-a/secret/data/app", "team-b/secret/data/app"}
+	if got := group.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+
+	vault.Put("team-a/secret/app", map[string]interface{}{"value": "a-v2"})
+	group.checkAll()
+	mu.Lock()
+	if !reflect.DeepEqual(changed, []string{"team-a/secret/data/app"}) {
+		t.Errorf("changed = %v, want [team-a/secret/data/app]", changed)
+	}
+	mu.Unlock()
+
+	// A token of one namespace can't read another
+	err = group.AddGroupPath(GroupPath{Path: "secret/data/app", Namespace: "team-b", Token: "team-a-token"})
+	AssertError(t, err, `path "team-b/secret/data/app" is already watched`, "AddGroupPath() twice")
+	vault.Put("team-b/secret/other", map[string]interface{}{"value": "other"})
+	if err := group.AddGroupPath(GroupPath{Path: "secret/data/other", Namespace: "team-b", Token: "team-a-token"}); err == nil {
+		t.Error("AddGroupPath() with a token of another namespace: expected an error")
+	}
+	AssertNoError(t, group.AddGroupPath(GroupPath{Path: "secret/data/other", Namespace: "team-b", Token: "team-b-token"}), "AddGroupPath()")
+
+	_, err = NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, nil, time.Hour, func(string) error { return nil },
+		WithGroupPaths(GroupPath{Path: "secret/data/app", Namespace: "team-a"}, GroupPath{Path: "secret/data/app", Namespace: "team-a"}))
+	AssertError(t, err, `path "team-a/secret/data/app" is listed twice`, "NewWatcherGroup() with a duplicate")
+}
+
+func TestWatcherGroup_PathSettings(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/fast", map[string]interface{}{"value": "fast-v1", "note": "a"})
+	vault.Put("secret/slow", map[string]interface{}{"value": "slow-v1"})
+
+	var mu sync.Mutex
+	var groupChanges []string
+	fastChanges := 0
+	group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, []string{"secret/data/slow"}, time.Hour,
+		func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			groupChanges = append(groupChanges, path)
+			return nil
+		}, WithGroupPaths(GroupPath{
+			Path:     "secret/data/fast",
+			Interval: 20 * time.Millisecond,
+			OnChange: func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				fastChanges++
+				return nil
+			},
+			Options: []Option{WithKeyFilter(ExactKey("value")), WithHashAlgorithm(HashSHA512)},
+		}))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	if hash := group.Watcher("secret/data/fast").GetCurrentHash(); len(hash) != 128 {
+		t.Errorf("hash of the fast path = %q, want a SHA512 hash", hash)
+	}
+
+	// Only the fast path is checked within the group's interval
+	slowReads := vault.Reads("secret/slow")
+	vault.Put("secret/fast", map[string]interface{}{"value": "fast-v2", "note": "a"})
+	waitFor(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return fastChanges == 1
+	}, "the fast path's callback")
+
+	// Changes to filtered out keys are ignored
+	vault.Put("secret/fast", map[string]interface{}{"value": "fast-v2", "note": "b"})
+	fastReads := vault.Reads("secret/fast")
+	waitFor(t, time.Second, func() bool { return vault.Reads("secret/fast") > fastReads+2 }, "more checks of the fast path")
+
+	mu.Lock()
+	AssertStringEquals(t, fmt.Sprint(fastChanges), "1", "fast path changes")
+	AssertStringEquals(t, fmt.Sprint(groupChanges), "[]", "group callback")
+	mu.Unlock()
+	AssertStringEquals(t, fmt.Sprint(vault.Reads("secret/slow")), fmt.Sprint(slowReads), "reads of the slow path")
+
+	_, err = NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, nil, time.Hour, func(string) error { return nil },
+		WithGroupPaths(GroupPath{Path: "secret/data/app", Options: []Option{WithHashAlgorithm("md5")}}))
+	AssertError(t, err, `invalid options for secret/data/app: unknown hash algorithm "md5"`, "NewWatcherGroup() with invalid options")
+}
+
+func TestWatcherGroup_Priority(t *testing.T) {
+	values := map[string]string{"kv/data/low": "l", "kv/data/normal": "n", "kv/data/tls": "t"}
+	var mu sync.Mutex
+	var reads []string
+	handler := &slowHandler{
+		next: &fakeKVPaths{values: values},
+		delay: func(path string) time.Duration {
+			mu.Lock()
+			defer mu.Unlock()
+			reads = append(reads, strings.TrimPrefix(path, "/v1/"))
+			return 0
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	group, err := NewWatcherGroup(&VaultConfig{Host: server.URL, Token: "test-token"}, nil, time.Hour,
+		func(string) error { return nil }, WithGroupConcurrency(1),
+		WithGroupPaths(
+			GroupPath{Path: "kv/data/low", Priority: PriorityLow},
+			GroupPath{Path: "kv/data/normal"},
+			GroupPath{Path: "kv/data/tls", Priority: PriorityHigh},
+		))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	readsOf := func(check func()) []string {
+		mu.Lock()
+		reads = nil
+		mu.Unlock()
+		check()
+		mu.Lock()
+		defer mu.Unlock()
+		return reads
+	}
+	behind := func() {
+		group.mu.Lock()
+		defer group.mu.Unlock()
+		group.metrics.LastCycleDuration = 2 * time.Hour
+		group.lastCycleBusy = 2 * time.Hour
+	}
+
+	tests := []struct {
+		name     string
+		behind   bool
+		want     []string
+		deferred int
+	}{
+		{"on time", false, []string{"kv/data/tls", "kv/data/normal", "kv/data/low"}, 0},
+		{"behind", true, []string{"kv/data/tls", "kv/data/normal"}, 1},
+		{"still behind", true, []string{"kv/data/tls", "kv/data/normal", "kv/data/low"}, 0},
+		{"behind again", true, []string{"kv/data/tls", "kv/data/normal"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.behind {
+				behind()
+			}
+			if got := readsOf(group.checkDue); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reads = %v, want %v", got, tt.want)
+			}
+			if got := group.Metrics().DeferredPaths; got != tt.deferred {
+				t.Errorf("DeferredPaths = %d, want %d", got, tt.deferred)
+			}
+		})
+	}
+}
+
+func TestWatcherGroup_PathHandlers(t *testing.T) {
+	vault := vaultwatchertest.NewServer(vaultwatchertest.WithMount("kv", 1))
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"value": "v1"})
+	vault.Put("kv/legacy", map[string]interface{}{"value": "v1"})
+	vault.PutCubbyhole(vault.Token, "app", map[string]interface{}{"value": "v1"})
+	vault.Put("secret/other", map[string]interface{}{"value": "v1"})
+
+	var mu sync.Mutex
+	var events []ChangeEvent
+	var groupChanges []string
+	record := func(event ChangeEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+		return nil
+	}
+	group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token},
+		[]string{"secret/data/app", "kv/legacy", "cubbyhole/app", "secret/data/other"}, time.Hour,
+		func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			groupChanges = append(groupChanges, path)
+			return nil
+		})
+	AssertNoError(t, err, "NewWatcherGroup()")
+	for _, path := range []string{"secret/data/app", "kv/legacy", "cubbyhole/app"} {
+		group.RegisterPathHandler(path, record)
+	}
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	vault.Put("secret/app", map[string]interface{}{"value": "v2"})
+	vault.Put("kv/legacy", map[string]interface{}{"value": "v2"})
+	vault.PutCubbyhole(vault.Token, "app", map[string]interface{}{"value": "v2"})
+	vault.Put("secret/other", map[string]interface{}{"value": "v2"})
+	group.checkAll()
+
+	mu.Lock()
+	got := map[string]MountType{}
+	for _, event := range events {
+		got[event.Path] = event.MountType
+	}
+	want := map[string]MountType{
+		"secret/data/app": MountTypeKVv2,
+		"kv/legacy":       MountTypeKV,
+		"cubbyhole/app":   MountTypeCubbyhole,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("handled events = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(groupChanges, []string{"secret/data/other"}) {
+		t.Errorf("group callback got %v, want [secret/data/other]", groupChanges)
+	}
+	events, groupChanges = nil, nil
+	mu.Unlock()
+
+	// Without its handler the path goes back to the group's callback
+	group.RegisterPathHandler("secret/data/app", nil)
+	vault.Put("secret/app", map[string]interface{}{"value": "v3"})
+	group.checkAll()
+
+	mu.Lock()
+	defer mu.Unlock()
+	AssertStringEquals(t, fmt.Sprint(len(events)), "0", "handled events after removing the handler")
+	if !reflect.DeepEqual(groupChanges, []string{"secret/data/app"}) {
+		t.Errorf("group callback got %v, want [secret/data/app]", groupChanges)
+	}
+}
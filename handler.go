@@ -0,0 +1,28 @@
+package vaultwatcher
+
+import "context"
+
+// ChangeHandler handles the changes a watcher detects. Unlike the onChange
+// callback of NewWatcher, it receives the change being applied and a context
+// cancelled when the watcher stops, so new fields of ChangeEvent reach it
+// without changing its signature.
+type ChangeHandler interface {
+	HandleChange(ctx context.Context, event ChangeEvent) error
+}
+
+// ChangeHandlerFunc adapts a function to ChangeHandler
+type ChangeHandlerFunc func(ctx context.Context, event ChangeEvent) error
+
+// HandleChange calls f
+func (f ChangeHandlerFunc) HandleChange(ctx context.Context, event ChangeEvent) error {
+	return f(ctx, event)
+}
+
+// LegacyHandler adapts an onChange callback as taken by NewWatcher to a
+// ChangeHandler, e.g. to move to NewWatcherWithHandler one callback at a time.
+// The callback can still read the change with LastChange.
+func LegacyHandler(onChange func() error) ChangeHandler {
+	return ChangeHandlerFunc(func(context.Context, ChangeEvent) error {
+		return onChange()
+	})
+}
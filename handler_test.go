@@ -0,0 +1,85 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestNewWatcherWithHandler_Errors(t *testing.T) {
+	handler := ChangeHandlerFunc(func(context.Context, ChangeEvent) error { return nil })
+	config := &VaultConfig{Host: "https://vault.example.com", Path: "secret/data/app", Token: "test-token"}
+
+	_, err := NewWatcherWithHandler(nil, time.Hour, handler)
+	AssertError(t, err, "vault config cannot be nil", "NewWatcherWithHandler() without a config")
+	_, err = NewWatcherWithHandler(config, time.Hour, nil)
+	AssertError(t, err, "change handler cannot be nil", "NewWatcherWithHandler() without a handler")
+	_, err = NewSourceWatcherWithHandler("app", &fakeSource{}, time.Hour, nil)
+	AssertError(t, err, "change handler cannot be nil", "NewSourceWatcherWithHandler() without a handler")
+}
+
+func TestNewWatcherWithHandler(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"user": "app", "password": "one"})
+
+	events := make(chan ChangeEvent, 1)
+	var handlerCtx context.Context
+	watcher, err := NewWatcherWithHandler(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		ChangeHandlerFunc(func(ctx context.Context, event ChangeEvent) error {
+			handlerCtx = ctx
+			events <- event
+			return nil
+		}))
+	AssertNoError(t, err, "NewWatcherWithHandler()")
+	AssertNoError(t, watcher.Start(), "Start()")
+
+	vault.Put("secret/app", map[string]interface{}{"user": "app", "password": "two"})
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+
+	event := <-events
+	AssertStringEquals(t, event.Path, "secret/data/app", "event path")
+	AssertStringEquals(t, string(event.MountType), string(MountTypeKVv2), "event mount type")
+	AssertStringEquals(t, fmt.Sprint(event.ChangedKeys), "[password]", "changed keys")
+	AssertStringEquals(t, fmt.Sprint(event.Version), "2", "event version")
+
+	// The handler's context ends with the watcher
+	watcher.Stop()
+	AssertBoolEquals(t, handlerCtx.Err() != nil, true, "handler context done after Stop()")
+}
+
+func TestLegacyHandler(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"key": "one"})
+
+	var watcher *Watcher
+	var seen []string
+	failing := errors.New("callback failed")
+	fail := true
+	watcher, err := NewSourceWatcherWithHandler("app", source, time.Hour, LegacyHandler(func() error {
+		event, _ := watcher.LastChange()
+		seen = append(seen, event.NewHash)
+		if fail {
+			return failing
+		}
+		return nil
+	}))
+	AssertNoError(t, err, "NewSourceWatcherWithHandler()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	source.set(map[string]interface{}{"key": "two"})
+	if err := watcher.checkForChanges(); !errors.Is(err, failing) {
+		t.Errorf("checkForChanges() = %v, want the callback's error", err)
+	}
+	fail = false
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges() after the callback recovered")
+
+	if len(seen) != 2 || seen[0] != seen[1] || seen[0] != watcher.GetCurrentHash() {
+		t.Errorf("callback saw hashes %v, want the new hash %s twice", seen, watcher.GetCurrentHash())
+	}
+}
@@ -2,22 +2,98 @@ package vaultwatcher
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
+	"hash"
+	"sort"
 )
 
-// CalculateHash calculates a SHA256 hash of all variables in the vault data
+// HashAlgorithm is the digest secrets are compared by
+type HashAlgorithm string
+
+const (
+	HashSHA256 HashAlgorithm = "sha256" // The default
+	HashSHA384 HashAlgorithm = "sha384"
+	HashSHA512 HashAlgorithm = "sha512"
+)
+
+// newHash returns a new digest of the algorithm; empty means SHA256
+func (a HashAlgorithm) newHash() (hash.Hash, error) {
+	switch a {
+	case "", HashSHA256:
+		return sha256.New(), nil
+	case HashSHA384:
+		return sha512.New384(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unknown hash algorithm %q", string(a))
+}
+
+// CalculateHash calculates a SHA256 hash of all variables in the vault data.
+// The data is encoded as JSON with sorted keys straight into the hash, so
+// large secrets are hashed without holding their whole encoding in memory.
 func CalculateHash(vaultData map[string]interface{}) (string, error) {
+	return calculateHash(vaultData, HashSHA256)
+}
+
+func calculateHash(vaultData map[string]interface{}, algorithm HashAlgorithm) (string, error) {
 	if vaultData == nil {
 		return "", fmt.Errorf("vault data cannot be nil")
 	}
 
-	jsonBytes, err := json.Marshal(vaultData)
+	h, err := algorithm.newHash()
 	if err != nil {
 		return "", err
 	}
+	if err := writeCanonicalJSON(h, vaultData); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CalculateKeyHashes calculates a SHA256 hash of each variable in the vault data.
+// Only the hashes are kept, so changed keys can be reported without retaining values.
+func CalculateKeyHashes(vaultData map[string]interface{}) (map[string]string, error) {
+	return calculateKeyHashes(vaultData, HashSHA256)
+}
+
+func calculateKeyHashes(vaultData map[string]interface{}, algorithm HashAlgorithm) (map[string]string, error) {
+	if vaultData == nil {
+		return nil, fmt.Errorf("vault data cannot be nil")
+	}
+
+	keyHashes := make(map[string]string, len(vaultData))
+	for key, value := range vaultData {
+		h, err := algorithm.newHash()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeCanonicalJSON(h, value); err != nil {
+			return nil, fmt.Errorf("failed to marshal key %q: %w", key, err)
+		}
+		keyHashes[key] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	return keyHashes, nil
+}
+
+// ChangedKeys returns the sorted names of keys that were added, removed or modified
+// between two sets of key hashes
+func ChangedKeys(oldHashes, newHashes map[string]string) []string {
+	changed := []string{}
+	for key, newHash := range newHashes {
+		if oldHash, ok := oldHashes[key]; !ok || oldHash != newHash {
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldHashes {
+		if _, ok := newHashes[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
 
-	hash := sha256.Sum256(jsonBytes)
-	return hex.EncodeToString(hash[:]), nil
+	sort.Strings(changed)
+	return changed
 }
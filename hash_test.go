@@ -2,6 +2,7 @@ package vaultwatcher
 
 import (
 	"testing"
+	"time"
 )
 
 func TestCalculateHash(t *testing.T) {
@@ -208,3 +209,99 @@ func TestCalculateHashDetectsChanges(t *testing.T) {
 		t.Error("CalculateHash() should detect key removals")
 	}
 }
+
+func TestCalculateKeyHashes(t *testing.T) {
+	if _, err := CalculateKeyHashes(nil); err == nil {
+		t.Error("CalculateKeyHashes() expected error for nil input")
+	}
+
+	original, err := CalculateKeyHashes(MockVaultData())
+	if err != nil {
+		t.Fatalf("CalculateKeyHashes() error = %v", err)
+	}
+	if len(original) != len(MockVaultData()) {
+		t.Errorf("CalculateKeyHashes() returned %d hashes, want %d", len(original), len(MockVaultData()))
+	}
+
+	again, err := CalculateKeyHashes(MockVaultData())
+	if err != nil {
+		t.Fatalf("CalculateKeyHashes() error = %v", err)
+	}
+	for key, hash := range original {
+		if again[key] != hash {
+			t.Errorf("CalculateKeyHashes() should be consistent for key %q", key)
+		}
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	original, err := CalculateKeyHashes(MockVaultData())
+	if err != nil {
+		t.Fatalf("CalculateKeyHashes() error = %v", err)
+	}
+	modified, err := CalculateKeyHashes(MockVaultDataModified())
+	if err != nil {
+		t.Fatalf("CalculateKeyHashes() error = %v", err)
+	}
+
+	want := []string{"api_key", "debug_mode", "features", "max_connections", "nested_config", "new_feature", "timeout_seconds"}
+	got := ChangedKeys(original, modified)
+	if len(got) != len(want) {
+		t.Fatalf("ChangedKeys() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ChangedKeys()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	removed := ChangedKeys(map[string]string{"a": "1", "b": "2"}, map[string]string{"a": "1"})
+	if len(removed) != 1 || removed[0] != "b" {
+		t.Errorf("ChangedKeys() = %v, want [b]", removed)
+	}
+
+	if unchanged := ChangedKeys(original, original); len(unchanged) != 0 {
+		t.Errorf("ChangedKeys() = %v, want no changes", unchanged)
+	}
+}
+
+func TestWatcher_HashAlgorithm(t *testing.T) {
+	data := map[string]interface{}{"password": "one"}
+	sha256Hash, err := CalculateHash(data)
+	AssertNoError(t, err, "CalculateHash()")
+
+	tests := []struct {
+		name      string
+		algorithm HashAlgorithm
+		wantLen   int
+		wantErr   string
+	}{
+		{name: "default", wantLen: 64},
+		{name: "sha256", algorithm: HashSHA256, wantLen: 64},
+		{name: "sha384", algorithm: HashSHA384, wantLen: 96},
+		{name: "sha512", algorithm: HashSHA512, wantLen: 128},
+		{name: "unknown", algorithm: "md5", wantErr: `unknown hash algorithm "md5"`},
+	}
+
+	for _, tt := range tests {
+		source := &fakeSource{}
+		source.set(data)
+		watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil }, WithHashAlgorithm(tt.algorithm))
+		if tt.wantErr != "" {
+			AssertError(t, err, tt.wantErr, tt.name)
+			continue
+		}
+		AssertNoError(t, err, tt.name)
+		AssertNoError(t, watcher.initialize(), tt.name+": initialize()")
+
+		hash := watcher.GetCurrentHash()
+		if len(hash) != tt.wantLen {
+			t.Errorf("%s: hash %q has %d characters, want %d", tt.name, hash, len(hash), tt.wantLen)
+		}
+		AssertBoolEquals(t, hash == sha256Hash, tt.wantLen == 64, tt.name+": same hash as CalculateHash()")
+	}
+
+	_, err = NewWatcher(&VaultConfig{Host: "https://vault.example.com", Path: "secret/data/app", Token: "test-token"}, time.Hour,
+		func() error { return nil }, WithHashAlgorithm(HashSHA512), WithTransitHMAC("transit/keys/watcher"))
+	AssertError(t, err, "WithHashAlgorithm can't be combined with WithTransitHMAC", "NewWatcher() with transit")
+}
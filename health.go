@@ -0,0 +1,87 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const defaultFailureThreshold = 3
+
+// HealthEvent describes a transition of the watcher between healthy and unhealthy.
+// The watcher becomes unhealthy once the failure threshold of consecutive failed
+// checks is reached, and healthy again after the next successful check.
+type HealthEvent struct {
+	Path                string    `json:"path"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Error               string    `json:"error,omitempty"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// HealthNotifier is implemented by notifiers that also want health transitions.
+// Notifiers registered with WithNotifier are checked for it automatically.
+type HealthNotifier interface {
+	NotifyHealth(ctx context.Context, event HealthEvent) error
+}
+
+// IsHealthy returns false while the watcher has reached its failure threshold
+func (w *Watcher) IsHealthy() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return !w.unhealthy
+}
+
+// recordCheckResult updates the failure counter and emits a health event
+// when the watcher crosses between healthy and unhealthy
+func (w *Watcher) recordCheckResult(checkErr error) {
+	w.emitCount("checks", 1)
+	if checkErr != nil {
+		w.emitCount("check_errors", 1)
+	}
+
+	w.mu.Lock()
+	w.authFailed = errors.Is(checkErr, ErrPermissionDenied)
+	if checkErr == nil {
+		w.lastSuccessfulCheck = time.Now()
+	}
+	var event *HealthEvent
+	if checkErr != nil {
+		w.consecutiveFailures++
+		if !w.unhealthy && w.consecutiveFailures >= w.failureThreshold {
+			w.unhealthy = true
+			event = &HealthEvent{
+				Healthy:             false,
+				ConsecutiveFailures: w.consecutiveFailures,
+				Error:               checkErr.Error(),
+			}
+		}
+	} else {
+		if w.unhealthy {
+			w.unhealthy = false
+			event = &HealthEvent{Healthy: true}
+		}
+		w.consecutiveFailures = 0
+	}
+	w.mu.Unlock()
+
+	if event != nil {
+		event.Path = w.vaultConfig.Path
+		event.Timestamp = time.Now().UTC()
+		w.notifyHealth(*event)
+	}
+	w.reportFatal(checkErr)
+}
+
+// notifyHealth delivers the health event to every notifier implementing HealthNotifier
+func (w *Watcher) notifyHealth(event HealthEvent) {
+	for _, notifier := range w.notifiers {
+		healthNotifier, ok := notifier.(HealthNotifier)
+		if !ok {
+			continue
+		}
+		if err := healthNotifier.NotifyHealth(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying vault watcher health", "error", w.redactError(err))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// healthRecorder collects the health events it is notified about
+type healthRecorder struct {
+	events []HealthEvent
+}
+
+func (r *healthRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *healthRecorder) NotifyHealth(ctx context.Context, event HealthEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestWatcher_HealthTransitions(t *testing.T) {
+	recorder := &healthRecorder{}
+	watcher, err := NewWatcher(TestVaultConfig(), time.Second, func() error { return nil },
+		WithNotifier(recorder),
+		WithFailureThreshold(2),
+	)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	checkErr := errors.New("connection refused")
+
+	watcher.recordCheckResult(checkErr)
+	AssertBoolEquals(t, watcher.IsHealthy(), true, "after one failure")
+	if len(recorder.events) != 0 {
+		t.Fatalf("got %d health events before threshold, want 0", len(recorder.events))
+	}
+
+	watcher.recordCheckResult(checkErr)
+	watcher.recordCheckResult(checkErr)
+	AssertBoolEquals(t, watcher.IsHealthy(), false, "after reaching threshold")
+	if len(recorder.events) != 1 {
+		t.Fatalf("got %d health events, want 1", len(recorder.events))
+	}
+	unhealthy := recorder.events[0]
+	AssertBoolEquals(t, unhealthy.Healthy, false, "unhealthy event")
+	AssertStringEquals(t, unhealthy.Error, "connection refused", "unhealthy event error")
+	AssertStringEquals(t, unhealthy.Path, "kv/data/test", "unhealthy event path")
+	if unhealthy.ConsecutiveFailures != 2 {
+		t.Errorf("ConsecutiveFailures = %d, want 2", unhealthy.ConsecutiveFailures)
+	}
+
+	watcher.recordCheckResult(nil)
+	watcher.recordCheckResult(nil)
+	AssertBoolEquals(t, watcher.IsHealthy(), true, "after recovery")
+	if len(recorder.events) != 2 {
+		t.Fatalf("got %d health events, want 2", len(recorder.events))
+	}
+	AssertBoolEquals(t, recorder.events[1].Healthy, true, "recovery event")
+}
+
+func TestWithFailureThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		want      int
+	}{
+		{name: "custom threshold", threshold: 5, want: 5},
+		{name: "zero keeps default", threshold: 0, want: defaultFailureThreshold},
+		{name: "negative keeps default", threshold: -1, want: defaultFailureThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := TestWatcher(t, nil)
+			defer watcher.Stop()
+
+			WithFailureThreshold(tt.threshold)(watcher)
+			if watcher.failureThreshold != tt.want {
+				t.Errorf("failureThreshold = %d, want %d", watcher.failureThreshold, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// staleCheckIntervals is how many check intervals may pass without a
+// successful check before a watcher is no longer ready
+const staleCheckIntervals = 3
+
+// probeResponse is the body served by HealthzHandler and ReadyzHandler
+type probeResponse struct {
+	Status   string         `json:"status"` // "ok" or "unavailable"
+	Watchers []watcherProbe `json:"watchers"`
+}
+
+type watcherProbe struct {
+	Path     string   `json:"path"`
+	Status   string   `json:"status"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// HealthzHandler serves a liveness probe for watchers, e.g. on /healthz. It
+// answers 503 Service Unavailable while any watcher has reached its failure
+// threshold or is denied permission to read its secret, and 200 OK otherwise.
+// The JSON body lists the problems of each watcher.
+func HealthzHandler(watchers ...*Watcher) http.Handler {
+	return probeHandler(watchers, (*Watcher).healthProblems)
+}
+
+// ReadyzHandler serves a readiness probe for watchers, e.g. on /readyz. A
+// watcher is ready once its initial fetch succeeded, while its token may read
+// the secret and while it checked successfully within the last three check
+// intervals. Instances following an elected leader, and watchers on a cron
+// schedule, skip the last condition. The handler answers 503 Service
+// Unavailable unless every watcher is ready.
+func ReadyzHandler(watchers ...*Watcher) http.Handler {
+	return probeHandler(watchers, func(w *Watcher) []string {
+		return w.readinessProblems(time.Now())
+	})
+}
+
+func probeHandler(watchers []*Watcher, problems func(*Watcher) []string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		response := probeResponse{Status: "ok", Watchers: make([]watcherProbe, 0, len(watchers))}
+		for _, w := range watchers {
+			probe := watcherProbe{Path: w.vaultConfig.Path, Status: "ok", Problems: problems(w)}
+			if len(probe.Problems) > 0 {
+				probe.Status = "unavailable"
+				response.Status = "unavailable"
+			}
+			response.Watchers = append(response.Watchers, probe)
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Cache-Control", "no-store")
+		if response.Status != "ok" {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(rw).Encode(response); err != nil {
+			slog.Error("Error writing vault watcher probe", "error", err)
+		}
+	})
+}
+
+// healthProblems returns why the watcher isn't live, if it isn't
+func (w *Watcher) healthProblems() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var problems []string
+	if w.unhealthy {
+		problems = append(problems, fmt.Sprintf("%d consecutive checks failed", w.consecutiveFailures))
+	}
+	if w.authFailed {
+		problems = append(problems, "permission to read the secret was denied")
+	}
+	return problems
+}
+
+// readinessProblems returns why the watcher isn't ready at now, if it isn't
+func (w *Watcher) readinessProblems(now time.Time) []string {
+	following := !w.IsLeader()
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.initialized {
+		return []string{"the initial fetch has not succeeded"}
+	}
+
+	var problems []string
+	if w.authFailed {
+		problems = append(problems, "permission to read the secret was denied")
+	}
+	maxAge := staleCheckIntervals * w.checkInterval
+	if !following && w.schedule == nil && now.Sub(w.lastSuccessfulCheck) > maxAge {
+		problems = append(problems, fmt.Sprintf("no successful check since %s", w.lastSuccessfulCheck.UTC().Format(time.RFC3339)))
+	}
+	return problems
+}
+
+// ProbeHealth queries a HealthzHandler or ReadyzHandler at url and returns an
+// error unless every watcher passes. It backs a healthcheck subcommand exiting
+// 0 or 1, for Docker HEALTHCHECK or Kubernetes exec probes in images without
+// curl.
+func ProbeHealth(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create probe request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to probe vault watcher: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var response probeResponse
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(body, &response) != nil || response.Status == "" {
+		return fmt.Errorf("vault watcher probe returned %s", resp.Status)
+	}
+	var problems []string
+	for _, probe := range response.Watchers {
+		for _, problem := range probe.Problems {
+			problems = append(problems, probe.Path+": "+problem)
+		}
+	}
+	return fmt.Errorf("vault watcher is %s: %s", response.Status, strings.Join(problems, "; "))
+}
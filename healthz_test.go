@@ -0,0 +1,111 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeHandlers(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	probe := func(handler http.Handler) (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	code, body := probe(ReadyzHandler(watcher))
+	AssertStringEquals(t, fmt.Sprint(code), "503", "readyz status before Start")
+	AssertStringEquals(t, body, `{"status":"unavailable","watchers":[{"path":"app","status":"unavailable","problems":["the initial fetch has not succeeded"]}]}`, "readyz body before Start")
+
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{name: "healthz", handler: HealthzHandler(watcher)},
+		{name: "readyz", handler: ReadyzHandler(watcher)},
+	}
+	for _, tt := range tests {
+		code, body := probe(tt.handler)
+		AssertStringEquals(t, fmt.Sprint(code), "200", tt.name+" status")
+		AssertStringEquals(t, body, `{"status":"ok","watchers":[{"path":"app","status":"ok"}]}`, tt.name+" body")
+	}
+
+	source.mu.Lock()
+	source.err = &sentinelError{sentinel: ErrPermissionDenied, err: errors.New("unexpected status 403")}
+	source.mu.Unlock()
+	waitFor(t, time.Second, func() bool { return !watcher.IsHealthy() }, "failure threshold")
+
+	code, body = probe(HealthzHandler(watcher))
+	AssertStringEquals(t, fmt.Sprint(code), "503", "healthz status while denied")
+	AssertBoolEquals(t, strings.Contains(body, `"permission to read the secret was denied"`), true, "healthz reports the denied permission")
+	code, _ = probe(ReadyzHandler(watcher))
+	AssertStringEquals(t, fmt.Sprint(code), "503", "readyz status while denied")
+	AssertBoolEquals(t, watcher.Status().AuthValid, false, "Status().AuthValid")
+}
+
+func TestWatcher_ReadinessProblems_Stale(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+	watcher, err := NewSourceWatcher("app", source, time.Minute, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	lastCheck := watcher.Status().LastSuccessfulCheck
+	tests := []struct {
+		after time.Duration
+		want  string
+	}{
+		{after: time.Minute, want: "[]"},
+		{after: 3 * time.Minute, want: "[]"},
+		{after: 4 * time.Minute, want: "[no successful check since " + lastCheck.UTC().Format(time.RFC3339) + "]"},
+	}
+	for _, tt := range tests {
+		AssertStringEquals(t, fmt.Sprint(watcher.readinessProblems(lastCheck.Add(tt.after))), tt.want, "readinessProblems() after "+tt.after.String())
+	}
+}
+
+func TestProbeHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "ok", status: http.StatusOK, body: `{"status":"ok","watchers":[{"path":"app","status":"ok"}]}`},
+		{name: "unavailable", status: http.StatusServiceUnavailable,
+			body:    `{"status":"unavailable","watchers":[{"path":"app","status":"unavailable","problems":["the initial fetch has not succeeded"]},{"path":"db","status":"ok"}]}`,
+			wantErr: "vault watcher is unavailable: app: the initial fetch has not succeeded"},
+		{name: "not a probe", status: http.StatusNotFound, body: "404 page not found", wantErr: "vault watcher probe returned 404 Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(tt.status)
+				rw.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			err := ProbeHealth(context.Background(), server.URL+"/readyz")
+			if tt.wantErr == "" {
+				AssertNoError(t, err, "ProbeHealth()")
+				return
+			}
+			AssertError(t, err, tt.wantErr, "ProbeHealth()")
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package vaultwatcher
+
+// LastChange returns the most recently detected change. Inside the onChange
+// callback it is the change being applied, so callbacks can read its keys and
+// version. It returns false before the first change.
+func (w *Watcher) LastChange() (ChangeEvent, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.lastChange == nil {
+		return ChangeEvent{}, false
+	}
+	return *w.lastChange, true
+}
+
+// ChangeHistory returns the applied changes kept with WithChangeHistory,
+// oldest first
+func (w *Watcher) ChangeHistory() []ChangeEvent {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]ChangeEvent(nil), w.history...)
+}
+
+// recordChange adds an applied change to the history; w.mu must be held
+func (w *Watcher) recordChange(event ChangeEvent) {
+	if w.historyLength == 0 {
+		return
+	}
+	w.history = append(w.history, event)
+	if len(w.history) > w.historyLength {
+		w.history = append([]ChangeEvent(nil), w.history[len(w.history)-w.historyLength:]...)
+	}
+}
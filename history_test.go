@@ -0,0 +1,101 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestWatcher_ChangeHistory(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	var watcher *Watcher
+	var seen []ChangeEvent
+	onChange := func() error {
+		event, ok := watcher.LastChange()
+		AssertBoolEquals(t, ok, true, "LastChange() inside onChange")
+		seen = append(seen, event)
+		return nil
+	}
+
+	var err error
+	watcher, err = NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		onChange, WithChangeHistory(2))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	if _, ok := watcher.LastChange(); ok {
+		t.Error("LastChange() before any change should return false")
+	}
+
+	for _, password := range []string{"two", "three", "four"} {
+		vault.Put("secret/app", map[string]interface{}{"password": password})
+		AssertNoError(t, watcher.check(), "check()")
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("callbacks = %d, want 3", len(seen))
+	}
+	first := seen[0]
+	if first.Version != 2 || first.PreviousVersion != 1 {
+		t.Errorf("first change versions = %d <- %d, want 2 <- 1", first.Version, first.PreviousVersion)
+	}
+	if first.CreatedTime == nil || first.DetectionLatency < 0 || first.Timestamp.Before(*first.CreatedTime) {
+		t.Errorf("first change CreatedTime = %v, DetectionLatency = %v, Timestamp = %v", first.CreatedTime, first.DetectionLatency, first.Timestamp)
+	}
+	AssertStringEquals(t, first.ChangedKeys[0], "password", "ChangedKeys")
+
+	history := watcher.ChangeHistory()
+	if len(history) != 2 || history[0].Version != 3 || history[1].Version != 4 {
+		t.Errorf("ChangeHistory() = %+v, want versions 3 and 4", history)
+	}
+
+	// Changes from another instance keep their version
+	remote := ChangeEvent{Path: "secret/data/app", NewHash: "remote", Version: 7}
+	AssertNoError(t, watcher.ApplyRemoteChange(remote), "ApplyRemoteChange()")
+	if watcher.CurrentVersion() != 7 {
+		t.Errorf("CurrentVersion() = %d, want 7", watcher.CurrentVersion())
+	}
+	if history := watcher.ChangeHistory(); history[len(history)-1].NewHash != "remote" {
+		t.Errorf("last history entry = %+v, want the remote change", history[len(history)-1])
+	}
+}
+
+func TestWatcher_ChangeHistoryDisabled(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.check(), "check()")
+
+	if history := watcher.ChangeHistory(); len(history) != 0 {
+		t.Errorf("ChangeHistory() = %+v, want none without WithChangeHistory", history)
+	}
+	if event, ok := watcher.LastChange(); !ok || event.Version != 2 {
+		t.Errorf("LastChange() = %+v, %v, want version 2", event, ok)
+	}
+}
+
+func TestChangeEvent_OmitsUnknownCreatedTime(t *testing.T) {
+	data, err := json.Marshal(ChangeEvent{Path: "secret/app"})
+	AssertNoError(t, err, "json.Marshal()")
+	AssertBoolEquals(t, strings.Contains(string(data), "created_time"), false, "created_time in "+string(data))
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err = json.Marshal(ChangeEvent{Path: "secret/app", CreatedTime: &created})
+	AssertNoError(t, err, "json.Marshal()")
+	AssertBoolEquals(t, strings.Contains(string(data), `"created_time":"2026-01-02T03:04:05Z"`), true, "created_time in "+string(data))
+}
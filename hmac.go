@@ -0,0 +1,137 @@
+package vaultwatcher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// transitHMAC names the transit key used to hash secrets inside Vault, or to
+// decrypt values with WithTransitDecrypt
+type transitHMAC struct {
+	mount   string
+	key     string
+	version int // Key version pinned by the first HMAC, so a key rotation isn't a change
+}
+
+// newTransitHMAC parses a transit key path like "transit/keys/watcher"
+func newTransitHMAC(keyPath string) (*transitHMAC, error) {
+	mount, key, ok := strings.Cut(keyPath, "/keys/")
+	if !ok || mount == "" || key == "" {
+		return nil, fmt.Errorf("transit key path must look like <mount>/keys/<name>, got %q", keyPath)
+	}
+	return &transitHMAC{mount: mount, key: key}, nil
+}
+
+// calculateHashes returns the hash of the watched keys of data and, when
+// they come at no extra cost, the hashes of those keys. With WithTransitHMAC
+// both are computed by Vault in one request; otherwise key hashes are left to
+// calculateKeyHashes.
+func (w *Watcher) calculateHashes(data map[string]interface{}) (string, map[string]string, error) {
+	data = w.watchedData(data)
+	if w.transitHMAC == nil {
+		if len(w.binaryKeys) > 0 {
+			return w.calculateBinaryHashes(data)
+		}
+		hash, err := calculateHash(data, w.hashAlgorithm)
+		return hash, nil, err
+	}
+	if data == nil {
+		return "", nil, fmt.Errorf("vault data cannot be nil")
+	}
+
+	// The first input is the whole secret, then each key in order
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	inputs := make([]interface{}, 0, len(keys)+1)
+	whole, err := json.Marshal(data)
+	if err != nil {
+		return "", nil, err
+	}
+	inputs = append(inputs, map[string]interface{}{"input": base64.StdEncoding.EncodeToString(whole)})
+	for _, key := range keys {
+		value, err := json.Marshal(data[key])
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal key %q: %w", key, err)
+		}
+		inputs = append(inputs, map[string]interface{}{"input": base64.StdEncoding.EncodeToString(value)})
+	}
+
+	hmacs, err := w.transitHMACs(inputs)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(hmacs) != len(inputs) {
+		return "", nil, fmt.Errorf("failed to hash with transit: got %d results for %d inputs", len(hmacs), len(inputs))
+	}
+
+	keyHashes := make(map[string]string, len(keys))
+	for i, key := range keys {
+		keyHashes[key] = hmacs[i+1]
+	}
+	return hmacs[0], keyHashes, nil
+}
+
+// calculateKeyHashes returns the hashes of the watched keys of data
+func (w *Watcher) calculateKeyHashes(data map[string]interface{}) (map[string]string, error) {
+	if len(w.binaryKeys) > 0 {
+		_, keyHashes, err := w.calculateBinaryHashes(w.watchedData(data))
+		return keyHashes, err
+	}
+	return calculateKeyHashes(w.watchedData(data), w.hashAlgorithm)
+}
+
+// transitHMACs sends a batch to transit/hmac and returns the HMACs in order
+func (w *Watcher) transitHMACs(inputs []interface{}) ([]string, error) {
+	w.mu.RLock()
+	version := w.transitHMAC.version
+	w.mu.RUnlock()
+
+	body := map[string]interface{}{"batch_input": inputs}
+	if version > 0 {
+		body["key_version"] = version
+	}
+
+	ctx, cancel := w.requestContext()
+	defer cancel()
+	secret, err := w.client.Logical().WriteWithContext(ctx, w.transitHMAC.mount+"/hmac/"+w.transitHMAC.key, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash with transit: %w", classifyVaultError(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("failed to hash with transit: empty response")
+	}
+	results, _ := secret.Data["batch_results"].([]interface{})
+
+	hmacs := make([]string, 0, len(results))
+	for _, result := range results {
+		fields, _ := result.(map[string]interface{})
+		if message, _ := fields["error"].(string); message != "" {
+			return nil, fmt.Errorf("failed to hash with transit: %s", message)
+		}
+		hmac, _ := fields["hmac"].(string)
+		if hmac == "" {
+			return nil, fmt.Errorf("failed to hash with transit: result has no hmac")
+		}
+		hmacs = append(hmacs, hmac)
+	}
+
+	if version == 0 && len(hmacs) > 0 {
+		// Pin the key version, e.g. 3 from "vault:v3:..."
+		if parts := strings.SplitN(hmacs[0], ":", 3); len(parts) == 3 {
+			if pinned, err := strconv.Atoi(strings.TrimPrefix(parts[1], "v")); err == nil {
+				w.mu.Lock()
+				w.transitHMAC.version = pinned
+				w.mu.Unlock()
+			}
+		}
+	}
+	return hmacs, nil
+}
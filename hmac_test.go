@@ -0,0 +1,101 @@
+package vaultwatcher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransitHMAC serves a KV v2 secret and transit/hmac for key "watcher"
+type fakeTransitHMAC struct {
+	mu         sync.Mutex
+	password   string
+	keyVersion int // Latest version of the key
+	requested  []int
+}
+
+func (f *fakeTransitHMAC) set(update func(*fakeTransitHMAC)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	update(f)
+}
+
+func (f *fakeTransitHMAC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path != "/v1/transit/hmac/watcher" {
+		fmt.Fprintf(w, `{"data": {"data": {"password": %q, "user": "app"}, "metadata": {"version": 1}}}`, f.password)
+		return
+	}
+
+	var body struct {
+		BatchInput []struct{ Input string } `json:"batch_input"`
+		KeyVersion int                      `json:"key_version"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.requested = append(f.requested, body.KeyVersion)
+	version := body.KeyVersion
+	if version == 0 {
+		version = f.keyVersion
+	}
+
+	results := make([]string, len(body.BatchInput))
+	for i, input := range body.BatchInput {
+		decoded, _ := base64.StdEncoding.DecodeString(input.Input)
+		mac := hmac.New(sha256.New, []byte(fmt.Sprintf("key-%d", version)))
+		mac.Write(decoded)
+		results[i] = fmt.Sprintf(`{"hmac": "vault:v%d:%s"}`, version, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+	fmt.Fprintf(w, `{"data": {"batch_results": [%s]}}`, strings.Join(results, ","))
+}
+
+func TestWatcher_TransitHMAC(t *testing.T) {
+	fake := &fakeTransitHMAC{password: "one", keyVersion: 1}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	recorder := &changeRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithTransitHMAC("transit/keys/watcher"), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	initial := watcher.GetCurrentHash()
+	if !strings.HasPrefix(initial, "vault:v1:") {
+		t.Errorf("hash = %q, want a transit HMAC", initial)
+	}
+
+	// Rotating the key doesn't look like a change
+	fake.set(func(f *fakeTransitHMAC) { f.keyVersion = 2 })
+	AssertNoError(t, watcher.check(), "check() after key rotation")
+	AssertStringEquals(t, watcher.GetCurrentHash(), initial, "hash after key rotation")
+
+	fake.set(func(f *fakeTransitHMAC) { f.password = "two" })
+	AssertNoError(t, watcher.check(), "check() after change")
+
+	changed := recorder.changedKeys()
+	if len(changed) != 1 {
+		t.Fatalf("events = %d, want 1", len(changed))
+	}
+	AssertStringEquals(t, strings.Join(changed[0], ","), "password", "ChangedKeys")
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	AssertStringEquals(t, fmt.Sprint(fake.requested), "[0 1 1]", "requested key versions")
+}
+
+func TestWatcher_TransitHMACErrors(t *testing.T) {
+	_, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, WithTransitHMAC("transit/watcher"))
+	AssertError(t, err, `transit key path must look like <mount>/keys/<name>, got "transit/watcher"`, "NewWatcher() with a bad key path")
+}
@@ -0,0 +1,118 @@
+//go:build integration
+// +build integration
+
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	integrationVaultImage = "hashicorp/vault:1.17"
+	integrationVaultToken = "root"
+	integrationVaultPath  = "secret/data/test"
+)
+
+// integrationVault returns a ready VaultConfig for integration tests, seeded with
+// key1=value1 and key2=value2 at secret/test. It uses VAULT_HOST, VAULT_PATH and
+// VAULT_TOKEN when they are set; otherwise it starts a Vault dev server in
+// Docker, removed again when the test ends. Tests are skipped without either.
+func integrationVault(t testing.TB) (*VaultConfig, *api.Client) {
+	t.Helper()
+
+	config, err := LoadVaultConfigFromEnv()
+	if err != nil {
+		config = startVaultContainer(t)
+	}
+
+	client, err := newVaultClient(config, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create vault client: %v", err)
+	}
+	writeIntegrationSecret(t, client, config.Path, map[string]interface{}{"key1": "value1", "key2": "value2"})
+
+	return config, client
+}
+
+// startVaultContainer runs a Vault dev server container and waits until it is unsealed
+func startVaultContainer(t testing.TB) *VaultConfig {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("Skipping integration test: VAULT_HOST is not set and docker is not available")
+	}
+
+	id, err := docker("run", "-d", "--rm",
+		"--cap-add=IPC_LOCK",
+		"-e", "VAULT_DEV_ROOT_TOKEN_ID="+integrationVaultToken,
+		"-p", "127.0.0.1::8200",
+		integrationVaultImage)
+	if err != nil {
+		t.Skipf("Skipping integration test: failed to start vault container: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := docker("rm", "-f", id); err != nil {
+			t.Logf("Failed to remove vault container %s: %v", id, err)
+		}
+	})
+
+	address, err := docker("port", id, "8200/tcp")
+	if err != nil {
+		t.Fatalf("Failed to find vault container port: %v", err)
+	}
+	// docker port may list an IPv6 binding too; the first line is enough
+	address = strings.SplitN(address, "\n", 2)[0]
+
+	config := &VaultConfig{
+		Host:  "http://" + address,
+		Path:  integrationVaultPath,
+		Token: integrationVaultToken,
+	}
+	client, err := newVaultClient(config, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create vault client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for {
+		health, err := client.Sys().Health()
+		if err == nil && health.Initialized && !health.Sealed {
+			return config
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Vault container did not become ready: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// writeIntegrationSecret replaces the KV v2 secret at a secret/data/... path
+func writeIntegrationSecret(t testing.TB, client *api.Client, path string, data map[string]interface{}) {
+	t.Helper()
+
+	if _, err := client.Logical().Write(path, map[string]interface{}{"data": data}); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// docker runs a docker command and returns its trimmed output
+func docker(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,552 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultConsulWatchWait is how long a Consul blocking query waits for a change
+	defaultConsulWatchWait = 5 * time.Minute
+	// maxKVWatchBackoff caps the delay between failed watch requests
+	maxKVWatchBackoff = 30 * time.Second
+)
+
+// errWatchUnsupported is returned when a KV store has no watch API, so the
+// watcher only polls
+var errWatchUnsupported = errors.New("watch api is not available")
+
+// ConsulKVSourceConfig holds the configuration for a ConsulKVSource
+type ConsulKVSourceConfig struct {
+	Address    string        // Consul HTTP address (default "http://127.0.0.1:8500")
+	Prefix     string        // Key prefix to watch, e.g. "myapp/config/"
+	Token      string        // Optional ACL token
+	WaitTime   time.Duration // Longest a blocking query waits for a change (default 5m)
+	HTTPClient *http.Client  // Optional custom HTTP client
+}
+
+// ConsulKVSource reads every key under a Consul KV prefix. Changes are
+// watched with blocking queries.
+type ConsulKVSource struct {
+	address string
+	prefix  string
+	token   string
+	wait    time.Duration
+	client  *http.Client
+
+	mu    sync.Mutex
+	index uint64 // X-Consul-Index of the last fetch
+}
+
+// NewConsulKVSource creates a source reading the keys under config.Prefix
+func NewConsulKVSource(config ConsulKVSourceConfig) (*ConsulKVSource, error) {
+	if config.Prefix == "" {
+		return nil, fmt.Errorf("consul kv prefix is required")
+	}
+	if config.Address == "" {
+		config.Address = "http://127.0.0.1:8500"
+	}
+	if config.WaitTime <= 0 {
+		config.WaitTime = defaultConsulWatchWait
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &ConsulKVSource{
+		address: strings.TrimSuffix(config.Address, "/"),
+		prefix:  strings.TrimPrefix(config.Prefix, "/"),
+		token:   config.Token,
+		wait:    config.WaitTime,
+		client:  client,
+	}, nil
+}
+
+// Fetch returns the values under the prefix as strings, keyed by their name
+// relative to the prefix. The Consul index is reported as the version.
+func (s *ConsulKVSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	pairs, index, err := s.list(ctx, s.client, 0)
+	if err != nil {
+		return nil, Meta{}, fmt.Errorf("failed to read consul kv %s: %w", s.prefix, err)
+	}
+	s.mu.Lock()
+	s.index = index
+	s.mu.Unlock()
+
+	data := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		if strings.HasSuffix(pair.Key, "/") {
+			// Folder entries have no value
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(pair.Value)
+		if err != nil {
+			return nil, Meta{}, fmt.Errorf("failed to decode consul kv %s: %w", pair.Key, err)
+		}
+		data[relativeKey(pair.Key, s.prefix)] = string(value)
+	}
+	return data, Meta{Version: int(index)}, nil
+}
+
+// Changes runs blocking queries on the prefix from the index of the last
+// fetch, signalling each time the index moves
+func (s *ConsulKVSource) Changes(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		client := watchClient(s.client)
+
+		s.mu.Lock()
+		index := s.index
+		s.mu.Unlock()
+
+		failures := 0
+		for {
+			_, next, err := s.list(ctx, client, index)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil && !errors.Is(err, ErrSecretNotFound) {
+				failures++
+				if !sleepContext(ctx, kvWatchBackoff(failures)) {
+					return
+				}
+				continue
+			}
+			failures = 0
+
+			if next == 0 {
+				// Without an index a blocking query returns at once
+				slog.Warn("Error watching consul kv, polling instead", "prefix", s.prefix, "error", errWatchUnsupported)
+				return
+			}
+			if index > 0 && next != index {
+				notifyChange(changes)
+			}
+			if next < index {
+				// The index went backwards, e.g. after a snapshot restore
+				next = 0
+			}
+			index = next
+		}
+	}()
+	return changes
+}
+
+// list reads the keys under the prefix and the X-Consul-Index of the
+// response. A positive index makes it a blocking query. A missing prefix
+// returns ErrSecretNotFound along with the index.
+func (s *ConsulKVSource) list(ctx context.Context, client *http.Client, index uint64) ([]consulKVPair, uint64, error) {
+	requestURL := s.address + "/v1/kv/" + s.prefix + "?recurse=true"
+	if index > 0 {
+		requestURL += fmt.Sprintf("&index=%d&wait=%ds", index, int(s.wait.Seconds()))
+		// Consul adds up to wait/16 of jitter before answering
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.wait+s.wait/16+10*time.Second)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, next, ErrSecretNotFound
+	case resp.StatusCode == http.StatusForbidden:
+		return nil, next, &sentinelError{sentinel: ErrPermissionDenied, err: fmt.Errorf("unexpected status %d", resp.StatusCode)}
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, next, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var pairs []consulKVPair
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, next, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return pairs, next, nil
+}
+
+// EtcdSourceConfig holds the configuration for an EtcdSource
+type EtcdSourceConfig struct {
+	Address    string       // etcd v3 HTTP gateway address (default "http://127.0.0.1:2379")
+	Prefix     string       // Key prefix to watch, e.g. "/myapp/config/"
+	Username   string       // Optional user for etcd authentication
+	Password   string       // Password of Username
+	HTTPClient *http.Client // Optional custom HTTP client
+}
+
+// EtcdSource reads every key under an etcd prefix through the v3 JSON
+// gateway. Changes are streamed from its watch API.
+type EtcdSource struct {
+	address  string
+	prefix   string
+	username string
+	password string
+	client   *http.Client
+
+	mu       sync.Mutex
+	token    string // Authentication token, empty until authenticated
+	revision int64  // Store revision of the last fetch
+}
+
+// NewEtcdSource creates a source reading the keys under config.Prefix
+func NewEtcdSource(config EtcdSourceConfig) (*EtcdSource, error) {
+	if config.Prefix == "" {
+		return nil, fmt.Errorf("etcd prefix is required")
+	}
+	if config.Address == "" {
+		config.Address = "http://127.0.0.1:2379"
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &EtcdSource{
+		address:  strings.TrimSuffix(config.Address, "/"),
+		prefix:   config.Prefix,
+		username: config.Username,
+		password: config.Password,
+		client:   client,
+	}, nil
+}
+
+// etcdHeader is the response header of the etcd v3 API. The gateway
+// encodes 64-bit integers as strings.
+type etcdHeader struct {
+	Revision json.Number `json:"revision"`
+}
+
+// etcdKeyValue is a key returned by a range request, base64 encoded
+type etcdKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// etcdRangeRequest selects the keys from Key up to RangeEnd, base64 encoded
+type etcdRangeRequest struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"range_end"`
+}
+
+type etcdRangeResponse struct {
+	Header etcdHeader     `json:"header"`
+	Kvs    []etcdKeyValue `json:"kvs"`
+}
+
+type etcdWatchRequest struct {
+	CreateRequest struct {
+		Key           string `json:"key"`
+		RangeEnd      string `json:"range_end"`
+		StartRevision int64  `json:"start_revision,omitempty"`
+	} `json:"create_request"`
+}
+
+// etcdWatchMessage is one message of the watch stream
+type etcdWatchMessage struct {
+	Result struct {
+		Header          etcdHeader        `json:"header"`
+		Canceled        bool              `json:"canceled"`
+		CancelReason    string            `json:"cancel_reason"`
+		CompactRevision json.Number       `json:"compact_revision"`
+		Events          []json.RawMessage `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Fetch returns the values under the prefix as strings, keyed by their name
+// relative to the prefix. The store revision is reported as the version.
+func (s *EtcdSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	key, rangeEnd := s.keyRange()
+	var resp etcdRangeResponse
+	if err := s.call(ctx, "/v3/kv/range", etcdRangeRequest{Key: key, RangeEnd: rangeEnd}, &resp); err != nil {
+		return nil, Meta{}, fmt.Errorf("failed to read etcd prefix %s: %w", s.prefix, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, Meta{}, fmt.Errorf("failed to read etcd prefix %s: %w", s.prefix, ErrSecretNotFound)
+	}
+	revision, _ := resp.Header.Revision.Int64()
+	s.mu.Lock()
+	s.revision = revision
+	s.mu.Unlock()
+
+	data := make(map[string]interface{}, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		name, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			return nil, Meta{}, fmt.Errorf("failed to decode etcd key: %w", err)
+		}
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return nil, Meta{}, fmt.Errorf("failed to decode etcd key %s: %w", name, err)
+		}
+		data[relativeKey(string(name), s.prefix)] = string(value)
+	}
+	return data, Meta{Version: int(revision)}, nil
+}
+
+// Changes watches the prefix from the revision after the last fetch,
+// signalling for every batch of events. If the gateway has no watch API the
+// channel is closed and the watcher only polls.
+func (s *EtcdSource) Changes(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		client := watchClient(s.client)
+
+		s.mu.Lock()
+		revision := s.revision
+		s.mu.Unlock()
+
+		failures := 0
+		for {
+			next, err := s.watch(ctx, client, revision, changes)
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, errWatchUnsupported) {
+				slog.Warn("Error watching etcd prefix, polling instead", "prefix", s.prefix, "error", err)
+				return
+			}
+			if next > revision {
+				failures = 0
+			}
+			revision = next
+			failures++
+			if !sleepContext(ctx, kvWatchBackoff(failures)) {
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// watch streams changes after revision until the stream ends, and returns
+// the last revision it saw
+func (s *EtcdSource) watch(ctx context.Context, client *http.Client, revision int64, changes chan<- struct{}) (int64, error) {
+	var body etcdWatchRequest
+	body.CreateRequest.Key, body.CreateRequest.RangeEnd = s.keyRange()
+	if revision > 0 {
+		body.CreateRequest.StartRevision = revision + 1
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return revision, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	token, err := s.authenticate(ctx)
+	if err != nil {
+		return revision, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.address+"/v3/watch", bytes.NewReader(payload))
+	if err != nil {
+		return revision, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return revision, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented:
+		return revision, fmt.Errorf("%w: status %d", errWatchUnsupported, resp.StatusCode)
+	case resp.StatusCode == http.StatusUnauthorized:
+		s.resetToken()
+		return revision, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return revision, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message etcdWatchMessage
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return revision, fmt.Errorf("watch stream closed")
+			}
+			return revision, fmt.Errorf("failed to decode watch response: %w", err)
+		}
+		if message.Error != nil {
+			return revision, fmt.Errorf("watch failed: %s", message.Error.Message)
+		}
+
+		result := message.Result
+		if compacted, _ := result.CompactRevision.Int64(); compacted > 0 {
+			// Changes since revision were compacted away; check now and
+			// watch from the current revision
+			notifyChange(changes)
+			return 0, fmt.Errorf("watch revision %d was compacted", revision+1)
+		}
+		if result.Canceled {
+			return revision, fmt.Errorf("watch canceled: %s", result.CancelReason)
+		}
+		if len(result.Events) > 0 {
+			notifyChange(changes)
+		}
+		if next, _ := result.Header.Revision.Int64(); next > revision {
+			revision = next
+		}
+	}
+}
+
+// call posts body to an etcd v3 API path and decodes the response into out
+func (s *EtcdSource) call(ctx context.Context, apiPath string, body, out interface{}) error {
+	token, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := doJSON(ctx, s.client, http.MethodPost, s.address+apiPath, body, out, func(req *http.Request) {
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+	})
+	switch status {
+	case http.StatusUnauthorized:
+		// The token may have expired; authenticate again next time
+		s.resetToken()
+		return &sentinelError{sentinel: ErrPermissionDenied, err: err}
+	case http.StatusForbidden:
+		return &sentinelError{sentinel: ErrPermissionDenied, err: err}
+	}
+	return err
+}
+
+// authenticate returns the token for Username, requesting one if needed. It
+// returns an empty token when authentication isn't configured.
+func (s *EtcdSource) authenticate(ctx context.Context) (string, error) {
+	if s.username == "" {
+		return "", nil
+	}
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+	if token != "" {
+		return token, nil
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	request := map[string]string{"name": s.username, "password": s.password}
+	status, err := doJSON(ctx, s.client, http.MethodPost, s.address+"/v3/auth/authenticate", request, &resp, func(*http.Request) {})
+	if err != nil {
+		if status == http.StatusUnauthorized || status == http.StatusBadRequest {
+			err = &sentinelError{sentinel: ErrPermissionDenied, err: err}
+		}
+		return "", fmt.Errorf("failed to authenticate to etcd: %w", err)
+	}
+
+	s.mu.Lock()
+	s.token = resp.Token
+	s.mu.Unlock()
+	return resp.Token, nil
+}
+
+func (s *EtcdSource) resetToken() {
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+}
+
+// keyRange returns the base64 encoded key range covering the prefix
+func (s *EtcdSource) keyRange() (string, string) {
+	return base64.StdEncoding.EncodeToString([]byte(s.prefix)),
+		base64.StdEncoding.EncodeToString(etcdPrefixEnd([]byte(s.prefix)))
+}
+
+// etcdPrefixEnd returns the end of the range of keys starting with prefix:
+// the prefix with its last byte below 0xff incremented
+func etcdPrefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// Every byte is 0xff: range to the end of the keyspace
+	return []byte{0}
+}
+
+// relativeKey names key relative to prefix. A prefix naming a single key
+// keeps its last segment, like a single Parameter Store parameter.
+func relativeKey(key, prefix string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(key, prefix), "/")
+	if name == "" {
+		return path.Base(key)
+	}
+	return name
+}
+
+// watchClient returns a copy of client without its timeout, since watch
+// requests stay open until something changes
+func watchClient(client *http.Client) *http.Client {
+	watch := *client
+	watch.Timeout = 0
+	return &watch
+}
+
+// kvWatchBackoff returns the delay after the given number of failed watch
+// requests in a row
+func kvWatchBackoff(failures int) time.Duration {
+	delay := time.Second
+	for i := 1; i < failures && delay < maxKVWatchBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxKVWatchBackoff {
+		delay = maxKVWatchBackoff
+	}
+	return delay
+}
+
+// sleepContext waits for d and reports false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// notifyChange signals changes without blocking; one pending signal is enough
+func notifyChange(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
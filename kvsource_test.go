@@ -0,0 +1,284 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsulPrefix serves a prefix of the Consul KV API with blocking queries
+type fakeConsulPrefix struct {
+	t       *testing.T
+	mu      sync.Mutex
+	index   uint64
+	values  map[string]string
+	changed chan struct{} // Closed and replaced on every change
+}
+
+func newFakeConsulPrefix(t *testing.T, values map[string]string) *fakeConsulPrefix {
+	return &fakeConsulPrefix{t: t, index: 10, values: values, changed: make(chan struct{})}
+}
+
+func (f *fakeConsulPrefix) set(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = value
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsulPrefix) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	AssertStringEquals(f.t, r.URL.Path, "/v1/kv/myapp/", "path")
+	AssertStringEquals(f.t, r.Header.Get("X-Consul-Token"), "acl-token", "X-Consul-Token")
+
+	f.mu.Lock()
+	index, changed := f.index, f.changed
+	f.mu.Unlock()
+	if wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); wait >= index {
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	if len(f.values) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	pairs := []map[string]interface{}{{"Key": "myapp/", "Value": nil, "ModifyIndex": 1}}
+	for key, value := range f.values {
+		pairs = append(pairs, map[string]interface{}{
+			"Key":         key,
+			"Value":       base64.StdEncoding.EncodeToString([]byte(value)),
+			"ModifyIndex": f.index,
+		})
+	}
+	json.NewEncoder(w).Encode(pairs)
+}
+
+func testConsulKVSource(t *testing.T, address string) *ConsulKVSource {
+	source, err := NewConsulKVSource(ConsulKVSourceConfig{Address: address, Prefix: "myapp/", Token: "acl-token"})
+	AssertNoError(t, err, "NewConsulKVSource()")
+	return source
+}
+
+func TestConsulKVSource_Fetch(t *testing.T) {
+	fake := newFakeConsulPrefix(t, map[string]string{"myapp/db/password": "hunter2", "myapp/port": "5432"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	data, meta, err := testConsulKVSource(t, server.URL).Fetch(context.Background())
+	AssertNoError(t, err, "Fetch()")
+	assertJSONEquals(t, data, `{"db/password":"hunter2","port":"5432"}`)
+	if meta.Version != 10 {
+		t.Errorf("Version = %d, want the consul index 10", meta.Version)
+	}
+
+	fake.values = map[string]string{}
+	_, _, err = testConsulKVSource(t, server.URL).Fetch(context.Background())
+	AssertBoolEquals(t, errors.Is(err, ErrSecretNotFound), true, "missing prefix error is ErrSecretNotFound")
+}
+
+func TestConsulKVSource_Changes(t *testing.T) {
+	fake := newFakeConsulPrefix(t, map[string]string{"myapp/port": "5432"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	source := testConsulKVSource(t, server.URL)
+	_, _, err := source.Fetch(context.Background())
+	AssertNoError(t, err, "Fetch()")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := source.Changes(ctx)
+
+	select {
+	case <-changes:
+		t.Fatal("Changes() signalled before anything changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fake.set("myapp/port", "6543")
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("Changes() did not signal the change")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-changes:
+		AssertBoolEquals(t, ok, false, "channel open after cancel")
+	case <-time.After(time.Second):
+		t.Fatal("Changes() was not closed after cancel")
+	}
+}
+
+func TestConsulKVSource_WatcherChecksOnChange(t *testing.T) {
+	fake := newFakeConsulPrefix(t, map[string]string{"myapp/port": "5432"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	changed := make(chan struct{}, 1)
+	// The interval is too long to matter: only the blocking query can trigger the check
+	watcher, err := NewSourceWatcher("myapp/", testConsulKVSource(t, server.URL), time.Hour, func() error {
+		changed <- struct{}{}
+		return nil
+	})
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	fake.set("myapp/port", "6543")
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called after the consul key changed")
+	}
+}
+
+// fakeEtcd serves the etcd v3 JSON gateway for range and watch requests
+type fakeEtcd struct {
+	t         *testing.T
+	events    chan string // Watch messages to stream
+	watchCode int         // Status of watch requests, 200 if zero
+	startRev  chan string // Receives the start revision of each watch
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if r.URL.Path == "/v3/auth/authenticate" {
+		AssertStringEquals(f.t, fmt.Sprint(body["name"], ":", body["password"]), "app:secret", "credentials")
+		io.WriteString(w, `{"header": {"revision": "7"}, "token": "etcd-token"}`)
+		return
+	}
+	AssertStringEquals(f.t, r.Header.Get("Authorization"), "etcd-token", "Authorization")
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		AssertStringEquals(f.t, fmt.Sprint(body["key"]), base64.StdEncoding.EncodeToString([]byte("/myapp/")), "key")
+		AssertStringEquals(f.t, fmt.Sprint(body["range_end"]), base64.StdEncoding.EncodeToString([]byte("/myapp0")), "range_end")
+		io.WriteString(w, `{"header": {"revision": "7"}, "kvs": [`+
+			`{"key": "L215YXBwL2RiL3Bhc3N3b3Jk", "value": "aHVudGVyMg==", "mod_revision": "5"},`+
+			`{"key": "L215YXBwL3BvcnQ=", "value": "NTQzMg==", "mod_revision": "7"}], "count": "2"}`)
+	case "/v3/watch":
+		if f.watchCode != 0 {
+			w.WriteHeader(f.watchCode)
+			return
+		}
+		request, _ := body["create_request"].(map[string]interface{})
+		f.startRev <- fmt.Sprint(request["start_revision"])
+		io.WriteString(w, `{"result": {"header": {"revision": "7"}, "created": true}}`+"\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case message := <-f.events:
+				io.WriteString(w, message+"\n")
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	default:
+		f.t.Errorf("unexpected request to %s", r.URL.Path)
+	}
+}
+
+func testEtcdSource(t *testing.T, address string) *EtcdSource {
+	source, err := NewEtcdSource(EtcdSourceConfig{Address: address, Prefix: "/myapp/", Username: "app", Password: "secret"})
+	AssertNoError(t, err, "NewEtcdSource()")
+	return source
+}
+
+func TestEtcdSource_Fetch(t *testing.T) {
+	server := httptest.NewServer(&fakeEtcd{t: t})
+	defer server.Close()
+
+	data, meta, err := testEtcdSource(t, server.URL).Fetch(context.Background())
+	AssertNoError(t, err, "Fetch()")
+	assertJSONEquals(t, data, `{"db/password":"hunter2","port":"5432"}`)
+	if meta.Version != 7 {
+		t.Errorf("Version = %d, want the store revision 7", meta.Version)
+	}
+}
+
+func TestEtcdSource_Changes(t *testing.T) {
+	fake := &fakeEtcd{t: t, events: make(chan string), startRev: make(chan string, 1)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	source := testEtcdSource(t, server.URL)
+	_, _, err := source.Fetch(context.Background())
+	AssertNoError(t, err, "Fetch()")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := source.Changes(ctx)
+
+	select {
+	case rev := <-fake.startRev:
+		AssertStringEquals(t, rev, "8", "start revision")
+	case <-time.After(time.Second):
+		t.Fatal("Changes() did not start a watch")
+	}
+
+	fake.events <- `{"result": {"header": {"revision": "8"}, "events": [{"kv": {"key": "L215YXBwL3BvcnQ="}}]}}`
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("Changes() did not signal the event")
+	}
+}
+
+func TestEtcdSource_WatchUnsupported(t *testing.T) {
+	server := httptest.NewServer(&fakeEtcd{t: t, watchCode: http.StatusNotFound})
+	defer server.Close()
+
+	changes := testEtcdSource(t, server.URL).Changes(context.Background())
+	select {
+	case _, ok := <-changes:
+		AssertBoolEquals(t, ok, false, "channel open without a watch api")
+	case <-time.After(time.Second):
+		t.Fatal("Changes() was not closed without a watch api")
+	}
+}
+
+func TestEtcdPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "/myapp/", want: "/myapp0"},
+		{prefix: "a", want: "b"},
+		{prefix: "a\xff", want: "b"},
+		{prefix: "\xff\xff", want: "\x00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			AssertStringEquals(t, string(etcdPrefixEnd([]byte(tt.prefix))), tt.want, "etcdPrefixEnd()")
+		})
+	}
+}
+
+func TestNewKVSources_Errors(t *testing.T) {
+	_, err := NewConsulKVSource(ConsulKVSourceConfig{})
+	AssertError(t, err, "consul kv prefix is required", "NewConsulKVSource()")
+	_, err = NewEtcdSource(EtcdSourceConfig{})
+	AssertError(t, err, "etcd prefix is required", "NewEtcdSource()")
+}
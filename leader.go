@@ -0,0 +1,152 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const defaultLockTTL = 15 * time.Second
+
+// Lock is a distributed lock used for leader election. Implementations must
+// let the holder renew the lock by acquiring it again before the TTL ends.
+type Lock interface {
+	// TryAcquire takes or renews the lock for id and reports whether id holds it
+	TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	// Release gives up the lock if id holds it
+	Release(ctx context.Context, id string) error
+}
+
+// IsLeader returns whether this instance currently holds the leader lock.
+// It is always true without WithLeaderElection.
+func (w *Watcher) IsLeader() bool {
+	if w.lock == nil {
+		return true
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.leader
+}
+
+// ApplyRemoteChange applies a change detected by another instance, typically
+// the elected leader. The onChange callback runs unless this instance already
+// has the new hash, which is then adopted so the change isn't reported again
+// if this instance becomes the leader.
+func (w *Watcher) ApplyRemoteChange(event ChangeEvent) error {
+	if event.Path != w.vaultConfig.Path {
+		return fmt.Errorf("change event for %s does not match watched path %s", event.Path, w.vaultConfig.Path)
+	}
+
+	w.mu.RLock()
+	currentHash := w.currentHash
+	w.mu.RUnlock()
+
+	if event.NewHash == currentHash {
+		return nil
+	}
+
+	w.mu.Lock()
+	w.lastChange = &event
+	w.mu.Unlock()
+	onChange := func() error {
+		return w.handler.HandleChange(w.ctx, event)
+	}
+	if w.hasBindings() || len(w.participants) > 0 || len(w.templates) > 0 {
+		// The event carries no data; read it to update bound structs and
+		// participants
+		onChange = func() error {
+			data, err := w.readData()
+			if err != nil {
+				return fmt.Errorf("failed to read data to apply: %w", err)
+			}
+			return w.applyChange(event, data)
+		}
+	}
+	if err := w.runCallback("onChange", false, onChange); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.currentHash = event.NewHash
+	w.currentVersion = event.Version
+	w.currentCreated = time.Time{}
+	if event.CreatedTime != nil {
+		w.currentCreated = *event.CreatedTime
+	}
+	// Per-key hashes and data of the remote data are unknown; the next local
+	// change reports every key
+	w.keyHashes = nil
+	w.valuePathHashes = nil
+	w.forgetData()
+	w.recordChange(event)
+	w.mu.Unlock()
+
+	w.emitCount("changes", 1)
+	w.notifyListeners(event, nil)
+
+	return nil
+}
+
+// startElection campaigns once and keeps campaigning in the background
+func (w *Watcher) startElection() {
+	if w.lock == nil {
+		return
+	}
+
+	w.campaign()
+	w.wg.Add(1)
+	go w.elect()
+}
+
+// campaign tries to take or renew the leader lock once
+func (w *Watcher) campaign() {
+	ctx, cancel := context.WithTimeout(w.ctx, w.lockTTL/3)
+	defer cancel()
+
+	leader, err := w.lock.TryAcquire(ctx, w.lockID, w.lockTTL)
+	if err != nil {
+		// Step down: another instance may take over once our lease lapses
+		w.logger.Error("Error acquiring leader lock", "error", err)
+		leader = false
+	}
+
+	w.mu.Lock()
+	w.leader = leader
+	w.mu.Unlock()
+}
+
+// elect runs in a goroutine and keeps campaigning for leadership
+func (w *Watcher) elect() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.campaign()
+		}
+	}
+}
+
+// resign releases the leader lock after the watcher has stopped
+func (w *Watcher) resign() {
+	w.mu.Lock()
+	leader := w.leader
+	w.leader = false
+	w.mu.Unlock()
+
+	if !leader {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.lockTTL/3)
+	defer cancel()
+	if err := w.lock.Release(ctx, w.lockID); err != nil {
+		w.logger.Error("Error releasing leader lock", "error", err)
+	}
+}
@@ -0,0 +1,125 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryLock is an in-process Lock shared by the watchers of a test
+type memoryLock struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+}
+
+func (l *memoryLock) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder != "" && l.holder != id && time.Now().Before(l.expires) {
+		return false, nil
+	}
+	l.holder, l.expires = id, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (l *memoryLock) Release(ctx context.Context, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == id {
+		l.holder = ""
+	}
+	return nil
+}
+
+// countingVault serves a KV v2 secret and counts reads per token
+type countingVault struct {
+	mu    sync.Mutex
+	reads map[string]int
+	value string
+}
+
+func (v *countingVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if isMountLookup(r) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	v.reads[r.Header.Get("X-Vault-Token")]++
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{"data": map[string]interface{}{"key": v.value}},
+	})
+}
+
+func (v *countingVault) readsBy(token string) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.reads[token]
+}
+
+func TestWatcher_LeaderElection(t *testing.T) {
+	vault := &countingVault{reads: map[string]int{}, value: "v1"}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	lock := &memoryLock{}
+	newReplica := func(token string) *Watcher {
+		watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "kv/data/test", Token: token},
+			10*time.Millisecond, func() error { return nil },
+			WithLeaderElection(lock, token, 300*time.Millisecond))
+		AssertNoError(t, err, "NewWatcher()")
+		AssertNoError(t, watcher.Start(), "Start()")
+		return watcher
+	}
+
+	first := newReplica("first")
+	second := newReplica("second")
+	defer second.Stop()
+
+	AssertBoolEquals(t, first.IsLeader(), true, "first IsLeader()")
+	AssertBoolEquals(t, second.IsLeader(), false, "second IsLeader()")
+
+	time.Sleep(100 * time.Millisecond)
+	if reads := vault.readsBy("second"); reads != 1 {
+		t.Errorf("follower read the secret %d times, want only the initial read", reads)
+	}
+	if reads := vault.readsBy("first"); reads < 3 {
+		t.Errorf("leader read the secret %d times, want it to poll", reads)
+	}
+
+	// The follower takes over once the leader stops
+	first.Stop()
+	waitFor(t, time.Second, second.IsLeader, "follower takes over")
+}
+
+func TestWatcher_ApplyRemoteChange(t *testing.T) {
+	var calls atomic.Int32
+	watcher := TestWatcher(t, func() error {
+		calls.Add(1)
+		return nil
+	})
+	watcher.currentHash = "old"
+
+	err := watcher.ApplyRemoteChange(ChangeEvent{Path: "kv/data/other", NewHash: "new"})
+	AssertError(t, err, "change event for kv/data/other does not match watched path kv/data/test", "ApplyRemoteChange(other path)")
+
+	AssertNoError(t, watcher.ApplyRemoteChange(ChangeEvent{Path: "kv/data/test", OldHash: "old", NewHash: "new"}), "ApplyRemoteChange()")
+	AssertStringEquals(t, watcher.GetCurrentHash(), "new", "GetCurrentHash()")
+
+	// Duplicate deliveries are ignored
+	AssertNoError(t, watcher.ApplyRemoteChange(ChangeEvent{Path: "kv/data/test", OldHash: "old", NewHash: "new"}), "ApplyRemoteChange(duplicate)")
+	if calls.Load() != 1 {
+		t.Errorf("onChange called %d times, want 1", calls.Load())
+	}
+}
+
+func TestWatcher_IsLeaderWithoutElection(t *testing.T) {
+	watcher := TestWatcher(t, nil)
+	AssertBoolEquals(t, watcher.IsLeader(), true, "IsLeader()")
+}
@@ -0,0 +1,361 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultLeaseRetryInterval = 5 * time.Second
+	minLeaseRetryInterval     = 100 * time.Millisecond
+)
+
+// Lease identifies a Vault lease and its current duration
+type Lease struct {
+	ID        string
+	Path      string // Path the leased secret was read from
+	Duration  time.Duration
+	Renewable bool
+	RenewedAt time.Time // When the lease was issued or last renewed
+}
+
+// ExpiresAt returns when the lease expires unless it is renewed
+func (l Lease) ExpiresAt() time.Time {
+	return l.RenewedAt.Add(l.Duration)
+}
+
+// LeaseEventType identifies what happened to a tracked lease
+type LeaseEventType string
+
+const (
+	// LeaseRenewed means the lease was extended
+	LeaseRenewed LeaseEventType = "renewed"
+	// LeaseRenewalFailed means a renewal attempt failed; it will be retried until the lease expires
+	LeaseRenewalFailed LeaseEventType = "renewal_failed"
+	// LeaseExpiring means the lease can't be extended any further (not renewable
+	// or max TTL reached) and the secret should be replaced before it expires
+	LeaseExpiring LeaseEventType = "expiring"
+	// LeaseExpired means the lease has expired; it is no longer tracked
+	LeaseExpired LeaseEventType = "expired"
+)
+
+// LeaseEvent describes something that happened to a tracked lease
+type LeaseEvent struct {
+	Type      LeaseEventType
+	Lease     Lease
+	Error     error
+	Timestamp time.Time
+}
+
+// LeaseManagerOption configures optional LeaseManager behaviour
+type LeaseManagerOption func(*LeaseManager)
+
+// WithLeaseRetryInterval sets how long to wait before retrying a failed renewal
+// (default 5s). It is capped so retries still happen before expiry.
+func WithLeaseRetryInterval(interval time.Duration) LeaseManagerOption {
+	return func(m *LeaseManager) {
+		if interval > 0 {
+			m.retryInterval = interval
+		}
+	}
+}
+
+// WithLeaseEventHandler registers a handler receiving the events of every
+// tracked lease, e.g. for logging or metrics
+func WithLeaseEventHandler(handler func(LeaseEvent)) LeaseManagerOption {
+	return func(m *LeaseManager) {
+		if handler != nil {
+			m.eventHandlers = append(m.eventHandlers, handler)
+		}
+	}
+}
+
+// LeaseManager tracks the leases of dynamic secrets and renews them through
+// sys/leases/renew when two thirds of each lease have elapsed. A single manager
+// can be shared by many watchers; it uses one goroutine regardless of the
+// number of leases.
+type LeaseManager struct {
+	client        *api.Client
+	retryInterval time.Duration
+	eventHandlers []func(LeaseEvent)
+	leases        map[string]*trackedLease
+	wake          chan struct{}
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	mu            sync.Mutex
+	started       bool
+}
+
+// trackedLease is the manager's bookkeeping for one lease
+type trackedLease struct {
+	lease     Lease
+	issuedTTL time.Duration
+	handler   func(LeaseEvent)
+	nextCheck time.Time
+	expiring  bool
+}
+
+// NewLeaseManager creates a lease manager. The token in vaultConfig must be
+// allowed to renew the tracked leases; vaultConfig.Path is not used.
+func NewLeaseManager(vaultConfig *VaultConfig, opts ...LeaseManagerOption) (*LeaseManager, error) {
+	if vaultConfig == nil {
+		return nil, fmt.Errorf("vault config cannot be nil")
+	}
+	if vaultConfig.Host == "" {
+		return nil, fmt.Errorf("VAULT_HOST is required")
+	}
+	if vaultConfig.Token == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN is required")
+	}
+
+	client, err := newVaultClient(vaultConfig, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLeaseManager(client, opts...), nil
+}
+
+// newLeaseManager creates a lease manager using an existing client
+func newLeaseManager(client *api.Client, opts ...LeaseManagerOption) *LeaseManager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := &LeaseManager{
+		client:        client,
+		retryInterval: defaultLeaseRetryInterval,
+		leases:        make(map[string]*trackedLease),
+		wake:          make(chan struct{}, 1),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Start begins renewing tracked leases
+func (m *LeaseManager) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started {
+		return fmt.Errorf("lease manager is already started")
+	}
+	m.started = true
+
+	m.wg.Add(1)
+	go m.run()
+
+	return nil
+}
+
+// Stop stops renewing leases. Tracked leases are kept but expire naturally.
+func (m *LeaseManager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	m.started = false
+	m.mu.Unlock()
+}
+
+// Track starts managing a lease. The handler receives the events of this lease
+// and is called from the manager's goroutine, so it should not block for long.
+func (m *LeaseManager) Track(lease Lease, handler func(LeaseEvent)) error {
+	if lease.ID == "" {
+		return fmt.Errorf("lease ID is required")
+	}
+	if lease.Duration <= 0 {
+		return fmt.Errorf("lease %s has no duration", lease.ID)
+	}
+	if lease.RenewedAt.IsZero() {
+		lease.RenewedAt = time.Now()
+	}
+
+	m.mu.Lock()
+	m.leases[lease.ID] = &trackedLease{
+		lease:     lease,
+		issuedTTL: lease.Duration,
+		handler:   handler,
+		nextCheck: renewalTime(lease),
+	}
+	m.mu.Unlock()
+
+	m.signal()
+	return nil
+}
+
+// Untrack stops managing a lease, e.g. after the secret was replaced
+func (m *LeaseManager) Untrack(leaseID string) {
+	m.mu.Lock()
+	delete(m.leases, leaseID)
+	m.mu.Unlock()
+
+	m.signal()
+}
+
+// Leases returns the currently tracked leases
+func (m *LeaseManager) Leases() []Lease {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	leases := make([]Lease, 0, len(m.leases))
+	for _, tracked := range m.leases {
+		leases = append(leases, tracked.lease)
+	}
+	return leases
+}
+
+// signal wakes the run loop so it can reschedule
+func (m *LeaseManager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sleeps until the next lease is due and processes it
+func (m *LeaseManager) run() {
+	defer m.wg.Done()
+
+	for {
+		timer := time.NewTimer(m.untilNextCheck())
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-m.wake:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		m.processDue(time.Now())
+	}
+}
+
+// untilNextCheck returns the time until the earliest lease is due
+func (m *LeaseManager) untilNextCheck() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := time.Hour
+	for _, tracked := range m.leases {
+		if wait := time.Until(tracked.nextCheck); wait < next {
+			next = wait
+		}
+	}
+	return max(next, 0)
+}
+
+// processDue renews, flags or expires every lease whose check time has come
+func (m *LeaseManager) processDue(now time.Time) {
+	m.mu.Lock()
+	var due []*trackedLease
+	for _, tracked := range m.leases {
+		if !tracked.nextCheck.After(now) {
+			due = append(due, tracked)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, tracked := range due {
+		m.process(tracked, now)
+	}
+}
+
+// process handles a single due lease. Vault is called without holding the lock.
+func (m *LeaseManager) process(tracked *trackedLease, now time.Time) {
+	m.mu.Lock()
+	lease, expiring, issuedTTL := tracked.lease, tracked.expiring, tracked.issuedTTL
+	m.mu.Unlock()
+
+	if !now.Before(lease.ExpiresAt()) {
+		m.mu.Lock()
+		delete(m.leases, lease.ID)
+		m.mu.Unlock()
+		m.emit(tracked, LeaseEvent{Type: LeaseExpired, Lease: lease})
+		return
+	}
+
+	if !lease.Renewable || expiring {
+		m.markExpiring(tracked, lease)
+		return
+	}
+
+	renewed, err := m.client.Sys().RenewWithContext(m.ctx, lease.ID, int(issuedTTL.Seconds()))
+	if err == nil && renewed == nil {
+		err = fmt.Errorf("empty response renewing lease")
+	}
+	if m.ctx.Err() != nil {
+		// Stopped during the renewal
+		return
+	}
+	if err != nil {
+		m.mu.Lock()
+		tracked.nextCheck = now.Add(m.retryDelay(lease, now))
+		m.mu.Unlock()
+		m.emit(tracked, LeaseEvent{Type: LeaseRenewalFailed, Lease: lease, Error: err})
+		return
+	}
+
+	lease.Duration = time.Duration(renewed.LeaseDuration) * time.Second
+	lease.RenewedAt = time.Now()
+	if lease.Duration < issuedTTL/3 {
+		// Vault capped the renewal at the max TTL
+		m.markExpiring(tracked, lease)
+		return
+	}
+
+	m.mu.Lock()
+	tracked.lease = lease
+	tracked.nextCheck = renewalTime(lease)
+	m.mu.Unlock()
+	m.emit(tracked, LeaseEvent{Type: LeaseRenewed, Lease: lease})
+}
+
+// markExpiring records that a lease can't be extended and emits LeaseExpiring once.
+// The lease is checked again when it expires.
+func (m *LeaseManager) markExpiring(tracked *trackedLease, lease Lease) {
+	m.mu.Lock()
+	alreadyExpiring := tracked.expiring
+	tracked.lease = lease
+	tracked.expiring = true
+	tracked.nextCheck = lease.ExpiresAt()
+	m.mu.Unlock()
+
+	if !alreadyExpiring {
+		m.emit(tracked, LeaseEvent{Type: LeaseExpiring, Lease: lease})
+	}
+}
+
+// retryDelay returns the retry interval, shortened so retries happen before the lease expires
+func (m *LeaseManager) retryDelay(lease Lease, now time.Time) time.Duration {
+	if delay := lease.ExpiresAt().Sub(now) / 4; delay < m.retryInterval {
+		return max(delay, minLeaseRetryInterval)
+	}
+	return m.retryInterval
+}
+
+// emit delivers an event to the lease's handler and the manager-wide handlers
+func (m *LeaseManager) emit(tracked *trackedLease, event LeaseEvent) {
+	event.Timestamp = time.Now().UTC()
+	if tracked.handler != nil {
+		tracked.handler(event)
+	}
+	for _, handler := range m.eventHandlers {
+		handler(event)
+	}
+}
+
+// renewalTime returns when two thirds of the lease have elapsed
+func renewalTime(lease Lease) time.Time {
+	return lease.RenewedAt.Add(lease.Duration * 2 / 3)
+}
@@ -0,0 +1,182 @@
+package vaultwatcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// leaseEventRecorder collects lease events from the manager goroutine
+type leaseEventRecorder struct {
+	mu     sync.Mutex
+	events []LeaseEvent
+}
+
+func (r *leaseEventRecorder) handle(event LeaseEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *leaseEventRecorder) types() []LeaseEventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]LeaseEventType, len(r.events))
+	for i, event := range r.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func (r *leaseEventRecorder) has(eventType LeaseEventType) bool {
+	for _, t := range r.types() {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestLeaseManager(t *testing.T, vault *fakeDynamicVault, opts ...LeaseManagerOption) *LeaseManager {
+	manager, err := NewLeaseManager(&VaultConfig{Host: vault.server.URL, Token: "test-token"}, opts...)
+	if err != nil {
+		t.Fatalf("NewLeaseManager() error = %v", err)
+	}
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return manager
+}
+
+func TestNewLeaseManager(t *testing.T) {
+	_, err := NewLeaseManager(nil)
+	AssertError(t, err, "vault config cannot be nil", "NewLeaseManager(nil)")
+
+	_, err = NewLeaseManager(&VaultConfig{Host: "https://vault.example.com"})
+	AssertError(t, err, "VAULT_TOKEN is required", "NewLeaseManager(no token)")
+
+	manager, err := NewLeaseManager(&VaultConfig{Host: "https://vault.example.com", Token: "t"})
+	AssertNoError(t, err, "NewLeaseManager() without path")
+	AssertError(t, manager.Track(Lease{Duration: time.Second}, nil), "lease ID is required", "Track(no ID)")
+	AssertError(t, manager.Track(Lease{ID: "l1"}, nil), "lease l1 has no duration", "Track(no duration)")
+}
+
+func TestLeaseManager_RenewsLease(t *testing.T) {
+	vault := newFakeDynamicVault(true, 1)
+	defer vault.server.Close()
+
+	global := &leaseEventRecorder{}
+	manager := newTestLeaseManager(t, vault, WithLeaseEventHandler(global.handle))
+	defer manager.Stop()
+
+	recorder := &leaseEventRecorder{}
+	AssertNoError(t, manager.Track(Lease{ID: "lease-1", Duration: time.Second, Renewable: true}, recorder.handle), "Track()")
+
+	waitFor(t, 2*time.Second, func() bool { return len(recorder.types()) >= 2 }, "renewals")
+	for _, eventType := range recorder.types() {
+		if eventType != LeaseRenewed {
+			t.Errorf("event type = %s, want %s", eventType, LeaseRenewed)
+		}
+	}
+	if len(global.types()) < 2 {
+		t.Errorf("manager-wide handler received %d events, want at least 2", len(global.types()))
+	}
+	if len(manager.Leases()) != 1 {
+		t.Errorf("Leases() = %d, want 1", len(manager.Leases()))
+	}
+
+	manager.Untrack("lease-1")
+	if len(manager.Leases()) != 0 {
+		t.Errorf("Leases() = %d after Untrack, want 0", len(manager.Leases()))
+	}
+}
+
+func TestLeaseManager_NonRenewableLeaseExpires(t *testing.T) {
+	vault := newFakeDynamicVault(false, 0)
+	defer vault.server.Close()
+
+	manager := newTestLeaseManager(t, vault)
+	defer manager.Stop()
+
+	recorder := &leaseEventRecorder{}
+	AssertNoError(t, manager.Track(Lease{ID: "lease-1", Duration: time.Second}, recorder.handle), "Track()")
+
+	waitFor(t, 2*time.Second, func() bool { return recorder.has(LeaseExpired) }, "expiry")
+	types := recorder.types()
+	if len(types) != 2 || types[0] != LeaseExpiring || types[1] != LeaseExpired {
+		t.Errorf("events = %v, want [expiring expired]", types)
+	}
+	if len(manager.Leases()) != 0 {
+		t.Errorf("expired lease should no longer be tracked")
+	}
+	if _, renewals := vault.counts(); renewals != 0 {
+		t.Errorf("non-renewable lease was renewed %d times", renewals)
+	}
+}
+
+func TestLeaseManager_MaxTTLReached(t *testing.T) {
+	// Renewals are capped at 0s, far below a third of the issued TTL
+	vault := newFakeDynamicVault(true, 0)
+	defer vault.server.Close()
+
+	manager := newTestLeaseManager(t, vault)
+	defer manager.Stop()
+
+	recorder := &leaseEventRecorder{}
+	AssertNoError(t, manager.Track(Lease{ID: "lease-1", Duration: time.Second, Renewable: true}, recorder.handle), "Track()")
+
+	waitFor(t, 2*time.Second, func() bool { return recorder.has(LeaseExpiring) }, "expiring after capped renewal")
+}
+
+func TestLeaseManager_RenewalFailure(t *testing.T) {
+	vault := newFakeDynamicVault(true, 1)
+	vault.revoked = true
+	defer vault.server.Close()
+
+	manager := newTestLeaseManager(t, vault, WithLeaseRetryInterval(50*time.Millisecond))
+	defer manager.Stop()
+
+	recorder := &leaseEventRecorder{}
+	AssertNoError(t, manager.Track(Lease{ID: "lease-1", Duration: time.Second, Renewable: true}, recorder.handle), "Track()")
+
+	waitFor(t, 2*time.Second, func() bool { return recorder.has(LeaseExpired) }, "expiry after failed renewals")
+	types := recorder.types()
+	if types[0] != LeaseRenewalFailed {
+		t.Errorf("first event = %s, want %s", types[0], LeaseRenewalFailed)
+	}
+
+	recorder.mu.Lock()
+	if recorder.events[0].Error == nil {
+		t.Error("renewal failure event should carry the error")
+	}
+	recorder.mu.Unlock()
+}
+
+func TestDynamicSecretWatcher_SharedLeaseManager(t *testing.T) {
+	vault := newFakeDynamicVault(true, 1)
+	defer vault.server.Close()
+
+	manager := newTestLeaseManager(t, vault)
+	defer manager.Stop()
+
+	first, err := NewDynamicSecretWatcher(vault.config(), func(*DynamicSecret) error { return nil }, WithLeaseManager(manager))
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+	second, err := NewDynamicSecretWatcher(vault.config(), func(*DynamicSecret) error { return nil }, WithLeaseManager(manager))
+	if err != nil {
+		t.Fatalf("NewDynamicSecretWatcher() error = %v", err)
+	}
+
+	AssertNoError(t, first.Start(), "first.Start()")
+	AssertNoError(t, second.Start(), "second.Start()")
+	if len(manager.Leases()) != 2 {
+		t.Errorf("shared manager tracks %d leases, want 2", len(manager.Leases()))
+	}
+
+	first.Stop()
+	second.Stop()
+	if len(manager.Leases()) != 0 {
+		t.Errorf("shared manager tracks %d leases after Stop, want 0", len(manager.Leases()))
+	}
+}
@@ -0,0 +1,336 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// KeyFilter selects the secret keys a listener is interested in
+type KeyFilter struct {
+	kind     keyFilterKind
+	pattern  string
+	segments []pathSegment // Parsed pattern of a value path
+	err      error         // Why the pattern is invalid, reported by AddListener
+}
+
+type keyFilterKind int
+
+const (
+	keyFilterExact keyFilterKind = iota
+	keyFilterPrefix
+	keyFilterGlob
+	keyFilterPath
+)
+
+// ExactKey matches the key named key
+func ExactKey(key string) KeyFilter {
+	return KeyFilter{kind: keyFilterExact, pattern: key}
+}
+
+// KeyPrefix matches keys starting with prefix, e.g. "database_"
+func KeyPrefix(prefix string) KeyFilter {
+	return KeyFilter{kind: keyFilterPrefix, pattern: prefix}
+}
+
+// KeyGlob matches keys against a shell pattern as in path.Match, e.g.
+// "*_password" or "db?_url"
+func KeyGlob(pattern string) KeyFilter {
+	return KeyFilter{kind: keyFilterGlob, pattern: pattern}
+}
+
+// ValuePath matches changes to one nested value, e.g.
+// "database.credentials.password" or, in JSONPath form,
+// "$.database.credentials.password". Keys containing dots are quoted in
+// brackets, "$['tls.crt']", and list items are selected by index,
+// "hosts[0].name". The listener is only notified when that value changed,
+// not for changes elsewhere in the same top-level key.
+func ValuePath(path string) KeyFilter {
+	segments, err := parseValuePath(path)
+	return KeyFilter{kind: keyFilterPath, pattern: path, segments: segments, err: err}
+}
+
+// Match reports whether key is selected by the filter. A value path selects
+// the top-level key it starts with.
+func (f KeyFilter) Match(key string) bool {
+	switch f.kind {
+	case keyFilterPrefix:
+		return strings.HasPrefix(key, f.pattern)
+	case keyFilterGlob:
+		matched, _ := path.Match(f.pattern, key)
+		return matched
+	case keyFilterPath:
+		return len(f.segments) > 0 && f.segments[0].name == key
+	default:
+		return key == f.pattern
+	}
+}
+
+// String returns the filter as written, e.g. "prefix:database_"
+func (f KeyFilter) String() string {
+	switch f.kind {
+	case keyFilterPrefix:
+		return "prefix:" + f.pattern
+	case keyFilterGlob:
+		return "glob:" + f.pattern
+	case keyFilterPath:
+		return "path:" + f.pattern
+	default:
+		return "exact:" + f.pattern
+	}
+}
+
+// validateKeyFilters returns why a filter is invalid, if one is
+func validateKeyFilters(filters []KeyFilter) error {
+	for _, filter := range filters {
+		if filter.err != nil {
+			return filter.err
+		}
+		if filter.kind != keyFilterGlob {
+			continue
+		}
+		if _, err := path.Match(filter.pattern, ""); err != nil {
+			return fmt.Errorf("invalid key pattern %q: %w", filter.pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateHashing checks the options deciding what is hashed and how
+func (w *Watcher) validateHashing() error {
+	if _, err := w.hashAlgorithm.newHash(); err != nil {
+		return err
+	}
+	if w.hashAlgorithm != "" && w.hmacKeyPath != "" {
+		return fmt.Errorf("WithHashAlgorithm can't be combined with WithTransitHMAC")
+	}
+	if len(w.binaryKeys) > 0 && w.hmacKeyPath != "" {
+		return fmt.Errorf("WithBinaryKeys can't be combined with WithTransitHMAC")
+	}
+	if err := validateKeyFilters(w.binaryKeys); err != nil {
+		return err
+	}
+	return validateKeyFilters(w.keyFilters)
+}
+
+// watchedData returns the keys of data selected by WithKeyFilter, or all of
+// data without filters
+func (w *Watcher) watchedData(data map[string]interface{}) map[string]interface{} {
+	if len(w.keyFilters) == 0 || data == nil {
+		return data
+	}
+	watched := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		for _, filter := range w.keyFilters {
+			if filter.Match(key) {
+				watched[key] = value
+				break
+			}
+		}
+	}
+	return watched
+}
+
+// ListenerID identifies a listener added with AddListener
+type ListenerID uint64
+
+// changeListener is a notifier limited to some keys
+type changeListener struct {
+	notifier Notifier
+	filters  []KeyFilter
+}
+
+// AddListener registers a notifier for the changes to keys matching any of
+// filters, or every change without filters. Listeners can be added and
+// removed while the watcher runs, e.g. by plugins loaded later. Like
+// notifiers, they run after onChange succeeds and their errors are logged.
+// The event they receive lists only the matching keys in ChangedKeys and
+// Patch.
+func (w *Watcher) AddListener(listener Notifier, filters ...KeyFilter) (ListenerID, error) {
+	if listener == nil {
+		return 0, fmt.Errorf("listener cannot be nil")
+	}
+	if err := validateKeyFilters(filters); err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.listeners == nil {
+		w.listeners = map[ListenerID]changeListener{}
+	}
+	w.nextListenerID++
+	id := w.nextListenerID
+	w.listeners[id] = changeListener{notifier: listener, filters: append([]KeyFilter(nil), filters...)}
+	return id, nil
+}
+
+// RemoveListener unregisters a listener and reports whether it was registered
+func (w *Watcher) RemoveListener(id ListenerID) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.listeners[id]; !ok {
+		return false
+	}
+	delete(w.listeners, id)
+	return true
+}
+
+// notifyListeners passes event to every listener interested in its keys, in
+// the order they were added. valuePaths tells which value paths changed, by
+// JSON Pointer; paths missing from it are assumed changed with their
+// top-level key.
+func (w *Watcher) notifyListeners(event ChangeEvent, valuePaths map[string]bool) {
+	w.mu.RLock()
+	ids := make([]ListenerID, 0, len(w.listeners))
+	listeners := make(map[ListenerID]changeListener, len(w.listeners))
+	for id, listener := range w.listeners {
+		ids = append(ids, id)
+		listeners[id] = listener
+	}
+	w.mu.RUnlock()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		listener := listeners[id]
+		filtered, ok := listener.filter(event, valuePaths)
+		if !ok {
+			continue
+		}
+		if err := listener.notifier.Notify(w.ctx, filtered); err != nil {
+			w.logger.Error("Error notifying listener of vault change", "error", w.redactError(err))
+		}
+	}
+}
+
+// filter returns event limited to the keys the listener is interested in,
+// and false when none of them changed
+func (l changeListener) filter(event ChangeEvent, valuePaths map[string]bool) (ChangeEvent, bool) {
+	if len(l.filters) == 0 {
+		return event, true
+	}
+
+	var keys []string
+	for _, key := range event.ChangedKeys {
+		if l.matchesKey(key, valuePaths) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return event, false
+	}
+	event.ChangedKeys = keys
+
+	if event.Patch != nil {
+		patch := []PatchOperation{}
+		for _, op := range event.Patch {
+			if l.matchesPatch(op.Path) {
+				patch = append(patch, op)
+			}
+		}
+		event.Patch = patch
+	}
+	return event, true
+}
+
+// matchesKey reports whether any filter selects a changed top-level key. A
+// value path selects it only if the value changed, or might have.
+func (l changeListener) matchesKey(key string, valuePaths map[string]bool) bool {
+	for _, filter := range l.filters {
+		if !filter.Match(key) {
+			continue
+		}
+		if filter.kind != keyFilterPath {
+			return true
+		}
+		if changed, known := valuePaths[pathPointer(filter.segments)]; !known || changed {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPatch reports whether a JSON Patch operation touches a selected
+// key, or a selected value, its parents or children
+func (l changeListener) matchesPatch(pointer string) bool {
+	for _, filter := range l.filters {
+		if filter.kind != keyFilterPath {
+			if filter.Match(patchKey(pointer)) {
+				return true
+			}
+			continue
+		}
+		selected := pathPointer(filter.segments)
+		if pointer == selected || strings.HasPrefix(pointer, selected+"/") || strings.HasPrefix(selected, pointer+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePaths returns the value paths the listeners select, parsed
+func (w *Watcher) valuePaths() map[string][]pathSegment {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var paths map[string][]pathSegment
+	for _, listener := range w.listeners {
+		for _, filter := range listener.filters {
+			if filter.kind != keyFilterPath {
+				continue
+			}
+			if paths == nil {
+				paths = map[string][]pathSegment{}
+			}
+			paths[pathPointer(filter.segments)] = filter.segments
+		}
+	}
+	return paths
+}
+
+// trackValuePaths hashes the values selected by listeners in data, the data
+// being applied, and reports which of them changed since the last applied
+// data. Only hashes are kept. Paths added since then are left out, so their
+// listeners fall back to the top-level key.
+func (w *Watcher) trackValuePaths(data map[string]interface{}) map[string]bool {
+	paths := w.valuePaths()
+	hashes := make(map[string]string, len(paths))
+	for pointer, segments := range paths {
+		value, ok := selectPath(data, segments)
+		if !ok {
+			hashes[pointer] = ""
+			continue
+		}
+		hash, err := CalculateHash(map[string]interface{}{"value": value})
+		if err != nil {
+			// Unknown, so listeners fall back to the top-level key
+			continue
+		}
+		hashes[pointer] = hash
+	}
+
+	w.mu.Lock()
+	previous := w.valuePathHashes
+	w.valuePathHashes = hashes
+	w.mu.Unlock()
+
+	changed := make(map[string]bool, len(hashes))
+	for pointer, hash := range hashes {
+		if previousHash, known := previous[pointer]; known {
+			changed[pointer] = hash != previousHash
+		}
+	}
+	return changed
+}
+
+// patchKey returns the top-level key a JSON Patch path points into
+func patchKey(pointer string) string {
+	key := strings.TrimPrefix(pointer, "/")
+	if i := strings.Index(key, "/"); i >= 0 {
+		key = key[:i]
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+}
@@ -0,0 +1,197 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestKeyFilter_Match(t *testing.T) {
+	tests := []struct {
+		filter KeyFilter
+		key    string
+		want   bool
+	}{
+		{filter: ExactKey("db_password"), key: "db_password", want: true},
+		{filter: ExactKey("db_password"), key: "db_password_old", want: false},
+		{filter: KeyPrefix("db_"), key: "db_password", want: true},
+		{filter: KeyPrefix("db_"), key: "api_key", want: false},
+		{filter: KeyGlob("*_password"), key: "db_password", want: true},
+		{filter: KeyGlob("*_password"), key: "db_user", want: false},
+		{filter: KeyGlob("db?_url"), key: "db2_url", want: true},
+		{filter: KeyGlob("[a-c]*"), key: "database", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter.String()+"/"+tt.key, func(t *testing.T) {
+			AssertBoolEquals(t, tt.filter.Match(tt.key), tt.want, "Match()")
+		})
+	}
+}
+
+func TestChangeListener_Filter(t *testing.T) {
+	event := ChangeEvent{
+		ChangedKeys: []string{"api_key", "db_password", "db_user"},
+		Patch: []PatchOperation{
+			{Op: "replace", Path: "/api_key", Value: "a"},
+			{Op: "replace", Path: "/db_password", Value: "b"},
+			{Op: "add", Path: "/db_user/name", Value: "c"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		filters   []KeyFilter
+		wantOK    bool
+		wantKeys  string
+		wantPatch string
+	}{
+		{name: "no filters", wantOK: true, wantKeys: "[api_key db_password db_user]", wantPatch: "/api_key /db_password /db_user/name"},
+		{name: "prefix", filters: []KeyFilter{KeyPrefix("db_")}, wantOK: true, wantKeys: "[db_password db_user]", wantPatch: "/db_password /db_user/name"},
+		{name: "any filter", filters: []KeyFilter{ExactKey("api_key"), KeyGlob("*_user")}, wantOK: true, wantKeys: "[api_key db_user]", wantPatch: "/api_key /db_user/name"},
+		{name: "no match", filters: []KeyFilter{ExactKey("tls_cert")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, ok := changeListener{filters: tt.filters}.filter(event, nil)
+			AssertBoolEquals(t, ok, tt.wantOK, "filter() ok")
+			if !ok {
+				return
+			}
+			AssertStringEquals(t, fmt.Sprint(filtered.ChangedKeys), tt.wantKeys, "ChangedKeys")
+			var paths string
+			for i, op := range filtered.Patch {
+				if i > 0 {
+					paths += " "
+				}
+				paths += op.Path
+			}
+			AssertStringEquals(t, paths, tt.wantPatch, "Patch paths")
+		})
+	}
+	AssertStringEquals(t, fmt.Sprint(event.ChangedKeys), "[api_key db_password db_user]", "original ChangedKeys")
+}
+
+func TestWatcher_AddListener_Errors(t *testing.T) {
+	watcher, err := NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	_, err = watcher.AddListener(nil)
+	AssertError(t, err, "listener cannot be nil", "AddListener(nil)")
+	_, err = watcher.AddListener(NotifierFunc(func(context.Context, ChangeEvent) error { return nil }), KeyGlob("[db"))
+	AssertError(t, err, `invalid key pattern "[db": syntax error in pattern`, "AddListener(bad glob)")
+	AssertBoolEquals(t, watcher.RemoveListener(42), false, "RemoveListener(unknown)")
+}
+
+func TestWatcher_Listeners(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"db_password": "one", "api_key": "one"})
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	// Added while running
+	database := make(chan ChangeEvent, 4)
+	all := make(chan ChangeEvent, 4)
+	databaseID, err := watcher.AddListener(listenerTo(database), KeyPrefix("db_"))
+	AssertNoError(t, err, "AddListener(database)")
+	_, err = watcher.AddListener(listenerTo(all))
+	AssertNoError(t, err, "AddListener(all)")
+
+	source.set(map[string]interface{}{"db_password": "one", "api_key": "two"})
+	select {
+	case event := <-all:
+		AssertStringEquals(t, fmt.Sprint(event.ChangedKeys), "[api_key]", "ChangedKeys of the unfiltered listener")
+	case <-time.After(time.Second):
+		t.Fatal("unfiltered listener was not notified")
+	}
+	select {
+	case event := <-database:
+		t.Fatalf("database listener notified of %v", event.ChangedKeys)
+	default:
+	}
+
+	source.set(map[string]interface{}{"db_password": "two", "api_key": "three"})
+	select {
+	case event := <-database:
+		AssertStringEquals(t, fmt.Sprint(event.ChangedKeys), "[db_password]", "ChangedKeys of the database listener")
+	case <-time.After(time.Second):
+		t.Fatal("database listener was not notified")
+	}
+	<-all
+
+	AssertBoolEquals(t, watcher.RemoveListener(databaseID), true, "RemoveListener()")
+	source.set(map[string]interface{}{"db_password": "three", "api_key": "three"})
+	select {
+	case <-all:
+	case <-time.After(time.Second):
+		t.Fatal("unfiltered listener was not notified")
+	}
+	select {
+	case event := <-database:
+		t.Fatalf("removed listener notified of %v", event.ChangedKeys)
+	default:
+	}
+}
+
+// listenerTo returns a listener sending events to events
+func listenerTo(events chan ChangeEvent) Notifier {
+	return NotifierFunc(func(ctx context.Context, event ChangeEvent) error {
+		events <- event
+		return nil
+	})
+}
+
+// receiveEvent waits for an event on events
+func receiveEvent(t *testing.T, events chan ChangeEvent, what string) ChangeEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("%s: listener was not notified", what)
+	}
+	return ChangeEvent{}
+}
+
+func TestWatcher_KeyFilter(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"db_host": "db-1", "db_password": "one", "log_level": "info"})
+
+	changes := 0
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		changes++
+		return nil
+	}, WithKeyFilter(KeyPrefix("db_")))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	tests := []struct {
+		name        string
+		data        map[string]interface{}
+		wantChanges int
+		wantKeys    string
+	}{
+		{name: "unwatched key", data: map[string]interface{}{"db_host": "db-1", "db_password": "one", "log_level": "debug"}},
+		{name: "watched key", data: map[string]interface{}{"db_host": "db-1", "db_password": "two", "log_level": "info"},
+			wantChanges: 1, wantKeys: "[db_password]"},
+		{name: "both", data: map[string]interface{}{"db_host": "db-2", "db_password": "two", "log_level": "warn"},
+			wantChanges: 2, wantKeys: "[db_host]"},
+	}
+
+	for _, tt := range tests {
+		source.set(tt.data)
+		AssertNoError(t, watcher.checkForChanges(), tt.name)
+		AssertStringEquals(t, fmt.Sprint(changes), fmt.Sprint(tt.wantChanges), tt.name+": changes")
+		if tt.wantKeys != "" {
+			AssertStringEquals(t, fmt.Sprint(watcher.lastChange.ChangedKeys), tt.wantKeys, tt.name+": ChangedKeys")
+		}
+	}
+
+	_, err = NewSourceWatcher("app", source, time.Hour, func() error { return nil }, WithKeyFilter(KeyGlob("[")))
+	AssertError(t, err, `invalid key pattern "[": syntax error in pattern`, "NewSourceWatcher() with an invalid filter")
+}
@@ -0,0 +1,503 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileLock is a Lock backed by a file, for replicas on one host or sharing a
+// volume. Acquiring and releasing hold an flock on path+".flock", so two
+// instances racing for an expired lock can't both lead. Where flock is
+// unavailable, and on network file systems that don't honour it, it is best
+// effort.
+type FileLock struct {
+	path string
+}
+
+// fileLockRetryInterval is how often Release retries while another instance
+// holds the flock
+const fileLockRetryInterval = 10 * time.Millisecond
+
+// fileLockRecord is the content of a FileLock file
+type fileLockRecord struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewFileLock creates a lock stored at path
+func NewFileLock(path string) (*FileLock, error) {
+	if path == "" {
+		return nil, fmt.Errorf("lock file path is required")
+	}
+	return &FileLock{path: path}, nil
+}
+
+// TryAcquire takes the lock if it is free, expired or already held by id. It
+// returns false without waiting while another instance is acquiring or
+// releasing the lock.
+func (l *FileLock) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	var acquired bool
+	err := l.locked(func() error {
+		record, err := l.read()
+		if err != nil {
+			return err
+		}
+		if record.Holder != "" && record.Holder != id && time.Now().Before(record.Expires) {
+			return nil
+		}
+		if err := l.write(fileLockRecord{Holder: id, Expires: time.Now().Add(ttl)}); err != nil {
+			return err
+		}
+		acquired = true
+		return nil
+	})
+	if errors.Is(err, errFileLocked) {
+		return false, nil
+	}
+	return acquired, err
+}
+
+// Release removes the lock file if id holds the lock
+func (l *FileLock) Release(ctx context.Context, id string) error {
+	for {
+		err := l.locked(func() error {
+			record, err := l.read()
+			if err != nil {
+				return err
+			}
+			if record.Holder != id {
+				return nil
+			}
+			if err := os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove lock file: %w", err)
+			}
+			return nil
+		})
+		if !errors.Is(err, errFileLocked) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to release lock file: %w", ctx.Err())
+		case <-time.After(fileLockRetryInterval):
+		}
+	}
+}
+
+// locked runs fn while holding the flock of the lock file. It returns
+// errFileLocked if another instance holds it.
+func (l *FileLock) locked(fn func() error) error {
+	file, err := os.OpenFile(l.path+".flock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer file.Close()
+	if err := lockFile(file); err != nil {
+		if errors.Is(err, errFileLocked) {
+			return err
+		}
+		return fmt.Errorf("failed to lock %s: %w", file.Name(), err)
+	}
+	return fn()
+}
+
+func (l *FileLock) read() (fileLockRecord, error) {
+	var record fileLockRecord
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return record, nil
+	}
+	if err != nil {
+		return record, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		// A corrupt lock is treated as free
+		return fileLockRecord{}, nil
+	}
+	return record, nil
+}
+
+// write replaces the lock file atomically
+func (l *FileLock) write(record fileLockRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock record: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// ConsulLockConfig holds the configuration for a ConsulLock
+type ConsulLockConfig struct {
+	Address    string       // Consul HTTP address (default "http://127.0.0.1:8500")
+	Key        string       // KV key used as the lock, e.g. "locks/vault-watcher/myapp"
+	Token      string       // Optional ACL token
+	HTTPClient *http.Client // Optional custom HTTP client
+}
+
+// ConsulLock is a Lock using a Consul session and KV acquire
+type ConsulLock struct {
+	config ConsulLockConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	session string
+}
+
+// NewConsulLock creates a lock on a Consul KV key
+func NewConsulLock(config ConsulLockConfig) (*ConsulLock, error) {
+	if config.Key == "" {
+		return nil, fmt.Errorf("consul lock key is required")
+	}
+	if config.Address == "" {
+		config.Address = "http://127.0.0.1:8500"
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &ConsulLock{config: config, client: client}, nil
+}
+
+// TryAcquire renews the session, creating it if needed, and acquires the key with it
+func (l *ConsulLock) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.session != "" {
+		status, err := l.do(ctx, http.MethodPut, "/v1/session/renew/"+l.session, nil, nil)
+		if err != nil && status != http.StatusNotFound {
+			return false, fmt.Errorf("failed to renew consul session: %w", err)
+		}
+		if status == http.StatusNotFound {
+			// The session expired; the key was released with it
+			l.session = ""
+		}
+	}
+
+	if l.session == "" {
+		// Consul rejects session TTLs below 10s
+		ttl = max(ttl, 10*time.Second)
+		var created struct {
+			ID string `json:"ID"`
+		}
+		body := map[string]string{"Name": "vault-watcher " + id, "TTL": ttl.String(), "Behavior": "release", "LockDelay": "0s"}
+		if _, err := l.do(ctx, http.MethodPut, "/v1/session/create", body, &created); err != nil {
+			return false, fmt.Errorf("failed to create consul session: %w", err)
+		}
+		l.session = created.ID
+	}
+
+	var acquired bool
+	path := fmt.Sprintf("/v1/kv/%s?acquire=%s", l.config.Key, url.QueryEscape(l.session))
+	if _, err := l.do(ctx, http.MethodPut, path, map[string]string{"holder": id}, &acquired); err != nil {
+		return false, fmt.Errorf("failed to acquire consul lock: %w", err)
+	}
+	return acquired, nil
+}
+
+// Release releases the key and destroys the session
+func (l *ConsulLock) Release(ctx context.Context, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.session == "" {
+		return nil
+	}
+
+	path := fmt.Sprintf("/v1/kv/%s?release=%s", l.config.Key, url.QueryEscape(l.session))
+	if _, err := l.do(ctx, http.MethodPut, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to release consul lock: %w", err)
+	}
+	if _, err := l.do(ctx, http.MethodPut, "/v1/session/destroy/"+l.session, nil, nil); err != nil {
+		return fmt.Errorf("failed to destroy consul session: %w", err)
+	}
+	l.session = ""
+	return nil
+}
+
+// do sends a request to the Consul HTTP API and decodes the response into out
+func (l *ConsulLock) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	return doJSON(ctx, l.client, method, l.config.Address+path, body, out, func(req *http.Request) {
+		if l.config.Token != "" {
+			req.Header.Set("X-Consul-Token", l.config.Token)
+		}
+	})
+}
+
+const (
+	serviceAccountDir      = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubernetesMicroTimeFmt = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// KubernetesLeaseLockConfig holds the configuration for a KubernetesLeaseLock
+type KubernetesLeaseLockConfig struct {
+	Name       string       // Name of the coordination.k8s.io Lease
+	Namespace  string       // Namespace of the Lease (default: the pod's namespace)
+	APIServer  string       // API server URL (default "https://kubernetes.default.svc")
+	Token      string       // Bearer token (default: the pod's service account token)
+	HTTPClient *http.Client // Optional custom HTTP client (default trusts the service account CA)
+}
+
+// KubernetesLeaseLock is a Lock using a coordination.k8s.io/v1 Lease, the same
+// mechanism used by Kubernetes controllers. The service account needs get,
+// create and update on leases.
+type KubernetesLeaseLock struct {
+	config KubernetesLeaseLockConfig
+	client *http.Client
+}
+
+// kubernetesLease is the subset of a Lease object used for locking
+type kubernetesLease struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		HolderIdentity       *string `json:"holderIdentity,omitempty"`
+		LeaseDurationSeconds *int    `json:"leaseDurationSeconds,omitempty"`
+		AcquireTime          *string `json:"acquireTime,omitempty"`
+		RenewTime            *string `json:"renewTime,omitempty"`
+		LeaseTransitions     int     `json:"leaseTransitions"`
+	} `json:"spec"`
+}
+
+// NewKubernetesLeaseLock creates a lock on a Kubernetes Lease. Defaults are
+// taken from the pod's service account when running in a cluster.
+func NewKubernetesLeaseLock(config KubernetesLeaseLockConfig) (*KubernetesLeaseLock, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("lease name is required")
+	}
+	if config.Namespace == "" {
+		namespace, err := serviceAccountFile("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("lease namespace is required outside a cluster: %w", err)
+		}
+		config.Namespace = namespace
+	}
+	var client *http.Client
+	var err error
+	config.APIServer, config.Token, client, err = kubernetesClient(config.APIServer, config.Token, config.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	return &KubernetesLeaseLock{config: config, client: client}, nil
+}
+
+// serviceAccountFile reads a file of the pod's service account, e.g. "namespace"
+func serviceAccountFile(name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(serviceAccountDir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// kubernetesClient fills in the API server, token and HTTP client of a
+// Kubernetes API client, defaulting to the pod's service account
+func kubernetesClient(apiServer, token string, client *http.Client) (string, string, *http.Client, error) {
+	if apiServer == "" {
+		apiServer = "https://kubernetes.default.svc"
+	}
+	apiServer = strings.TrimSuffix(apiServer, "/")
+	if token == "" {
+		var err error
+		if token, err = serviceAccountFile("token"); err != nil {
+			return "", "", nil, fmt.Errorf("token is required outside a cluster: %w", err)
+		}
+	}
+
+	if client == nil {
+		ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to read service account CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		client = &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		}
+	}
+	return apiServer, token, client, nil
+}
+
+// TryAcquire creates or updates the Lease when it is free, expired or already held by id.
+// Concurrent updates are resolved by the API server through the resource version.
+func (l *KubernetesLeaseLock) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	var lease kubernetesLease
+	status, err := l.do(ctx, http.MethodGet, l.leaseURL(), nil, &lease)
+	if err != nil && status != http.StatusNotFound {
+		return false, fmt.Errorf("failed to get lease: %w", err)
+	}
+
+	now := time.Now()
+	nowStr := now.UTC().Format(kubernetesMicroTimeFmt)
+	seconds := max(int(ttl.Seconds()), 1)
+
+	if status == http.StatusNotFound {
+		lease.APIVersion, lease.Kind = "coordination.k8s.io/v1", "Lease"
+		lease.Metadata.Name, lease.Metadata.Namespace = l.config.Name, l.config.Namespace
+		lease.Spec.HolderIdentity = &id
+		lease.Spec.LeaseDurationSeconds = &seconds
+		lease.Spec.AcquireTime, lease.Spec.RenewTime = &nowStr, &nowStr
+
+		status, err = l.do(ctx, http.MethodPost, l.leasesURL(), lease, nil)
+		if status == http.StatusConflict {
+			// Created by another instance
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to create lease: %w", err)
+		}
+		return true, nil
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	if holder != "" && holder != id && !leaseExpired(lease, now) {
+		return false, nil
+	}
+
+	if holder != id {
+		lease.Spec.AcquireTime = &nowStr
+		lease.Spec.LeaseTransitions++
+	}
+	lease.Spec.HolderIdentity = &id
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &nowStr
+
+	status, err = l.do(ctx, http.MethodPut, l.leaseURL(), lease, nil)
+	if status == http.StatusConflict {
+		// Updated by another instance since we read it
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update lease: %w", err)
+	}
+	return true, nil
+}
+
+// Release clears the holder of the Lease if id holds it
+func (l *KubernetesLeaseLock) Release(ctx context.Context, id string) error {
+	var lease kubernetesLease
+	status, err := l.do(ctx, http.MethodGet, l.leaseURL(), nil, &lease)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get lease: %w", err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != id {
+		return nil
+	}
+
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.RenewTime = nil
+	status, err = l.do(ctx, http.MethodPut, l.leaseURL(), lease, nil)
+	if err != nil && status != http.StatusConflict {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+func (l *KubernetesLeaseLock) leasesURL() string {
+	return fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", l.config.APIServer, url.PathEscape(l.config.Namespace))
+}
+
+func (l *KubernetesLeaseLock) leaseURL() string {
+	return l.leasesURL() + "/" + url.PathEscape(l.config.Name)
+}
+
+func (l *KubernetesLeaseLock) do(ctx context.Context, method, requestURL string, body, out interface{}) (int, error) {
+	return doJSON(ctx, l.client, method, requestURL, body, out, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+l.config.Token)
+	})
+}
+
+// leaseExpired reports whether the holder of a Lease failed to renew it in time
+func leaseExpired(lease kubernetesLease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	renewed, err := time.Parse(kubernetesMicroTimeFmt, *lease.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// doJSON sends a JSON request and decodes a JSON response into out. The status
+// code is returned with the error for non-2xx responses.
+func doJSON(ctx context.Context, client *http.Client, method, requestURL string, body, out interface{}, prepare func(*http.Request)) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	prepare(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,225 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileLock(t *testing.T) {
+	ctx := context.Background()
+	lock, err := NewFileLock(filepath.Join(t.TempDir(), "leader.lock"))
+	AssertNoError(t, err, "NewFileLock()")
+
+	acquired, err := lock.TryAcquire(ctx, "a", 100*time.Millisecond)
+	AssertNoError(t, err, "TryAcquire(a)")
+	AssertBoolEquals(t, acquired, true, "a acquires free lock")
+
+	acquired, _ = lock.TryAcquire(ctx, "b", 100*time.Millisecond)
+	AssertBoolEquals(t, acquired, false, "b while a holds the lock")
+
+	acquired, _ = lock.TryAcquire(ctx, "a", 100*time.Millisecond)
+	AssertBoolEquals(t, acquired, true, "a renews")
+
+	time.Sleep(150 * time.Millisecond)
+	acquired, _ = lock.TryAcquire(ctx, "b", time.Second)
+	AssertBoolEquals(t, acquired, true, "b takes expired lock")
+
+	AssertNoError(t, lock.Release(ctx, "a"), "Release(a) by non-holder")
+	acquired, _ = lock.TryAcquire(ctx, "a", time.Second)
+	AssertBoolEquals(t, acquired, false, "a after non-holder release")
+
+	AssertNoError(t, lock.Release(ctx, "b"), "Release(b)")
+	acquired, _ = lock.TryAcquire(ctx, "a", time.Second)
+	AssertBoolEquals(t, acquired, true, "a after release")
+}
+
+// fakeConsul implements the session and KV acquire endpoints used by ConsulLock
+type fakeConsul struct {
+	mu       sync.Mutex
+	sessions map[string]bool
+	holder   string
+	nextID   int
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		f.nextID++
+		id := fmt.Sprintf("session-%d", f.nextID)
+		f.sessions[id] = true
+		json.NewEncoder(w).Encode(map[string]string{"ID": id})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		if !f.sessions[strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("[]"))
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		delete(f.sessions, strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/"))
+		w.Write([]byte("true"))
+	case r.URL.Path == "/v1/kv/locks/app":
+		if session := r.URL.Query().Get("acquire"); session != "" {
+			if f.holder == "" || f.holder == session {
+				f.holder = session
+				w.Write([]byte("true"))
+				return
+			}
+			w.Write([]byte("false"))
+			return
+		}
+		if session := r.URL.Query().Get("release"); session == f.holder {
+			f.holder = ""
+		}
+		w.Write([]byte("true"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// expire drops every session, releasing the key like Consul does on session invalidation
+func (f *fakeConsul) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions = map[string]bool{}
+	f.holder = ""
+}
+
+func TestConsulLock(t *testing.T) {
+	ctx := context.Background()
+	consul := &fakeConsul{sessions: map[string]bool{}}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	_, err := NewConsulLock(ConsulLockConfig{Address: server.URL})
+	AssertError(t, err, "consul lock key is required", "NewConsulLock(no key)")
+
+	a, _ := NewConsulLock(ConsulLockConfig{Address: server.URL, Key: "locks/app"})
+	b, _ := NewConsulLock(ConsulLockConfig{Address: server.URL, Key: "locks/app"})
+
+	acquired, err := a.TryAcquire(ctx, "a", 15*time.Second)
+	AssertNoError(t, err, "TryAcquire(a)")
+	AssertBoolEquals(t, acquired, true, "a acquires")
+
+	acquired, err = b.TryAcquire(ctx, "b", 15*time.Second)
+	AssertNoError(t, err, "TryAcquire(b)")
+	AssertBoolEquals(t, acquired, false, "b while a holds the lock")
+
+	acquired, _ = a.TryAcquire(ctx, "a", 15*time.Second)
+	AssertBoolEquals(t, acquired, true, "a renews")
+
+	// An expired session is recreated
+	consul.expire()
+	acquired, err = b.TryAcquire(ctx, "b", 15*time.Second)
+	AssertNoError(t, err, "TryAcquire(b) after expiry")
+	AssertBoolEquals(t, acquired, true, "b after a's session expired")
+
+	AssertNoError(t, b.Release(ctx, "b"), "Release(b)")
+	acquired, _ = a.TryAcquire(ctx, "a", 15*time.Second)
+	AssertBoolEquals(t, acquired, true, "a after release")
+}
+
+// fakeLeaseAPI implements the Lease endpoints used by KubernetesLeaseLock,
+// including optimistic concurrency through resourceVersion
+type fakeLeaseAPI struct {
+	mu      sync.Mutex
+	lease   *kubernetesLease
+	version int
+}
+
+func (f *fakeLeaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer sa-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const leases = "/apis/coordination.k8s.io/v1/namespaces/apps/leases"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == leases+"/vault-watcher":
+		if f.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(f.lease)
+	case r.Method == http.MethodPost && r.URL.Path == leases:
+		if f.lease != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.store(w, r)
+	case r.Method == http.MethodPut && r.URL.Path == leases+"/vault-watcher":
+		var update kubernetesLease
+		json.NewDecoder(r.Body).Decode(&update)
+		if update.Metadata.ResourceVersion != strconv.Itoa(f.version) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.lease = &update
+		f.version++
+		f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		json.NewEncoder(w).Encode(f.lease)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeLeaseAPI) store(w http.ResponseWriter, r *http.Request) {
+	var lease kubernetesLease
+	json.NewDecoder(r.Body).Decode(&lease)
+	f.version++
+	lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+	f.lease = &lease
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(f.lease)
+}
+
+func TestKubernetesLeaseLock(t *testing.T) {
+	ctx := context.Background()
+	api := &fakeLeaseAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	config := KubernetesLeaseLockConfig{Name: "vault-watcher", Namespace: "apps", APIServer: server.URL, Token: "sa-token", HTTPClient: server.Client()}
+	lock, err := NewKubernetesLeaseLock(config)
+	AssertNoError(t, err, "NewKubernetesLeaseLock()")
+
+	acquired, err := lock.TryAcquire(ctx, "pod-a", time.Second)
+	AssertNoError(t, err, "TryAcquire(pod-a)")
+	AssertBoolEquals(t, acquired, true, "pod-a creates the lease")
+
+	acquired, err = lock.TryAcquire(ctx, "pod-b", time.Second)
+	AssertNoError(t, err, "TryAcquire(pod-b)")
+	AssertBoolEquals(t, acquired, false, "pod-b while pod-a holds the lease")
+
+	acquired, _ = lock.TryAcquire(ctx, "pod-a", time.Second)
+	AssertBoolEquals(t, acquired, true, "pod-a renews")
+	if api.lease.Spec.LeaseTransitions != 0 {
+		t.Errorf("LeaseTransitions = %d after renewal, want 0", api.lease.Spec.LeaseTransitions)
+	}
+
+	AssertNoError(t, lock.Release(ctx, "pod-a"), "Release(pod-a)")
+	acquired, _ = lock.TryAcquire(ctx, "pod-b", time.Second)
+	AssertBoolEquals(t, acquired, true, "pod-b after release")
+	AssertStringEquals(t, *api.lease.Spec.HolderIdentity, "pod-b", "holder")
+	if api.lease.Spec.LeaseTransitions != 1 {
+		t.Errorf("LeaseTransitions = %d, want 1", api.lease.Spec.LeaseTransitions)
+	}
+
+	if _, err := NewKubernetesLeaseLock(KubernetesLeaseLockConfig{}); err == nil {
+		t.Error("NewKubernetesLeaseLock() expected error without a lease name")
+	}
+}
@@ -0,0 +1,41 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package vaultwatcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLock_Flock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	lock, err := NewFileLock(path)
+	AssertNoError(t, err, "NewFileLock()")
+
+	// Another instance in the middle of acquiring holds the flock
+	other, err := os.OpenFile(path+".flock", os.O_CREATE|os.O_RDWR, 0o600)
+	AssertNoError(t, err, "OpenFile()")
+	AssertNoError(t, lockFile(other), "lockFile()")
+
+	acquired, err := lock.TryAcquire(ctx, "a", time.Second)
+	AssertNoError(t, err, "TryAcquire(a) while flocked")
+	AssertBoolEquals(t, acquired, false, "a while another instance holds the flock")
+
+	other.Close()
+	acquired, err = lock.TryAcquire(ctx, "a", time.Second)
+	AssertNoError(t, err, "TryAcquire(a)")
+	AssertBoolEquals(t, acquired, true, "a after the flock is released")
+
+	other, err = os.OpenFile(path+".flock", os.O_RDWR, 0o600)
+	AssertNoError(t, err, "OpenFile()")
+	AssertNoError(t, lockFile(other), "lockFile()")
+	time.AfterFunc(50*time.Millisecond, func() { other.Close() })
+	AssertNoError(t, lock.Release(ctx, "a"), "Release(a) waits for the flock")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file after Release() error = %v, want it removed", err)
+	}
+}
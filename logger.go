@@ -0,0 +1,68 @@
+package vaultwatcher
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger sends the watcher's log messages to logger instead of
+// slog.Default(). Errors the watcher recovers from, such as a failed check or
+// notifier, are logged at Error; sealed Vaults, failovers and expiring tokens
+// at Warn; and other state changes at Info.
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *Watcher) {
+		if logger != nil {
+			w.logger = logger
+		}
+	}
+}
+
+// WithGroupLogger sends the log messages of the group and its paths to logger
+// instead of slog.Default(), see WithLogger
+func WithGroupLogger(logger *slog.Logger) GroupOption {
+	return func(g *WatcherGroup) {
+		if logger != nil {
+			g.logger = logger
+		}
+	}
+}
+
+// WithDynamicLogger sends the watcher's log messages to logger instead of
+// slog.Default(), see WithLogger
+func WithDynamicLogger(logger *slog.Logger) DynamicSecretOption {
+	return func(w *DynamicSecretWatcher) {
+		if logger != nil {
+			w.logger = logger
+		}
+	}
+}
+
+// leveledHandler drops records below a level that can change at runtime, on
+// top of whatever level its handler has
+type leveledHandler struct {
+	handler slog.Handler
+	level   *slog.LevelVar
+}
+
+func (h *leveledHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.handler.Enabled(ctx, level)
+}
+
+func (h *leveledHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *leveledHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &leveledHandler{handler: h.handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *leveledHandler) WithGroup(name string) slog.Handler {
+	return &leveledHandler{handler: h.handler.WithGroup(name), level: h.level}
+}
+
+// SetLogLevel drops log messages of the group and its paths below level.
+// Until it is called, the logger's handler decides which levels are logged.
+// ConfigReloader sets it from log_level.
+func (g *WatcherGroup) SetLogLevel(level slog.Level) {
+	g.logLevel.Set(level)
+}
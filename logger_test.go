@@ -0,0 +1,91 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// syncBuffer is a bytes.Buffer safe for loggers used from several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatcher_WithLogger(t *testing.T) {
+	var logs syncBuffer
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "hunter22"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		return errors.New("reload failed with hunter23")
+	}, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	source.set(map[string]interface{}{"password": "hunter23"})
+	AssertError(t, watcher.check(), "onChange callback failed: reload failed with [REDACTED]", "check()")
+	AssertBoolEquals(t, strings.Contains(logs.String(), `level=ERROR msg="Error checking for vault changes" error="onChange callback failed: reload failed with [REDACTED]"`), true, "logged error in "+logs.String())
+}
+
+func TestWatcherGroup_WithGroupLogger(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	var logs syncBuffer
+	group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, []string{"secret/data/app"}, time.Hour,
+		func(string) error { return errors.New("reload failed") }, WithGroupLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	if err := group.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer group.Stop()
+
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	group.checkAll()
+	AssertBoolEquals(t, strings.Contains(logs.String(), `msg="Error checking for vault changes"`), true, "member error in "+logs.String())
+}
+
+func TestWatcherGroup_SetLogLevel(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	var logs syncBuffer
+	group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, []string{"secret/data/app"}, time.Hour,
+		func(string) error { return errors.New("reload failed") }, WithGroupLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	if err := group.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer group.Stop()
+
+	group.SetLogLevel(slog.LevelError + 1)
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	group.checkAll()
+	AssertStringEquals(t, logs.String(), "", "member logs above error level")
+
+	group.SetLogLevel(slog.LevelError)
+	vault.Put("secret/app", map[string]interface{}{"password": "three"})
+	group.checkAll()
+	AssertBoolEquals(t, strings.Contains(logs.String(), `msg="Error checking for vault changes"`), true, "member error in "+logs.String())
+}
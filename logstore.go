@@ -0,0 +1,301 @@
+package vaultwatcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// logCompactMinSize is the log size below which LogStateStore never compacts
+const logCompactMinSize = 64 << 10
+
+// Log record operations
+const (
+	logOpPut    byte = 1
+	logOpDelete byte = 2
+)
+
+// logHeaderSize is the size of a record header: CRC-32, operation, key and
+// value lengths
+const logHeaderSize = 4 + 1 + 4 + 4
+
+// LogStateStore is an embedded StateStore keeping every key in a single
+// append-only file, for deployments with too many paths for a file per key.
+// Values are kept in memory and every write is synced to the log, which is
+// compacted once most of it is overwritten records. It is used by one process
+// at a time, which a lock file next to the log enforces; replicas share a
+// FileStateStore or ConsulStateStore instead.
+type LogStateStore struct {
+	path   string
+	mu     sync.Mutex
+	lock   *os.File // path + ".lock", locked while the store is open
+	file   *os.File
+	values map[string][]byte
+	size   int64 // Bytes in the log
+	live   int64 // Bytes of the records still in use
+}
+
+// NewLogStateStore opens the log at path, creating it if needed. A record cut
+// short by a crash is dropped. It fails if another process has the log open.
+func NewLogStateStore(path string) (*LogStateStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("state log path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// The log itself is replaced by compaction, so the lock is on its own file
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state log lock: %w", err)
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("failed to lock state log %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("failed to open state log: %w", err)
+	}
+
+	s := &LogStateStore{path: path, lock: lock, file: file, values: make(map[string][]byte)}
+	if err := s.replay(); err != nil {
+		file.Close()
+		lock.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the value of key, or nil if it doesn't exist
+func (s *LogStateStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.values[key]), nil
+}
+
+// Put sets the value of key
+func (s *LogStateStore) Put(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.put(key, value)
+}
+
+// CompareAndSwap sets key to new only if its value is old (nil: absent)
+func (s *LogStateStore) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.values[key]
+	if (old == nil && exists) || (old != nil && (!exists || !bytes.Equal(current, old))) {
+		return false, nil
+	}
+	if err := s.put(key, new); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes key
+func (s *LogStateStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.values[key]
+	if !exists {
+		return nil
+	}
+	if err := s.append(logOpDelete, key, nil); err != nil {
+		return fmt.Errorf("failed to delete state %s: %w", key, err)
+	}
+	s.live -= logRecordSize(key, current)
+	delete(s.values, key)
+	return s.compactIfNeeded()
+}
+
+// Len returns the number of keys
+func (s *LogStateStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.values)
+}
+
+// Size returns the size of the log in bytes
+func (s *LogStateStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Compact rewrites the log with only the current value of every key
+func (s *LogStateStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+// Close closes the log and releases its lock
+func (s *LogStateStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.file.Close()
+	s.lock.Close()
+	return err
+}
+
+// put appends key's new value. Called with s.mu held.
+func (s *LogStateStore) put(key string, value []byte) error {
+	if err := s.append(logOpPut, key, value); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", key, err)
+	}
+	if current, exists := s.values[key]; exists {
+		s.live -= logRecordSize(key, current)
+	}
+	s.values[key] = bytes.Clone(value)
+	s.live += logRecordSize(key, value)
+	return s.compactIfNeeded()
+}
+
+// append writes and syncs one record. Called with s.mu held.
+func (s *LogStateStore) append(op byte, key string, value []byte) error {
+	record := encodeLogRecord(op, key, value)
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.size += int64(len(record))
+	return nil
+}
+
+// compactIfNeeded compacts the log once more than half of it is overwritten
+// or deleted records. Called with s.mu held.
+func (s *LogStateStore) compactIfNeeded() error {
+	if s.size < logCompactMinSize || s.live*2 > s.size {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact writes the live records to a new log and swaps it in. Called with
+// s.mu held.
+func (s *LogStateStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-log-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact state log: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	var size int64
+	for key, value := range s.values {
+		record := encodeLogRecord(logOpPut, key, value)
+		if _, err := writer.Write(record); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact state log: %w", err)
+		}
+		size += int64(len(record))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact state log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact state log: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact state log: %w", err)
+	}
+
+	s.file.Close()
+	s.file = tmp
+	s.size = size
+	s.live = size
+
+	// Otherwise a crash could bring back the old log, losing the records
+	// appended to the new one
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("failed to sync state directory after compaction: %w", err)
+	}
+	return nil
+}
+
+// replay loads the log into memory, truncating a torn or corrupt tail
+func (s *LogStateStore) replay() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read state log: %w", err)
+	}
+	reader := bufio.NewReader(s.file)
+	header := make([]byte, logHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return fmt.Errorf("failed to read state log: %w", err)
+		}
+		keyLen := binary.BigEndian.Uint32(header[5:9])
+		valueLen := binary.BigEndian.Uint32(header[9:13])
+		if s.size+logHeaderSize+int64(keyLen)+int64(valueLen) > info.Size() {
+			break
+		}
+		body := make([]byte, int(keyLen)+int(valueLen))
+		if _, err := io.ReadFull(reader, body); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return fmt.Errorf("failed to read state log: %w", err)
+		}
+		if crc32.Update(crc32.ChecksumIEEE(header[4:]), crc32.IEEETable, body) != binary.BigEndian.Uint32(header[:4]) {
+			break
+		}
+
+		key, value := string(body[:keyLen]), body[keyLen:]
+		if current, exists := s.values[key]; exists {
+			s.live -= logRecordSize(key, current)
+		}
+		switch header[4] {
+		case logOpPut:
+			s.values[key] = value
+			s.live += logRecordSize(key, value)
+		case logOpDelete:
+			delete(s.values, key)
+		}
+		s.size += int64(logHeaderSize + len(body))
+	}
+
+	if err := s.file.Truncate(s.size); err != nil {
+		return fmt.Errorf("failed to repair state log: %w", err)
+	}
+	return nil
+}
+
+// encodeLogRecord encodes one record, its checksum covering everything after it
+func encodeLogRecord(op byte, key string, value []byte) []byte {
+	record := make([]byte, logHeaderSize, logRecordSize(key, value))
+	record[4] = op
+	binary.BigEndian.PutUint32(record[5:9], uint32(len(key)))
+	binary.BigEndian.PutUint32(record[9:13], uint32(len(value)))
+	record = append(append(record, key...), value...)
+	binary.BigEndian.PutUint32(record[:4], crc32.ChecksumIEEE(record[4:]))
+	return record
+}
+
+// logRecordSize returns the size of the record holding key and value
+func logRecordSize(key string, value []byte) int64 {
+	return int64(logHeaderSize + len(key) + len(value))
+}
@@ -0,0 +1,99 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewLogStateStore(t *testing.T) {
+	_, err := NewLogStateStore("")
+	AssertError(t, err, "state log path is required", "NewLogStateStore(\"\")")
+}
+
+func TestLogStateStore_Reopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "state.log")
+
+	store, err := NewLogStateStore(path)
+	AssertNoError(t, err, "NewLogStateStore()")
+	AssertNoError(t, store.Put(ctx, "run/kv/data/a", []byte("a1")), "Put()")
+	AssertNoError(t, store.Put(ctx, "run/kv/data/b", []byte("b1")), "Put()")
+	AssertNoError(t, store.Put(ctx, "run/kv/data/a", []byte("a2")), "Put()")
+	AssertNoError(t, store.Delete(ctx, "run/kv/data/b"), "Delete()")
+	AssertNoError(t, store.Put(ctx, "run/kv/data/c", []byte("c1")), "Put()")
+	AssertNoError(t, store.Close(), "Close()")
+
+	// A write cut short by a crash
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	AssertNoError(t, err, "OpenFile()")
+	record := encodeLogRecord(logOpPut, "run/kv/data/c", []byte("c2"))
+	_, err = file.Write(record[:len(record)-1])
+	AssertNoError(t, err, "Write()")
+	file.Close()
+
+	store, err = NewLogStateStore(path)
+	AssertNoError(t, err, "NewLogStateStore() again")
+	defer store.Close()
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"run/kv/data/a", "a2"},
+		{"run/kv/data/b", ""},
+		{"run/kv/data/c", "c1"},
+	}
+	for _, tt := range tests {
+		value, err := store.Get(ctx, tt.key)
+		AssertNoError(t, err, "Get()")
+		AssertStringEquals(t, string(value), tt.want, tt.key)
+	}
+	if store.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", store.Len())
+	}
+
+	// The torn record was cut off, so new writes follow the last good one
+	AssertNoError(t, store.Put(ctx, "run/kv/data/d", []byte("d1")), "Put()")
+	info, err := os.Stat(path)
+	AssertNoError(t, err, "Stat()")
+	if info.Size() != store.Size() {
+		t.Errorf("log is %d bytes, want %d", info.Size(), store.Size())
+	}
+}
+
+func TestLogStateStore_Compaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.log")
+
+	store, err := NewLogStateStore(path)
+	AssertNoError(t, err, "NewLogStateStore()")
+	defer store.Close()
+
+	// 100 paths overwritten many times; without compaction the log would
+	// pass 1 MiB
+	value := make([]byte, 100)
+	for round := 0; round < 100; round++ {
+		for i := 0; i < 100; i++ {
+			AssertNoError(t, store.Put(ctx, fmt.Sprintf("run/kv/data/%d", i), value), "Put()")
+		}
+	}
+	if size := store.Size(); size >= 2*logCompactMinSize {
+		t.Errorf("log is %d bytes, want it compacted below %d", size, 2*logCompactMinSize)
+	}
+
+	AssertNoError(t, store.Compact(), "Compact()")
+	if want := 100 * logRecordSize("run/kv/data/10", value); store.Size() > want {
+		t.Errorf("log is %d bytes after Compact(), want at most %d", store.Size(), want)
+	}
+
+	AssertNoError(t, store.Close(), "Close()")
+	store, err = NewLogStateStore(path)
+	AssertNoError(t, err, "NewLogStateStore() after compaction")
+	defer store.Close()
+	if store.Len() != 100 {
+		t.Errorf("Len() after reopening = %d, want 100", store.Len())
+	}
+}
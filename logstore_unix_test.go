@@ -0,0 +1,35 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package vaultwatcher
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogStateStore_Lock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+
+	store, err := NewLogStateStore(path)
+	AssertNoError(t, err, "NewLogStateStore()")
+
+	// flock locks belong to the open file, so a second open in this process
+	// conflicts like another process would
+	_, err = NewLogStateStore(path)
+	if !errors.Is(err, errFileLocked) {
+		t.Errorf("NewLogStateStore() while open error = %v, want it locked", err)
+	}
+
+	// Compaction replaces the log but not the lock
+	AssertNoError(t, store.Compact(), "Compact()")
+	_, err = NewLogStateStore(path)
+	if !errors.Is(err, errFileLocked) {
+		t.Errorf("NewLogStateStore() after compaction error = %v, want it locked", err)
+	}
+
+	AssertNoError(t, store.Close(), "Close()")
+	store, err = NewLogStateStore(path)
+	AssertNoError(t, err, "NewLogStateStore() after Close()")
+	store.Close()
+}
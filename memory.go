@@ -0,0 +1,61 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// secretBuffer holds secret data serialized in memory that is locked against
+// swapping where the OS allows it, and zeroed by wipe. The maps decoded from
+// it are ordinary Go values that can't be wiped, so callers should only keep
+// them as long as they need them.
+type secretBuffer struct {
+	b []byte
+}
+
+// newSecretBuffer copies data into a new buffer
+func newSecretBuffer(data map[string]interface{}) (*secretBuffer, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store secret data: %w", err)
+	}
+
+	// Copy into a buffer of the exact size, so no spare capacity holds a copy
+	buffer := &secretBuffer{b: make([]byte, len(encoded))}
+	copy(buffer.b, encoded)
+	wipeBytes(encoded)
+	lockMemory(buffer.b)
+	return buffer, nil
+}
+
+// data decodes the buffer; numbers stay json.Number as in data read from Vault
+func (b *secretBuffer) data() (map[string]interface{}, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b.b))
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to read stored secret data: %w", err)
+	}
+	return data, nil
+}
+
+// wipe zeroes and unlocks the buffer; it is safe on a nil buffer
+func (b *secretBuffer) wipe() {
+	if b == nil || b.b == nil {
+		return
+	}
+	wipeBytes(b.b)
+	unlockMemory(b.b)
+	b.b = nil
+}
+
+func wipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package vaultwatcher
+
+// lockMemory is a no-op where memory can't be locked; buffers are still wiped
+func lockMemory(b []byte) {}
+
+func unlockMemory(b []byte) {}
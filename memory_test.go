@@ -0,0 +1,74 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestSecretBuffer(t *testing.T) {
+	buffer, err := newSecretBuffer(map[string]interface{}{"password": "s3cr3t", "port": json.Number("5432")})
+	AssertNoError(t, err, "newSecretBuffer()")
+
+	data, err := buffer.data()
+	AssertNoError(t, err, "data()")
+	AssertStringEquals(t, data["password"].(string), "s3cr3t", "password")
+	AssertStringEquals(t, data["port"].(json.Number).String(), "5432", "port")
+
+	stored := buffer.b
+	buffer.wipe()
+	for i, b := range stored {
+		if b != 0 {
+			t.Fatalf("byte %d = %d after wipe, want 0", i, b)
+		}
+	}
+	data, err = buffer.data()
+	if err == nil || data != nil {
+		t.Errorf("data() after wipe = %v, %v, want an error", data, err)
+	}
+
+	var missing *secretBuffer
+	missing.wipe()
+	data, err = missing.data()
+	AssertNoError(t, err, "data() of a nil buffer")
+	AssertBoolEquals(t, data == nil, true, "nil buffer data")
+}
+
+func TestWatcher_SecureMemory(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil }, WithJSONPatch(), WithSecureMemory())
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+
+	watcher.mu.RLock()
+	plain, buffer := watcher.currentData, watcher.currentBuffer
+	watcher.mu.RUnlock()
+	AssertBoolEquals(t, plain == nil, true, "plaintext data retained")
+	AssertBoolEquals(t, buffer != nil, true, "buffer retained")
+
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.check(), "check()")
+	AssertBoolEquals(t, buffer.b == nil, true, "previous buffer wiped")
+
+	watcher.Stop()
+	watcher.mu.RLock()
+	buffer = watcher.currentBuffer
+	watcher.mu.RUnlock()
+	AssertBoolEquals(t, buffer == nil, true, "buffer dropped on Stop")
+}
+
+func TestWatcher_HashOnly(t *testing.T) {
+	_, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, WithHashOnly(), WithJSONPatch())
+	AssertError(t, err, "json patches keep secret data in memory, which hash-only watchers don't allow", "NewWatcher() with JSON patches")
+
+	watcher, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, WithHashOnly())
+	AssertNoError(t, err, "NewWatcher()")
+	_, err = NewFileSync(watcher, t.TempDir()+"/secret.json", time.Minute)
+	AssertError(t, err, "file sync keeps secret data in memory, which hash-only watchers don't allow", "NewFileSync()")
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package vaultwatcher
+
+import "syscall"
+
+// lockMemory keeps b out of swap. It is best effort: without the privilege or
+// enough RLIMIT_MEMLOCK the buffer is still wiped, just not locked.
+func lockMemory(b []byte) {
+	if len(b) > 0 {
+		_ = syscall.Mlock(b)
+	}
+}
+
+func unlockMemory(b []byte) {
+	if len(b) > 0 {
+		_ = syscall.Munlock(b)
+	}
+}
@@ -0,0 +1,55 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// CustomMetadataKey is the key under which WithCustomMetadata adds a KV v2
+// secret's custom_metadata to the hashed data, and reports it in ChangedKeys
+const CustomMetadataKey = "_custom_metadata"
+
+// customMetadata returns the custom_metadata returned with a KV v2 read
+func customMetadata(secret *api.Secret) map[string]interface{} {
+	metadata, ok := secret.Data["metadata"].(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+	custom, ok := metadata["custom_metadata"].(map[string]interface{})
+	if !ok {
+		// Secrets without custom metadata return null
+		return map[string]interface{}{}
+	}
+	return custom
+}
+
+// withCustomMetadata returns a copy of data with the secret's custom_metadata added
+func withCustomMetadata(data map[string]interface{}, secret *api.Secret) (map[string]interface{}, error) {
+	if _, exists := data[CustomMetadataKey]; exists {
+		return nil, fmt.Errorf("secret data already contains the key %s", CustomMetadataKey)
+	}
+
+	combined := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		combined[k] = v
+	}
+	combined[CustomMetadataKey] = customMetadata(secret)
+	return combined, nil
+}
+
+// fetchCustomMetadata reads only the custom_metadata of the watched KV v2 secret
+func (w *Watcher) fetchCustomMetadata(ctx context.Context) (map[string]interface{}, error) {
+	metadata, err := w.readKVMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	custom, ok := metadata["custom_metadata"].(map[string]interface{})
+	if !ok {
+		// Secrets without custom metadata return null
+		return map[string]interface{}{}, nil
+	}
+	return custom, nil
+}
@@ -0,0 +1,135 @@
+package vaultwatcher
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// changeRecorder collects change events
+type changeRecorder struct {
+	mu     sync.Mutex
+	events []ChangeEvent
+}
+
+func (r *changeRecorder) Notify(ctx context.Context, event ChangeEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *changeRecorder) changedKeys() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([][]string, len(r.events))
+	for i, event := range r.events {
+		keys[i] = event.ChangedKeys
+	}
+	return keys
+}
+
+func TestWatcher_WithCustomMetadata(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	recorder := &changeRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil }, WithCustomMetadata(), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.SetCustomMetadata("secret/app", map[string]string{"owner": "payments"})
+	AssertNoError(t, watcher.checkForChanges(), "check after metadata change")
+
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.checkForChanges(), "check after data change")
+
+	want := [][]string{{CustomMetadataKey}, {"password"}}
+	if got := recorder.changedKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("changed keys = %v, want %v", got, want)
+	}
+}
+
+func TestWatcher_CustomMetadataIgnoredByDefault(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	changes := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			changes++
+			return nil
+		})
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.SetCustomMetadata("secret/app", map[string]string{"owner": "payments"})
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if changes != 0 {
+		t.Errorf("changes = %d, want 0 without WithCustomMetadata", changes)
+	}
+}
+
+func TestWatcher_WithCustomMetadataErrors(t *testing.T) {
+	_, err := NewWatcher(&VaultConfig{Host: "https://vault.example.com", Path: "kv/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithCustomMetadata())
+	AssertError(t, err, `path must be a KV v2 path like <mount>/data/<key>, got "kv/app"`, "NewWatcher() with KV v1 path")
+
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{CustomMetadataKey: "clash"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil }, WithCustomMetadata())
+	AssertNoError(t, err, "NewWatcher()")
+	_, err = watcher.fetchVaultData()
+	AssertError(t, err, "secret data already contains the key _custom_metadata", "fetchVaultData()")
+}
+
+func TestWatcher_WithMetadataOnly(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	recorder := &changeRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil }, WithMetadataOnly(), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	// Data changes are ignored
+	vault.Put("secret/app", map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.checkForChanges(), "check after data change")
+
+	vault.SetCustomMetadata("secret/app", map[string]string{"rollout": "canary"})
+	AssertNoError(t, watcher.checkForChanges(), "check after metadata change")
+	vault.SetCustomMetadata("secret/app", map[string]string{"rollout": "all", "owner": "payments"})
+	AssertNoError(t, watcher.checkForChanges(), "check after second metadata change")
+
+	want := [][]string{{"rollout"}, {"owner", "rollout"}}
+	if got := recorder.changedKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("changed keys = %v, want %v", got, want)
+	}
+	if vault.Reads("secret/app") != 0 {
+		t.Errorf("secret data was read %d times, want 0", vault.Reads("secret/app"))
+	}
+}
+
+func TestWatcher_WithMetadataOnlyErrors(t *testing.T) {
+	_, err := NewWatcher(&VaultConfig{Host: "https://vault.example.com", Path: "kv/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithMetadataOnly())
+	AssertError(t, err, `path must be a KV v2 path like <mount>/data/<key>, got "kv/app"`, "NewWatcher() with KV v1 path")
+
+	_, err = NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, WithMetadataOnly(), WithPinnedVersion(2))
+	AssertError(t, err, "metadata-only watchers cannot pin a version", "NewWatcher() with pinned version")
+}
@@ -0,0 +1,202 @@
+package vaultwatcher
+
+import (
+	"time"
+)
+
+// callbackDurationBuckets are the upper bounds, in seconds, of the callback
+// duration histogram exported to Prometheus
+var callbackDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// CallbackMetrics describes the runs of one callback. Slow callbacks delay
+// every change behind them, so durations are the first thing to look at.
+type CallbackMetrics struct {
+	Calls         int64
+	Successes     int64
+	Failures      int64
+	Retries       int64         // Runs for a change whose callback failed before
+	TotalDuration time.Duration // Sum of all run durations
+	LastDuration  time.Duration
+	MaxDuration   time.Duration
+	LastRun       time.Time
+	LastError     string // Error of the last failed run, with secret values redacted
+
+	buckets []int64 // Runs per callbackDurationBuckets bound, plus one for longer runs
+}
+
+// WatcherStatus is a snapshot of a watcher's state
+type WatcherStatus struct {
+	Path                string
+	Started             bool
+	Healthy             bool
+	ConsecutiveFailures int
+	LastSuccessfulCheck time.Time // Of the initial fetch or a later check
+	AuthValid           bool      // False while checks are denied permission
+	Leader              bool
+	VaultAvailable      bool
+	CurrentHash         string
+	CurrentVersion      int           // KV v2 version applied, zero if unknown
+	LastChange          time.Time     // When the last applied change was detected
+	TokenExpiresAt      time.Time     // With WithTokenMonitoring, zero if unknown or the token doesn't expire
+	TokenTTL            time.Duration // Remaining until TokenExpiresAt
+
+	// Callbacks holds the metrics of each callback by name, e.g. "onChange"
+	Callbacks map[string]CallbackMetrics
+}
+
+// Status returns a snapshot of the watcher's state and callback metrics
+func (w *Watcher) Status() WatcherStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	status := WatcherStatus{
+		Path:                w.vaultConfig.Path,
+		Started:             w.started,
+		Healthy:             !w.unhealthy,
+		ConsecutiveFailures: w.consecutiveFailures,
+		LastSuccessfulCheck: w.lastSuccessfulCheck,
+		AuthValid:           !w.authFailed,
+		Leader:              w.lock == nil || w.leader,
+		VaultAvailable:      !w.vaultUnavailable,
+		CurrentHash:         w.currentHash,
+		CurrentVersion:      w.currentVersion,
+		Callbacks:           make(map[string]CallbackMetrics, len(w.callbackMetrics)),
+	}
+	if w.lastChange != nil {
+		status.LastChange = w.lastChange.Timestamp
+	}
+	if !w.tokenExpiresAt.IsZero() {
+		status.TokenExpiresAt = w.tokenExpiresAt
+		status.TokenTTL = time.Until(w.tokenExpiresAt)
+		if status.TokenTTL < 0 {
+			status.TokenTTL = 0
+		}
+	}
+	for name, metrics := range w.callbackMetrics {
+		copied := *metrics
+		copied.buckets = append([]int64(nil), metrics.buckets...)
+		status.Callbacks[name] = copied
+	}
+	return status
+}
+
+// runCallback runs a callback and records its duration and outcome. A
+// failure is returned as a CallbackError. retry tells whether the callback
+// already failed for the same change.
+func (w *Watcher) runCallback(name string, retry bool, callback func() error) error {
+	start := time.Now()
+	err := callback()
+	duration := time.Since(start)
+
+	var lastError string
+	if err != nil {
+		err = &CallbackError{Callback: name, Err: err}
+		lastError = w.redact(err.Error())
+	}
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	w.emitTiming("callback.duration", duration, "callback", name, "result", result)
+	if err != nil {
+		w.emitCount("callback_errors", 1, "callback", name)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.callbackMetrics == nil {
+		w.callbackMetrics = map[string]*CallbackMetrics{}
+	}
+	metrics := w.callbackMetrics[name]
+	if metrics == nil {
+		metrics = &CallbackMetrics{buckets: make([]int64, len(callbackDurationBuckets)+1)}
+		w.callbackMetrics[name] = metrics
+	}
+
+	metrics.Calls++
+	if retry {
+		metrics.Retries++
+	}
+	if err != nil {
+		metrics.Failures++
+		metrics.LastError = lastError
+	} else {
+		metrics.Successes++
+	}
+	metrics.TotalDuration += duration
+	metrics.LastDuration = duration
+	if duration > metrics.MaxDuration {
+		metrics.MaxDuration = duration
+	}
+	metrics.LastRun = start
+	metrics.buckets[durationBucket(duration)]++
+
+	return err
+}
+
+// durationBucket returns the index of the smallest bucket holding d
+func durationBucket(d time.Duration) int {
+	seconds := d.Seconds()
+	for i, bound := range callbackDurationBuckets {
+		if seconds <= bound {
+			return i
+		}
+	}
+	return len(callbackDurationBuckets)
+}
+
+// recordCallbackFailure counts a failed onChange for the change to newHash
+// and returns how many times in a row it failed
+func (w *Watcher) recordCallbackFailure(newHash string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failingHash != newHash {
+		w.failingHash, w.callbackFailures = newHash, 0
+	}
+	w.callbackFailures++
+	return w.callbackFailures
+}
+
+// resetCallbackFailures forgets the failed attempts after the change was handled
+func (w *Watcher) resetCallbackFailures() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failingHash, w.callbackFailures = "", 0
+}
+
+// MetricsSink receives metrics as they happen, for push-based systems such
+// as StatsD. Register one with WithMetricsSink. Every metric is tagged with
+// the watched path; callback metrics also with the callback name.
+//
+// Counters: "checks" and "check_errors" per check, "changes" per applied
+// change and "callback_errors" per failed callback run. Timings:
+// "callback.duration" per callback run, tagged with its result.
+type MetricsSink interface {
+	Count(name string, value int64, tags map[string]string)
+	Timing(name string, duration time.Duration, tags map[string]string)
+}
+
+// emitCount adds value to a counter of every metrics sink; tags are name/value pairs
+func (w *Watcher) emitCount(name string, value int64, tags ...string) {
+	for _, sink := range w.metricsSinks {
+		sink.Count(name, value, w.metricTags(tags))
+	}
+}
+
+// emitTiming records a duration in every metrics sink; tags are name/value pairs
+func (w *Watcher) emitTiming(name string, duration time.Duration, tags ...string) {
+	for _, sink := range w.metricsSinks {
+		sink.Timing(name, duration, w.metricTags(tags))
+	}
+}
+
+// metricTags returns the path tag and the name/value pairs as a map
+func (w *Watcher) metricTags(pairs []string) map[string]string {
+	tags := map[string]string{"path": w.vaultConfig.Path}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		tags[pairs[i]] = pairs[i+1]
+	}
+	return tags
+}
@@ -0,0 +1,85 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestWatcher_Status(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	failing := true
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			time.Sleep(10 * time.Millisecond)
+			if failing {
+				return errors.New("cannot apply password-two")
+			}
+			return nil
+		})
+	AssertNoError(t, err, "NewWatcher()")
+
+	status := watcher.Status()
+	AssertBoolEquals(t, status.Started, false, "Started before Start()")
+	if len(status.Callbacks) != 0 {
+		t.Errorf("Callbacks before any change = %v, want none", status.Callbacks)
+	}
+
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.Put("secret/app", map[string]interface{}{"password": "password-two"})
+	AssertError(t, watcher.check(), "onChange callback failed: cannot apply [REDACTED]", "first check")
+	AssertError(t, watcher.check(), "onChange callback failed: cannot apply [REDACTED]", "second check")
+	failing = false
+	AssertNoError(t, watcher.check(), "third check")
+
+	status = watcher.Status()
+	AssertStringEquals(t, status.Path, "secret/data/app", "Path")
+	AssertBoolEquals(t, status.Started, true, "Started")
+	AssertBoolEquals(t, status.Healthy, true, "Healthy")
+	AssertBoolEquals(t, status.Leader, true, "Leader")
+	AssertStringEquals(t, status.CurrentHash, watcher.GetCurrentHash(), "CurrentHash")
+	if status.CurrentVersion != 2 {
+		t.Errorf("CurrentVersion = %d, want 2", status.CurrentVersion)
+	}
+	AssertBoolEquals(t, status.LastChange.IsZero(), false, "LastChange is zero")
+
+	metrics := status.Callbacks["onChange"]
+	if metrics.Calls != 3 || metrics.Successes != 1 || metrics.Failures != 2 || metrics.Retries != 2 {
+		t.Errorf("calls/successes/failures/retries = %d/%d/%d/%d, want 3/1/2/2",
+			metrics.Calls, metrics.Successes, metrics.Failures, metrics.Retries)
+	}
+	if metrics.LastDuration < 10*time.Millisecond || metrics.MaxDuration < metrics.LastDuration ||
+		metrics.TotalDuration < 30*time.Millisecond {
+		t.Errorf("durations last/max/total = %v/%v/%v, want at least 10ms per run",
+			metrics.LastDuration, metrics.MaxDuration, metrics.TotalDuration)
+	}
+	AssertStringEquals(t, metrics.LastError, "onChange callback failed: cannot apply [REDACTED]", "LastError")
+}
+
+func TestDurationBucket(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     int
+	}{
+		{duration: 0, want: 0},
+		{duration: 5 * time.Millisecond, want: 0},
+		{duration: 6 * time.Millisecond, want: 1},
+		{duration: time.Second, want: 7},
+		{duration: time.Hour, want: len(callbackDurationBuckets)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.duration.String(), func(t *testing.T) {
+			if got := durationBucket(tt.duration); got != tt.want {
+				t.Errorf("durationBucket(%v) = %d, want %d", tt.duration, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	secretMountsPath = "sys/mounts"
+	authMountsPath   = "sys/auth"
+)
+
+// Mount identifies a secrets engine or auth method mount
+type Mount struct {
+	Path        string // Mount path, e.g. "kv/" or "approle/"
+	Type        string // Engine or method type, e.g. "kv" or "approle"
+	Description string
+}
+
+// MountChange describes how the mounts at sys/mounts or sys/auth changed
+type MountChange struct {
+	Path     string  // sys/mounts or sys/auth
+	Added    []Mount // Newly enabled mounts
+	Removed  []Mount // Disabled mounts
+	Modified []Mount // Mounts whose options or tune settings changed
+}
+
+// MountWatcher fires a callback when secrets engines (sys/mounts) or auth
+// methods (sys/auth) are enabled, disabled or tuned, for auditing Vault
+// configuration drift
+type MountWatcher struct {
+	*Watcher
+	onChange func(MountChange) error
+
+	mu             sync.Mutex
+	mounts         map[string]Mount
+	mountHashes    map[string]string
+	fetchedMounts  map[string]Mount
+	fetchedHashes  map[string]string
+	initialFetched bool
+}
+
+// NewMountWatcher creates a watcher for secrets engine or auth method mounts
+// vaultConfig: Vault connection configuration, Path is "sys/mounts" or "sys/auth"
+// checkInterval: How often to check for changes
+// onChange: Callback invoked with the added, removed and modified mounts
+func NewMountWatcher(vaultConfig *VaultConfig, checkInterval time.Duration, onChange func(MountChange) error, opts ...Option) (*MountWatcher, error) {
+	if onChange == nil {
+		return nil, fmt.Errorf("onChange callback cannot be nil")
+	}
+	if vaultConfig != nil && vaultConfig.Path != secretMountsPath && vaultConfig.Path != authMountsPath {
+		return nil, fmt.Errorf("mount path must be %s or %s, got %q", secretMountsPath, authMountsPath, vaultConfig.Path)
+	}
+
+	mw := &MountWatcher{onChange: onChange}
+
+	watcher, err := NewWatcher(vaultConfig, checkInterval, mw.handleChange, opts...)
+	if err != nil {
+		return nil, err
+	}
+	watcher.fetchData = mw.fetchMounts
+	mw.Watcher = watcher
+
+	return mw, nil
+}
+
+// Mounts returns the mounts as of the last processed change, sorted by path
+func (mw *MountWatcher) Mounts() []Mount {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	return sortedMounts(mw.mounts, nil)
+}
+
+// fetchMounts reads the mount table into a map of mount path to configuration
+func (mw *MountWatcher) fetchMounts() (map[string]interface{}, error) {
+	ctx, cancel := mw.requestContext()
+	defer cancel()
+
+	secret, err := mw.client.Logical().ReadWithContext(ctx, mw.vaultConfig.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from vault: %w", mw.vaultConfig.Path, classifyVaultError(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("failed to read %s from vault: secret is nil", mw.vaultConfig.Path)
+	}
+
+	table := make(map[string]interface{}, len(secret.Data))
+	mounts := make(map[string]Mount, len(secret.Data))
+	for path, value := range secret.Data {
+		entry, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		table[path] = entry
+
+		mount := Mount{Path: path}
+		mount.Type, _ = entry["type"].(string)
+		mount.Description, _ = entry["description"].(string)
+		mounts[path] = mount
+	}
+
+	hashes, err := CalculateKeyHashes(table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate mount hashes: %w", err)
+	}
+
+	mw.mu.Lock()
+	mw.fetchedMounts, mw.fetchedHashes = mounts, hashes
+	if !mw.initialFetched {
+		// First read when the watcher starts
+		mw.mounts, mw.mountHashes = mounts, hashes
+		mw.initialFetched = true
+	}
+	mw.mu.Unlock()
+
+	return table, nil
+}
+
+// handleChange is the Watcher callback, called when the mount table changed
+func (mw *MountWatcher) handleChange() error {
+	mw.mu.Lock()
+	previous, previousHashes := mw.mounts, mw.mountHashes
+	fetched, fetchedHashes := mw.fetchedMounts, mw.fetchedHashes
+	mw.mu.Unlock()
+
+	change := MountChange{Path: mw.vaultConfig.Path}
+	added, removed, modified := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, path := range ChangedKeys(previousHashes, fetchedHashes) {
+		_, existed := previous[path]
+		_, exists := fetched[path]
+		switch {
+		case !existed:
+			added[path] = true
+		case !exists:
+			removed[path] = true
+		default:
+			modified[path] = true
+		}
+	}
+	change.Added = sortedMounts(fetched, added)
+	change.Removed = sortedMounts(previous, removed)
+	change.Modified = sortedMounts(fetched, modified)
+
+	if err := mw.onChange(change); err != nil {
+		return err
+	}
+
+	mw.mu.Lock()
+	mw.mounts, mw.mountHashes = fetched, fetchedHashes
+	mw.mu.Unlock()
+
+	return nil
+}
+
+// sortedMounts returns the mounts whose paths are in include (all when nil), sorted by path
+func sortedMounts(mounts map[string]Mount, include map[string]bool) []Mount {
+	var result []Mount
+	for path, mount := range mounts {
+		if include == nil || include[path] {
+			result = append(result, mount)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
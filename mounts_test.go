@@ -0,0 +1,105 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMountTable serves sys/auth from a settable mount table
+type fakeMountTable struct {
+	mu     sync.Mutex
+	mounts map[string]interface{}
+}
+
+func (f *fakeMountTable) set(path string, entry map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if entry == nil {
+		delete(f.mounts, path)
+		return
+	}
+	f.mounts[path] = entry
+}
+
+func (f *fakeMountTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path != "/v1/sys/auth" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": f.mounts})
+}
+
+func authMount(mountType string, maxTTL int) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        mountType,
+		"description": mountType + " auth",
+		"config":      map[string]interface{}{"default_lease_ttl": 0, "max_lease_ttl": maxTTL},
+	}
+}
+
+func TestNewMountWatcher(t *testing.T) {
+	config := &VaultConfig{Host: "https://vault.example.com", Path: "sys/policies/acl", Token: "test-token"}
+	_, err := NewMountWatcher(config, time.Second, func(MountChange) error { return nil })
+	AssertError(t, err, `mount path must be sys/mounts or sys/auth, got "sys/policies/acl"`, "NewMountWatcher(bad path)")
+
+	config.Path = "sys/mounts"
+	_, err = NewMountWatcher(config, time.Second, nil)
+	AssertError(t, err, "onChange callback cannot be nil", "NewMountWatcher(nil callback)")
+}
+
+func TestMountWatcher_DetectsChanges(t *testing.T) {
+	table := &fakeMountTable{mounts: map[string]interface{}{
+		"token/":   authMount("token", 0),
+		"approle/": authMount("approle", 0),
+		"ldap/":    authMount("ldap", 0),
+	}}
+	server := httptest.NewServer(table)
+	defer server.Close()
+
+	var changes []MountChange
+	watcher, err := NewMountWatcher(
+		&VaultConfig{Host: server.URL, Path: "sys/auth", Token: "test-token"},
+		time.Hour,
+		func(change MountChange) error {
+			changes = append(changes, change)
+			return nil
+		},
+	)
+	AssertNoError(t, err, "NewMountWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	if got := len(watcher.Mounts()); got != 3 {
+		t.Errorf("len(Mounts()) = %d, want 3", got)
+	}
+
+	table.set("kubernetes/", authMount("kubernetes", 0))
+	table.set("ldap/", nil)
+	table.set("approle/", authMount("approle", 3600))
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+
+	want := MountChange{
+		Path:     "sys/auth",
+		Added:    []Mount{{Path: "kubernetes/", Type: "kubernetes", Description: "kubernetes auth"}},
+		Removed:  []Mount{{Path: "ldap/", Type: "ldap", Description: "ldap auth"}},
+		Modified: []Mount{{Path: "approle/", Type: "approle", Description: "approle auth"}},
+	}
+	if !reflect.DeepEqual(changes[0], want) {
+		t.Errorf("change = %+v, want %+v", changes[0], want)
+	}
+
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(changes) != 1 {
+		t.Errorf("got %d changes without modification, want 1", len(changes))
+	}
+}
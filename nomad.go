@@ -0,0 +1,133 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NomadConfig holds the configuration for a NomadNotifier
+type NomadConfig struct {
+	Address    string            // Nomad HTTP address (default NOMAD_ADDR or "http://127.0.0.1:4646")
+	Token      string            // ACL token (default NOMAD_TOKEN)
+	Namespace  string            // Namespace of the job (default NOMAD_NAMESPACE)
+	Job        string            // ID of the job
+	TaskGroup  string            // Restart only this task group's allocations
+	Task       string            // Restart only this task instead of every task of the allocations
+	Stagger    time.Duration     // Pause between allocation restarts, so they don't all restart at once
+	Dispatch   bool              // Dispatch the parameterized job instead of restarting allocations
+	Meta       map[string]string // Metadata of dispatched jobs
+	HTTPClient *http.Client      // Optional custom HTTP client
+}
+
+// NomadNotifier restarts the running allocations of a Nomad job, or
+// dispatches a parameterized job, when the watched secret changes, for
+// services scheduled by Nomad rather than Kubernetes. The token needs the
+// alloc-lifecycle capability to restart, or dispatch-job to dispatch.
+type NomadNotifier struct {
+	config NomadConfig
+	client *http.Client
+}
+
+// nomadAllocation is the subset of an allocation stub used to restart it
+type nomadAllocation struct {
+	ID           string
+	TaskGroup    string
+	ClientStatus string
+}
+
+// NewNomadNotifier creates a Nomad notifier
+func NewNomadNotifier(config NomadConfig) (*NomadNotifier, error) {
+	if config.Job == "" {
+		return nil, fmt.Errorf("nomad job is required")
+	}
+	if config.Address == "" {
+		config.Address = getEnv("NOMAD_ADDR", "http://127.0.0.1:4646")
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+	if config.Token == "" {
+		config.Token = getEnv("NOMAD_TOKEN", "")
+	}
+	if config.Namespace == "" {
+		config.Namespace = getEnv("NOMAD_NAMESPACE", "")
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &NomadNotifier{config: config, client: client}, nil
+}
+
+// Notify restarts or dispatches the job
+func (n *NomadNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	if n.config.Dispatch {
+		return n.DispatchJob(ctx)
+	}
+	return n.Restart(ctx)
+}
+
+// Restart restarts the running allocations of the job, or of its task group,
+// one after the other. Every allocation is tried; the errors are joined.
+func (n *NomadNotifier) Restart(ctx context.Context) error {
+	var allocations []nomadAllocation
+	if _, err := n.do(ctx, http.MethodGet, "/v1/job/"+url.PathEscape(n.config.Job)+"/allocations", nil, &allocations); err != nil {
+		return fmt.Errorf("failed to list allocations of nomad job %s: %w", n.config.Job, err)
+	}
+
+	body := map[string]interface{}{"AllTasks": n.config.Task == ""}
+	if n.config.Task != "" {
+		body["TaskName"] = n.config.Task
+	}
+
+	var errs []error
+	restarted := 0
+	for _, allocation := range allocations {
+		if allocation.ClientStatus != "running" || (n.config.TaskGroup != "" && allocation.TaskGroup != n.config.TaskGroup) {
+			continue
+		}
+		if restarted > 0 && n.config.Stagger > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.config.Stagger):
+			}
+		}
+		if _, err := n.do(ctx, http.MethodPost, "/v1/client/allocation/"+url.PathEscape(allocation.ID)+"/restart", body, nil); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restart nomad allocation %s: %w", allocation.ID, err))
+		}
+		restarted++
+	}
+	if restarted == 0 {
+		return fmt.Errorf("nomad job %s has no running allocations to restart", n.config.Job)
+	}
+	return errors.Join(errs...)
+}
+
+// DispatchJob dispatches the parameterized job with the configured metadata
+func (n *NomadNotifier) DispatchJob(ctx context.Context) error {
+	body := map[string]interface{}{}
+	if len(n.config.Meta) > 0 {
+		body["Meta"] = n.config.Meta
+	}
+	if _, err := n.do(ctx, http.MethodPost, "/v1/job/"+url.PathEscape(n.config.Job)+"/dispatch", body, nil); err != nil {
+		return fmt.Errorf("failed to dispatch nomad job %s: %w", n.config.Job, err)
+	}
+	return nil
+}
+
+func (n *NomadNotifier) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	requestURL := n.config.Address + path
+	if n.config.Namespace != "" {
+		requestURL += "?namespace=" + url.QueryEscape(n.config.Namespace)
+	}
+	return doJSON(ctx, n.client, method, requestURL, body, out, func(req *http.Request) {
+		if n.config.Token != "" {
+			req.Header.Set("X-Nomad-Token", n.config.Token)
+		}
+	})
+}
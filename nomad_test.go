@@ -0,0 +1,114 @@
+package vaultwatcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeNomad records the requests of a NomadNotifier
+type fakeNomad struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeNomad) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("X-Nomad-Token") != "nomad-token" {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "Permission denied")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+r.URL.String()+" "+string(body)))
+
+	switch {
+	case r.URL.Path == "/v1/job/billing/allocations":
+		io.WriteString(w, `[
+			{"ID": "a1", "TaskGroup": "api", "ClientStatus": "running"},
+			{"ID": "a2", "TaskGroup": "worker", "ClientStatus": "running"},
+			{"ID": "a3", "TaskGroup": "api", "ClientStatus": "complete"}
+		]`)
+	case r.URL.Path == "/v1/client/allocation/a2/restart":
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "node unreachable")
+	default:
+		io.WriteString(w, "{}")
+	}
+}
+
+func TestNewNomadNotifier_Errors(t *testing.T) {
+	_, err := NewNomadNotifier(NomadConfig{})
+	AssertError(t, err, "nomad job is required", "NewNomadNotifier()")
+}
+
+func TestNomadNotifier(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       NomadConfig
+		token        string
+		wantRequests string
+		wantErr      string
+	}{
+		{
+			name:   "restart task group",
+			config: NomadConfig{Job: "billing", TaskGroup: "api", Task: "server"},
+			wantRequests: "GET /v1/job/billing/allocations\n" +
+				`POST /v1/client/allocation/a1/restart {"AllTasks":false,"TaskName":"server"}`,
+		},
+		{
+			name:   "restart every group",
+			config: NomadConfig{Job: "billing", Namespace: "payments"},
+			wantRequests: "GET /v1/job/billing/allocations?namespace=payments\n" +
+				`POST /v1/client/allocation/a1/restart?namespace=payments {"AllTasks":true}` + "\n" +
+				`POST /v1/client/allocation/a2/restart?namespace=payments {"AllTasks":true}`,
+			wantErr: "failed to restart nomad allocation a2: unexpected status 500: node unreachable",
+		},
+		{
+			name:         "no running allocations",
+			config:       NomadConfig{Job: "billing", TaskGroup: "cron"},
+			wantRequests: "GET /v1/job/billing/allocations",
+			wantErr:      "nomad job billing has no running allocations to restart",
+		},
+		{
+			name:         "dispatch",
+			config:       NomadConfig{Job: "rotate-keys", Dispatch: true, Meta: map[string]string{"reason": "vault"}},
+			wantRequests: `POST /v1/job/rotate-keys/dispatch {"Meta":{"reason":"vault"}}`,
+		},
+		{
+			name:    "denied",
+			config:  NomadConfig{Job: "billing", Dispatch: true},
+			token:   "wrong",
+			wantErr: "failed to dispatch nomad job billing: unexpected status 403: Permission denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeNomad{}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			tt.config.Address, tt.config.Token = server.URL, "nomad-token"
+			if tt.token != "" {
+				tt.config.Token = tt.token
+			}
+			notifier, err := NewNomadNotifier(tt.config)
+			AssertNoError(t, err, "NewNomadNotifier()")
+
+			err = notifier.Notify(context.Background(), ChangeEvent{Path: "secret/data/billing"})
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "Notify()")
+			} else {
+				AssertNoError(t, err, "Notify()")
+			}
+			AssertStringEquals(t, strings.Join(fake.requests, "\n"), tt.wantRequests, "requests")
+		})
+	}
+}
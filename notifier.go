@@ -0,0 +1,25 @@
+package vaultwatcher
+
+import (
+	"context"
+	"time"
+)
+
+// defaultNotifyTimeout bounds each notifier call, see WithNotifyTimeout
+const defaultNotifyTimeout = 15 * time.Second
+
+// Notifier is notified after the watcher has detected and applied a change.
+// Notifiers run after the onChange callback succeeds; their errors are logged
+// and never stop the watcher. Each call gets a context with the deadline set
+// by WithNotifyTimeout.
+type Notifier interface {
+	Notify(ctx context.Context, event ChangeEvent) error
+}
+
+// NotifierFunc adapts an ordinary function to the Notifier interface
+type NotifierFunc func(ctx context.Context, event ChangeEvent) error
+
+// Notify calls f(ctx, event)
+func (f NotifierFunc) Notify(ctx context.Context, event ChangeEvent) error {
+	return f(ctx, event)
+}
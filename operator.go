@@ -0,0 +1,440 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	vaultWatchAPI               = "/apis/vaultwatcher.io/v1alpha1"
+	defaultOperatorResync       = 30 * time.Second
+	defaultOperatorInterval     = time.Minute
+	defaultVaultWatchAnnotation = "vaultwatcher.io/secret-hash"
+)
+
+// VaultWatch is a vaultwatcher.io/v1alpha1 custom resource declaring a
+// watch: the Vault path, how often to check it and what to update when it
+// changes
+type VaultWatch struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Metadata   struct {
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Generation int64  `json:"generation,omitempty"`
+	} `json:"metadata"`
+	Spec   VaultWatchSpec   `json:"spec"`
+	Status VaultWatchStatus `json:"status,omitempty"`
+}
+
+// VaultWatchSpec describes a watch
+type VaultWatchSpec struct {
+	Path     string           `json:"path"`               // Vault path, e.g. "secret/data/billing"
+	Interval string           `json:"interval,omitempty"` // Check interval as a Go duration, e.g. "30s"
+	Target   VaultWatchTarget `json:"target"`
+}
+
+// VaultWatchTarget is what a watch keeps up to date, in the namespace of the
+// VaultWatch. A Secret or ConfigMap gets one entry per secret key, replacing
+// its data; non-string values are stored as JSON. An Annotation target sets
+// an annotation of a workload's pod template to the secret's hash, so
+// Kubernetes rolls out the workload on every change.
+type VaultWatchTarget struct {
+	Kind       string `json:"kind"` // Secret, ConfigMap or Annotation
+	Name       string `json:"name"`
+	APIVersion string `json:"apiVersion,omitempty"` // Of an annotated workload (default "apps/v1")
+	Resource   string `json:"resource,omitempty"`   // Plural of an annotated workload (default "deployments")
+	Annotation string `json:"annotation,omitempty"` // Default "vaultwatcher.io/secret-hash"
+}
+
+// VaultWatchStatus is the observed state of a watch
+type VaultWatchStatus struct {
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	Hash               string `json:"hash,omitempty"`         // Of the last synced data
+	LastSyncTime       string `json:"lastSyncTime,omitempty"` // RFC 3339
+	Error              string `json:"error,omitempty"`        // Why the watch or the last sync failed
+}
+
+// KubernetesOperatorConfig configures a KubernetesOperator
+type KubernetesOperatorConfig struct {
+	Vault           *VaultConfig  // Vault connection; the path comes from each VaultWatch
+	Namespace       string        // Namespace of the VaultWatch resources (default: the pod's namespace)
+	AllNamespaces   bool          // Watch VaultWatch resources in every namespace
+	APIServer       string        // API server URL (default "https://kubernetes.default.svc")
+	Token           string        // Bearer token (default: the pod's service account token)
+	HTTPClient      *http.Client  // Optional custom HTTP client (default trusts the service account CA)
+	ResyncInterval  time.Duration // How often VaultWatch resources are listed (default 30s)
+	DefaultInterval time.Duration // Check interval of watches without one (default 1m)
+	Options         []Option      // Applied to every watcher, e.g. WithNotifier
+}
+
+// KubernetesOperator runs a Watcher for every VaultWatch resource, so
+// platform teams can declare watches in YAML. It lists the resources every
+// resync interval, starts watchers for new ones, restarts them when their
+// spec changes and stops them when they are deleted. The service account
+// needs list on vaultwatches, patch on vaultwatches/status, get, create and
+// update on the targeted secrets and configmaps, and patch on annotated
+// workloads.
+type KubernetesOperator struct {
+	config KubernetesOperatorConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	watches map[string]*operatorWatch // By namespace/name
+}
+
+// operatorWatch is the watcher running for one VaultWatch
+type operatorWatch struct {
+	resource VaultWatch
+	watcher  *Watcher   // Nil when the spec is invalid
+	syncMu   sync.Mutex // Serializes syncs of the target
+}
+
+// NewKubernetesOperator creates an operator. Defaults are taken from the
+// pod's service account when running in a cluster.
+func NewKubernetesOperator(config KubernetesOperatorConfig) (*KubernetesOperator, error) {
+	if config.Vault == nil || config.Vault.Host == "" || config.Vault.Token == "" {
+		return nil, fmt.Errorf("vault host and token are required")
+	}
+	if config.Namespace == "" && !config.AllNamespaces {
+		namespace, err := serviceAccountFile("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("namespace is required outside a cluster: %w", err)
+		}
+		config.Namespace = namespace
+	}
+	if config.ResyncInterval <= 0 {
+		config.ResyncInterval = defaultOperatorResync
+	}
+	if config.DefaultInterval <= 0 {
+		config.DefaultInterval = defaultOperatorInterval
+	}
+
+	var client *http.Client
+	var err error
+	config.APIServer, config.Token, client, err = kubernetesClient(config.APIServer, config.Token, config.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	return &KubernetesOperator{config: config, client: client, watches: map[string]*operatorWatch{}}, nil
+}
+
+// Run reconciles the VaultWatch resources until ctx is done, then stops
+// every watcher. Failed reconciliations are logged and retried at the next
+// resync.
+func (o *KubernetesOperator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.config.ResyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := o.reconcile(ctx); err != nil {
+			slog.Error("Error reconciling vault watches", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			o.stopAll()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watches returns the namespace/name of every running watch
+func (o *KubernetesOperator) Watches() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	keys := make([]string, 0, len(o.watches))
+	for key, watch := range o.watches {
+		if watch.watcher != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reconcile brings the running watchers in line with the VaultWatch resources
+func (o *KubernetesOperator) reconcile(ctx context.Context) error {
+	var list struct {
+		Items []VaultWatch `json:"items"`
+	}
+	if _, err := o.do(ctx, http.MethodGet, o.vaultWatchesURL(), nil, &list); err != nil {
+		return fmt.Errorf("failed to list vault watches: %w", err)
+	}
+
+	resources := make(map[string]VaultWatch, len(list.Items))
+	for _, resource := range list.Items {
+		resources[resource.Metadata.Namespace+"/"+resource.Metadata.Name] = resource
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for key, watch := range o.watches {
+		resource, ok := resources[key]
+		if ok && resource.Metadata.Generation == watch.resource.Metadata.Generation {
+			continue
+		}
+		if ok && reflect.DeepEqual(resource.Spec, watch.resource.Spec) {
+			// Only metadata or, without the status subresource, the status
+			// changed; the next status update reports the new generation
+			watch.syncMu.Lock()
+			watch.resource.Metadata.Generation = resource.Metadata.Generation
+			watch.syncMu.Unlock()
+			continue
+		}
+		// Deleted or changed
+		if watch.watcher != nil {
+			watch.watcher.Stop()
+		}
+		delete(o.watches, key)
+	}
+
+	for key, resource := range resources {
+		if _, ok := o.watches[key]; ok {
+			continue
+		}
+		watch, err := o.startWatch(ctx, resource)
+		if err != nil {
+			o.updateStatus(ctx, resource, VaultWatchStatus{Error: err.Error()})
+			if watch == nil {
+				// Retried at the next resync
+				continue
+			}
+		}
+		o.watches[key] = watch
+	}
+	return nil
+}
+
+// startWatch starts the watcher of resource and syncs its target. An invalid
+// spec returns a watch without a watcher, so it isn't retried until the
+// resource changes; other errors return no watch.
+func (o *KubernetesOperator) startWatch(ctx context.Context, resource VaultWatch) (*operatorWatch, error) {
+	watch := &operatorWatch{resource: resource}
+	spec := resource.Spec
+	interval, err := validateVaultWatch(spec, o.config.DefaultInterval)
+	if err != nil {
+		return watch, err
+	}
+
+	vaultConfig := *o.config.Vault
+	vaultConfig.Path = spec.Path
+	watcher, err := NewWatcher(&vaultConfig, interval, func() error {
+		return o.sync(context.Background(), watch)
+	}, o.config.Options...)
+	if err != nil {
+		return watch, err
+	}
+	watch.watcher = watcher
+
+	if err := watcher.Start(); err != nil {
+		return nil, err
+	}
+	if err := o.sync(ctx, watch); err != nil {
+		// The watcher retries with the next change; report it meanwhile
+		slog.Error("Error syncing vault watch", "namespace", resource.Metadata.Namespace, "name", resource.Metadata.Name, "error", err)
+	}
+	return watch, nil
+}
+
+// validateVaultWatch checks spec and returns its check interval
+func validateVaultWatch(spec VaultWatchSpec, defaultInterval time.Duration) (time.Duration, error) {
+	if spec.Path == "" {
+		return 0, fmt.Errorf("spec.path is required")
+	}
+	switch spec.Target.Kind {
+	case "Secret", "ConfigMap", "Annotation":
+	default:
+		return 0, fmt.Errorf("spec.target.kind must be Secret, ConfigMap or Annotation, got %q", spec.Target.Kind)
+	}
+	if spec.Target.Name == "" {
+		return 0, fmt.Errorf("spec.target.name is required")
+	}
+	if spec.Interval == "" {
+		return defaultInterval, nil
+	}
+	interval, err := time.ParseDuration(spec.Interval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("spec.interval must be a positive duration, got %q", spec.Interval)
+	}
+	return interval, nil
+}
+
+// sync writes the current secret to the target of watch and records the
+// outcome in the status of the VaultWatch. The secret is read again, so
+// concurrent syncs all converge on the latest data.
+func (o *KubernetesOperator) sync(ctx context.Context, watch *operatorWatch) error {
+	watch.syncMu.Lock()
+	defer watch.syncMu.Unlock()
+
+	resource := watch.resource
+	data, err := watch.watcher.readData()
+	if err != nil {
+		err = fmt.Errorf("failed to read %s: %w", resource.Spec.Path, err)
+		o.updateStatus(ctx, resource, VaultWatchStatus{Error: watch.watcher.redact(err.Error())})
+		return err
+	}
+	hash, err := CalculateHash(data)
+	if err != nil {
+		return fmt.Errorf("failed to calculate hash: %w", err)
+	}
+
+	target := resource.Spec.Target
+	switch target.Kind {
+	case "Secret":
+		err = o.writeData(ctx, resource.Metadata.Namespace, "Secret", target.Name, data)
+	case "ConfigMap":
+		err = o.writeData(ctx, resource.Metadata.Namespace, "ConfigMap", target.Name, data)
+	case "Annotation":
+		err = o.annotate(ctx, resource.Metadata.Namespace, target, hash)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to update %s %s: %w", target.Kind, target.Name, watch.watcher.redactError(err))
+		o.updateStatus(ctx, resource, VaultWatchStatus{Error: err.Error()})
+		return err
+	}
+
+	o.updateStatus(ctx, resource, VaultWatchStatus{Hash: hash, LastSyncTime: time.Now().UTC().Format(time.RFC3339)})
+	return nil
+}
+
+// writeData replaces the data of a Secret or ConfigMap, creating it if missing
+func (o *KubernetesOperator) writeData(ctx context.Context, namespace, kind, name string, data map[string]interface{}) error {
+	entries := make(map[string]string, len(data))
+	for key, value := range data {
+		text, ok := value.(string)
+		if !ok {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode key %q: %w", key, err)
+			}
+			text = string(encoded)
+		}
+		if kind == "Secret" {
+			text = base64.StdEncoding.EncodeToString([]byte(text))
+		}
+		entries[key] = text
+	}
+
+	resource := "configmaps"
+	if kind == "Secret" {
+		resource = "secrets"
+	}
+	objectURL := o.objectURL("/api/v1", namespace, resource, name)
+	var object map[string]interface{}
+	status, err := o.do(ctx, http.MethodGet, objectURL, nil, &object)
+	if err != nil && status != http.StatusNotFound {
+		return err
+	}
+
+	if status == http.StatusNotFound {
+		object = map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels":    map[string]string{"app.kubernetes.io/managed-by": "vault-watcher"},
+			},
+			"data": entries,
+		}
+		_, err = o.do(ctx, http.MethodPost, o.objectURL("/api/v1", namespace, resource, ""), object, nil)
+		return err
+	}
+
+	// The resource version in the metadata makes the update fail on conflict
+	object["data"] = entries
+	_, err = o.do(ctx, http.MethodPut, objectURL, object, nil)
+	return err
+}
+
+// annotate sets the annotation of a workload's pod template to hash
+func (o *KubernetesOperator) annotate(ctx context.Context, namespace string, target VaultWatchTarget, hash string) error {
+	apiVersion, resource, annotation := target.APIVersion, target.Resource, target.Annotation
+	if apiVersion == "" {
+		apiVersion = "apps/v1"
+	}
+	if resource == "" {
+		resource = "deployments"
+	}
+	if annotation == "" {
+		annotation = defaultVaultWatchAnnotation
+	}
+
+	_, err := o.patch(ctx, o.objectURL("/apis/"+apiVersion, namespace, resource, target.Name), podTemplateAnnotation(annotation, hash))
+	return err
+}
+
+// updateStatus records status on the VaultWatch. Failures are logged, since
+// the watch itself keeps working.
+func (o *KubernetesOperator) updateStatus(ctx context.Context, resource VaultWatch, status VaultWatchStatus) {
+	status.ObservedGeneration = resource.Metadata.Generation
+	statusURL := o.objectURL(vaultWatchAPI, resource.Metadata.Namespace, "vaultwatches", resource.Metadata.Name) + "/status"
+	fields := map[string]interface{}{"observedGeneration": status.ObservedGeneration}
+	if status.Error != "" {
+		// The last successful sync is kept
+		fields["error"] = status.Error
+	} else {
+		// Null clears the error of an earlier sync
+		fields["hash"], fields["lastSyncTime"], fields["error"] = status.Hash, status.LastSyncTime, nil
+	}
+	patch := map[string]interface{}{"status": fields}
+	code, err := o.patch(ctx, statusURL, patch)
+	if code == http.StatusNotFound {
+		err = fmt.Errorf("%w (the resource was deleted, or the VaultWatch CRD doesn't enable the status subresource)", err)
+	}
+	if err != nil {
+		slog.Error("Error updating the status of vault watch", "namespace", resource.Metadata.Namespace, "name", resource.Metadata.Name, "error", err)
+	}
+}
+
+// stopAll stops every watcher
+func (o *KubernetesOperator) stopAll() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for key, watch := range o.watches {
+		if watch.watcher != nil {
+			watch.watcher.Stop()
+		}
+		delete(o.watches, key)
+	}
+}
+
+func (o *KubernetesOperator) vaultWatchesURL() string {
+	if o.config.AllNamespaces {
+		return o.config.APIServer + vaultWatchAPI + "/vaultwatches"
+	}
+	return o.objectURL(vaultWatchAPI, o.config.Namespace, "vaultwatches", "")
+}
+
+// objectURL returns the URL of a namespaced object, or of its collection
+// without a name
+func (o *KubernetesOperator) objectURL(api, namespace, resource, name string) string {
+	objectURL := fmt.Sprintf("%s%s/namespaces/%s/%s", o.config.APIServer, api, url.PathEscape(namespace), resource)
+	if name != "" {
+		objectURL += "/" + url.PathEscape(name)
+	}
+	return objectURL
+}
+
+func (o *KubernetesOperator) do(ctx context.Context, method, requestURL string, body, out interface{}) (int, error) {
+	return doJSON(ctx, o.client, method, requestURL, body, out, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+o.config.Token)
+	})
+}
+
+func (o *KubernetesOperator) patch(ctx context.Context, requestURL string, patch interface{}) (int, error) {
+	return patchJSON(ctx, o.client, o.config.Token, requestURL, patch)
+}
@@ -0,0 +1,229 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOperatorAPI implements the Kubernetes endpoints used by
+// KubernetesOperator in the namespace "apps"
+type fakeOperatorAPI struct {
+	mu       sync.Mutex
+	watches  []VaultWatch
+	objects  map[string]map[string]interface{} // By URL path
+	statuses map[string]map[string]interface{} // Status patches by VaultWatch name
+	patches  map[string]string                 // Merge patches by URL path
+}
+
+func (f *fakeOperatorAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer sa-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const watches = "/apis/vaultwatcher.io/v1alpha1/namespaces/apps/vaultwatches"
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == watches:
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": f.watches})
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, watches+"/") && strings.HasSuffix(r.URL.Path, "/status"):
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, watches+"/"), "/status")
+		f.statuses[name] = body["status"].(map[string]interface{})
+	case r.Method == http.MethodPatch:
+		patch, _ := json.Marshal(body)
+		f.patches[r.URL.Path] = string(patch)
+	case r.Method == http.MethodGet:
+		object, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(object)
+	case r.Method == http.MethodPost:
+		metadata := body["metadata"].(map[string]interface{})
+		f.objects[r.URL.Path+"/"+metadata["name"].(string)] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		f.objects[r.URL.Path] = body
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeOperatorAPI) object(path string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[path]
+}
+
+func (f *fakeOperatorAPI) status(name string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.statuses[name]
+}
+
+func newVaultWatch(name string, generation int64, spec VaultWatchSpec) VaultWatch {
+	var watch VaultWatch
+	watch.Metadata.Name, watch.Metadata.Namespace, watch.Metadata.Generation = name, "apps", generation
+	watch.Spec = spec
+	return watch
+}
+
+func TestValidateVaultWatch(t *testing.T) {
+	target := VaultWatchTarget{Kind: "Secret", Name: "billing"}
+	tests := []struct {
+		name    string
+		spec    VaultWatchSpec
+		want    time.Duration
+		wantErr string
+	}{
+		{name: "default interval", spec: VaultWatchSpec{Path: "secret/data/billing", Target: target}, want: time.Minute},
+		{name: "interval", spec: VaultWatchSpec{Path: "secret/data/billing", Interval: "30s", Target: target}, want: 30 * time.Second},
+		{name: "no path", spec: VaultWatchSpec{Target: target}, wantErr: "spec.path is required"},
+		{name: "bad kind", spec: VaultWatchSpec{Path: "secret/data/billing", Target: VaultWatchTarget{Kind: "Pod", Name: "x"}},
+			wantErr: `spec.target.kind must be Secret, ConfigMap or Annotation, got "Pod"`},
+		{name: "no name", spec: VaultWatchSpec{Path: "secret/data/billing", Target: VaultWatchTarget{Kind: "ConfigMap"}}, wantErr: "spec.target.name is required"},
+		{name: "bad interval", spec: VaultWatchSpec{Path: "secret/data/billing", Interval: "-1s", Target: target},
+			wantErr: `spec.interval must be a positive duration, got "-1s"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, err := validateVaultWatch(tt.spec, time.Minute)
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "validateVaultWatch()")
+				return
+			}
+			AssertNoError(t, err, "validateVaultWatch()")
+			AssertStringEquals(t, interval.String(), tt.want.String(), "interval")
+		})
+	}
+}
+
+func TestKubernetesOperator(t *testing.T) {
+	var vaultMu sync.Mutex
+	password := "one"
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vaultMu.Lock()
+		defer vaultMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data": {"data": {"password": %q, "port": 5432}, "metadata": {"version": 1}}}`, password)
+	}))
+	defer vault.Close()
+
+	api := &fakeOperatorAPI{objects: map[string]map[string]interface{}{}, statuses: map[string]map[string]interface{}{}, patches: map[string]string{}}
+	api.watches = []VaultWatch{
+		newVaultWatch("billing", 1, VaultWatchSpec{Path: "secret/data/billing", Interval: "10ms", Target: VaultWatchTarget{Kind: "Secret", Name: "billing-db"}}),
+		newVaultWatch("rollout", 1, VaultWatchSpec{Path: "secret/data/billing", Target: VaultWatchTarget{Kind: "Annotation", Name: "billing-api"}}),
+		newVaultWatch("broken", 1, VaultWatchSpec{Path: "secret/data/billing", Target: VaultWatchTarget{Kind: "Pod", Name: "x"}}),
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	operator, err := NewKubernetesOperator(KubernetesOperatorConfig{
+		Vault:     &VaultConfig{Host: vault.URL, Token: "t"},
+		Namespace: "apps", APIServer: server.URL, Token: "sa-token", HTTPClient: server.Client(),
+	})
+	AssertNoError(t, err, "NewKubernetesOperator()")
+	ctx := context.Background()
+	AssertNoError(t, operator.reconcile(ctx), "reconcile()")
+	defer operator.stopAll()
+	AssertStringEquals(t, fmt.Sprint(operator.Watches()), "[apps/billing apps/rollout]", "Watches()")
+
+	secretPath := "/api/v1/namespaces/apps/secrets/billing-db"
+	secretData := func() string {
+		object := api.object(secretPath)
+		if object == nil {
+			return ""
+		}
+		data := object["data"].(map[string]interface{})
+		keys := make([]string, 0, len(data))
+		for key, value := range data {
+			decoded, _ := base64.StdEncoding.DecodeString(value.(string))
+			keys = append(keys, key+"="+string(decoded))
+		}
+		sort.Strings(keys)
+		return strings.Join(keys, " ")
+	}
+	AssertStringEquals(t, secretData(), "password=one port=5432", "Secret data")
+	AssertStringEquals(t, fmt.Sprint(api.object(secretPath)["metadata"].(map[string]interface{})["labels"]), "map[app.kubernetes.io/managed-by:vault-watcher]", "Secret labels")
+	AssertBoolEquals(t, api.status("billing")["hash"] != nil, true, "status hash")
+	AssertStringEquals(t, fmt.Sprint(api.status("broken")["error"]), `spec.target.kind must be Secret, ConfigMap or Annotation, got "Pod"`, "status error")
+
+	api.mu.Lock()
+	patch := api.patches["/apis/apps/v1/namespaces/apps/deployments/billing-api"]
+	api.mu.Unlock()
+	AssertBoolEquals(t, strings.HasPrefix(patch, `{"spec":{"template":{"metadata":{"annotations":{"vaultwatcher.io/secret-hash":"`), true, "annotation patch "+patch)
+
+	// A change in Vault updates the Secret
+	vaultMu.Lock()
+	password = "two"
+	vaultMu.Unlock()
+	waitFor(t, time.Second, func() bool { return secretData() == "password=two port=5432" }, "updated Secret")
+	AssertStringEquals(t, secretData(), "password=two port=5432", "Secret data after the change")
+
+	// Deleted and changed resources
+	api.mu.Lock()
+	api.watches = []VaultWatch{
+		newVaultWatch("billing", 2, VaultWatchSpec{Path: "secret/data/billing", Target: VaultWatchTarget{Kind: "ConfigMap", Name: "billing"}}),
+	}
+	api.mu.Unlock()
+	AssertNoError(t, operator.reconcile(ctx), "reconcile() after the changes")
+	AssertStringEquals(t, fmt.Sprint(operator.Watches()), "[apps/billing]", "Watches() after the changes")
+	configMap := api.object("/api/v1/namespaces/apps/configmaps/billing")
+	AssertStringEquals(t, fmt.Sprint(configMap["data"]), "map[password:two port:5432]", "ConfigMap data")
+	AssertStringEquals(t, fmt.Sprint(api.status("billing")["observedGeneration"]), "2", "observedGeneration")
+}
+
+func TestKubernetesOperator_GenerationWithoutSpecChange(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data": {"data": {"password": "one"}, "metadata": {"version": 1}}}`)
+	}))
+	defer vault.Close()
+
+	spec := VaultWatchSpec{Path: "secret/data/billing", Target: VaultWatchTarget{Kind: "Secret", Name: "billing-db"}}
+	api := &fakeOperatorAPI{objects: map[string]map[string]interface{}{}, statuses: map[string]map[string]interface{}{}, patches: map[string]string{}}
+	api.watches = []VaultWatch{newVaultWatch("billing", 1, spec)}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	operator, err := NewKubernetesOperator(KubernetesOperatorConfig{
+		Vault:     &VaultConfig{Host: vault.URL, Token: "t"},
+		Namespace: "apps", APIServer: server.URL, Token: "sa-token", HTTPClient: server.Client(),
+	})
+	AssertNoError(t, err, "NewKubernetesOperator()")
+	ctx := context.Background()
+	AssertNoError(t, operator.reconcile(ctx), "reconcile()")
+	defer operator.stopAll()
+	watcher := operator.watches["apps/billing"].watcher
+
+	// As when the CRD lacks the status subresource and a status update bumped
+	// the generation: the watcher keeps running
+	api.mu.Lock()
+	api.watches = []VaultWatch{newVaultWatch("billing", 2, spec)}
+	api.mu.Unlock()
+	AssertNoError(t, operator.reconcile(ctx), "reconcile() after the generation changed")
+	AssertBoolEquals(t, operator.watches["apps/billing"].watcher == watcher, true, "watcher kept")
+
+	AssertNoError(t, operator.sync(ctx, operator.watches["apps/billing"]), "sync()")
+	AssertStringEquals(t, fmt.Sprint(api.status("billing")["observedGeneration"]), "2", "observedGeneration")
+}
+
+func TestNewKubernetesOperator_Errors(t *testing.T) {
+	_, err := NewKubernetesOperator(KubernetesOperatorConfig{})
+	AssertError(t, err, "vault host and token are required", "NewKubernetesOperator() without vault")
+}
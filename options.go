@@ -0,0 +1,561 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Option configures optional Watcher behaviour
+type Option func(*Watcher)
+
+// WithNotifier registers a notifier that is called for every applied change.
+// It can be given multiple times to fan out to several notifiers.
+func WithNotifier(notifier Notifier) Option {
+	return func(w *Watcher) {
+		if notifier != nil {
+			w.notifiers = append(w.notifiers, notifier)
+		}
+	}
+}
+
+// WithNotifyTimeout limits how long each notifier may take to handle a change
+// (default 15s). Notifiers run on the check path, so a slow webhook would
+// otherwise hold up the next check. A notifier that ignores its context's
+// deadline is not interrupted.
+func WithNotifyTimeout(timeout time.Duration) Option {
+	return func(w *Watcher) {
+		if timeout > 0 {
+			w.notifyTimeout = timeout
+		}
+	}
+}
+
+// WithFailureThreshold sets how many consecutive failed checks mark the watcher
+// as unhealthy (default 3). Values below 1 are ignored.
+func WithFailureThreshold(threshold int) Option {
+	return func(w *Watcher) {
+		if threshold >= 1 {
+			w.failureThreshold = threshold
+		}
+	}
+}
+
+// WithChurnLimit flags unusual churn when more than maxChanges changes are
+// applied within window. Notifiers implementing ChurnNotifier are told when
+// churn starts and when it has settled again.
+func WithChurnLimit(maxChanges int, window time.Duration) Option {
+	return func(w *Watcher) {
+		if maxChanges >= 1 && window > 0 {
+			w.churnLimit = maxChanges
+			w.churnWindow = window
+		}
+	}
+}
+
+// WithSealAwareness makes the watcher poll sys/health before each check. While
+// Vault is sealed, uninitialized or a standby that can't serve reads, secret
+// reads are suspended and notifiers implementing AvailabilityNotifier are told
+// instead of seeing a stream of read errors.
+func WithSealAwareness() Option {
+	return func(w *Watcher) {
+		w.sealAware = true
+	}
+}
+
+// WithLeaderElection makes replicas of the same watcher elect a leader through
+// lock, so only one instance polls Vault. The leader's notifiers broadcast
+// changes, e.g. with a PublisherNotifier; other instances pass the events they
+// receive to ApplyRemoteChange. id identifies this instance (default hostname
+// and PID) and ttl is how long leadership lasts without renewal (default 15s).
+func WithLeaderElection(lock Lock, id string, ttl time.Duration) Option {
+	return func(w *Watcher) {
+		if lock == nil {
+			return
+		}
+		if id == "" {
+			hostname, _ := os.Hostname()
+			id = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+		}
+		if ttl <= 0 {
+			ttl = defaultLockTTL
+		}
+		w.lock, w.lockID, w.lockTTL = lock, id, ttl
+	}
+}
+
+// WithStateStore shares handled changes through store. When several instances
+// watch the same path, only the first to claim a change runs the onChange
+// callback and notifiers; the others adopt the new hash silently. A claim is
+// undone when the callback fails so the change is retried.
+func WithStateStore(store StateStore) Option {
+	return func(w *Watcher) {
+		if store != nil {
+			w.stateStore = store
+		}
+	}
+}
+
+// WithRateLimiter makes every request of the watcher wait for limiter. Share
+// one limiter between watchers to keep them within a common budget.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(w *Watcher) {
+		if limiter != nil {
+			w.rateLimiter = limiter
+		}
+	}
+}
+
+// WithSecretReader makes the watcher read its path through reader instead of
+// the Vault client, e.g. a fake in unit tests
+func WithSecretReader(reader SecretReader) Option {
+	return func(w *Watcher) {
+		if reader != nil {
+			w.reader = reader
+		}
+	}
+}
+
+// WithPinnedVersion makes the watcher read version of a KV v2 secret instead of
+// the latest. Newer versions are reported to notifiers implementing
+// NewVersionNotifier rather than adopted; PinVersion promotes them.
+func WithPinnedVersion(version int) Option {
+	return func(w *Watcher) {
+		if version >= 1 {
+			w.pinnedVersion = version
+		}
+	}
+}
+
+// WithBaselineHash compares every check against an expected hash, e.g. one
+// committed in git, and reports deviations to notifiers implementing
+// DriftNotifier. Changes are still followed and passed to onChange.
+func WithBaselineHash(hash string) Option {
+	return func(w *Watcher) {
+		w.baselineHash = hash
+	}
+}
+
+// WithExpectedValues compares every check against the key/value pairs the
+// secret is expected to hold and reports drift to notifiers implementing
+// DriftNotifier, naming the missing, extra and mismatched keys. Only hashes of
+// the values are kept. Changes are still followed and passed to onChange.
+func WithExpectedValues(values map[string]interface{}) Option {
+	return func(w *Watcher) {
+		w.expectedValues = values
+	}
+}
+
+// WithExpectedValuesFile is WithExpectedValues with the values read from a
+// JSON, YAML or dotenv file when the watcher is created, e.g. one committed
+// in git. The format is detected from the extension.
+func WithExpectedValuesFile(path string) Option {
+	return func(w *Watcher) {
+		w.expectedValuesFile = path
+	}
+}
+
+// WithCooldown runs the onChange callback at most once per cooldown, e.g. for
+// handlers that restart the service. A change detected during the cooldown is
+// applied when it elapses, with the data current at that time.
+func WithCooldown(cooldown time.Duration) Option {
+	return func(w *Watcher) {
+		if cooldown > 0 {
+			w.cooldown = cooldown
+		}
+	}
+}
+
+// WithQuietWindows defers onChange callbacks while any of the windows is open,
+// e.g. during maintenance. Changes are still detected and the latest one is
+// applied when the windows close.
+func WithQuietWindows(windows ...QuietWindow) Option {
+	return func(w *Watcher) {
+		for _, window := range windows {
+			if window != nil {
+				w.quietWindows = append(w.quietWindows, window)
+			}
+		}
+	}
+}
+
+// WithCronSchedule checks at the times matching schedule instead of every
+// check interval, e.g. only during business hours. Create it with ParseCron.
+func WithCronSchedule(schedule *CronSchedule) Option {
+	return func(w *Watcher) {
+		if schedule != nil {
+			w.schedule = schedule
+		}
+	}
+}
+
+// WithCustomMetadata hashes a KV v2 secret's custom_metadata along with its
+// data, so ownership or label changes also run the onChange callback. The
+// metadata appears under CustomMetadataKey in ChangedKeys.
+func WithCustomMetadata() Option {
+	return func(w *Watcher) {
+		w.includeCustomMetadata = true
+	}
+}
+
+// WithMetadataOnly watches only a KV v2 secret's custom_metadata, read from
+// <mount>/metadata/<key>, and ignores its data. This suits workflows that tag
+// secrets to coordinate rollouts, and only needs read access to the metadata.
+func WithMetadataOnly() Option {
+	return func(w *Watcher) {
+		w.metadataOnly = true
+	}
+}
+
+// WithJSONPatch adds an RFC 6902 JSON Patch of each change to ChangeEvent, so
+// downstream systems can audit the exact change. The watcher then keeps the
+// last applied data in memory. Values in the patch are replaced with Redacted
+// unless WithPatchValues is set too.
+func WithJSONPatch() Option {
+	return func(w *Watcher) {
+		w.jsonPatch = true
+	}
+}
+
+// WithPatchValues keeps secret values in the JSON patches of WithJSONPatch, so
+// downstream systems can apply them. Notifiers then receive secret values;
+// values of keys passed to WithRedactedKeys are still replaced.
+func WithPatchValues() Option {
+	return func(w *Watcher) {
+		w.patchValues = true
+	}
+}
+
+// WithChangeHistory keeps the last n applied changes for ChangeHistory
+func WithChangeHistory(n int) Option {
+	return func(w *Watcher) {
+		if n > 0 {
+			w.historyLength = n
+		}
+	}
+}
+
+// WithRequestTimeout limits how long each Vault API call of the watcher may
+// take, independent of the check interval, so a hung connection can't hold up
+// a check. It covers reads, health checks and writes. Stop interrupts
+// in-flight requests with or without a timeout.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(w *Watcher) {
+		if timeout > 0 {
+			w.requestTimeout = timeout
+		}
+	}
+}
+
+// WithRetryPolicy sets how the watcher's client retries requests that failed
+// with a connection error or a 5xx response, e.g. more patiently against a
+// remote cluster or not at all in tests
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(w *Watcher) {
+		w.retryPolicy = &policy
+	}
+}
+
+// WithConsistency sets how reads behave against Vault Enterprise performance
+// standbys. With any mode other than ConsistencyDefault, a read returning an
+// older KV v2 version than the one applied is ignored as stale, so a lagging
+// standby can't make change detection flap.
+func WithConsistency(consistency Consistency) Option {
+	return func(w *Watcher) {
+		w.consistency = consistency
+	}
+}
+
+// WithActiveNodeDiscovery makes the watcher ask sys/leader for the active
+// Vault node at startup and before every check, and move its client there
+// when leadership changes. Point Host at any node of the cluster; it can't be
+// combined with FailoverHosts or a unix socket address.
+func WithActiveNodeDiscovery() Option {
+	return func(w *Watcher) {
+		w.followActive = true
+	}
+}
+
+// WithRedactedKeys marks keys whose values need extra care. Their values are
+// replaced with Redacted in JSON patches, and scrubbed from errors and logs
+// however short they are; other values are scrubbed once they are at least
+// six characters long.
+func WithRedactedKeys(keys ...string) Option {
+	return func(w *Watcher) {
+		if w.redactedKeys == nil {
+			w.redactedKeys = map[string]bool{}
+		}
+		for _, key := range keys {
+			w.redactedKeys[key] = true
+		}
+	}
+}
+
+// WithHashOnly guarantees the watcher never retains plaintext secret data: it
+// hashes what it reads and discards it, as it does by default. Features that
+// keep data, such as WithJSONPatch and NewFileSync, are refused.
+func WithHashOnly() Option {
+	return func(w *Watcher) {
+		w.hashOnly = true
+	}
+}
+
+// WithSecureMemory keeps data the watcher has to retain, such as the applied
+// data for WithJSONPatch, in a buffer locked against swapping where the OS
+// allows it. The buffer is zeroed when the data is replaced and on Stop.
+func WithSecureMemory() Option {
+	return func(w *Watcher) {
+		w.secureMemory = true
+	}
+}
+
+// WithTransitHMAC has Vault compute the hashes with the transit key at
+// keyPath, e.g. "transit/keys/watcher", instead of SHA-256 in this process.
+// The secret is still read, but its hashes, which are stored in state stores
+// and sent in events, can't be brute-forced without the key. The key version
+// of the first hash is pinned, so rotating the key isn't seen as a change.
+func WithTransitHMAC(keyPath string) Option {
+	return func(w *Watcher) {
+		w.hmacKeyPath = keyPath
+	}
+}
+
+// WithTransitDecrypt decrypts values stored as transit ciphertext, such as
+// "vault:v1:...", with the transit key at keyPath, e.g. "transit/keys/app",
+// before they are hashed or handed out. Nested values are decrypted too.
+// When a value can't be decrypted the check fails, nothing is delivered and
+// notifiers implementing DecryptFailureNotifier are told which values failed.
+func WithTransitDecrypt(keyPath string) Option {
+	return func(w *Watcher) {
+		w.decryptKeyPath = keyPath
+	}
+}
+
+// WithSchema validates every changed secret against a JSON Schema document.
+// A change that fails is rejected: onChange isn't called, the hash isn't
+// advanced and notifiers implementing ValidationFailureNotifier receive a
+// ValidationFailedEvent, so a bad write to Vault doesn't reach running
+// services. The keywords type, properties, required, additionalProperties,
+// items, enum, const, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, minItems and maxItems are supported.
+func WithSchema(schema []byte) Option {
+	return func(w *Watcher) {
+		w.schemaDocument = schema
+	}
+}
+
+// WithSecretValidator rejects changed secrets for which validate returns an
+// error, like WithSchema. Both can be used together.
+func WithSecretValidator(validate func(data map[string]interface{}) error) Option {
+	return func(w *Watcher) {
+		w.validator = validate
+	}
+}
+
+// WithApprovalHook asks hook before a change is applied, e.g. to require a
+// human approval in an external system. Returning false defers the change:
+// onChange isn't called, the hash isn't advanced and the hook is asked again
+// by the next check, with the newest secret. A rejected change is one the hook
+// never approves. An error fails the check. The hook runs after validation
+// and before the change is claimed in a state store.
+func WithApprovalHook(hook func(event ChangeEvent) (approve bool, err error)) Option {
+	return func(w *Watcher) {
+		w.approvalHook = hook
+	}
+}
+
+// WithMetricsSink sends check, change and callback metrics to sink as they
+// happen, e.g. a StatsDSink. It can be used more than once.
+func WithMetricsSink(sink MetricsSink) Option {
+	return func(w *Watcher) {
+		w.metricsSinks = append(w.metricsSinks, sink)
+	}
+}
+
+// WithChangeDelay waits delay after a change is detected before applying it,
+// like a canary. If the secret is reverted meanwhile the change is dropped,
+// so writes that are undone right away never reach onChange. A newer change
+// restarts the delay. The change is applied with the data current when the
+// delay has passed.
+func WithChangeDelay(delay time.Duration) Option {
+	return func(w *Watcher) {
+		w.changeDelay = delay
+	}
+}
+
+// WithParticipants applies each change in two phases across participants:
+// all of them prepare it, then onChange runs and all of them commit it. If a
+// participant fails to prepare, or onChange fails, the participants that
+// prepared roll it back and the change fails like a failing onChange, so it
+// is retried by the next check. Participants prepare and commit in the order
+// given and roll back in reverse.
+func WithParticipants(participants ...ChangeParticipant) Option {
+	return func(w *Watcher) {
+		w.participants = append(w.participants, participants...)
+	}
+}
+
+// WithDeadLetter gives up on a change once onChange failed maxAttempts checks
+// in a row (default 3) and sends its event to sink, so it can be replayed
+// later with ReplayDeadLetter instead of blocking newer changes. Without it,
+// a failing change is retried by every check.
+func WithDeadLetter(sink DeadLetterSink, maxAttempts int) Option {
+	return func(w *Watcher) {
+		w.deadLetter = sink
+		w.deadLetterAttempts = maxAttempts
+		if maxAttempts <= 0 {
+			w.deadLetterAttempts = defaultDeadLetterAttempts
+		}
+	}
+}
+
+// WithUnsealWait makes a watcher whose read finds Vault sealed or uninitialized
+// wait for unseal instead of failing every check: sys/health is polled every
+// interval (default 1m) until Vault is unsealed, then the normal cadence
+// resumes. Notifiers implementing UnsealWaitNotifier are told when the wait
+// starts and ends. Unlike WithSealAwareness, no health check is made while
+// Vault is unsealed. Cron schedules keep their schedule while waiting.
+func WithUnsealWait(interval time.Duration) Option {
+	return func(w *Watcher) {
+		w.unsealWait = interval
+		if interval <= 0 {
+			w.unsealWait = defaultUnsealWait
+		}
+	}
+}
+
+// WithSystemdNotify integrates with a systemd service of Type=notify: READY=1
+// is sent once the first fetch succeeded and, with WatchdogSec set, the
+// watchdog is pet after every check cycle while the watcher is healthy, so
+// systemd restarts a process whose watcher stalled or keeps failing. It does
+// nothing outside systemd.
+func WithSystemdNotify() Option {
+	return func(w *Watcher) {
+		w.systemd = newSystemdNotifier()
+	}
+}
+
+// WithFileTemplates renders the secret to files, like Vault Agent templates
+// but with the watcher's change semantics: files are written at Start and
+// for every applied change, before onChange runs, all replaced together
+// once every template rendered, and written back if onChange fails. A
+// template that fails, e.g. on a missing key, fails the change, which is
+// retried like a failing callback. The
+// secret's data is the template's dot, so values are {{ .password }} or
+// {{ index . "db-host" }}, and {{ json .database }} writes a value as JSON.
+func WithFileTemplates(templates ...FileTemplate) Option {
+	return func(w *Watcher) {
+		w.fileTemplates = append(w.fileTemplates, templates...)
+	}
+}
+
+// WithExpiryMonitoring looks for values of the secret that expire, such as
+// certificates or tokens, on every check, and reports them to notifiers
+// implementing ExpiryNotifier as they come within each lead time (default 30
+// days, 7 days and 1 day) of their expiry, and once they expired. Parsers
+// interpret the values, the first that understands one wins; without any,
+// CertificateExpiry, JWTExpiry and TimestampExpiry are used.
+func WithExpiryMonitoring(leadTimes []time.Duration, parsers ...ExpiryParser) Option {
+	return func(w *Watcher) {
+		w.expiryLeadTimes = nil
+		for _, lead := range leadTimes {
+			if lead > 0 {
+				w.expiryLeadTimes = append(w.expiryLeadTimes, lead)
+			}
+		}
+		if len(w.expiryLeadTimes) == 0 {
+			w.expiryLeadTimes = defaultExpiryLeadTimes
+		}
+		w.expiryParsers = parsers
+		if len(parsers) == 0 {
+			w.expiryParsers = []ExpiryParser{CertificateExpiry, JWTExpiry, TimestampExpiry}
+		}
+		w.expiries = make(map[string]*expiryState)
+	}
+}
+
+// WithTokenMonitoring looks the watcher's token up every interval (default 5
+// minutes) and reports its remaining TTL in Status and the Prometheus
+// metrics. When the TTL falls within warning (default 24 hours), notifiers
+// implementing TokenExpiryNotifier receive a TokenExpiringSoonEvent, so a
+// static token can be rotated before the watcher loses access. The token
+// needs no policy for this.
+func WithTokenMonitoring(interval, warning time.Duration) Option {
+	return func(w *Watcher) {
+		w.tokenCheckInterval = interval
+		if interval <= 0 {
+			w.tokenCheckInterval = defaultTokenCheckInterval
+		}
+		w.tokenWarning = warning
+		if warning <= 0 {
+			w.tokenWarning = defaultTokenWarning
+		}
+	}
+}
+
+// WithKeyFilter limits change detection to the keys matching any of filters:
+// changes to other keys of the secret are ignored, and events list only the
+// matching keys. The whole secret is still read, bound and rendered. A value
+// path selects the top-level key it starts with.
+func WithKeyFilter(filters ...KeyFilter) Option {
+	return func(w *Watcher) {
+		w.keyFilters = append(w.keyFilters, filters...)
+	}
+}
+
+// WithHashAlgorithm sets the digest secrets are compared by, HashSHA256 by
+// default. Hashes in events and state stores use it, so instances sharing a
+// state store must agree on it. It can't be combined with WithTransitHMAC.
+func WithHashAlgorithm(algorithm HashAlgorithm) Option {
+	return func(w *Watcher) {
+		w.hashAlgorithm = algorithm
+	}
+}
+
+// WithPriority ranks the watcher against others sharing its RateLimiter, whose
+// waiting requests are served by priority, and against the other paths of a
+// WatcherGroup, which checks higher priorities first and defers low-priority
+// paths while it is behind. Critical secrets such as TLS certificates can so
+// be kept on time when Vault is rate limited or slow.
+func WithPriority(priority Priority) Option {
+	return func(w *Watcher) {
+		w.priority = priority
+	}
+}
+
+// WithScheduler checks the watcher from a Scheduler shared with other watchers
+// instead of its own goroutine and ticker. Watchers with a cron schedule or a
+// source that notifies of changes keep their own goroutine.
+func WithScheduler(scheduler *Scheduler) Option {
+	return func(w *Watcher) {
+		w.scheduler = scheduler
+	}
+}
+
+// WithBinaryKeys marks the keys holding large base64 blobs, such as keystores
+// or archives. Their values are decoded as they are streamed into the hash
+// rather than hashed as JSON text, and the secret's hash covers their digests.
+// A value that isn't standard base64 is hashed like any other.
+func WithBinaryKeys(filters ...KeyFilter) Option {
+	return func(w *Watcher) {
+		w.binaryKeys = append(w.binaryKeys, filters...)
+	}
+}
+
+// WithoutBinaryDiffs leaves the keys of WithBinaryKeys out of JSON patches, so
+// blobs are neither copied into change events nor kept in memory for them.
+// Changed blobs are still listed in ChangedKeys.
+func WithoutBinaryDiffs() Option {
+	return func(w *Watcher) {
+		w.skipBinaryDiffs = true
+	}
+}
+
+// WithFatalErrors makes checks failing with one of errs, matched with
+// errors.Is, end Run, e.g. ErrPermissionDenied for a watcher whose policy
+// won't be fixed without a restart. ErrCubbyholeInaccessible is always fatal.
+func WithFatalErrors(errs ...error) Option {
+	return func(w *Watcher) {
+		w.fatalErrors = append(w.fatalErrors, errs...)
+	}
+}
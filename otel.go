@@ -0,0 +1,264 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOTLPTimeout = 10 * time.Second
+	otelScopeName      = "github.com/naman-dave/vault-watcher"
+
+	// Severity numbers of the OpenTelemetry log data model
+	otelSeverityInfo  = 9
+	otelSeverityWarn  = 13
+	otelSeverityError = 17
+)
+
+// OTLPLogConfig holds the configuration for an OTLPLogNotifier. Empty fields
+// fall back to the standard OpenTelemetry environment variables.
+type OTLPLogConfig struct {
+	// Endpoint is the base URL of an OTLP/HTTP receiver such as the
+	// OpenTelemetry Collector, e.g. "http://localhost:4318"; records are
+	// POSTed to its /v1/logs. Defaults to OTEL_EXPORTER_OTLP_LOGS_ENDPOINT,
+	// used as is, or OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint string
+	// Headers are sent with every request, e.g. for authentication. Defaults
+	// to OTEL_EXPORTER_OTLP_HEADERS.
+	Headers map[string]string
+	// ServiceName is the service.name resource attribute. Defaults to
+	// OTEL_SERVICE_NAME, or "vault-watcher".
+	ServiceName string
+	Timeout     time.Duration // Timeout of a single request (default 10s)
+	HTTPClient  *http.Client  // Optional custom HTTP client
+}
+
+// OTLPLogNotifier exports change events, health transitions, rejected changes
+// and decryption failures as OpenTelemetry log records over OTLP/HTTP, so they
+// reach the same backend as the application's traces and logs. Secret values
+// are never included.
+type OTLPLogNotifier struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	timeout     time.Duration
+	client      *http.Client
+}
+
+// NewOTLPLogNotifier creates a notifier exporting log records to an OTLP/HTTP
+// receiver. Register it with WithNotifier.
+func NewOTLPLogNotifier(config OTLPLogConfig) (*OTLPLogNotifier, error) {
+	logsURL := ""
+	switch {
+	case config.Endpoint != "":
+		logsURL = strings.TrimRight(config.Endpoint, "/") + "/v1/logs"
+	case getEnv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "") != "":
+		logsURL = getEnv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "")
+	case getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "") != "":
+		logsURL = strings.TrimRight(getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""), "/") + "/v1/logs"
+	default:
+		return nil, fmt.Errorf("OTLP endpoint is required")
+	}
+	if _, err := url.ParseRequestURI(logsURL); err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+
+	headers := config.Headers
+	if headers == nil {
+		var err error
+		if headers, err = parseOTLPHeaders(getEnv("OTEL_EXPORTER_OTLP_HEADERS", "")); err != nil {
+			return nil, err
+		}
+	}
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = getEnv("OTEL_SERVICE_NAME", "vault-watcher")
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultOTLPTimeout
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &OTLPLogNotifier{
+		url:         logsURL,
+		headers:     headers,
+		serviceName: serviceName,
+		timeout:     timeout,
+		client:      client,
+	}, nil
+}
+
+// parseOTLPHeaders parses OTEL_EXPORTER_OTLP_HEADERS, e.g. "api-key=abc,team=x"
+func parseOTLPHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS entry %q", pair)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS entry %q: %w", pair, err)
+		}
+		headers[strings.TrimSpace(key)] = decoded
+	}
+	return headers, nil
+}
+
+// Notify exports a change event
+func (n *OTLPLogNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	attributes := []otlpAttribute{
+		otlpString("vault.path", event.Path),
+		otlpStrings("vault.changed_keys", event.ChangedKeys),
+		otlpString("vault.old_hash", event.OldHash),
+		otlpString("vault.new_hash", event.NewHash),
+	}
+	if event.Version != 0 {
+		attributes = append(attributes, otlpInt("vault.version", event.Version))
+	}
+	if event.RequestID != "" {
+		attributes = append(attributes, otlpString("vault.request_id", event.RequestID))
+	}
+	return n.export(ctx, event.Timestamp, otelSeverityInfo, "vault secret changed", "vault.secret.changed", attributes)
+}
+
+// NotifyHealth exports a health transition, as an error when the watcher
+// became unhealthy
+func (n *OTLPLogNotifier) NotifyHealth(ctx context.Context, event HealthEvent) error {
+	attributes := []otlpAttribute{
+		otlpString("vault.path", event.Path),
+		otlpInt("vault.consecutive_failures", event.ConsecutiveFailures),
+	}
+	if event.Healthy {
+		return n.export(ctx, event.Timestamp, otelSeverityInfo, "vault watcher recovered", "vault.watcher.healthy", attributes)
+	}
+	attributes = append(attributes, otlpString("exception.message", event.Error))
+	return n.export(ctx, event.Timestamp, otelSeverityError, "vault watcher unhealthy", "vault.watcher.unhealthy", attributes)
+}
+
+// NotifyValidationFailure exports a change rejected by validation
+func (n *OTLPLogNotifier) NotifyValidationFailure(ctx context.Context, event ValidationFailedEvent) error {
+	attributes := []otlpAttribute{
+		otlpString("vault.path", event.Path),
+		otlpStrings("vault.changed_keys", event.ChangedKeys),
+		otlpString("vault.new_hash", event.NewHash),
+		otlpStrings("vault.validation_errors", event.Errors),
+	}
+	if event.RequestID != "" {
+		attributes = append(attributes, otlpString("vault.request_id", event.RequestID))
+	}
+	return n.export(ctx, event.Timestamp, otelSeverityWarn, "vault secret change rejected", "vault.secret.rejected", attributes)
+}
+
+// NotifyDecryptFailure exports values that couldn't be decrypted
+func (n *OTLPLogNotifier) NotifyDecryptFailure(ctx context.Context, event DecryptFailedEvent) error {
+	attributes := []otlpAttribute{
+		otlpString("vault.path", event.Path),
+		otlpStrings("vault.keys", event.Keys),
+		otlpString("exception.message", event.Reason),
+	}
+	return n.export(ctx, event.Timestamp, otelSeverityError, "vault secret decryption failed", "vault.secret.decrypt_failed", attributes)
+}
+
+// otlpAttribute is a key-value pair in the OTLP JSON encoding
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"` // int64 values are strings in OTLP JSON
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpValue `json:"values"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int) otlpAttribute {
+	text := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &text}}
+}
+
+func otlpStrings(key string, values []string) otlpAttribute {
+	array := &otlpArrayValue{Values: make([]otlpValue, len(values))}
+	for i := range values {
+		array.Values[i] = otlpValue{StringValue: &values[i]}
+	}
+	return otlpAttribute{Key: key, Value: otlpValue{ArrayValue: array}}
+}
+
+// export POSTs one log record
+func (n *OTLPLogNotifier) export(ctx context.Context, timestamp time.Time, severity int, body, eventName string, attributes []otlpAttribute) error {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	severityText := map[int]string{otelSeverityInfo: "INFO", otelSeverityWarn: "WARN", otelSeverityError: "ERROR"}[severity]
+	attributes = append(attributes, otlpString("event.name", eventName))
+
+	record := map[string]interface{}{
+		"timeUnixNano":         strconv.FormatInt(timestamp.UnixNano(), 10),
+		"observedTimeUnixNano": strconv.FormatInt(time.Now().UnixNano(), 10),
+		"severityNumber":       severity,
+		"severityText":         severityText,
+		"body":                 otlpValue{StringValue: &body},
+		"attributes":           attributes,
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{otlpString("service.name", n.serviceName)},
+			},
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      map[string]interface{}{"name": otelScopeName},
+				"logRecords": []interface{}{record},
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal log record: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vault-watcher")
+	for key, value := range n.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export log record: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export log record: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
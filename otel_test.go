@@ -0,0 +1,91 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewOTLPLogNotifier_Endpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		env      map[string]string
+		wantURL  string
+		wantErr  string
+	}{
+		{name: "config", endpoint: "http://collector:4318/", wantURL: "http://collector:4318/v1/logs"},
+		{name: "logs endpoint used as is", env: map[string]string{"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT": "http://collector:4318/custom"},
+			wantURL: "http://collector:4318/custom"},
+		{name: "base endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318"},
+			wantURL: "http://collector:4318/v1/logs"},
+		{name: "missing", wantErr: "OTLP endpoint is required"},
+		{name: "bad headers", endpoint: "http://collector:4318", env: map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "api-key"},
+			wantErr: `invalid OTEL_EXPORTER_OTLP_HEADERS entry "api-key"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_HEADERS"} {
+				t.Setenv(key, tt.env[key])
+			}
+			notifier, err := NewOTLPLogNotifier(OTLPLogConfig{Endpoint: tt.endpoint})
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "NewOTLPLogNotifier()")
+				return
+			}
+			AssertNoError(t, err, "NewOTLPLogNotifier()")
+			AssertStringEquals(t, notifier.url, tt.wantURL, "logs URL")
+		})
+	}
+}
+
+func TestOTLPLogNotifier_Export(t *testing.T) {
+	requests := make(chan map[string]interface{}, 2)
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			http.NotFound(w, r)
+			return
+		}
+		header = r.Header.Get("Api-Key")
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests <- body
+	}))
+	defer server.Close()
+
+	notifier, err := NewOTLPLogNotifier(OTLPLogConfig{Endpoint: server.URL, ServiceName: "billing", Headers: map[string]string{"Api-Key": "abc"}})
+	AssertNoError(t, err, "NewOTLPLogNotifier()")
+
+	event := ChangeEvent{Path: "secret/data/app", ChangedKeys: []string{"password"}, NewHash: "new", Version: 3,
+		Timestamp: time.Unix(1700000000, 0)}
+	AssertNoError(t, notifier.Notify(context.Background(), event), "Notify()")
+	body := <-requests
+	AssertStringEquals(t, header, "abc", "Api-Key header")
+
+	resourceLog := body["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	resource, _ := json.Marshal(resourceLog["resource"])
+	AssertStringEquals(t, string(resource), `{"attributes":[{"key":"service.name","value":{"stringValue":"billing"}}]}`, "resource")
+	record := resourceLog["scopeLogs"].([]interface{})[0].(map[string]interface{})["logRecords"].([]interface{})[0].(map[string]interface{})
+	AssertStringEquals(t, fmt.Sprint(record["timeUnixNano"], " ", record["severityText"], " ", record["body"]),
+		"1700000000000000000 INFO map[stringValue:vault secret changed]", "record")
+	attributes, _ := json.Marshal(record["attributes"])
+	AssertStringEquals(t, string(attributes), `[{"key":"vault.path","value":{"stringValue":"secret/data/app"}},`+
+		`{"key":"vault.changed_keys","value":{"arrayValue":{"values":[{"stringValue":"password"}]}}},`+
+		`{"key":"vault.old_hash","value":{"stringValue":""}},{"key":"vault.new_hash","value":{"stringValue":"new"}},`+
+		`{"key":"vault.version","value":{"intValue":"3"}},{"key":"event.name","value":{"stringValue":"vault.secret.changed"}}]`, "attributes")
+
+	health := HealthEvent{Path: "secret/data/app", ConsecutiveFailures: 3, Error: "permission denied"}
+	AssertNoError(t, notifier.NotifyHealth(context.Background(), health), "NotifyHealth()")
+	record = (<-requests)["resourceLogs"].([]interface{})[0].(map[string]interface{})["scopeLogs"].([]interface{})[0].(map[string]interface{})["logRecords"].([]interface{})[0].(map[string]interface{})
+	AssertStringEquals(t, fmt.Sprint(record["severityText"], " ", record["severityNumber"]), "ERROR 17", "unhealthy severity")
+
+	failing, err := NewOTLPLogNotifier(OTLPLogConfig{Endpoint: server.URL + "/missing"})
+	AssertNoError(t, err, "NewOTLPLogNotifier()")
+	AssertError(t, failing.Notify(context.Background(), event), "failed to export log record: unexpected status 404", "Notify() to a missing path")
+}
@@ -0,0 +1,64 @@
+package vaultwatcher
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PatchOperation is one operation of an RFC 6902 JSON Patch document
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// JSONPatch returns the RFC 6902 JSON Patch that turns oldData into newData.
+// Nested objects are diffed key by key; other values, including arrays, are
+// replaced whole. Operations are sorted by path, so equal inputs give equal
+// documents.
+func JSONPatch(oldData, newData map[string]interface{}) []PatchOperation {
+	patch := []PatchOperation{}
+	diffObjects("", oldData, newData, &patch)
+	return patch
+}
+
+func diffObjects(prefix string, oldData, newData map[string]interface{}, patch *[]PatchOperation) {
+	keys := make([]string, 0, len(oldData)+len(newData))
+	for key := range oldData {
+		keys = append(keys, key)
+	}
+	for key := range newData {
+		if _, ok := oldData[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := prefix + "/" + escapePointer(key)
+		oldValue, inOld := oldData[key]
+		newValue, inNew := newData[key]
+
+		switch {
+		case !inNew:
+			*patch = append(*patch, PatchOperation{Op: "remove", Path: path})
+		case !inOld:
+			*patch = append(*patch, PatchOperation{Op: "add", Path: path, Value: newValue})
+		case reflect.DeepEqual(oldValue, newValue):
+		default:
+			oldObject, oldIsObject := oldValue.(map[string]interface{})
+			newObject, newIsObject := newValue.(map[string]interface{})
+			if oldIsObject && newIsObject {
+				diffObjects(path, oldObject, newObject, patch)
+			} else {
+				*patch = append(*patch, PatchOperation{Op: "replace", Path: path, Value: newValue})
+			}
+		}
+	}
+}
+
+// escapePointer escapes a key for use in a JSON Pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
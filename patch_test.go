@@ -0,0 +1,113 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldData map[string]interface{}
+		newData map[string]interface{}
+		want    string
+	}{
+		{
+			name:    "no change",
+			oldData: map[string]interface{}{"a": "1"},
+			newData: map[string]interface{}{"a": "1"},
+			want:    `[]`,
+		},
+		{
+			name:    "add, remove and replace",
+			oldData: map[string]interface{}{"keep": "x", "old": "1", "password": "one"},
+			newData: map[string]interface{}{"keep": "x", "new": "2", "password": "two"},
+			want:    `[{"op":"add","path":"/new","value":"2"},{"op":"remove","path":"/old"},{"op":"replace","path":"/password","value":"two"}]`,
+		},
+		{
+			name:    "nested objects",
+			oldData: map[string]interface{}{"db": map[string]interface{}{"host": "a", "port": "5432"}},
+			newData: map[string]interface{}{"db": map[string]interface{}{"host": "b", "port": "5432", "user": "app"}},
+			want:    `[{"op":"replace","path":"/db/host","value":"b"},{"op":"add","path":"/db/user","value":"app"}]`,
+		},
+		{
+			name:    "arrays and type changes are replaced",
+			oldData: map[string]interface{}{"hosts": []interface{}{"a"}, "db": map[string]interface{}{"host": "a"}},
+			newData: map[string]interface{}{"hosts": []interface{}{"a", "b"}, "db": "postgres://b"},
+			want:    `[{"op":"replace","path":"/db","value":"postgres://b"},{"op":"replace","path":"/hosts","value":["a","b"]}]`,
+		},
+		{
+			name:    "escaped keys",
+			oldData: map[string]interface{}{},
+			newData: map[string]interface{}{"a/b": "1", "c~d": "2"},
+			want:    `[{"op":"add","path":"/a~1b","value":"1"},{"op":"add","path":"/c~0d","value":"2"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(JSONPatch(tt.oldData, tt.newData))
+			AssertNoError(t, err, "json.Marshal()")
+			AssertStringEquals(t, string(got), tt.want, "JSONPatch()")
+		})
+	}
+}
+
+// patchRecorder collects the patches of change events
+type patchRecorder struct {
+	mu      sync.Mutex
+	patches [][]PatchOperation
+}
+
+func (r *patchRecorder) Notify(ctx context.Context, event ChangeEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patches = append(r.patches, event.Patch)
+	return nil
+}
+
+func TestWatcher_JSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "without option", want: `null`},
+		{name: "with option", opts: []Option{WithJSONPatch()}, want: `[{"op":"replace","path":"/password","value":"[REDACTED]"}]`},
+		{name: "with values", opts: []Option{WithJSONPatch(), WithPatchValues()}, want: `[{"op":"replace","path":"/password","value":"two"}]`},
+		{name: "with secure memory", opts: []Option{WithJSONPatch(), WithPatchValues(), WithSecureMemory()}, want: `[{"op":"replace","path":"/password","value":"two"}]`},
+		{name: "with redacted key", opts: []Option{WithJSONPatch(), WithPatchValues(), WithRedactedKeys("password")}, want: `[{"op":"replace","path":"/password","value":"[REDACTED]"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := vaultwatchertest.NewServer()
+			defer vault.Close()
+			vault.Put("secret/app", map[string]interface{}{"password": "one", "user": "app"})
+
+			recorder := &patchRecorder{}
+			watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+				func() error { return nil }, append(tt.opts, WithNotifier(recorder))...)
+			AssertNoError(t, err, "NewWatcher()")
+			AssertNoError(t, watcher.Start(), "Start()")
+			defer watcher.Stop()
+
+			vault.Put("secret/app", map[string]interface{}{"password": "two", "user": "app"})
+			AssertNoError(t, watcher.check(), "check()")
+
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			if len(recorder.patches) != 1 {
+				t.Fatalf("events = %d, want 1", len(recorder.patches))
+			}
+			got, err := json.Marshal(recorder.patches[0])
+			AssertNoError(t, err, "json.Marshal()")
+			AssertStringEquals(t, string(got), tt.want, "Patch")
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const policiesPath = "sys/policies/acl"
+
+// PolicyChange describes how ACL policies changed between two checks
+type PolicyChange struct {
+	Path     string
+	Added    []string // Names of new policies
+	Modified []string // Names of policies whose rules changed
+	Removed  []string // Names of deleted policies
+}
+
+// PolicyWatcher fires a callback when Vault ACL policies change, so security
+// teams can be told when access rules are modified. It watches a single policy
+// (sys/policies/acl/<name>) or every policy (sys/policies/acl). Only hashes of
+// the policy rules are kept.
+type PolicyWatcher struct {
+	*Watcher
+	onChange func(PolicyChange) error
+
+	mu             sync.Mutex
+	policyHashes   map[string]string
+	fetchedHashes  map[string]string
+	initialFetched bool
+}
+
+// NewPolicyWatcher creates a watcher for ACL policies
+// vaultConfig: Vault connection configuration, Path is "sys/policies/acl" or "sys/policies/acl/<name>"
+// checkInterval: How often to check for changes
+// onChange: Callback invoked with the added, modified and removed policies
+func NewPolicyWatcher(vaultConfig *VaultConfig, checkInterval time.Duration, onChange func(PolicyChange) error, opts ...Option) (*PolicyWatcher, error) {
+	if onChange == nil {
+		return nil, fmt.Errorf("onChange callback cannot be nil")
+	}
+	if vaultConfig != nil && vaultConfig.Path != policiesPath && !strings.HasPrefix(vaultConfig.Path, policiesPath+"/") {
+		return nil, fmt.Errorf("policy path must be %s or %s/<name>, got %q", policiesPath, policiesPath, vaultConfig.Path)
+	}
+
+	pw := &PolicyWatcher{onChange: onChange}
+
+	watcher, err := NewWatcher(vaultConfig, checkInterval, pw.handleChange, opts...)
+	if err != nil {
+		return nil, err
+	}
+	watcher.fetchData = pw.fetchPolicies
+	pw.Watcher = watcher
+
+	return pw, nil
+}
+
+// Policies returns the names of the policies as of the last processed change
+func (pw *PolicyWatcher) Policies() []string {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	names := make([]string, 0, len(pw.policyHashes))
+	for name := range pw.policyHashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fetchPolicies reads the watched policies into a map of policy name to rules
+func (pw *PolicyWatcher) fetchPolicies() (map[string]interface{}, error) {
+	names := []string{strings.TrimPrefix(pw.vaultConfig.Path, policiesPath+"/")}
+	if pw.vaultConfig.Path == policiesPath {
+		listed, err := pw.listPolicies()
+		if err != nil {
+			return nil, err
+		}
+		names = listed
+	}
+
+	policies := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		secret, err := pw.readPolicy(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy %s from vault: %w", name, classifyVaultError(err))
+		}
+		if secret == nil || secret.Data == nil {
+			// Deleted between listing and reading, or the watched policy doesn't exist
+			continue
+		}
+		policies[name] = secret.Data["policy"]
+	}
+
+	hashes, err := CalculateKeyHashes(policies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate policy hashes: %w", err)
+	}
+
+	pw.mu.Lock()
+	pw.fetchedHashes = hashes
+	if !pw.initialFetched {
+		// First read when the watcher starts
+		pw.policyHashes = hashes
+		pw.initialFetched = true
+	}
+	pw.mu.Unlock()
+
+	return policies, nil
+}
+
+// listPolicies returns the names of every ACL policy
+func (pw *PolicyWatcher) listPolicies() ([]string, error) {
+	ctx, cancel := pw.requestContext()
+	defer cancel()
+
+	secret, err := pw.client.Logical().ListWithContext(ctx, policiesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list policies from vault: %w", classifyVaultError(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	keys, _ := secret.Data["keys"].([]interface{})
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if name, ok := key.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// handleChange is the Watcher callback, called when any policy changed
+func (pw *PolicyWatcher) handleChange() error {
+	pw.mu.Lock()
+	previous, fetched := pw.policyHashes, pw.fetchedHashes
+	pw.mu.Unlock()
+
+	change := PolicyChange{Path: pw.vaultConfig.Path}
+	for _, name := range ChangedKeys(previous, fetched) {
+		_, existed := previous[name]
+		_, exists := fetched[name]
+		switch {
+		case !existed:
+			change.Added = append(change.Added, name)
+		case !exists:
+			change.Removed = append(change.Removed, name)
+		default:
+			change.Modified = append(change.Modified, name)
+		}
+	}
+
+	if err := pw.onChange(change); err != nil {
+		return err
+	}
+
+	pw.mu.Lock()
+	pw.policyHashes = fetched
+	pw.mu.Unlock()
+
+	return nil
+}
+
+// readPolicy reads one ACL policy
+func (pw *PolicyWatcher) readPolicy(name string) (*api.Secret, error) {
+	ctx, cancel := pw.requestContext()
+	defer cancel()
+	return pw.client.Logical().ReadWithContext(ctx, policiesPath+"/"+name)
+}
@@ -0,0 +1,153 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePolicies serves sys/policies/acl from a settable set of policies
+type fakePolicies struct {
+	mu       sync.Mutex
+	policies map[string]string
+}
+
+func (f *fakePolicies) set(name, rules string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if rules == "" {
+		delete(f.policies, name)
+		return
+	}
+	f.policies[name] = rules
+}
+
+func (f *fakePolicies) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// The client sends LIST as GET with list=true
+	isList := r.Method == "LIST" || r.URL.Query().Get("list") == "true"
+	if r.URL.Path == "/v1/sys/policies/acl" && isList {
+		keys := make([]string, 0, len(f.policies))
+		for name := range f.policies {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/v1/sys/policies/acl/")
+	rules, ok := f.policies[name]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{"name": name, "policy": rules},
+	})
+}
+
+func TestNewPolicyWatcher(t *testing.T) {
+	config := &VaultConfig{Host: "https://vault.example.com", Path: "sys/policy/admin", Token: "test-token"}
+	_, err := NewPolicyWatcher(config, time.Second, func(PolicyChange) error { return nil })
+	AssertError(t, err, `policy path must be sys/policies/acl or sys/policies/acl/<name>, got "sys/policy/admin"`, "NewPolicyWatcher(bad path)")
+
+	config.Path = "sys/policies/acl"
+	_, err = NewPolicyWatcher(config, time.Second, nil)
+	AssertError(t, err, "onChange callback cannot be nil", "NewPolicyWatcher(nil callback)")
+}
+
+func TestPolicyWatcher_AllPolicies(t *testing.T) {
+	policies := &fakePolicies{policies: map[string]string{
+		"default": `path "auth/token/lookup-self" { capabilities = ["read"] }`,
+		"admin":   `path "*" { capabilities = ["sudo"] }`,
+	}}
+	server := httptest.NewServer(policies)
+	defer server.Close()
+
+	var changes []PolicyChange
+	watcher, err := NewPolicyWatcher(
+		&VaultConfig{Host: server.URL, Path: "sys/policies/acl", Token: "test-token"},
+		time.Hour,
+		func(change PolicyChange) error {
+			changes = append(changes, change)
+			return nil
+		},
+	)
+	AssertNoError(t, err, "NewPolicyWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	if got := watcher.Policies(); !reflect.DeepEqual(got, []string{"admin", "default"}) {
+		t.Errorf("Policies() = %v, want [admin default]", got)
+	}
+
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(changes) != 0 {
+		t.Fatalf("got %d changes without modification, want 0", len(changes))
+	}
+
+	policies.set("admin", `path "secret/*" { capabilities = ["read"] }`)
+	policies.set("default", "")
+	policies.set("ci", `path "kv/data/ci/*" { capabilities = ["read"] }`)
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+
+	want := PolicyChange{
+		Path:     "sys/policies/acl",
+		Added:    []string{"ci"},
+		Modified: []string{"admin"},
+		Removed:  []string{"default"},
+	}
+	if !reflect.DeepEqual(changes[0], want) {
+		t.Errorf("change = %+v, want %+v", changes[0], want)
+	}
+	if got := watcher.Policies(); !reflect.DeepEqual(got, []string{"admin", "ci"}) {
+		t.Errorf("Policies() = %v, want [admin ci]", got)
+	}
+}
+
+func TestPolicyWatcher_SinglePolicy(t *testing.T) {
+	policies := &fakePolicies{policies: map[string]string{
+		"admin": `path "*" { capabilities = ["sudo"] }`,
+		"ci":    `path "kv/data/ci/*" { capabilities = ["read"] }`,
+	}}
+	server := httptest.NewServer(policies)
+	defer server.Close()
+
+	var changes []PolicyChange
+	watcher, err := NewPolicyWatcher(
+		&VaultConfig{Host: server.URL, Path: "sys/policies/acl/admin", Token: "test-token"},
+		time.Hour,
+		func(change PolicyChange) error {
+			changes = append(changes, change)
+			return nil
+		},
+	)
+	AssertNoError(t, err, "NewPolicyWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	// Other policies are not watched
+	policies.set("ci", `path "kv/*" { capabilities = ["read"] }`)
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(changes) != 0 {
+		t.Fatalf("got %d changes for an unwatched policy, want 0", len(changes))
+	}
+
+	policies.set("admin", "")
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(changes) != 1 || !reflect.DeepEqual(changes[0].Removed, []string{"admin"}) {
+		t.Errorf("changes = %+v, want admin removed", changes)
+	}
+}
@@ -0,0 +1,53 @@
+package vaultwatcher
+
+import (
+	"context"
+	"sort"
+)
+
+// Priority ranks watchers competing for a shared RateLimiter or for the
+// check cycles of a busy WatcherGroup
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0 // The default
+	PriorityHigh   Priority = 1
+)
+
+// String returns "low", "normal" or "high"
+func (p Priority) String() string {
+	switch {
+	case p < PriorityNormal:
+		return "low"
+	case p > PriorityNormal:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// priorityKey is the context key of a request's priority
+type priorityKey struct{}
+
+// withPriority marks the requests made with ctx as having priority
+func withPriority(ctx context.Context, priority Priority) context.Context {
+	if priority == PriorityNormal {
+		return ctx
+	}
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// requestPriority returns the priority of a request made with ctx
+func requestPriority(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+// byPriority returns watchers with the higher priorities first, keeping the
+// order of watchers with the same priority
+func byPriority(watchers []*Watcher) []*Watcher {
+	sorted := append([]*Watcher(nil), watchers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].priority > sorted[j].priority })
+	return sorted
+}
@@ -0,0 +1,124 @@
+package vaultwatcher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusHandler serves the status and callback metrics of watchers in the
+// Prometheus text format, e.g. on /metrics. Pass WatcherGroup.Watchers() to
+// export every path of a group.
+func PrometheusHandler(watchers ...*Watcher) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := WritePrometheus(rw, watchers...); err != nil {
+			slog.Error("Error writing prometheus metrics", "error", err)
+		}
+	})
+}
+
+// WritePrometheus writes the status and callback metrics of watchers in the
+// Prometheus text format
+func WritePrometheus(out io.Writer, watchers ...*Watcher) error {
+	statuses := make([]WatcherStatus, len(watchers))
+	for i, w := range watchers {
+		statuses[i] = w.Status()
+	}
+
+	b := bufio.NewWriter(out)
+
+	family(b, "vaultwatcher_healthy", "gauge", "Whether the watcher is below its failure threshold.")
+	for _, status := range statuses {
+		sample(b, "vaultwatcher_healthy", boolValue(status.Healthy), "path", status.Path)
+	}
+	family(b, "vaultwatcher_consecutive_failures", "gauge", "Checks failed in a row.")
+	for _, status := range statuses {
+		sample(b, "vaultwatcher_consecutive_failures", strconv.Itoa(status.ConsecutiveFailures), "path", status.Path)
+	}
+	family(b, "vaultwatcher_token_ttl_seconds", "gauge", "Remaining TTL of the Vault token, for watchers monitoring an expiring token.")
+	for _, status := range statuses {
+		if !status.TokenExpiresAt.IsZero() {
+			sample(b, "vaultwatcher_token_ttl_seconds", strconv.FormatFloat(status.TokenTTL.Seconds(), 'f', 0, 64), "path", status.Path)
+		}
+	}
+
+	family(b, "vaultwatcher_callback_runs_total", "counter", "Callback runs by result.")
+	eachCallback(statuses, func(path, name string, m CallbackMetrics) {
+		sample(b, "vaultwatcher_callback_runs_total", strconv.FormatInt(m.Successes, 10), "path", path, "callback", name, "result", "success")
+		sample(b, "vaultwatcher_callback_runs_total", strconv.FormatInt(m.Failures, 10), "path", path, "callback", name, "result", "failure")
+	})
+	family(b, "vaultwatcher_callback_retries_total", "counter", "Callback runs for a change whose callback failed before.")
+	eachCallback(statuses, func(path, name string, m CallbackMetrics) {
+		sample(b, "vaultwatcher_callback_retries_total", strconv.FormatInt(m.Retries, 10), "path", path, "callback", name)
+	})
+	family(b, "vaultwatcher_callback_duration_seconds", "histogram", "Callback run durations.")
+	eachCallback(statuses, func(path, name string, m CallbackMetrics) {
+		var cumulative int64
+		for i, bound := range callbackDurationBuckets {
+			if i < len(m.buckets) {
+				cumulative += m.buckets[i]
+			}
+			sample(b, "vaultwatcher_callback_duration_seconds_bucket", strconv.FormatInt(cumulative, 10),
+				"path", path, "callback", name, "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		}
+		sample(b, "vaultwatcher_callback_duration_seconds_bucket", strconv.FormatInt(m.Calls, 10), "path", path, "callback", name, "le", "+Inf")
+		sample(b, "vaultwatcher_callback_duration_seconds_sum", strconv.FormatFloat(m.TotalDuration.Seconds(), 'g', -1, 64), "path", path, "callback", name)
+		sample(b, "vaultwatcher_callback_duration_seconds_count", strconv.FormatInt(m.Calls, 10), "path", path, "callback", name)
+	})
+	family(b, "vaultwatcher_callback_max_duration_seconds", "gauge", "Longest callback run.")
+	eachCallback(statuses, func(path, name string, m CallbackMetrics) {
+		sample(b, "vaultwatcher_callback_max_duration_seconds", strconv.FormatFloat(m.MaxDuration.Seconds(), 'g', -1, 64), "path", path, "callback", name)
+	})
+
+	return b.Flush()
+}
+
+// eachCallback calls fn for every callback of every status, sorted by name
+func eachCallback(statuses []WatcherStatus, fn func(path, name string, metrics CallbackMetrics)) {
+	for _, status := range statuses {
+		names := make([]string, 0, len(status.Callbacks))
+		for name := range status.Callbacks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fn(status.Path, name, status.Callbacks[name])
+		}
+	}
+}
+
+func family(b *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample; labels are name/value pairs
+func sample(b *bufio.Writer, name, value string, labels ...string) {
+	b.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i == 0 {
+			b.WriteByte('{')
+		} else {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(b, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+	}
+	if len(labels) > 0 {
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(b, " %s\n", value)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func boolValue(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
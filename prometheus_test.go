@@ -0,0 +1,48 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	watcher := TestWatcherWithConfig(t, &VaultConfig{Host: "https://vault.example.com", Path: `kv/data/"quoted"`, Token: "t"},
+		time.Hour, nil)
+	watcher.runCallback("onChange", false, func() error { return nil })
+	watcher.runCallback("onChange", true, func() error { return errors.New("failed") })
+
+	recorder := httptest.NewRecorder()
+	PrometheusHandler(watcher).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	AssertStringEquals(t, recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8", "Content-Type")
+	body := recorder.Body.String()
+	for _, want := range []string{
+		"# TYPE vaultwatcher_healthy gauge\n",
+		`vaultwatcher_healthy{path="kv/data/\"quoted\""} 1` + "\n",
+		`vaultwatcher_consecutive_failures{path="kv/data/\"quoted\""} 0` + "\n",
+		"# TYPE vaultwatcher_callback_runs_total counter\n",
+		`vaultwatcher_callback_runs_total{path="kv/data/\"quoted\"",callback="onChange",result="success"} 1` + "\n",
+		`vaultwatcher_callback_runs_total{path="kv/data/\"quoted\"",callback="onChange",result="failure"} 1` + "\n",
+		`vaultwatcher_callback_retries_total{path="kv/data/\"quoted\"",callback="onChange"} 1` + "\n",
+		"# TYPE vaultwatcher_callback_duration_seconds histogram\n",
+		`vaultwatcher_callback_duration_seconds_bucket{path="kv/data/\"quoted\"",callback="onChange",le="0.005"} 2` + "\n",
+		`vaultwatcher_callback_duration_seconds_bucket{path="kv/data/\"quoted\"",callback="onChange",le="+Inf"} 2` + "\n",
+		`vaultwatcher_callback_duration_seconds_count{path="kv/data/\"quoted\"",callback="onChange"} 2` + "\n",
+		"# TYPE vaultwatcher_callback_max_duration_seconds gauge\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestWritePrometheus_NoWatchers(t *testing.T) {
+	var b strings.Builder
+	AssertNoError(t, WritePrometheus(&b), "WritePrometheus()")
+	if strings.Contains(b.String(), "{") {
+		t.Errorf("metrics without watchers contain samples:\n%s", b.String())
+	}
+}
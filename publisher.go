@@ -0,0 +1,91 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MessagePublisher publishes a message to a message bus topic or subject.
+// Implementations wrap a concrete client such as NATS, or Kafka with the
+// contrib/kafka module.
+type MessagePublisher interface {
+	Publish(ctx context.Context, topic string, key, value []byte) error
+}
+
+// MessagePublisherFunc adapts an ordinary function to the MessagePublisher interface
+type MessagePublisherFunc func(ctx context.Context, topic string, key, value []byte) error
+
+// Publish calls f(ctx, topic, key, value)
+func (f MessagePublisherFunc) Publish(ctx context.Context, topic string, key, value []byte) error {
+	return f(ctx, topic, key, value)
+}
+
+// PublisherNotifier publishes every change event as JSON through a MessagePublisher.
+// The message key is the changed path, so partitioned buses keep per-path ordering.
+type PublisherNotifier struct {
+	publisher MessagePublisher
+	topic     string
+}
+
+// NewPublisherNotifier creates a notifier publishing to topic. A "{path}"
+// placeholder in topic is replaced with the path of the change.
+func NewPublisherNotifier(publisher MessagePublisher, topic string) (*PublisherNotifier, error) {
+	if publisher == nil {
+		return nil, fmt.Errorf("publisher cannot be nil")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+
+	return &PublisherNotifier{
+		publisher: publisher,
+		topic:     topic,
+	}, nil
+}
+
+// Notify publishes the change event
+func (n *PublisherNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal change event: %w", err)
+	}
+
+	topic := strings.ReplaceAll(n.topic, "{path}", event.Path)
+	if err := n.publisher.Publish(ctx, topic, []byte(event.Path), value); err != nil {
+		return fmt.Errorf("failed to publish change event to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// NATSConn is the subset of *nats.Conn used for publishing
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+}
+
+// NewNATSPublisher adapts a NATS connection to MessagePublisher. The topic is
+// made a valid subject with NATSSubject.
+func NewNATSPublisher(conn NATSConn) MessagePublisher {
+	return MessagePublisherFunc(func(ctx context.Context, topic string, key, value []byte) error {
+		return conn.Publish(NATSSubject(topic), value)
+	})
+}
+
+// NATSSubject makes topic a valid NATS subject: slashes (e.g. from a "{path}"
+// placeholder) become dots, the NATS token separator, empty tokens are
+// dropped, and the wildcards "*" and ">", whitespace and control characters
+// become "_".
+func NATSSubject(topic string) string {
+	var tokens []string
+	for _, token := range strings.FieldsFunc(topic, func(r rune) bool { return r == '/' || r == '.' }) {
+		tokens = append(tokens, strings.Map(func(r rune) rune {
+			if r == '*' || r == '>' || unicode.IsSpace(r) || unicode.IsControl(r) {
+				return '_'
+			}
+			return r
+		}, token))
+	}
+	return strings.Join(tokens, ".")
+}
@@ -0,0 +1,94 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// fakeNATSConn records published messages
+type fakeNATSConn struct {
+	subjects []string
+	payloads [][]byte
+}
+
+func (c *fakeNATSConn) Publish(subject string, data []byte) error {
+	c.subjects = append(c.subjects, subject)
+	c.payloads = append(c.payloads, data)
+	return nil
+}
+
+func TestNewPublisherNotifier(t *testing.T) {
+	publisher := MessagePublisherFunc(func(ctx context.Context, topic string, key, value []byte) error { return nil })
+
+	_, err := NewPublisherNotifier(nil, "vault.changes")
+	AssertError(t, err, "publisher cannot be nil", "NewPublisherNotifier(nil)")
+
+	_, err = NewPublisherNotifier(publisher, "")
+	AssertError(t, err, "topic is required", "NewPublisherNotifier(empty topic)")
+}
+
+func TestPublisherNotifier_Kafka(t *testing.T) {
+	var gotTopic, gotKey string
+	var gotEvent ChangeEvent
+	publisher := MessagePublisherFunc(func(ctx context.Context, topic string, key, value []byte) error {
+		gotTopic, gotKey = topic, string(key)
+		return json.Unmarshal(value, &gotEvent)
+	})
+
+	notifier, err := NewPublisherNotifier(publisher, "vault-changes")
+	if err != nil {
+		t.Fatalf("NewPublisherNotifier() error = %v", err)
+	}
+
+	event := ChangeEvent{Path: "kv/data/app", NewHash: "h1", ChangedKeys: []string{"api_key"}}
+	AssertNoError(t, notifier.Notify(context.Background(), event), "Notify()")
+	AssertStringEquals(t, gotTopic, "vault-changes", "topic")
+	AssertStringEquals(t, gotKey, "kv/data/app", "key")
+	AssertStringEquals(t, gotEvent.NewHash, "h1", "published event")
+}
+
+func TestPublisherNotifier_NATS(t *testing.T) {
+	conn := &fakeNATSConn{}
+	notifier, err := NewPublisherNotifier(NewNATSPublisher(conn), "vault.changes.{path}")
+	if err != nil {
+		t.Fatalf("NewPublisherNotifier() error = %v", err)
+	}
+
+	AssertNoError(t, notifier.Notify(context.Background(), ChangeEvent{Path: "kv/data/app"}), "Notify()")
+	if len(conn.subjects) != 1 {
+		t.Fatalf("published %d messages, want 1", len(conn.subjects))
+	}
+	AssertStringEquals(t, conn.subjects[0], "vault.changes.kv.data.app", "subject")
+}
+
+func TestNATSSubject(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  string
+	}{
+		{"vault.changes.kv/data/app", "vault.changes.kv.data.app"},
+		{"/vault.changes./kv//data/app/", "vault.changes.kv.data.app"},
+		{"vault.changes.kv/data/*/>", "vault.changes.kv.data._._"},
+		{"vault.changes.kv/data/my app\t1", "vault.changes.kv.data.my_app_1"},
+	}
+	for _, tt := range tests {
+		AssertStringEquals(t, NATSSubject(tt.topic), tt.want, fmt.Sprintf("NATSSubject(%q)", tt.topic))
+	}
+}
+
+func TestPublisherNotifier_Error(t *testing.T) {
+	publisher := MessagePublisherFunc(func(ctx context.Context, topic string, key, value []byte) error {
+		return errors.New("broker unavailable")
+	})
+
+	notifier, err := NewPublisherNotifier(publisher, "vault-changes")
+	if err != nil {
+		t.Fatalf("NewPublisherNotifier() error = %v", err)
+	}
+
+	err = notifier.Notify(context.Background(), ChangeEvent{Path: "kv/data/app"})
+	AssertError(t, err, "failed to publish change event to vault-changes: broker unavailable", "Notify()")
+}
@@ -0,0 +1,141 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting requests to Vault. One limiter can be
+// shared by many watchers to keep all of them within a single budget.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // Tokens added per second
+	burst   float64
+	tokens  float64
+	last    time.Time
+	waiting []*rateWaiter // By priority, then in order
+	timer   *time.Timer   // Serves the waiting requests once a token is due
+}
+
+// rateWaiter is a request waiting for a token
+type rateWaiter struct {
+	priority Priority
+	ready    chan struct{} // Closed when the request may be sent
+}
+
+// NewRateLimiter creates a limiter allowing requestsPerSecond on average and up
+// to burst requests at once (at least 1)
+func NewRateLimiter(requestsPerSecond float64, burst int) (*RateLimiter, error) {
+	if requestsPerSecond <= 0 {
+		return nil, fmt.Errorf("requests per second must be positive")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}, nil
+}
+
+// Wait blocks until a request may be sent or ctx is done. Waiting requests are
+// served by the priority of their watcher, set with WithPriority, and in order
+// within a priority.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	l.refill()
+	if len(l.waiting) == 0 && l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+
+	waiter := &rateWaiter{priority: requestPriority(ctx), ready: make(chan struct{})}
+	i := sort.Search(len(l.waiting), func(i int) bool { return l.waiting[i].priority < waiter.priority })
+	l.waiting = append(l.waiting[:i], append([]*rateWaiter{waiter}, l.waiting[i:]...)...)
+	l.serve()
+	l.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-waiter.ready:
+		// Served meanwhile: give the token back
+		l.tokens = min(l.burst, l.tokens+1)
+		l.serve()
+	default:
+		for i, w := range l.waiting {
+			if w == waiter {
+				l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// refill adds the tokens earned since the last refill. Called with l.mu held.
+func (l *RateLimiter) refill() {
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
+
+// serve lets waiting requests go while there are tokens, and wakes up again
+// when the next token is due. Called with l.mu held.
+func (l *RateLimiter) serve() {
+	l.refill()
+	for len(l.waiting) > 0 && l.tokens >= 1 {
+		l.tokens--
+		close(l.waiting[0].ready)
+		l.waiting = l.waiting[1:]
+	}
+	if len(l.waiting) == 0 {
+		return
+	}
+
+	delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if l.timer == nil {
+		l.timer = time.AfterFunc(delay, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.serve()
+		})
+		return
+	}
+	l.timer.Reset(delay)
+}
+
+// transport wraps next so every request waits for the limiter
+func (l *RateLimiter) transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &rateLimitedTransport{limiter: l, next: next}
+}
+
+// rateLimitedTransport is an http.RoundTripper waiting for a RateLimiter
+type rateLimitedTransport struct {
+	limiter *RateLimiter
+	next    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,85 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	_, err := NewRateLimiter(0, 1)
+	AssertError(t, err, "requests per second must be positive", "NewRateLimiter(0)")
+
+	limiter, err := NewRateLimiter(10, 0)
+	AssertNoError(t, err, "NewRateLimiter()")
+	if limiter.burst != 1 {
+		t.Errorf("burst = %v, want 1", limiter.burst)
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter, err := NewRateLimiter(20, 2)
+	AssertNoError(t, err, "NewRateLimiter()")
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		AssertNoError(t, limiter.Wait(ctx), "Wait()")
+	}
+
+	// The burst of 2 is free, the other 4 requests take 50ms each
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond || elapsed > time.Second {
+		t.Errorf("6 requests took %v, want about 200ms", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	limiter, err := NewRateLimiter(1, 1)
+	AssertNoError(t, err, "NewRateLimiter()")
+	AssertNoError(t, limiter.Wait(context.Background()), "Wait()")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The cancelled reservation is returned, so the next token is due after about one second
+	if tokens := limiter.tokens; tokens < -0.1 {
+		t.Errorf("tokens = %v after cancellation, want about 0", tokens)
+	}
+}
+
+func TestRateLimiter_WaitPriority(t *testing.T) {
+	limiter, err := NewRateLimiter(5, 1)
+	AssertNoError(t, err, "NewRateLimiter()")
+	AssertNoError(t, limiter.Wait(context.Background()), "Wait()")
+
+	var mu sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+	// Queued in the reverse order of their priority
+	for i, priority := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		wg.Add(1)
+		go func(priority Priority) {
+			defer wg.Done()
+			AssertNoError(t, limiter.Wait(withPriority(context.Background(), priority)), "Wait()")
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, priority)
+		}(priority)
+		waitFor(t, time.Second, func() bool {
+			limiter.mu.Lock()
+			defer limiter.mu.Unlock()
+			return len(limiter.waiting) == i+1
+		}, "request to queue")
+	}
+	wg.Wait()
+
+	if !reflect.DeepEqual(order, []Priority{PriorityHigh, PriorityNormal, PriorityLow}) {
+		t.Errorf("requests served in order %v, want [high normal low]", order)
+	}
+}
@@ -0,0 +1,78 @@
+package vaultwatcher
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// SecretReader reads a secret from Vault. The client's *api.Logical implements
+// it; tests can inject a fake with WithSecretReader to run the watcher, and
+// their onChange logic, without a Vault server.
+type SecretReader interface {
+	Read(path string) (*api.Secret, error)
+}
+
+// SecretReaderFunc adapts a function to a SecretReader
+type SecretReaderFunc func(path string) (*api.Secret, error)
+
+// Read calls f(path)
+func (f SecretReaderFunc) Read(path string) (*api.Secret, error) {
+	return f(path)
+}
+
+// ContextSecretReader is a SecretReader whose reads can be cancelled, as the
+// client's *api.Logical can. Reads of other readers aren't interrupted by Stop
+// or the request timeout.
+type ContextSecretReader interface {
+	SecretReader
+	ReadWithContext(ctx context.Context, path string) (*api.Secret, error)
+}
+
+// contextVersionedReader is a VersionedSecretReader whose reads can be cancelled
+type contextVersionedReader interface {
+	ReadWithDataWithContext(ctx context.Context, path string, data map[string][]string) (*api.Secret, error)
+}
+
+// secretReader returns the injected reader or the Vault client
+func (w *Watcher) secretReader() SecretReader {
+	if w.reader != nil {
+		return w.reader
+	}
+	return w.client.Logical()
+}
+
+// requestContext returns a context for one Vault request. It is cancelled by
+// Stop and, with WithRequestTimeout, after the timeout.
+func (w *Watcher) requestContext() (context.Context, context.CancelFunc) {
+	return w.withRequestTimeout(w.ctx)
+}
+
+// withRequestTimeout applies the request timeout, if any, and the watcher's
+// priority to ctx
+func (w *Watcher) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = withPriority(ctx, w.priority)
+	if w.requestTimeout > 0 {
+		return context.WithTimeout(ctx, w.requestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// read reads path with the secret reader, cancelled with ctx if it can be
+func (w *Watcher) read(ctx context.Context, path string) (*api.Secret, error) {
+	reader := w.secretReader()
+	contextReader, ok := reader.(ContextSecretReader)
+	if !ok {
+		return reader.Read(path)
+	}
+	return contextReader.ReadWithContext(ctx, path)
+}
+
+// readWithData reads path with query parameters, cancelled with ctx if it can be
+func (w *Watcher) readWithData(ctx context.Context, reader VersionedSecretReader, path string, data map[string][]string) (*api.Secret, error) {
+	contextReader, ok := reader.(contextVersionedReader)
+	if !ok {
+		return reader.ReadWithData(path, data)
+	}
+	return contextReader.ReadWithDataWithContext(ctx, path, data)
+}
@@ -0,0 +1,222 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// fakeSecrets is a SecretReader serving KV v2 secrets from memory
+type fakeSecrets struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]interface{}
+	err     error
+	reads   []string
+}
+
+func (f *fakeSecrets) set(path string, data map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secrets[path] = data
+}
+
+func (f *fakeSecrets) Read(path string) (*api.Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads = append(f.reads, path)
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, ok := f.secrets[path]
+	if !ok {
+		return nil, nil
+	}
+	return &api.Secret{Data: map[string]interface{}{"data": data}}, nil
+}
+
+func TestWatcher_WithSecretReader(t *testing.T) {
+	reader := &fakeSecrets{secrets: map[string]map[string]interface{}{
+		"kv/data/test": {"password": "one"},
+	}}
+
+	changes := 0
+	watcher, err := NewWatcher(TestVaultConfig(), time.Hour, func() error {
+		changes++
+		return nil
+	}, WithSecretReader(reader))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges() without change")
+	if changes != 0 {
+		t.Errorf("changes = %d, want 0", changes)
+	}
+
+	reader.set("kv/data/test", map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges() after change")
+	if changes != 1 {
+		t.Errorf("changes = %d, want 1", changes)
+	}
+
+	reader.mu.Lock()
+	defer reader.mu.Unlock()
+	for _, path := range reader.reads {
+		AssertStringEquals(t, path, "kv/data/test", "read path")
+	}
+}
+
+func TestWatcher_WithSecretReaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader SecretReader
+		errMsg string
+	}{
+		{
+			name: "read error",
+			reader: SecretReaderFunc(func(string) (*api.Secret, error) {
+				return nil, fmt.Errorf("permission denied")
+			}),
+			errMsg: "failed to read secret from vault: permission denied",
+		},
+		{
+			name:   "missing secret",
+			reader: SecretReaderFunc(func(string) (*api.Secret, error) { return nil, nil }),
+			errMsg: "failed to read secret from vault: secret not found",
+		},
+		{
+			name:   "missing data",
+			reader: SecretReaderFunc(func(string) (*api.Secret, error) { return &api.Secret{}, nil }),
+			errMsg: "failed to read secret from vault: secret data is nil",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, WithSecretReader(tt.reader))
+			AssertNoError(t, err, "NewWatcher()")
+
+			_, err = watcher.fetchVaultData()
+			AssertError(t, err, tt.errMsg, "fetchVaultData()")
+		})
+	}
+}
+
+func TestWatcherGroup_WithSecretReader(t *testing.T) {
+	reader := &fakeSecrets{secrets: map[string]map[string]interface{}{
+		"kv/data/a": {"value": "a1"},
+		"kv/data/b": {"value": "b1"},
+	}}
+
+	var changed []string
+	group, err := NewWatcherGroup(TestVaultConfig(), []string{"kv/data/a", "kv/data/b"}, time.Hour,
+		func(path string) error {
+			changed = append(changed, path)
+			return nil
+		}, WithGroupConcurrency(1), WithWatcherOptions(WithSecretReader(reader)))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	reader.set("kv/data/b", map[string]interface{}{"value": "b2"})
+	group.checkAll()
+
+	if len(changed) != 1 || changed[0] != "kv/data/b" {
+		t.Errorf("changed = %v, want [kv/data/b]", changed)
+	}
+}
+
+func TestWatcher_RequestContext(t *testing.T) {
+	release := make(chan struct{})
+	var hang bool
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hanging := hang
+		mu.Unlock()
+		if hanging {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data": {"data": {"password": "one"}, "metadata": {"version": 1}}}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	setHang := func(h bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		hang = h
+	}
+
+	t.Run("request timeout", func(t *testing.T) {
+		setHang(false)
+		watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+			func() error { return nil }, WithRequestTimeout(50*time.Millisecond))
+		AssertNoError(t, err, "NewWatcher()")
+		AssertNoError(t, watcher.Start(), "Start()")
+		defer watcher.Stop()
+
+		setHang(true)
+		start := time.Now()
+		_, err = watcher.fetchVaultData()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("fetchVaultData() error = %v, want a deadline error", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("fetchVaultData() took %v, want about the request timeout", elapsed)
+		}
+	})
+
+	t.Run("health checks and writes", func(t *testing.T) {
+		setHang(false)
+		watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+			func() error { return nil }, WithRequestTimeout(50*time.Millisecond))
+		AssertNoError(t, err, "NewWatcher()")
+
+		setHang(true)
+		_, err = watcher.checkVaultAvailability()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("checkVaultAvailability() error = %v, want a deadline error", err)
+		}
+		_, err = watcher.UpdateSecret(context.Background(), map[string]interface{}{"password": "two"}, UpdateOptions{Force: true})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("UpdateSecret() error = %v, want a deadline error", err)
+		}
+	})
+
+	t.Run("stop interrupts read", func(t *testing.T) {
+		setHang(false)
+		watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+			func() error { return nil })
+		AssertNoError(t, err, "NewWatcher()")
+		AssertNoError(t, watcher.Start(), "Start()")
+
+		setHang(true)
+		done := make(chan error, 1)
+		go func() { done <- watcher.check() }()
+		time.Sleep(50 * time.Millisecond)
+		watcher.Stop()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("check() error = %v, want a cancellation error", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Stop() did not interrupt the in-flight read")
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package vaultwatcher
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces secret values in errors, logs and events
+const Redacted = "[REDACTED]"
+
+// minRedactedLength is the shortest value scrubbed from messages. Shorter
+// values such as "1" or "true" would redact unrelated text; keys passed to
+// WithRedactedKeys are scrubbed whatever their length.
+const minRedactedLength = 6
+
+// redactPrefixLength is how many leading bytes of a value the prefix filter
+// of valueDigests covers
+const redactPrefixLength = 4
+
+// valueDigests holds SHA-256 digests of secret values by value length, so
+// messages can be scrubbed without keeping the values themselves. Values of
+// at least redactPrefixLength bytes also leave a hash of their prefix, so
+// redact only hashes windows that start like a value.
+type valueDigests struct {
+	byLength map[int]map[[sha256.Size]byte]struct{}
+	prefixes map[uint32]struct{}
+}
+
+func (d *valueDigests) add(value string) {
+	if d.byLength == nil {
+		d.byLength = map[int]map[[sha256.Size]byte]struct{}{}
+		d.prefixes = map[uint32]struct{}{}
+	}
+	if d.byLength[len(value)] == nil {
+		d.byLength[len(value)] = map[[sha256.Size]byte]struct{}{}
+	}
+	d.byLength[len(value)][sha256.Sum256([]byte(value))] = struct{}{}
+	if len(value) >= redactPrefixLength {
+		d.prefixes[prefixHash(value)] = struct{}{}
+	}
+}
+
+func (d *valueDigests) contains(value string) bool {
+	_, ok := d.byLength[len(value)][sha256.Sum256([]byte(value))]
+	return ok
+}
+
+func (d *valueDigests) hasPrefix(value string) bool {
+	_, ok := d.prefixes[prefixHash(value)]
+	return ok
+}
+
+// prefixHash is the FNV-1a hash of the first redactPrefixLength bytes of value
+func prefixHash(value string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < redactPrefixLength; i++ {
+		h = (h ^ uint32(value[i])) * 16777619
+	}
+	return h
+}
+
+// redactedError is an error whose message had secret values scrubbed. It
+// unwraps to the original error, so errors.Is and errors.As keep working.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// rememberValues records digests of the values in data, the secret just read
+// with the given hash. The values of the previous secret stay scrubbed too,
+// since a callback failing on the change may still mention them.
+func (w *Watcher) rememberValues(data map[string]interface{}, hash string) {
+	digests := &valueDigests{}
+	for key, value := range data {
+		minLength := minRedactedLength
+		if w.redactedKeys[key] {
+			minLength = 1
+		}
+		collectValues(value, minLength, digests)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if hash == w.digestsHash {
+		return
+	}
+	w.previousDigests, w.digests, w.digestsHash = w.digests, *digests, hash
+}
+
+// collectValues adds every scalar in value of at least minLength bytes. Its
+// Go-quoted and JSON-escaped forms are added too, so a value with quotes,
+// backslashes or control characters is also scrubbed from %q output and JSON.
+func collectValues(value interface{}, minLength int, digests *valueDigests) {
+	var text string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, nested := range v {
+			collectValues(nested, minLength, digests)
+		}
+		return
+	case []interface{}:
+		for _, nested := range v {
+			collectValues(nested, minLength, digests)
+		}
+		return
+	case nil:
+		return
+	case string:
+		text = v
+	case json.Number:
+		text = v.String()
+	default:
+		text = fmt.Sprint(v)
+	}
+	if len(text) < minLength {
+		return
+	}
+	digests.add(text)
+	if quoted := strconv.Quote(text); quoted[1:len(quoted)-1] != text {
+		digests.add(quoted[1 : len(quoted)-1])
+	}
+	if escaped, err := json.Marshal(text); err == nil && string(escaped[1:len(escaped)-1]) != text {
+		digests.add(string(escaped[1 : len(escaped)-1]))
+	}
+}
+
+// redact replaces every secret value found in msg with Redacted
+func (w *Watcher) redact(msg string) string {
+	w.mu.RLock()
+	sets := []valueDigests{w.digests, w.previousDigests}
+	w.mu.RUnlock()
+
+	// Short values are looked for at every offset; longer ones only where a
+	// value's prefix starts, which keeps the hashing cheap with many lengths
+	lengths := map[int]bool{}
+	for _, digests := range sets {
+		for length := range digests.byLength {
+			lengths[length] = true
+		}
+	}
+	if len(lengths) == 0 {
+		return msg
+	}
+	var short, long []int
+	for length := range lengths {
+		if length < redactPrefixLength {
+			short = append(short, length)
+		} else {
+			long = append(long, length)
+		}
+	}
+
+	// Mark every byte covered by a value, then replace each marked run
+	covered := make([]bool, len(msg))
+	found := false
+	mark := func(i, length int) {
+		window := msg[i : i+length]
+		if sets[0].contains(window) || sets[1].contains(window) {
+			for j := i; j < i+length; j++ {
+				covered[j] = true
+			}
+			found = true
+		}
+	}
+	for i := range msg {
+		for _, length := range short {
+			if i+length <= len(msg) {
+				mark(i, length)
+			}
+		}
+		if i+redactPrefixLength > len(msg) {
+			continue
+		}
+		if prefix := msg[i:]; !sets[0].hasPrefix(prefix) && !sets[1].hasPrefix(prefix) {
+			continue
+		}
+		for _, length := range long {
+			if i+length <= len(msg) {
+				mark(i, length)
+			}
+		}
+	}
+	if !found {
+		return msg
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if !covered[i] {
+			b.WriteByte(msg[i])
+			continue
+		}
+		b.WriteString(Redacted)
+		for i+1 < len(msg) && covered[i+1] {
+			i++
+		}
+	}
+	return b.String()
+}
+
+// redactError returns err with secret values scrubbed from its message
+func (w *Watcher) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if redacted := w.redact(msg); redacted != msg {
+		return &redactedError{msg: redacted, err: err}
+	}
+	return err
+}
+
+// redactPatch replaces the values in a JSON patch, keeping the operations so
+// consumers still see which keys changed. With WithPatchValues only the values
+// of redacted keys are replaced.
+func (w *Watcher) redactPatch(patch []PatchOperation) []PatchOperation {
+	if w.patchValues && len(w.redactedKeys) == 0 {
+		return patch
+	}
+
+	keys := make([]string, 0, len(w.redactedKeys))
+	for key := range w.redactedKeys {
+		keys = append(keys, "/"+escapePointer(key))
+	}
+
+	for i, op := range patch {
+		if op.Value == nil {
+			continue
+		}
+		if !w.patchValues {
+			patch[i].Value = Redacted
+			continue
+		}
+		for _, key := range keys {
+			if op.Path == key || strings.HasPrefix(op.Path, key+"/") {
+				patch[i].Value = Redacted
+				break
+			}
+		}
+	}
+	return patch
+}
@@ -0,0 +1,93 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestWatcher_RedactsCallbackErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{
+			name:    "long values",
+			wantErr: "onChange callback failed: cannot connect to postgres://app:[REDACTED]@db with pin 42 (was [REDACTED])",
+		},
+		{
+			name:    "redacted key",
+			opts:    []Option{WithRedactedKeys("pin")},
+			wantErr: "onChange callback failed: cannot connect to postgres://app:[REDACTED]@db with pin [REDACTED] (was [REDACTED])",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := vaultwatchertest.NewServer()
+			defer vault.Close()
+			vault.Put("secret/app", map[string]interface{}{"password": "old-password", "pin": "41"})
+
+			watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+				func() error {
+					return fmt.Errorf("cannot connect to postgres://app:s3cr3t-password@db with pin 42 (was old-password)")
+				}, tt.opts...)
+			AssertNoError(t, err, "NewWatcher()")
+			AssertNoError(t, watcher.Start(), "Start()")
+			defer watcher.Stop()
+
+			vault.Put("secret/app", map[string]interface{}{"password": "s3cr3t-password", "pin": "42"})
+			err = watcher.check()
+			AssertError(t, err, tt.wantErr, "check()")
+
+			var callbackErr *CallbackError
+			AssertBoolEquals(t, errors.As(err, &callbackErr), true, "errors.As(CallbackError)")
+			AssertBoolEquals(t, errors.Is(err, ErrCallbackFailed), true, "errors.Is(ErrCallbackFailed)")
+		})
+	}
+}
+
+func TestWatcher_Redact(t *testing.T) {
+	watcher := TestWatcher(t, func() error { return nil })
+	AssertStringEquals(t, watcher.redact("nothing read yet: hunter22"), "nothing read yet: hunter22", "before any read")
+
+	watcher.rememberValues(map[string]interface{}{
+		"password": "hunter22",
+		"nested":   map[string]interface{}{"token": "abcdef", "short": "abc"},
+		"list":     []interface{}{"first-item"},
+		"quoted":   `pa"ss\word`,
+		"multi":    "line-one\nline-two",
+	}, "hash")
+
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{msg: "no secrets here", want: "no secrets here"},
+		{msg: "password hunter22 rejected", want: "password [REDACTED] rejected"},
+		{msg: "token=abcdef&short=abc", want: "token=[REDACTED]&short=abc"},
+		{msg: "first-itemhunter22", want: "[REDACTED]"},
+		{msg: "hunter22", want: "[REDACTED]"},
+		{msg: fmt.Sprintf("value %q rejected", `pa"ss\word`), want: `value "[REDACTED]" rejected`},
+		{msg: `{"quoted":"pa\"ss\\word","multi":"line-one\nline-two"}`, want: `{"quoted":"[REDACTED]","multi":"[REDACTED]"}`},
+		{msg: fmt.Sprintf("%q", "line-one\nline-two"), want: `"[REDACTED]"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			AssertStringEquals(t, watcher.redact(tt.msg), tt.want, "redact()")
+		})
+	}
+
+	// The previous secret's values stay redacted after a change
+	watcher.rememberValues(map[string]interface{}{"password": "correct-horse"}, "next")
+	AssertStringEquals(t, watcher.redact("hunter22 -> correct-horse"), "[REDACTED] -> [REDACTED]", "after a change")
+	watcher.rememberValues(map[string]interface{}{"password": "battery-staple"}, "last")
+	AssertStringEquals(t, watcher.redact("hunter22"), "hunter22", "two changes later")
+
+	AssertBoolEquals(t, watcher.redactError(nil) == nil, true, "redactError(nil)")
+}
@@ -0,0 +1,75 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxStandbyRedirects bounds the redirects followed for one request. Behind
+// a load balancer mixing active and standby nodes, a redirect to the
+// balancer's address can land on another standby.
+const maxStandbyRedirects = 5
+
+// standbyRedirectTransport follows the 307 redirects standby nodes answer
+// with, re-sending the body to the active node. It sits below failover and
+// discovery, which rewrite every request to their current address and would
+// send a redirected request back to the standby. A redirect that isn't
+// followed fails with ErrVaultStandby.
+type standbyRedirectTransport struct {
+	next   http.RoundTripper
+	follow bool
+}
+
+func newStandbyRedirectTransport(next http.RoundTripper, follow bool) *standbyRedirectTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &standbyRedirectTransport{next: next, follow: follow}
+}
+
+func (t *standbyRedirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body has to be sent again to the redirect target
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	current := req
+	for redirects := 0; ; redirects++ {
+		resp, err := t.next.RoundTrip(withBody(current.Clone(current.Context()), body))
+		if err != nil || !isStandbyRedirect(resp.StatusCode) {
+			return resp, err
+		}
+
+		location, err := resp.Location()
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("vault at %s redirected without a valid location: %w", current.URL.Host, err)
+		}
+		if !t.follow {
+			return nil, fmt.Errorf("vault at %s redirected to %s, following redirects is disabled: %w",
+				current.URL.Host, location.Redacted(), ErrVaultStandby)
+		}
+		if redirects == maxStandbyRedirects {
+			return nil, fmt.Errorf("vault at %s redirected more than %d times: %w", req.URL.Host, maxStandbyRedirects, ErrVaultStandby)
+		}
+		if current.URL.Scheme == "https" && location.Scheme != "https" {
+			return nil, fmt.Errorf("vault at %s redirected to %s, which would downgrade from https", current.URL.Host, location.Redacted())
+		}
+
+		current = current.Clone(current.Context())
+		current.URL = location
+	}
+}
+
+// isStandbyRedirect reports whether status is a redirect keeping the method
+// and body, which is how standbys point to the active node
+func isStandbyRedirect(status int) bool {
+	return status == http.StatusTemporaryRedirect || status == http.StatusPermanentRedirect
+}
@@ -0,0 +1,131 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStandby redirects every request to the active node, or to itself for
+// the first loops requests like a load balancer landing on standbys
+type fakeStandby struct {
+	active string
+	self   string
+
+	mu    sync.Mutex
+	loops int
+}
+
+func (s *fakeStandby) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	target := s.active
+	if s.loops > 0 {
+		s.loops--
+		target = s.self
+	}
+	s.mu.Unlock()
+
+	http.Redirect(w, r, target+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+}
+
+// echoActive serves a secret and records the body of writes
+type echoActive struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (a *echoActive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	a.mu.Lock()
+	a.bodies = append(a.bodies, string(body))
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"data": {"data": {"node": "active"}, "metadata": {"version": 1}}}`)
+}
+
+func TestWatcher_FollowsStandbyRedirects(t *testing.T) {
+	tests := []struct {
+		name    string
+		loops   int
+		disable bool
+		wantErr error
+	}{
+		{name: "redirect to active"},
+		{name: "load balancer landing on standbys", loops: 3},
+		{name: "too many redirects", loops: maxStandbyRedirects + 1, wantErr: ErrVaultStandby},
+		{name: "redirects disabled", disable: true, wantErr: ErrVaultStandby},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := httptest.NewServer(&echoActive{})
+			defer active.Close()
+			standby := &fakeStandby{active: active.URL, loops: tt.loops}
+			standbyServer := httptest.NewServer(standby)
+			defer standbyServer.Close()
+			standby.self = standbyServer.URL
+
+			watcher, err := NewWatcher(&VaultConfig{
+				Host:             standbyServer.URL,
+				Path:             "secret/data/app",
+				Token:            "t",
+				DisableRedirects: tt.disable,
+			}, time.Hour, func() error { return nil }, WithRetryPolicy(RetryPolicy{}))
+			AssertNoError(t, err, "NewWatcher()")
+
+			data, err := watcher.fetchVaultData()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("fetchVaultData() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			AssertNoError(t, err, "fetchVaultData()")
+			AssertStringEquals(t, data["node"].(string), "active", "node")
+		})
+	}
+}
+
+func TestStandbyRedirectTransport_ResendsBody(t *testing.T) {
+	active := &echoActive{}
+	activeServer := httptest.NewServer(active)
+	defer activeServer.Close()
+	standby := httptest.NewServer(&fakeStandby{active: activeServer.URL})
+	defer standby.Close()
+
+	client := &http.Client{Transport: newStandbyRedirectTransport(nil, true)}
+	resp, err := client.Post(standby.URL+"/v1/secret/data/app", "application/json", strings.NewReader(`{"data":{"k":"v"}}`))
+	AssertNoError(t, err, "Post()")
+	resp.Body.Close()
+
+	if len(active.bodies) != 1 {
+		t.Fatalf("active node got %d requests, want 1", len(active.bodies))
+	}
+	AssertStringEquals(t, active.bodies[0], `{"data":{"k":"v"}}`, "body")
+}
+
+func TestStandbyRedirectTransport_RefusesDowngrade(t *testing.T) {
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Location", "http://vault-active.example.com/v1/secret/data/app")
+		return &http.Response{StatusCode: http.StatusTemporaryRedirect, Header: header, Body: http.NoBody, Request: req}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "https://vault.example.com/v1/secret/data/app", nil)
+	AssertNoError(t, err, "NewRequest()")
+	_, err = newStandbyRedirectTransport(next, true).RoundTrip(req)
+	AssertError(t, err, "vault at vault.example.com redirected to http://vault-active.example.com/v1/secret/data/app, which would downgrade from https", "RoundTrip()")
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
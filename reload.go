@@ -0,0 +1,216 @@
+package vaultwatcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultReloadInterval = 5 * time.Second
+
+// ConfigReloader watches a configuration file and applies changes to a running
+// WatcherGroup: the check interval, log level and added or removed paths take
+// effect without a restart. Changes of host or token are rejected.
+type ConfigReloader struct {
+	path         string
+	group        *WatcherGroup
+	pollInterval time.Duration
+	onReload     func(previous, current *FileConfig) error
+	current      *FileConfig
+	lastSum      [sha256.Size]byte
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	mu           sync.RWMutex
+	started      bool
+}
+
+// NewConfigReloader creates a reloader for a group built from the file at path
+// path: Configuration file read with LoadConfigFile
+// group: Group to update, typically created from the same file
+// pollInterval: How often to look for changes of the file (default 5s)
+// onReload: Optional callback invoked after a change was applied
+func NewConfigReloader(path string, group *WatcherGroup, pollInterval time.Duration, onReload func(previous, current *FileConfig) error) (*ConfigReloader, error) {
+	if path == "" {
+		return nil, fmt.Errorf("config file path is required")
+	}
+	if group == nil {
+		return nil, fmt.Errorf("watcher group cannot be nil")
+	}
+	if pollInterval <= 0 {
+		pollInterval = defaultReloadInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ConfigReloader{
+		path:         path,
+		group:        group,
+		pollInterval: pollInterval,
+		onReload:     onReload,
+		ctx:          ctx,
+		cancel:       cancel,
+	}, nil
+}
+
+// Start reads the file and begins watching it for changes
+func (r *ConfigReloader) Start() error {
+	r.mu.Lock()
+	if r.started {
+		r.mu.Unlock()
+		return fmt.Errorf("config reloader is already started")
+	}
+	r.started = true
+	r.mu.Unlock()
+
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, err := parseFileConfig(content)
+	if err != nil {
+		return err
+	}
+
+	r.applyLogLevel(config)
+
+	r.mu.Lock()
+	r.current = config
+	r.lastSum = sha256.Sum256(content)
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go r.run()
+
+	return nil
+}
+
+// Stop stops watching the file
+func (r *ConfigReloader) Stop() {
+	r.cancel()
+	r.wg.Wait()
+
+	r.mu.Lock()
+	r.started = false
+	r.mu.Unlock()
+}
+
+// Current returns the configuration most recently applied
+func (r *ConfigReloader) Current() *FileConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// run runs in a goroutine and reloads the file when its content changes
+func (r *ConfigReloader) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reload(); err != nil {
+				// Keep the previous configuration
+				slog.Error("Error reloading config file", "error", err)
+			}
+		}
+	}
+}
+
+// reload applies the file if its content changed since the last reload
+func (r *ConfigReloader) reload() error {
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	sum := sha256.Sum256(content)
+
+	r.mu.RLock()
+	unchanged := sum == r.lastSum
+	previous := r.current
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	config, err := parseFileConfig(content)
+	if err != nil {
+		r.remember(sum, previous)
+		return err
+	}
+	if config.Host != previous.Host || config.Token != previous.Token {
+		r.remember(sum, previous)
+		return fmt.Errorf("changing host or token requires a restart")
+	}
+
+	if err := r.apply(config); err != nil {
+		// The file isn't remembered, so the next poll retries the failed paths
+		return err
+	}
+	r.remember(sum, config)
+
+	if r.onReload != nil {
+		if err := r.onReload(previous, config); err != nil {
+			return &CallbackError{Callback: "onReload", Err: err}
+		}
+	}
+	return nil
+}
+
+// apply updates the group to match config
+func (r *ConfigReloader) apply(config *FileConfig) error {
+	var errs []error
+
+	r.applyLogLevel(config)
+	if config.Interval != r.group.Interval() {
+		if err := r.group.SetInterval(config.Interval); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	wanted := make(map[string]bool, len(config.Paths))
+	for _, path := range config.Paths {
+		wanted[path] = true
+	}
+	for _, path := range r.group.Paths() {
+		if !wanted[path] {
+			if err := r.group.RemovePath(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, path := range config.Paths {
+		if r.group.Watcher(path) == nil {
+			if err := r.group.AddPath(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyLogLevel sets the group's log level from config, which parseFileConfig
+// has validated
+func (r *ConfigReloader) applyLogLevel(config *FileConfig) {
+	level, _ := config.logLevel()
+	r.group.SetLogLevel(level)
+}
+
+// remember records the file content that was handled and the configuration in effect
+func (r *ConfigReloader) remember(sum [sha256.Size]byte, config *FileConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastSum = sum
+	r.current = config
+}
@@ -0,0 +1,106 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestConfigReloader_AppliesChanges(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		vault.Put("secret/"+name, map[string]interface{}{"value": name})
+	}
+
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, fmt.Sprintf(`{"host": %q, "token": %q, "paths": ["secret/data/a", "secret/data/b"], "interval": "1h", "log_level": "info"}`, vault.URL, vault.Token))
+
+	config, err := LoadConfigFile(path)
+	AssertNoError(t, err, "LoadConfigFile()")
+	group, err := NewWatcherGroup(config.VaultConfig(), config.Paths, config.Interval, func(string) error { return nil })
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	var reloads []string
+	reloader, err := NewConfigReloader(path, group, time.Hour, func(previous, current *FileConfig) error {
+		reloads = append(reloads, previous.LogLevel+"->"+current.LogLevel)
+		return nil
+	})
+	AssertNoError(t, err, "NewConfigReloader()")
+	AssertNoError(t, reloader.Start(), "Start()")
+	defer reloader.Stop()
+	AssertStringEquals(t, group.logLevel.Level().String(), "INFO", "log level")
+
+	// Unchanged file
+	AssertNoError(t, reloader.reload(), "reload() without change")
+	if len(reloads) != 0 {
+		t.Errorf("onReload called %d times for an unchanged file", len(reloads))
+	}
+
+	writeConfigFile(t, dir, fmt.Sprintf(`{"host": %q, "token": %q, "paths": ["secret/data/b", "secret/data/c"], "interval": "5m", "log_level": "debug"}`, vault.URL, vault.Token))
+	AssertNoError(t, reloader.reload(), "reload()")
+
+	if got := group.Paths(); !reflect.DeepEqual(got, []string{"secret/data/b", "secret/data/c"}) {
+		t.Errorf("Paths() = %v, want [secret/data/b secret/data/c]", got)
+	}
+	if group.Interval() != 5*time.Minute {
+		t.Errorf("Interval() = %v, want 5m", group.Interval())
+	}
+	if !reflect.DeepEqual(reloads, []string{"info->debug"}) {
+		t.Errorf("reloads = %v, want [info->debug]", reloads)
+	}
+	AssertStringEquals(t, group.logLevel.Level().String(), "DEBUG", "reloaded log level")
+	AssertBoolEquals(t, group.Watcher("secret/data/c").IsStarted(), true, "added path IsStarted()")
+	if group.Watcher("secret/data/c").GetCurrentHash() == "" {
+		t.Error("added path was not read")
+	}
+}
+
+func TestConfigReloader_RejectsChanges(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/a", map[string]interface{}{"value": "a"})
+
+	base := fmt.Sprintf(`{"host": %q, "token": %q, "paths": ["secret/data/a"]}`, vault.URL, vault.Token)
+
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"invalid file", `{"paths": `, "failed to parse config file: unexpected end of JSON input"},
+		{"host change", fmt.Sprintf(`{"host": "https://other.example.com", "token": %q, "paths": ["secret/data/a"]}`, vault.Token), "changing host or token requires a restart"},
+		{"token change", fmt.Sprintf(`{"host": %q, "token": "other", "paths": ["secret/data/a"]}`, vault.URL), "changing host or token requires a restart"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeConfigFile(t, dir, base)
+
+			group, err := NewWatcherGroup(&VaultConfig{Host: vault.URL, Token: vault.Token}, []string{"secret/data/a"}, time.Hour, func(string) error { return nil })
+			AssertNoError(t, err, "NewWatcherGroup()")
+			AssertNoError(t, group.Start(), "Start()")
+			defer group.Stop()
+
+			reloader, err := NewConfigReloader(path, group, time.Hour, nil)
+			AssertNoError(t, err, "NewConfigReloader()")
+			AssertNoError(t, reloader.Start(), "Start()")
+			defer reloader.Stop()
+			previous := reloader.Current()
+
+			writeConfigFile(t, dir, tt.content)
+			AssertError(t, reloader.reload(), tt.errMsg, "reload()")
+			if reloader.Current() != previous {
+				t.Error("rejected file replaced the current configuration")
+			}
+			// The rejected file is not reported again
+			AssertNoError(t, reloader.reload(), "second reload()")
+		})
+	}
+}
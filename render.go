@@ -0,0 +1,166 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// FileTemplate renders the watched secret to a file, like a Vault Agent
+// template
+type FileTemplate struct {
+	Destination string      // File to write, replaced atomically
+	Template    string      // text/template source; empty writes the secret as JSON
+	Mode        os.FileMode // Permissions of the file (default 0600)
+}
+
+// compiledTemplate is a FileTemplate ready to render
+type compiledTemplate struct {
+	FileTemplate
+	template *template.Template // Nil to write JSON
+}
+
+// templateFuncs are the functions available to file templates
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+}
+
+// loadTemplates compiles the templates given to WithFileTemplates
+func (w *Watcher) loadTemplates() error {
+	w.templates = nil
+	for _, file := range w.fileTemplates {
+		if file.Destination == "" {
+			return fmt.Errorf("file template destination is required")
+		}
+		if file.Mode == 0 {
+			file.Mode = 0600
+		}
+		compiled := compiledTemplate{FileTemplate: file}
+		if file.Template != "" {
+			parsed, err := template.New(filepath.Base(file.Destination)).Funcs(templateFuncs).Option("missingkey=error").Parse(file.Template)
+			if err != nil {
+				return fmt.Errorf("invalid template for %s: %w", file.Destination, err)
+			}
+			compiled.template = parsed
+		}
+		w.templates = append(w.templates, compiled)
+	}
+	return nil
+}
+
+// renderTemplates renders data to every file template. All files are
+// rendered before any is replaced, so a template failing leaves every file
+// as it was.
+func (w *Watcher) renderTemplates(data map[string]interface{}) error {
+	if len(w.templates) == 0 {
+		return nil
+	}
+
+	staged := make([]string, 0, len(w.templates))
+	defer func() {
+		for _, path := range staged {
+			os.Remove(path)
+		}
+	}()
+
+	for _, file := range w.templates {
+		var content bytes.Buffer
+		if file.template == nil {
+			encoder := json.NewEncoder(&content)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(data); err != nil {
+				return fmt.Errorf("failed to render %s: %w", file.Destination, err)
+			}
+		} else if err := file.template.Execute(&content, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", file.Destination, err)
+		}
+
+		path, err := stageFile(file.Destination, content.Bytes(), file.Mode)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Destination, err)
+		}
+		staged = append(staged, path)
+	}
+
+	for len(staged) > 0 {
+		file := w.templates[len(w.templates)-len(staged)]
+		if err := os.Rename(staged[0], file.Destination); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", file.Destination, err)
+		}
+		staged = staged[1:]
+	}
+	return nil
+}
+
+// renderedFile is the destination of a file template before rendering
+type renderedFile struct {
+	content []byte
+	exists  bool
+}
+
+// readTemplates returns the destination of every file template, so a change
+// that fails after renderTemplates can be reverted with restoreTemplates
+func (w *Watcher) readTemplates() []renderedFile {
+	files := make([]renderedFile, len(w.templates))
+	for i, file := range w.templates {
+		content, err := os.ReadFile(file.Destination)
+		files[i] = renderedFile{content: content, exists: err == nil}
+	}
+	return files
+}
+
+// restoreTemplates writes back the destinations returned by readTemplates
+// that were replaced, removing those that didn't exist
+func (w *Watcher) restoreTemplates(files []renderedFile) {
+	for i, previous := range files {
+		destination := w.templates[i].Destination
+		current, err := os.ReadFile(destination)
+		if (err == nil) == previous.exists && bytes.Equal(current, previous.content) {
+			continue
+		}
+		if !previous.exists {
+			if err := os.Remove(destination); err != nil && !errors.Is(err, os.ErrNotExist) {
+				w.logger.Error("Error restoring rendered file", "path", destination, "error", err)
+			}
+			continue
+		}
+		path, err := stageFile(destination, previous.content, w.templates[i].Mode)
+		if err == nil {
+			if err = os.Rename(path, destination); err != nil {
+				os.Remove(path)
+			}
+		}
+		if err != nil {
+			w.logger.Error("Error restoring rendered file", "path", destination, "error", err)
+		}
+	}
+}
+
+// stageFile writes content to a temporary file next to destination, so it
+// can be renamed over it
+func stageFile(destination string, content []byte, mode os.FileMode) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(destination), "."+filepath.Base(destination)+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Chmod(mode)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
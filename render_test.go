@@ -0,0 +1,78 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher_FileTemplates_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template FileTemplate
+		wantErr  string
+	}{
+		{name: "no destination", template: FileTemplate{Template: "x"}, wantErr: "file template destination is required"},
+		{name: "bad template", template: FileTemplate{Destination: "/tmp/app.env", Template: "{{ .password"},
+			wantErr: "invalid template for /tmp/app.env: template: app.env:1: unclosed action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil }, WithFileTemplates(tt.template))
+			AssertError(t, err, tt.wantErr, "NewSourceWatcher()")
+		})
+	}
+}
+
+func TestWatcher_FileTemplates(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "db.env")
+	jsonFile := filepath.Join(dir, "app.json")
+	read := func(path string) string {
+		content, err := os.ReadFile(path)
+		AssertNoError(t, err, "ReadFile("+filepath.Base(path)+")")
+		return string(content)
+	}
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one", "hosts": []interface{}{"db-1"}})
+	seen := make(chan string, 4)
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error {
+		// The callback sees the rendered files
+		seen <- read(envFile)
+		return nil
+	}, WithFileTemplates(
+		FileTemplate{Destination: envFile, Template: "DB_PASSWORD={{ .password }}\nDB_HOSTS={{ json .hosts }}\n", Mode: 0640},
+		FileTemplate{Destination: jsonFile},
+	))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	AssertStringEquals(t, read(envFile), "DB_PASSWORD=one\nDB_HOSTS=[\"db-1\"]\n", "env file after Start")
+	AssertStringEquals(t, read(jsonFile), "{\n  \"hosts\": [\n    \"db-1\"\n  ],\n  \"password\": \"one\"\n}\n", "json file after Start")
+	info, err := os.Stat(envFile)
+	AssertNoError(t, err, "Stat()")
+	AssertStringEquals(t, info.Mode().Perm().String(), "-rw-r-----", "env file mode")
+
+	source.set(map[string]interface{}{"password": "two", "hosts": []interface{}{"db-1"}})
+	select {
+	case content := <-seen:
+		AssertStringEquals(t, content, "DB_PASSWORD=two\nDB_HOSTS=[\"db-1\"]\n", "env file seen by onChange")
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called")
+	}
+
+	// A missing key fails the change and leaves every file as it was
+	before := read(jsonFile)
+	source.set(map[string]interface{}{"hosts": []interface{}{"db-2"}})
+	waitFor(t, time.Second, func() bool { return watcher.Status().ConsecutiveFailures > 0 }, "failed render")
+	AssertStringEquals(t, read(envFile), "DB_PASSWORD=two\nDB_HOSTS=[\"db-1\"]\n", "env file after the failed render")
+	AssertStringEquals(t, read(jsonFile), before, "json file after the failed render")
+	entries, err := os.ReadDir(dir)
+	AssertNoError(t, err, "ReadDir()")
+	AssertStringEquals(t, fmt.Sprint(len(entries)), "2", "files left in the directory")
+}
@@ -0,0 +1,58 @@
+package vaultwatcher
+
+import "github.com/hashicorp/vault/api"
+
+// Consistency selects how reads behave against Vault Enterprise performance
+// standbys, which may serve data that lags behind the active node
+type Consistency int
+
+const (
+	// ConsistencyDefault sends reads wherever the load balancer routes them
+	ConsistencyDefault Consistency = iota
+	// ConsistencyReadYourWrites sends the X-Vault-Index of earlier responses
+	// with each request, so a standby that hasn't caught up retries until it
+	// has, instead of serving older data
+	ConsistencyReadYourWrites
+	// ConsistencyForwardInconsistent is like ConsistencyReadYourWrites, but a
+	// standby that hasn't caught up forwards the request to the active node
+	ConsistencyForwardInconsistent
+	// ConsistencyActiveNode forwards every request to the active node
+	ConsistencyActiveNode
+)
+
+const (
+	inconsistentHeader = "X-Vault-Inconsistent"
+	forwardHeader      = "X-Vault-Forward"
+)
+
+// apply configures a client for the consistency mode
+func (c Consistency) apply(client *api.Client) {
+	switch c {
+	case ConsistencyReadYourWrites:
+		client.SetReadYourWrites(true)
+	case ConsistencyForwardInconsistent:
+		client.SetReadYourWrites(true)
+		client.AddHeader(inconsistentHeader, "forward-active-node")
+	case ConsistencyActiveNode:
+		client.AddHeader(forwardHeader, "active-node")
+	}
+}
+
+// isStaleRead reports whether the last read returned an older KV v2 version
+// than the one applied, which a lagging standby can serve. Such reads are
+// ignored instead of being reported as a change back to old data. Versions
+// restart at 1 when a secret is deleted with its metadata and written again,
+// so a lower version created after the applied one isn't stale.
+func (w *Watcher) isStaleRead() bool {
+	if w.consistency == ConsistencyDefault {
+		return false
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.pinnedVersion != 0 || w.readVersion == 0 || w.readVersion >= w.currentVersion {
+		return false
+	}
+	recreated := !w.readCreated.IsZero() && !w.currentCreated.IsZero() && w.readCreated.After(w.currentCreated)
+	return !recreated
+}
@@ -0,0 +1,152 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestWithConsistency_Headers(t *testing.T) {
+	tests := []struct {
+		name             string
+		consistency      Consistency
+		wantInconsistent string
+		wantForward      string
+	}{
+		{name: "default", consistency: ConsistencyDefault},
+		{name: "read your writes", consistency: ConsistencyReadYourWrites},
+		{name: "forward inconsistent", consistency: ConsistencyForwardInconsistent, wantInconsistent: "forward-active-node"},
+		{name: "active node", consistency: ConsistencyActiveNode, wantForward: "active-node"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var headers http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				headers = r.Header.Clone()
+				mu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"data": {"data": {"password": "one"}, "metadata": {"version": 1}}}`)
+			}))
+			defer server.Close()
+
+			watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+				func() error { return nil }, WithConsistency(tt.consistency))
+			AssertNoError(t, err, "NewWatcher()")
+			_, err = watcher.fetchVaultData()
+			AssertNoError(t, err, "fetchVaultData()")
+
+			mu.Lock()
+			defer mu.Unlock()
+			AssertStringEquals(t, headers.Get("X-Vault-Inconsistent"), tt.wantInconsistent, "X-Vault-Inconsistent")
+			AssertStringEquals(t, headers.Get("X-Vault-Forward"), tt.wantForward, "X-Vault-Forward")
+		})
+	}
+}
+
+func TestWithConsistency_StaleReads(t *testing.T) {
+	tests := []struct {
+		name        string
+		consistency Consistency
+		versions    []int
+		wantChanges int
+	}{
+		{name: "stale read ignored", consistency: ConsistencyReadYourWrites, versions: []int{2, 1, 3}, wantChanges: 2},
+		{name: "stale read flaps without consistency", consistency: ConsistencyDefault, versions: []int{2, 1, 3}, wantChanges: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			version := 1
+			reader := SecretReaderFunc(func(string) (*api.Secret, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return &api.Secret{Data: map[string]interface{}{
+					"data":     map[string]interface{}{"password": fmt.Sprintf("v%d", version)},
+					"metadata": map[string]interface{}{"version": version},
+				}}, nil
+			})
+
+			changes := 0
+			watcher, err := NewWatcher(TestVaultConfig(), time.Hour, func() error {
+				changes++
+				return nil
+			}, WithSecretReader(reader), WithConsistency(tt.consistency))
+			AssertNoError(t, err, "NewWatcher()")
+			AssertNoError(t, watcher.Start(), "Start()")
+			defer watcher.Stop()
+
+			for _, v := range tt.versions {
+				mu.Lock()
+				version = v
+				mu.Unlock()
+				AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+			}
+
+			if changes != tt.wantChanges {
+				t.Errorf("changes = %d, want %d", changes, tt.wantChanges)
+			}
+			if watcher.CurrentVersion() != 3 {
+				t.Errorf("CurrentVersion() = %d, want 3", watcher.CurrentVersion())
+			}
+		})
+	}
+}
+
+func TestWithConsistency_RecreatedSecret(t *testing.T) {
+	type read struct {
+		version int
+		created time.Time
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	current := read{version: 1, created: base}
+	reader := SecretReaderFunc(func(string) (*api.Secret, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return &api.Secret{Data: map[string]interface{}{
+			"data": map[string]interface{}{"password": fmt.Sprintf("v%d-%d", current.version, current.created.Unix())},
+			"metadata": map[string]interface{}{
+				"version":      current.version,
+				"created_time": current.created.Format(time.RFC3339Nano),
+			},
+		}}, nil
+	})
+
+	changes := 0
+	watcher, err := NewWatcher(TestVaultConfig(), time.Hour, func() error {
+		changes++
+		return nil
+	}, WithSecretReader(reader), WithConsistency(ConsistencyReadYourWrites))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	reads := []read{
+		{version: 3, created: base.Add(3 * time.Minute)},
+		{version: 2, created: base.Add(2 * time.Minute)}, // Stale standby read
+		{version: 1, created: base.Add(time.Hour)},       // Deleted with its metadata and written again
+		{version: 2, created: base.Add(2 * time.Hour)},
+	}
+	for _, r := range reads {
+		mu.Lock()
+		current = r
+		mu.Unlock()
+		AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	}
+
+	if changes != 3 {
+		t.Errorf("changes = %d, want 3", changes)
+	}
+	if watcher.CurrentVersion() != 2 {
+		t.Errorf("CurrentVersion() = %d, want 2", watcher.CurrentVersion())
+	}
+}
@@ -0,0 +1,93 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// RetryPolicy tunes how the Vault client retries requests that failed with a
+// connection error, a 5xx response or 412. Retries happen within one Vault API
+// call, so they count towards WithRequestTimeout. A 503 from a sealed or
+// uninitialized Vault is never retried: it won't change within the retry
+// waits, and the watcher handles it with WithSealAwareness or WithUnsealWait.
+type RetryPolicy struct {
+	// MaxRetries is how often a request is retried; 0 disables retries.
+	// The Vault client's default is 2.
+	MaxRetries int
+	// MinWait and MaxWait bound the wait between retries (client defaults
+	// 1s and 1.5s when zero)
+	MinWait time.Duration
+	MaxWait time.Duration
+	// Backoff computes the wait before a retry; nil keeps the client's
+	// linear jitter backoff
+	Backoff func(min, max time.Duration, attempt int, resp *http.Response) time.Duration
+}
+
+// validate checks the policy's limits
+func (p *RetryPolicy) validate() error {
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("max retries cannot be negative")
+	}
+	if p.MinWait < 0 || p.MaxWait < 0 {
+		return fmt.Errorf("retry wait cannot be negative")
+	}
+	if p.MinWait > 0 && p.MaxWait > 0 && p.MinWait > p.MaxWait {
+		return fmt.Errorf("minimum retry wait %v exceeds maximum %v", p.MinWait, p.MaxWait)
+	}
+	return nil
+}
+
+// apply sets the policy on a Vault client configuration
+func (p *RetryPolicy) apply(config *api.Config) {
+	config.MaxRetries = p.MaxRetries
+	if p.MinWait > 0 {
+		config.MinRetryWait = p.MinWait
+	}
+	if p.MaxWait > 0 {
+		config.MaxRetryWait = p.MaxWait
+	}
+	if p.Backoff != nil {
+		config.Backoff = p.Backoff
+	}
+}
+
+// maxSealedBody caps how much of a 503 response checkRetry reads
+const maxSealedBody = 64 << 10
+
+// checkRetry is the Vault client's retry policy, except that responses from a
+// sealed or uninitialized Vault are returned at once
+func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if errors.Is(err, ErrVaultSealed) {
+		// Every failover address is sealed
+		return false, nil
+	}
+	if err == nil && resp != nil && resp.StatusCode == http.StatusServiceUnavailable && isSealedResponse(resp) {
+		return false, nil
+	}
+	return api.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// isSealedResponse reports whether the error messages of resp say Vault is
+// sealed or not initialized. The body is restored for the caller.
+func isSealedResponse(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSealedBody))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "vault is sealed") || strings.Contains(message, "not initialized")
+}
@@ -0,0 +1,110 @@
+package vaultwatcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      *RetryPolicy
+		wantRetries int
+		wantMinWait time.Duration
+		wantMaxWait time.Duration
+		wantErr     string
+	}{
+		{name: "client defaults", wantRetries: 2, wantMinWait: time.Second, wantMaxWait: 1500 * time.Millisecond},
+		{name: "no retries", policy: &RetryPolicy{}, wantRetries: 0, wantMinWait: time.Second, wantMaxWait: 1500 * time.Millisecond},
+		{
+			name:        "patient",
+			policy:      &RetryPolicy{MaxRetries: 5, MinWait: 2 * time.Second, MaxWait: 30 * time.Second},
+			wantRetries: 5, wantMinWait: 2 * time.Second, wantMaxWait: 30 * time.Second,
+		},
+		{name: "negative retries", policy: &RetryPolicy{MaxRetries: -1}, wantErr: "invalid retry policy: max retries cannot be negative"},
+		{name: "negative wait", policy: &RetryPolicy{MinWait: -time.Second}, wantErr: "invalid retry policy: retry wait cannot be negative"},
+		{
+			name:    "min above max",
+			policy:  &RetryPolicy{MinWait: 5 * time.Second, MaxWait: time.Second},
+			wantErr: "invalid retry policy: minimum retry wait 5s exceeds maximum 1s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.policy != nil {
+				opts = append(opts, WithRetryPolicy(*tt.policy))
+			}
+			watcher, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil }, opts...)
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "NewWatcher()")
+				return
+			}
+			AssertNoError(t, err, "NewWatcher()")
+
+			if got := watcher.client.MaxRetries(); got != tt.wantRetries {
+				t.Errorf("MaxRetries() = %d, want %d", got, tt.wantRetries)
+			}
+			if got := watcher.client.MinRetryWait(); got != tt.wantMinWait {
+				t.Errorf("MinRetryWait() = %v, want %v", got, tt.wantMinWait)
+			}
+			if got := watcher.client.MaxRetryWait(); got != tt.wantMaxWait {
+				t.Errorf("MaxRetryWait() = %v, want %v", got, tt.wantMaxWait)
+			}
+		})
+	}
+}
+
+func TestWithGroupRetryPolicy(t *testing.T) {
+	group, err := NewWatcherGroup(TestVaultConfig(), []string{"secret/data/a"}, time.Hour,
+		func(string) error { return nil }, WithGroupRetryPolicy(RetryPolicy{MaxRetries: 7}))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	if got := group.client.MaxRetries(); got != 7 {
+		t.Errorf("MaxRetries() = %d, want 7", got)
+	}
+}
+
+func TestCheckRetry_Sealed(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantReads int32
+		wantErr   error
+	}{
+		{name: "sealed", body: `{"errors":["Vault is sealed"]}`, wantReads: 1, wantErr: ErrVaultSealed},
+		{name: "not initialized", body: `{"errors":["Vault is not initialized"]}`, wantReads: 1, wantErr: ErrVaultSealed},
+		{name: "other 503", body: `{"errors":["upstream unavailable"]}`, wantReads: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reads int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&reads, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+				func() error { return nil }, WithRetryPolicy(RetryPolicy{MaxRetries: 2, MinWait: time.Millisecond, MaxWait: 2 * time.Millisecond}))
+			AssertNoError(t, err, "NewWatcher()")
+
+			_, err = watcher.fetchVaultData()
+			if err == nil {
+				t.Fatal("fetchVaultData() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("fetchVaultData() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&reads); got != tt.wantReads {
+				t.Errorf("got %d requests, want %d", got, tt.wantReads)
+			}
+		})
+	}
+}
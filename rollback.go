@@ -0,0 +1,162 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RollbackMode selects how RollbackToPreviousVersion restores a version
+type RollbackMode int
+
+const (
+	// RollbackWrite writes the previous data back as a new version, so every
+	// reader of the secret gets it
+	RollbackWrite RollbackMode = iota
+	// RollbackPin only pins this watcher's reads to the previous version
+	RollbackPin
+)
+
+// String returns "write" or "pin"
+func (m RollbackMode) String() string {
+	if m == RollbackPin {
+		return "pin"
+	}
+	return "write"
+}
+
+// RolledBackEvent describes a rollback done by RollbackToPreviousVersion
+type RolledBackEvent struct {
+	Path        string    `json:"path"`
+	Mode        string    `json:"mode"`
+	FromVersion int       `json:"from_version"`          // Latest version when rolling back
+	ToVersion   int       `json:"to_version"`            // Version whose data was restored
+	NewVersion  int       `json:"new_version,omitempty"` // Version written by RollbackWrite
+	Reason      string    `json:"reason,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// RollbackNotifier is implemented by notifiers that want rollback events.
+// Notifiers registered with WithNotifier are checked for it automatically.
+type RollbackNotifier interface {
+	NotifyRolledBack(ctx context.Context, event RolledBackEvent) error
+}
+
+// RollbackToPreviousVersion restores the KV v2 version the watcher last
+// applied, typically from an onChange callback whose validation rejected a
+// new version. Returning an error from the callback afterwards keeps the
+// watcher on the previous data. Without a known applied version, the latest
+// live version before the current one is restored. RollbackWrite fails with
+// ErrVersionConflict if another version was written since listing them.
+func (w *Watcher) RollbackToPreviousVersion(mode RollbackMode, reason string) (RolledBackEvent, error) {
+	versions, err := w.ListVersions()
+	if err != nil {
+		return RolledBackEvent{}, fmt.Errorf("failed to roll back: %w", err)
+	}
+
+	latest := 0
+	for _, version := range versions {
+		if version.Current {
+			latest = version.Version
+		}
+	}
+
+	target := w.CurrentVersion()
+	if target == 0 || target >= latest {
+		target = 0
+		for _, version := range versions {
+			if version.Version < latest && version.DeletionTime.IsZero() && !version.Destroyed {
+				target = version.Version
+			}
+		}
+	}
+	if target == 0 {
+		return RolledBackEvent{}, fmt.Errorf("failed to roll back: no previous version of %s", w.vaultConfig.Path)
+	}
+
+	event := RolledBackEvent{
+		Path:        w.vaultConfig.Path,
+		Mode:        mode.String(),
+		FromVersion: latest,
+		ToVersion:   target,
+		Reason:      reason,
+	}
+
+	switch mode {
+	case RollbackPin:
+		if err := w.PinVersion(target); err != nil {
+			return RolledBackEvent{}, fmt.Errorf("failed to roll back: %w", err)
+		}
+	case RollbackWrite:
+		newVersion, err := w.rewriteVersion(target, latest)
+		if err != nil {
+			return RolledBackEvent{}, fmt.Errorf("failed to roll back: %w", err)
+		}
+		event.NewVersion = newVersion
+	default:
+		return RolledBackEvent{}, fmt.Errorf("unknown rollback mode %d", mode)
+	}
+
+	event.Timestamp = time.Now().UTC()
+	w.notifyRolledBack(event)
+
+	return event, nil
+}
+
+// rewriteVersion writes the data of a version back as the latest version.
+// The write uses check-and-set against latest, so it fails with
+// ErrVersionConflict instead of overwriting a version written meanwhile.
+func (w *Watcher) rewriteVersion(version, latest int) (int, error) {
+	reader, ok := w.secretReader().(VersionedSecretReader)
+	if !ok {
+		return 0, fmt.Errorf("secret reader cannot read previous versions")
+	}
+	readCtx, cancelRead := w.requestContext()
+	secret, err := w.readWithData(readCtx, reader, w.vaultConfig.Path, map[string][]string{"version": {strconv.Itoa(version)}})
+	cancelRead()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read version %d: %w", version, classifyVaultError(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return 0, fmt.Errorf("failed to read version %d: %w", version, ErrSecretNotFound)
+	}
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("failed to read version %d: secret data is nil", version)
+	}
+
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	written, err := w.client.Logical().WriteWithContext(ctx, w.vaultConfig.Path, map[string]interface{}{
+		"data":    data,
+		"options": map[string]interface{}{"cas": latest},
+	})
+	if err != nil {
+		err = classifyVaultError(err)
+		if errors.Is(err, ErrVersionConflict) {
+			return 0, fmt.Errorf("secret %s changed since version %d: %w", w.vaultConfig.Path, latest, err)
+		}
+		return 0, fmt.Errorf("failed to write version %d back: %w", version, err)
+	}
+	if written == nil || written.Data == nil {
+		return 0, nil
+	}
+	newVersion, _ := strconv.Atoi(fmt.Sprint(written.Data["version"]))
+	return newVersion, nil
+}
+
+// notifyRolledBack delivers the event to every notifier implementing RollbackNotifier
+func (w *Watcher) notifyRolledBack(event RolledBackEvent) {
+	for _, notifier := range w.notifiers {
+		rollbackNotifier, ok := notifier.(RollbackNotifier)
+		if !ok {
+			continue
+		}
+		if err := rollbackNotifier.NotifyRolledBack(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying vault rollback", "error", w.redactError(err))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// rollbackRecorder collects rollback events
+type rollbackRecorder struct {
+	mu     sync.Mutex
+	events []RolledBackEvent
+}
+
+func (r *rollbackRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *rollbackRecorder) NotifyRolledBack(ctx context.Context, event RolledBackEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *rollbackRecorder) all() []RolledBackEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RolledBackEvent(nil), r.events...)
+}
+
+func TestWatcher_RollbackToPreviousVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         RollbackMode
+		wantLatest   int
+		wantNew      int
+		wantPassword string
+	}{
+		{name: "write", mode: RollbackWrite, wantLatest: 3, wantNew: 3, wantPassword: "good"},
+		{name: "pin", mode: RollbackPin, wantLatest: 2, wantNew: 0, wantPassword: "bad"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := vaultwatchertest.NewServer()
+			defer vault.Close()
+			vault.Put("secret/app", map[string]interface{}{"password": "good"})
+
+			recorder := &rollbackRecorder{}
+			var watcher *Watcher
+			changes := 0
+			validate := func() error {
+				changes++
+				data, err := watcher.fetchVaultData()
+				if err != nil {
+					return err
+				}
+				if data["password"] != "bad" {
+					return nil
+				}
+				if _, err := watcher.RollbackToPreviousVersion(tt.mode, "invalid password"); err != nil {
+					return err
+				}
+				return errors.New("invalid password")
+			}
+
+			var err error
+			watcher, err = NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+				validate, WithNotifier(recorder))
+			AssertNoError(t, err, "NewWatcher()")
+			AssertNoError(t, watcher.Start(), "Start()")
+			defer watcher.Stop()
+
+			if watcher.CurrentVersion() != 1 {
+				t.Errorf("CurrentVersion() = %d, want 1", watcher.CurrentVersion())
+			}
+
+			vault.Put("secret/app", map[string]interface{}{"password": "bad"})
+			AssertError(t, watcher.check(), "onChange callback failed: invalid password", "check with bad version")
+
+			events := recorder.all()
+			if len(events) != 1 {
+				t.Fatalf("events = %v, want one rollback", events)
+			}
+			event := events[0]
+			AssertStringEquals(t, event.Mode, tt.mode.String(), "Mode")
+			AssertStringEquals(t, event.Reason, "invalid password", "Reason")
+			if event.FromVersion != 2 || event.ToVersion != 1 || event.NewVersion != tt.wantNew {
+				t.Errorf("event = %+v, want 2 -> 1 with new version %d", event, tt.wantNew)
+			}
+
+			versions, err := watcher.ListVersions()
+			AssertNoError(t, err, "ListVersions()")
+			if len(versions) != tt.wantLatest {
+				t.Errorf("len(versions) = %d, want %d", len(versions), tt.wantLatest)
+			}
+
+			// The restored data matches what was applied, so nothing changes
+			AssertNoError(t, watcher.check(), "check after rollback")
+			if changes != 1 {
+				t.Errorf("changes = %d, want 1", changes)
+			}
+
+			client, err := api.NewClient(&api.Config{Address: vault.URL})
+			AssertNoError(t, err, "NewClient()")
+			client.SetToken(vault.Token)
+			secret, err := client.Logical().Read("secret/data/app")
+			AssertNoError(t, err, "Read()")
+			AssertStringEquals(t, secret.Data["data"].(map[string]interface{})["password"].(string), tt.wantPassword, "latest password")
+		})
+	}
+}
+
+func TestWatcher_RollbackWithoutPreviousVersion(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "only"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+
+	_, err = watcher.RollbackToPreviousVersion(RollbackWrite, "")
+	AssertError(t, err, "failed to roll back: no previous version of secret/data/app", "RollbackToPreviousVersion()")
+}
+
+func TestWatcher_RollbackWriteConflict(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "good"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	// Version 3 is written after the rollback listed version 2 as the latest
+	vault.Put("secret/app", map[string]interface{}{"password": "bad"})
+	vault.Put("secret/app", map[string]interface{}{"password": "fixed"})
+	_, err = watcher.rewriteVersion(1, 2)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("rewriteVersion() error = %v, want ErrVersionConflict", err)
+	}
+
+	versions, err := watcher.ListVersions()
+	AssertNoError(t, err, "ListVersions()")
+	if len(versions) != 3 {
+		t.Errorf("len(versions) = %d, want 3", len(versions))
+	}
+	data, err := watcher.fetchVaultData()
+	AssertNoError(t, err, "fetchVaultData()")
+	AssertStringEquals(t, data["password"].(string), "fixed", "latest password")
+}
@@ -0,0 +1,110 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const defaultRolloutAnnotation = "checksum/vault"
+
+// rolloutResources maps the workload kinds a KubernetesRolloutNotifier
+// restarts to their resource
+var rolloutResources = map[string]string{
+	"Deployment":  "deployments",
+	"StatefulSet": "statefulsets",
+	"DaemonSet":   "daemonsets",
+}
+
+// KubernetesRolloutConfig holds the configuration for a KubernetesRolloutNotifier
+type KubernetesRolloutConfig struct {
+	Kind       string       // Deployment, StatefulSet or DaemonSet (default Deployment)
+	Name       string       // Name of the workload
+	Namespace  string       // Namespace of the workload (default: the pod's namespace)
+	Annotation string       // Pod template annotation set to the hash (default "checksum/vault")
+	APIServer  string       // API server URL (default "https://kubernetes.default.svc")
+	Token      string       // Bearer token (default: the pod's service account token)
+	HTTPClient *http.Client // Optional custom HTTP client (default trusts the service account CA)
+}
+
+// KubernetesRolloutNotifier restarts a workload when the watched secret
+// changes, by setting a pod template annotation to the new hash. Kubernetes
+// then rolls the pods as for any template change, respecting the workload's
+// update strategy. The service account needs patch on the workload.
+type KubernetesRolloutNotifier struct {
+	config KubernetesRolloutConfig
+	client *http.Client
+}
+
+// NewKubernetesRolloutNotifier creates a rollout notifier. Defaults are taken
+// from the pod's service account when running in a cluster.
+func NewKubernetesRolloutNotifier(config KubernetesRolloutConfig) (*KubernetesRolloutNotifier, error) {
+	if config.Kind == "" {
+		config.Kind = "Deployment"
+	}
+	if _, ok := rolloutResources[config.Kind]; !ok {
+		return nil, fmt.Errorf("workload kind must be Deployment, StatefulSet or DaemonSet, got %q", config.Kind)
+	}
+	if config.Name == "" {
+		return nil, fmt.Errorf("workload name is required")
+	}
+	if config.Annotation == "" {
+		config.Annotation = defaultRolloutAnnotation
+	}
+	if config.Namespace == "" {
+		namespace, err := serviceAccountFile("namespace")
+		if err != nil {
+			return nil, fmt.Errorf("workload namespace is required outside a cluster: %w", err)
+		}
+		config.Namespace = namespace
+	}
+
+	var client *http.Client
+	var err error
+	config.APIServer, config.Token, client, err = kubernetesClient(config.APIServer, config.Token, config.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+	return &KubernetesRolloutNotifier{config: config, client: client}, nil
+}
+
+// Notify rolls the workload out with the new hash of the change
+func (n *KubernetesRolloutNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	return n.Rollout(ctx, event.NewHash)
+}
+
+// Rollout sets the annotation to checksum, which restarts the workload
+// unless it already has that value. It can be called from onChange to fail
+// the change when the rollout can't be triggered.
+func (n *KubernetesRolloutNotifier) Rollout(ctx context.Context, checksum string) error {
+	workloadURL := fmt.Sprintf("%s/apis/apps/v1/namespaces/%s/%s/%s", n.config.APIServer,
+		url.PathEscape(n.config.Namespace), rolloutResources[n.config.Kind], url.PathEscape(n.config.Name))
+	_, err := patchJSON(ctx, n.client, n.config.Token, workloadURL, podTemplateAnnotation(n.config.Annotation, checksum))
+	if err != nil {
+		return fmt.Errorf("failed to roll out %s %s: %w", n.config.Kind, n.config.Name, err)
+	}
+	return nil
+}
+
+// podTemplateAnnotation returns a merge patch setting an annotation of a
+// workload's pod template
+func podTemplateAnnotation(annotation, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{annotation: value},
+				},
+			},
+		},
+	}
+}
+
+// patchJSON sends a JSON merge patch to the Kubernetes API
+func patchJSON(ctx context.Context, client *http.Client, token, requestURL string, patch interface{}) (int, error) {
+	return doJSON(ctx, client, http.MethodPatch, requestURL, patch, nil, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	})
+}
@@ -0,0 +1,87 @@
+package vaultwatcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewKubernetesRolloutNotifier_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  KubernetesRolloutConfig
+		wantErr string
+	}{
+		{name: "bad kind", config: KubernetesRolloutConfig{Kind: "Pod", Name: "api"}, wantErr: `workload kind must be Deployment, StatefulSet or DaemonSet, got "Pod"`},
+		{name: "no name", config: KubernetesRolloutConfig{}, wantErr: "workload name is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKubernetesRolloutNotifier(tt.config)
+			AssertError(t, err, tt.wantErr, "NewKubernetesRolloutNotifier()")
+		})
+	}
+}
+
+func TestKubernetesRolloutNotifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    KubernetesRolloutConfig
+		wantPath  string
+		wantPatch string
+		status    int
+		wantErr   string
+	}{
+		{
+			name:      "deployment",
+			config:    KubernetesRolloutConfig{Name: "billing-api"},
+			wantPath:  "/apis/apps/v1/namespaces/apps/deployments/billing-api",
+			wantPatch: `{"spec":{"template":{"metadata":{"annotations":{"checksum/vault":"abc123"}}}}}`,
+		},
+		{
+			name:      "statefulset",
+			config:    KubernetesRolloutConfig{Kind: "StatefulSet", Name: "ledger", Annotation: "example.com/vault-hash"},
+			wantPath:  "/apis/apps/v1/namespaces/apps/statefulsets/ledger",
+			wantPatch: `{"spec":{"template":{"metadata":{"annotations":{"example.com/vault-hash":"abc123"}}}}}`,
+		},
+		{
+			name:     "forbidden",
+			config:   KubernetesRolloutConfig{Name: "billing-api"},
+			wantPath: "/apis/apps/v1/namespaces/apps/deployments/billing-api",
+			status:   http.StatusForbidden,
+			wantErr:  "failed to roll out Deployment billing-api: unexpected status 403: forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, patch, contentType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				path, patch, contentType = r.URL.Path, string(body), r.Header.Get("Content-Type")
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					io.WriteString(w, "forbidden")
+				}
+			}))
+			defer server.Close()
+
+			tt.config.Namespace, tt.config.APIServer, tt.config.Token, tt.config.HTTPClient = "apps", server.URL, "sa-token", server.Client()
+			notifier, err := NewKubernetesRolloutNotifier(tt.config)
+			AssertNoError(t, err, "NewKubernetesRolloutNotifier()")
+
+			err = notifier.Notify(context.Background(), ChangeEvent{NewHash: "abc123"})
+			AssertStringEquals(t, path, tt.wantPath, "patched path")
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "Notify()")
+				return
+			}
+			AssertNoError(t, err, "Notify()")
+			AssertStringEquals(t, patch, tt.wantPatch, "patch")
+			AssertStringEquals(t, contentType, "application/merge-patch+json", "Content-Type")
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Run starts the watcher and blocks until ctx is cancelled or a check fails
+// with a fatal error, then stops it. It returns nil once ctx is cancelled,
+// and otherwise the error of Start or of the fatal check, so watchers can run
+// under errgroup or oklog/run instead of pairing Start and Stop. Only
+// ErrCubbyholeInaccessible and the errors of WithFatalErrors are fatal; other
+// failed checks are retried as they are with Start. Cancelling ctx also
+// cancels in-flight requests, and Run can be called again once it returned.
+func (w *Watcher) Run(ctx context.Context) error {
+	return w.run(ctx, nil)
+}
+
+// run is Run, calling started, if not nil, once the watcher started
+func (w *Watcher) run(ctx context.Context, started func()) error {
+	fatal := make(chan error, 1)
+	w.mu.Lock()
+	if w.started || w.fatal != nil {
+		w.mu.Unlock()
+		return fmt.Errorf("Run can't be used while the watcher is started")
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.fatal = fatal
+	w.mu.Unlock()
+	defer func() {
+		w.Stop()
+		w.mu.Lock()
+		w.fatal = nil
+		w.mu.Unlock()
+	}()
+
+	if err := w.Start(); err != nil {
+		return err
+	}
+	if started != nil {
+		started()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-fatal:
+		return err
+	}
+}
+
+// isFatal reports whether err should end Run
+func (w *Watcher) isFatal(err error) bool {
+	if errors.Is(err, ErrCubbyholeInaccessible) {
+		return true
+	}
+	for _, target := range w.fatalErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportFatal ends Run if err is fatal. Later errors are dropped while Run
+// is returning the first.
+func (w *Watcher) reportFatal(err error) {
+	if err == nil || !w.isFatal(err) {
+		return
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	select {
+	case w.fatal <- err:
+	default:
+	}
+}
@@ -0,0 +1,93 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		failure error // Returned by the source once running
+		wantErr error // nil: Run runs until cancelled
+	}{
+		{"cancelled", nil, nil, nil},
+		{"non-fatal error", nil, ErrPermissionDenied, nil},
+		{"cubbyhole inaccessible", nil, fmt.Errorf("token revoked: %w", ErrCubbyholeInaccessible), ErrCubbyholeInaccessible},
+		{"fatal error", []Option{WithFatalErrors(ErrPermissionDenied)}, ErrPermissionDenied, ErrPermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeSource{}
+			source.set(map[string]interface{}{"key": "one"})
+			watcher, err := NewSourceWatcher("app", source, 5*time.Millisecond, func() error { return nil }, tt.opts...)
+			AssertNoError(t, err, "NewSourceWatcher()")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- watcher.Run(ctx) }()
+			waitFor(t, time.Second, watcher.IsStarted, "the watcher to start")
+
+			source.mu.Lock()
+			source.err = tt.failure
+			source.mu.Unlock()
+
+			if tt.wantErr != nil {
+				select {
+				case err := <-done:
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("Run() = %v, want %v", err, tt.wantErr)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("Run() didn't return after a fatal error")
+				}
+				AssertBoolEquals(t, watcher.IsStarted(), false, "IsStarted() after Run()")
+				return
+			}
+
+			select {
+			case err := <-done:
+				t.Fatalf("Run() returned %v before being cancelled", err)
+			case <-time.After(30 * time.Millisecond):
+			}
+			cancel()
+			select {
+			case err := <-done:
+				AssertNoError(t, err, "Run() after cancelling")
+			case <-time.After(time.Second):
+				t.Fatal("Run() didn't return after cancelling")
+			}
+			AssertBoolEquals(t, watcher.IsStarted(), false, "IsStarted() after Run()")
+		})
+	}
+}
+
+func TestWatcher_RunErrors(t *testing.T) {
+	source := &fakeSource{err: ErrSecretNotFound}
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+	if err := watcher.Run(context.Background()); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Run() = %v, want the error of Start()", err)
+	}
+	AssertBoolEquals(t, watcher.IsStarted(), false, "IsStarted() after Start() failed")
+
+	// The watcher can run again once the secret is back
+	source.mu.Lock()
+	source.err = nil
+	source.data = map[string]interface{}{"key": "one"}
+	source.mu.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+	waitFor(t, time.Second, watcher.IsStarted, "the watcher to start")
+	AssertError(t, watcher.Run(ctx), "Run can't be used while the watcher is started", "second Run()")
+	cancel()
+	AssertNoError(t, <-done, "Run() after cancelling")
+}
@@ -0,0 +1,105 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// runState is what RunOnce keeps in the state store between runs
+type runState struct {
+	Hash      string            `json:"hash"`
+	Version   int               `json:"version,omitempty"`
+	KeyHashes map[string]string `json:"key_hashes"`
+}
+
+// runStateKey is the state key of the secret seen by the last RunOnce
+func runStateKey(path string) string {
+	return "run/" + path
+}
+
+// RunOnce checks the secret once against the state persisted by the
+// previous run and returns, so cron jobs and serverless functions can use
+// the watcher without a long-running loop. When the secret changed, onChange
+// and the notifiers run as for a started watcher and the applied event is
+// returned. The first run only records the secret. It needs WithStateStore,
+// which also keeps overlapping runs from handling a change twice, and can't
+// be used while the watcher is started. Cooldowns and change delays are kept
+// in memory, so they don't span runs.
+func (w *Watcher) RunOnce(ctx context.Context) (bool, ChangeEvent, error) {
+	if w.stateStore == nil {
+		return false, ChangeEvent{}, fmt.Errorf("RunOnce needs WithStateStore to remember the secret between runs")
+	}
+
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return false, ChangeEvent{}, fmt.Errorf("RunOnce can't be used while the watcher is started")
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	w.mu.Unlock()
+	defer func() {
+		w.cancel()
+		w.wg.Wait()
+	}()
+
+	key := runStateKey(w.vaultConfig.Path)
+	stored, err := w.stateStore.Get(ctx, key)
+	if err != nil {
+		return false, ChangeEvent{}, fmt.Errorf("failed to read run state: %w", err)
+	}
+	if stored == nil {
+		// Nothing to compare with yet
+		if err := w.initialize(); err != nil {
+			return false, ChangeEvent{}, err
+		}
+		return false, ChangeEvent{}, w.saveRunState(ctx, key)
+	}
+
+	var state runState
+	if err := json.Unmarshal(stored, &state); err != nil {
+		return false, ChangeEvent{}, fmt.Errorf("invalid run state: %w", err)
+	}
+	w.mu.Lock()
+	w.currentHash = state.Hash
+	w.currentVersion = state.Version
+	w.keyHashes = state.KeyHashes
+	previous := w.lastChange
+	w.mu.Unlock()
+
+	if err := w.check(); err != nil {
+		return false, ChangeEvent{}, err
+	}
+
+	w.mu.RLock()
+	unchanged := w.currentHash == state.Hash
+	event := w.lastChange
+	w.mu.RUnlock()
+	if unchanged {
+		return false, ChangeEvent{}, nil
+	}
+	if err := w.saveRunState(ctx, key); err != nil {
+		return false, ChangeEvent{}, err
+	}
+	if event == nil || event == previous {
+		// Adopted after another run handled it
+		return false, ChangeEvent{}, nil
+	}
+	return true, *event, nil
+}
+
+// saveRunState records the current secret for the next RunOnce
+func (w *Watcher) saveRunState(ctx context.Context, key string) error {
+	w.mu.RLock()
+	state := runState{Hash: w.currentHash, Version: w.currentVersion, KeyHashes: w.keyHashes}
+	w.mu.RUnlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode run state: %w", err)
+	}
+	if err := w.stateStore.Put(ctx, key, data); err != nil {
+		return fmt.Errorf("failed to save run state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWatcher_RunOnce_Errors(t *testing.T) {
+	watcher, err := NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+	_, _, err = watcher.RunOnce(context.Background())
+	AssertError(t, err, "RunOnce needs WithStateStore to remember the secret between runs", "RunOnce() without a state store")
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+	watcher, err = NewSourceWatcher("app", source, time.Hour, func() error { return nil }, WithStateStore(NewMemoryStateStore()))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+	_, _, err = watcher.RunOnce(context.Background())
+	AssertError(t, err, "RunOnce can't be used while the watcher is started", "RunOnce() while started")
+}
+
+func TestWatcher_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1", "port": "5432"})
+
+	// Every run is a new process, so a new watcher
+	calls := 0
+	failing := false
+	run := func() (bool, ChangeEvent, error) {
+		watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+			calls++
+			if failing {
+				return errors.New("reload failed")
+			}
+			return nil
+		}, WithStateStore(store))
+		AssertNoError(t, err, "NewSourceWatcher()")
+		return watcher.RunOnce(ctx)
+	}
+
+	tests := []struct {
+		name        string
+		data        map[string]interface{}
+		failing     bool
+		wantChanged bool
+		wantKeys    string
+		wantErr     string
+		wantCalls   int
+	}{
+		{name: "first run records the secret"},
+		{name: "unchanged"},
+		{name: "changed", data: map[string]interface{}{"host": "db-2", "port": "5432"}, wantChanged: true, wantKeys: "[host]", wantCalls: 1},
+		{name: "unchanged after the change", wantCalls: 1},
+		{name: "failing callback", data: map[string]interface{}{"host": "db-3", "port": "5432"}, failing: true,
+			wantErr: "onChange callback failed: reload failed", wantCalls: 2},
+		{name: "retried by the next run", wantChanged: true, wantKeys: "[host]", wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		if tt.data != nil {
+			source.set(tt.data)
+		}
+		failing = tt.failing
+		changed, event, err := run()
+		if tt.wantErr != "" {
+			AssertError(t, err, tt.wantErr, tt.name)
+		} else {
+			AssertNoError(t, err, tt.name)
+		}
+		AssertBoolEquals(t, changed, tt.wantChanged, tt.name+": changed")
+		if changed {
+			AssertStringEquals(t, fmt.Sprint(event.ChangedKeys), tt.wantKeys, tt.name+": ChangedKeys")
+			AssertStringEquals(t, event.Path, "app", tt.name+": Path")
+		}
+		AssertStringEquals(t, fmt.Sprint(calls), fmt.Sprint(tt.wantCalls), tt.name+": onChange calls")
+	}
+}
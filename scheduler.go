@@ -0,0 +1,239 @@
+package vaultwatcher
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Scheduler checks many watchers from a single timer and a small pool of
+// workers, so a process can watch thousands of paths without a goroutine and
+// a ticker for each. Watchers join it with WithScheduler when they start and
+// leave it when they stop.
+type Scheduler struct {
+	workers int
+	mu      sync.Mutex
+	queue   scheduleQueue // Waiting watchers, the next one due first
+	entries map[*Watcher]*scheduledCheck
+	wake    chan struct{} // Tells run the next check may have changed
+	jobs    chan *scheduledCheck
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+// scheduledCheck is a watcher's place in the schedule
+type scheduledCheck struct {
+	watcher *Watcher
+	due     time.Time
+	index   int           // In the queue; -1 while being checked
+	removed bool          // Left the schedule while being checked
+	done    chan struct{} // Closed when a check of a removed watcher ends
+}
+
+// NewScheduler creates a scheduler checking up to workers watchers at the
+// same time
+func NewScheduler(workers int) (*Scheduler, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("scheduler workers must be at least 1")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		workers: workers,
+		entries: make(map[*Watcher]*scheduledCheck),
+		wake:    make(chan struct{}, 1),
+		jobs:    make(chan *scheduledCheck),
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Len returns the number of scheduled watchers
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Stop stops checking every scheduled watcher. The watchers are left started;
+// stop them to release their other resources.
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// add schedules w to be checked after delay and then every w.nextCheck(),
+// starting the timer and the workers with the first watcher
+func (s *Scheduler) add(w *Watcher, delay time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return fmt.Errorf("scheduler is stopped")
+	}
+	if _, exists := s.entries[w]; exists {
+		return fmt.Errorf("watcher is already scheduled")
+	}
+	entry := &scheduledCheck{watcher: w, due: time.Now().Add(delay)}
+	s.entries[w] = entry
+	heap.Push(&s.queue, entry)
+
+	if !s.started {
+		s.started = true
+		for i := 0; i < s.workers; i++ {
+			s.wg.Add(1)
+			go s.work()
+		}
+		s.wg.Add(1)
+		go s.run()
+	}
+	s.notify()
+	return nil
+}
+
+// remove unschedules w, waiting for a check of it that is under way
+func (s *Scheduler) remove(w *Watcher) {
+	s.mu.Lock()
+	entry, ok := s.entries[w]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.entries, w)
+	if entry.index >= 0 {
+		heap.Remove(&s.queue, entry.index)
+		s.mu.Unlock()
+		s.notify()
+		return
+	}
+	entry.removed = true
+	entry.done = make(chan struct{})
+	s.mu.Unlock()
+
+	select {
+	case <-entry.done:
+	case <-s.ctx.Done():
+		// Workers stop between checks; wait for the last one
+		s.wg.Wait()
+	}
+}
+
+// notify wakes run up without blocking
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run hands watchers to the workers as they come due
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	for {
+		s.mu.Lock()
+		var due []*scheduledCheck
+		now := time.Now()
+		for s.queue.Len() > 0 && !s.queue[0].due.After(now) {
+			due = append(due, heap.Pop(&s.queue).(*scheduledCheck))
+		}
+		wait := time.Hour
+		if s.queue.Len() > 0 {
+			wait = s.queue[0].due.Sub(now)
+		}
+		s.mu.Unlock()
+
+		for _, entry := range due {
+			select {
+			case <-s.ctx.Done():
+				return
+			case s.jobs <- entry:
+			}
+		}
+		if len(due) > 0 {
+			// Handing out took a while; look at the queue again
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// work checks the watchers handed to it and puts them back in the queue. A
+// check waits for one of the same watcher already running elsewhere.
+func (s *Scheduler) work() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case entry := <-s.jobs:
+			s.mu.Lock()
+			removed := entry.removed
+			s.mu.Unlock()
+			if !removed {
+				entry.watcher.check()
+				entry.watcher.petWatchdog()
+			}
+
+			s.mu.Lock()
+			if entry.removed {
+				close(entry.done)
+			} else {
+				entry.due = time.Now().Add(entry.watcher.nextCheck())
+				heap.Push(&s.queue, entry)
+			}
+			s.mu.Unlock()
+			s.notify()
+		}
+	}
+}
+
+// scheduleQueue is a heap of scheduled checks, the next one due first
+type scheduleQueue []*scheduledCheck
+
+func (q scheduleQueue) Len() int           { return len(q) }
+func (q scheduleQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x interface{}) {
+	entry := x.(*scheduledCheck)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// usesScheduler reports whether the watcher is checked by a Scheduler
+func (w *Watcher) usesScheduler() bool {
+	if w.scheduler == nil || w.schedule != nil {
+		return false
+	}
+	_, notifies := w.source.(ChangeNotifyingSource)
+	return !notifies
+}
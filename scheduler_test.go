@@ -0,0 +1,148 @@
+package vaultwatcher
+
+import (
+	"container/heap"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewScheduler(t *testing.T) {
+	_, err := NewScheduler(0)
+	AssertError(t, err, "scheduler workers must be at least 1", "NewScheduler(0)")
+
+	scheduler, err := NewScheduler(2)
+	AssertNoError(t, err, "NewScheduler()")
+	scheduler.Stop()
+
+	watcher, err := NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil }, WithScheduler(scheduler))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertError(t, watcher.Start(), "failed to schedule watcher: scheduler is stopped", "Start() with a stopped scheduler")
+}
+
+func TestScheduler_ChecksWatchers(t *testing.T) {
+	scheduler, err := NewScheduler(2)
+	AssertNoError(t, err, "NewScheduler()")
+	defer scheduler.Stop()
+
+	const paths = 100
+	var mu sync.Mutex
+	changed := map[string]bool{}
+	sources := make([]*fakeSource, paths)
+	watchers := make([]*Watcher, paths)
+	goroutines := runtime.NumGoroutine()
+	for i := range sources {
+		name := fmt.Sprintf("app/%d", i)
+		sources[i] = &fakeSource{}
+		sources[i].set(map[string]interface{}{"key": "one"})
+		watchers[i], err = NewSourceWatcher(name, sources[i], 10*time.Millisecond, func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			changed[name] = true
+			return nil
+		}, WithScheduler(scheduler))
+		AssertNoError(t, err, "NewSourceWatcher()")
+		AssertNoError(t, watchers[i].Start(), "Start()")
+		defer watchers[i].Stop()
+	}
+
+	// One timer and two workers instead of a goroutine per watcher
+	if started := runtime.NumGoroutine() - goroutines; started > 3 {
+		t.Errorf("%d watchers started %d goroutines, want at most 3", paths, started)
+	}
+	if scheduler.Len() != paths {
+		t.Errorf("Len() = %d, want %d", scheduler.Len(), paths)
+	}
+
+	for _, source := range sources {
+		source.set(map[string]interface{}{"key": "two"})
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(changed) == paths
+	}, "every watcher to see its change")
+
+	watchers[0].Stop()
+	if scheduler.Len() != paths-1 {
+		t.Errorf("Len() after Stop() = %d, want %d", scheduler.Len(), paths-1)
+	}
+	mu.Lock()
+	delete(changed, "app/0")
+	mu.Unlock()
+	sources[0].set(map[string]interface{}{"key": "three"})
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if changed["app/0"] {
+		t.Error("stopped watcher was still checked")
+	}
+}
+
+func TestScheduler_ChecksDontOverlap(t *testing.T) {
+	scheduler, err := NewScheduler(2)
+	AssertNoError(t, err, "NewScheduler()")
+	defer scheduler.Stop()
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"key": "one"})
+	var mu sync.Mutex
+	calls := 0
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error {
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return nil
+	}, WithScheduler(scheduler))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	// Checks of the watcher itself run alongside the scheduled ones
+	source.set(map[string]interface{}{"key": "two"})
+	for i := 0; i < 10; i++ {
+		AssertNoError(t, watcher.check(), "check()")
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("onChange called %d times, want 1", calls)
+	}
+}
+
+func TestScheduleQueue(t *testing.T) {
+	now := time.Now()
+	var queue scheduleQueue
+	entries := map[string]*scheduledCheck{}
+	for _, entry := range []struct {
+		name  string
+		delay time.Duration
+	}{
+		{"slow", 3 * time.Second},
+		{"fast", time.Second},
+		{"removed", 2 * time.Second},
+		{"medium", 2 * time.Second},
+	} {
+		entries[entry.name] = &scheduledCheck{watcher: &Watcher{vaultConfig: &VaultConfig{Path: entry.name}}, due: now.Add(entry.delay)}
+		heap.Push(&queue, entries[entry.name])
+	}
+	heap.Remove(&queue, entries["removed"].index)
+
+	var order []string
+	for queue.Len() > 0 {
+		entry := heap.Pop(&queue).(*scheduledCheck)
+		if entry.index != -1 {
+			t.Errorf("%s index = %d after Pop(), want -1", entry.watcher.vaultConfig.Path, entry.index)
+		}
+		order = append(order, entry.watcher.vaultConfig.Path)
+	}
+	if !reflect.DeepEqual(order, []string{"fast", "medium", "slow"}) {
+		t.Errorf("order = %v, want [fast medium slow]", order)
+	}
+}
@@ -0,0 +1,437 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationFailedEvent describes a change rejected by WithSchema or
+// WithSecretValidator. The callback wasn't run and the hash wasn't advanced,
+// so the watcher keeps the last valid secret. Errors name the invalid values
+// by JSON Pointer but never quote them.
+type ValidationFailedEvent struct {
+	Path        string    `json:"path"`
+	OldHash     string    `json:"old_hash"`
+	NewHash     string    `json:"new_hash"`
+	ChangedKeys []string  `json:"changed_keys"`
+	Version     int       `json:"version,omitempty"` // KV v2 version rejected
+	RequestID   string    `json:"request_id,omitempty"`
+	Errors      []string  `json:"errors"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ValidationFailureNotifier is implemented by notifiers that want to know
+// when a change is rejected by validation. Notifiers registered with
+// WithNotifier are checked for it automatically. Each rejected secret is
+// reported once, not on every check that reads it again.
+type ValidationFailureNotifier interface {
+	NotifyValidationFailure(ctx context.Context, event ValidationFailedEvent) error
+}
+
+// jsonSchema is the compiled subset of JSON Schema supported by WithSchema
+type jsonSchema struct {
+	types                []string
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	noAdditional         bool
+	items                *jsonSchema
+	enum                 []interface{}
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	minItems, maxItems   *int
+}
+
+// schemaAnnotations are keywords that don't affect validation
+var schemaAnnotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true, "format": true,
+}
+
+// compileSchema parses a JSON Schema document
+func compileSchema(document []byte) (*jsonSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(document, &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	schema, err := compileSchemaNode(raw, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return schema, nil
+}
+
+func compileSchemaNode(raw interface{}, pointer string) (*jsonSchema, error) {
+	if allowed, ok := raw.(bool); ok {
+		// true accepts anything, false nothing
+		if allowed {
+			return &jsonSchema{}, nil
+		}
+		return &jsonSchema{types: []string{}}, nil
+	}
+	node, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", schemaLocation(pointer))
+	}
+
+	keywords := make([]string, 0, len(node))
+	for keyword := range node {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+
+	schema := &jsonSchema{}
+	for _, keyword := range keywords {
+		value := node[keyword]
+		at := schemaLocation(pointer + "/" + escapePointer(keyword))
+		var err error
+		switch keyword {
+		case "type":
+			schema.types, err = schemaTypes(value)
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an object", at)
+			}
+			schema.properties = make(map[string]*jsonSchema, len(properties))
+			for name, property := range properties {
+				if schema.properties[name], err = compileSchemaNode(property, pointer+"/properties/"+escapePointer(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			schema.required, err = schemaStrings(value)
+		case "additionalProperties":
+			if allowed, ok := value.(bool); ok {
+				schema.noAdditional = !allowed
+				continue
+			}
+			schema.additionalProperties, err = compileSchemaNode(value, pointer+"/additionalProperties")
+		case "items":
+			schema.items, err = compileSchemaNode(value, pointer+"/items")
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array", at)
+			}
+			schema.enum = values
+		case "const":
+			schema.enum = []interface{}{value}
+		case "minLength":
+			schema.minLength, err = schemaCount(value)
+		case "maxLength":
+			schema.maxLength, err = schemaCount(value)
+		case "minItems":
+			schema.minItems, err = schemaCount(value)
+		case "maxItems":
+			schema.maxItems, err = schemaCount(value)
+		case "pattern":
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a string", at)
+			}
+			schema.pattern, err = regexp.Compile(text)
+		case "minimum":
+			schema.minimum, err = schemaNumber(value)
+		case "maximum":
+			schema.maximum, err = schemaNumber(value)
+		case "exclusiveMinimum":
+			schema.exclusiveMinimum, err = schemaNumber(value)
+		case "exclusiveMaximum":
+			schema.exclusiveMaximum, err = schemaNumber(value)
+		default:
+			if !schemaAnnotations[keyword] {
+				return nil, fmt.Errorf("%s: unsupported keyword", at)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", at, err)
+		}
+	}
+	return schema, nil
+}
+
+// schemaLocation names a place in a schema or secret for error messages
+func schemaLocation(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
+
+func schemaTypes(value interface{}) ([]string, error) {
+	if name, ok := value.(string); ok {
+		value = []interface{}{name}
+	}
+	types, err := schemaStrings(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range types {
+		switch name {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return nil, fmt.Errorf("unknown type %q", name)
+		}
+	}
+	return types, nil
+}
+
+func schemaStrings(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	strs := make([]string, len(items))
+	for i, item := range items {
+		if strs[i], ok = item.(string); !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+	}
+	return strs, nil
+}
+
+func schemaCount(value interface{}) (*int, error) {
+	number, ok := value.(float64)
+	if !ok || number < 0 || number != math.Trunc(number) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	count := int(number)
+	return &count, nil
+}
+
+func schemaNumber(value interface{}) (*float64, error) {
+	number, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	return &number, nil
+}
+
+// validate checks value against the schema and returns every violation
+func (s *jsonSchema) validate(value interface{}, pointer string) []string {
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, schemaLocation(pointer)+": "+fmt.Sprintf(format, args...))
+	}
+
+	typeName := schemaTypeOf(value)
+	if s.types != nil && !s.allowsType(value, typeName) {
+		if len(s.types) == 0 {
+			fail("no value is allowed")
+		} else {
+			fail("must be %s, got %s", strings.Join(s.types, " or "), typeName)
+		}
+		return errs
+	}
+	if s.enum != nil && !schemaEnumContains(s.enum, value) {
+		fail("must be one of the allowed values")
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := typed[name]; !ok {
+				fail("missing required key %q", name)
+			}
+		}
+		names := make([]string, 0, len(typed))
+		for name := range typed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := pointer + "/" + escapePointer(name)
+			if property, ok := s.properties[name]; ok {
+				errs = append(errs, property.validate(typed[name], child)...)
+				continue
+			}
+			if s.noAdditional {
+				errs = append(errs, schemaLocation(child)+": key is not allowed")
+			} else if s.additionalProperties != nil {
+				errs = append(errs, s.additionalProperties.validate(typed[name], child)...)
+			}
+		}
+	case []interface{}:
+		if s.minItems != nil && len(typed) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(typed) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range typed {
+				errs = append(errs, s.items.validate(item, fmt.Sprintf("%s/%d", pointer, i))...)
+			}
+		}
+	case string:
+		length := len([]rune(typed))
+		if s.minLength != nil && length < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(typed) {
+			fail("must match pattern %s", s.pattern)
+		}
+	}
+
+	if number, ok := schemaNumberOf(value); ok {
+		if s.minimum != nil && number < *s.minimum {
+			fail("must be at least %v", *s.minimum)
+		}
+		if s.maximum != nil && number > *s.maximum {
+			fail("must be at most %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && number <= *s.exclusiveMinimum {
+			fail("must be greater than %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && number >= *s.exclusiveMaximum {
+			fail("must be less than %v", *s.exclusiveMaximum)
+		}
+	}
+	return errs
+}
+
+// allowsType reports whether value has one of the schema's types. Integers
+// are numbers too.
+func (s *jsonSchema) allowsType(value interface{}, typeName string) bool {
+	for _, allowed := range s.types {
+		if allowed == typeName || allowed == "number" && typeName == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaTypeOf returns the JSON Schema type of a decoded JSON value
+func schemaTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if number, ok := schemaNumberOf(value); ok {
+		if number == math.Trunc(number) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaNumberOf returns a JSON number as a float64
+func schemaNumberOf(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case json.Number:
+		number, err := typed.Float64()
+		return number, err == nil
+	case float64:
+		return typed, true
+	case float32:
+		return float64(typed), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		number := reflect.ValueOf(typed).Convert(reflect.TypeOf(float64(0)))
+		return number.Float(), true
+	}
+	return 0, false
+}
+
+// schemaEnumContains reports whether value equals one of values. Numbers are
+// compared by value, so json.Number("1") matches 1.
+func schemaEnumContains(values []interface{}, value interface{}) bool {
+	number, isNumber := schemaNumberOf(value)
+	for _, allowed := range values {
+		if isNumber {
+			if allowedNumber, ok := schemaNumberOf(allowed); ok && allowedNumber == number {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateData checks data against the schema and validator of the watcher
+// and returns the violations, or nil when the data is valid
+func (w *Watcher) validateData(data map[string]interface{}) []string {
+	var errs []string
+	if w.schema != nil {
+		errs = append(errs, w.schema.validate(data, "")...)
+	}
+	if w.validator != nil {
+		if err := w.validator(data); err != nil {
+			errs = append(errs, w.redactError(err).Error())
+		}
+	}
+	return errs
+}
+
+// rejectChange reports a change that failed validation and returns the error
+// failing the check. Notifiers hear about each rejected hash once.
+func (w *Watcher) rejectChange(event ChangeEvent, errs []string) error {
+	w.mu.Lock()
+	reported := w.rejectedHash == event.NewHash
+	w.rejectedHash = event.NewHash
+	w.mu.Unlock()
+
+	if !reported {
+		failed := ValidationFailedEvent{
+			Path:        event.Path,
+			OldHash:     event.OldHash,
+			NewHash:     event.NewHash,
+			ChangedKeys: event.ChangedKeys,
+			Version:     event.Version,
+			RequestID:   event.RequestID,
+			Errors:      errs,
+			Timestamp:   event.Timestamp,
+		}
+		for _, notifier := range w.notifiers {
+			validationNotifier, ok := notifier.(ValidationFailureNotifier)
+			if !ok {
+				continue
+			}
+			if err := validationNotifier.NotifyValidationFailure(w.ctx, failed); err != nil {
+				w.logger.Error("Error notifying validation failure", "error", w.redactError(err))
+			}
+		}
+	}
+	return &sentinelError{
+		sentinel: ErrValidationFailed,
+		err:      fmt.Errorf("secret failed validation: %s", strings.Join(errs, "; ")),
+	}
+}
+
+// loadSchema compiles the document given to WithSchema
+func (w *Watcher) loadSchema() error {
+	if w.schemaDocument == nil {
+		return nil
+	}
+	schema, err := compileSchema(w.schemaDocument)
+	if err != nil {
+		return err
+	}
+	w.schema = schema
+	return nil
+}
@@ -0,0 +1,150 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["host", "port"],
+	"additionalProperties": false,
+	"properties": {
+		"host": {"type": "string", "minLength": 1, "pattern": "^[a-z0-9.-]+$"},
+		"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+		"mode": {"enum": ["primary", "replica"]},
+		"replicas": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+	}
+}`
+
+func TestCompileSchema_Errors(t *testing.T) {
+	tests := []struct {
+		schema  string
+		wantErr string
+	}{
+		{schema: `{"type": `, wantErr: "invalid schema: unexpected end of JSON input"},
+		{schema: `[]`, wantErr: "invalid schema: /: schema must be an object or a boolean"},
+		{schema: `{"type": "text"}`, wantErr: `invalid schema: /type: unknown type "text"`},
+		{schema: `{"required": "host"}`, wantErr: "invalid schema: /required: must be an array of strings"},
+		{schema: `{"properties": {"port": {"minimum": "1"}}}`, wantErr: "invalid schema: /properties/port/minimum: must be a number"},
+		{schema: `{"maxLength": 1.5}`, wantErr: "invalid schema: /maxLength: must be a non-negative integer"},
+		{schema: `{"pattern": "("}`, wantErr: "invalid schema: /pattern: error parsing regexp: missing closing ): `(`"},
+		{schema: `{"oneOf": []}`, wantErr: "invalid schema: /oneOf: unsupported keyword"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			_, err := compileSchema([]byte(tt.schema))
+			AssertError(t, err, tt.wantErr, "compileSchema()")
+		})
+	}
+}
+
+func TestJSONSchema_Validate(t *testing.T) {
+	schema, err := compileSchema([]byte(testSchema))
+	AssertNoError(t, err, "compileSchema()")
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "valid", data: `{"host": "db-1", "port": 5432, "mode": "replica", "replicas": ["db-2"]}`},
+		{name: "missing keys", data: `{}`, want: `/: missing required key "host"; /: missing required key "port"`},
+		{name: "wrong type", data: `{"host": "db-1", "port": "5432"}`, want: "/port: must be integer, got string"},
+		{name: "not an integer", data: `{"host": "db-1", "port": 5432.5}`, want: "/port: must be integer, got number"},
+		{name: "out of range", data: `{"host": "db-1", "port": 0}`, want: "/port: must be at least 1"},
+		{name: "pattern", data: `{"host": "DB 1", "port": 5432}`, want: "/host: must match pattern ^[a-z0-9.-]+$"},
+		{name: "enum", data: `{"host": "db-1", "port": 5432, "mode": "standby"}`, want: "/mode: must be one of the allowed values"},
+		{name: "items", data: `{"host": "db-1", "port": 5432, "replicas": ["db-2", 3, "db-4"]}`,
+			want: "/replicas: must have at most 2 items; /replicas/1: must be string, got integer"},
+		{name: "additional key", data: `{"host": "db-1", "port": 5432, "password": "s3cret"}`, want: "/password: key is not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(tt.data))
+			decoder.UseNumber()
+			var data map[string]interface{}
+			AssertNoError(t, decoder.Decode(&data), "Decode()")
+			AssertStringEquals(t, strings.Join(schema.validate(data, ""), "; "), tt.want, "validate()")
+		})
+	}
+}
+
+// validationRecorder records rejected changes
+type validationRecorder struct {
+	NotifierFunc
+	failures chan ValidationFailedEvent
+}
+
+func (r validationRecorder) NotifyValidationFailure(ctx context.Context, event ValidationFailedEvent) error {
+	r.failures <- event
+	return nil
+}
+
+func TestWatcher_Schema(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1", "port": json.Number("5432")})
+
+	called := 0
+	recorder := validationRecorder{
+		NotifierFunc: func(context.Context, ChangeEvent) error { return nil },
+		failures:     make(chan ValidationFailedEvent, 2),
+	}
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		called++
+		return nil
+	}, WithSchema([]byte(testSchema)), WithNotifier(recorder), WithSecretValidator(func(data map[string]interface{}) error {
+		if data["host"] == "localhost" {
+			return fmt.Errorf("host must not be a loopback address")
+		}
+		return nil
+	}))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+	hash := watcher.GetCurrentHash()
+
+	// A bad write is rejected and reported once
+	source.set(map[string]interface{}{"host": "db-2", "port": "5432"})
+	for i := 0; i < 2; i++ {
+		err = watcher.check()
+		AssertError(t, err, "secret failed validation: /port: must be integer, got string", "check()")
+		AssertBoolEquals(t, errors.Is(err, ErrValidationFailed), true, "errors.Is(ErrValidationFailed)")
+	}
+	AssertStringEquals(t, watcher.GetCurrentHash(), hash, "hash after the rejected change")
+	AssertBoolEquals(t, called == 0, true, "onChange not called")
+	event := <-recorder.failures
+	AssertStringEquals(t, fmt.Sprint(event.ChangedKeys, event.Version, event.Errors), "[host port] 2 [/port: must be integer, got string]", "ValidationFailedEvent")
+	select {
+	case event := <-recorder.failures:
+		t.Fatalf("rejected change reported twice: %v", event.Errors)
+	default:
+	}
+
+	source.set(map[string]interface{}{"host": "localhost", "port": json.Number("5432")})
+	AssertError(t, watcher.check(), "secret failed validation: host must not be a loopback address", "check() with the validator failing")
+	<-recorder.failures
+
+	source.set(map[string]interface{}{"host": "db-2", "port": json.Number("6432")})
+	AssertNoError(t, watcher.check(), "check() with a valid change")
+	AssertBoolEquals(t, called == 1, true, "onChange called for the valid change")
+}
+
+func TestWatcher_SchemaErrors(t *testing.T) {
+	_, err := NewSourceWatcher("app", &fakeSource{}, time.Hour, func() error { return nil }, WithSchema([]byte(`{"type": 1}`)))
+	AssertError(t, err, "invalid schema: /type: must be an array of strings", "NewSourceWatcher() with a bad schema")
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil }, WithSchema([]byte(testSchema)))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertError(t, watcher.Start(), `initial vault data failed validation: /: missing required key "port"`, "Start()")
+}
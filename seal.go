@@ -0,0 +1,89 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AvailabilityEvent describes a transition of Vault between available and
+// unavailable (sealed, uninitialized, or a standby that can't serve reads).
+// It is only emitted by watchers created with WithSealAwareness.
+type AvailabilityEvent struct {
+	Path        string    `json:"path"`
+	Available   bool      `json:"available"`
+	Initialized bool      `json:"initialized"`
+	Sealed      bool      `json:"sealed"`
+	Standby     bool      `json:"standby"`
+	Reason      string    `json:"reason,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// AvailabilityNotifier is implemented by notifiers that want to know when Vault
+// becomes unavailable and available again. Notifiers registered with
+// WithNotifier are checked for it automatically.
+type AvailabilityNotifier interface {
+	NotifyAvailability(ctx context.Context, event AvailabilityEvent) error
+}
+
+// IsVaultAvailable returns false while the last health check found Vault sealed,
+// uninitialized or on standby. It is always true without WithSealAwareness.
+func (w *Watcher) IsVaultAvailable() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return !w.vaultUnavailable
+}
+
+// checkVaultAvailability polls sys/health and emits an availability event when
+// Vault crosses between available and unavailable. An error means the health
+// endpoint itself couldn't be reached.
+func (w *Watcher) checkVaultAvailability() (AvailabilityEvent, error) {
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	health, err := w.client.Sys().HealthWithContext(ctx)
+	if err != nil {
+		return AvailabilityEvent{}, fmt.Errorf("failed to read vault health: %w", err)
+	}
+
+	event := AvailabilityEvent{
+		Path:        w.vaultConfig.Path,
+		Available:   true,
+		Initialized: health.Initialized,
+		Sealed:      health.Sealed,
+		Standby:     health.Standby,
+	}
+	switch {
+	case !health.Initialized:
+		event.Available, event.Reason = false, "vault is not initialized"
+	case health.Sealed:
+		event.Available, event.Reason = false, "vault is sealed"
+	case health.Standby && !health.PerformanceStandby:
+		event.Available, event.Reason = false, "vault is in standby"
+	}
+
+	w.mu.Lock()
+	changed := w.vaultUnavailable == event.Available
+	w.vaultUnavailable = !event.Available
+	w.mu.Unlock()
+
+	if changed {
+		event.Timestamp = time.Now().UTC()
+		w.notifyAvailability(event)
+	}
+
+	return event, nil
+}
+
+// notifyAvailability delivers the event to every notifier implementing AvailabilityNotifier
+func (w *Watcher) notifyAvailability(event AvailabilityEvent) {
+	for _, notifier := range w.notifiers {
+		availabilityNotifier, ok := notifier.(AvailabilityNotifier)
+		if !ok {
+			continue
+		}
+		if err := availabilityNotifier.NotifyAvailability(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying vault availability", "error", w.redactError(err))
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSealableVault serves sys/health and a KV v2 secret, counting secret reads
+type fakeSealableVault struct {
+	mu      sync.Mutex
+	sealed  bool
+	standby bool
+	reads   int
+}
+
+func (f *fakeSealableVault) setSealed(sealed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sealed = sealed
+}
+
+func (f *fakeSealableVault) secretReads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+func (f *fakeSealableVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/sys/health":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"initialized": true,
+			"sealed":      f.sealed,
+			"standby":     f.standby,
+		})
+	case "/v1/kv/data/test":
+		f.reads++
+		if f.sealed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"Vault is sealed"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// availabilityRecorder collects availability and health events
+type availabilityRecorder struct {
+	mu           sync.Mutex
+	availability []AvailabilityEvent
+	health       []HealthEvent
+}
+
+func (r *availabilityRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *availabilityRecorder) NotifyAvailability(ctx context.Context, event AvailabilityEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.availability = append(r.availability, event)
+	return nil
+}
+
+func (r *availabilityRecorder) NotifyHealth(ctx context.Context, event HealthEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health = append(r.health, event)
+	return nil
+}
+
+func (r *availabilityRecorder) counts() (availability, health int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.availability), len(r.health)
+}
+
+func TestWatcher_CheckVaultAvailability(t *testing.T) {
+	tests := []struct {
+		name      string
+		sealed    bool
+		standby   bool
+		available bool
+		reason    string
+	}{
+		{name: "active", available: true},
+		{name: "sealed", sealed: true, reason: "vault is sealed"},
+		{name: "standby", standby: true, reason: "vault is in standby"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := &fakeSealableVault{sealed: tt.sealed, standby: tt.standby}
+			server := httptest.NewServer(vault)
+			defer server.Close()
+
+			recorder := &availabilityRecorder{}
+			watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "kv/data/test", Token: "test-token"},
+				time.Hour, func() error { return nil }, WithSealAwareness(), WithNotifier(recorder))
+			AssertNoError(t, err, "NewWatcher()")
+
+			availability, err := watcher.checkVaultAvailability()
+			AssertNoError(t, err, "checkVaultAvailability()")
+			AssertBoolEquals(t, availability.Available, tt.available, "available")
+			AssertBoolEquals(t, watcher.IsVaultAvailable(), tt.available, "IsVaultAvailable()")
+
+			if tt.available {
+				if n, _ := recorder.counts(); n != 0 {
+					t.Errorf("got %d availability events while available, want 0", n)
+				}
+				return
+			}
+			if n, _ := recorder.counts(); n != 1 {
+				t.Fatalf("got %d availability events, want 1", n)
+			}
+			AssertStringEquals(t, recorder.availability[0].Reason, tt.reason, "reason")
+		})
+	}
+}
+
+func TestWatcher_SealAwarenessSuspendsReads(t *testing.T) {
+	vault := &fakeSealableVault{}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	recorder := &availabilityRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "kv/data/test", Token: "test-token"},
+		20*time.Millisecond, func() error { return nil },
+		WithSealAwareness(), WithNotifier(recorder), WithFailureThreshold(1))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.setSealed(true)
+	waitFor(t, time.Second, func() bool { return !watcher.IsVaultAvailable() }, "vault unavailable")
+	sealedReads := vault.secretReads()
+	time.Sleep(100 * time.Millisecond)
+
+	if reads := vault.secretReads(); reads != sealedReads {
+		t.Errorf("secret read %d times while sealed, want 0", reads-sealedReads)
+	}
+	if _, health := recorder.counts(); health != 0 {
+		t.Errorf("got %d health events while sealed, want 0", health)
+	}
+	AssertBoolEquals(t, watcher.IsHealthy(), true, "IsHealthy() while sealed")
+
+	vault.setSealed(false)
+	waitFor(t, time.Second, func() bool { return vault.secretReads() > sealedReads }, "reads resumed")
+
+	if availability, _ := recorder.counts(); availability != 2 {
+		t.Fatalf("got %d availability events, want 2", availability)
+	}
+	AssertBoolEquals(t, recorder.availability[1].Available, true, "recovery event")
+}
+
+func TestWatcher_SealAwarenessStartWhileSealed(t *testing.T) {
+	vault := &fakeSealableVault{sealed: true}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "kv/data/test", Token: "test-token"},
+		time.Hour, func() error { return nil }, WithSealAwareness())
+	AssertNoError(t, err, "NewWatcher()")
+
+	AssertError(t, watcher.Start(), "failed to fetch initial vault data: vault is sealed", "Start()")
+	if vault.secretReads() != 0 {
+		t.Errorf("secret read %d times while sealed, want 0", vault.secretReads())
+	}
+}
@@ -0,0 +1,151 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Service runs a Watcher under a supervisor or lifecycle manager. Its methods
+// match what the common ones expect, without this package importing them:
+//
+//   - suture: Serve(ctx) and String() make it a suture.Service
+//   - oklog/run: g.Add(service.Actor())
+//   - fx: lc.Append(fx.StartStopHook(service.OnStart, service.OnStop))
+//   - others: Name(), Serve(ctx) and Shutdown(ctx)
+//
+// The watcher runs as with Run, so fatal errors end Serve.
+type Service struct {
+	watcher *Watcher
+	mu      sync.Mutex
+	cancel  context.CancelFunc // Of the running Serve
+	done    chan struct{}      // Closed once it returned
+}
+
+// NewService wraps watcher in a Service
+func NewService(watcher *Watcher) *Service {
+	return &Service{watcher: watcher}
+}
+
+// ServiceProvider returns a constructor of a watcher's Service taking the
+// VaultConfig from a dependency injection container. The contrib/fxwatcher and
+// contrib/wirewatcher modules build on it to also start and stop the service.
+func ServiceProvider(checkInterval time.Duration, handler ChangeHandler, opts ...Option) func(*VaultConfig) (*Service, error) {
+	return func(vaultConfig *VaultConfig) (*Service, error) {
+		watcher, err := NewWatcherWithHandler(vaultConfig, checkInterval, handler, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return NewService(watcher), nil
+	}
+}
+
+// Watcher returns the wrapped watcher
+func (s *Service) Watcher() *Watcher {
+	return s.watcher
+}
+
+// Name identifies the service in supervisor logs, e.g.
+// "vault-watcher secret/data/app"
+func (s *Service) Name() string {
+	return "vault-watcher " + s.watcher.vaultConfig.Path
+}
+
+// String returns Name, which suture logs services by
+func (s *Service) String() string {
+	return s.Name()
+}
+
+// Serve runs the watcher until ctx is cancelled, Shutdown is called or a check
+// fails with a fatal error; see Run
+func (s *Service) Serve(ctx context.Context) error {
+	return s.serve(ctx, nil)
+}
+
+// serve is Serve, calling started once the watcher started
+func (s *Service) serve(ctx context.Context, started func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		cancel()
+		return fmt.Errorf("%s is already running", s.Name())
+	}
+	s.cancel, s.done = cancel, done
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.cancel, s.done = nil, nil
+		s.mu.Unlock()
+		cancel()
+		close(done)
+	}()
+
+	return s.watcher.run(ctx, started)
+}
+
+// Shutdown stops a running Serve and waits for it to return, or for ctx to
+// end first
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// OnStart starts the watcher in the background and returns once it read the
+// secret, or with the error of the initial read. ctx only bounds the start;
+// the watcher runs until OnStop. A later fatal error is logged.
+func (s *Service) OnStart(ctx context.Context) error {
+	started := make(chan struct{})
+	errs := make(chan error, 1)
+	runCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		errs <- s.serve(runCtx, func() { close(started) })
+	}()
+
+	select {
+	case <-started:
+		go func() {
+			defer cancel()
+			if err := <-errs; err != nil {
+				s.watcher.logger.Error("Error running service", "service", s.Name(), "error", err)
+			}
+		}()
+		return nil
+	case err := <-errs:
+		cancel()
+		return err
+	case <-ctx.Done():
+		cancel()
+		<-errs
+		return ctx.Err()
+	}
+}
+
+// OnStop stops the watcher started by OnStart; it is Shutdown
+func (s *Service) OnStop(ctx context.Context) error {
+	return s.Shutdown(ctx)
+}
+
+// Actor returns the execute and interrupt functions of an oklog/run actor
+func (s *Service) Actor() (execute func() error, interrupt func(error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	return func() error {
+			return s.Serve(ctx)
+		}, func(error) {
+			cancel()
+		}
+}
@@ -0,0 +1,101 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newServiceWatcher returns a watcher of a fake source and the source
+func newServiceWatcher(t *testing.T, opts ...Option) (*Watcher, *fakeSource) {
+	t.Helper()
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"key": "one"})
+	watcher, err := NewSourceWatcher("app", source, 5*time.Millisecond, func() error { return nil }, opts...)
+	AssertNoError(t, err, "NewSourceWatcher()")
+	return watcher, source
+}
+
+func TestServiceProvider(t *testing.T) {
+	handler := ChangeHandlerFunc(func(context.Context, ChangeEvent) error { return nil })
+	provide := ServiceProvider(time.Minute, handler, WithHashAlgorithm(HashSHA512))
+
+	service, err := provide(&VaultConfig{Host: "https://vault.example.com", Path: "secret/data/app", Token: "test-token"})
+	AssertNoError(t, err, "provider")
+	AssertStringEquals(t, service.Name(), "vault-watcher secret/data/app", "Name()")
+	AssertStringEquals(t, service.Watcher().checkInterval.String(), "1m0s", "watcher interval")
+
+	_, err = provide(&VaultConfig{Host: "https://vault.example.com", Path: "secret/data/app"})
+	AssertError(t, err, "VAULT_TOKEN is required", "provider without a token")
+}
+
+func TestService_Serve(t *testing.T) {
+	watcher, _ := newServiceWatcher(t)
+	service := NewService(watcher)
+	AssertStringEquals(t, service.Name(), "vault-watcher app", "Name()")
+	AssertStringEquals(t, service.String(), "vault-watcher app", "String()")
+	AssertNoError(t, service.Shutdown(context.Background()), "Shutdown() before Serve()")
+
+	done := make(chan error, 1)
+	go func() { done <- service.Serve(context.Background()) }()
+	waitFor(t, time.Second, watcher.IsStarted, "the watcher to start")
+	AssertError(t, service.Serve(context.Background()), "vault-watcher app is already running", "second Serve()")
+
+	AssertNoError(t, service.Shutdown(context.Background()), "Shutdown()")
+	AssertNoError(t, <-done, "Serve() after Shutdown()")
+	AssertBoolEquals(t, watcher.IsStarted(), false, "IsStarted() after Shutdown()")
+
+	// Supervisors restart services by calling Serve again
+	go func() { done <- service.Serve(context.Background()) }()
+	waitFor(t, time.Second, watcher.IsStarted, "the watcher to restart")
+	AssertNoError(t, service.Shutdown(context.Background()), "Shutdown() after restarting")
+	AssertNoError(t, <-done, "Serve() after restarting")
+}
+
+func TestService_Lifecycle(t *testing.T) {
+	watcher, source := newServiceWatcher(t)
+	service := NewService(watcher)
+
+	AssertNoError(t, service.OnStart(context.Background()), "OnStart()")
+	AssertBoolEquals(t, watcher.IsStarted(), true, "IsStarted() after OnStart()")
+	AssertNoError(t, service.OnStop(context.Background()), "OnStop()")
+	AssertBoolEquals(t, watcher.IsStarted(), false, "IsStarted() after OnStop()")
+
+	source.mu.Lock()
+	source.err = ErrPermissionDenied
+	source.mu.Unlock()
+	if err := service.OnStart(context.Background()); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("OnStart() = %v, want the error of the initial read", err)
+	}
+	AssertBoolEquals(t, watcher.IsStarted(), false, "IsStarted() after OnStart() failed")
+}
+
+func TestService_Actor(t *testing.T) {
+	watcher, source := newServiceWatcher(t, WithFatalErrors(ErrPermissionDenied))
+	service := NewService(watcher)
+
+	// Interrupted by another actor
+	execute, interrupt := service.Actor()
+	done := make(chan error, 1)
+	go func() { done <- execute() }()
+	waitFor(t, time.Second, watcher.IsStarted, "the watcher to start")
+	interrupt(errors.New("another actor returned"))
+	AssertNoError(t, <-done, "execute() after interrupt()")
+
+	// Returning a fatal error ends the group
+	execute, _ = service.Actor()
+	go func() { done <- execute() }()
+	waitFor(t, time.Second, watcher.IsStarted, "the watcher to start again")
+	source.mu.Lock()
+	source.err = ErrPermissionDenied
+	source.mu.Unlock()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("execute() = %v, want %v", err, ErrPermissionDenied)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("execute() didn't return after a fatal error")
+	}
+}
@@ -0,0 +1,149 @@
+package vaultwatcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ShardMembership tells a sharded WatcherGroup which instances split its
+// paths, e.g. from the pods of a StatefulSet or the healthy instances of a
+// Consul service
+type ShardMembership interface {
+	// Self returns the ID of this instance
+	Self() string
+	// Members returns the IDs of every live instance, including this one
+	Members(ctx context.Context) ([]string, error)
+}
+
+// staticShards is a fixed membership of count instances numbered 0 to count-1
+type staticShards struct {
+	index int
+	count int
+}
+
+func (s staticShards) Self() string {
+	return strconv.Itoa(s.index)
+}
+
+func (s staticShards) Members(ctx context.Context) ([]string, error) {
+	if s.count < 1 {
+		return nil, fmt.Errorf("shard count must be at least 1")
+	}
+	if s.index < 0 || s.index >= s.count {
+		return nil, fmt.Errorf("shard index must be between 0 and %d", s.count-1)
+	}
+	members := make([]string, s.count)
+	for i := range members {
+		members[i] = strconv.Itoa(i)
+	}
+	return members, nil
+}
+
+// WithSharding splits the group's paths between count instances, this one
+// being number index (from 0), e.g. from the ordinal of a StatefulSet pod.
+// Each instance only reads and checks the paths it owns.
+func WithSharding(index, count int) GroupOption {
+	return WithShardMembership(staticShards{index: index, count: count}, 0)
+}
+
+// WithShardMembership splits the group's paths between the instances listed
+// by membership. Each instance only reads and checks the paths it owns, and
+// with a positive refresh the members are listed again every refresh: paths
+// whose owner changed are dropped by the old owner and read by the new one.
+// Paths are assigned by rendezvous hashing, so a member joining or leaving
+// only moves the paths it gains or loses.
+func WithShardMembership(membership ShardMembership, refresh time.Duration) GroupOption {
+	return func(g *WatcherGroup) {
+		g.shards = membership
+		g.shardRefresh = refresh
+	}
+}
+
+// ShardOwner returns which of members owns path, or "" if there are none.
+// Every instance computes the same owner from the same members, in any order.
+func ShardOwner(path string, members []string) string {
+	var owner string
+	var best uint64
+	for _, member := range members {
+		sum := sha256.Sum256([]byte(member + "\x00" + path))
+		score := binary.BigEndian.Uint64(sum[:8])
+		if owner == "" || score > best || (score == best && member < owner) {
+			owner, best = member, score
+		}
+	}
+	return owner
+}
+
+// ownsLocked reports whether this instance owns path. Called with g.mu held.
+func (g *WatcherGroup) ownsLocked(path string) bool {
+	return ShardOwner(path, g.shardMembers) == g.shards.Self()
+}
+
+// forgetShardPathLocked drops path from the paths split between instances,
+// reporting whether it was one of them. Called with g.mu held.
+func (g *WatcherGroup) forgetShardPathLocked(path string) bool {
+	for i, entry := range g.shardPaths {
+		if entry.name() == path {
+			g.shardPaths = append(g.shardPaths[:i:i], g.shardPaths[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// refreshShards runs in a goroutine and rebalances the group every refresh
+func (g *WatcherGroup) refreshShards() {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.shardRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-ticker.C:
+			g.rebalance()
+		}
+	}
+}
+
+// rebalance lists the shard members again, then stops watching the paths
+// this instance lost and starts watching the ones it gained
+func (g *WatcherGroup) rebalance() {
+	members, err := g.shards.Members(g.ctx)
+	if err != nil {
+		g.logger.Error("Error listing shard members", "error", err)
+		return
+	}
+
+	g.mu.Lock()
+	g.shardMembers = members
+	g.metrics.Shards = len(members)
+	var gained []GroupPath
+	var lost []string
+	for _, entry := range g.shardPaths {
+		path := entry.name()
+		_, watched := g.byPath[path]
+		switch owned := g.ownsLocked(path); {
+		case owned && !watched:
+			gained = append(gained, entry)
+		case !owned && watched:
+			lost = append(lost, path)
+		}
+	}
+	g.mu.Unlock()
+
+	for _, path := range lost {
+		g.removeMember(path)
+	}
+	for _, entry := range gained {
+		if err := g.addMember(entry); err != nil {
+			g.logger.Error("Error taking over a shard path", "error", err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardOwner(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []string
+		want    string
+	}{
+		{name: "no members", want: ""},
+		{name: "one member", members: []string{"a"}, want: "a"},
+		{name: "any order", members: []string{"c", "a", "b"}, want: ShardOwner("kv/data/app", []string{"a", "b", "c"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertStringEquals(t, ShardOwner("kv/data/app", tt.members), tt.want, "ShardOwner()")
+		})
+	}
+
+	counts := map[string]int{}
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		path := fmt.Sprintf("kv/data/%d", i)
+		owner := ShardOwner(path, []string{"a", "b", "c"})
+		counts[owner]++
+		// Only the paths of the member leaving move
+		if after := ShardOwner(path, []string{"a", "b"}); after != owner {
+			moved++
+			if owner != "c" {
+				t.Errorf("%s moved from %s to %s when c left", path, owner, after)
+			}
+		}
+	}
+	for member, count := range counts {
+		if count < 800 || count > 1200 {
+			t.Errorf("%s owns %d of 3000 paths, want about 1000", member, count)
+		}
+	}
+	if moved != counts["c"] {
+		t.Errorf("%d paths moved when c left, want %d", moved, counts["c"])
+	}
+}
+
+// fakeMembership is a ShardMembership whose members the test sets
+type fakeMembership struct {
+	mu      sync.Mutex
+	self    string
+	members []string
+}
+
+func (m *fakeMembership) set(members ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members = members
+}
+
+func (m *fakeMembership) Self() string {
+	return m.self
+}
+
+func (m *fakeMembership) Members(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.members...), nil
+}
+
+func shardTestServer(paths []string) *httptest.Server {
+	values := map[string]string{}
+	for _, path := range paths {
+		values[path] = path
+	}
+	return httptest.NewServer(&fakeKVPaths{values: values})
+}
+
+func TestWatcherGroup_Sharding(t *testing.T) {
+	var paths []string
+	for i := 0; i < 12; i++ {
+		paths = append(paths, fmt.Sprintf("kv/data/%d", i))
+	}
+	server := shardTestServer(paths)
+	defer server.Close()
+	config := &VaultConfig{Host: server.URL, Token: "test-token"}
+
+	for _, tt := range []struct {
+		index, count int
+		wantErr      string
+	}{
+		{index: 0, count: 0, wantErr: "failed to list shard members: shard count must be at least 1"},
+		{index: 3, count: 3, wantErr: "failed to list shard members: shard index must be between 0 and 2"},
+	} {
+		_, err := NewWatcherGroup(config, paths, time.Hour, func(string) error { return nil }, WithSharding(tt.index, tt.count))
+		AssertError(t, err, tt.wantErr, fmt.Sprintf("WithSharding(%d, %d)", tt.index, tt.count))
+	}
+
+	var all []string
+	for i := 0; i < 3; i++ {
+		group, err := NewWatcherGroup(config, paths, time.Hour, func(string) error { return nil }, WithSharding(i, 3))
+		AssertNoError(t, err, "NewWatcherGroup()")
+		AssertNoError(t, group.Start(), "Start()")
+		defer group.Stop()
+
+		for _, path := range group.Paths() {
+			AssertStringEquals(t, ShardOwner(path, []string{"0", "1", "2"}), fmt.Sprint(i), path+" owner")
+		}
+		if group.Metrics().Shards != 3 {
+			t.Errorf("Metrics().Shards = %d, want 3", group.Metrics().Shards)
+		}
+		all = append(all, group.Paths()...)
+	}
+
+	// Every path is watched by exactly one instance
+	sort.Strings(all)
+	want := append([]string(nil), paths...)
+	sort.Strings(want)
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("paths of every shard = %v, want %v", all, want)
+	}
+}
+
+func TestWatcherGroup_ShardRebalance(t *testing.T) {
+	var paths []string
+	for i := 0; i < 12; i++ {
+		paths = append(paths, fmt.Sprintf("kv/data/%d", i))
+	}
+	server := shardTestServer(append(paths, "kv/data/added"))
+	defer server.Close()
+
+	membership := &fakeMembership{self: "a"}
+	membership.set("a")
+	group, err := NewWatcherGroup(&VaultConfig{Host: server.URL, Token: "test-token"}, paths, time.Hour,
+		func(string) error { return nil }, WithShardMembership(membership, 10*time.Millisecond))
+	AssertNoError(t, err, "NewWatcherGroup()")
+	AssertNoError(t, group.Start(), "Start()")
+	defer group.Stop()
+
+	owned := func(members ...string) []string {
+		var want []string
+		for _, path := range group.shardPaths {
+			if ShardOwner(path.name(), members) == "a" {
+				want = append(want, path.name())
+			}
+		}
+		return want
+	}
+	sameOwned := func(members ...string) func() bool {
+		return func() bool {
+			got := group.Paths()
+			sort.Strings(got)
+			group.mu.RLock()
+			want := owned(members...)
+			group.mu.RUnlock()
+			sort.Strings(want)
+			return reflect.DeepEqual(got, want)
+		}
+	}
+
+	if len(group.Paths()) != len(paths) {
+		t.Errorf("alone, the instance watches %d paths, want %d", len(group.Paths()), len(paths))
+	}
+
+	membership.set("a", "b", "c")
+	waitFor(t, time.Second, sameOwned("a", "b", "c"), "paths of b and c to be dropped")
+
+	AssertNoError(t, group.AddPath("kv/data/added"), "AddPath()")
+	AssertError(t, group.AddPath("kv/data/added"), `path "kv/data/added" is already watched`, "AddPath() twice")
+	if !sameOwned("a", "b", "c")() {
+		t.Errorf("Paths() = %v after AddPath(), want the paths owned by a", group.Paths())
+	}
+
+	membership.set("a")
+	waitFor(t, time.Second, sameOwned("a"), "paths of b and c to be taken over")
+	if len(group.Paths()) != len(paths)+1 {
+		t.Errorf("alone again, the instance watches %d paths, want %d", len(group.Paths()), len(paths)+1)
+	}
+
+	AssertNoError(t, group.RemovePath("kv/data/added"), "RemovePath()")
+	AssertError(t, group.RemovePath("kv/data/added"), `path "kv/data/added" is not watched`, "RemovePath() twice")
+}
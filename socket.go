@@ -0,0 +1,38 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// unixScheme prefixes Vault addresses of a unix domain socket, such as the
+// listener of a local Vault Agent: unix:///var/run/vault/agent.sock
+const unixScheme = "unix://"
+
+// useUnixSocket makes the client dial the socket of a unix:// address for
+// every request. Requests are sent over HTTP to a placeholder host.
+func useUnixSocket(config *api.Config, address string) error {
+	socket := strings.TrimPrefix(address, unixScheme)
+	if socket == "" {
+		return fmt.Errorf("unix socket address %q has no path", address)
+	}
+
+	transport, ok := config.HttpClient.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+
+	config.HttpClient.Transport = transport
+	config.Address = "http://localhost"
+	return nil
+}
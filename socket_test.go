@@ -0,0 +1,46 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher_UnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, so avoid the long t.TempDir
+	dir, err := os.MkdirTemp("", "vw")
+	AssertNoError(t, err, "MkdirTemp()")
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+
+	listener, err := net.Listen("unix", socket)
+	AssertNoError(t, err, "Listen()")
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/app" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data": {"data": {"password": "one"}, "metadata": {"version": 1}}}`)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	limiter, err := NewRateLimiter(100, 10)
+	AssertNoError(t, err, "NewRateLimiter()")
+
+	watcher, err := NewWatcher(&VaultConfig{Host: "unix://" + socket, Path: "secret/data/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithRateLimiter(limiter))
+	AssertNoError(t, err, "NewWatcher()")
+
+	data, err := watcher.fetchVaultData()
+	AssertNoError(t, err, "fetchVaultData()")
+	AssertStringEquals(t, data["password"].(string), "one", "password")
+
+	_, err = NewWatcher(&VaultConfig{Host: "unix://", Path: "secret/data/app", Token: "t"}, time.Hour, func() error { return nil })
+	AssertError(t, err, `unix socket address "unix://" has no path`, "NewWatcher() without socket path")
+}
@@ -0,0 +1,276 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// errCustomSource is returned by operations that write to or read versions
+// from Vault on a watcher created with NewSourceWatcher
+var errCustomSource = errors.New("the watcher reads a custom source, not vault")
+
+// Meta describes the data returned by a SecretSource
+type Meta struct {
+	Version     int       // Version of the data, zero if the source doesn't version it
+	CreatedTime time.Time // When that version was created, zero if unknown
+
+	// RequestID and Warnings are those of the Vault response, if any. Vault
+	// logs the request ID in its audit log.
+	RequestID string
+	Warnings  []string
+
+	// MountType is the kind of secrets engine the data was read from, empty
+	// for sources outside Vault or when Vault doesn't tell. Other engines
+	// than KV are reported by their type, e.g. "database" or "pki".
+	MountType MountType
+}
+
+// MountType is the kind of Vault secrets engine a path is in
+type MountType string
+
+const (
+	// MountTypeKV is a KV version 1 mount
+	MountTypeKV MountType = "kv"
+	// MountTypeKVv2 is a versioned KV version 2 mount
+	MountTypeKVv2 MountType = "kv-v2"
+	// MountTypeCubbyhole is the cubbyhole of the watcher's token
+	MountTypeCubbyhole MountType = "cubbyhole"
+)
+
+// mountsLookupPath is where Vault tells which mount a path is in
+const mountsLookupPath = "sys/internal/ui/mounts/"
+
+// SecretSource is where a watcher reads the data it hashes. Vault is the
+// default; NewSourceWatcher watches any other source with the same polling,
+// hashing, callbacks and notifiers.
+type SecretSource interface {
+	Fetch(ctx context.Context) (map[string]interface{}, Meta, error)
+}
+
+// SecretSourceFunc adapts a function to a SecretSource
+type SecretSourceFunc func(ctx context.Context) (map[string]interface{}, Meta, error)
+
+// Fetch calls f(ctx)
+func (f SecretSourceFunc) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	return f(ctx)
+}
+
+// ChangeNotifyingSource is a SecretSource that can tell when its data may
+// have changed. Watchers check as soon as Changes signals instead of waiting
+// for the next interval, which keeps running as a fallback. The channel is
+// closed when ctx is done, or early if the source can't watch.
+type ChangeNotifyingSource interface {
+	SecretSource
+	Changes(ctx context.Context) <-chan struct{}
+}
+
+// NewSourceWatcher creates a watcher polling source instead of Vault
+// name: Identifies the source in events, logs and state keys, like a Vault path
+// checkInterval: How often to check for changes
+// onChange: Callback function to execute when changes are detected
+// opts: Optional settings; those talking to Vault can't be used
+func NewSourceWatcher(name string, source SecretSource, checkInterval time.Duration, onChange func() error, opts ...Option) (*Watcher, error) {
+	if name == "" {
+		return nil, fmt.Errorf("source name is required")
+	}
+	if source == nil {
+		return nil, fmt.Errorf("source cannot be nil")
+	}
+	if onChange == nil {
+		return nil, fmt.Errorf("onChange callback cannot be nil")
+	}
+	return NewSourceWatcherWithHandler(name, source, checkInterval, LegacyHandler(onChange), opts...)
+}
+
+// NewSourceWatcherWithHandler is NewSourceWatcher with a ChangeHandler
+func NewSourceWatcherWithHandler(name string, source SecretSource, checkInterval time.Duration, handler ChangeHandler, opts ...Option) (*Watcher, error) {
+	if name == "" {
+		return nil, fmt.Errorf("source name is required")
+	}
+	if source == nil {
+		return nil, fmt.Errorf("source cannot be nil")
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("change handler cannot be nil")
+	}
+
+	w := newWatcher(&VaultConfig{Path: name}, checkInterval, handler, opts...)
+	if option := w.vaultOnlyOption(); option != "" {
+		return nil, fmt.Errorf("%s needs vault and can't be used with a custom source", option)
+	}
+	if w.hashOnly && w.jsonPatch {
+		return nil, fmt.Errorf("json patches keep secret data in memory, which hash-only watchers don't allow")
+	}
+	if err := w.validateHashing(); err != nil {
+		return nil, err
+	}
+	if err := w.loadSchema(); err != nil {
+		return nil, err
+	}
+	if err := w.loadExpectedValues(); err != nil {
+		return nil, err
+	}
+	if err := w.loadTemplates(); err != nil {
+		return nil, err
+	}
+	w.source = source
+
+	return w, nil
+}
+
+// vaultOnlyOption returns the name of an applied option that talks to Vault
+func (w *Watcher) vaultOnlyOption() string {
+	switch {
+	case w.reader != nil:
+		return "WithSecretReader"
+	case w.pinnedVersion > 0:
+		return "WithPinnedVersion"
+	case w.includeCustomMetadata:
+		return "WithCustomMetadata"
+	case w.metadataOnly:
+		return "WithMetadataOnly"
+	case w.sealAware:
+		return "WithSealAwareness"
+	case w.unsealWait > 0:
+		return "WithUnsealWait"
+	case w.followActive:
+		return "WithActiveNodeDiscovery"
+	case w.hmacKeyPath != "":
+		return "WithTransitHMAC"
+	case w.decryptKeyPath != "":
+		return "WithTransitDecrypt"
+	case w.rateLimiter != nil:
+		return "WithRateLimiter"
+	case w.retryPolicy != nil:
+		return "WithRetryPolicy"
+	case w.consistency != ConsistencyDefault:
+		return "WithConsistency"
+	case w.tokenCheckInterval > 0:
+		return "WithTokenMonitoring"
+	}
+	return ""
+}
+
+// secretSource returns the custom source or the watcher's Vault path
+func (w *Watcher) secretSource() SecretSource {
+	if w.source != nil {
+		return w.source
+	}
+	return vaultSource{w: w}
+}
+
+// NewVaultSource creates a SecretSource reading a Vault path, e.g. to layer
+// it with other sources in a CompositeWatcher. Options shaping the read, such
+// as WithPinnedVersion or WithCustomMetadata, apply.
+func NewVaultSource(vaultConfig *VaultConfig, opts ...Option) (SecretSource, error) {
+	w, err := NewWatcher(vaultConfig, time.Hour, func() error { return nil }, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return vaultSource{w: w}, nil
+}
+
+// vaultSource reads the watched Vault path. It is the default SecretSource.
+type vaultSource struct {
+	w *Watcher
+}
+
+// Fetch reads the secret, or only its custom metadata for metadata-only
+// watchers. KV v2 secrets report their version in Meta.
+func (s vaultSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	w := s.w
+	if w.metadataOnly {
+		data, err := w.fetchCustomMetadata(ctx)
+		return data, Meta{MountType: MountTypeKVv2}, err
+	}
+
+	secret, err := w.readSecret(ctx)
+	if err == nil && secret == nil {
+		err = ErrSecretNotFound
+	}
+	cubbyhole := isCubbyholePath(w.vaultConfig.Path)
+	if err != nil {
+		if cubbyhole {
+			err = w.cubbyholeError(ctx, err)
+		}
+		return nil, Meta{}, fmt.Errorf("failed to read secret from vault: %w", classifyVaultError(err))
+	}
+	if cubbyhole {
+		w.rememberCubbyholeToken(ctx)
+	}
+	if secret.Data == nil {
+		return nil, Meta{}, fmt.Errorf("failed to read secret from vault: secret data is nil")
+	}
+
+	meta := Meta{RequestID: secret.RequestID, Warnings: secret.Warnings, MountType: w.resolveMountType(ctx)}
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok || cubbyhole || meta.MountType == MountTypeKV {
+		// KV v1 format or direct data; cubbyholes are always KV v1
+		data, err := w.decryptValues(ctx, secret.Data)
+		if err != nil {
+			return nil, meta, err
+		}
+		return data, meta, nil
+	}
+
+	// KV v2 format
+	meta.Version, meta.CreatedTime = secretVersion(secret), secretCreatedTime(secret)
+	if w.includeCustomMetadata {
+		if data, err = withCustomMetadata(data, secret); err != nil {
+			return nil, meta, err
+		}
+	}
+	if data, err = w.decryptValues(ctx, data); err != nil {
+		// Keep the request ID for the error
+		return nil, Meta{RequestID: meta.RequestID, MountType: meta.MountType}, err
+	}
+	return data, meta, nil
+}
+
+// resolveMountType returns the type of the secrets engine the watched path is
+// in. It is looked up once in sys/internal/ui/mounts and is empty if Vault
+// doesn't tell, e.g. because the token may not look it up, or the watcher
+// reads through a custom SecretReader.
+func (w *Watcher) resolveMountType(ctx context.Context) MountType {
+	if isCubbyholePath(w.vaultConfig.Path) {
+		return MountTypeCubbyhole
+	}
+	if w.reader != nil {
+		return ""
+	}
+
+	w.mu.RLock()
+	resolved, mountType := w.mountTypeResolved, w.mountType
+	w.mu.RUnlock()
+	if resolved {
+		return mountType
+	}
+
+	mount, err := w.client.Logical().ReadWithContext(ctx, mountsLookupPath+w.vaultConfig.Path)
+	var responseErr *api.ResponseError
+	if err != nil && (!errors.As(err, &responseErr) || responseErr.StatusCode >= http.StatusInternalServerError ||
+		responseErr.StatusCode == http.StatusTooManyRequests) {
+		// Looked up again with the next read
+		return ""
+	}
+	if mount != nil && mount.Data != nil {
+		engine, _ := mount.Data["type"].(string)
+		mountType = MountType(engine)
+		if engine == "kv" || engine == "generic" {
+			mountType = MountTypeKV
+			if options, ok := mount.Data["options"].(map[string]interface{}); ok && fmt.Sprint(options["version"]) == "2" {
+				mountType = MountTypeKVv2
+			}
+		}
+	}
+
+	w.mu.Lock()
+	w.mountType, w.mountTypeResolved = mountType, true
+	w.mu.Unlock()
+	return mountType
+}
@@ -0,0 +1,210 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// isMountLookup reports whether r asks which mount a path is in, so fakes
+// counting reads can ignore it
+func isMountLookup(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/v1/"+mountsLookupPath)
+}
+
+// fakeSource serves data set by the test, versioning every change
+type fakeSource struct {
+	mu      sync.Mutex
+	data    map[string]interface{}
+	version int
+	err     error
+}
+
+func (s *fakeSource) set(data map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	s.version++
+}
+
+func (s *fakeSource) Fetch(ctx context.Context) (map[string]interface{}, Meta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, Meta{}, s.err
+	}
+	data := make(map[string]interface{}, len(s.data))
+	for k, v := range s.data {
+		data[k] = v
+	}
+	return data, Meta{Version: s.version}, nil
+}
+
+func TestNewSourceWatcher_Errors(t *testing.T) {
+	source := &fakeSource{}
+	onChange := func() error { return nil }
+
+	tests := []struct {
+		name     string
+		source   string
+		src      SecretSource
+		onChange func() error
+		opts     []Option
+		wantErr  string
+	}{
+		{name: "no name", src: source, onChange: onChange, wantErr: "source name is required"},
+		{name: "no source", source: "app", onChange: onChange, wantErr: "source cannot be nil"},
+		{name: "no callback", source: "app", src: source, wantErr: "onChange callback cannot be nil"},
+		{name: "pinned version", source: "app", src: source, onChange: onChange, opts: []Option{WithPinnedVersion(2)},
+			wantErr: "WithPinnedVersion needs vault and can't be used with a custom source"},
+		{name: "seal awareness", source: "app", src: source, onChange: onChange, opts: []Option{WithSealAwareness()},
+			wantErr: "WithSealAwareness needs vault and can't be used with a custom source"},
+		{name: "retry policy", source: "app", src: source, onChange: onChange, opts: []Option{WithRetryPolicy(RetryPolicy{})},
+			wantErr: "WithRetryPolicy needs vault and can't be used with a custom source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSourceWatcher(tt.source, tt.src, time.Hour, tt.onChange, tt.opts...)
+			AssertError(t, err, tt.wantErr, "NewSourceWatcher()")
+		})
+	}
+}
+
+func TestSourceWatcher_DetectsChanges(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"key": "one"})
+
+	changes := make(chan ChangeEvent, 1)
+	var watcher *Watcher
+	watcher, err := NewSourceWatcher("app/config", source, 10*time.Millisecond, func() error {
+		event, _ := watcher.LastChange()
+		changes <- event
+		return nil
+	})
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	if version := watcher.CurrentVersion(); version != 1 {
+		t.Errorf("CurrentVersion() = %d, want 1", version)
+	}
+
+	source.set(map[string]interface{}{"key": "two"})
+	select {
+	case event := <-changes:
+		AssertStringEquals(t, event.Path, "app/config", "event path")
+		if event.Version != 2 {
+			t.Errorf("event version = %d, want 2", event.Version)
+		}
+		if len(event.ChangedKeys) != 1 || event.ChangedKeys[0] != "key" {
+			t.Errorf("ChangedKeys = %v, want [key]", event.ChangedKeys)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onChange was not called")
+	}
+}
+
+func TestSourceWatcher_FetchError(t *testing.T) {
+	source := &fakeSource{err: errors.New("source unavailable")}
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	AssertError(t, watcher.Start(), "failed to fetch initial vault data: source unavailable", "Start()")
+}
+
+func TestSourceWatcher_VaultOperations(t *testing.T) {
+	watcher, err := NewSourceWatcher("secret/data/app", &fakeSource{}, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewSourceWatcher()")
+
+	_, err = watcher.UpdateSecret(context.Background(), map[string]interface{}{"key": "value"}, UpdateOptions{Force: true})
+	AssertBoolEquals(t, errors.Is(err, errCustomSource), true, "UpdateSecret() error is errCustomSource")
+	_, err = watcher.ListVersions()
+	AssertBoolEquals(t, errors.Is(err, errCustomSource), true, "ListVersions() error is errCustomSource")
+	AssertBoolEquals(t, errors.Is(watcher.PinVersion(1), errCustomSource), true, "PinVersion() error is errCustomSource")
+
+	_, err = NewFileSync(watcher, t.TempDir()+"/app.json", time.Hour)
+	AssertError(t, err, "file sync writes to vault, so it can't be used with a custom source", "NewFileSync()")
+}
+
+func TestVaultSource_MountType(t *testing.T) {
+	tests := []struct {
+		name      string
+		mount     string // Response of sys/internal/ui/mounts, empty for 403
+		secret    string
+		wantType  MountType
+		wantData  string
+		wantLooks int
+	}{
+		{
+			name:     "kv v2",
+			mount:    `{"data": {"path": "secret/", "type": "kv", "options": {"version": "2"}}}`,
+			secret:   `{"data": {"data": {"password": "one"}, "metadata": {"version": 1}}}`,
+			wantType: MountTypeKVv2, wantData: `{"password":"one"}`, wantLooks: 1,
+		},
+		{
+			name:     "kv v1 secret with a data key",
+			mount:    `{"data": {"path": "secret/", "type": "kv", "options": {"version": "1"}}}`,
+			secret:   `{"data": {"data": {"password": "one"}}}`,
+			wantType: MountTypeKV, wantData: `{"data":{"password":"one"}}`, wantLooks: 1,
+		},
+		{
+			name:     "database",
+			mount:    `{"data": {"path": "secret/", "type": "database", "options": null}}`,
+			secret:   `{"data": {"username": "v-app", "password": "one"}}`,
+			wantType: "database", wantData: `{"password":"one","username":"v-app"}`, wantLooks: 1,
+		},
+		{
+			name:     "lookup denied",
+			secret:   `{"data": {"data": {"password": "one"}, "metadata": {"version": 1}}}`,
+			wantType: "", wantData: `{"password":"one"}`, wantLooks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			lookups := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if !isMountLookup(r) {
+					fmt.Fprint(w, tt.secret)
+					return
+				}
+				mu.Lock()
+				lookups++
+				mu.Unlock()
+				if tt.mount == "" {
+					w.WriteHeader(http.StatusForbidden)
+					fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+					return
+				}
+				fmt.Fprint(w, tt.mount)
+			}))
+			defer server.Close()
+
+			watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "secret/data/app", Token: "t"}, time.Hour,
+				func() error { return nil })
+			AssertNoError(t, err, "NewWatcher()")
+			source := watcher.secretSource()
+			for i := 0; i < 2; i++ {
+				data, meta, err := source.Fetch(context.Background())
+				AssertNoError(t, err, "Fetch()")
+				AssertStringEquals(t, string(meta.MountType), string(tt.wantType), "MountType")
+				assertJSONEquals(t, data, tt.wantData)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if lookups != tt.wantLooks {
+				t.Errorf("mount looked up %d times, want %d", lookups, tt.wantLooks)
+			}
+		})
+	}
+}
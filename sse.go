@@ -0,0 +1,78 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultSSEKeepAlive = 15 * time.Second
+
+// SSEHandler streams change events to browsers and dashboards as Server-Sent Events.
+// Each change is sent as an event named "change" whose data is the JSON ChangeEvent.
+// Clients can limit the stream to specific paths with one or more ?path= parameters.
+type SSEHandler struct {
+	broadcaster *Broadcaster
+	keepAlive   time.Duration
+}
+
+// NewSSEHandler creates an http.Handler streaming the broadcaster's events
+func NewSSEHandler(broadcaster *Broadcaster) *SSEHandler {
+	return &SSEHandler{
+		broadcaster: broadcaster,
+		keepAlive:   defaultSSEKeepAlive,
+	}
+}
+
+// ServeHTTP streams events until the client disconnects or the broadcaster is closed
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := h.broadcaster.Subscribe(r.URL.Query()["path"]...)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	// Tell the browser how long to wait before reconnecting
+	fmt.Fprintf(w, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(h.keepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			// Comments keep proxies from closing idle connections
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: change\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package vaultwatcher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEHandler_StreamsEvents(t *testing.T) {
+	broadcaster := NewBroadcaster(4)
+	defer broadcaster.Close()
+
+	server := httptest.NewServer(NewSSEHandler(broadcaster))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?path=kv/data/app")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	AssertStringEquals(t, resp.Header.Get("Content-Type"), "text/event-stream", "Content-Type")
+
+	// Wait until the handler has subscribed before publishing
+	deadline := time.Now().Add(time.Second)
+	for broadcaster.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx := context.Background()
+	AssertNoError(t, broadcaster.Notify(ctx, ChangeEvent{Path: "kv/data/other", NewHash: "skipped"}), "Notify()")
+	AssertNoError(t, broadcaster.Notify(ctx, ChangeEvent{Path: "kv/data/app", NewHash: "h1", ChangedKeys: []string{"a"}}), "Notify()")
+
+	reader := bufio.NewReader(resp.Body)
+	var eventName, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventName = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	AssertStringEquals(t, eventName, "change", "event name")
+	var event ChangeEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("invalid event data %q: %v", data, err)
+	}
+	AssertStringEquals(t, event.Path, "kv/data/app", "event path")
+	AssertStringEquals(t, event.NewHash, "h1", "event hash")
+}
+
+func TestSSEHandler_MethodNotAllowed(t *testing.T) {
+	broadcaster := NewBroadcaster(1)
+	defer broadcaster.Close()
+
+	recorder := httptest.NewRecorder()
+	NewSSEHandler(broadcaster).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/events", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestSSEHandler_StopsWhenBroadcasterCloses(t *testing.T) {
+	broadcaster := NewBroadcaster(1)
+
+	done := make(chan struct{})
+	recorder := httptest.NewRecorder()
+	go func() {
+		NewSSEHandler(broadcaster).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/events", nil))
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for broadcaster.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	broadcaster.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ServeHTTP() did not return after the broadcaster closed")
+	}
+}
@@ -0,0 +1,348 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultSSHTTL = time.Hour
+
+// SSHConfig describes the key material requested from the ssh secrets engine
+type SSHConfig struct {
+	PublicKey       string        // Public key to sign, required for <mount>/sign/<role>
+	ValidPrincipals string        // Optional comma separated principals for the certificate
+	CertType        string        // "user" or "host" (default "user")
+	IP              string        // Target host IP, required for <mount>/creds/<role> OTPs
+	Username        string        // Optional username for OTPs
+	TTL             time.Duration // Requested validity (default 1h)
+	RetryInterval   time.Duration // Wait before retrying a failed refresh (default 5s)
+}
+
+// SSHCredential holds a signed certificate or an OTP issued by the ssh secrets engine
+type SSHCredential struct {
+	Path         string
+	SignedKey    string // OpenSSH certificate, empty for OTPs
+	SerialNumber string
+	OTP          string // One-time password, empty for certificates
+	Username     string
+	IP           string
+	IssuedAt     time.Time
+	ExpiresAt    time.Time
+}
+
+// SSHWatcher keeps SSH key material fresh. For <mount>/sign/<role> it signs
+// the configured public key; for <mount>/creds/<role> it requests an OTP. New
+// material is requested when two thirds of the previous validity have elapsed.
+type SSHWatcher struct {
+	vaultConfig *VaultConfig
+	sshConfig   SSHConfig
+	client      *api.Client
+	onRefresh   func(*SSHCredential) error
+	sign        bool
+	current     *SSHCredential
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	mu          sync.RWMutex
+	started     bool
+}
+
+// NewSSHWatcher creates a watcher for the ssh secrets engine
+// vaultConfig: Vault connection configuration, Path is e.g. "ssh-client-signer/sign/deploy" or "ssh/creds/otp"
+// sshConfig: Key material to request
+// onRefresh: Callback invoked with the initial and every newly issued credential
+func NewSSHWatcher(vaultConfig *VaultConfig, sshConfig SSHConfig, onRefresh func(*SSHCredential) error) (*SSHWatcher, error) {
+	if err := validateVaultConfig(vaultConfig); err != nil {
+		return nil, err
+	}
+	if onRefresh == nil {
+		return nil, fmt.Errorf("onRefresh callback cannot be nil")
+	}
+
+	sign := strings.Contains(vaultConfig.Path, "/sign/")
+	switch {
+	case sign && sshConfig.PublicKey == "":
+		return nil, fmt.Errorf("public key is required to sign ssh certificates")
+	case !sign && !strings.Contains(vaultConfig.Path, "/creds/"):
+		return nil, fmt.Errorf("ssh path must look like <mount>/sign/<role> or <mount>/creds/<role>, got %q", vaultConfig.Path)
+	case !sign && sshConfig.IP == "":
+		return nil, fmt.Errorf("IP is required to request ssh OTPs")
+	}
+	if sshConfig.CertType == "" {
+		sshConfig.CertType = "user"
+	}
+	if sshConfig.TTL <= 0 {
+		sshConfig.TTL = defaultSSHTTL
+	}
+	if sshConfig.RetryInterval <= 0 {
+		sshConfig.RetryInterval = defaultDynamicRetryInterval
+	}
+
+	client, err := newVaultClient(vaultConfig, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SSHWatcher{
+		vaultConfig: vaultConfig,
+		sshConfig:   sshConfig,
+		client:      client,
+		onRefresh:   onRefresh,
+		sign:        sign,
+		ctx:         ctx,
+		cancel:      cancel,
+	}, nil
+}
+
+// Start requests the initial credential, passes it to the callback and begins refreshing it
+func (w *SSHWatcher) Start() error {
+	w.mu.Lock()
+	if w.started {
+		w.mu.Unlock()
+		return fmt.Errorf("watcher is already started")
+	}
+	w.started = true
+	w.mu.Unlock()
+
+	if err := w.refresh(); err != nil {
+		w.mu.Lock()
+		w.started = false
+		w.mu.Unlock()
+		return fmt.Errorf("failed to issue initial ssh credential: %w", err)
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return nil
+}
+
+// Stop stops refreshing. The current credential stays valid until it expires.
+func (w *SSHWatcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+
+	w.mu.Lock()
+	w.started = false
+	w.mu.Unlock()
+}
+
+// Current returns the credential most recently delivered to the callback
+func (w *SSHWatcher) Current() *SSHCredential {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// IsStarted returns whether the watcher is currently running
+func (w *SSHWatcher) IsStarted() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.started
+}
+
+// run refreshes the credential on schedule, retrying failures before it expires
+func (w *SSHWatcher) run() {
+	defer w.wg.Done()
+
+	current := w.Current()
+	next := current.IssuedAt.Add(current.ExpiresAt.Sub(current.IssuedAt) * 2 / 3)
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := w.refresh(); err != nil {
+			slog.Error("Error refreshing ssh credential", "error", err)
+			next = time.Now().Add(w.retryDelay())
+			continue
+		}
+		current = w.Current()
+		next = current.IssuedAt.Add(current.ExpiresAt.Sub(current.IssuedAt) * 2 / 3)
+	}
+}
+
+// retryDelay returns the retry interval, shortened so retries happen before the credential expires
+func (w *SSHWatcher) retryDelay() time.Duration {
+	remaining := time.Until(w.Current().ExpiresAt)
+	if delay := remaining / 4; delay < w.sshConfig.RetryInterval {
+		return max(delay, minLeaseRetryInterval)
+	}
+	return w.sshConfig.RetryInterval
+}
+
+// refresh requests new key material and passes it to the callback. The current
+// credential is only replaced once the callback has accepted the new one.
+func (w *SSHWatcher) refresh() error {
+	data := map[string]interface{}{"ttl": w.sshConfig.TTL.String()}
+	if w.sign {
+		data["public_key"] = w.sshConfig.PublicKey
+		data["cert_type"] = w.sshConfig.CertType
+		if w.sshConfig.ValidPrincipals != "" {
+			data["valid_principals"] = w.sshConfig.ValidPrincipals
+		}
+	} else {
+		data["ip"] = w.sshConfig.IP
+		if w.sshConfig.Username != "" {
+			data["username"] = w.sshConfig.Username
+		}
+	}
+
+	secret, err := w.client.Logical().WriteWithContext(w.ctx, w.vaultConfig.Path, data)
+	if err != nil {
+		return fmt.Errorf("failed to request ssh credential from vault: %w", classifyVaultError(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return fmt.Errorf("failed to request ssh credential from vault: secret is nil")
+	}
+
+	issued := parseSSHCredential(w.vaultConfig.Path, secret, w.sshConfig.TTL, time.Now())
+	if w.sign && issued.SignedKey == "" {
+		return fmt.Errorf("response from %s does not contain a signed key", w.vaultConfig.Path)
+	}
+	if !w.sign && issued.OTP == "" {
+		return fmt.Errorf("response from %s does not contain an OTP", w.vaultConfig.Path)
+	}
+
+	if err := w.onRefresh(issued); err != nil {
+		return &CallbackError{Callback: "onRefresh", Err: err}
+	}
+
+	w.mu.Lock()
+	w.current = issued
+	w.mu.Unlock()
+
+	return nil
+}
+
+// parseSSHCredential builds a credential from a sign or creds response. The expiry
+// comes from the certificate itself, the OTP lease, or the requested TTL.
+func parseSSHCredential(path string, secret *api.Secret, ttl time.Duration, now time.Time) *SSHCredential {
+	get := func(key string) string {
+		if value, ok := secret.Data[key]; ok && value != nil {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+
+	credential := &SSHCredential{
+		Path:         path,
+		SignedKey:    strings.TrimSpace(get("signed_key")),
+		SerialNumber: get("serial_number"),
+		Username:     get("username"),
+		IP:           get("ip"),
+		IssuedAt:     now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	if get("key_type") == "otp" {
+		credential.OTP = get("key")
+	}
+
+	if credential.SignedKey != "" {
+		if validBefore, err := certificateValidBefore(credential.SignedKey); err == nil {
+			credential.ExpiresAt = validBefore
+		}
+	} else if secret.LeaseDuration > 0 {
+		credential.ExpiresAt = now.Add(time.Duration(secret.LeaseDuration) * time.Second)
+	}
+
+	return credential
+}
+
+// certificateValidBefore reads the valid_before field of an OpenSSH certificate
+// in authorized_keys format, e.g. "ssh-ed25519-cert-v01@openssh.com AAAA..."
+func certificateValidBefore(signedKey string) (time.Time, error) {
+	fields := strings.Fields(signedKey)
+	if len(fields) < 2 {
+		return time.Time{}, fmt.Errorf("malformed ssh certificate")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed ssh certificate: %w", err)
+	}
+
+	r := &sshReader{buf: blob}
+	certType := string(r.bytes())
+	r.bytes() // nonce
+
+	// Public key fields, see PROTOCOL.certkeys in OpenSSH
+	switch {
+	case strings.HasPrefix(certType, "ssh-rsa-cert"):
+		r.bytes() // e
+		r.bytes() // n
+	case strings.HasPrefix(certType, "ssh-ed25519-cert"):
+		r.bytes() // pk
+	case strings.HasPrefix(certType, "ecdsa-sha2-"):
+		r.bytes() // curve
+		r.bytes() // public key
+	default:
+		return time.Time{}, fmt.Errorf("unsupported ssh certificate type %q", certType)
+	}
+
+	r.uint64() // serial
+	r.uint32() // type
+	r.bytes()  // key id
+	r.bytes()  // valid principals
+	r.uint64() // valid after
+	validBefore := r.uint64()
+	if r.err != nil {
+		return time.Time{}, fmt.Errorf("malformed ssh certificate: %w", r.err)
+	}
+	if validBefore > uint64(1<<62) {
+		// Certificates valid forever use the maximum value
+		return time.Time{}, fmt.Errorf("ssh certificate does not expire")
+	}
+
+	return time.Unix(int64(validBefore), 0), nil
+}
+
+// sshReader decodes the SSH wire format, remembering the first error
+type sshReader struct {
+	buf []byte
+	err error
+}
+
+func (r *sshReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = fmt.Errorf("unexpected end of data")
+		return nil
+	}
+	data := r.buf[:n]
+	r.buf = r.buf[n:]
+	return data
+}
+
+func (r *sshReader) uint32() uint32 {
+	if data := r.next(4); data != nil {
+		return binary.BigEndian.Uint32(data)
+	}
+	return 0
+}
+
+func (r *sshReader) uint64() uint64 {
+	if data := r.next(8); data != nil {
+		return binary.BigEndian.Uint64(data)
+	}
+	return 0
+}
+
+func (r *sshReader) bytes() []byte {
+	return r.next(int(r.uint32()))
+}
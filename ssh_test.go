@@ -0,0 +1,156 @@
+package vaultwatcher
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// buildSSHCertificate builds an ed25519 OpenSSH certificate with the given validity
+func buildSSHCertificate(validBefore uint64) string {
+	var blob []byte
+	putString := func(s string) {
+		blob = binary.BigEndian.AppendUint32(blob, uint32(len(s)))
+		blob = append(blob, s...)
+	}
+
+	putString("ssh-ed25519-cert-v01@openssh.com")
+	putString("nonce")
+	putString(strings.Repeat("k", 32))
+	blob = binary.BigEndian.AppendUint64(blob, 42) // serial
+	blob = binary.BigEndian.AppendUint32(blob, 1)  // user certificate
+	putString("vault-deploy")
+	putString("")
+	blob = binary.BigEndian.AppendUint64(blob, 0)
+	blob = binary.BigEndian.AppendUint64(blob, validBefore)
+
+	return "ssh-ed25519-cert-v01@openssh.com " + base64.StdEncoding.EncodeToString(blob)
+}
+
+func TestCertificateValidBefore(t *testing.T) {
+	validBefore, err := certificateValidBefore(buildSSHCertificate(1700000000))
+	AssertNoError(t, err, "certificateValidBefore()")
+	if !validBefore.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("certificateValidBefore() = %v, want %v", validBefore, time.Unix(1700000000, 0))
+	}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"not a certificate", "ssh-ed25519"},
+		{"bad base64", "ssh-ed25519-cert-v01@openssh.com !!!"},
+		{"truncated", buildSSHCertificate(1700000000)[:60]},
+		{"forever", buildSSHCertificate(^uint64(0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := certificateValidBefore(tt.key); err == nil {
+				t.Error("certificateValidBefore() expected error")
+			}
+		})
+	}
+}
+
+func TestNewSSHWatcher_Validation(t *testing.T) {
+	onRefresh := func(*SSHCredential) error { return nil }
+	tests := []struct {
+		name      string
+		path      string
+		sshConfig SSHConfig
+		errMsg    string
+	}{
+		{"sign without key", "ssh/sign/deploy", SSHConfig{}, "public key is required to sign ssh certificates"},
+		{"otp without ip", "ssh/creds/otp", SSHConfig{}, "IP is required to request ssh OTPs"},
+		{"unknown path", "ssh/roles/deploy", SSHConfig{}, `ssh path must look like <mount>/sign/<role> or <mount>/creds/<role>, got "ssh/roles/deploy"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &VaultConfig{Host: "https://vault.example.com", Path: tt.path, Token: "t"}
+			_, err := NewSSHWatcher(config, tt.sshConfig, onRefresh)
+			AssertError(t, err, tt.errMsg, "NewSSHWatcher()")
+		})
+	}
+}
+
+func TestSSHWatcher_RefreshesCertificate(t *testing.T) {
+	var mu sync.Mutex
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, body)
+		serial := len(requests)
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"serial_number": fmt.Sprintf("serial-%d", serial),
+				"signed_key":    buildSSHCertificate(uint64(time.Now().Add(time.Second).Unix())) + "\n",
+			},
+		})
+	}))
+	defer server.Close()
+
+	var delivered []string
+	config := &VaultConfig{Host: server.URL, Path: "ssh-client-signer/sign/deploy", Token: "t"}
+	watcher, err := NewSSHWatcher(config, SSHConfig{PublicKey: "ssh-ed25519 AAAA", ValidPrincipals: "deploy"}, func(c *SSHCredential) error {
+		mu.Lock()
+		delivered = append(delivered, c.SerialNumber)
+		mu.Unlock()
+		return nil
+	})
+	AssertNoError(t, err, "NewSSHWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	waitFor(t, 3*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(delivered) >= 2
+	}, "certificate refresh")
+
+	mu.Lock()
+	defer mu.Unlock()
+	AssertStringEquals(t, delivered[0], "serial-1", "first serial")
+	AssertStringEquals(t, requests[0]["public_key"].(string), "ssh-ed25519 AAAA", "public_key")
+	AssertStringEquals(t, requests[0]["cert_type"].(string), "user", "cert_type")
+	AssertStringEquals(t, requests[0]["valid_principals"].(string), "deploy", "valid_principals")
+}
+
+func TestSSHWatcher_OTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       "ssh/creds/otp/abc",
+			"lease_duration": 600,
+			"data": map[string]interface{}{
+				"key":      "2f7e25a2-24c9-4b7b-0d35-27d5e5203a5c",
+				"key_type": "otp",
+				"username": "ubuntu",
+				"ip":       "10.0.0.5",
+			},
+		})
+	}))
+	defer server.Close()
+
+	config := &VaultConfig{Host: server.URL, Path: "ssh/creds/otp", Token: "t"}
+	watcher, err := NewSSHWatcher(config, SSHConfig{IP: "10.0.0.5"}, func(*SSHCredential) error { return nil })
+	AssertNoError(t, err, "NewSSHWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	current := watcher.Current()
+	AssertStringEquals(t, current.OTP, "2f7e25a2-24c9-4b7b-0d35-27d5e5203a5c", "OTP")
+	AssertStringEquals(t, current.Username, "ubuntu", "Username")
+	if ttl := current.ExpiresAt.Sub(current.IssuedAt); ttl != 10*time.Minute {
+		t.Errorf("OTP validity = %v, want 10m from the lease", ttl)
+	}
+}
@@ -0,0 +1,398 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateStore is a key/value store for watcher state. Sharing one store between
+// instances lets them coordinate, e.g. so a change is handled only once.
+type StateStore interface {
+	// Get returns the value of key, or nil if it doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put sets the value of key
+	Put(ctx context.Context, key string, value []byte) error
+	// CompareAndSwap sets key to new only if its value is old, where a nil old
+	// means the key must not exist. It reports whether the value was swapped.
+	CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error)
+	// Delete removes key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// handledChangeKey is the state key recording the last handled hash of a path
+func handledChangeKey(path string) string {
+	return "handled/" + path
+}
+
+// claimChange records newHash as handled for the watched path. It returns false
+// when another instance sharing the state store already handled it, and the
+// previously recorded value so the claim can be undone.
+func (w *Watcher) claimChange(newHash string) (bool, []byte, error) {
+	key := handledChangeKey(w.vaultConfig.Path)
+	for attempt := 0; attempt < 3; attempt++ {
+		stored, err := w.stateStore.Get(w.ctx, key)
+		if err != nil {
+			return false, nil, err
+		}
+		if string(stored) == newHash {
+			return false, nil, nil
+		}
+
+		swapped, err := w.stateStore.CompareAndSwap(w.ctx, key, stored, []byte(newHash))
+		if err != nil {
+			return false, nil, err
+		}
+		if swapped {
+			return true, stored, nil
+		}
+		// Another instance updated the record in the meantime; look again
+	}
+	return false, nil, fmt.Errorf("state for %s keeps changing", w.vaultConfig.Path)
+}
+
+// releaseChange undoes a claim after the change could not be handled, so this
+// or another instance retries it
+func (w *Watcher) releaseChange(newHash string, previous []byte) {
+	key := handledChangeKey(w.vaultConfig.Path)
+
+	var err error
+	if previous == nil {
+		var stored []byte
+		if stored, err = w.stateStore.Get(w.ctx, key); err == nil && string(stored) == newHash {
+			err = w.stateStore.Delete(w.ctx, key)
+		}
+	} else {
+		_, err = w.stateStore.CompareAndSwap(w.ctx, key, []byte(newHash), previous)
+	}
+	if err != nil {
+		w.logger.Error("Error releasing change claim", "error", err)
+	}
+}
+
+// MemoryStateStore is a StateStore kept in memory, shared by watchers in one process
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+// NewMemoryStateStore creates an empty in-memory state store
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{values: make(map[string][]byte)}
+}
+
+// Get returns the value of key, or nil if it doesn't exist
+func (s *MemoryStateStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.values[key]), nil
+}
+
+// Put sets the value of key
+func (s *MemoryStateStore) Put(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = bytes.Clone(value)
+	return nil
+}
+
+// CompareAndSwap sets key to new only if its value is old (nil: absent)
+func (s *MemoryStateStore) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.values[key]
+	if (old == nil && exists) || (old != nil && (!exists || !bytes.Equal(current, old))) {
+		return false, nil
+	}
+	s.values[key] = bytes.Clone(new)
+	return true, nil
+}
+
+// Delete removes key
+func (s *MemoryStateStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+// staleFileLockAge is when a leftover lock of a crashed process is ignored
+const staleFileLockAge = 10 * time.Second
+
+// FileStateStore is a StateStore keeping one file per key in a directory, e.g.
+// on a volume shared by replicas. Compare-and-swap is serialised with lock files.
+type FileStateStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStateStore creates a state store in dir, creating it if needed
+func NewFileStateStore(dir string) (*FileStateStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("state directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return &FileStateStore{dir: dir}, nil
+}
+
+// Get returns the value of key, or nil if it doesn't exist
+func (s *FileStateStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(s.file(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Put sets the value of key
+func (s *FileStateStore) Put(ctx context.Context, key string, value []byte) error {
+	return s.write(key, value)
+}
+
+// CompareAndSwap sets key to new only if its value is old (nil: absent)
+func (s *FileStateStore) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error) {
+	unlock, err := s.lock(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	current, err := s.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if (old == nil) != (current == nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+	if err := s.write(key, new); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes key
+func (s *FileStateStore) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(s.file(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete state %s: %w", key, err)
+	}
+	return nil
+}
+
+// file returns the file holding key; keys are escaped into a single file name
+func (s *FileStateStore) file(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key)+".state")
+}
+
+// write replaces the file of key atomically
+func (s *FileStateStore) write(key string, value []byte) error {
+	tmp, err := os.CreateTemp(s.dir, ".state-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state %s: %w", key, err)
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), s.file(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write state %s: %w", key, err)
+	}
+	return nil
+}
+
+// lock takes the lock file of key, waiting while another process holds it
+func (s *FileStateStore) lock(ctx context.Context, key string) (func(), error) {
+	s.mu.Lock()
+	lockFile := s.file(key) + ".lock"
+
+	for {
+		f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(lockFile)
+				s.mu.Unlock()
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("failed to lock state %s: %w", key, err)
+		}
+
+		if info, statErr := os.Stat(lockFile); statErr == nil && time.Since(info.ModTime()) > staleFileLockAge {
+			os.Remove(lockFile)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			s.mu.Unlock()
+			return nil, fmt.Errorf("failed to lock state %s: %w", key, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// ConsulStateStore is a StateStore in Consul KV, using its check-and-set
+// index for CompareAndSwap
+type ConsulStateStore struct {
+	address string
+	prefix  string
+	token   string
+	client  *http.Client
+}
+
+// ConsulStateStoreConfig holds the configuration for a ConsulStateStore
+type ConsulStateStoreConfig struct {
+	Address    string       // Consul HTTP address (default "http://127.0.0.1:8500")
+	Prefix     string       // Key prefix (default "vault-watcher/")
+	Token      string       // Optional ACL token
+	HTTPClient *http.Client // Optional custom HTTP client
+}
+
+// NewConsulStateStore creates a state store in Consul KV
+func NewConsulStateStore(config ConsulStateStoreConfig) *ConsulStateStore {
+	if config.Address == "" {
+		config.Address = "http://127.0.0.1:8500"
+	}
+	if config.Prefix == "" {
+		config.Prefix = "vault-watcher/"
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &ConsulStateStore{
+		address: strings.TrimSuffix(config.Address, "/"),
+		prefix:  config.Prefix,
+		token:   config.Token,
+		client:  client,
+	}
+}
+
+// consulKVPair is an entry returned by the Consul KV API
+type consulKVPair struct {
+	Key         string `json:"Key"`
+	Value       string `json:"Value"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+// Get returns the value of key, or nil if it doesn't exist
+func (s *ConsulStateStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := s.get(ctx, key)
+	return value, err
+}
+
+// Put sets the value of key
+func (s *ConsulStateStore) Put(ctx context.Context, key string, value []byte) error {
+	if _, err := s.do(ctx, http.MethodPut, s.url(key, ""), value, nil); err != nil {
+		return fmt.Errorf("failed to put state %s: %w", key, err)
+	}
+	return nil
+}
+
+// CompareAndSwap sets key to new only if its value is old (nil: absent)
+func (s *ConsulStateStore) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error) {
+	current, index, err := s.get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if (old == nil) != (current == nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	var swapped bool
+	if _, err := s.do(ctx, http.MethodPut, s.url(key, fmt.Sprintf("cas=%d", index)), new, &swapped); err != nil {
+		return false, fmt.Errorf("failed to swap state %s: %w", key, err)
+	}
+	return swapped, nil
+}
+
+// Delete removes key
+func (s *ConsulStateStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.do(ctx, http.MethodDelete, s.url(key, ""), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete state %s: %w", key, err)
+	}
+	return nil
+}
+
+// get returns the value and modify index of key; the index is 0 when it doesn't exist
+func (s *ConsulStateStore) get(ctx context.Context, key string) ([]byte, uint64, error) {
+	var pairs []consulKVPair
+	status, err := s.do(ctx, http.MethodGet, s.url(key, ""), nil, &pairs)
+	if status == http.StatusNotFound {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get state %s: %w", key, err)
+	}
+	if len(pairs) == 0 {
+		return nil, 0, nil
+	}
+
+	value, err := base64.StdEncoding.DecodeString(pairs[0].Value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode state %s: %w", key, err)
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, pairs[0].ModifyIndex, nil
+}
+
+func (s *ConsulStateStore) url(key, query string) string {
+	u := s.address + "/v1/kv/" + s.prefix + key
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+// do sends a raw value to the Consul KV API and decodes the JSON response into out
+func (s *ConsulStateStore) do(ctx context.Context, method, requestURL string, value []byte, out interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(value))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
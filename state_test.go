@@ -0,0 +1,220 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsulKV implements the KV endpoints used by ConsulStateStore
+type fakeConsulKV struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	index  map[string]uint64
+	next   uint64
+}
+
+func (f *fakeConsulKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	switch r.Method {
+	case http.MethodGet:
+		value, ok := f.values[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{
+			"Key":         key,
+			"Value":       base64.StdEncoding.EncodeToString(value),
+			"ModifyIndex": f.index[key],
+		}})
+	case http.MethodPut:
+		if cas := r.URL.Query().Get("cas"); cas != "" {
+			want, _ := strconv.ParseUint(cas, 10, 64)
+			if f.index[key] != want {
+				w.Write([]byte("false"))
+				return
+			}
+		}
+		value, _ := io.ReadAll(r.Body)
+		f.next++
+		f.values[key], f.index[key] = value, f.next
+		w.Write([]byte("true"))
+	case http.MethodDelete:
+		delete(f.values, key)
+		delete(f.index, key)
+		w.Write([]byte("true"))
+	}
+}
+
+func TestStateStores(t *testing.T) {
+	consul := httptest.NewServer(&fakeConsulKV{values: map[string][]byte{}, index: map[string]uint64{}})
+	defer consul.Close()
+
+	fileStore, err := NewFileStateStore(t.TempDir())
+	AssertNoError(t, err, "NewFileStateStore()")
+
+	logStore, err := NewLogStateStore(filepath.Join(t.TempDir(), "state.log"))
+	AssertNoError(t, err, "NewLogStateStore()")
+	defer logStore.Close()
+
+	cipher, err := NewAESStateCipher(make([]byte, 32))
+	AssertNoError(t, err, "NewAESStateCipher()")
+
+	stores := map[string]StateStore{
+		"memory":    NewMemoryStateStore(),
+		"file":      fileStore,
+		"log":       logStore,
+		"consul":    NewConsulStateStore(ConsulStateStoreConfig{Address: consul.URL}),
+		"encrypted": NewEncryptedStateStore(NewMemoryStateStore(), cipher),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "handled/kv/data/app"
+
+			value, err := store.Get(ctx, key)
+			AssertNoError(t, err, "Get(missing)")
+			if value != nil {
+				t.Errorf("Get(missing) = %q, want nil", value)
+			}
+
+			swapped, err := store.CompareAndSwap(ctx, key, []byte("a"), []byte("b"))
+			AssertNoError(t, err, "CompareAndSwap(missing)")
+			AssertBoolEquals(t, swapped, false, "swap of a missing key with an old value")
+
+			swapped, _ = store.CompareAndSwap(ctx, key, nil, []byte("a"))
+			AssertBoolEquals(t, swapped, true, "swap of a missing key")
+
+			swapped, _ = store.CompareAndSwap(ctx, key, nil, []byte("b"))
+			AssertBoolEquals(t, swapped, false, "create of an existing key")
+
+			swapped, _ = store.CompareAndSwap(ctx, key, []byte("a"), []byte("b"))
+			AssertBoolEquals(t, swapped, true, "swap with matching old value")
+
+			value, _ = store.Get(ctx, key)
+			AssertStringEquals(t, string(value), "b", "Get()")
+
+			AssertNoError(t, store.Put(ctx, key, []byte("c")), "Put()")
+			value, _ = store.Get(ctx, key)
+			AssertStringEquals(t, string(value), "c", "Get() after Put()")
+
+			AssertNoError(t, store.Delete(ctx, key), "Delete()")
+			AssertNoError(t, store.Delete(ctx, key), "Delete(missing)")
+			value, _ = store.Get(ctx, key)
+			if value != nil {
+				t.Errorf("Get() after Delete() = %q, want nil", value)
+			}
+		})
+	}
+}
+
+func TestFileStateStore_ConcurrentCompareAndSwap(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// Separate stores on the same directory behave like separate processes
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	swaps := 0
+	for i := 0; i < 8; i++ {
+		store, err := NewFileStateStore(dir)
+		AssertNoError(t, err, "NewFileStateStore()")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			swapped, err := store.CompareAndSwap(ctx, "claim", nil, []byte("x"))
+			if err != nil {
+				t.Errorf("CompareAndSwap() error = %v", err)
+			}
+			if swapped {
+				mu.Lock()
+				swaps++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if swaps != 1 {
+		t.Errorf("%d instances claimed the key, want 1", swaps)
+	}
+}
+
+func TestWatcher_StateStoreDeduplicatesChanges(t *testing.T) {
+	vault := &countingVault{reads: map[string]int{}, value: "v1"}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	store := NewMemoryStateStore()
+	var mu sync.Mutex
+	handled := map[string]int{}
+	failNext := false
+	newReplica := func(name string) *Watcher {
+		watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "kv/data/test", Token: name},
+			time.Hour, func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				if failNext {
+					failNext = false
+					return errors.New("migration failed")
+				}
+				handled[name]++
+				return nil
+			}, WithStateStore(store))
+		AssertNoError(t, err, "NewWatcher()")
+		AssertNoError(t, watcher.Start(), "Start()")
+		return watcher
+	}
+
+	first, second := newReplica("first"), newReplica("second")
+	defer first.Stop()
+	defer second.Stop()
+
+	setValue := func(value string) {
+		vault.mu.Lock()
+		vault.value = value
+		vault.mu.Unlock()
+	}
+
+	setValue("v2")
+	AssertNoError(t, first.checkForChanges(), "first checkForChanges()")
+	AssertNoError(t, second.checkForChanges(), "second checkForChanges()")
+	if handled["first"] != 1 || handled["second"] != 0 {
+		t.Fatalf("handled = %v, want the change handled once by first", handled)
+	}
+	AssertStringEquals(t, second.GetCurrentHash(), first.GetCurrentHash(), "second adopts the new hash")
+
+	// Reverting is a new change
+	setValue("v1")
+	AssertNoError(t, second.checkForChanges(), "second checkForChanges()")
+	AssertNoError(t, first.checkForChanges(), "first checkForChanges()")
+	if handled["first"] != 1 || handled["second"] != 1 {
+		t.Fatalf("handled = %v, want the revert handled once by second", handled)
+	}
+
+	// A failed callback releases the claim so another instance retries
+	setValue("v3")
+	failNext = true
+	if err := first.checkForChanges(); err == nil {
+		t.Fatal("first checkForChanges() expected error")
+	}
+	AssertNoError(t, second.checkForChanges(), "second checkForChanges()")
+	if handled["second"] != 2 {
+		t.Errorf("handled = %v, want second to handle the released change", handled)
+	}
+}
@@ -0,0 +1,116 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStatsDAddress = "127.0.0.1:8125"
+	defaultStatsDPrefix  = "vaultwatcher"
+)
+
+// StatsDConfig holds the configuration for a StatsDSink
+type StatsDConfig struct {
+	Address string            // UDP address of the agent (default "127.0.0.1:8125")
+	Prefix  string            // Prepended to metric names with a dot (default "vaultwatcher")
+	Tags    map[string]string // Added to every metric, e.g. {"env": "prod"}
+	// DogStatsD sends tags in the DogStatsD format understood by the Datadog
+	// agent and Telegraf. Plain StatsD has no tags, so they are dropped.
+	DogStatsD bool
+}
+
+// StatsDSink is a MetricsSink sending metrics to a StatsD or DogStatsD agent
+// over UDP. Counters are sent as "|c" and timings in milliseconds as "|ms".
+// Sends are fire-and-forget; a missing agent loses the metrics silently.
+type StatsDSink struct {
+	conn       net.Conn
+	prefix     string
+	tags       map[string]string
+	dogStatsD  bool
+	tagEscaper *strings.Replacer
+}
+
+// NewStatsDSink creates a sink sending to config.Address. Register it with
+// WithMetricsSink.
+func NewStatsDSink(config StatsDConfig) (*StatsDSink, error) {
+	address := config.Address
+	if address == "" {
+		address = defaultStatsDAddress
+	}
+	prefix := config.Prefix
+	if prefix == "" {
+		prefix = defaultStatsDPrefix
+	}
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open statsd connection: %w", err)
+	}
+	return &StatsDSink{
+		conn:       conn,
+		prefix:     strings.TrimSuffix(prefix, ".") + ".",
+		tags:       config.Tags,
+		dogStatsD:  config.DogStatsD,
+		tagEscaper: strings.NewReplacer(",", "_", "|", "_", "#", "_"),
+	}, nil
+}
+
+// Count sends a counter increment
+func (s *StatsDSink) Count(name string, value int64, tags map[string]string) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Timing sends a duration in milliseconds
+func (s *StatsDSink) Timing(name string, duration time.Duration, tags map[string]string) {
+	ms := float64(duration) / float64(time.Millisecond)
+	s.send(name, strconv.FormatFloat(ms, 'f', -1, 64), "ms", tags)
+}
+
+// Close closes the connection to the agent
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+// send writes one metric line, e.g. "vaultwatcher.checks:1|c|#path:app"
+func (s *StatsDSink) send(name, value, kind string, tags map[string]string) {
+	var line strings.Builder
+	line.WriteString(s.prefix)
+	line.WriteString(name)
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(kind)
+
+	if s.dogStatsD && len(tags)+len(s.tags) > 0 {
+		merged := make(map[string]string, len(tags)+len(s.tags))
+		for key, value := range s.tags {
+			merged[key] = value
+		}
+		for key, value := range tags {
+			merged[key] = value
+		}
+		keys := make([]string, 0, len(merged))
+		for key := range merged {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		line.WriteString("|#")
+		for i, key := range keys {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(s.tagEscaper.Replace(key))
+			line.WriteByte(':')
+			line.WriteString(s.tagEscaper.Replace(merged[key]))
+		}
+	}
+
+	// UDP, so a missing agent isn't an error worth reporting
+	s.conn.Write([]byte(line.String()))
+}
@@ -0,0 +1,87 @@
+package vaultwatcher
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenStatsD returns a UDP listener and a function reading the lines
+// received until none arrive for a short while
+func listenStatsD(t *testing.T) (*net.UDPConn, func() []string) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	AssertNoError(t, err, "ListenUDP()")
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, func() []string {
+		var lines []string
+		buffer := make([]byte, 1024)
+		for {
+			conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, err := conn.Read(buffer)
+			if err != nil {
+				return lines
+			}
+			lines = append(lines, string(buffer[:n]))
+		}
+	}
+}
+
+func TestStatsDSink_Format(t *testing.T) {
+	tests := []struct {
+		name   string
+		config StatsDConfig
+		want   string
+	}{
+		{name: "plain", want: "vaultwatcher.checks:1|c vaultwatcher.callback.duration:12.5|ms"},
+		{name: "dogstatsd", config: StatsDConfig{Prefix: "app.vault.", DogStatsD: true, Tags: map[string]string{"env": "prod"}},
+			want: "app.vault.checks:1|c|#env:prod,path:secret/data/a_b app.vault.callback.duration:12.5|ms|#env:prod,path:secret/data/a_b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, received := listenStatsD(t)
+			tt.config.Address = conn.LocalAddr().String()
+			sink, err := NewStatsDSink(tt.config)
+			AssertNoError(t, err, "NewStatsDSink()")
+			defer sink.Close()
+
+			tags := map[string]string{"path": "secret/data/a,b"}
+			sink.Count("checks", 1, tags)
+			sink.Timing("callback.duration", 12500*time.Microsecond, tags)
+			AssertStringEquals(t, strings.Join(received(), " "), tt.want, "lines")
+		})
+	}
+}
+
+func TestWatcher_MetricsSink(t *testing.T) {
+	conn, received := listenStatsD(t)
+	sink, err := NewStatsDSink(StatsDConfig{Address: conn.LocalAddr().String(), DogStatsD: true})
+	AssertNoError(t, err, "NewStatsDSink()")
+	defer sink.Close()
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil }, WithMetricsSink(sink))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	source.set(map[string]interface{}{"password": "two"})
+	AssertNoError(t, watcher.check(), "check()")
+
+	var names []string
+	for _, line := range received() {
+		name, _, _ := strings.Cut(line, "|#")
+		if strings.Contains(name, "callback.duration") {
+			name = "vaultwatcher.callback.duration:<ms>|ms"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	AssertStringEquals(t, strings.Join(names, " "),
+		"vaultwatcher.callback.duration:<ms>|ms vaultwatcher.changes:1|c vaultwatcher.checks:1|c", "metrics")
+}
@@ -0,0 +1,84 @@
+package vaultwatcher
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// systemdNotifier sends sd_notify messages to the socket systemd passes in
+// NOTIFY_SOCKET
+type systemdNotifier struct {
+	addr     *net.UnixAddr
+	watchdog time.Duration // WATCHDOG_USEC, zero without a watchdog
+	logger   *slog.Logger  // Of the watcher or group it notifies for
+}
+
+// newSystemdNotifier returns a notifier for the service manager, or nil when
+// the process doesn't run under systemd with Type=notify
+func newSystemdNotifier() *systemdNotifier {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		// Abstract namespace
+		socket = "\x00" + socket[1:]
+	}
+	n := &systemdNotifier{addr: &net.UnixAddr{Name: socket, Net: "unixgram"}, logger: slog.Default()}
+
+	// The watchdog applies to the main process only
+	if pid := os.Getenv("WATCHDOG_PID"); pid == "" || pid == strconv.Itoa(os.Getpid()) {
+		if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 {
+			n.watchdog = time.Duration(usec) * time.Microsecond
+		}
+	}
+	return n
+}
+
+// notify sends state, e.g. "READY=1", to systemd. A nil notifier does nothing.
+func (n *systemdNotifier) notify(state string) {
+	if n == nil {
+		return
+	}
+	conn, err := net.DialUnix("unixgram", nil, n.addr)
+	if err != nil {
+		n.logger.Error("Error notifying systemd", "error", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		n.logger.Error("Error notifying systemd", "error", err)
+	}
+}
+
+// ready tells systemd the service started. The check interval must be well
+// below the watchdog timeout, or healthy services get restarted.
+func (n *systemdNotifier) ready(checkInterval time.Duration) {
+	if n == nil {
+		return
+	}
+	if n.watchdog > 0 && checkInterval >= n.watchdog/2 {
+		n.logger.Warn("Systemd watchdog fires before two checks; set WatchdogSec above twice the check interval", "check_interval", checkInterval, "watchdog", n.watchdog)
+	}
+	n.notify("READY=1")
+}
+
+// petWatchdog resets the systemd watchdog, if it is enabled
+func (n *systemdNotifier) petWatchdog() {
+	if n == nil || n.watchdog == 0 {
+		return
+	}
+	n.notify("WATCHDOG=1")
+}
+
+// petWatchdog resets the systemd watchdog after a check cycle while the
+// watcher is healthy. A watcher that stalls, or keeps failing, stops petting
+// it and systemd restarts the process.
+func (w *Watcher) petWatchdog() {
+	if w.systemd != nil && w.IsHealthy() {
+		w.systemd.petWatchdog()
+	}
+}
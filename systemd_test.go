@@ -0,0 +1,97 @@
+package vaultwatcher
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// listenSystemd listens on a notify socket and points NOTIFY_SOCKET at it
+func listenSystemd(t *testing.T) *net.UnixConn {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, too short for t.TempDir
+	dir, err := os.MkdirTemp("", "sd")
+	AssertNoError(t, err, "MkdirTemp()")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	AssertNoError(t, err, "ListenUnixgram()")
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", socket)
+	return conn
+}
+
+// receiveSystemd returns the next sd_notify message
+func receiveSystemd(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	AssertNoError(t, err, "reading the notify socket")
+	return string(buf[:n])
+}
+
+func TestNewSystemdNotifier(t *testing.T) {
+	tests := []struct {
+		name         string
+		socket       string
+		watchdogUSec string
+		watchdogPID  string
+		wantNil      bool
+		wantWatchdog time.Duration
+	}{
+		{name: "outside systemd", wantNil: true},
+		{name: "no watchdog", socket: "/run/systemd/notify"},
+		{name: "watchdog", socket: "/run/systemd/notify", watchdogUSec: "30000000", wantWatchdog: 30 * time.Second},
+		{name: "watchdog of another process", socket: "/run/systemd/notify", watchdogUSec: "30000000", watchdogPID: "1"},
+		{name: "bad watchdog", socket: "@notify", watchdogUSec: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOTIFY_SOCKET", tt.socket)
+			t.Setenv("WATCHDOG_USEC", tt.watchdogUSec)
+			t.Setenv("WATCHDOG_PID", tt.watchdogPID)
+
+			n := newSystemdNotifier()
+			AssertBoolEquals(t, n == nil, tt.wantNil, "nil notifier")
+			if n != nil {
+				AssertStringEquals(t, n.watchdog.String(), tt.wantWatchdog.String(), "watchdog")
+			}
+		})
+	}
+}
+
+func TestWatcher_SystemdNotify(t *testing.T) {
+	conn := listenSystemd(t)
+	t.Setenv("WATCHDOG_USEC", "1000000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error { return nil },
+		WithSystemdNotify(), WithFailureThreshold(1))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+
+	AssertStringEquals(t, receiveSystemd(t, conn), "READY=1", "message after Start")
+	AssertStringEquals(t, receiveSystemd(t, conn), "WATCHDOG=1", "message after a healthy check")
+
+	// An unhealthy watcher stops petting the watchdog
+	source.mu.Lock()
+	source.err = ErrSecretNotFound
+	source.mu.Unlock()
+	waitFor(t, time.Second, func() bool { return !watcher.IsHealthy() }, "unhealthy watcher")
+	for {
+		conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		if _, err := conn.Read(make([]byte, 256)); err != nil {
+			break
+		}
+	}
+
+	watcher.Stop()
+	AssertStringEquals(t, receiveSystemd(t, conn), "STOPPING=1", "message after Stop")
+}
@@ -0,0 +1,117 @@
+package vaultwatcher
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultTokenCheckInterval = 5 * time.Minute
+	defaultTokenWarning       = 24 * time.Hour
+)
+
+// TokenExpiringSoonEvent describes the watcher's Vault token getting within
+// the warning time of its expiry. Once it expires, every check fails with
+// permission denied.
+type TokenExpiringSoonEvent struct {
+	Path      string        `json:"path"`
+	TTL       time.Duration `json:"ttl"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Renewable bool          `json:"renewable"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// TokenExpiryNotifier is implemented by notifiers that want to know when the
+// watcher's token is about to expire. Notifiers registered with WithNotifier
+// are checked for it automatically.
+type TokenExpiryNotifier interface {
+	NotifyTokenExpiringSoon(ctx context.Context, event TokenExpiringSoonEvent) error
+}
+
+// startTokenMonitor looks the token up once and keeps doing so in the
+// background
+func (w *Watcher) startTokenMonitor() {
+	if w.tokenCheckInterval == 0 {
+		return
+	}
+
+	w.lookupToken()
+	w.wg.Add(1)
+	go w.monitorToken()
+}
+
+// monitorToken runs in a goroutine and looks the token up periodically
+func (w *Watcher) monitorToken() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.tokenCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.lookupToken()
+		}
+	}
+}
+
+// lookupToken records the remaining TTL of the token and emits an event when
+// it falls within the warning time. A token renewed or replaced with a longer
+// TTL is reported again the next time it runs low.
+func (w *Watcher) lookupToken() {
+	ctx, cancel := context.WithTimeout(w.ctx, w.tokenCheckInterval)
+	defer cancel()
+
+	secret, err := w.client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		w.logger.Error("Error looking up vault token", "error", w.redactError(err))
+		return
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		w.logger.Error("Error looking up vault token", "error", w.redactError(err))
+		return
+	}
+	renewable := false
+	if secret != nil {
+		renewable, _ = secret.Data["renewable"].(bool)
+	}
+
+	now := time.Now()
+	var expiresAt time.Time
+	if ttl > 0 {
+		// Root and other tokens without a TTL never expire
+		expiresAt = now.Add(ttl)
+	}
+	expiring := ttl > 0 && ttl <= w.tokenWarning
+
+	w.mu.Lock()
+	w.tokenExpiresAt = expiresAt
+	notify := expiring && !w.tokenExpiring
+	w.tokenExpiring = expiring
+	w.mu.Unlock()
+
+	if !notify {
+		return
+	}
+
+	w.logger.Warn("Vault token of the watcher expires soon", "path", w.vaultConfig.Path, "ttl", ttl.Round(time.Second))
+	event := TokenExpiringSoonEvent{
+		Path:      w.vaultConfig.Path,
+		TTL:       ttl,
+		ExpiresAt: expiresAt.UTC(),
+		Renewable: renewable,
+		Timestamp: now.UTC(),
+	}
+	for _, notifier := range w.notifiers {
+		tokenNotifier, ok := notifier.(TokenExpiryNotifier)
+		if !ok {
+			continue
+		}
+		if err := tokenNotifier.NotifyTokenExpiringSoon(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying vault token expiry", "error", w.redactError(err))
+		}
+	}
+}
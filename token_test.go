@@ -0,0 +1,88 @@
+package vaultwatcher
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// tokenRecorder collects token expiry events
+type tokenRecorder struct {
+	mu     sync.Mutex
+	events []TokenExpiringSoonEvent
+}
+
+func (r *tokenRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *tokenRecorder) NotifyTokenExpiringSoon(ctx context.Context, event TokenExpiringSoonEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *tokenRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+func TestWatcher_TokenMonitoring(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+	vault.SetTokenTTL(72 * time.Hour)
+
+	recorder := &tokenRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil }, WithTokenMonitoring(time.Hour, 24*time.Hour), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		wantEvents int
+		wantTTL    time.Duration // Zero for a token without expiry
+	}{
+		{name: "far from expiry", ttl: 72 * time.Hour, wantTTL: 72 * time.Hour},
+		{name: "within the warning", ttl: 2 * time.Hour, wantEvents: 1, wantTTL: 2 * time.Hour},
+		{name: "reported once", ttl: time.Hour, wantEvents: 1, wantTTL: time.Hour},
+		{name: "renewed", ttl: 48 * time.Hour, wantEvents: 1, wantTTL: 48 * time.Hour},
+		{name: "low again", ttl: 30 * time.Minute, wantEvents: 2, wantTTL: 30 * time.Minute},
+		{name: "never expires", ttl: 0, wantEvents: 2},
+	}
+
+	for _, tt := range tests {
+		vault.SetTokenTTL(tt.ttl)
+		watcher.lookupToken()
+
+		if got := recorder.count(); got != tt.wantEvents {
+			t.Errorf("%s: %d token events, want %d", tt.name, got, tt.wantEvents)
+		}
+		status := watcher.Status()
+		if status.TokenTTL > tt.wantTTL || status.TokenTTL < tt.wantTTL-time.Minute {
+			t.Errorf("%s: Status().TokenTTL = %v, want %v", tt.name, status.TokenTTL, tt.wantTTL)
+		}
+		AssertBoolEquals(t, status.TokenExpiresAt.IsZero(), tt.wantTTL == 0, tt.name+": TokenExpiresAt is zero")
+	}
+
+	recorder.mu.Lock()
+	event := recorder.events[0]
+	recorder.mu.Unlock()
+	AssertStringEquals(t, event.Path, "secret/data/app", "Path")
+	AssertBoolEquals(t, event.Renewable, true, "Renewable")
+
+	vault.SetTokenTTL(2 * time.Hour)
+	watcher.lookupToken()
+	var b strings.Builder
+	AssertNoError(t, WritePrometheus(&b, watcher), "WritePrometheus()")
+	if !strings.Contains(b.String(), `vaultwatcher_token_ttl_seconds{path="secret/data/app"} 7`) {
+		t.Errorf("metrics don't contain the token TTL:\n%s", b.String())
+	}
+}
@@ -0,0 +1,119 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransitKeyRotation describes a rotation of a transit encryption key
+type TransitKeyRotation struct {
+	Path            string
+	PreviousVersion int64
+	LatestVersion   int64
+}
+
+// TransitKeyWatcher fires a callback when a transit key is rotated, so services
+// can re-wrap data keys and refresh envelope-encryption caches. Only the key's
+// latest_version is compared; other metadata changes are ignored.
+type TransitKeyWatcher struct {
+	*Watcher
+	onRotate func(TransitKeyRotation) error
+
+	mu             sync.Mutex
+	currentVersion int64
+	fetchedVersion int64
+}
+
+// NewTransitKeyWatcher creates a watcher for a transit key
+// vaultConfig: Vault connection configuration, Path is the key metadata path, e.g. "transit/keys/orders"
+// checkInterval: How often to check for rotation
+// onRotate: Callback invoked with the previous and latest key version after each rotation
+func NewTransitKeyWatcher(vaultConfig *VaultConfig, checkInterval time.Duration, onRotate func(TransitKeyRotation) error, opts ...Option) (*TransitKeyWatcher, error) {
+	if onRotate == nil {
+		return nil, fmt.Errorf("onRotate callback cannot be nil")
+	}
+	if vaultConfig != nil && !strings.Contains(vaultConfig.Path, "/keys/") {
+		return nil, fmt.Errorf("transit key path must look like <mount>/keys/<name>, got %q", vaultConfig.Path)
+	}
+
+	tw := &TransitKeyWatcher{onRotate: onRotate}
+
+	watcher, err := NewWatcher(vaultConfig, checkInterval, tw.handleChange, opts...)
+	if err != nil {
+		return nil, err
+	}
+	watcher.selectData = tw.selectLatestVersion
+	tw.Watcher = watcher
+
+	return tw, nil
+}
+
+// LatestVersion returns the most recently observed latest_version of the key
+func (tw *TransitKeyWatcher) LatestVersion() int64 {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.currentVersion
+}
+
+// selectLatestVersion reduces the key metadata to its latest_version
+func (tw *TransitKeyWatcher) selectLatestVersion(data map[string]interface{}) (map[string]interface{}, error) {
+	version, err := toInt64(data["latest_version"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid transit key metadata: latest_version: %w", err)
+	}
+
+	tw.mu.Lock()
+	tw.fetchedVersion = version
+	if tw.currentVersion == 0 {
+		// First read when the watcher starts
+		tw.currentVersion = version
+	}
+	tw.mu.Unlock()
+
+	return map[string]interface{}{"latest_version": version}, nil
+}
+
+// handleChange is the Watcher callback, called when latest_version changed
+func (tw *TransitKeyWatcher) handleChange() error {
+	tw.mu.Lock()
+	rotation := TransitKeyRotation{
+		Path:            tw.vaultConfig.Path,
+		PreviousVersion: tw.currentVersion,
+		LatestVersion:   tw.fetchedVersion,
+	}
+	tw.mu.Unlock()
+
+	if err := tw.onRotate(rotation); err != nil {
+		return err
+	}
+
+	tw.mu.Lock()
+	tw.currentVersion = rotation.LatestVersion
+	tw.mu.Unlock()
+
+	return nil
+}
+
+// toInt64 converts a numeric value decoded from a Vault response to int64
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Int64()
+	case float64:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case nil:
+		return 0, fmt.Errorf("value is missing")
+	default:
+		return 0, fmt.Errorf("unexpected type %T", value)
+	}
+}
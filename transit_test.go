@@ -0,0 +1,131 @@
+package vaultwatcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransitKey serves transit key metadata with a settable latest_version
+type fakeTransitKey struct {
+	mu      sync.Mutex
+	version int
+	minDec  int
+}
+
+func (f *fakeTransitKey) set(version, minDecryption int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version, f.minDec = version, minDecryption
+}
+
+func (f *fakeTransitKey) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path != "/v1/transit/keys/orders" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"name":                   "orders",
+			"type":                   "aes256-gcm96",
+			"latest_version":         f.version,
+			"min_decryption_version": f.minDec,
+		},
+	})
+}
+
+func TestNewTransitKeyWatcher(t *testing.T) {
+	config := &VaultConfig{Host: "https://vault.example.com", Path: "transit/orders", Token: "test-token"}
+	_, err := NewTransitKeyWatcher(config, time.Second, func(TransitKeyRotation) error { return nil })
+	AssertError(t, err, `transit key path must look like <mount>/keys/<name>, got "transit/orders"`, "NewTransitKeyWatcher(bad path)")
+
+	config.Path = "transit/keys/orders"
+	_, err = NewTransitKeyWatcher(config, time.Second, nil)
+	AssertError(t, err, "onRotate callback cannot be nil", "NewTransitKeyWatcher(nil callback)")
+
+	_, err = NewTransitKeyWatcher(nil, time.Second, func(TransitKeyRotation) error { return nil })
+	AssertError(t, err, "vault config cannot be nil", "NewTransitKeyWatcher(nil config)")
+}
+
+func TestTransitKeyWatcher_DetectsRotation(t *testing.T) {
+	key := &fakeTransitKey{version: 1, minDec: 1}
+	server := httptest.NewServer(key)
+	defer server.Close()
+
+	var rotations []TransitKeyRotation
+	watcher, err := NewTransitKeyWatcher(
+		&VaultConfig{Host: server.URL, Path: "transit/keys/orders", Token: "test-token"},
+		time.Hour,
+		func(rotation TransitKeyRotation) error {
+			rotations = append(rotations, rotation)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewTransitKeyWatcher() error = %v", err)
+	}
+	if err := watcher.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	if watcher.LatestVersion() != 1 {
+		t.Errorf("LatestVersion() = %d, want 1", watcher.LatestVersion())
+	}
+
+	// Metadata changes other than the version are ignored
+	key.set(1, 0)
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(rotations) != 0 {
+		t.Fatalf("got %d rotations for a metadata-only change, want 0", len(rotations))
+	}
+
+	key.set(2, 0)
+	AssertNoError(t, watcher.checkForChanges(), "checkForChanges()")
+	if len(rotations) != 1 {
+		t.Fatalf("got %d rotations, want 1", len(rotations))
+	}
+	if rotations[0].PreviousVersion != 1 || rotations[0].LatestVersion != 2 {
+		t.Errorf("rotation = %+v, want 1 -> 2", rotations[0])
+	}
+	if watcher.LatestVersion() != 2 {
+		t.Errorf("LatestVersion() = %d, want 2", watcher.LatestVersion())
+	}
+}
+
+func TestToInt64(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    int64
+		wantErr bool
+	}{
+		{name: "json number", value: json.Number("42"), want: 42},
+		{name: "float", value: float64(7), want: 7},
+		{name: "int", value: 3, want: 3},
+		{name: "string", value: "12", want: 12},
+		{name: "missing", value: nil, wantErr: true},
+		{name: "wrong type", value: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toInt64(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("toInt64() expected error but got none")
+				}
+				return
+			}
+			AssertNoError(t, err, "toInt64()")
+			if got != tt.want {
+				t.Errorf("toInt64() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+)
+
+// ChangeParticipant is a subsystem that applies a change in two phases, so a
+// change can be staged across several subsystems and rolled back in all of
+// them when one can't take it. Prepare stages the change without making it
+// visible, Commit makes it visible and Rollback discards what Prepare staged.
+type ChangeParticipant interface {
+	Prepare(ctx context.Context, change StagedChange) error
+	Commit(ctx context.Context, change StagedChange) error
+	Rollback(ctx context.Context, change StagedChange) error
+}
+
+// StagedChange is a change being applied by participants. It holds the new
+// secret only while the change is applied.
+type StagedChange struct {
+	Event ChangeEvent
+	data  map[string]interface{}
+}
+
+// DecodeInto decodes the new secret into out, see DecodeInto
+func (c StagedChange) DecodeInto(out interface{}) error {
+	return DecodeInto(c.data, out)
+}
+
+// applyChange applies data to the participants, bound structs, file templates
+// and onChange. Every participant prepares the change first; if one fails, or
+// binding, rendering or onChange fails afterwards, those already prepared
+// roll it back in reverse order, and bound structs and files get their
+// previous values back. Otherwise all of them commit.
+func (w *Watcher) applyChange(event ChangeEvent, data map[string]interface{}) error {
+	change := StagedChange{Event: event, data: data}
+
+	for i, participant := range w.participants {
+		if err := participant.Prepare(w.ctx, change); err != nil {
+			w.rollbackChange(change, i)
+			return fmt.Errorf("participant %d failed to prepare the change: %w", i+1, err)
+		}
+	}
+
+	bound := w.bindingValues()
+	rendered := w.readTemplates()
+	revert := func() {
+		w.restoreBindings(bound)
+		w.restoreTemplates(rendered)
+		w.rollbackChange(change, len(w.participants))
+	}
+	if err := w.applyBindings(data); err != nil {
+		w.rollbackChange(change, len(w.participants))
+		return err
+	}
+	if err := w.renderTemplates(data); err != nil {
+		// Renaming can fail part way, leaving some files replaced
+		revert()
+		return err
+	}
+	if err := w.handler.HandleChange(w.ctx, event); err != nil {
+		revert()
+		return err
+	}
+
+	for i, participant := range w.participants {
+		// Others may have committed already, so a failure can only be logged
+		if err := participant.Commit(w.ctx, change); err != nil {
+			w.logger.Error("Error committing vault change", "participant", i+1, "error", w.redactError(err))
+		}
+	}
+	return nil
+}
+
+// rollbackChange rolls the change back in the first prepared participants,
+// last first
+func (w *Watcher) rollbackChange(change StagedChange, prepared int) {
+	for i := prepared - 1; i >= 0; i-- {
+		if err := w.participants[i].Rollback(w.ctx, change); err != nil {
+			w.logger.Error("Error rolling back vault change", "participant", i+1, "error", w.redactError(err))
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingParticipant logs the phases it runs and can fail to prepare
+type recordingParticipant struct {
+	name        string
+	log         *[]string
+	failPrepare bool
+}
+
+func (p *recordingParticipant) Prepare(ctx context.Context, change StagedChange) error {
+	var config decodeDatabase
+	if err := change.DecodeInto(&config); err != nil {
+		return err
+	}
+	*p.log = append(*p.log, p.name+" prepare "+config.Host)
+	if p.failPrepare {
+		return errors.New("not ready")
+	}
+	return nil
+}
+
+func (p *recordingParticipant) Commit(ctx context.Context, change StagedChange) error {
+	*p.log = append(*p.log, p.name+" commit")
+	return nil
+}
+
+func (p *recordingParticipant) Rollback(ctx context.Context, change StagedChange) error {
+	*p.log = append(*p.log, p.name+" rollback")
+	return nil
+}
+
+func TestWatcher_Participants(t *testing.T) {
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+
+	var log []string
+	var onChangeErr error
+	cache := &recordingParticipant{name: "cache", log: &log}
+	pool := &recordingParticipant{name: "pool", log: &log}
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error {
+		log = append(log, "onChange")
+		return onChangeErr
+	}, WithParticipants(cache, pool))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	tests := []struct {
+		name        string
+		failPrepare bool
+		onChangeErr error
+		wantErr     string
+		wantLog     string
+	}{
+		{name: "prepare fails", failPrepare: true,
+			wantErr: "participant 2 failed to prepare the change: not ready",
+			wantLog: "cache prepare db-2, pool prepare db-2, cache rollback"},
+		{name: "onChange fails", onChangeErr: errors.New("reload failed"), wantErr: "reload failed",
+			wantLog: "cache prepare db-2, pool prepare db-2, onChange, pool rollback, cache rollback"},
+		{name: "committed", wantLog: "cache prepare db-2, pool prepare db-2, onChange, cache commit, pool commit"},
+	}
+
+	source.set(map[string]interface{}{"host": "db-2"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log = nil
+			pool.failPrepare, onChangeErr = tt.failPrepare, tt.onChangeErr
+			err := watcher.check()
+			if tt.wantErr != "" {
+				AssertError(t, err, "onChange callback failed: "+tt.wantErr, "check()")
+			} else {
+				AssertNoError(t, err, "check()")
+			}
+			AssertStringEquals(t, strings.Join(log, ", "), tt.wantLog, "phases")
+		})
+	}
+}
+
+func TestWatcher_ApplyChange_Reverts(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "db.env")
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"host": "db-1"})
+
+	onChangeErr := errors.New("reload failed")
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return onChangeErr },
+		WithFileTemplates(FileTemplate{Destination: destination, Template: "DB_HOST={{ .host }}\n"}))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	var config decodeDatabase
+	binding, err := watcher.Bind(&config)
+	AssertNoError(t, err, "Bind()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	// A failing onChange leaves the bound struct and the file as they were
+	source.set(map[string]interface{}{"host": "db-2"})
+	AssertError(t, watcher.check(), "onChange callback failed: reload failed", "check()")
+	AssertStringEquals(t, binding.Load().(*decodeDatabase).Host+" "+config.Host, "db-1 db-1", "bound host after the failed change")
+	content, err := os.ReadFile(destination)
+	AssertNoError(t, err, "ReadFile()")
+	AssertStringEquals(t, string(content), "DB_HOST=db-1\n", "file after the failed change")
+
+	onChangeErr = nil
+	AssertNoError(t, watcher.check(), "check() once onChange succeeds")
+	AssertStringEquals(t, binding.Load().(*decodeDatabase).Host, "db-2", "bound host after the change")
+}
@@ -0,0 +1,115 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const defaultUnsealWait = time.Minute
+
+// UnsealWaitEvent describes a watcher starting or stopping to wait for Vault
+// to be unsealed. It is only emitted by watchers created with WithUnsealWait.
+type UnsealWaitEvent struct {
+	Path      string        `json:"path"`
+	Waiting   bool          `json:"waiting"`
+	Reason    string        `json:"reason,omitempty"`
+	WaitedFor time.Duration `json:"waited_for,omitempty"` // Set when the wait is over
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// UnsealWaitNotifier is implemented by notifiers that want to know when the
+// watcher starts and stops waiting for unseal. Notifiers registered with
+// WithNotifier are checked for it automatically.
+type UnsealWaitNotifier interface {
+	NotifyUnsealWait(ctx context.Context, event UnsealWaitEvent) error
+}
+
+// IsWaitingForUnseal returns true while a read found Vault sealed or
+// uninitialized and sys/health hasn't reported it unsealed since
+func (w *Watcher) IsWaitingForUnseal() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return !w.unsealWaitStart.IsZero()
+}
+
+// startUnsealWait switches the watcher to waiting for unseal after a read
+// failed with ErrVaultSealed
+func (w *Watcher) startUnsealWait(readErr error) {
+	now := time.Now()
+	w.mu.Lock()
+	if !w.unsealWaitStart.IsZero() {
+		w.mu.Unlock()
+		return
+	}
+	w.unsealWaitStart, w.lastUnsealPoll = now, now
+	w.mu.Unlock()
+
+	w.logger.Warn("Vault is sealed, checking until it is unsealed", "interval", w.unsealWait, "error", readErr)
+	w.notifyUnsealWait(UnsealWaitEvent{
+		Path:      w.vaultConfig.Path,
+		Waiting:   true,
+		Reason:    readErr.Error(),
+		Timestamp: now.UTC(),
+	})
+}
+
+// pollUnseal checks sys/health at most once per unseal wait interval and
+// reports whether Vault is unsealed again. An error means the health endpoint
+// itself couldn't be reached.
+func (w *Watcher) pollUnseal() (bool, error) {
+	w.mu.Lock()
+	if time.Since(w.lastUnsealPoll) < w.unsealWait {
+		// Group members are checked at the group's interval
+		w.mu.Unlock()
+		return false, nil
+	}
+	w.lastUnsealPoll = time.Now()
+	w.mu.Unlock()
+
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	health, err := w.client.Sys().HealthWithContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to read vault health: %w", err)
+	}
+	if !health.Initialized || health.Sealed {
+		return false, nil
+	}
+
+	w.mu.Lock()
+	started := w.unsealWaitStart
+	w.unsealWaitStart = time.Time{}
+	w.mu.Unlock()
+
+	w.logger.Info("Vault is unsealed, resuming checks", "interval", w.checkInterval)
+	w.notifyUnsealWait(UnsealWaitEvent{
+		Path:      w.vaultConfig.Path,
+		Waiting:   false,
+		WaitedFor: time.Since(started),
+		Timestamp: time.Now().UTC(),
+	})
+	return true, nil
+}
+
+// nextCheck returns how long monitor waits before the next check
+func (w *Watcher) nextCheck() time.Duration {
+	if w.IsWaitingForUnseal() {
+		return w.unsealWait
+	}
+	return w.checkInterval
+}
+
+// notifyUnsealWait delivers the event to every notifier implementing UnsealWaitNotifier
+func (w *Watcher) notifyUnsealWait(event UnsealWaitEvent) {
+	for _, notifier := range w.notifiers {
+		unsealNotifier, ok := notifier.(UnsealWaitNotifier)
+		if !ok {
+			continue
+		}
+		if err := unsealNotifier.NotifyUnsealWait(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying unseal wait", "error", w.redactError(err))
+		}
+	}
+}
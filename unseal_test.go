@@ -0,0 +1,121 @@
+package vaultwatcher
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// unsealWaitRecorder collects unseal wait events
+type unsealWaitRecorder struct {
+	mu     sync.Mutex
+	events []UnsealWaitEvent
+}
+
+func (r *unsealWaitRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *unsealWaitRecorder) NotifyUnsealWait(ctx context.Context, event UnsealWaitEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *unsealWaitRecorder) recorded() []UnsealWaitEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]UnsealWaitEvent(nil), r.events...)
+}
+
+func TestWithUnsealWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{name: "default", want: defaultUnsealWait},
+		{name: "custom", interval: 5 * time.Minute, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := TestWatcher(t, func() error { return nil })
+			WithUnsealWait(tt.interval)(watcher)
+			if watcher.unsealWait != tt.want {
+				t.Errorf("unsealWait = %s, want %s", watcher.unsealWait, tt.want)
+			}
+			if next := watcher.nextCheck(); next != watcher.checkInterval {
+				t.Errorf("nextCheck() = %s before waiting, want the check interval %s", next, watcher.checkInterval)
+			}
+		})
+	}
+}
+
+func TestWatcher_UnsealWait(t *testing.T) {
+	vault := &fakeSealableVault{}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	recorder := &unsealWaitRecorder{}
+	health := &availabilityRecorder{}
+	watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "kv/data/test", Token: "test-token"},
+		10*time.Millisecond, func() error { return nil },
+		WithUnsealWait(50*time.Millisecond), WithNotifier(recorder), WithNotifier(health), WithFailureThreshold(1))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.setSealed(true)
+	waitFor(t, time.Second, watcher.IsWaitingForUnseal, "waiting for unseal")
+	if next := watcher.nextCheck(); next != 50*time.Millisecond {
+		t.Errorf("nextCheck() = %s while waiting, want 50ms", next)
+	}
+
+	sealedReads := vault.secretReads()
+	time.Sleep(150 * time.Millisecond)
+	if reads := vault.secretReads(); reads != sealedReads {
+		t.Errorf("secret read %d times while waiting for unseal, want 0", reads-sealedReads)
+	}
+	if _, events := health.counts(); events != 0 {
+		t.Errorf("got %d health events while sealed, want 0", events)
+	}
+	AssertBoolEquals(t, watcher.IsHealthy(), true, "IsHealthy() while sealed")
+
+	vault.setSealed(false)
+	waitFor(t, time.Second, func() bool { return vault.secretReads() > sealedReads }, "reads resumed")
+	AssertBoolEquals(t, watcher.IsWaitingForUnseal(), false, "IsWaitingForUnseal() after unseal")
+
+	events := recorder.recorded()
+	if len(events) != 2 {
+		t.Fatalf("got %d unseal wait events, want 2", len(events))
+	}
+	AssertBoolEquals(t, events[0].Waiting, true, "first event waiting")
+	AssertStringEquals(t, events[0].Path, "kv/data/test", "path")
+	if events[0].Reason == "" {
+		t.Error("first event has no reason")
+	}
+	AssertBoolEquals(t, events[1].Waiting, false, "second event waiting")
+	if events[1].WaitedFor < 150*time.Millisecond {
+		t.Errorf("WaitedFor = %s, want at least 150ms", events[1].WaitedFor)
+	}
+}
+
+func TestWatcher_SealedWithoutUnsealWait(t *testing.T) {
+	vault := &fakeSealableVault{}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	watcher, err := NewWatcher(&VaultConfig{Host: server.URL, Path: "kv/data/test", Token: "test-token"},
+		time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.setSealed(true)
+	if err := watcher.check(); err == nil {
+		t.Error("check() of a sealed vault returned no error")
+	}
+	AssertBoolEquals(t, watcher.IsWaitingForUnseal(), false, "IsWaitingForUnseal()")
+}
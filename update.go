@@ -0,0 +1,59 @@
+package vaultwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// UpdateOptions controls how UpdateSecret writes the watched secret
+type UpdateOptions struct {
+	// Version is the check-and-set version; 0 uses the version the watcher last applied
+	Version int
+	// Force writes without check-and-set, overwriting any newer version
+	Force bool
+}
+
+// UpdateSecret writes data to the watched KV v2 secret through the watcher's
+// client. Unless opts.Force is set, the write uses check-and-set against the
+// version the watcher last applied, so it fails instead of overwriting a
+// version the watcher has not seen. It returns the version written. The
+// watcher picks up the write on its next check like any other change.
+func (w *Watcher) UpdateSecret(ctx context.Context, data map[string]interface{}, opts UpdateOptions) (int, error) {
+	if w.source != nil {
+		return 0, errCustomSource
+	}
+	if _, err := kvMetadataPath(w.vaultConfig.Path); err != nil {
+		return 0, err
+	}
+
+	body := map[string]interface{}{"data": data}
+	if !opts.Force {
+		version := opts.Version
+		if version == 0 {
+			version = w.CurrentVersion()
+		}
+		if version == 0 {
+			return 0, fmt.Errorf("no observed version of %s to check against", w.vaultConfig.Path)
+		}
+		body["options"] = map[string]interface{}{"cas": version}
+	}
+
+	ctx, cancel := w.withRequestTimeout(ctx)
+	defer cancel()
+
+	secret, err := w.client.Logical().WriteWithContext(ctx, w.vaultConfig.Path, body)
+	if err != nil {
+		err = classifyVaultError(err)
+		if errors.Is(err, ErrVersionConflict) {
+			return 0, fmt.Errorf("secret %s changed since the observed version: %w", w.vaultConfig.Path, err)
+		}
+		return 0, fmt.Errorf("failed to write secret to vault: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return 0, nil
+	}
+	version, _ := strconv.Atoi(fmt.Sprint(secret.Data["version"]))
+	return version, nil
+}
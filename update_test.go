@@ -0,0 +1,70 @@
+package vaultwatcher
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+func TestWatcher_UpdateSecret(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"password": "one"})
+
+	changes := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			changes++
+			return nil
+		})
+	AssertNoError(t, err, "NewWatcher()")
+
+	_, err = watcher.UpdateSecret(context.Background(), map[string]interface{}{"password": "two"}, UpdateOptions{})
+	AssertError(t, err, "no observed version of secret/data/app to check against", "UpdateSecret() before Start()")
+
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	version, err := watcher.UpdateSecret(context.Background(), map[string]interface{}{"password": "two"}, UpdateOptions{})
+	AssertNoError(t, err, "UpdateSecret()")
+	if version != 2 {
+		t.Errorf("version = %d, want 2", version)
+	}
+
+	// The write is picked up like any other change
+	AssertNoError(t, watcher.check(), "check after update")
+	if changes != 1 || watcher.CurrentVersion() != 2 {
+		t.Errorf("changes = %d, CurrentVersion() = %d, want 1 and 2", changes, watcher.CurrentVersion())
+	}
+
+	// Someone else writes before the watcher sees it
+	vault.Put("secret/app", map[string]interface{}{"password": "three"})
+	_, err = watcher.UpdateSecret(context.Background(), map[string]interface{}{"password": "four"}, UpdateOptions{})
+	if err == nil || !strings.Contains(err.Error(), "secret secret/data/app changed since the observed version") {
+		t.Errorf("UpdateSecret() error = %v, want a check-and-set conflict", err)
+	}
+
+	version, err = watcher.UpdateSecret(context.Background(), map[string]interface{}{"password": "four"}, UpdateOptions{Version: 3})
+	AssertNoError(t, err, "UpdateSecret() with explicit version")
+	if version != 4 {
+		t.Errorf("version = %d, want 4", version)
+	}
+
+	version, err = watcher.UpdateSecret(context.Background(), map[string]interface{}{"password": "five"}, UpdateOptions{Force: true})
+	AssertNoError(t, err, "UpdateSecret() with Force")
+	if version != 5 {
+		t.Errorf("version = %d, want 5", version)
+	}
+}
+
+func TestWatcher_UpdateSecretKVv1(t *testing.T) {
+	watcher, err := NewWatcher(&VaultConfig{Host: "https://vault.example.com", Path: "kv/app", Token: "t"}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+
+	_, err = watcher.UpdateSecret(context.Background(), map[string]interface{}{"password": "two"}, UpdateOptions{})
+	AssertError(t, err, `path must be a KV v2 path like <mount>/data/<key>, got "kv/app"`, "UpdateSecret() on a KV v1 path")
+}
@@ -0,0 +1,117 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a value path: a key or a list index
+type pathSegment struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// parseValuePath parses a dotted path such as "database.credentials.password"
+// or its JSONPath form "$.database.credentials.password". Keys containing
+// dots are quoted in brackets, "$['tls.crt']", and list items are selected
+// by index, "hosts[0].name".
+func parseValuePath(path string) ([]pathSegment, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid value path %q: %s", path, reason)
+	}
+
+	rest := path
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else if rest != "" && rest[0] != '[' {
+		// A dotted path starts with a key
+		rest = "." + rest
+	}
+
+	var segments []pathSegment
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, invalid("empty key")
+			}
+			segments = append(segments, pathSegment{name: name})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, invalid("unclosed [")
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') {
+				if inner[len(inner)-1] != inner[0] {
+					return nil, invalid("unterminated quote")
+				}
+				segments = append(segments, pathSegment{name: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, invalid(fmt.Sprintf("bad index [%s]", inner))
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, invalid(fmt.Sprintf("unexpected %q", rest[0]))
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, invalid("no keys")
+	}
+	if segments[0].isIndex {
+		return nil, invalid("the secret is a map, so the path must start with a key")
+	}
+	return segments, nil
+}
+
+// selectPath returns the value at segments in data
+func selectPath(data map[string]interface{}, segments []pathSegment) (interface{}, bool) {
+	var value interface{} = data
+	for _, segment := range segments {
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			if segment.isIndex {
+				return nil, false
+			}
+			var ok bool
+			if value, ok = typed[segment.name]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			if !segment.isIndex || segment.index >= len(typed) {
+				return nil, false
+			}
+			value = typed[segment.index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// pathPointer returns segments as a JSON Pointer, the form Patch paths use
+func pathPointer(segments []pathSegment) string {
+	var pointer strings.Builder
+	for _, segment := range segments {
+		pointer.WriteByte('/')
+		if segment.isIndex {
+			pointer.WriteString(strconv.Itoa(segment.index))
+		} else {
+			pointer.WriteString(escapePointer(segment.name))
+		}
+	}
+	return pointer.String()
+}
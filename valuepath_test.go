@@ -0,0 +1,124 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseValuePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantPointer string
+		wantErr     string
+	}{
+		{path: "database.credentials.password", wantPointer: "/database/credentials/password"},
+		{path: "$.database.credentials.password", wantPointer: "/database/credentials/password"},
+		{path: "$['tls.crt']", wantPointer: "/tls.crt"},
+		{path: `$["a/b"].c`, wantPointer: "/a~1b/c"},
+		{path: "hosts[0].name", wantPointer: "/hosts/0/name"},
+		{path: "['matrix'][1][2]", wantPointer: "/matrix/1/2"},
+		{path: "", wantErr: `invalid value path "": no keys`},
+		{path: "$", wantErr: `invalid value path "$": no keys`},
+		{path: "a..b", wantErr: `invalid value path "a..b": empty key`},
+		{path: "hosts[0", wantErr: `invalid value path "hosts[0": unclosed [`},
+		{path: "hosts[-1]", wantErr: `invalid value path "hosts[-1]": bad index [-1]`},
+		{path: "$['open]", wantErr: `invalid value path "$['open]": unterminated quote`},
+		{path: "[0].name", wantErr: `invalid value path "[0].name": the secret is a map, so the path must start with a key`},
+		{path: "$database", wantErr: `invalid value path "$database": unexpected 'd'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segments, err := parseValuePath(tt.path)
+			if tt.wantErr != "" {
+				AssertError(t, err, tt.wantErr, "parseValuePath()")
+				return
+			}
+			AssertNoError(t, err, "parseValuePath()")
+			AssertStringEquals(t, pathPointer(segments), tt.wantPointer, "pathPointer()")
+		})
+	}
+}
+
+func TestSelectPath(t *testing.T) {
+	data := map[string]interface{}{
+		"database": map[string]interface{}{
+			"credentials": map[string]interface{}{"password": "s3cret"},
+		},
+		"hosts": []interface{}{map[string]interface{}{"name": "db-1"}},
+	}
+
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{path: "database.credentials.password", want: "s3cret", wantOK: true},
+		{path: "hosts[0].name", want: "db-1", wantOK: true},
+		{path: "database.credentials", want: "map[password:s3cret]", wantOK: true},
+		{path: "hosts[1].name"},
+		{path: "database[0]"},
+		{path: "database.credentials.password.length"},
+		{path: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segments, err := parseValuePath(tt.path)
+			AssertNoError(t, err, "parseValuePath()")
+			value, ok := selectPath(data, segments)
+			AssertBoolEquals(t, ok, tt.wantOK, "selectPath() ok")
+			if ok {
+				AssertStringEquals(t, fmt.Sprint(value), tt.want, "selectPath()")
+			}
+		})
+	}
+}
+
+func TestWatcher_ValuePathListener(t *testing.T) {
+	source := &fakeSource{}
+	database := func(user, password string) map[string]interface{} {
+		return map[string]interface{}{
+			"database": map[string]interface{}{
+				"credentials": map[string]interface{}{"user": user, "password": password},
+			},
+		}
+	}
+	source.set(database("app", "one"))
+
+	watcher, err := NewSourceWatcher("app", source, 10*time.Millisecond, func() error { return nil }, WithJSONPatch())
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	password := make(chan ChangeEvent, 4)
+	all := make(chan ChangeEvent, 4)
+	_, err = watcher.AddListener(listenerTo(password), ValuePath("$.database.credentials.password"))
+	AssertNoError(t, err, "AddListener(password)")
+	_, err = watcher.AddListener(listenerTo(all))
+	AssertNoError(t, err, "AddListener(all)")
+	_, err = watcher.AddListener(listenerTo(all), ValuePath("database..password"))
+	AssertError(t, err, `invalid value path "database..password": empty key`, "AddListener(bad path)")
+
+	// The first change after adding the listener only knows the top-level key changed
+	source.set(database("admin", "one"))
+	receiveEvent(t, all, "first change")
+	receiveEvent(t, password, "first change, before the value is tracked")
+
+	// Now the selected value is tracked
+	source.set(database("app", "one"))
+	receiveEvent(t, all, "user change")
+	select {
+	case event := <-password:
+		t.Fatalf("password listener notified of a user change: %v", event.Patch)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	source.set(database("app", "two"))
+	event := receiveEvent(t, password, "password change")
+	AssertStringEquals(t, fmt.Sprint(event.ChangedKeys), "[database]", "ChangedKeys")
+	if len(event.Patch) != 1 || event.Patch[0].Path != "/database/credentials/password" {
+		t.Errorf("Patch = %v, want only the password", event.Patch)
+	}
+}
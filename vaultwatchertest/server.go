@@ -0,0 +1,598 @@
+// Package vaultwatchertest provides an in-memory fake Vault for tests. It
+// serves KV v1 and v2 reads, writes (with KV v2 check-and-set), deletes and
+// LIST over HTTP, and a cubbyhole per token, so watchers can be exercised end
+// to end without Docker or a Vault binary.
+package vaultwatchertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultToken is the token accepted by a Server unless WithToken is given
+const DefaultToken = "test-token"
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithToken sets the token the server accepts
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.Token = token
+	}
+}
+
+// WithMount adds a KV mount, e.g. WithMount("kv", 1). Servers always have the
+// "secret" mount with KV v2, like a Vault dev server.
+func WithMount(path string, version int) Option {
+	return func(s *Server) {
+		s.mounts[strings.Trim(path, "/")] = version
+	}
+}
+
+// WithNamespace adds a Vault Enterprise namespace with its own KV v2 "secret"
+// mount, e.g. WithNamespace("team-a", "team-a-token"). Requests select it with
+// the X-Vault-Namespace header or a path prefix, and its secrets are written
+// with the prefix, e.g. Put("team-a/secret/app", data). A non-empty token is
+// only accepted in the namespace; the server's token is accepted everywhere.
+func WithNamespace(namespace, token string) Option {
+	return func(s *Server) {
+		namespace = strings.Trim(namespace, "/")
+		s.mounts[namespace+"/secret"] = 2
+		if token != "" {
+			s.namespaceTokens[token] = namespace
+		}
+	}
+}
+
+// Change is a scripted update of a secret. It is applied once the secret
+// has been read AfterReads times; nil Data deletes the secret.
+type Change struct {
+	AfterReads int
+	Data       map[string]interface{}
+}
+
+// secret holds every version of a secret; KV v1 secrets only have one
+type secret struct {
+	versions       []secretVersion
+	customMetadata map[string]string
+	reads          int
+	script         []Change
+}
+
+type secretVersion struct {
+	data    map[string]interface{}
+	created time.Time
+	deleted time.Time
+}
+
+// Server is a fake Vault server backed by memory
+type Server struct {
+	URL   string // Base URL, use it as VaultConfig.Host
+	Token string // Token the server accepts
+
+	server   *httptest.Server
+	mu       sync.Mutex
+	mounts   map[string]int
+	secrets  map[string]*secret
+	sealed   bool
+	tokenTTL time.Duration
+
+	namespaceTokens map[string]string // Token to namespace
+	tokens          map[string]bool   // Tokens added with AddToken
+}
+
+// NewServer starts a fake Vault server. Call Close when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		Token:           DefaultToken,
+		mounts:          map[string]int{"secret": 2, "cubbyhole": 1},
+		secrets:         make(map[string]*secret),
+		namespaceTokens: make(map[string]string),
+		tokens:          make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Put writes a secret as "vault kv put" would, e.g. Put("secret/app", data).
+// On KV v2 mounts every Put adds a version.
+func (s *Server) Put(path string, data map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(strings.Trim(path, "/"), data)
+}
+
+// Delete removes a secret as "vault kv delete" would. On KV v2 mounts the
+// latest version is marked deleted.
+func (s *Server) Delete(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delete(strings.Trim(path, "/"))
+}
+
+// Script queues changes to the secret at path, applied as it is read. The
+// secret must exist or be created by the first change.
+func (s *Server) Script(path string, changes ...Change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec := s.secret(strings.Trim(path, "/"))
+	sec.script = append(sec.script, changes...)
+	sort.SliceStable(sec.script, func(i, j int) bool { return sec.script[i].AfterReads < sec.script[j].AfterReads })
+}
+
+// SetCustomMetadata replaces the custom_metadata of a KV v2 secret, as
+// "vault kv metadata put -custom-metadata" would
+func (s *Server) SetCustomMetadata(path string, metadata map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secret(strings.Trim(path, "/")).customMetadata = copyMetadata(metadata)
+}
+
+// Reads returns how often the secret at path was read
+func (s *Server) Reads(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sec, ok := s.secrets[strings.Trim(path, "/")]; ok {
+		return sec.reads
+	}
+	return 0
+}
+
+// SetSealed makes sys/health report a sealed Vault and every other request fail
+func (s *Server) SetSealed(sealed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sealed = sealed
+}
+
+// SetTokenTTL sets the TTL auth/token/lookup-self reports for the token. The
+// default of zero is a token that never expires, like a root token.
+func (s *Server) SetTokenTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenTTL = ttl
+}
+
+// AddToken makes the server accept another token, with access to every path
+// and its own cubbyhole
+func (s *Server) AddToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = true
+}
+
+// RevokeToken stops accepting token and destroys its cubbyhole, as
+// "vault token revoke" would
+func (s *Server) RevokeToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, token)
+	delete(s.namespaceTokens, token)
+	if token == s.Token {
+		s.Token = ""
+	}
+	for path := range s.secrets {
+		if strings.HasPrefix(path, cubbyholePath(token, "")) {
+			delete(s.secrets, path)
+		}
+	}
+}
+
+// PutCubbyhole writes a secret to the cubbyhole of token, e.g.
+// PutCubbyhole(token, "cubbyhole/app", data)
+func (s *Server) PutCubbyhole(token, path string, data map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(cubbyholePath(token, path), data)
+}
+
+// cubbyholePath returns where a cubbyhole path of token is stored: cubbyholes
+// are scoped to their token, so the same path of two tokens holds two secrets
+func cubbyholePath(token, path string) string {
+	rest := strings.TrimPrefix(strings.TrimPrefix(strings.Trim(path, "/"), "cubbyhole"), "/")
+	return strings.TrimSuffix("cubbyhole/"+token+"/"+rest, "/")
+}
+
+// accessor returns the accessor of token, which identifies it without revealing it
+func accessor(token string) string {
+	return fmt.Sprintf("accessor-%08x", crc32.ChecksumIEEE([]byte(token)))
+}
+
+// secret returns the secret at a logical path, creating it if needed
+func (s *Server) secret(path string) *secret {
+	sec, ok := s.secrets[path]
+	if !ok {
+		sec = &secret{}
+		s.secrets[path] = sec
+	}
+	return sec
+}
+
+func (s *Server) put(path string, data map[string]interface{}) int {
+	sec := s.secret(path)
+	version := secretVersion{data: copyData(data), created: time.Now().UTC()}
+	if _, v := s.mount(path); v == 1 {
+		sec.versions = []secretVersion{version}
+	} else {
+		sec.versions = append(sec.versions, version)
+	}
+	return len(sec.versions)
+}
+
+func (s *Server) delete(path string) {
+	sec, ok := s.secrets[path]
+	if !ok || len(sec.versions) == 0 {
+		return
+	}
+	if _, v := s.mount(path); v == 1 {
+		sec.versions = nil
+		return
+	}
+	sec.versions[len(sec.versions)-1].deleted = time.Now().UTC()
+}
+
+// currentVersion returns the number of the latest version of a secret, or 0
+func (s *Server) currentVersion(path string) int {
+	if sec, ok := s.secrets[path]; ok {
+		return len(sec.versions)
+	}
+	return 0
+}
+
+// mount returns the mount of a logical path and its KV version, or "" and 0
+func (s *Server) mount(path string) (string, int) {
+	best := ""
+	for mount := range s.mounts {
+		if (path == mount || strings.HasPrefix(path, mount+"/")) && len(mount) > len(best) {
+			best = mount
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	return best, s.mounts[best]
+}
+
+// read returns the requested version of a secret, applying due scripted changes first
+func (s *Server) read(path string, version int) (*secretVersion, int, bool) {
+	sec, ok := s.secrets[path]
+	if !ok {
+		return nil, 0, false
+	}
+	for len(sec.script) > 0 && sec.script[0].AfterReads <= sec.reads {
+		change := sec.script[0]
+		sec.script = sec.script[1:]
+		if change.Data == nil {
+			s.delete(path)
+		} else {
+			s.put(path, change.Data)
+		}
+	}
+	sec.reads++
+
+	if version == 0 {
+		version = len(sec.versions)
+	}
+	if version < 1 || version > len(sec.versions) || !sec.versions[version-1].deleted.IsZero() {
+		return nil, 0, false
+	}
+	return &sec.versions[version-1], version, true
+}
+
+// list returns the keys directly below a logical prefix, folders ending in "/"
+func (s *Server) list(prefix string) []string {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	seen := make(map[string]bool)
+	for path, sec := range s.secrets {
+		if !strings.HasPrefix(path, prefix) || len(sec.versions) == 0 {
+			continue
+		}
+		key := strings.TrimPrefix(path, prefix)
+		if i := strings.Index(key, "/"); i >= 0 {
+			key = key[:i+1]
+		}
+		seen[key] = true
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	if path == "sys/health" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"initialized": true,
+			"sealed":      s.sealed,
+			"standby":     false,
+			"version":     "fake",
+		})
+		return
+	}
+	if s.sealed {
+		writeError(w, http.StatusServiceUnavailable, "Vault is sealed")
+		return
+	}
+	if namespace := strings.Trim(r.Header.Get("X-Vault-Namespace"), "/"); namespace != "" {
+		path = namespace + "/" + path
+	}
+	if !s.authorized(r.Header.Get("X-Vault-Token"), path) {
+		writeError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	if namespace, lookup, ok := strings.Cut(path, "sys/internal/ui/mounts/"); ok && r.Method == http.MethodGet &&
+		(namespace == "" || strings.HasSuffix(namespace, "/")) {
+		s.serveMountLookup(w, namespace+lookup)
+		return
+	}
+	if path == "auth/token/lookup-self" && r.Method == http.MethodGet {
+		s.serveLookupSelf(w, r.Header.Get("X-Vault-Token"))
+		return
+	}
+	if path == "cubbyhole" || strings.HasPrefix(path, "cubbyhole/") {
+		path = cubbyholePath(r.Header.Get("X-Vault-Token"), path)
+	}
+
+	mount, version := s.mount(path)
+	if mount == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no handler for route %q", path))
+		return
+	}
+
+	method := r.Method
+	if method == http.MethodGet && r.URL.Query().Get("list") == "true" {
+		method = "LIST"
+	}
+
+	if version == 1 {
+		s.serveKVv1(w, r, method, path)
+		return
+	}
+	s.serveKVv2(w, r, method, mount, strings.TrimPrefix(strings.TrimPrefix(path, mount), "/"))
+}
+
+// serveMountLookup answers sys/internal/ui/mounts with the mount of path
+func (s *Server) serveMountLookup(w http.ResponseWriter, path string) {
+	if path == "cubbyhole" || strings.HasPrefix(path, "cubbyhole/") {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"path": "cubbyhole/", "type": "cubbyhole"},
+		})
+		return
+	}
+	mount, version := s.mount(path)
+	if mount == "" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("no mount found for path %q", path))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"path":    mount + "/",
+			"type":    "kv",
+			"options": map[string]interface{}{"version": strconv.Itoa(version)},
+		},
+	})
+}
+
+// authorized reports whether token may access path
+func (s *Server) authorized(token, path string) bool {
+	if token != "" && (token == s.Token || s.tokens[token]) {
+		return true
+	}
+	namespace, ok := s.namespaceTokens[token]
+	return ok && strings.HasPrefix(path, namespace+"/")
+}
+
+func (s *Server) serveLookupSelf(w http.ResponseWriter, token string) {
+	var expireTime interface{}
+	if s.tokenTTL > 0 {
+		expireTime = time.Now().Add(s.tokenTTL).UTC().Format(time.RFC3339Nano)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"accessor":     accessor(token),
+			"display_name": "token",
+			"expire_time":  expireTime,
+			"policies":     []string{"default"},
+			"renewable":    s.tokenTTL > 0,
+			"ttl":          int64(s.tokenTTL / time.Second),
+		},
+	})
+}
+
+func (s *Server) serveKVv1(w http.ResponseWriter, r *http.Request, method, path string) {
+	switch method {
+	case "LIST":
+		s.writeKeys(w, s.list(path))
+	case http.MethodGet:
+		version, _, ok := s.read(path, 0)
+		if !ok {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": version.data})
+	case http.MethodPut, http.MethodPost:
+		var data map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.put(path, data)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		s.delete(path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) serveKVv2(w http.ResponseWriter, r *http.Request, method, mount, rest string) {
+	kind, key, _ := strings.Cut(rest, "/")
+	path := mount + "/" + key
+
+	switch {
+	case kind == "metadata" && method == "LIST":
+		s.writeKeys(w, s.list(path))
+	case kind == "metadata" && method == http.MethodGet:
+		sec, ok := s.secrets[path]
+		if !ok || len(sec.versions) == 0 {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		versions := make(map[string]interface{}, len(sec.versions))
+		for i, v := range sec.versions {
+			versions[strconv.Itoa(i+1)] = map[string]interface{}{
+				"created_time":  v.created.Format(time.RFC3339Nano),
+				"deletion_time": deletionTime(v),
+				"destroyed":     false,
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"current_version": len(sec.versions),
+			"oldest_version":  1,
+			"custom_metadata": sec.customMetadata,
+			"versions":        versions,
+		}})
+	case kind == "metadata" && (method == http.MethodPut || method == http.MethodPost):
+		var body struct {
+			CustomMetadata map[string]string `json:"custom_metadata"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.secret(path).customMetadata = copyMetadata(body.CustomMetadata)
+		w.WriteHeader(http.StatusNoContent)
+	case kind == "metadata" && method == http.MethodDelete:
+		delete(s.secrets, path)
+		w.WriteHeader(http.StatusNoContent)
+	case kind == "data" && method == http.MethodGet:
+		requested, _ := strconv.Atoi(r.URL.Query().Get("version"))
+		version, number, ok := s.read(path, requested)
+		if !ok {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"data":     version.data,
+			"metadata": s.readMetadata(path, *version, number),
+		}})
+	case kind == "data" && (method == http.MethodPut || method == http.MethodPost):
+		var body struct {
+			Data    map[string]interface{} `json:"data"`
+			Options struct {
+				CAS *int `json:"cas"`
+			} `json:"options"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if body.Options.CAS != nil && *body.Options.CAS != s.currentVersion(path) {
+			writeError(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+			return
+		}
+		number := s.put(path, body.Data)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": versionMetadata(s.secrets[path].versions[number-1], number),
+		})
+	case kind == "data" && method == http.MethodDelete:
+		s.delete(path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unsupported KV v2 request %s %s", method, rest))
+	}
+}
+
+func (s *Server) writeKeys(w http.ResponseWriter, keys []string) {
+	if len(keys) == 0 {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+}
+
+// readMetadata is the metadata returned with a read, which includes custom_metadata
+func (s *Server) readMetadata(path string, v secretVersion, number int) map[string]interface{} {
+	metadata := versionMetadata(v, number)
+	metadata["custom_metadata"] = s.secrets[path].customMetadata
+	return metadata
+}
+
+func versionMetadata(v secretVersion, number int) map[string]interface{} {
+	return map[string]interface{}{
+		"version":       number,
+		"created_time":  v.created.Format(time.RFC3339Nano),
+		"deletion_time": deletionTime(v),
+		"destroyed":     false,
+	}
+}
+
+func deletionTime(v secretVersion) string {
+	if !v.deleted.IsZero() {
+		return v.deleted.Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+func copyData(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		copied[k] = v
+	}
+	return copied
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, errs ...string) {
+	if errs == nil {
+		errs = []string{}
+	}
+	writeJSON(w, status, map[string]interface{}{"errors": errs})
+}
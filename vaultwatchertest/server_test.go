@@ -0,0 +1,260 @@
+package vaultwatchertest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func newClient(t *testing.T, s *Server) *api.Client {
+	t.Helper()
+
+	config := api.DefaultConfig()
+	config.Address = s.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.SetToken(s.Token)
+	return client
+}
+
+func TestServer_KVv2(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	s.Put("secret/app/db", map[string]interface{}{"password": "one"})
+	s.Put("secret/app/db", map[string]interface{}{"password": "two"})
+
+	secret, err := client.Logical().Read("secret/data/app/db")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	data := secret.Data["data"].(map[string]interface{})
+	if data["password"] != "two" {
+		t.Errorf("password = %v, want two", data["password"])
+	}
+	metadata := secret.Data["metadata"].(map[string]interface{})
+	if fmt.Sprint(metadata["version"]) != "2" {
+		t.Errorf("version = %v, want 2", metadata["version"])
+	}
+
+	old, err := client.Logical().ReadWithData("secret/data/app/db", map[string][]string{"version": {"1"}})
+	if err != nil {
+		t.Fatalf("ReadWithData() error = %v", err)
+	}
+	if got := old.Data["data"].(map[string]interface{})["password"]; got != "one" {
+		t.Errorf("version 1 password = %v, want one", got)
+	}
+
+	if _, err := client.Logical().Write("secret/data/app/cache", map[string]interface{}{
+		"data": map[string]interface{}{"ttl": "60"},
+	}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	list, err := client.Logical().List("secret/metadata/app")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if keys := list.Data["keys"]; !reflect.DeepEqual(keys, []interface{}{"cache", "db"}) {
+		t.Errorf("keys = %v, want [cache db]", keys)
+	}
+
+	if _, err := client.Logical().Delete("secret/data/app/db"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if secret, err := client.Logical().Read("secret/data/app/db"); err != nil || secret != nil {
+		t.Errorf("Read() after delete = %v, %v, want nil, nil", secret, err)
+	}
+}
+
+func TestServer_KVv1(t *testing.T) {
+	s := NewServer(WithMount("kv", 1))
+	defer s.Close()
+	client := newClient(t, s)
+
+	if _, err := client.Logical().Write("kv/app/db", map[string]interface{}{"password": "one"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	s.Put("kv/app/nested/key", map[string]interface{}{"a": "b"})
+
+	secret, err := client.Logical().Read("kv/app/db")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if secret.Data["password"] != "one" {
+		t.Errorf("password = %v, want one", secret.Data["password"])
+	}
+
+	list, err := client.Logical().List("kv/app")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if keys := list.Data["keys"]; !reflect.DeepEqual(keys, []interface{}{"db", "nested/"}) {
+		t.Errorf("keys = %v, want [db nested/]", keys)
+	}
+}
+
+func TestServer_Script(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	s.Put("secret/app", map[string]interface{}{"value": "v1"})
+	s.Script("secret/app",
+		Change{AfterReads: 2, Data: map[string]interface{}{"value": "v2"}},
+		Change{AfterReads: 3, Data: nil},
+	)
+
+	want := []interface{}{"v1", "v1", "v2", nil}
+	for i, expected := range want {
+		secret, err := client.Logical().Read("secret/data/app")
+		if err != nil {
+			t.Fatalf("read %d: error = %v", i+1, err)
+		}
+		var got interface{}
+		if secret != nil {
+			got = secret.Data["data"].(map[string]interface{})["value"]
+		}
+		if got != expected {
+			t.Errorf("read %d: value = %v, want %v", i+1, got, expected)
+		}
+	}
+	if reads := s.Reads("secret/app"); reads != 4 {
+		t.Errorf("Reads() = %d, want 4", reads)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	s := NewServer(WithToken("right"))
+	defer s.Close()
+	s.Put("secret/app", map[string]interface{}{"value": "v1"})
+
+	client := newClient(t, s)
+	client.SetToken("wrong")
+	if _, err := client.Logical().Read("secret/data/app"); err == nil {
+		t.Error("Read() with a wrong token succeeded")
+	}
+
+	client.SetToken("right")
+	s.SetSealed(true)
+	if _, err := client.Logical().Read("secret/data/app"); err == nil {
+		t.Error("Read() while sealed succeeded")
+	}
+	health, err := client.Sys().Health()
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	if !health.Sealed {
+		t.Error("Health().Sealed = false, want true")
+	}
+}
+
+func TestServer_CustomMetadata(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	s.Put("secret/app", map[string]interface{}{"value": "v1"})
+	s.SetCustomMetadata("secret/app", map[string]string{"owner": "payments"})
+
+	secret, err := client.Logical().Read("secret/data/app")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	custom := secret.Data["metadata"].(map[string]interface{})["custom_metadata"]
+	if !reflect.DeepEqual(custom, map[string]interface{}{"owner": "payments"}) {
+		t.Errorf("custom_metadata = %v, want owner=payments", custom)
+	}
+
+	if _, err := client.Logical().Write("secret/metadata/app", map[string]interface{}{
+		"custom_metadata": map[string]string{"owner": "platform"},
+	}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	metadata, err := client.Logical().Read("secret/metadata/app")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(metadata.Data["custom_metadata"], map[string]interface{}{"owner": "platform"}) {
+		t.Errorf("custom_metadata = %v, want owner=platform", metadata.Data["custom_metadata"])
+	}
+}
+
+func TestServer_LookupSelf(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	for _, want := range []time.Duration{0, 2 * time.Hour} {
+		s.SetTokenTTL(want)
+		secret, err := client.Auth().Token().LookupSelf()
+		if err != nil {
+			t.Fatalf("LookupSelf() error = %v", err)
+		}
+		ttl, err := secret.TokenTTL()
+		if err != nil {
+			t.Fatalf("TokenTTL() error = %v", err)
+		}
+		if ttl != want {
+			t.Errorf("TokenTTL() = %v, want %v", ttl, want)
+		}
+	}
+}
+
+func TestServer_Cubbyhole(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddToken("other-token")
+	client := newClient(t, s)
+	other := newClient(t, s)
+	other.SetToken("other-token")
+
+	s.PutCubbyhole(s.Token, "cubbyhole/app", map[string]interface{}{"password": "mine"})
+	if _, err := other.Logical().Write("cubbyhole/app", map[string]interface{}{"password": "theirs"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// Each token sees only its own cubbyhole
+	for _, tt := range []struct {
+		client *api.Client
+		want   string
+	}{
+		{client, "mine"},
+		{other, "theirs"},
+	} {
+		secret, err := tt.client.Logical().Read("cubbyhole/app")
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if secret.Data["password"] != tt.want {
+			t.Errorf("password = %v, want %s", secret.Data["password"], tt.want)
+		}
+	}
+
+	mine, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		t.Fatalf("LookupSelf() error = %v", err)
+	}
+	theirs, err := other.Auth().Token().LookupSelf()
+	if err != nil {
+		t.Fatalf("LookupSelf() error = %v", err)
+	}
+	if mine.Data["accessor"] == theirs.Data["accessor"] {
+		t.Errorf("both tokens have accessor %v", mine.Data["accessor"])
+	}
+
+	s.RevokeToken("other-token")
+	if _, err := other.Logical().Read("cubbyhole/app"); err == nil {
+		t.Error("Read() with a revoked token succeeded")
+	}
+	s.AddToken("other-token")
+	if secret, err := other.Logical().Read("cubbyhole/app"); err != nil || secret != nil {
+		t.Errorf("Read() of a revoked cubbyhole = %v, %v, want nothing", secret, err)
+	}
+}
@@ -0,0 +1,235 @@
+package vaultwatcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// NewVersionAvailableEvent is emitted when a watcher pinned with
+// WithPinnedVersion sees a newer version of its KV v2 secret. The newer
+// version is not adopted; call PinVersion to promote it.
+type NewVersionAvailableEvent struct {
+	Path          string    `json:"path"`
+	PinnedVersion int       `json:"pinned_version"`
+	LatestVersion int       `json:"latest_version"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// NewVersionNotifier is implemented by notifiers that want to know when a
+// pinned secret has newer versions. Notifiers registered with WithNotifier
+// are checked for it automatically.
+type NewVersionNotifier interface {
+	NotifyNewVersionAvailable(ctx context.Context, event NewVersionAvailableEvent) error
+}
+
+// VersionedSecretReader is a SecretReader that can send query parameters, as
+// the client's *api.Logical does. Pinned watchers need it to request a version.
+type VersionedSecretReader interface {
+	SecretReader
+	ReadWithData(path string, data map[string][]string) (*api.Secret, error)
+}
+
+// SecretVersion describes one version of a KV v2 secret
+type SecretVersion struct {
+	Version      int       `json:"version"`
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime time.Time `json:"deletion_time,omitempty"` // Zero unless the version was deleted
+	Destroyed    bool      `json:"destroyed"`
+	Current      bool      `json:"current"` // The latest version
+}
+
+// ListVersions returns the version history of the watched KV v2 secret,
+// oldest first, e.g. to offer rolling back to a previous version
+func (w *Watcher) ListVersions() ([]SecretVersion, error) {
+	if w.source != nil {
+		return nil, errCustomSource
+	}
+
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	metadata, err := w.readKVMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := strconv.Atoi(fmt.Sprint(metadata["current_version"]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret metadata from vault: invalid current_version %v", metadata["current_version"])
+	}
+
+	raw, _ := metadata["versions"].(map[string]interface{})
+	versions := make([]SecretVersion, 0, len(raw))
+	for number, details := range raw {
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret metadata from vault: invalid version %q", number)
+		}
+		fields, _ := details.(map[string]interface{})
+
+		version := SecretVersion{Version: n, Current: n == current}
+		version.CreatedTime, _ = time.Parse(time.RFC3339Nano, fmt.Sprint(fields["created_time"]))
+		version.DeletionTime, _ = time.Parse(time.RFC3339Nano, fmt.Sprint(fields["deletion_time"]))
+		version.Destroyed, _ = fields["destroyed"].(bool)
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	return versions, nil
+}
+
+// readKVMetadata reads the metadata of the watched KV v2 secret
+func (w *Watcher) readKVMetadata(ctx context.Context) (map[string]interface{}, error) {
+	metadataPath, err := kvMetadataPath(w.vaultConfig.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := w.read(ctx, metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret metadata from vault: %w", classifyVaultError(err))
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("failed to read secret metadata from vault: %w", ErrSecretNotFound)
+	}
+	return secret.Data, nil
+}
+
+// CurrentVersion returns the KV v2 version of the data the watcher last
+// applied, or 0 if it is unknown, e.g. for KV v1 secrets. It is the Version
+// of the last applied ChangeEvent; inside onChange it is still the previous
+// version, and LastChange returns the one being applied.
+func (w *Watcher) CurrentVersion() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.currentVersion
+}
+
+// secretVersion returns the version in the metadata of a KV v2 read, or 0
+func secretVersion(secret *api.Secret) int {
+	metadata, ok := secret.Data["metadata"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	version, err := strconv.Atoi(fmt.Sprint(metadata["version"]))
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// secretCreatedTime returns the creation time in the metadata of a KV v2 read
+func secretCreatedTime(secret *api.Secret) time.Time {
+	metadata, ok := secret.Data["metadata"].(map[string]interface{})
+	if !ok {
+		return time.Time{}
+	}
+	created, _ := time.Parse(time.RFC3339Nano, fmt.Sprint(metadata["created_time"]))
+	return created
+}
+
+// PinnedVersion returns the KV v2 version the watcher reads, or 0 for the latest
+func (w *Watcher) PinnedVersion() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.pinnedVersion
+}
+
+// LatestVersion returns the newest version seen by the last check of a pinned
+// watcher, or 0 if it hasn't checked yet
+func (w *Watcher) LatestVersion() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.latestVersion
+}
+
+// PinVersion changes the pinned version, e.g. to promote a newer version. The
+// next check reads it and runs the onChange callback if its data differs.
+func (w *Watcher) PinVersion(version int) error {
+	if w.source != nil {
+		return errCustomSource
+	}
+	if version < 1 {
+		return fmt.Errorf("version must be at least 1")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pinnedVersion = version
+	return nil
+}
+
+// readSecret reads the watched path, asking for the pinned version if there is one
+func (w *Watcher) readSecret(ctx context.Context) (*api.Secret, error) {
+	pinned := w.PinnedVersion()
+	if pinned == 0 {
+		return w.read(ctx, w.vaultConfig.Path)
+	}
+
+	reader, ok := w.secretReader().(VersionedSecretReader)
+	if !ok {
+		return nil, fmt.Errorf("secret reader cannot read pinned versions")
+	}
+	return w.readWithData(ctx, reader, w.vaultConfig.Path, map[string][]string{"version": {strconv.Itoa(pinned)}})
+}
+
+// checkNewVersion reads the secret's metadata and emits a NewVersionAvailableEvent
+// the first time a version newer than the pin is seen
+func (w *Watcher) checkNewVersion() error {
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	metadata, err := w.readKVMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	latest, err := strconv.Atoi(fmt.Sprint(metadata["current_version"]))
+	if err != nil {
+		return fmt.Errorf("failed to read secret metadata from vault: invalid current_version %v", metadata["current_version"])
+	}
+
+	w.mu.Lock()
+	pinned := w.pinnedVersion
+	seen := w.latestVersion
+	w.latestVersion = latest
+	w.mu.Unlock()
+
+	if latest <= pinned || latest == seen {
+		return nil
+	}
+
+	w.notifyNewVersion(NewVersionAvailableEvent{
+		Path:          w.vaultConfig.Path,
+		PinnedVersion: pinned,
+		LatestVersion: latest,
+		Timestamp:     time.Now().UTC(),
+	})
+	return nil
+}
+
+// notifyNewVersion delivers the event to every notifier implementing NewVersionNotifier
+func (w *Watcher) notifyNewVersion(event NewVersionAvailableEvent) {
+	for _, notifier := range w.notifiers {
+		versionNotifier, ok := notifier.(NewVersionNotifier)
+		if !ok {
+			continue
+		}
+		if err := versionNotifier.NotifyNewVersionAvailable(w.ctx, event); err != nil {
+			w.logger.Error("Error notifying new secret version", "error", w.redactError(err))
+		}
+	}
+}
+
+// kvMetadataPath turns a KV v2 data path like "secret/data/app" into "secret/metadata/app"
+func kvMetadataPath(path string) (string, error) {
+	mount, key, ok := strings.Cut(path, "/data/")
+	if !ok || mount == "" || key == "" {
+		return "", fmt.Errorf("path must be a KV v2 path like <mount>/data/<key>, got %q", path)
+	}
+	return mount + "/metadata/" + key, nil
+}
@@ -0,0 +1,138 @@
+package vaultwatcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// versionRecorder collects new version events
+type versionRecorder struct {
+	mu     sync.Mutex
+	events []NewVersionAvailableEvent
+}
+
+func (r *versionRecorder) Notify(ctx context.Context, event ChangeEvent) error { return nil }
+
+func (r *versionRecorder) NotifyNewVersionAvailable(ctx context.Context, event NewVersionAvailableEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *versionRecorder) latest() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := make([]int, len(r.events))
+	for i, event := range r.events {
+		versions[i] = event.LatestVersion
+	}
+	return versions
+}
+
+func TestWatcher_PinnedVersion(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	for _, password := range []string{"one", "two", "three"} {
+		vault.Put("secret/app", map[string]interface{}{"password": password})
+	}
+
+	recorder := &versionRecorder{}
+	changes := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			changes++
+			return nil
+		}, WithPinnedVersion(1), WithNotifier(recorder))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	data, err := watcher.fetchVaultData()
+	AssertNoError(t, err, "fetchVaultData()")
+	AssertStringEquals(t, data["password"].(string), "one", "pinned password")
+
+	AssertNoError(t, watcher.check(), "first check")
+	AssertNoError(t, watcher.check(), "second check")
+	if got := recorder.latest(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("events = %v, want one event for version 3", got)
+	}
+	if watcher.LatestVersion() != 3 {
+		t.Errorf("LatestVersion() = %d, want 3", watcher.LatestVersion())
+	}
+
+	vault.Put("secret/app", map[string]interface{}{"password": "four"})
+	AssertNoError(t, watcher.check(), "check after new version")
+	if got := recorder.latest(); len(got) != 2 || got[1] != 4 {
+		t.Errorf("events = %v, want a second event for version 4", got)
+	}
+	if changes != 0 {
+		t.Errorf("changes = %d, newer versions must not be adopted", changes)
+	}
+
+	// Promote the latest version
+	AssertError(t, watcher.PinVersion(0), "version must be at least 1", "PinVersion(0)")
+	AssertNoError(t, watcher.PinVersion(4), "PinVersion(4)")
+	AssertNoError(t, watcher.check(), "check after promotion")
+	if changes != 1 {
+		t.Errorf("changes after promotion = %d, want 1", changes)
+	}
+	if len(recorder.latest()) != 2 {
+		t.Errorf("events = %v, want no event once the pin is current", recorder.latest())
+	}
+}
+
+func TestWatcher_PinnedVersionErrors(t *testing.T) {
+	_, err := NewWatcher(&VaultConfig{Host: "https://vault.example.com", Path: "kv/app", Token: "t"}, time.Hour,
+		func() error { return nil }, WithPinnedVersion(2))
+	AssertError(t, err, `path must be a KV v2 path like <mount>/data/<key>, got "kv/app"`, "NewWatcher() with KV v1 path")
+
+	reader := SecretReaderFunc(func(string) (*api.Secret, error) { return nil, nil })
+	watcher, err := NewWatcher(TestVaultConfig(), time.Hour, func() error { return nil },
+		WithPinnedVersion(2), WithSecretReader(reader))
+	AssertNoError(t, err, "NewWatcher()")
+
+	_, err = watcher.fetchVaultData()
+	AssertError(t, err, "failed to read secret from vault: secret reader cannot read pinned versions", "fetchVaultData()")
+}
+
+func TestWatcher_ListVersions(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	for _, password := range []string{"one", "two", "three"} {
+		vault.Put("secret/app", map[string]interface{}{"password": password})
+	}
+	vault.Delete("secret/app")
+	vault.Put("secret/app", map[string]interface{}{"password": "four"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+
+	versions, err := watcher.ListVersions()
+	AssertNoError(t, err, "ListVersions()")
+
+	if len(versions) != 4 {
+		t.Fatalf("len(versions) = %d, want 4", len(versions))
+	}
+	for i, version := range versions {
+		if version.Version != i+1 {
+			t.Errorf("versions[%d].Version = %d, want %d", i, version.Version, i+1)
+		}
+		if version.CreatedTime.IsZero() {
+			t.Errorf("versions[%d].CreatedTime is zero", i)
+		}
+		AssertBoolEquals(t, version.Current, i == 3, "Current")
+		AssertBoolEquals(t, !version.DeletionTime.IsZero(), i == 2, "deleted")
+	}
+
+	missing, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/missing", Token: vault.Token}, time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+	_, err = missing.ListVersions()
+	AssertError(t, err, "failed to read secret metadata from vault: secret not found", "ListVersions() of a missing secret")
+}
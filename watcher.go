@@ -10,6 +10,7 @@
 //   - Configurable polling intervals
 //   - Support for both KV v1 and KV v2 secret engines
 //   - Callback mechanism for custom change handling
+//   - Pluggable notifiers, including signed webhooks
 //
 // Basic usage:
 //
@@ -37,7 +38,11 @@ package vaultwatcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -49,6 +54,18 @@ type VaultConfig struct {
 	Host  string // VAULT_HOST
 	Path  string // VAULT_PATH
 	Token string // VAULT_TOKEN
+
+	// Namespace is the Vault Enterprise namespace of Path, empty for the root
+	// namespace
+	Namespace string // VAULT_NAMESPACE
+
+	// FailoverHosts are addresses tried in order when Host is unreachable or
+	// sealed. Further comma-separated addresses in VAULT_HOST end up here.
+	FailoverHosts []string
+
+	// DisableRedirects stops the client from following the redirects standby
+	// nodes answer with. They then fail with ErrVaultStandby.
+	DisableRedirects bool // VAULT_DISABLE_REDIRECTS
 }
 
 // Watcher monitors a Vault path for changes by comparing hashes of the variables
@@ -56,39 +73,334 @@ type Watcher struct {
 	vaultConfig   *VaultConfig
 	client        *api.Client
 	currentHash   string
+	keyHashes     map[string]string
 	checkInterval time.Duration
-	onChange      func() error
+	handler       ChangeHandler
+	notifiers     []Notifier
+	notifyTimeout time.Duration
+	logger        *slog.Logger
+	fetchData     func() (map[string]interface{}, error)
+	reader        SecretReader
+	source        SecretSource
+	selectData    func(map[string]interface{}) (map[string]interface{}, error)
 	ctx           context.Context
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
 	mu            sync.RWMutex
+	checkMu       sync.Mutex // Serializes checks from the monitor, timers and the scheduler
 	started       bool
+
+	failureThreshold    int
+	consecutiveFailures int
+	unhealthy           bool
+	initialized         bool      // The initial fetch succeeded
+	lastSuccessfulCheck time.Time // Of the initial fetch or a check
+	authFailed          bool      // The last check was denied permission
+
+	churnLimit  int
+	churnWindow time.Duration
+	changeTimes []time.Time
+	churning    bool
+
+	sealAware        bool
+	vaultUnavailable bool
+
+	lock    Lock
+	lockID  string
+	lockTTL time.Duration
+	leader  bool
+
+	stateStore  StateStore
+	rateLimiter *RateLimiter
+
+	pinnedVersion int
+	latestVersion int
+
+	baselineHash       string
+	expectedValues     map[string]interface{}
+	expectedValuesFile string
+	expectedHashes     map[string]string
+	drifted            bool
+	keyDrift           keyDrift
+
+	tokenCheckInterval time.Duration
+	tokenWarning       time.Duration
+	tokenExpiresAt     time.Time
+	tokenExpiring      bool
+
+	expiryLeadTimes []time.Duration
+	expiryParsers   []ExpiryParser
+	expiries        map[string]*expiryState
+
+	cooldown      time.Duration
+	lastCallback  time.Time
+	pendingChange bool
+	quietWindows  []QuietWindow
+
+	schedule *CronSchedule
+
+	includeCustomMetadata bool
+	metadataOnly          bool
+
+	readVersion    int       // KV v2 version returned by the last read
+	readCreated    time.Time // Creation time of readVersion
+	readRequestID  string    // Vault request ID of the last read
+	readWarnings   []string  // Vault warnings of the last read
+	readMountType  MountType // Secrets engine of the last read
+	currentVersion int       // KV v2 version of the data behind currentHash
+	currentCreated time.Time // Creation time of currentVersion
+
+	mountType         MountType // Secrets engine of the watched path, once resolved
+	mountTypeResolved bool
+
+	lastChange    *ChangeEvent
+	history       []ChangeEvent
+	historyLength int
+
+	requestTimeout time.Duration
+	retryPolicy    *RetryPolicy
+	consistency    Consistency
+
+	followActive bool
+
+	unsealWait      time.Duration
+	unsealWaitStart time.Time // Zero unless waiting for unseal
+	lastUnsealPoll  time.Time
+
+	jsonPatch      bool
+	patchValues    bool                   // Keep values in jsonPatch patches
+	currentData    map[string]interface{} // Data behind currentHash, kept only for jsonPatch
+	secureMemory   bool
+	currentBuffer  *secretBuffer // Replaces currentData with secureMemory
+	hashOnly       bool
+	hmacKeyPath    string
+	transitHMAC    *transitHMAC
+	decryptKeyPath string
+	transitDecrypt *transitHMAC // Key decrypting ciphertext values
+	schemaDocument []byte
+	schema         *jsonSchema
+	validator      func(map[string]interface{}) error
+	rejectedHash   string // New hash last rejected by validation
+	fileTemplates  []FileTemplate
+	templates      []compiledTemplate
+
+	approvalHook     func(ChangeEvent) (bool, error)
+	awaitingApproval *ChangeEvent // Change deferred by approvalHook
+	participants     []ChangeParticipant
+
+	metricsSinks []MetricsSink
+	systemd      *systemdNotifier // Nil unless WithSystemdNotify runs under systemd
+
+	changeDelay  time.Duration
+	delayedHash  string // New hash waiting out changeDelay
+	delayedSince time.Time
+
+	deadLetter         DeadLetterSink
+	deadLetterAttempts int
+	failingHash        string // New hash whose callback is failing
+	callbackFailures   int
+	callbackMetrics    map[string]*CallbackMetrics
+
+	bindings []*Binding
+
+	listeners       map[ListenerID]changeListener
+	nextListenerID  ListenerID
+	valuePathHashes map[string]string // Hashes of the values selected by listeners, by JSON Pointer
+
+	hashAlgorithm     HashAlgorithm
+	keyFilters        []KeyFilter
+	priority          Priority
+	scheduler         *Scheduler
+	binaryKeys        []KeyFilter
+	skipBinaryDiffs   bool
+	cubbyholeAccessor string // Of the token the cubbyhole was first read with
+	fatalErrors       []error
+	fatal             chan error // Set while Run is running
+
+	redactedKeys    map[string]bool
+	digests         valueDigests // Digests of the last read values, scrubbed from messages
+	previousDigests valueDigests
+	digestsHash     string
 }
 
 // NewWatcher creates a new Vault watcher instance
 // vaultConfig: Vault connection configuration
 // checkInterval: How often to check for changes (e.g., 30 * time.Second)
 // onChange: Callback function to execute when changes are detected
-func NewWatcher(vaultConfig *VaultConfig, checkInterval time.Duration, onChange func() error) (*Watcher, error) {
+// opts: Optional settings such as WithNotifier
+func NewWatcher(vaultConfig *VaultConfig, checkInterval time.Duration, onChange func() error, opts ...Option) (*Watcher, error) {
+	if err := validateVaultConfig(vaultConfig); err != nil {
+		return nil, err
+	}
+	if onChange == nil {
+		return nil, fmt.Errorf("onChange callback cannot be nil")
+	}
+	return NewWatcherWithHandler(vaultConfig, checkInterval, LegacyHandler(onChange), opts...)
+}
+
+// NewWatcherWithHandler is NewWatcher with a ChangeHandler, which receives
+// the change being applied instead of reading it with LastChange
+func NewWatcherWithHandler(vaultConfig *VaultConfig, checkInterval time.Duration, handler ChangeHandler, opts ...Option) (*Watcher, error) {
+	if err := validateVaultConfig(vaultConfig); err != nil {
+		return nil, err
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("change handler cannot be nil")
+	}
+
+	w := newWatcher(vaultConfig, checkInterval, handler, opts...)
+	if err := w.prepare(); err != nil {
+		return nil, err
+	}
+
+	client, err := newVaultClient(vaultConfig, w.rateLimiter, w.retryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	w.consistency.apply(client)
+	w.client = client
+
+	return w, nil
+}
+
+// newWatcher creates a watcher with its options applied but no Vault client yet
+func newWatcher(vaultConfig *VaultConfig, checkInterval time.Duration, handler ChangeHandler, opts ...Option) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &Watcher{
+		vaultConfig:      vaultConfig,
+		checkInterval:    checkInterval,
+		handler:          handler,
+		ctx:              ctx,
+		cancel:           cancel,
+		failureThreshold: defaultFailureThreshold,
+		notifyTimeout:    defaultNotifyTimeout,
+		logger:           slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.systemd != nil {
+		w.systemd.logger = w.logger
+	}
+
+	return w
+}
+
+// validateVaultConfig checks that all required connection details are present
+func validateVaultConfig(vaultConfig *VaultConfig) error {
 	if vaultConfig == nil {
-		return nil, fmt.Errorf("vault config cannot be nil")
+		return fmt.Errorf("vault config cannot be nil")
 	}
 	if vaultConfig.Host == "" {
-		return nil, fmt.Errorf("VAULT_HOST is required")
+		return fmt.Errorf("VAULT_HOST is required")
 	}
 	if vaultConfig.Path == "" {
-		return nil, fmt.Errorf("VAULT_PATH is required")
+		return fmt.Errorf("VAULT_PATH is required")
 	}
 	if vaultConfig.Token == "" {
-		return nil, fmt.Errorf("VAULT_TOKEN is required")
+		return fmt.Errorf("VAULT_TOKEN is required")
 	}
-	if onChange == nil {
-		return nil, fmt.Errorf("onChange callback cannot be nil")
+	return nil
+}
+
+// prepare checks and compiles the options of a watcher reading Vault
+func (w *Watcher) prepare() error {
+	vaultConfig := w.vaultConfig
+	if w.pinnedVersion > 0 || w.includeCustomMetadata || w.metadataOnly {
+		if _, err := kvMetadataPath(vaultConfig.Path); err != nil {
+			return err
+		}
+	}
+	if w.metadataOnly && w.pinnedVersion > 0 {
+		return fmt.Errorf("metadata-only watchers cannot pin a version")
 	}
+	if w.hmacKeyPath != "" {
+		hmac, err := newTransitHMAC(w.hmacKeyPath)
+		if err != nil {
+			return err
+		}
+		w.transitHMAC = hmac
+	}
+	if w.decryptKeyPath != "" {
+		key, err := newTransitHMAC(w.decryptKeyPath)
+		if err != nil {
+			return err
+		}
+		w.transitDecrypt = key
+	}
+	if err := w.validateHashing(); err != nil {
+		return err
+	}
+	if err := w.loadSchema(); err != nil {
+		return err
+	}
+	if err := w.loadExpectedValues(); err != nil {
+		return err
+	}
+	if err := w.loadTemplates(); err != nil {
+		return err
+	}
+	if w.hashOnly && w.jsonPatch {
+		return fmt.Errorf("json patches keep secret data in memory, which hash-only watchers don't allow")
+	}
+	if w.followActive && (len(vaultConfig.FailoverHosts) > 0 || strings.HasPrefix(vaultConfig.Host, unixScheme) ||
+		isDiscoveryAddress(vaultConfig.Host)) {
+		return fmt.Errorf("active node discovery needs a single tcp vault address")
+	}
+	return nil
+}
 
+// newVaultClient creates an authenticated Vault client for the configuration.
+// Requests wait for limiter and are retried according to retry when they are
+// not nil.
+func newVaultClient(vaultConfig *VaultConfig, limiter *RateLimiter, retry *RetryPolicy) (*api.Client, error) {
 	// Create Vault client
 	vaultClientConfig := api.DefaultConfig()
 	vaultClientConfig.Address = vaultConfig.Host
+	vaultClientConfig.CheckRetry = checkRetry
+	if strings.HasPrefix(vaultConfig.Host, unixScheme) {
+		if err := useUnixSocket(vaultClientConfig, vaultConfig.Host); err != nil {
+			return nil, err
+		}
+	}
+	vaultClientConfig.HttpClient.Transport = newStandbyRedirectTransport(vaultClientConfig.HttpClient.Transport, !vaultConfig.DisableRedirects)
+	if isDiscoveryAddress(vaultConfig.Host) {
+		if len(vaultConfig.FailoverHosts) > 0 {
+			return nil, fmt.Errorf("failover hosts cannot be combined with service discovery")
+		}
+		resolve, err := newAddressResolver(vaultConfig.Host)
+		if err != nil {
+			return nil, err
+		}
+		hosts, err := discoverHosts(resolve, vaultConfig.Host)
+		if err != nil {
+			return nil, err
+		}
+		failover, err := newFailoverTransport(vaultClientConfig.HttpClient.Transport, hosts)
+		if err != nil {
+			return nil, err
+		}
+		failover.resolve = resolve
+		vaultClientConfig.HttpClient.Transport = failover
+		vaultClientConfig.Address = hosts[0]
+	} else if len(vaultConfig.FailoverHosts) > 0 {
+		hosts := append([]string{vaultConfig.Host}, vaultConfig.FailoverHosts...)
+		failover, err := newFailoverTransport(vaultClientConfig.HttpClient.Transport, hosts)
+		if err != nil {
+			return nil, err
+		}
+		vaultClientConfig.HttpClient.Transport = failover
+	}
+	if limiter != nil {
+		vaultClientConfig.HttpClient.Transport = limiter.transport(vaultClientConfig.HttpClient.Transport)
+	}
+	if retry != nil {
+		if err := retry.validate(); err != nil {
+			return nil, fmt.Errorf("invalid retry policy: %w", err)
+		}
+		retry.apply(vaultClientConfig)
+	}
 
 	client, err := api.NewClient(vaultClientConfig)
 	if err != nil {
@@ -97,17 +409,11 @@ func NewWatcher(vaultConfig *VaultConfig, checkInterval time.Duration, onChange
 
 	// Set the token
 	client.SetToken(vaultConfig.Token)
+	if vaultConfig.Namespace != "" {
+		client.SetNamespace(vaultConfig.Namespace)
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	return &Watcher{
-		vaultConfig:   vaultConfig,
-		client:        client,
-		checkInterval: checkInterval,
-		onChange:      onChange,
-		ctx:           ctx,
-		cancel:        cancel,
-	}, nil
+	return client, nil
 }
 
 // LoadVaultConfigFromEnv loads Vault connection details from environment variables
@@ -126,37 +432,57 @@ func LoadVaultConfigFromEnv() (*VaultConfig, error) {
 		return nil, fmt.Errorf("VAULT_TOKEN environment variable is required")
 	}
 
-	return &VaultConfig{
-		Host:  host,
-		Path:  path,
-		Token: token,
-	}, nil
+	config := &VaultConfig{
+		Host:      host,
+		Path:      path,
+		Token:     token,
+		Namespace: getEnv("VAULT_NAMESPACE", ""),
+	}
+	if hosts := strings.Split(host, ","); len(hosts) > 1 {
+		for i := range hosts {
+			hosts[i] = strings.TrimSpace(hosts[i])
+		}
+		config.Host, config.FailoverHosts = hosts[0], hosts[1:]
+	}
+	if value := getEnv("VAULT_DISABLE_REDIRECTS", ""); value != "" {
+		disable, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VAULT_DISABLE_REDIRECTS %q: %w", value, err)
+		}
+		config.DisableRedirects = disable
+	}
+	return config, nil
 }
 
-// fetchVaultData reads data from Vault and returns it as a map
+// fetchVaultData reads data from the source and returns it as a map
 func (w *Watcher) fetchVaultData() (map[string]interface{}, error) {
-	// Read secret from Vault
-	secret, err := w.client.Logical().Read(w.vaultConfig.Path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read secret from vault: %w", err)
+	// Specialised watchers may read something other than a single secret
+	if w.fetchData != nil {
+		return w.fetchData()
 	}
-	if secret == nil {
-		return nil, fmt.Errorf("failed to read secret from vault: secret is nil")
+
+	ctx, cancel := w.requestContext()
+	defer cancel()
+
+	data, meta, err := w.secretSource().Fetch(ctx)
+	w.mu.Lock()
+	w.readRequestID, w.readWarnings = meta.RequestID, meta.Warnings
+	w.readMountType = meta.MountType
+	if err == nil {
+		w.readVersion = meta.Version
+		w.readCreated = meta.CreatedTime
 	}
-	if secret.Data == nil {
-		return nil, fmt.Errorf("failed to read secret from vault: secret data is nil")
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
 	}
 
-	var vaultData map[string]interface{}
-	if data, ok := secret.Data["data"].(map[string]interface{}); ok {
-		// KV v2 format
-		vaultData = data
-	} else {
-		// KV v1 format or direct data
-		vaultData = secret.Data
+	// Specialised watchers only hash the fields they care about
+	if w.selectData != nil {
+		return w.selectData(data)
 	}
 
-	return vaultData, nil
+	return data, nil
 }
 
 // Start begins monitoring the Vault path for changes
@@ -170,35 +496,114 @@ func (w *Watcher) Start() error {
 	w.started = true
 	w.mu.Unlock()
 
+	if err := w.initialize(); err != nil {
+		return err
+	}
+
+	w.startElection()
+	w.startTokenMonitor()
+
+	if w.usesScheduler() {
+		if err := w.scheduler.add(w, w.checkInterval); err != nil {
+			return fmt.Errorf("failed to schedule watcher: %w", err)
+		}
+	} else {
+		// Start the monitoring goroutine
+		w.wg.Add(1)
+		go w.monitor()
+	}
+
+	w.systemd.ready(w.checkInterval)
+
+	return nil
+}
+
+// initialize reads the secret and records its initial hashes
+func (w *Watcher) initialize() error {
+	if w.followActive {
+		if err := w.followActiveNode(); err != nil {
+			return err
+		}
+	}
+
+	if w.sealAware {
+		availability, err := w.checkVaultAvailability()
+		if err != nil {
+			return fmt.Errorf("failed to check vault health: %w", err)
+		}
+		if availability.Sealed {
+			return fmt.Errorf("failed to fetch initial vault data: %w", ErrVaultSealed)
+		}
+		if !availability.Available {
+			return fmt.Errorf("failed to fetch initial vault data: vault is unavailable")
+		}
+	}
+
 	// Calculate initial hash
 	vaultData, err := w.fetchVaultData()
 	if err != nil {
 		return fmt.Errorf("failed to fetch initial vault data: %w", err)
 	}
 
-	initialHash, err := CalculateHash(vaultData)
+	initialHash, keyHashes, err := w.calculateHashes(vaultData)
 	if err != nil {
 		return fmt.Errorf("failed to calculate initial hash: %w", err)
 	}
+	w.rememberValues(vaultData, initialHash)
+	w.checkDrift(vaultData, initialHash)
+	w.checkExpiry(vaultData)
+
+	if errs := w.validateData(vaultData); len(errs) > 0 {
+		return fmt.Errorf("initial vault data failed validation: %s", strings.Join(errs, "; "))
+	}
+
+	if err := w.applyBindings(vaultData); err != nil {
+		return fmt.Errorf("failed to bind initial vault data: %w", err)
+	}
+	if err := w.renderTemplates(vaultData); err != nil {
+		return fmt.Errorf("failed to render initial vault data: %w", err)
+	}
+
+	if keyHashes == nil {
+		if keyHashes, err = w.calculateKeyHashes(vaultData); err != nil {
+			return fmt.Errorf("failed to calculate initial key hashes: %w", err)
+		}
+	}
+
+	w.trackValuePaths(vaultData)
 
 	w.mu.Lock()
 	w.currentHash = initialHash
+	w.currentVersion = w.readVersion
+	w.currentCreated = w.readCreated
+	w.keyHashes = keyHashes
+	w.rememberData(vaultData)
+	w.initialized = true
+	w.lastSuccessfulCheck = time.Now()
+	w.authFailed = false
 	w.mu.Unlock()
 
-	// Start the monitoring goroutine
-	w.wg.Add(1)
-	go w.monitor()
-
 	return nil
 }
 
 // Stop stops the watcher
 func (w *Watcher) Stop() {
+	w.systemd.notify("STOPPING=1")
+	if w.usesScheduler() {
+		w.scheduler.remove(w)
+	}
 	w.cancel()
 	w.wg.Wait()
 
+	if w.lock != nil {
+		w.resign()
+	}
+
 	w.mu.Lock()
 	w.started = false
+	w.initialized = false
+	w.cubbyholeAccessor = ""
+	w.forgetData()
 	w.mu.Unlock()
 }
 
@@ -206,56 +611,349 @@ func (w *Watcher) Stop() {
 func (w *Watcher) monitor() {
 	defer w.wg.Done()
 
+	if w.schedule != nil {
+		runCronSchedule(w.ctx, w.logger, w.schedule, func() {
+			w.check()
+			w.petWatchdog()
+		})
+		return
+	}
+
 	ticker := time.NewTicker(w.checkInterval)
 	defer ticker.Stop()
 
+	// Sources that watch natively trigger checks between ticks
+	var changes <-chan struct{}
+	if source, ok := w.source.(ChangeNotifyingSource); ok {
+		changes = source.Changes(w.ctx)
+	}
+
 	for {
 		select {
 		case <-w.ctx.Done():
 			return
 		case <-ticker.C:
-			if err := w.checkForChanges(); err != nil {
-				// Log error but continue monitoring
-				// You might want to add a logger here
-				fmt.Printf("Error checking for vault changes: %v\n", err)
+			w.check()
+			w.petWatchdog()
+			ticker.Reset(w.nextCheck())
+		case _, ok := <-changes:
+			if !ok {
+				// The source stopped watching; keep polling
+				changes = nil
 				continue
 			}
+			w.check()
+			w.petWatchdog()
+			ticker.Reset(w.nextCheck())
 		}
 	}
 }
 
+// check runs one scheduled check and returns its error. It is skipped while
+// another instance is the leader or Vault is unavailable. Checks never
+// overlap, so each one compares against the hash the previous one applied.
+func (w *Watcher) check() error {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+
+	if !w.IsLeader() {
+		// Another instance polls and broadcasts changes
+		return nil
+	}
+
+	if w.followActive {
+		if err := w.followActiveNode(); err != nil {
+			w.recordCheckResult(err)
+			w.logger.Error("Error following the active vault node", "error", err)
+			return err
+		}
+	}
+
+	if w.unsealWait > 0 && w.IsWaitingForUnseal() {
+		unsealed, err := w.pollUnseal()
+		if err != nil {
+			w.recordCheckResult(err)
+			w.logger.Error("Error checking vault health", "error", err)
+			return err
+		}
+		if !unsealed {
+			return nil
+		}
+	}
+
+	if w.sealAware {
+		// Reads are suspended while Vault is sealed or on standby
+		availability, err := w.checkVaultAvailability()
+		if err != nil {
+			w.recordCheckResult(err)
+			w.logger.Error("Error checking vault health", "error", err)
+			return err
+		}
+		if !availability.Available {
+			return nil
+		}
+	}
+
+	err := w.checkForChanges()
+	if err != nil {
+		w.mu.RLock()
+		requestID := w.readRequestID
+		w.mu.RUnlock()
+		if requestID != "" {
+			err = &RequestError{RequestID: requestID, Err: err}
+		}
+	}
+	if err == nil && w.PinnedVersion() > 0 {
+		err = w.checkNewVersion()
+	}
+	if w.unsealWait > 0 && errors.Is(err, ErrVaultSealed) {
+		// A sealed Vault isn't a failure of the watcher
+		w.startUnsealWait(err)
+		return nil
+	}
+	// Callback errors may quote the secret
+	err = w.redactError(err)
+	w.recordCheckResult(err)
+	if err != nil {
+		// Log error but continue monitoring
+		w.logger.Error("Error checking for vault changes", "error", err)
+	}
+	return err
+}
+
 // checkForChanges fetches the current vault data, calculates its hash,
-// and compares it with the stored hash. If different, calls the onChange callback.
+// and compares it with the stored hash. If different, calls the onChange callback
+// and then the registered notifiers.
 func (w *Watcher) checkForChanges() error {
 	vaultData, err := w.fetchVaultData()
 	if err != nil {
 		return fmt.Errorf("failed to fetch vault data: %w", err)
 	}
 
-	newHash, err := CalculateHash(vaultData)
+	newHash, newKeyHashes, err := w.calculateHashes(vaultData)
 	if err != nil {
 		return fmt.Errorf("failed to calculate hash: %w", err)
 	}
+	w.rememberValues(vaultData, newHash)
+	w.checkDrift(vaultData, newHash)
+	w.checkExpiry(vaultData)
 
 	w.mu.RLock()
 	currentHash := w.currentHash
+	currentKeyHashes := w.keyHashes
+	currentData, dataErr := w.appliedData()
+	currentVersion := w.currentVersion
+	readVersion := w.readVersion
+	readCreated := w.readCreated
+	readRequestID := w.readRequestID
+	readWarnings := w.readWarnings
+	readMountType := w.readMountType
+	pending := w.rejectedHash != "" || w.awaitingApproval != nil || w.delayedHash != ""
 	w.mu.RUnlock()
+	if dataErr != nil {
+		w.logger.Error("Error reading applied vault data", "error", dataErr)
+	}
 
-	if newHash != currentHash {
-		// Hash changed, execute callback
-		if err := w.onChange(); err != nil {
-			return fmt.Errorf("onChange callback failed: %w", err)
+	if newHash == currentHash {
+		if pending {
+			// Reverted, so a rejected or deferred secret is handled anew if it returns
+			w.mu.Lock()
+			w.rejectedHash = ""
+			w.awaitingApproval = nil
+			w.mu.Unlock()
+			w.clearDelayedChange()
 		}
+		w.updateChurn(false)
+		return nil
+	}
 
-		// Update the current hash
-		w.mu.Lock()
-		w.currentHash = newHash
-		w.mu.Unlock()
+	if w.isStaleRead() {
+		// A lagging performance standby served an older version
+		return nil
+	}
+
+	if w.delayChange(newHash) {
+		// Applied once the change delay has passed, unless reverted meanwhile
+		return nil
+	}
+
+	if w.deferCallback() {
+		// Applied by a later check once the callback is allowed
+		return nil
+	}
+
+	if newKeyHashes == nil {
+		if newKeyHashes, err = w.calculateKeyHashes(vaultData); err != nil {
+			return fmt.Errorf("failed to calculate key hashes: %w", err)
+		}
+	}
+
+	event := ChangeEvent{
+		Path:            w.vaultConfig.Path,
+		MountType:       readMountType,
+		OldHash:         currentHash,
+		NewHash:         newHash,
+		ChangedKeys:     ChangedKeys(currentKeyHashes, newKeyHashes),
+		Timestamp:       time.Now().UTC(),
+		Version:         readVersion,
+		PreviousVersion: currentVersion,
+		RequestID:       readRequestID,
+		Warnings:        readWarnings,
+	}
+	if !readCreated.IsZero() {
+		event.CreatedTime = &readCreated
+		event.DetectionLatency = event.Timestamp.Sub(readCreated)
+	}
+	if w.jsonPatch && currentData != nil {
+		event.Patch = w.redactPatch(JSONPatch(currentData, w.diffData(vaultData)))
+	}
+
+	if errs := w.validateData(vaultData); len(errs) > 0 {
+		// Keep the last valid secret; the hash isn't advanced
+		return w.rejectChange(event, errs)
+	}
+
+	approved, err := w.approveChange(event)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		// Deferred; the hash isn't advanced so the next check asks again
+		return nil
+	}
+
+	var previousClaim []byte
+	if w.stateStore != nil {
+		ok, previous, err := w.claimChange(newHash)
+		if err != nil {
+			return fmt.Errorf("failed to claim change: %w", err)
+		}
+		if !ok {
+			// Another instance sharing the state store already handled this change
+			if err := w.applyBindings(vaultData); err != nil {
+				w.logger.Error("Error binding vault data", "error", w.redactError(err))
+			}
+			if err := w.renderTemplates(vaultData); err != nil {
+				w.logger.Error("Error rendering vault data", "error", w.redactError(err))
+			}
+			w.trackValuePaths(vaultData)
+			w.mu.Lock()
+			w.currentHash = newHash
+			w.currentVersion = w.readVersion
+			w.currentCreated = w.readCreated
+			w.keyHashes = newKeyHashes
+			w.rememberData(vaultData)
+			w.mu.Unlock()
+			return nil
+		}
+		previousClaim = previous
 	}
 
+	// Hash changed, execute callback
+	w.mu.Lock()
+	w.lastChange = &event
+	retry := w.failingHash == newHash
+	w.mu.Unlock()
+	// Bound structs and participants are updated around the callback
+	onChange := func() error {
+		return w.applyChange(event, vaultData)
+	}
+	if callbackErr := w.runCallback("onChange", retry, onChange); callbackErr != nil {
+		attempts := w.recordCallbackFailure(newHash)
+		if w.deadLetterChange(event, callbackErr, attempts) {
+			// Given up on; keep the claim so other instances move on too
+			w.trackValuePaths(vaultData)
+			w.mu.Lock()
+			w.currentHash = newHash
+			w.currentVersion = w.readVersion
+			w.currentCreated = w.readCreated
+			w.keyHashes = newKeyHashes
+			w.rememberData(vaultData)
+			w.mu.Unlock()
+			return callbackErr
+		}
+		if w.stateStore != nil {
+			w.releaseChange(newHash, previousClaim)
+		}
+		return callbackErr
+	}
+	w.resetCallbackFailures()
+	w.mu.Lock()
+	w.rejectedHash = ""
+	w.mu.Unlock()
+	valuePaths := w.trackValuePaths(vaultData)
+
+	// Update the current hash
+	w.mu.Lock()
+	w.currentHash = newHash
+	w.currentVersion = event.Version
+	w.currentCreated = time.Time{}
+	if event.CreatedTime != nil {
+		w.currentCreated = *event.CreatedTime
+	}
+	w.keyHashes = newKeyHashes
+	w.rememberData(vaultData)
+	w.lastCallback = time.Now()
+	w.recordChange(event)
+	w.mu.Unlock()
+
+	w.emitCount("changes", 1)
+	w.notify(event)
+	w.notifyListeners(event, valuePaths)
+	w.updateChurn(true)
+
 	return nil
 }
 
+// rememberData keeps the applied data for JSON patches; w.mu must be held
+func (w *Watcher) rememberData(vaultData map[string]interface{}) {
+	if !w.jsonPatch {
+		return
+	}
+	vaultData = w.diffData(vaultData)
+	if !w.secureMemory {
+		w.currentData = vaultData
+		return
+	}
+
+	buffer, err := newSecretBuffer(vaultData)
+	if err != nil {
+		// The next change is reported without a patch
+		w.logger.Error("Error storing applied vault data", "error", err)
+	}
+	w.currentBuffer.wipe()
+	w.currentBuffer = buffer
+}
+
+// appliedData returns the data kept by rememberData; w.mu must be held
+func (w *Watcher) appliedData() (map[string]interface{}, error) {
+	if w.secureMemory {
+		return w.currentBuffer.data()
+	}
+	return w.currentData, nil
+}
+
+// forgetData drops the applied data, wiping it with secureMemory; w.mu must be held
+func (w *Watcher) forgetData() {
+	w.currentData = nil
+	w.currentBuffer.wipe()
+	w.currentBuffer = nil
+}
+
+// notify delivers the change event to every registered notifier, each
+// bounded by the notify timeout. Notifier errors are logged but don't affect
+// the watcher.
+func (w *Watcher) notify(event ChangeEvent) {
+	for _, notifier := range w.notifiers {
+		ctx, cancel := context.WithTimeout(w.ctx, w.notifyTimeout)
+		err := notifier.Notify(ctx, event)
+		cancel()
+		if err != nil {
+			w.logger.Error("Error notifying vault change", "error", w.redactError(err))
+		}
+	}
+}
+
 // GetCurrentHash returns the current hash of the vault data
 func (w *Watcher) GetCurrentHash() string {
 	w.mu.RLock()
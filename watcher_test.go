@@ -1,10 +1,14 @@
 package vaultwatcher
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
 )
 
 // Test helper functions and utilities
@@ -394,3 +398,76 @@ func TestVaultConfig_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestNewWatcher_WithNotifier(t *testing.T) {
+	notifier := NotifierFunc(func(ctx context.Context, event ChangeEvent) error { return nil })
+
+	watcher, err := NewWatcher(TestVaultConfig(), time.Second, func() error { return nil },
+		WithNotifier(notifier),
+		WithNotifier(nil),
+		WithNotifier(notifier),
+	)
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	defer watcher.Stop()
+
+	if len(watcher.notifiers) != 2 {
+		t.Errorf("NewWatcher() registered %d notifiers, want 2", len(watcher.notifiers))
+	}
+}
+
+func TestWatcher_FakeServer(t *testing.T) {
+	tests := []struct {
+		name   string
+		put    string
+		path   string
+		mounts []vaultwatchertest.Option
+	}{
+		{"kv v2", "secret/app", "secret/data/app", nil},
+		{"kv v1", "kv/app", "kv/app", []vaultwatchertest.Option{vaultwatchertest.WithMount("kv", 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := vaultwatchertest.NewServer(tt.mounts...)
+			defer vault.Close()
+
+			vault.Put(tt.put, map[string]interface{}{"password": "one"})
+			vault.Script(tt.put, vaultwatchertest.Change{AfterReads: 2, Data: map[string]interface{}{"password": "two"}})
+
+			changes := 0
+			watcher := TestWatcherWithConfig(t, &VaultConfig{Host: vault.URL, Path: tt.path, Token: vault.Token}, time.Hour, func() error {
+				changes++
+				return nil
+			})
+			AssertNoError(t, watcher.Start(), "Start()")
+			defer watcher.Stop()
+
+			AssertNoError(t, watcher.checkForChanges(), "first check")
+			if changes != 0 {
+				t.Errorf("changes after first check = %d, want 0", changes)
+			}
+			AssertNoError(t, watcher.checkForChanges(), "second check")
+			if changes != 1 {
+				t.Errorf("changes after scripted change = %d, want 1", changes)
+			}
+		})
+	}
+}
+
+func TestWatcher_Namespace(t *testing.T) {
+	vault := vaultwatchertest.NewServer(vaultwatchertest.WithNamespace("team-a", "team-a-token"))
+	defer vault.Close()
+	vault.Put("team-a/secret/app", map[string]interface{}{"password": "one"})
+
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: "team-a-token", Namespace: "team-a"},
+		time.Hour, func() error { return nil })
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	data, err := watcher.readData()
+	AssertNoError(t, err, "readData()")
+	AssertStringEquals(t, fmt.Sprint(data["password"]), "one", "password")
+}
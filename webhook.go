@@ -0,0 +1,179 @@
+package vaultwatcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// WebhookSignatureHeader carries the HMAC-SHA256 signature of the request body
+	WebhookSignatureHeader = "X-Vault-Watcher-Signature"
+
+	// WebhookNoRetries as WebhookConfig.MaxRetries makes a single attempt per
+	// URL, since 0 selects the default
+	WebhookNoRetries = -1
+
+	defaultWebhookMaxRetries = 3
+	defaultWebhookRetryDelay = time.Second
+	defaultWebhookTimeout    = 10 * time.Second
+)
+
+// WebhookConfig holds the configuration for a WebhookNotifier
+type WebhookConfig struct {
+	URLs       []string          // Endpoints that receive the change event
+	Secret     string            // HMAC signing key, requests are unsigned when empty
+	Headers    map[string]string // Extra headers sent with every request
+	MaxRetries int               // Retries per URL after the first attempt (default 3, WebhookNoRetries for none)
+	RetryDelay time.Duration     // Delay before the first retry, doubled on each retry (default 1s)
+	Timeout    time.Duration     // Timeout of a single request (default 10s)
+	HTTPClient *http.Client      // Optional custom HTTP client
+}
+
+// WebhookNotifier POSTs change events as JSON to one or more webhook URLs
+type WebhookNotifier struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that POSTs every change event to the configured URLs
+func NewWebhookNotifier(config WebhookConfig) (*WebhookNotifier, error) {
+	if len(config.URLs) == 0 {
+		return nil, fmt.Errorf("at least one webhook URL is required")
+	}
+	for _, url := range config.URLs {
+		if url == "" {
+			return nil, fmt.Errorf("webhook URL cannot be empty")
+		}
+	}
+	switch {
+	case config.MaxRetries == WebhookNoRetries:
+		config.MaxRetries = 0
+	case config.MaxRetries < 0:
+		return nil, fmt.Errorf("webhook max retries cannot be negative other than WebhookNoRetries")
+	case config.MaxRetries == 0:
+		config.MaxRetries = defaultWebhookMaxRetries
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaultWebhookRetryDelay
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &WebhookNotifier{
+		config: config,
+		client: client,
+	}, nil
+}
+
+// Notify sends the event to every configured URL, retrying transient failures.
+// Delivery to one URL failing doesn't prevent delivery to the others.
+func (n *WebhookNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal change event: %w", err)
+	}
+
+	var errs []error
+	for _, url := range n.config.URLs {
+		if err := n.deliver(ctx, url, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", url, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deliver POSTs the body to a single URL with exponential backoff between attempts
+func (n *WebhookNotifier) deliver(ctx context.Context, url string, body []byte) error {
+	delay := n.config.RetryDelay
+
+	var lastErr error
+	for attempt := 0; attempt <= n.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		retry, err := n.post(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// post performs a single delivery attempt and reports whether a failure is worth retrying
+func (n *WebhookNotifier) post(ctx context.Context, url string, body []byte) (bool, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vault-watcher")
+	for key, value := range n.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if n.config.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(n.config.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+}
+
+// SignWebhookPayload returns the signature header value for a webhook body,
+// formatted as "sha256=<hex HMAC-SHA256 of body>"
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is a valid signature of body.
+// Receivers can use it to authenticate requests sent by a WebhookNotifier.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	expected := SignWebhookPayload(secret, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
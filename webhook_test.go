@@ -0,0 +1,211 @@
+package vaultwatcher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      WebhookConfig
+		expectError bool
+		errorMsg    string
+		wantRetries int
+	}{
+		{
+			name:        "valid configuration",
+			config:      WebhookConfig{URLs: []string{"https://hooks.example.com/vault"}},
+			expectError: false,
+			wantRetries: defaultWebhookMaxRetries,
+		},
+		{
+			name:        "no retries",
+			config:      WebhookConfig{URLs: []string{"https://hooks.example.com/vault"}, MaxRetries: WebhookNoRetries},
+			expectError: false,
+			wantRetries: 0,
+		},
+		{
+			name:        "no URLs",
+			config:      WebhookConfig{},
+			expectError: true,
+			errorMsg:    "at least one webhook URL is required",
+		},
+		{
+			name:        "empty URL",
+			config:      WebhookConfig{URLs: []string{""}},
+			expectError: true,
+			errorMsg:    "webhook URL cannot be empty",
+		},
+		{
+			name:        "negative retries",
+			config:      WebhookConfig{URLs: []string{"https://hooks.example.com/vault"}, MaxRetries: -2},
+			expectError: true,
+			errorMsg:    "webhook max retries cannot be negative other than WebhookNoRetries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, err := NewWebhookNotifier(tt.config)
+
+			if tt.expectError {
+				AssertError(t, err, tt.errorMsg, "NewWebhookNotifier()")
+				if notifier != nil {
+					t.Errorf("NewWebhookNotifier() expected nil notifier when error occurs")
+				}
+				return
+			}
+
+			AssertNoError(t, err, "NewWebhookNotifier()")
+			if notifier.config.MaxRetries != tt.wantRetries {
+				t.Errorf("NewWebhookNotifier() MaxRetries = %d, want %d", notifier.config.MaxRetries, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	secret := "webhook-secret"
+	event := ChangeEvent{
+		Path:        "kv/data/test",
+		OldHash:     "old",
+		NewHash:     "new",
+		ChangedKeys: []string{"api_key"},
+		Timestamp:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	var received ChangeEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !VerifyWebhookSignature(secret, body, r.Header.Get(WebhookSignatureHeader)) {
+			t.Errorf("Notify() sent invalid signature %q", r.Header.Get(WebhookSignatureHeader))
+		}
+		AssertStringEquals(t, r.Header.Get("Content-Type"), "application/json", "Content-Type")
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("Notify() sent invalid JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(WebhookConfig{URLs: []string{server.URL}, Secret: secret})
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() error = %v", err)
+	}
+
+	AssertNoError(t, notifier.Notify(context.Background(), event), "Notify()")
+	AssertStringEquals(t, received.Path, event.Path, "Path")
+	AssertStringEquals(t, received.NewHash, event.NewHash, "NewHash")
+	if len(received.ChangedKeys) != 1 || received.ChangedKeys[0] != "api_key" {
+		t.Errorf("ChangedKeys = %v, want [api_key]", received.ChangedKeys)
+	}
+	if !received.Timestamp.Equal(event.Timestamp) {
+		t.Errorf("Timestamp = %v, want %v", received.Timestamp, event.Timestamp)
+	}
+}
+
+func TestWebhookNotifier_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		expectError  bool
+		wantAttempts int32
+	}{
+		{
+			name:         "succeeds after transient failures",
+			statuses:     []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK},
+			expectError:  false,
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up after max retries",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			expectError:  true,
+			wantAttempts: 3,
+		},
+		{
+			name:         "does not retry client errors",
+			statuses:     []int{http.StatusBadRequest, http.StatusOK},
+			expectError:  true,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer server.Close()
+
+			notifier, err := NewWebhookNotifier(WebhookConfig{
+				URLs:       []string{server.URL},
+				MaxRetries: 2,
+				RetryDelay: time.Millisecond,
+			})
+			if err != nil {
+				t.Fatalf("NewWebhookNotifier() error = %v", err)
+			}
+
+			err = notifier.Notify(context.Background(), ChangeEvent{Path: "kv/data/test"})
+			if tt.expectError && err == nil {
+				t.Errorf("Notify() expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Notify() unexpected error = %v", err)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.wantAttempts {
+				t.Errorf("Notify() attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"path":"kv/data/test"}`)
+	signature := SignWebhookPayload("secret", body)
+
+	AssertBoolEquals(t, VerifyWebhookSignature("secret", body, signature), true, "valid signature")
+	AssertBoolEquals(t, VerifyWebhookSignature("other", body, signature), false, "wrong secret")
+	AssertBoolEquals(t, VerifyWebhookSignature("secret", []byte("tampered"), signature), false, "tampered body")
+	AssertBoolEquals(t, VerifyWebhookSignature("secret", body, "md5=abc"), false, "unknown scheme")
+}
+
+func TestWatcher_NotifyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	webhook, err := NewWebhookNotifier(WebhookConfig{URLs: []string{server.URL}, MaxRetries: WebhookNoRetries})
+	AssertNoError(t, err, "NewWebhookNotifier()")
+
+	source := &fakeSource{}
+	source.set(map[string]interface{}{"password": "one"})
+	watcher, err := NewSourceWatcher("app", source, time.Hour, func() error { return nil },
+		WithNotifier(webhook), WithNotifyTimeout(50*time.Millisecond))
+	AssertNoError(t, err, "NewSourceWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	source.set(map[string]interface{}{"password": "two"})
+	start := time.Now()
+	AssertNoError(t, watcher.check(), "check()")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("check() with a hanging webhook took %v, want about 50ms", elapsed)
+	}
+}
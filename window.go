@@ -0,0 +1,140 @@
+package vaultwatcher
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxWindowExtensions bounds how often back-to-back windows extend each other
+const maxWindowExtensions = 1000
+
+// QuietWindow is a period, such as a maintenance window, during which
+// onChange callbacks are deferred. Change detection continues meanwhile.
+type QuietWindow interface {
+	// Until returns when the window containing t closes, or the zero time if
+	// t is outside the window
+	Until(t time.Time) time.Time
+}
+
+// cronWindow is open for a fixed duration from every start matching a schedule
+type cronWindow struct {
+	schedule *CronSchedule
+	duration time.Duration
+}
+
+// CronWindow returns a quiet window opening at every time matching expr and
+// lasting duration, e.g. CronWindow("0 2 * * SAT", 4*time.Hour)
+func CronWindow(expr string, duration time.Duration) (QuietWindow, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("window duration must be positive")
+	}
+	return &cronWindow{schedule: schedule, duration: duration}, nil
+}
+
+// Until finds the latest start within duration before t and extends the window
+// by starts that happen before it closes
+func (c *cronWindow) Until(t time.Time) time.Time {
+	// Next is exclusive, so look from just before the earliest possible start
+	start := c.schedule.Next(t.Add(-c.duration).Add(-time.Nanosecond))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}
+	}
+
+	// Overlapping starts keep the window open; bounded for windows that never close
+	end := start.Add(c.duration)
+	for i := 0; i < maxWindowExtensions; i++ {
+		next := c.schedule.Next(start)
+		if next.IsZero() || next.After(end) {
+			break
+		}
+		start, end = next, next.Add(c.duration)
+	}
+	if !end.After(t) {
+		return time.Time{}
+	}
+	return end
+}
+
+// dailyWindow is open between two clock times, optionally only on some weekdays
+type dailyWindow struct {
+	start time.Duration // Since midnight
+	end   time.Duration // Since midnight, before start if the window crosses midnight
+	days  map[time.Weekday]bool
+}
+
+// DailyWindow returns a quiet window between two clock times such as "22:00"
+// and "06:00"; windows ending before they start cross midnight. With days, the
+// window only opens on those weekdays. Times are evaluated in the location of
+// the time checked, normally the local time zone.
+func DailyWindow(start, end string, days ...time.Weekday) (QuietWindow, error) {
+	from, err := parseClock(start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseClock(end)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &dailyWindow{start: from, end: to}
+	if len(days) > 0 {
+		w.days = make(map[time.Weekday]bool, len(days))
+		for _, day := range days {
+			w.days[day] = true
+		}
+	}
+	return w, nil
+}
+
+// Until checks the windows opening today and yesterday, which may still be
+// open after midnight
+func (d *dailyWindow) Until(t time.Time) time.Time {
+	length := d.end - d.start
+	if length <= 0 {
+		length += 24 * time.Hour
+	}
+
+	for _, offset := range []int{0, -1} {
+		day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, t.Location())
+		if d.days != nil && !d.days[day.Weekday()] {
+			continue
+		}
+		start := day.Add(d.start)
+		end := start.Add(length)
+		if !t.Before(start) && t.Before(end) {
+			return end
+		}
+	}
+	return time.Time{}
+}
+
+// parseClock parses a clock time like "06:30" into the time since midnight
+func parseClock(clock string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", clock)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// quietUntil returns when every quiet window containing t has closed, or the
+// zero time if t is outside all of them. Callers must hold w.mu.
+func (w *Watcher) quietUntil(t time.Time) time.Time {
+	var until time.Time
+	for i := 0; i < maxWindowExtensions; i++ {
+		extended := false
+		for _, window := range w.quietWindows {
+			if end := window.Until(t); end.After(t) {
+				t, until, extended = end, end, true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+	return until
+}
@@ -0,0 +1,165 @@
+package vaultwatcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naman-dave/vault-watcher/vaultwatchertest"
+)
+
+// quietWindowFunc adapts a function to a QuietWindow
+type quietWindowFunc func(t time.Time) time.Time
+
+func (f quietWindowFunc) Until(t time.Time) time.Time { return f(t) }
+
+func TestCronWindow_Until(t *testing.T) {
+	// Saturdays from 02:00 for four hours
+	window, err := CronWindow("0 2 * * SAT", 4*time.Hour)
+	AssertNoError(t, err, "CronWindow()")
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"before", time.Date(2024, time.May, 18, 1, 59, 0, 0, time.UTC), time.Time{}},
+		{"at start", time.Date(2024, time.May, 18, 2, 0, 0, 0, time.UTC), time.Date(2024, time.May, 18, 6, 0, 0, 0, time.UTC)},
+		{"inside", time.Date(2024, time.May, 18, 5, 30, 0, 0, time.UTC), time.Date(2024, time.May, 18, 6, 0, 0, 0, time.UTC)},
+		{"at end", time.Date(2024, time.May, 18, 6, 0, 0, 0, time.UTC), time.Time{}},
+		{"other day", time.Date(2024, time.May, 19, 3, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := window.Until(tt.at); !got.Equal(tt.want) {
+				t.Errorf("Until() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Overlapping starts extend the window
+	overlapping, err := CronWindow("0 * * * *", 90*time.Minute)
+	AssertNoError(t, err, "CronWindow()")
+	if got := overlapping.Until(time.Date(2024, time.May, 18, 2, 0, 0, 0, time.UTC)); got.IsZero() || got.Sub(time.Date(2024, time.May, 18, 2, 0, 0, 0, time.UTC)) < 24*time.Hour {
+		t.Errorf("Until() of an always open window = %v, want far in the future", got)
+	}
+
+	_, err = CronWindow("0 2 * * SAT", 0)
+	AssertError(t, err, "window duration must be positive", "CronWindow() without duration")
+}
+
+func TestDailyWindow_Until(t *testing.T) {
+	overnight, err := DailyWindow("22:00", "06:00")
+	AssertNoError(t, err, "DailyWindow()")
+	weekdays, err := DailyWindow("12:00", "13:00", time.Monday, time.Tuesday)
+	AssertNoError(t, err, "DailyWindow()")
+
+	// Monday, May 20 2024
+	day := func(d, h, m int) time.Time { return time.Date(2024, time.May, d, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		window QuietWindow
+		at     time.Time
+		want   time.Time
+	}{
+		{"overnight before midnight", overnight, day(20, 23, 0), day(21, 6, 0)},
+		{"overnight after midnight", overnight, day(21, 5, 59), day(21, 6, 0)},
+		{"overnight closed", overnight, day(21, 6, 0), time.Time{}},
+		{"weekday open", weekdays, day(20, 12, 30), day(20, 13, 0)},
+		{"weekday other day", weekdays, day(22, 12, 30), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Until(tt.at); !got.Equal(tt.want) {
+				t.Errorf("Until() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err = DailyWindow("25:00", "06:00")
+	AssertError(t, err, `invalid time "25:00", want HH:MM`, "DailyWindow() with invalid time")
+}
+
+func TestWatcher_QuietWindows(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"value": "v1"})
+
+	var mu sync.Mutex
+	closesAt := time.Now().Add(time.Hour)
+	window := quietWindowFunc(func(t time.Time) time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		if t.Before(closesAt) {
+			return closesAt
+		}
+		return time.Time{}
+	})
+
+	calls := 0
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return nil
+		}, WithQuietWindows(window))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	initialHash := watcher.GetCurrentHash()
+	vault.Put("secret/app", map[string]interface{}{"value": "v2"})
+	AssertNoError(t, watcher.checkForChanges(), "check during window")
+	AssertBoolEquals(t, watcher.HasPendingChange(), true, "HasPendingChange() during window")
+	AssertStringEquals(t, watcher.GetCurrentHash(), initialHash, "hash during window")
+
+	// Close the window early; the next check applies the change
+	mu.Lock()
+	closesAt = time.Now()
+	mu.Unlock()
+	AssertNoError(t, watcher.checkForChanges(), "check after window")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestWatcher_QuietWindowsApplyWhenClosed(t *testing.T) {
+	vault := vaultwatchertest.NewServer()
+	defer vault.Close()
+	vault.Put("secret/app", map[string]interface{}{"value": "v1"})
+
+	closesAt := time.Now().Add(100 * time.Millisecond)
+	window := quietWindowFunc(func(t time.Time) time.Time {
+		if t.Before(closesAt) {
+			return closesAt
+		}
+		return time.Time{}
+	})
+
+	changed := make(chan struct{}, 1)
+	watcher, err := NewWatcher(&VaultConfig{Host: vault.URL, Path: "secret/data/app", Token: vault.Token}, time.Hour,
+		func() error {
+			changed <- struct{}{}
+			return nil
+		}, WithQuietWindows(window))
+	AssertNoError(t, err, "NewWatcher()")
+	AssertNoError(t, watcher.Start(), "Start()")
+	defer watcher.Stop()
+
+	vault.Put("secret/app", map[string]interface{}{"value": "v2"})
+	AssertNoError(t, watcher.checkForChanges(), "check during window")
+
+	select {
+	case <-changed:
+		if time.Now().Before(closesAt) {
+			t.Error("callback ran before the window closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("deferred change was not applied after the window closed")
+	}
+}